@@ -4,12 +4,14 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/gorilla/mux"
+	"github.com/r3labs/sse/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,7 +43,6 @@ func newTestBackend(t require.TestingT, numBeaconNodes int) *testBackend {
 func TestBeaconInstance(t *testing.T) {
 	r := mux.NewRouter()
 	srv := httptest.NewServer(r)
-	bc := NewProdBeaconInstance(common.TestLog, srv.URL)
 
 	r.HandleFunc("/eth/v1/beacon/states/1/validators", func(w http.ResponseWriter, _ *http.Request) {
 		resp := []byte(`{
@@ -68,6 +69,7 @@ func TestBeaconInstance(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	bc := NewProdBeaconInstance(common.TestLog, srv.URL)
 	vals, err := bc.FetchValidators(1)
 	require.NoError(t, err)
 	require.Equal(t, 1, len(vals))
@@ -154,6 +156,70 @@ func TestUpdateProposerDuties(t *testing.T) {
 	})
 }
 
+func TestGetCrossCheckedProposerDuties(t *testing.T) {
+	newDuties := func(pubkeys ...string) *ProposerDutiesResponse {
+		duties := &ProposerDutiesResponse{Data: []ProposerDutiesResponseData{}}
+		for i, pubkey := range pubkeys {
+			duties.Data = append(duties.Data, ProposerDutiesResponseData{Pubkey: pubkey, Slot: uint64(32 + i)})
+		}
+		return duties
+	}
+
+	t.Run("returns the duties all nodes agree on", func(t *testing.T) {
+		backend := newTestBackend(t, 3)
+		for _, mock := range backend.beaconInstances {
+			mock.MockProposerDuties = newDuties(testPubKey, "0xb2")
+		}
+		// the order of the duties doesn't matter
+		backend.beaconInstances[1].MockProposerDuties = &ProposerDutiesResponse{Data: []ProposerDutiesResponseData{
+			{Pubkey: "0xb2", Slot: 33},
+			{Pubkey: testPubKey, Slot: 32},
+		}}
+
+		duties, err := backend.beaconClient.GetCrossCheckedProposerDuties(1)
+		require.NoError(t, err)
+		require.Equal(t, *newDuties(testPubKey, "0xb2"), *duties)
+	})
+
+	t.Run("unavailable nodes are ignored", func(t *testing.T) {
+		backend := newTestBackend(t, 2)
+		backend.beaconInstances[0].MockProposerDutiesErr = errTest
+		backend.beaconInstances[1].MockProposerDuties = newDuties(testPubKey)
+
+		duties, err := backend.beaconClient.GetCrossCheckedProposerDuties(1)
+		require.NoError(t, err)
+		require.Equal(t, *newDuties(testPubKey), *duties)
+	})
+
+	t.Run("a single disagreeing node fails", func(t *testing.T) {
+		backend := newTestBackend(t, 3)
+		backend.beaconInstances[0].MockProposerDuties = newDuties(testPubKey, "0xb2")
+		backend.beaconInstances[1].MockProposerDuties = newDuties(testPubKey, "0xb2")
+		backend.beaconInstances[2].MockProposerDuties = newDuties(testPubKey, "0xb3")
+
+		_, err := backend.beaconClient.GetCrossCheckedProposerDuties(1)
+		require.ErrorIs(t, err, ErrBeaconNodesDisagree)
+	})
+
+	t.Run("missing slots are a disagreement", func(t *testing.T) {
+		backend := newTestBackend(t, 2)
+		backend.beaconInstances[0].MockProposerDuties = newDuties(testPubKey, "0xb2")
+		backend.beaconInstances[1].MockProposerDuties = newDuties(testPubKey)
+
+		_, err := backend.beaconClient.GetCrossCheckedProposerDuties(1)
+		require.ErrorIs(t, err, ErrBeaconNodesDisagree)
+	})
+
+	t.Run("returns err if all of the beacon nodes return error", func(t *testing.T) {
+		backend := newTestBackend(t, 2)
+		backend.beaconInstances[0].MockProposerDutiesErr = errTest
+		backend.beaconInstances[1].MockProposerDutiesErr = errTest
+
+		_, err := backend.beaconClient.GetCrossCheckedProposerDuties(1)
+		require.ErrorIs(t, err, ErrBeaconNodesUnavailable)
+	})
+}
+
 func TestFetchValidators(t *testing.T) {
 	t.Run("returns err if all of the beacon nodes return error", func(t *testing.T) {
 		backend := newTestBackend(t, 2)
@@ -199,7 +265,6 @@ func TestFetchValidators(t *testing.T) {
 func TestGetForkSchedule(t *testing.T) {
 	r := mux.NewRouter()
 	srv := httptest.NewServer(r)
-	bc := NewProdBeaconInstance(common.TestLog, srv.URL)
 
 	r.HandleFunc("/eth/v1/config/fork_schedule", func(w http.ResponseWriter, _ *http.Request) {
 		resp := []byte(`{
@@ -230,7 +295,203 @@ func TestGetForkSchedule(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	bc := NewProdBeaconInstance(common.TestLog, srv.URL)
 	forkSchedule, err := bc.GetForkSchedule()
 	require.NoError(t, err)
 	require.Equal(t, 4, len(forkSchedule.Data))
 }
+
+func TestBeaconClientQuirks(t *testing.T) {
+	require.Equal(t, BeaconClientLighthouse, parseBeaconClientType("Lighthouse/v4.1.0-693886b/x86_64-linux"))
+	require.Equal(t, BeaconClientPrysm, parseBeaconClientType("Prysm/v4.0.3/3a5e6c9ba8e2ac4b4a4d8a4ba2c2c0e9d0ad6c1a"))
+	require.Equal(t, BeaconClientTeku, parseBeaconClientType("teku/v23.4.0/linux-x86_64/-eclipseadoptium-openjdk64bitservervm-java-17"))
+	require.Equal(t, BeaconClientNimbus, parseBeaconClientType("Nimbus/v23.3.2-6c0d756d-stateofus"))
+	require.Equal(t, BeaconClientUnknown, parseBeaconClientType(""))
+
+	t.Run("validators are filtered without the status filter", func(t *testing.T) {
+		r := mux.NewRouter()
+		srv := httptest.NewServer(r)
+
+		r.HandleFunc("/eth/v1/node/version", func(w http.ResponseWriter, _ *http.Request) {
+			_, err := w.Write([]byte(`{"data":{"version":"Prysm/v4.0.3/3a5e6c9ba8e2ac4b4a4d8a4ba2c2c0e9d0ad6c1a"}}`))
+			require.NoError(t, err)
+		})
+		r.HandleFunc("/eth/v1/beacon/states/1/validators", func(w http.ResponseWriter, req *http.Request) {
+			require.Empty(t, req.URL.Query().Get("status"))
+			_, err := w.Write([]byte(`{"data":[
+				{"index":"1","balance":"1","status":"ACTIVE_ONGOING","validator":{"pubkey":"` + testPubKey + `"}},
+				{"index":"2","balance":"1","status":"exited_unslashed","validator":{"pubkey":"0x01"}}
+			]}`))
+			require.NoError(t, err)
+		})
+
+		// the client is detected in the background
+		bc := NewProdBeaconInstance(common.TestLog, srv.URL)
+		require.Eventually(t, func() bool { return bc.ClientType() == BeaconClientPrysm }, time.Second, 10*time.Millisecond)
+
+		vals, err := bc.FetchValidators(1)
+		require.NoError(t, err)
+		require.Len(t, vals, 1)
+		require.Equal(t, "active_ongoing", vals[types.PubkeyHex(testPubKey)].Status)
+	})
+
+	t.Run("withdrawals are skipped if unsupported", func(t *testing.T) {
+		r := mux.NewRouter()
+		srv := httptest.NewServer(r)
+
+		r.HandleFunc("/eth/v1/node/version", func(w http.ResponseWriter, _ *http.Request) {
+			_, err := w.Write([]byte(`{"data":{"version":"teku/v23.4.0/linux-x86_64"}}`))
+			require.NoError(t, err)
+		})
+
+		bc := NewProdBeaconInstance(common.TestLog, srv.URL)
+		require.Eventually(t, func() bool { return bc.ClientType() == BeaconClientTeku }, time.Second, 10*time.Millisecond)
+
+		_, err := bc.GetWithdrawals(1)
+		require.ErrorIs(t, err, ErrUnsupportedByBeaconClient)
+	})
+
+	t.Run("keep-alives and other events are skipped", func(t *testing.T) {
+		_, ok := headEventData(&sse.Event{ID: []byte("1")}) //nolint:exhaustruct
+		require.False(t, ok)
+		_, ok = headEventData(&sse.Event{Event: []byte("block"), Data: []byte(`{"slot":"1"}`)}) //nolint:exhaustruct
+		require.False(t, ok)
+		data, ok := headEventData(&sse.Event{Event: []byte("head"), Data: []byte(`{"slot":"1"}`)}) //nolint:exhaustruct
+		require.True(t, ok)
+		require.Equal(t, `{"slot":"1"}`, string(data))
+	})
+}
+
+func TestMockForkTransition(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	mock := backend.beaconInstances[0]
+	mock.SetForks(
+		MockFork{Name: MockForkDeneb, Version: "0x04000000", Epoch: 2},
+		MockFork{Name: MockForkCapella, Version: "0x03000000", Epoch: 1},
+		MockFork{Name: MockForkBellatrix, Version: "0x02000000", Epoch: 0},
+	)
+
+	forkSchedule, err := backend.beaconClient.GetForkSchedule()
+	require.NoError(t, err)
+	require.Len(t, forkSchedule.Data, 3)
+	require.Equal(t, "0x03000000", forkSchedule.Data[2].PreviousVersion)
+	require.Equal(t, "0x04000000", forkSchedule.Data[2].CurrentVersion)
+	require.Equal(t, uint64(2), forkSchedule.Data[2].Epoch)
+
+	spec, err := backend.beaconClient.GetSpec()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), spec.CapellaForkEpoch)
+	require.Equal(t, uint64(2), spec.DenebForkEpoch)
+
+	// the blocks and withdrawals follow the fork of their slot
+	for slot, version := range map[uint64]string{31: MockForkBellatrix, 32: MockForkCapella, 63: MockForkCapella, 64: MockForkDeneb} {
+		block, err := backend.beaconClient.GetBlock(strconv.FormatUint(slot, 10))
+		require.NoError(t, err)
+		require.Equal(t, version, block.Version)
+		require.Equal(t, slot, block.Data.Message.Slot)
+	}
+	_, err = backend.beaconClient.GetWithdrawals(31)
+	require.ErrorIs(t, err, ErrWithdrawalsBeforeCapella)
+	_, err = backend.beaconClient.GetWithdrawals(32)
+	require.NoError(t, err)
+
+	// the head moves slot by slot across the boundary
+	headC := make(chan HeadEventData)
+	mock.SubscribeToHeadEvents(headC)
+	go mock.AdvanceHead(31)
+	require.Equal(t, uint64(31), (<-headC).Slot)
+	go mock.AdvanceHead(33)
+	require.Equal(t, uint64(32), (<-headC).Slot)
+	require.Equal(t, uint64(33), (<-headC).Slot)
+
+	block, err := backend.beaconClient.GetBlock("head")
+	require.NoError(t, err)
+	require.Equal(t, uint64(33), block.Data.Message.Slot)
+	require.Equal(t, MockForkCapella, mock.ForkAtSlot(33).Name)
+}
+
+func TestCrossCheckAttributes(t *testing.T) {
+	newCrossCheckClient := func(numBeaconNodes int) (*MultiBeaconClient, []*MockBeaconInstance) {
+		backend := newTestBackend(t, numBeaconNodes)
+		for _, mock := range backend.beaconInstances {
+			mock.SetForks(MockFork{Name: MockForkCapella, Version: "0x03000000", Epoch: 0})
+		}
+		client := backend.beaconClient.(*MultiBeaconClient)
+		client.ffCrossCheckAttributes = true
+		return client, backend.beaconInstances
+	}
+	expectedRandao := mockHash("randao", 10).Hex()
+
+	t.Run("stale node is outvoted", func(t *testing.T) {
+		client, mocks := newCrossCheckClient(3)
+		mocks[0].MockRandaoLag = 1
+		randao, err := client.GetRandao(10)
+		require.NoError(t, err)
+		require.Equal(t, expectedRandao, randao.Data.Randao)
+	})
+
+	t.Run("unavailable nodes are ignored", func(t *testing.T) {
+		client, mocks := newCrossCheckClient(2)
+		mocks[0].SetForks()
+		randao, err := client.GetRandao(10)
+		require.NoError(t, err)
+		require.Equal(t, expectedRandao, randao.Data.Randao)
+	})
+
+	t.Run("without quorum the first answer is used", func(t *testing.T) {
+		client, mocks := newCrossCheckClient(2)
+		require.Equal(t, 0, client.quorum())
+		mocks[0].MockRandaoLag = 1
+		mocks[0].ResponseDelay = 50 * time.Millisecond
+		randao, err := client.GetRandao(10)
+		require.NoError(t, err)
+		require.Equal(t, expectedRandao, randao.Data.Randao)
+
+		// disagreeing answers aren't cached
+		mocks[0].ResponseDelay = 0
+		mocks[1].ResponseDelay = 50 * time.Millisecond
+		randao, err = client.GetRandao(10)
+		require.NoError(t, err)
+		require.Equal(t, mockHash("randao", 9).Hex(), randao.Data.Randao)
+	})
+
+	t.Run("no quorum", func(t *testing.T) {
+		client, mocks := newCrossCheckClient(3)
+		mocks[0].MockRandaoLag = 1
+		mocks[1].MockRandaoLag = 2
+		_, err := client.GetRandao(10)
+		require.ErrorIs(t, err, ErrBeaconNodesDisagree)
+	})
+
+	t.Run("configured quorum", func(t *testing.T) {
+		crossCheckQuorum = 3
+		defer func() { crossCheckQuorum = 0 }()
+		client, mocks := newCrossCheckClient(3)
+		require.Equal(t, 3, client.quorum())
+		mocks[0].MockRandaoLag = 1
+		_, err := client.GetRandao(10)
+		require.ErrorIs(t, err, ErrBeaconNodesDisagree)
+	})
+
+	t.Run("agreed values are cached for the slot", func(t *testing.T) {
+		client, mocks := newCrossCheckClient(3)
+		randao, err := client.GetRandao(10)
+		require.NoError(t, err)
+		for _, mock := range mocks {
+			mock.MockRandaoLag = 1
+		}
+		cached, err := client.GetRandao(10)
+		require.NoError(t, err)
+		require.Equal(t, randao, cached)
+
+		randao, err = client.GetRandao(11)
+		require.NoError(t, err)
+		require.Equal(t, expectedRandao, randao.Data.Randao)
+	})
+
+	t.Run("withdrawals", func(t *testing.T) {
+		client, _ := newCrossCheckClient(3)
+		_, err := client.GetWithdrawals(10)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,116 @@
+package beaconclient
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrUnsupportedByBeaconClient = errors.New("not supported by the beacon node client")
+
+// BeaconClientType is the consensus client implementation of a beacon node
+type BeaconClientType string
+
+const (
+	BeaconClientLighthouse BeaconClientType = "lighthouse"
+	BeaconClientPrysm      BeaconClientType = "prysm"
+	BeaconClientTeku       BeaconClientType = "teku"
+	BeaconClientNimbus     BeaconClientType = "nimbus"
+	BeaconClientLodestar   BeaconClientType = "lodestar"
+	BeaconClientUnknown    BeaconClientType = "unknown"
+)
+
+// how long to wait before trying to detect the client of a beacon node again, if it failed
+const clientDetectionRetryInterval = time.Minute
+
+// clientQuirks are the known differences of a client from the beacon API spec, the zero value is the spec behavior
+type clientQuirks struct {
+	// the validators endpoint rejects the aggregate "active" and "pending" status filters, so all validators are
+	// fetched and filtered here
+	noValidatorStatusFilter bool
+
+	// the non-standard withdrawals endpoint isn't served, so the other beacon nodes are asked right away
+	noWithdrawalsEndpoint bool
+}
+
+var beaconClientQuirks = map[BeaconClientType]clientQuirks{
+	BeaconClientPrysm:    {noValidatorStatusFilter: true, noWithdrawalsEndpoint: false},
+	BeaconClientTeku:     {noValidatorStatusFilter: false, noWithdrawalsEndpoint: true},
+	BeaconClientNimbus:   {noValidatorStatusFilter: false, noWithdrawalsEndpoint: true},
+	BeaconClientLodestar: {noValidatorStatusFilter: false, noWithdrawalsEndpoint: true},
+}
+
+// GetNodeVersionResponse is the response of /eth/v1/node/version, e.g.
+// {"data":{"version":"Lighthouse/v4.1.0-693886b/x86_64-linux"}}
+type GetNodeVersionResponse struct {
+	Data struct {
+		Version string `json:"version"`
+	}
+}
+
+// parseBeaconClientType returns the client of a node version string, which starts with the client name
+func parseBeaconClientType(version string) BeaconClientType {
+	name, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(version)), "/")
+	switch clientType := BeaconClientType(name); clientType {
+	case BeaconClientLighthouse, BeaconClientPrysm, BeaconClientTeku, BeaconClientNimbus, BeaconClientLodestar:
+		return clientType
+	}
+	return BeaconClientUnknown
+}
+
+// clientDetector holds the client of a beacon node, which is detected in the background so that no request waits for
+// the node version
+type clientDetector struct {
+	lock       sync.RWMutex
+	clientType BeaconClientType // empty until detected
+}
+
+// detectClientType fetches the node version until the client is detected, retrying if the node couldn't be reached.
+// Until then, the spec behavior is assumed.
+func (c *ProdBeaconInstance) detectClientType() {
+	for {
+		resp := new(GetNodeVersionResponse)
+		_, err := fetchBeacon(http.MethodGet, c.beaconURI+"/eth/v1/node/version", nil, resp)
+		if err == nil {
+			clientType := parseBeaconClientType(resp.Data.Version)
+			c.detector.lock.Lock()
+			c.detector.clientType = clientType
+			c.detector.lock.Unlock()
+			c.log.WithFields(logrus.Fields{
+				"version":    resp.Data.Version,
+				"clientType": clientType,
+			}).Info("detected beacon node client")
+			return
+		}
+		c.log.WithError(err).Warn("could not detect the beacon node client, assuming the spec behavior")
+		time.Sleep(clientDetectionRetryInterval)
+	}
+}
+
+// ClientType returns the client of the beacon node, BeaconClientUnknown if it wasn't detected yet
+func (c *ProdBeaconInstance) ClientType() BeaconClientType {
+	c.detector.lock.RLock()
+	defer c.detector.lock.RUnlock()
+	if c.detector.clientType == "" {
+		return BeaconClientUnknown
+	}
+	return c.detector.clientType
+}
+
+func (c *ProdBeaconInstance) quirks() clientQuirks {
+	return beaconClientQuirks[c.ClientType()]
+}
+
+// normalizeValidatorStatus returns the status in the lowercase spec format, some client versions use uppercase
+func normalizeValidatorStatus(status string) string {
+	return strings.ToLower(strings.TrimSpace(status))
+}
+
+// isActiveOrPendingStatus returns whether the validator status is one of the active or pending ones
+func isActiveOrPendingStatus(status string) bool {
+	return strings.HasPrefix(status, "active") || strings.HasPrefix(status, "pending")
+}
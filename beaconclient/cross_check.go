@@ -0,0 +1,176 @@
+package beaconclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errEmptyResponse = errors.New("empty response")
+
+	// crossCheckQuorum is the number of beacon nodes that have to agree on a value, 0 for a strict majority of the
+	// configured nodes. It's only enforced with crossCheckMinNodes or more nodes: with fewer, a single lagging node
+	// would block every value.
+	crossCheckQuorum   = cli.GetEnvInt("BEACON_CROSS_CHECK_QUORUM", 0)
+	crossCheckMinNodes = 3
+)
+
+// crossCheckCacheKey identifies a cross-checked value
+type crossCheckCacheKey struct {
+	attribute string
+	slot      uint64
+}
+
+// crossCheckCache has the cross-checked values of the recent slots, so that each value is agreed on once per slot
+type crossCheckCache struct {
+	mu     sync.Mutex
+	values map[crossCheckCacheKey]any
+}
+
+func (c *crossCheckCache) get(attribute string, slot uint64) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[crossCheckCacheKey{attribute, slot}]
+	return value, ok
+}
+
+// set adds a value, and drops the ones of slots more than an epoch older
+func (c *crossCheckCache) set(attribute string, slot uint64, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = make(map[crossCheckCacheKey]any)
+	}
+	for key := range c.values {
+		if key.slot+uint64(common.SlotsPerEpoch) < slot {
+			delete(c.values, key)
+		}
+	}
+	c.values[crossCheckCacheKey{attribute, slot}] = value
+}
+
+// quorum returns the number of nodes that have to agree on a value, 0 if not enforced
+func (c *MultiBeaconClient) quorum() int {
+	numNodes := len(c.beaconInstances)
+	if numNodes < crossCheckMinNodes {
+		return 0
+	}
+	if crossCheckQuorum > 0 && crossCheckQuorum <= numNodes {
+		return crossCheckQuorum
+	}
+	return numNodes/2 + 1
+}
+
+// crossCheck queries all beacon nodes of the slot's value of attribute in parallel. Responses are compared by the key
+// returned by keyFn. With crossCheckMinNodes or more nodes, it returns the response the quorum of nodes agrees on, so a
+// single stale node is outvoted instead of being used to validate the submissions of the slot. With fewer nodes there
+// is no quorum, and the response of the node that answered first is used. Agreed values are cached for the slot.
+func crossCheck[T any](c *MultiBeaconClient, slot uint64, attribute string, queryFn func(IBeaconInstance) (*T, error), keyFn func(*T) (string, error)) (*T, error) {
+	if cached, ok := c.crossCheckCache.get(attribute, slot); ok {
+		return cached.(*T), nil
+	}
+
+	type response struct {
+		uri  string
+		resp *T
+		key  string
+	}
+
+	log := c.log.WithField("slot", slot)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var err error
+	responses := make([]response, 0, len(c.beaconInstances))
+	for _, instance := range c.beaconInstances {
+		wg.Add(1)
+		go func(instance IBeaconInstance) {
+			defer wg.Done()
+			resp, _err := queryFn(instance)
+			key := ""
+			if _err == nil && resp == nil {
+				_err = errEmptyResponse
+			} else if _err == nil {
+				key, _err = keyFn(resp)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if _err != nil {
+				log.WithField("uri", instance.GetURI()).WithError(_err).Warnf("failed to get %s", attribute)
+				err = _err
+				return
+			}
+			responses = append(responses, response{uri: instance.GetURI(), resp: resp, key: key})
+		}(instance)
+	}
+	wg.Wait()
+
+	if len(responses) == 0 {
+		return nil, err
+	}
+
+	votes := make(map[string]int)
+	for _, r := range responses {
+		votes[r.key]++
+	}
+	quorum := c.quorum()
+	for _, r := range responses {
+		if votes[r.key] < quorum {
+			continue
+		}
+		if len(votes) > 1 {
+			common.BeaconCrossCheckDisagreementsTotal.WithLabelValues(attribute).Inc()
+			for _, other := range responses {
+				if other.key != r.key {
+					log.WithFields(logrus.Fields{
+						"uri":    other.uri,
+						"got":    other.key,
+						"agreed": r.key,
+						"quorum": quorum,
+					}).Warnf("beacon node disagrees on %s", attribute)
+				}
+			}
+		}
+		// without a quorum, disagreeing responses aren't cached, the nodes are asked again the next time
+		if quorum > 0 || len(votes) == 1 {
+			c.crossCheckCache.set(attribute, slot, r.resp)
+		}
+		return r.resp, nil
+	}
+
+	common.BeaconCrossCheckDisagreementsTotal.WithLabelValues(attribute).Inc()
+	log.WithFields(logrus.Fields{
+		"numResponses": len(responses),
+		"quorum":       quorum,
+	}).Errorf("no quorum of the beacon nodes agrees on %s", attribute)
+	return nil, fmt.Errorf("%w on %s", ErrBeaconNodesDisagree, attribute)
+}
+
+func (c *MultiBeaconClient) crossCheckRandao(slot uint64) (*GetRandaoResponse, error) {
+	return crossCheck(c, slot, "randao",
+		func(instance IBeaconInstance) (*GetRandaoResponse, error) {
+			return instance.GetRandao(slot)
+		},
+		func(resp *GetRandaoResponse) (string, error) {
+			return resp.Data.Randao, nil
+		},
+	)
+}
+
+func (c *MultiBeaconClient) crossCheckWithdrawals(slot uint64) (*GetWithdrawalsResponse, error) {
+	return crossCheck(c, slot, "withdrawals",
+		func(instance IBeaconInstance) (*GetWithdrawalsResponse, error) {
+			return instance.GetWithdrawals(slot)
+		},
+		func(resp *GetWithdrawalsResponse) (string, error) {
+			withdrawals, err := json.Marshal(resp.Data.Withdrawals)
+			return string(withdrawals), err
+		},
+	)
+}
@@ -1,22 +1,56 @@
 package beaconclient
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/common"
 )
 
+// Names of the forks of the mock beacon node, as the version of the blocks
+const (
+	MockForkBellatrix = "bellatrix"
+	MockForkCapella   = "capella"
+	MockForkDeneb     = "deneb"
+)
+
+// errMockWithdrawalsBeforeCapella is the error of a beacon node asked for withdrawals before capella
+var errMockWithdrawalsBeforeCapella = errors.New("Withdrawals not enabled before capella") //nolint:stylecheck
+
+// MockFork is a fork of the mock beacon node, active from its epoch on
+type MockFork struct {
+	Name    string
+	Version string
+	Epoch   uint64
+}
+
 type MockBeaconInstance struct {
 	mu           sync.RWMutex
 	validatorSet map[types.PubkeyHex]ValidatorResponseEntry
 
+	// with forks, the mock serves the fork schedule, spec, blocks, randao and withdrawals of its chain
+	forks           []MockFork
+	headSlot        uint64
+	headSubscribers []chan HeadEventData
+
 	MockSyncStatus         *SyncStatusPayloadData
 	MockSyncStatusErr      error
 	MockProposerDuties     *ProposerDutiesResponse
 	MockProposerDutiesErr  error
 	MockFetchValidatorsErr error
+	MockForkScheduleErr    error
+
+	// MockRandaoLag makes the mock answer the randao of this many slots earlier, like a stale beacon node
+	MockRandaoLag uint64
 
 	ResponseDelay time.Duration
 }
@@ -72,6 +106,19 @@ func (c *MockBeaconInstance) FetchValidators(headSlot uint64) (map[types.PubkeyH
 	return c.validatorSet, c.MockFetchValidatorsErr
 }
 
+func (c *MockBeaconInstance) FetchValidatorsFromIndex(headSlot, fromIndex uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error) {
+	c.addDelay()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	validators := make(map[types.PubkeyHex]ValidatorResponseEntry)
+	for pubkey, entry := range c.validatorSet {
+		if entry.Index >= fromIndex {
+			validators[pubkey] = entry
+		}
+	}
+	return validators, c.MockFetchValidatorsErr
+}
+
 func (c *MockBeaconInstance) SyncStatus() (*SyncStatusPayloadData, error) {
 	c.addDelay()
 	return c.MockSyncStatus, c.MockSyncStatusErr
@@ -82,7 +129,77 @@ func (c *MockBeaconInstance) CurrentSlot() (uint64, error) {
 	return c.MockSyncStatus.HeadSlot, nil
 }
 
-func (c *MockBeaconInstance) SubscribeToHeadEvents(slotC chan HeadEventData) {}
+func (c *MockBeaconInstance) SubscribeToHeadEvents(slotC chan HeadEventData) {
+	c.mu.Lock()
+	c.headSubscribers = append(c.headSubscribers, slotC)
+	c.mu.Unlock()
+}
+
+// SetForks schedules the forks of the mock chain. The fork schedule, the spec and the blocks follow them, and the
+// withdrawals are only available from capella on.
+func (c *MockBeaconInstance) SetForks(forks ...MockFork) {
+	forks = append([]MockFork{}, forks...)
+	sort.Slice(forks, func(i, j int) bool { return forks[i].Epoch < forks[j].Epoch })
+	c.mu.Lock()
+	c.forks = forks
+	c.mu.Unlock()
+}
+
+// ForkAtSlot returns the fork active at the slot, the zero value before the first fork
+func (c *MockBeaconInstance) ForkAtSlot(slot uint64) MockFork {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.forkAtSlot(slot)
+}
+
+func (c *MockBeaconInstance) forkAtSlot(slot uint64) MockFork {
+	epoch := slot / uint64(common.SlotsPerEpoch)
+	fork := MockFork{}
+	for _, f := range c.forks {
+		if f.Epoch <= epoch {
+			fork = f
+		}
+	}
+	return fork
+}
+
+func (c *MockBeaconInstance) forkEpoch(name string) uint64 {
+	for _, f := range c.forks {
+		if f.Name == name {
+			return f.Epoch
+		}
+	}
+	return math.MaxUint64
+}
+
+// AdvanceHead moves the head of the mock chain slot by slot up to the given slot, sending a head event for every slot
+// to the subscribers, which have to receive them
+func (c *MockBeaconInstance) AdvanceHead(slot uint64) {
+	c.mu.Lock()
+	from := c.headSlot + 1
+	if c.headSlot == 0 {
+		from = slot
+	}
+	subscribers := c.headSubscribers
+	c.mu.Unlock()
+
+	for s := from; s <= slot; s++ {
+		c.mu.Lock()
+		c.headSlot = s
+		c.MockSyncStatus = &SyncStatusPayloadData{HeadSlot: s, IsSyncing: false}
+		c.mu.Unlock()
+
+		event := HeadEventData{Slot: s, Block: mockHash("block", s).Hex()}
+		for _, slotC := range subscribers {
+			slotC <- event
+		}
+	}
+}
+
+// mockHash returns a deterministic hash for a slot of the mock chain
+func mockHash(kind string, slot uint64) ethcommon.Hash {
+	return crypto.Keccak256Hash([]byte(fmt.Sprintf("%s/%d", kind, slot)))
+}
 
 func (c *MockBeaconInstance) GetProposerDuties(epoch uint64) (*ProposerDutiesResponse, error) {
 	c.addDelay()
@@ -99,30 +216,121 @@ func (c *MockBeaconInstance) addDelay() {
 	}
 }
 
-func (c *MockBeaconInstance) PublishBlock(block *common.SignedBeaconBlock) (code int, err error) {
+func (c *MockBeaconInstance) PublishBlock(ctx context.Context, block *common.SignedBeaconBlock) (code int, err error) {
 	return 0, nil
 }
 
 func (c *MockBeaconInstance) GetGenesis() (*GetGenesisResponse, error) {
-	return nil, nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+	genesis := new(GetGenesisResponse)
+	genesis.Data.GenesisForkVersion = c.forks[0].Version
+	return genesis, nil
 }
 
+// GetBlock returns the block of a slot ('head' or a slot number) of the mock chain, with the version of its fork
 func (c *MockBeaconInstance) GetBlock(blockID string) (block *GetBlockResponse, err error) {
-	return nil, nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+
+	slot := c.headSlot
+	if blockID != "head" {
+		slot, err = strconv.ParseUint(blockID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	block = new(GetBlockResponse)
+	block.Version = c.forkAtSlot(slot).Name
+	block.Data.Message.Slot = slot
+	block.Data.Message.Body.ExecutionPayload.BlockNumber = slot
+	block.Data.Message.Body.ExecutionPayload.ParentHash = types.Hash(mockHash("payload", slot-1))
+	block.Data.Message.Body.ExecutionPayload.BlockHash = types.Hash(mockHash("payload", slot))
+	return block, nil
+}
+
+// GetBlockRoot returns the root of the block of a slot ('head' or a slot number), the one of its head event
+func (c *MockBeaconInstance) GetBlockRoot(blockID string) (root *GetBlockRootResponse, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+
+	slot := c.headSlot
+	if blockID != "head" {
+		slot, err = strconv.ParseUint(blockID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	root = new(GetBlockRootResponse)
+	root.Data.Root = mockHash("block", slot).Hex()
+	return root, nil
 }
 
 func (c *MockBeaconInstance) GetSpec() (spec *GetSpecResponse, err error) {
-	return nil, nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+	return &GetSpecResponse{
+		SecondsPerSlot:   uint64(common.DurationPerSlot.Seconds()),
+		CapellaForkEpoch: c.forkEpoch(MockForkCapella),
+		DenebForkEpoch:   c.forkEpoch(MockForkDeneb),
+	}, nil
 }
 
 func (c *MockBeaconInstance) GetForkSchedule() (spec *GetForkScheduleResponse, err error) {
-	return nil, nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.MockForkScheduleErr != nil {
+		return nil, c.MockForkScheduleErr
+	}
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+	spec = new(GetForkScheduleResponse)
+	previousVersion := c.forks[0].Version
+	for _, fork := range c.forks {
+		spec.Data = append(spec.Data, struct {
+			PreviousVersion string `json:"previous_version"`
+			CurrentVersion  string `json:"current_version"`
+			Epoch           uint64 `json:"epoch,string"`
+		}{PreviousVersion: previousVersion, CurrentVersion: fork.Version, Epoch: fork.Epoch})
+		previousVersion = fork.Version
+	}
+	return spec, nil
 }
 
 func (c *MockBeaconInstance) GetRandao(slot uint64) (spec *GetRandaoResponse, err error) {
-	return nil, nil
+	c.addDelay()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+	spec = new(GetRandaoResponse)
+	spec.Data.Randao = mockHash("randao", slot-c.MockRandaoLag).Hex()
+	return spec, nil
 }
 
+// GetWithdrawals returns no withdrawals from capella on, and the error of a beacon node before
 func (c *MockBeaconInstance) GetWithdrawals(slot uint64) (spec *GetWithdrawalsResponse, err error) {
-	return nil, nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.forks) == 0 {
+		return nil, nil
+	}
+	if slot/uint64(common.SlotsPerEpoch) < c.forkEpoch(MockForkCapella) {
+		return nil, errMockWithdrawalsBeforeCapella
+	}
+	return new(GetWithdrawalsResponse), nil
 }
@@ -2,8 +2,11 @@
 package beaconclient
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -17,6 +20,9 @@ var (
 	ErrBeaconNodeSyncing        = errors.New("beacon node is syncing or unavailable")
 	ErrBeaconNodesUnavailable   = errors.New("all beacon nodes responded with error")
 	ErrWithdrawalsBeforeCapella = errors.New("withdrawals are not supported before capella")
+
+	// ErrBeaconNodesDisagree is returned if the beacon nodes respond with conflicting data
+	ErrBeaconNodesDisagree = errors.New("beacon nodes disagree")
 )
 
 // IMultiBeaconClient is the interface for the MultiBeaconClient, which can manage several beacon client instances under the hood
@@ -26,12 +32,19 @@ type IMultiBeaconClient interface {
 
 	// FetchValidators returns all active and pending validators from the beacon node
 	FetchValidators(headSlot uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error)
+	// FetchValidatorsFromIndex returns the active and pending validators with an index of at least fromIndex
+	FetchValidatorsFromIndex(headSlot, fromIndex uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error)
 	GetProposerDuties(epoch uint64) (*ProposerDutiesResponse, error)
-	PublishBlock(block *common.SignedBeaconBlock) (code int, err error)
+	// GetCrossCheckedProposerDuties returns the proposer duties only if all responding beacon nodes agree on them
+	GetCrossCheckedProposerDuties(epoch uint64) (*ProposerDutiesResponse, error)
+	PublishBlock(ctx context.Context, block *common.SignedBeaconBlock) (code int, err error)
+	BroadcastBlock(ctx context.Context, block *common.SignedBeaconBlock) (numPublished int, err error)
 	GetGenesis() (*GetGenesisResponse, error)
 	GetSpec() (spec *GetSpecResponse, err error)
 	GetForkSchedule() (spec *GetForkScheduleResponse, err error)
+	GetForkSchedules() []NodeForkSchedule
 	GetBlock(blockID string) (block *GetBlockResponse, err error)
+	GetBlockRoot(blockID string) (*GetBlockRootResponse, error)
 	GetRandao(slot uint64) (spec *GetRandaoResponse, err error)
 	GetWithdrawals(slot uint64) (spec *GetWithdrawalsResponse, err error)
 }
@@ -42,13 +55,15 @@ type IBeaconInstance interface {
 	CurrentSlot() (uint64, error)
 	SubscribeToHeadEvents(slotC chan HeadEventData)
 	FetchValidators(headSlot uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error)
+	FetchValidatorsFromIndex(headSlot, fromIndex uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error)
 	GetProposerDuties(epoch uint64) (*ProposerDutiesResponse, error)
 	GetURI() string
-	PublishBlock(block *common.SignedBeaconBlock) (code int, err error)
+	PublishBlock(ctx context.Context, block *common.SignedBeaconBlock) (code int, err error)
 	GetGenesis() (*GetGenesisResponse, error)
 	GetSpec() (spec *GetSpecResponse, err error)
 	GetForkSchedule() (spec *GetForkScheduleResponse, err error)
 	GetBlock(blockID string) (*GetBlockResponse, error)
+	GetBlockRoot(blockID string) (*GetBlockRootResponse, error)
 	GetRandao(slot uint64) (spec *GetRandaoResponse, err error)
 	GetWithdrawals(slot uint64) (spec *GetWithdrawalsResponse, err error)
 }
@@ -60,6 +75,9 @@ type MultiBeaconClient struct {
 
 	// feature flags
 	ffAllowSyncingBeaconNode bool
+	ffCrossCheckAttributes   bool
+
+	crossCheckCache crossCheckCache
 }
 
 func NewMultiBeaconClient(log *logrus.Entry, beaconInstances []IBeaconInstance) *MultiBeaconClient {
@@ -68,6 +86,7 @@ func NewMultiBeaconClient(log *logrus.Entry, beaconInstances []IBeaconInstance)
 		beaconInstances:          beaconInstances,
 		bestBeaconIndex:          *uberatomic.NewInt64(0),
 		ffAllowSyncingBeaconNode: false,
+		ffCrossCheckAttributes:   false,
 	}
 
 	// feature flags
@@ -76,6 +95,11 @@ func NewMultiBeaconClient(log *logrus.Entry, beaconInstances []IBeaconInstance)
 		client.ffAllowSyncingBeaconNode = true
 	}
 
+	if os.Getenv("BEACON_CROSS_CHECK_ATTRIBUTES") == "1" {
+		client.log.WithField("quorum", client.quorum()).Info("env: BEACON_CROSS_CHECK_ATTRIBUTES: cross-check randao and withdrawals across all beacon nodes")
+		client.ffCrossCheckAttributes = true
+	}
+
 	return client
 }
 
@@ -162,6 +186,30 @@ func (c *MultiBeaconClient) FetchValidators(headSlot uint64) (map[types.PubkeyHe
 	return nil, ErrBeaconNodesUnavailable
 }
 
+func (c *MultiBeaconClient) FetchValidatorsFromIndex(headSlot, fromIndex uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error) {
+	// return the first successful beacon node response
+	clients := c.beaconInstancesByLastResponse()
+
+	for i, client := range clients {
+		log := c.log.WithFields(logrus.Fields{
+			"uri":       client.GetURI(),
+			"fromIndex": fromIndex,
+		})
+		log.Debug("fetching new validators")
+
+		validators, err := client.FetchValidatorsFromIndex(headSlot, fromIndex)
+		if err != nil {
+			log.WithError(err).Error("failed to fetch new validators")
+			continue
+		}
+
+		c.bestBeaconIndex.Store(int64(i))
+		return validators, nil
+	}
+
+	return nil, ErrBeaconNodesUnavailable
+}
+
 func (c *MultiBeaconClient) GetProposerDuties(epoch uint64) (*ProposerDutiesResponse, error) {
 	// return the first successful beacon node response
 	clients := c.beaconInstancesByLastResponse()
@@ -186,6 +234,94 @@ func (c *MultiBeaconClient) GetProposerDuties(epoch uint64) (*ProposerDutiesResp
 	return nil, ErrBeaconNodesUnavailable
 }
 
+// maxLoggedDutyMismatches caps the mismatching slots logged per beacon node
+const maxLoggedDutyMismatches = 8
+
+// GetCrossCheckedProposerDuties queries the proposer duties of the epoch from all beacon nodes in parallel, and only
+// returns them if all responding nodes agree on the proposer of every slot. A disagreeing node indicates a forked or
+// buggy beacon node, whose duties mustn't be served silently.
+func (c *MultiBeaconClient) GetCrossCheckedProposerDuties(epoch uint64) (*ProposerDutiesResponse, error) {
+	log := c.log.WithField("epoch", epoch)
+
+	type response struct {
+		uri    string
+		duties map[uint64]string
+		resp   *ProposerDutiesResponse
+	}
+	responses := make([]*response, len(c.beaconInstances))
+	var wg sync.WaitGroup
+	for i, instance := range c.beaconInstances {
+		wg.Add(1)
+		go func(i int, instance IBeaconInstance) {
+			defer wg.Done()
+			resp, err := instance.GetProposerDuties(epoch)
+			if err != nil || resp == nil {
+				log.WithField("uri", instance.GetURI()).WithError(err).Warn("failed to get proposer duties")
+				return
+			}
+			duties := make(map[uint64]string, len(resp.Data))
+			for _, duty := range resp.Data {
+				duties[duty.Slot] = duty.Pubkey
+			}
+			responses[i] = &response{uri: instance.GetURI(), duties: duties, resp: resp}
+		}(i, instance)
+	}
+	wg.Wait()
+
+	// the responses are compared to the first responding node, in the order the nodes are configured
+	var reference *response
+	numDisagreeing := 0
+	for _, r := range responses {
+		if r == nil {
+			continue
+		}
+		if reference == nil {
+			reference = r
+			continue
+		}
+
+		mismatches := []uint64{}
+		for slot, pubkey := range reference.duties {
+			if r.duties[slot] != pubkey {
+				mismatches = append(mismatches, slot)
+			}
+		}
+		for slot := range r.duties {
+			if _, found := reference.duties[slot]; !found {
+				mismatches = append(mismatches, slot)
+			}
+		}
+		if len(mismatches) == 0 {
+			continue
+		}
+
+		numDisagreeing++
+		sort.Slice(mismatches, func(i, j int) bool { return mismatches[i] < mismatches[j] })
+		slots := []string{}
+		for i, slot := range mismatches {
+			if i == maxLoggedDutyMismatches {
+				slots = append(slots, "...")
+				break
+			}
+			slots = append(slots, fmt.Sprint(slot))
+		}
+		log.WithFields(logrus.Fields{
+			"uri":           r.uri,
+			"referenceURI":  reference.uri,
+			"numMismatches": len(mismatches),
+			"slots":         strings.Join(slots, ","),
+		}).Error("beacon nodes disagree on the proposer duties")
+	}
+
+	if reference == nil {
+		return nil, ErrBeaconNodesUnavailable
+	}
+	if numDisagreeing > 0 {
+		return nil, fmt.Errorf("%w on the proposer duties of epoch %d", ErrBeaconNodesDisagree, epoch)
+	}
+	return reference.resp, nil
+}
+
 // beaconInstancesByLastResponse returns a list of beacon clients that has the client
 // with the last successful response as the first element of the slice
 func (c *MultiBeaconClient) beaconInstancesByLastResponse() []IBeaconInstance {
@@ -202,7 +338,7 @@ func (c *MultiBeaconClient) beaconInstancesByLastResponse() []IBeaconInstance {
 }
 
 // PublishBlock publishes the signed beacon block via https://ethereum.github.io/beacon-APIs/#/ValidatorRequiredApi/publishBlock
-func (c *MultiBeaconClient) PublishBlock(block *common.SignedBeaconBlock) (code int, err error) {
+func (c *MultiBeaconClient) PublishBlock(ctx context.Context, block *common.SignedBeaconBlock) (code int, err error) {
 	log := c.log.WithFields(logrus.Fields{
 		"slot":      block.Slot(),
 		"blockHash": block.BlockHash(),
@@ -213,12 +349,14 @@ func (c *MultiBeaconClient) PublishBlock(block *common.SignedBeaconBlock) (code
 		log := log.WithField("uri", client.GetURI())
 		log.Debug("publishing block")
 
-		if code, err = client.PublishBlock(block); err != nil {
+		if code, err = client.PublishBlock(ctx, block); err != nil {
 			log.WithField("statusCode", code).WithError(err).Warn("failed to publish block")
+			common.BeaconPublishTotal.WithLabelValues("publish", "failure").Inc()
 			continue
 		}
 
 		log.WithField("statusCode", code).Info("published block")
+		common.BeaconPublishTotal.WithLabelValues("publish", "success").Inc()
 		return code, nil
 	}
 
@@ -226,6 +364,44 @@ func (c *MultiBeaconClient) PublishBlock(block *common.SignedBeaconBlock) (code
 	return code, err
 }
 
+// BroadcastBlock publishes the signed beacon block on all beacon nodes in parallel, and returns the number of nodes that accepted it
+func (c *MultiBeaconClient) BroadcastBlock(ctx context.Context, block *common.SignedBeaconBlock) (numPublished int, err error) {
+	log := c.log.WithFields(logrus.Fields{
+		"slot":      block.Slot(),
+		"blockHash": block.BlockHash(),
+	})
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, instance := range c.beaconInstances {
+		wg.Add(1)
+		go func(instance IBeaconInstance) {
+			defer wg.Done()
+			log := log.WithField("uri", instance.GetURI())
+			code, _err := instance.PublishBlock(ctx, block)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if _err != nil {
+				log.WithField("statusCode", code).WithError(_err).Warn("failed to broadcast block")
+				common.BeaconPublishTotal.WithLabelValues("broadcast", "failure").Inc()
+				err = _err
+				return
+			}
+			common.BeaconPublishTotal.WithLabelValues("broadcast", "success").Inc()
+			numPublished++
+			log.WithField("statusCode", code).Info("broadcasted block")
+		}(instance)
+	}
+	wg.Wait()
+
+	if numPublished > 0 {
+		return numPublished, nil
+	}
+	log.WithError(err).Error("failed to broadcast block on any CL node")
+	return 0, err
+}
+
 // GetGenesis returns the genesis info - https://ethereum.github.io/beacon-APIs/#/Beacon/getGenesis
 func (c *MultiBeaconClient) GetGenesis() (genesisInfo *GetGenesisResponse, err error) {
 	clients := c.beaconInstancesByLastResponse()
@@ -277,6 +453,35 @@ func (c *MultiBeaconClient) GetForkSchedule() (spec *GetForkScheduleResponse, er
 	return nil, err
 }
 
+// NodeForkSchedule is the fork schedule advertised by one beacon node, identified by its index in the configured nodes
+// rather than its URI, which may contain credentials
+type NodeForkSchedule struct {
+	Index    int
+	Schedule *GetForkScheduleResponse // nil if Err is set or the node has no schedule
+	Err      error
+}
+
+// GetForkSchedules returns the fork schedules of all beacon nodes, in the order they are configured, to detect nodes
+// which disagree about a fork
+func (c *MultiBeaconClient) GetForkSchedules() []NodeForkSchedule {
+	schedules := make([]NodeForkSchedule, len(c.beaconInstances))
+	var wg sync.WaitGroup
+	for i, instance := range c.beaconInstances {
+		wg.Add(1)
+		go func(i int, instance IBeaconInstance) {
+			defer wg.Done()
+			schedule, err := instance.GetForkSchedule()
+			if err != nil {
+				c.log.WithField("uri", instance.GetURI()).WithError(err).Warn("failed to get fork schedule")
+				schedule = nil
+			}
+			schedules[i] = NodeForkSchedule{Index: i, Schedule: schedule, Err: err}
+		}(i, instance)
+	}
+	wg.Wait()
+	return schedules
+}
+
 // GetBlock returns a block - https://ethereum.github.io/beacon-APIs/#/Beacon/getBlockV2
 func (c *MultiBeaconClient) GetBlock(blockID string) (block *GetBlockResponse, err error) {
 	clients := c.beaconInstancesByLastResponse()
@@ -294,8 +499,29 @@ func (c *MultiBeaconClient) GetBlock(blockID string) (block *GetBlockResponse, e
 	return nil, err
 }
 
+// GetBlockRoot returns the root of a block - https://ethereum.github.io/beacon-APIs/#/Beacon/getBlockRoot
+func (c *MultiBeaconClient) GetBlockRoot(blockID string) (root *GetBlockRootResponse, err error) {
+	clients := c.beaconInstancesByLastResponse()
+	for _, client := range clients {
+		log := c.log.WithField("uri", client.GetURI())
+		if root, err = client.GetBlockRoot(blockID); err != nil {
+			log.WithField("blockID", blockID).WithError(err).Warn("failed to get block root")
+			continue
+		}
+
+		return root, nil
+	}
+
+	c.log.WithField("blockID", blockID).WithError(err).Error("failed to get block root from any CL node")
+	return nil, err
+}
+
 // GetRandao - 3500/eth/v1/beacon/states/<slot>/randao
 func (c *MultiBeaconClient) GetRandao(slot uint64) (randaoResp *GetRandaoResponse, err error) {
+	if c.ffCrossCheckAttributes {
+		return c.crossCheckRandao(slot)
+	}
+
 	clients := c.beaconInstancesByLastResponse()
 	for _, client := range clients {
 		log := c.log.WithField("uri", client.GetURI())
@@ -313,12 +539,23 @@ func (c *MultiBeaconClient) GetRandao(slot uint64) (randaoResp *GetRandaoRespons
 
 // GetWithdrawals - 3500/eth/v1/beacon/states/<slot>/withdrawals
 func (c *MultiBeaconClient) GetWithdrawals(slot uint64) (withdrawalsResp *GetWithdrawalsResponse, err error) {
+	if c.ffCrossCheckAttributes {
+		withdrawalsResp, err = c.crossCheckWithdrawals(slot)
+		if err != nil && strings.Contains(err.Error(), "Withdrawals not enabled before capella") {
+			return nil, ErrWithdrawalsBeforeCapella
+		}
+		return withdrawalsResp, err
+	}
+
 	clients := c.beaconInstancesByLastResponse()
 	for _, client := range clients {
 		log := c.log.WithField("uri", client.GetURI())
 		if withdrawalsResp, err = client.GetWithdrawals(slot); err != nil {
 			if strings.Contains(err.Error(), "Withdrawals not enabled before capella") {
 				break
+			} else if errors.Is(err, ErrUnsupportedByBeaconClient) {
+				log.WithError(err).Debug("skipping beacon node for withdrawals")
+				continue
 			}
 			log.WithField("slot", slot).WithError(err).Warn("failed to get withdrawals")
 			continue
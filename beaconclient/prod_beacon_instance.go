@@ -1,9 +1,14 @@
 package beaconclient
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/attestantio/go-eth2-client/spec/capella"
@@ -13,9 +18,14 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// reconnect to the event stream if it's silent for this long, a connection dropped without being closed isn't noticed
+// otherwise. Some clients send no keep-alives, so a few missed slots can trigger a reconnect as well.
+var headEventStallTimeout = 3 * common.DurationPerSlot
+
 type ProdBeaconInstance struct {
 	log       *logrus.Entry
 	beaconURI string
+	detector  clientDetector
 }
 
 func NewProdBeaconInstance(log *logrus.Entry, beaconURI string) *ProdBeaconInstance {
@@ -23,7 +33,9 @@ func NewProdBeaconInstance(log *logrus.Entry, beaconURI string) *ProdBeaconInsta
 		"component": "beaconInstance",
 		"beaconURI": beaconURI,
 	})
-	return &ProdBeaconInstance{_log, beaconURI}
+	instance := &ProdBeaconInstance{log: _log, beaconURI: beaconURI}
+	go instance.detectClientType()
+	return instance
 }
 
 // HeadEventData represents the data of a head event
@@ -40,17 +52,30 @@ func (c *ProdBeaconInstance) SubscribeToHeadEvents(slotC chan HeadEventData) {
 	log.Info("subscribing to head events")
 
 	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		stall := time.AfterFunc(headEventStallTimeout, func() {
+			log.Warn("no head event received, reconnecting")
+			cancel()
+		})
+
 		client := sse.NewClient(eventsURL)
-		err := client.SubscribeRaw(func(msg *sse.Event) {
-			var data HeadEventData
-			err := json.Unmarshal(msg.Data, &data)
-			if err != nil {
+		client.ReconnectStrategy = noReconnect{} // reconnects are done here, to reset the stall timer
+		err := client.SubscribeRawWithContext(ctx, func(msg *sse.Event) {
+			stall.Reset(headEventStallTimeout)
+			data, ok := headEventData(msg)
+			if !ok {
+				return
+			}
+			var event HeadEventData
+			if err := json.Unmarshal(data, &event); err != nil {
 				log.WithError(err).Error("could not unmarshal head event")
 			} else {
-				slotC <- data
+				slotC <- event
 			}
 		})
-		if err != nil {
+		stall.Stop()
+		cancel()
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.WithError(err).Error("failed to subscribe to head events")
 			time.Sleep(1 * time.Second)
 		}
@@ -58,20 +83,74 @@ func (c *ProdBeaconInstance) SubscribeToHeadEvents(slotC chan HeadEventData) {
 	}
 }
 
+// noReconnect is the reconnect strategy of the SSE client which never retries
+type noReconnect struct{}
+
+func (noReconnect) NextBackOff() time.Duration { return -1 } // backoff.Stop
+func (noReconnect) Reset()                     {}
+
+// headEventData returns the data of a head event. Keep-alives, which some clients send as events without data, and
+// the events of other topics are skipped.
+func headEventData(msg *sse.Event) (data []byte, ok bool) {
+	if len(bytes.TrimSpace(msg.Data)) == 0 {
+		return nil, false
+	} else if len(msg.Event) > 0 && string(msg.Event) != "head" {
+		return nil, false
+	}
+	return msg.Data, true
+}
+
 func (c *ProdBeaconInstance) FetchValidators(headSlot uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error) {
-	vd, err := fetchAllValidators(c.beaconURI, headSlot)
+	statusFilter := !c.quirks().noValidatorStatusFilter
+	vd, err := fetchAllValidators(c.beaconURI, headSlot, statusFilter)
 	if err != nil {
 		return nil, err
 	}
 
 	newValidatorSet := make(map[types.PubkeyHex]ValidatorResponseEntry)
 	for _, vs := range vd.Data {
+		vs.Status = normalizeValidatorStatus(vs.Status)
+		if !statusFilter && !isActiveOrPendingStatus(vs.Status) {
+			continue
+		}
 		newValidatorSet[types.NewPubkeyHex(vs.Validator.Pubkey)] = vs
 	}
 
 	return newValidatorSet, nil
 }
 
+// number of validator indices requested at once by FetchValidatorsFromIndex, limited by the URL length
+const validatorsFromIndexBatchSize = 500
+
+// FetchValidatorsFromIndex requests the validators by index in batches, until an index doesn't exist yet
+func (c *ProdBeaconInstance) FetchValidatorsFromIndex(headSlot, fromIndex uint64) (map[types.PubkeyHex]ValidatorResponseEntry, error) {
+	newValidatorSet := make(map[types.PubkeyHex]ValidatorResponseEntry)
+	for batchStart := fromIndex; ; batchStart += validatorsFromIndexBatchSize {
+		ids := make([]string, validatorsFromIndexBatchSize)
+		for i := range ids {
+			ids[i] = strconv.FormatUint(batchStart+uint64(i), 10)
+		}
+
+		// https://ethereum.github.io/beacon-APIs/#/Beacon/getStateValidators
+		uri := fmt.Sprintf("%s/eth/v1/beacon/states/%d/validators?id=%s", c.beaconURI, headSlot, strings.Join(ids, ","))
+		vd := new(AllValidatorsResponse)
+		if _, err := fetchBeacon(http.MethodGet, uri, nil, vd); err != nil {
+			return nil, err
+		}
+
+		// filtered by status here instead of in the request, so that a short batch reliably means there are no more validators
+		for _, vs := range vd.Data {
+			vs.Status = normalizeValidatorStatus(vs.Status)
+			if isActiveOrPendingStatus(vs.Status) {
+				newValidatorSet[types.NewPubkeyHex(vs.Validator.Pubkey)] = vs
+			}
+		}
+		if len(vd.Data) < validatorsFromIndexBatchSize {
+			return newValidatorSet, nil
+		}
+	}
+}
+
 type ValidatorResponseEntry struct {
 	Index     uint64                         `json:"index,string"` // Index of validator in validator registry.
 	Balance   string                         `json:"balance"`      // Current validator balance in gwei.
@@ -87,8 +166,12 @@ type AllValidatorsResponse struct {
 	Data []ValidatorResponseEntry
 }
 
-func fetchAllValidators(endpoint string, headSlot uint64) (*AllValidatorsResponse, error) {
-	uri := fmt.Sprintf("%s/eth/v1/beacon/states/%d/validators?status=active,pending", endpoint, headSlot)
+// fetchAllValidators requests the active and pending validators, or all validators without the status filter
+func fetchAllValidators(endpoint string, headSlot uint64, statusFilter bool) (*AllValidatorsResponse, error) {
+	uri := fmt.Sprintf("%s/eth/v1/beacon/states/%d/validators", endpoint, headSlot)
+	if statusFilter {
+		uri += "?status=active,pending"
+	}
 	// https://ethereum.github.io/beacon-APIs/#/Beacon/getStateValidators
 	vd := new(AllValidatorsResponse)
 	_, err := fetchBeacon(http.MethodGet, uri, nil, vd)
@@ -176,7 +259,8 @@ func (c *ProdBeaconInstance) GetHeaderForSlot(slot uint64) (*GetHeaderResponse,
 }
 
 type GetBlockResponse struct {
-	Data struct {
+	Version string `json:"version"`
+	Data    struct {
 		Message struct {
 			Slot uint64 `json:"slot,string"`
 			Body struct {
@@ -196,6 +280,20 @@ func (c *ProdBeaconInstance) GetBlock(blockID string) (block *GetBlockResponse,
 }
 
 // GetBlockForSlot returns the block for a given slot - https://ethereum.github.io/beacon-APIs/#/Beacon/getBlockV2
+type GetBlockRootResponse struct {
+	Data struct {
+		Root string `json:"root"`
+	}
+}
+
+// GetBlockRoot returns the root of a block - https://ethereum.github.io/beacon-APIs/#/Beacon/getBlockRoot
+func (c *ProdBeaconInstance) GetBlockRoot(blockID string) (*GetBlockRootResponse, error) {
+	uri := fmt.Sprintf("%s/eth/v1/beacon/blocks/%s/root", c.beaconURI, blockID)
+	resp := new(GetBlockRootResponse)
+	_, err := fetchBeacon(http.MethodGet, uri, nil, resp)
+	return resp, err
+}
+
 func (c *ProdBeaconInstance) GetBlockForSlot(slot uint64) (*GetBlockResponse, error) {
 	uri := fmt.Sprintf("%s/eth/v2/beacon/blocks/%d", c.beaconURI, slot)
 	resp := new(GetBlockResponse)
@@ -207,9 +305,9 @@ func (c *ProdBeaconInstance) GetURI() string {
 	return c.beaconURI
 }
 
-func (c *ProdBeaconInstance) PublishBlock(block *common.SignedBeaconBlock) (code int, err error) {
+func (c *ProdBeaconInstance) PublishBlock(ctx context.Context, block *common.SignedBeaconBlock) (code int, err error) {
 	uri := fmt.Sprintf("%s/eth/v1/beacon/blocks", c.beaconURI)
-	return fetchBeacon(http.MethodPost, uri, block, nil)
+	return fetchBeaconWithContext(ctx, http.MethodPost, uri, block, nil)
 }
 
 type GetGenesisResponse struct {
@@ -235,6 +333,8 @@ type GetSpecResponse struct {
 	DomainAggregateAndProof         string `json:"DOMAIN_AGGREGATE_AND_PROOF"`         //nolint:tagliatelle
 	InactivityPenaltyQuotient       string `json:"INACTIVITY_PENALTY_QUOTIENT"`        //nolint:tagliatelle
 	InactivityPenaltyQuotientAltair string `json:"INACTIVITY_PENALTY_QUOTIENT_ALTAIR"` //nolint:tagliatelle
+	CapellaForkEpoch                uint64 `json:"CAPELLA_FORK_EPOCH,string"`          //nolint:tagliatelle
+	DenebForkEpoch                  uint64 `json:"DENEB_FORK_EPOCH,string"`            //nolint:tagliatelle
 }
 
 // GetSpec - https://ethereum.github.io/beacon-APIs/#/Config/getSpec
@@ -283,6 +383,9 @@ type GetWithdrawalsResponse struct {
 
 // GetWithdrawals - /eth/v1/beacon/states/<slot>/withdrawals
 func (c *ProdBeaconInstance) GetWithdrawals(slot uint64) (withdrawalsResp *GetWithdrawalsResponse, err error) {
+	if c.quirks().noWithdrawalsEndpoint {
+		return nil, fmt.Errorf("%w: withdrawals endpoint", ErrUnsupportedByBeaconClient)
+	}
 	uri := fmt.Sprintf("%s/eth/v1/beacon/states/%d/withdrawals", c.beaconURI, slot)
 	resp := new(GetWithdrawalsResponse)
 	_, err = fetchBeacon(http.MethodGet, uri, nil, resp)
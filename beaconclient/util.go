@@ -2,16 +2,24 @@ package beaconclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/flashbots/mev-boost-relay/common"
 )
 
 var ErrHTTPErrorResponse = errors.New("got an HTTP error response")
 
 func fetchBeacon(method, url string, payload, dst any) (code int, err error) {
+	return fetchBeaconWithContext(context.Background(), method, url, payload, dst)
+}
+
+// fetchBeaconWithContext propagates the trace context of ctx to the beacon node, the request isn't canceled with ctx
+func fetchBeaconWithContext(ctx context.Context, method, url string, payload, dst any) (code int, err error) {
 	var req *http.Request
 
 	if payload == nil {
@@ -31,6 +39,7 @@ func fetchBeacon(method, url string, payload, dst any) (code int, err error) {
 		return 0, fmt.Errorf("invalid request for %s: %w", url, err)
 	}
 	req.Header.Set("accept", "application/json")
+	common.InjectTraceContext(ctx, req.Header)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -1,39 +1,94 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/url"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/go-utils/cli"
 	"github.com/flashbots/mev-boost-relay/beaconclient"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/mev-boost-relay/database"
 	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/flashbots/mev-boost-relay/eventbus"
 	"github.com/flashbots/mev-boost-relay/services/api"
+	"github.com/flashbots/mev-boost-relay/webhook"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
+	ErrNetworkProfileWithoutNetwork = errors.New("network profile without network")
+	ErrNetworkProfilesShareDB       = errors.New("networks share the same db")
+
 	apiDefaultListenAddr = common.GetEnv("LISTEN_ADDR", "localhost:9062")
-	apiDefaultBlockSim   = common.GetEnv("BLOCKSIM_URI", "http://localhost:8545")
+	apiDefaultBlockSim   = common.GetSliceEnv("BLOCKSIM_URI", []string{"http://localhost:8545"})
+	apiDefaultBlockSimHP = common.GetEnv("BLOCKSIM_URI_HIGHPRIO", "")
 	apiDefaultSecretKey  = common.GetEnv("SECRET_KEY", "")
 	apiDefaultLogTag     = os.Getenv("LOG_TAG")
 
+	apiDefaultReadTimeout               = time.Duration(cli.GetEnvInt("API_TIMEOUT_READ_MS", int(api.DefaultReadTimeout.Milliseconds()))) * time.Millisecond
+	apiDefaultReadTimeoutRegistrations  = time.Duration(cli.GetEnvInt("API_TIMEOUT_READ_REGISTRATIONS_MS", int(api.DefaultReadTimeoutRegistrations.Milliseconds()))) * time.Millisecond
+	apiDefaultReadHeaderTimeout         = time.Duration(cli.GetEnvInt("API_TIMEOUT_READHEADER_MS", int(api.DefaultReadHeaderTimeout.Milliseconds()))) * time.Millisecond
+	apiDefaultWriteTimeout              = time.Duration(cli.GetEnvInt("API_TIMEOUT_WRITE_MS", int(api.DefaultWriteTimeout.Milliseconds()))) * time.Millisecond
+	apiDefaultIdleTimeout               = time.Duration(cli.GetEnvInt("API_TIMEOUT_IDLE_MS", int(api.DefaultIdleTimeout.Milliseconds()))) * time.Millisecond
+	apiDefaultShutdownTimeout           = time.Duration(cli.GetEnvInt("API_SHUTDOWN_TIMEOUT_MS", int(api.DefaultShutdownTimeout.Milliseconds()))) * time.Millisecond
+	apiDefaultMaxHeaderBytes            = cli.GetEnvInt("API_MAX_HEADER_BYTES", api.DefaultMaxHeaderBytes)
+	apiDefaultMaxBodyBytes              = int64(cli.GetEnvInt("API_MAX_BODY_BYTES", int(api.DefaultMaxBodyBytes)))
+	apiDefaultMaxBodyBytesRegistrations = int64(cli.GetEnvInt("API_MAX_BODY_BYTES_REGISTRATIONS", int(api.DefaultMaxBodyBytesRegistrations)))
+	apiDefaultMaxBodyBytesSubmissions   = int64(cli.GetEnvInt("API_MAX_BODY_BYTES_SUBMISSIONS", int(api.DefaultMaxBodyBytesSubmissions)))
+
 	apiDefaultPprofEnabled       = os.Getenv("PPROF") == "1"
 	apiDefaultInternalAPIEnabled = os.Getenv("ENABLE_INTERNAL_API") == "1"
+	apiDefaultAdminListenAddr    = os.Getenv("ADMIN_LISTEN_ADDR")
+	apiDefaultAdminToken         = os.Getenv("ADMIN_API_TOKEN")
+	apiDefaultAdminOpTokens      = common.GetSliceEnv("ADMIN_API_OPERATOR_TOKENS", nil)
+	apiDefaultTLSCertFile        = os.Getenv("TLS_CERT_FILE")
+	apiDefaultTLSKeyFile         = os.Getenv("TLS_KEY_FILE")
+	apiDefaultTLSClientCAFile    = os.Getenv("TLS_CLIENT_CA_FILE")
+
+	apiEventBusURL           = os.Getenv("EVENT_BUS_URL")
+	apiEventBusSubjects      = os.Getenv("EVENT_BUS_SUBJECTS")
+	apiEventBusSubjectPrefix = common.GetEnv("EVENT_BUS_SUBJECT_PREFIX", "mev-boost-relay")
+	apiEventBusQueueSize     = cli.GetEnvInt("EVENT_BUS_QUEUE_SIZE", 10_000)
+	apiEventBusOpts          = eventbus.Opts{
+		CAFile:              os.Getenv("EVENT_BUS_TLS_CA_FILE"),
+		CertFile:            os.Getenv("EVENT_BUS_TLS_CERT_FILE"),
+		KeyFile:             os.Getenv("EVENT_BUS_TLS_KEY_FILE"),
+		NATSCredentialsFile: os.Getenv("EVENT_BUS_NATS_CREDS_FILE"),
+	}
+
+	apiWebhookURLs          = os.Getenv("WEBHOOK_URLS")
+	apiWebhookSecret        = os.Getenv("WEBHOOK_SECRET")
+	apiWebhookEvents        = common.GetSliceEnv("WEBHOOK_EVENTS", nil)
+	apiWebhookTimeout       = time.Duration(cli.GetEnvInt("WEBHOOK_TIMEOUT_MS", 5000)) * time.Millisecond
+	apiWebhookMaxRetries    = cli.GetEnvInt("WEBHOOK_MAX_RETRIES", 3)
+	apiWebhookRetryInterval = time.Duration(cli.GetEnvInt("WEBHOOK_RETRY_INTERVAL_MS", 1000)) * time.Millisecond
+
+	apiListenAddr    string
+	apiPprofEnabled  bool
+	apiSecretKey     string
+	apiBlockSimURLs  []string
+	apiBlockSimHPURL string
+	apiDebug         bool
+	apiInternalAPI   bool
+	apiAdminAddr     string
+	apiAdminToken    string
+	apiAdminOpTokens []string
+	apiLogTag        string
+	apiHTTPServer    api.HTTPServerOpts
+	apiTLS           api.TLSOpts
 
-	apiListenAddr   string
-	apiPprofEnabled bool
-	apiSecretKey    string
-	apiBlockSimURL  string
-	apiDebug        bool
-	apiInternalAPI  bool
-	apiLogTag       string
+	// the networks served by the api command, set by the "networks" list of the config file
+	apiNetworkProfiles []apiNetworkProfile
 )
 
 func init() {
@@ -48,11 +103,31 @@ func init() {
 	apiCmd.Flags().StringVar(&redisURI, "redis-uri", defaultRedisURI, "redis uri")
 	apiCmd.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
 	apiCmd.Flags().StringVar(&apiSecretKey, "secret-key", apiDefaultSecretKey, "secret key for signing bids")
-	apiCmd.Flags().StringVar(&apiBlockSimURL, "blocksim", apiDefaultBlockSim, "URL for block simulator")
-	apiCmd.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use")
+	apiCmd.Flags().StringSliceVar(&apiBlockSimURLs, "blocksim", apiDefaultBlockSim, "URLs for block simulators (requests are balanced across healthy ones)")
+	apiCmd.Flags().StringVar(&apiBlockSimHPURL, "blocksim-highprio", apiDefaultBlockSimHP, "URL for a block simulator dedicated to high-prio builders (optional)")
+	apiCmd.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use: a built-in network, the path of a network config file, or the URL of a beacon node to load the config from")
+	apiCmd.Flags().StringVar(&metricsAddr, "metrics-addr", defaultMetricsAddr, "listen address for the prometheus metrics (/metrics), disabled if empty")
+
+	apiCmd.Flags().DurationVar(&apiHTTPServer.ReadTimeout, "http-read-timeout", apiDefaultReadTimeout, "maximum duration for reading a request, including the body")
+	apiCmd.Flags().DurationVar(&apiHTTPServer.ReadTimeoutRegistrations, "http-read-timeout-registrations", apiDefaultReadTimeoutRegistrations, "maximum duration for reading a validator registration request, including the body")
+	apiCmd.Flags().DurationVar(&apiHTTPServer.ReadHeaderTimeout, "http-read-header-timeout", apiDefaultReadHeaderTimeout, "maximum duration for reading the request headers")
+	apiCmd.Flags().DurationVar(&apiHTTPServer.WriteTimeout, "http-write-timeout", apiDefaultWriteTimeout, "maximum duration for writing a response")
+	apiCmd.Flags().DurationVar(&apiHTTPServer.IdleTimeout, "http-idle-timeout", apiDefaultIdleTimeout, "maximum duration to wait for the next request on a keep-alive connection")
+	apiCmd.Flags().DurationVar(&apiHTTPServer.ShutdownTimeout, "shutdown-timeout", apiDefaultShutdownTimeout, "maximum duration for draining the requests in flight, block publishing and pending database writes on shutdown")
+	apiCmd.Flags().IntVar(&apiHTTPServer.MaxHeaderBytes, "http-max-header-bytes", apiDefaultMaxHeaderBytes, "maximum size of the request headers")
+	apiCmd.Flags().Int64Var(&apiHTTPServer.MaxBodyBytes, "http-max-body-bytes", apiDefaultMaxBodyBytes, "maximum request body size, unless set per endpoint below")
+	apiCmd.Flags().Int64Var(&apiHTTPServer.MaxBodyBytesRegistrations, "http-max-body-bytes-registrations", apiDefaultMaxBodyBytesRegistrations, "maximum body size of validator registration requests")
+	apiCmd.Flags().Int64Var(&apiHTTPServer.MaxBodyBytesSubmissions, "http-max-body-bytes-submissions", apiDefaultMaxBodyBytesSubmissions, "maximum body size of block and header submissions")
 
 	apiCmd.Flags().BoolVar(&apiPprofEnabled, "pprof", apiDefaultPprofEnabled, "enable pprof API")
 	apiCmd.Flags().BoolVar(&apiInternalAPI, "internal-api", apiDefaultInternalAPIEnabled, "enable internal API (/internal/...)")
+	apiCmd.Flags().StringVar(&apiAdminAddr, "admin-listen-addr", apiDefaultAdminListenAddr, "listen address for the admin API (/admin/...), disabled if empty")
+	apiCmd.Flags().StringVar(&apiAdminToken, "admin-token", apiDefaultAdminToken, "bearer token required for the admin API")
+	apiCmd.Flags().StringSliceVar(&apiAdminOpTokens, "admin-operator-tokens", apiDefaultAdminOpTokens, "bearer tokens of named admin operators (name:token), recorded with their changes in the admin audit log")
+
+	apiCmd.Flags().StringVar(&apiTLS.CertFile, "tls-cert", apiDefaultTLSCertFile, "TLS certificate file, serves the API and admin API over TLS if set")
+	apiCmd.Flags().StringVar(&apiTLS.KeyFile, "tls-key", apiDefaultTLSKeyFile, "TLS private key file")
+	apiCmd.Flags().StringVar(&apiTLS.ClientCAFile, "tls-client-ca", apiDefaultTLSClientCAFile, "CA certificates of the builder client certificates (mTLS), whose common name is the builder pubkey")
 }
 
 var apiCmd = &cobra.Command{
@@ -73,85 +148,75 @@ var apiCmd = &cobra.Command{
 			log = log.WithField("tag", apiLogTag)
 		}
 		log.Infof("boost-relay %s", Version)
+		startMetricsServer(log)
 
-		networkInfo, err := common.NewEthNetworkDetails(network)
+		shutdownTracing, err := common.InitTracing(context.Background(), "relay/api")
 		if err != nil {
-			log.WithError(err).Fatalf("error getting network details")
-		}
-		log.Infof("Using network: %s", networkInfo.Name)
-
-		// Connect to beacon clients and ensure it's synced
-		if len(beaconNodeURIs) == 0 {
-			log.Fatalf("no beacon endpoints specified")
+			log.WithError(err).Fatal("failed to set up tracing")
 		}
-		log.Infof("Using beacon endpoints: %s", strings.Join(beaconNodeURIs, ", "))
-		var beaconInstances []beaconclient.IBeaconInstance
-		for _, uri := range beaconNodeURIs {
-			beaconInstances = append(beaconInstances, beaconclient.NewProdBeaconInstance(log, uri))
-		}
-		beaconClient := beaconclient.NewMultiBeaconClient(log, beaconInstances)
 
-		// Connect to Redis
-		redis, err := datastore.NewRedisCache(redisURI, networkInfo.Name)
+		profiles, err := resolveNetworkProfiles(apiNetworkProfiles)
 		if err != nil {
-			log.WithError(err).Fatalf("Failed to connect to Redis at %s", redisURI)
+			log.WithError(err).Fatal("invalid networks")
 		}
-		log.Infof("Connected to Redis at %s", redisURI)
 
-		// Connect to Postgres
-		dbURL, err := url.Parse(postgresDSN)
-		if err != nil {
-			log.WithError(err).Fatalf("couldn't read db URL")
-		}
-		log.Infof("Connecting to Postgres database at %s%s ...", dbURL.Host, dbURL.Path)
-		db, err := database.NewDatabaseService(postgresDSN)
-		if err != nil {
-			log.WithError(err).Fatalf("Failed to connect to Postgres database at %s%s", dbURL.Host, dbURL.Path)
+		// Set up the webhook notifications, shared by the networks
+		var notifier *webhook.Notifier
+		if apiWebhookURLs != "" {
+			urls, err := webhook.ParseURLs(apiWebhookURLs)
+			if err != nil {
+				log.WithError(err).Fatal("invalid WEBHOOK_URLS")
+			}
+			notifier, err = webhook.NewNotifier(log, webhook.Opts{
+				URLs:          urls,
+				Secret:        apiWebhookSecret,
+				Events:        apiWebhookEvents,
+				Timeout:       apiWebhookTimeout,
+				MaxRetries:    apiWebhookMaxRetries,
+				RetryInterval: apiWebhookRetryInterval,
+			})
+			if err != nil {
+				log.WithError(err).Fatal("invalid WEBHOOK_EVENTS")
+			}
+			log.Infof("Notifying %d webhooks of operational events", len(urls))
 		}
 
-		log.Info("Setting up datastore...")
-		ds, err := datastore.NewDatastore(log, redis, db)
-		if err != nil {
-			log.WithError(err).Fatalf("Failed setting up prod datastore")
-		}
-
-		opts := api.RelayAPIOpts{
-			Log:           log,
-			ListenAddr:    apiListenAddr,
-			BeaconClient:  beaconClient,
-			Datastore:     ds,
-			Redis:         redis,
-			DB:            db,
-			EthNetDetails: *networkInfo,
-			BlockSimURL:   apiBlockSimURL,
-
-			ProposerAPI:     true,
-			BlockBuilderAPI: true,
-			DataAPI:         true,
-			InternalAPI:     apiInternalAPI,
-			PprofAPI:        apiPprofEnabled,
-		}
-
-		// Decode the private key
-		if apiSecretKey == "" {
-			log.Warn("No secret key specified, block builder API is disabled")
-			opts.BlockBuilderAPI = false
-		} else {
-			envSkBytes, err := hexutil.Decode(apiSecretKey)
-			if err != nil {
-				log.WithError(err).Fatal("incorrect secret key provided")
+		var relays []*api.RelayAPI
+		var eventBuses []*eventbus.EventBus
+		for _, profile := range profiles {
+			networkLog := log
+			if len(apiNetworkProfiles) > 0 {
+				networkLog = log.WithField("network", profile.Network)
+			}
+			opts := newRelayAPIOpts(networkLog, profile)
+			if notifier != nil {
+				opts.Webhooks = notifier.WithNetwork(opts.EthNetDetails.Name)
+			}
+			opts.EventBus = newEventBus(networkLog, opts.EthNetDetails.Name, len(apiNetworkProfiles) > 0)
+			if opts.EventBus != nil {
+				eventBuses = append(eventBuses, opts.EventBus)
 			}
-			opts.SecretKey, err = bls.SecretKeyFromBytes(envSkBytes[:])
+
+			// Create the relay service
+			networkLog.Info("Setting up relay service...")
+			relay, err := api.NewRelayAPI(opts)
 			if err != nil {
-				log.WithError(err).Fatal("incorrect builder API secret key provided")
+				networkLog.WithError(err).Fatal("failed to create service")
 			}
+			relays = append(relays, relay)
 		}
 
-		// Create the relay service
-		log.Info("Setting up relay service...")
-		srv, err := api.NewRelayAPI(opts)
-		if err != nil {
-			log.WithError(err).Fatal("failed to create service")
+		var srv apiServer = relays[0]
+		if len(apiNetworkProfiles) > 0 {
+			srv, err = api.NewMultiNetworkAPI(api.MultiNetworkAPIOpts{
+				Log:        log,
+				ListenAddr: apiListenAddr,
+				HTTPServer: apiHTTPServer,
+				TLS:        apiTLS,
+			}, relays)
+			if err != nil {
+				log.WithError(err).Fatal("failed to create service")
+			}
 		}
 
 		// Create a signal handler
@@ -162,7 +227,18 @@ var apiCmd = &cobra.Command{
 			log.Infof("signal received: %s", sig)
 			err := srv.StopServer()
 			if err != nil {
-				log.WithError(err).Fatal("error stopping server")
+				log.WithError(err).Error("error stopping server")
+			}
+		}()
+
+		// Reload the builder statuses from the database on SIGHUP
+		reloadSigs := make(chan os.Signal, 1)
+		signal.Notify(reloadSigs, syscall.SIGHUP)
+		go func() {
+			for range reloadSigs {
+				if err := srv.ReloadBuilderStatuses(); err != nil {
+					log.WithError(err).Error("failed to reload builder statuses")
+				}
 			}
 		}()
 
@@ -172,6 +248,200 @@ var apiCmd = &cobra.Command{
 		if err != nil {
 			log.WithError(err).Fatal("server error")
 		}
+		for _, eventBus := range eventBuses {
+			if err := eventBus.Close(apiHTTPServer.ShutdownTimeout); err != nil {
+				log.WithError(err).Error("failed to close the event bus")
+			}
+		}
+		if notifier != nil {
+			notifier.Wait(apiHTTPServer.ShutdownTimeout)
+		}
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.WithError(err).Error("failed to flush traces")
+		}
 		log.Info("bye")
 	},
 }
+
+// apiServer is the relay of a single network, or the server of the relays of several networks
+type apiServer interface {
+	StartServer() error
+	StopServer() error
+	ReloadBuilderStatuses() error
+}
+
+// apiNetworkProfile is one of the networks served by the api command, from the "networks" list of the config file. The
+// settings left out default to the ones of the api command, except the admin API which is disabled unless set.
+type apiNetworkProfile struct {
+	Network         string   `yaml:"network"`
+	BeaconURIs      []string `yaml:"beacon-uris"`
+	RedisURI        string   `yaml:"redis-uri"`
+	PostgresDSN     string   `yaml:"db"`
+	SecretKey       string   `yaml:"secret-key"`
+	BlockSimURLs    []string `yaml:"blocksim"`
+	BlockSimHPURL   string   `yaml:"blocksim-highprio"`
+	AdminListenAddr string   `yaml:"admin-listen-addr"`
+}
+
+// resolveNetworkProfiles applies the defaults to the network profiles. Without profiles, the single network of the
+// api command is served.
+func resolveNetworkProfiles(profiles []apiNetworkProfile) ([]apiNetworkProfile, error) {
+	if len(profiles) == 0 {
+		return []apiNetworkProfile{{
+			Network:         network,
+			BeaconURIs:      beaconNodeURIs,
+			RedisURI:        redisURI,
+			PostgresDSN:     postgresDSN,
+			SecretKey:       apiSecretKey,
+			BlockSimURLs:    apiBlockSimURLs,
+			BlockSimHPURL:   apiBlockSimHPURL,
+			AdminListenAddr: apiAdminAddr,
+		}}, nil
+	}
+
+	resolved := make([]apiNetworkProfile, 0, len(profiles))
+	networkOfDB := make(map[string]string)
+	for _, profile := range profiles {
+		if profile.Network == "" {
+			return nil, ErrNetworkProfileWithoutNetwork
+		}
+		if len(profile.BeaconURIs) == 0 {
+			profile.BeaconURIs = beaconNodeURIs
+		}
+		if profile.RedisURI == "" {
+			profile.RedisURI = redisURI
+		}
+		if profile.PostgresDSN == "" {
+			profile.PostgresDSN = postgresDSN
+		}
+		if profile.SecretKey == "" {
+			profile.SecretKey = apiSecretKey
+		}
+		if len(profile.BlockSimURLs) == 0 {
+			profile.BlockSimURLs = apiBlockSimURLs
+		}
+		if profile.BlockSimHPURL == "" {
+			profile.BlockSimHPURL = apiBlockSimHPURL
+		}
+
+		// the table names are the same for all networks, so each needs its own database or schema
+		if other, found := networkOfDB[profile.PostgresDSN]; found {
+			return nil, fmt.Errorf("%w: %s and %s", ErrNetworkProfilesShareDB, other, profile.Network)
+		}
+		networkOfDB[profile.PostgresDSN] = profile.Network
+		resolved = append(resolved, profile)
+	}
+	return resolved, nil
+}
+
+// newRelayAPIOpts connects to the beacon nodes, redis and the database of the network
+func newRelayAPIOpts(log *logrus.Entry, profile apiNetworkProfile) api.RelayAPIOpts {
+	networkInfo, err := common.NewEthNetworkDetails(profile.Network)
+	if err != nil {
+		log.WithError(err).Fatalf("error getting network details")
+	}
+	log.Infof("Using network: %s", networkInfo.Name)
+
+	// Connect to beacon clients and ensure it's synced
+	if len(profile.BeaconURIs) == 0 {
+		log.Fatalf("no beacon endpoints specified")
+	}
+	log.Infof("Using beacon endpoints: %s", strings.Join(profile.BeaconURIs, ", "))
+	var beaconInstances []beaconclient.IBeaconInstance
+	for _, uri := range profile.BeaconURIs {
+		beaconInstances = append(beaconInstances, beaconclient.NewProdBeaconInstance(log, uri))
+	}
+	beaconClient := beaconclient.NewMultiBeaconClient(log, beaconInstances)
+
+	// Connect to Redis, the keys are prefixed with the network name
+	redis, err := datastore.NewRedisCache(profile.RedisURI, networkInfo.Name)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to connect to Redis at %s", profile.RedisURI)
+	}
+	log.Infof("Connected to Redis at %s", profile.RedisURI)
+
+	// Connect to Postgres
+	dbURL, err := url.Parse(profile.PostgresDSN)
+	if err != nil {
+		log.WithError(err).Fatalf("couldn't read db URL")
+	}
+	log.Infof("Connecting to Postgres database at %s%s ...", dbURL.Host, dbURL.Path)
+	db, err := database.NewDatabaseService(profile.PostgresDSN)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to connect to Postgres database at %s%s", dbURL.Host, dbURL.Path)
+	}
+
+	log.Info("Setting up datastore...")
+	ds, err := datastore.NewDatastore(log, redis, db)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed setting up prod datastore")
+	}
+
+	opts := api.RelayAPIOpts{
+		Log:           log,
+		ListenAddr:    apiListenAddr,
+		HTTPServer:    apiHTTPServer,
+		BeaconClient:  beaconClient,
+		Datastore:     ds,
+		Redis:         redis,
+		DB:            db,
+		EthNetDetails: *networkInfo,
+
+		BlockSimURLs:        profile.BlockSimURLs,
+		BlockSimHighPrioURL: profile.BlockSimHPURL,
+
+		ProposerAPI:     true,
+		BlockBuilderAPI: true,
+		DataAPI:         true,
+		InternalAPI:     apiInternalAPI,
+		PprofAPI:        apiPprofEnabled,
+
+		AdminListenAddr:     profile.AdminListenAddr,
+		AdminToken:          apiAdminToken,
+		AdminOperatorTokens: apiAdminOpTokens,
+
+		TLS: apiTLS,
+	}
+
+	// Decode the private key
+	if profile.SecretKey == "" {
+		log.Warn("No secret key specified, block builder API is disabled")
+		opts.BlockBuilderAPI = false
+	} else {
+		envSkBytes, err := hexutil.Decode(profile.SecretKey)
+		if err != nil {
+			log.WithError(err).Fatal("incorrect secret key provided")
+		}
+		opts.SecretKey, err = bls.SecretKeyFromBytes(envSkBytes[:])
+		if err != nil {
+			log.WithError(err).Fatal("incorrect builder API secret key provided")
+		}
+	}
+	return opts
+}
+
+// newEventBus connects to the event bus, if configured. The networks of a multi-network API publish to their own
+// subjects, unless EVENT_BUS_SUBJECTS sets them explicitly.
+func newEventBus(log *logrus.Entry, networkName string, multiNetwork bool) *eventbus.EventBus {
+	if apiEventBusURL == "" {
+		return nil
+	}
+	subjectPrefix := apiEventBusSubjectPrefix
+	if multiNetwork {
+		subjectPrefix += "." + networkName
+	}
+	subjects := eventbus.DefaultSubjects(subjectPrefix)
+	if apiEventBusSubjects != "" {
+		var err error
+		subjects, err = eventbus.ParseSubjects(apiEventBusSubjects)
+		if err != nil {
+			log.WithError(err).Fatal("invalid EVENT_BUS_SUBJECTS")
+		}
+	}
+	publisher, err := eventbus.NewPublisher(log, apiEventBusURL, apiEventBusOpts)
+	if err != nil {
+		log.WithError(err).Fatal("failed to connect to the event bus")
+	}
+	log.Infof("Publishing events to the event bus: %v", subjects)
+	return eventbus.New(log, publisher, subjects, apiEventBusQueueSize)
+}
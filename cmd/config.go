@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownConfigSetting    = errors.New("unknown config setting")
+	ErrInvalidConfigValue      = errors.New("invalid config value")
+	ErrUnexpectedConfigMapping = errors.New("expected a value or list, got a mapping")
+
+	defaultConfigFile = common.GetEnv("CONFIG_FILE", "")
+
+	configFile string
+)
+
+// flagEnvVars are the environment variables the flag defaults are read from. They take precedence over the config file.
+var flagEnvVars = map[string]string{
+	"json":         "LOG_JSON",
+	"loglevel":     "LOG_LEVEL",
+	"log-tag":      "LOG_TAG",
+	"network":      "NETWORK",
+	"beacon-uris":  "BEACON_URIS",
+	"redis-uri":    "REDIS_URI",
+	"db":           "POSTGRES_DSN",
+	"metrics-addr": "METRICS_ADDR",
+	"listen-addr":  "LISTEN_ADDR",
+
+	"secret-key":                        "SECRET_KEY",
+	"blocksim":                          "BLOCKSIM_URI",
+	"blocksim-highprio":                 "BLOCKSIM_URI_HIGHPRIO",
+	"pprof":                             "PPROF",
+	"internal-api":                      "ENABLE_INTERNAL_API",
+	"admin-listen-addr":                 "ADMIN_LISTEN_ADDR",
+	"admin-token":                       "ADMIN_API_TOKEN",
+	"admin-operator-tokens":             "ADMIN_API_OPERATOR_TOKENS",
+	"http-read-timeout":                 "API_TIMEOUT_READ_MS",
+	"http-read-timeout-registrations":   "API_TIMEOUT_READ_REGISTRATIONS_MS",
+	"http-read-header-timeout":          "API_TIMEOUT_READHEADER_MS",
+	"http-write-timeout":                "API_TIMEOUT_WRITE_MS",
+	"http-idle-timeout":                 "API_TIMEOUT_IDLE_MS",
+	"shutdown-timeout":                  "API_SHUTDOWN_TIMEOUT_MS",
+	"http-max-header-bytes":             "API_MAX_HEADER_BYTES",
+	"http-max-body-bytes":               "API_MAX_BODY_BYTES",
+	"http-max-body-bytes-registrations": "API_MAX_BODY_BYTES_REGISTRATIONS",
+	"http-max-body-bytes-submissions":   "API_MAX_BODY_BYTES_SUBMISSIONS",
+
+	"known-validators-interval":           "HOUSEKEEPER_KNOWN_VALIDATORS_INTERVAL_SEC",
+	"known-validators-full-sync-interval": "HOUSEKEEPER_KNOWN_VALIDATORS_FULL_SYNC_SEC",
+	"builder-status-interval":             "HOUSEKEEPER_BUILDER_STATUS_INTERVAL_SEC",
+	"proposer-duties-interval":            "HOUSEKEEPER_PROPOSER_DUTIES_INTERVAL_SLOTS",
+	"redis-gc-interval":                   "HOUSEKEEPER_REDIS_GC_INTERVAL_SEC",
+	"blocklist-source":                    "HOUSEKEEPER_BLOCKLIST_SOURCE",
+	"blocklist-interval":                  "HOUSEKEEPER_BLOCKLIST_INTERVAL_SEC",
+	"proposer-allowlist-source":           "HOUSEKEEPER_PROPOSER_ALLOWLIST_SOURCE",
+	"proposer-allowlist-interval":         "HOUSEKEEPER_PROPOSER_ALLOWLIST_INTERVAL_SEC",
+	"builder-scoreboard-interval":         "HOUSEKEEPER_BUILDER_SCOREBOARD_INTERVAL_SEC",
+	"stats-views-interval":                "HOUSEKEEPER_STATS_VIEWS_INTERVAL_SEC",
+	"leader-election":                     "HOUSEKEEPER_LEADER_ELECTION",
+
+	"pubkey-override":     "PUBKEY_OVERRIDE",
+	"show-config-details": "SHOW_CONFIG_DETAILS",
+	"link-beaconchain":    "LINK_BEACONCHAIN",
+	"link-etherscan":      "LINK_ETHERSCAN",
+	"relay-url":           "RELAY_URL",
+}
+
+// serviceCommands are the services configured by the config file, each has its own section
+func serviceCommands() []*cobra.Command {
+	return []*cobra.Command{apiCmd, housekeeperCmd, websiteCmd, archiverCmd}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", defaultConfigFile, "YAML config file, settings are overridden by environment variables and command line flags")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if configFile == "" {
+			return nil
+		}
+		cfg, err := loadRelayConfig(configFile)
+		if err != nil {
+			return err
+		}
+		if err := cfg.apply(cmd); err != nil {
+			return err
+		}
+		apiNetworkProfiles = cfg.networks
+		return nil
+	}
+}
+
+// relayConfig is the content of the config file. The keys are the names of the command line flags: top-level settings
+// apply to all services which have the flag, the "api", "housekeeper", "website" and "archiver" sections override them
+// for a single service. The "networks" list makes the api serve several networks, see apiNetworkProfile.
+type relayConfig struct {
+	settings map[string]any
+	sections map[string]map[string]any
+	networks []apiNetworkProfile
+}
+
+func loadRelayConfig(path string) (*relayConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRelayConfig(data)
+}
+
+func parseRelayConfig(data []byte) (*relayConfig, error) {
+	raw := make(map[string]any)
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	cfg := &relayConfig{settings: make(map[string]any), sections: make(map[string]map[string]any)}
+	for key, value := range raw {
+		if key == "networks" {
+			networks, err := parseNetworkProfiles(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: networks: %s", ErrInvalidConfigValue, err.Error())
+			}
+			cfg.networks = networks
+			continue
+		}
+		if cmd := findServiceCommand(key); cmd != nil {
+			section, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: section %s must be a mapping", ErrInvalidConfigValue, key)
+			}
+			for name := range section {
+				if cmd.Flags().Lookup(name) == nil {
+					return nil, fmt.Errorf("%w: %s.%s", ErrUnknownConfigSetting, key, name)
+				}
+			}
+			cfg.sections[key] = section
+			continue
+		}
+
+		isKnown := false
+		for _, cmd := range serviceCommands() {
+			isKnown = isKnown || cmd.Flags().Lookup(key) != nil
+		}
+		if !isKnown {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownConfigSetting, key)
+		}
+		cfg.settings[key] = value
+	}
+	return cfg, nil
+}
+
+// parseNetworkProfiles decodes the "networks" list, rejecting unknown settings
+func parseNetworkProfiles(value any) ([]apiNetworkProfile, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var profiles []apiNetworkProfile
+	if err := decoder.Decode(&profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func findServiceCommand(name string) *cobra.Command {
+	for _, cmd := range serviceCommands() {
+		if cmd.Name() == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// settingsFor returns the top-level settings of the flags of cmd, merged with the section of cmd
+func (cfg *relayConfig) settingsFor(cmd *cobra.Command) map[string]any {
+	settings := make(map[string]any)
+	for name, value := range cfg.settings {
+		if cmd.Flags().Lookup(name) != nil {
+			settings[name] = value
+		}
+	}
+	for name, value := range cfg.sections[cmd.Name()] {
+		settings[name] = value
+	}
+	return settings
+}
+
+// apply sets the flags of cmd to the config values, unless they were set on the command line or via their environment
+// variable
+func (cfg *relayConfig) apply(cmd *cobra.Command) error {
+	settings := cfg.settingsFor(cmd)
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag.Changed {
+			continue
+		}
+		if envVar, found := flagEnvVars[name]; found {
+			if _, isSet := os.LookupEnv(envVar); isSet {
+				continue
+			}
+		}
+		if err := setFlagValue(flag, settings[name]); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfigValue, name, err.Error())
+		}
+	}
+	return nil
+}
+
+// setFlagValue sets the flag to a value from the config file. Lists replace the default of slice flags, instead of
+// being appended to it.
+func setFlagValue(flag *pflag.Flag, value any) error {
+	var values []string
+	switch v := value.(type) {
+	case map[string]any:
+		return ErrUnexpectedConfigMapping
+	case []any:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	case nil:
+		values = []string{}
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		return sliceValue.Replace(values)
+	}
+	return flag.Value.Set(strings.Join(values, ","))
+}
+
+// resetFlags restores the defaults of the flags. Needed when applying the config to several services in one process,
+// since they share the flag variables.
+func resetFlags(cmd *cobra.Command) (err error) {
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			values := []string{}
+			if defValue := strings.Trim(flag.DefValue, "[]"); defValue != "" {
+				values = strings.Split(defValue, ",")
+			}
+			err = errors.Join(err, sliceValue.Replace(values))
+		} else {
+			err = errors.Join(err, flag.Value.Set(flag.DefValue))
+		}
+	})
+	return err
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+network: sepolia
+redis-uri: redis.internal:6379
+beacon-uris:
+  - http://beacon-1:3500
+  - http://beacon-2:3500
+
+api:
+  listen-addr: 0.0.0.0:9062
+  http-read-timeout: 2s
+
+housekeeper:
+  redis-uri: redis-hk.internal:6379
+`
+
+func TestParseRelayConfig(t *testing.T) {
+	_, err := parseRelayConfig([]byte(testConfig))
+	require.NoError(t, err)
+
+	_, err = parseRelayConfig([]byte("unknown-setting: 1"))
+	require.ErrorIs(t, err, ErrUnknownConfigSetting)
+
+	// the listen address of the housekeeper isn't configurable
+	_, err = parseRelayConfig([]byte("housekeeper:\n  listen-addr: localhost:1234"))
+	require.ErrorIs(t, err, ErrUnknownConfigSetting)
+
+	_, err = parseRelayConfig([]byte("api: localhost:1234"))
+	require.ErrorIs(t, err, ErrInvalidConfigValue)
+}
+
+func TestApplyRelayConfig(t *testing.T) {
+	cfg, err := parseRelayConfig([]byte(testConfig))
+	require.NoError(t, err)
+	defer func() {
+		for _, cmd := range serviceCommands() {
+			require.NoError(t, resetFlags(cmd))
+		}
+	}()
+
+	t.Run("top-level settings and the section of the service are applied", func(t *testing.T) {
+		require.NoError(t, resetFlags(apiCmd))
+		require.NoError(t, cfg.apply(apiCmd))
+		require.Equal(t, "sepolia", network)
+		require.Equal(t, "redis.internal:6379", redisURI)
+		require.Equal(t, []string{"http://beacon-1:3500", "http://beacon-2:3500"}, beaconNodeURIs)
+		require.Equal(t, "0.0.0.0:9062", apiListenAddr)
+		require.Equal(t, 2*time.Second, apiHTTPServer.ReadTimeout)
+
+		require.NoError(t, resetFlags(housekeeperCmd))
+		require.NoError(t, cfg.apply(housekeeperCmd))
+		require.Equal(t, "redis-hk.internal:6379", redisURI)
+	})
+
+	t.Run("environment variables override the config file", func(t *testing.T) {
+		t.Setenv("NETWORK", "goerli")
+		require.NoError(t, resetFlags(apiCmd))
+		require.NoError(t, cfg.apply(apiCmd))
+		require.Equal(t, defaultNetwork, network)
+		require.Equal(t, "redis.internal:6379", redisURI)
+	})
+
+	t.Run("command line flags override the config file", func(t *testing.T) {
+		require.NoError(t, resetFlags(apiCmd))
+		require.NoError(t, apiCmd.Flags().Set("redis-uri", "localhost:6380"))
+		defer func() { apiCmd.Flags().Lookup("redis-uri").Changed = false }()
+
+		require.NoError(t, cfg.apply(apiCmd))
+		require.Equal(t, "localhost:6380", redisURI)
+	})
+}
+
+func TestValidateRelayConfig(t *testing.T) {
+	defer func() {
+		for _, cmd := range serviceCommands() {
+			require.NoError(t, resetFlags(cmd))
+		}
+	}()
+
+	cfg, err := parseRelayConfig([]byte("network: sepolia\ndb: postgres://localhost:5432/relay\n"))
+	require.NoError(t, err)
+	require.Empty(t, cfg.validate(false))
+
+	// the services have to agree on the network, redis and the database
+	cfg, err = parseRelayConfig([]byte(testConfig + "db: postgres://localhost:5432/relay\nwebsite:\n  network: goerli\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"housekeeper: redis-uri differs from api",
+		"website: network goerli differs from sepolia of api",
+	}, cfg.validate(false))
+
+	// the archiver is only checked once it has sinks, and only shares the database
+	cfg, err = parseRelayConfig([]byte("network: sepolia\ndb: postgres://localhost:5432/relay\narchiver:\n  sink: [file:///archive, bigquery://project]\n  stream: [registrations]\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"archiver: invalid sink: unsupported sink: bigquery",
+		"archiver: unknown stream: registrations",
+	}, cfg.validate(false))
+}
+
+func TestNetworkProfiles(t *testing.T) {
+	defer func() {
+		require.NoError(t, resetFlags(apiCmd))
+	}()
+
+	cfg, err := parseRelayConfig([]byte(testConfig + `
+networks:
+  - network: holesky
+    beacon-uris: [http://holesky-beacon:3500]
+    db: postgres://localhost:5432/relay?search_path=holesky
+  - network: sepolia
+    db: postgres://localhost:5432/relay?search_path=sepolia
+`))
+	require.NoError(t, err)
+	require.Len(t, cfg.networks, 2)
+
+	_, err = parseRelayConfig([]byte("networks:\n  - network: holesky\n    listen-addr: localhost:1234\n"))
+	require.ErrorIs(t, err, ErrInvalidConfigValue)
+
+	t.Run("settings left out default to the api flags", func(t *testing.T) {
+		require.NoError(t, resetFlags(apiCmd))
+		require.NoError(t, cfg.apply(apiCmd))
+		profiles, err := resolveNetworkProfiles(cfg.networks)
+		require.NoError(t, err)
+		require.Equal(t, []string{"http://holesky-beacon:3500"}, profiles[0].BeaconURIs)
+		require.Equal(t, []string{"http://beacon-1:3500", "http://beacon-2:3500"}, profiles[1].BeaconURIs)
+		require.Equal(t, "redis.internal:6379", profiles[1].RedisURI)
+		require.Equal(t, "", profiles[1].AdminListenAddr)
+	})
+
+	t.Run("networks need their own db", func(t *testing.T) {
+		_, err := resolveNetworkProfiles([]apiNetworkProfile{{Network: "holesky"}, {Network: "sepolia"}})
+		require.ErrorIs(t, err, ErrNetworkProfilesShareDB)
+
+		_, err = resolveNetworkProfiles([]apiNetworkProfile{{PostgresDSN: "postgres://localhost:5432/relay"}})
+		require.ErrorIs(t, err, ErrNetworkProfileWithoutNetwork)
+	})
+}
@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/flashbots/mev-boost-relay/services/archiver"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrNoConfigFile = errors.New("no config file given, use --config or CONFIG_FILE")
+
+	configValidateConnect bool
+)
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configValidateCmd.Flags().BoolVar(&configValidateConnect, "connect", false, "also connect to the beacon nodes, redis and the database, and check the beacon nodes are on the configured network")
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Error: please use a valid subcommand")
+		_ = cmd.Help()
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file of all services for consistency before starting them",
+	Run: func(cmd *cobra.Command, args []string) {
+		if configFile == "" {
+			fmt.Println(ErrNoConfigFile)
+			os.Exit(1)
+		}
+		cfg, err := loadRelayConfig(configFile)
+		if err != nil {
+			fmt.Printf("failed to load %s: %s\n", configFile, err)
+			os.Exit(1)
+		}
+
+		problems := cfg.validate(configValidateConnect)
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			fmt.Printf("%s: %d problem(s) found\n", configFile, len(problems))
+			os.Exit(1)
+		}
+		fmt.Printf("%s: OK\n", configFile)
+	},
+}
+
+// serviceConfig is the effective configuration of a service, with the environment variables applied
+type serviceConfig struct {
+	name        string
+	network     string
+	beaconURIs  []string
+	redisURI    string
+	postgresDSN string
+	listenAddr  string
+	metricsAddr string
+}
+
+// hasFlag returns whether the service has the flag, e.g. the archiver uses neither the network nor redis
+func (s serviceConfig) hasFlag(name string) bool {
+	return findServiceCommand(s.name).Flags().Lookup(name) != nil
+}
+
+// validate checks the effective config of every service, and that they are consistent with each other. Returns the
+// problems found.
+func (cfg *relayConfig) validate(connect bool) (problems []string) {
+	addProblem := func(service, format string, args ...any) {
+		problems = append(problems, service+": "+fmt.Sprintf(format, args...))
+	}
+
+	var services []serviceConfig
+	for _, cmd := range serviceCommands() {
+		name := cmd.Name()
+		if err := resetFlags(cmd); err != nil {
+			addProblem(name, "failed to reset the flags: %s", err)
+			continue
+		}
+		if err := cfg.apply(cmd); err != nil {
+			addProblem(name, "%s", err)
+			continue
+		}
+		if cmd == archiverCmd && len(archiverSinks) == 0 {
+			continue // the archiver is optional, it's only checked once it has sinks
+		}
+
+		service := serviceConfig{name: name, network: network, redisURI: redisURI, postgresDSN: postgresDSN, metricsAddr: metricsAddr} //nolint:exhaustruct
+		switch cmd {
+		case apiCmd:
+			service.beaconURIs = append([]string{}, beaconNodeURIs...)
+			service.listenAddr = apiListenAddr
+			for _, uri := range apiBlockSimURLs {
+				if err := validateURL(uri); err != nil {
+					addProblem(name, "invalid blocksim URL %s: %s", uri, err)
+				}
+			}
+			if apiBlockSimHPURL != "" {
+				if err := validateURL(apiBlockSimHPURL); err != nil {
+					addProblem(name, "invalid blocksim-highprio URL %s: %s", apiBlockSimHPURL, err)
+				}
+			}
+			if err := validateSecretKey(apiSecretKey); err != nil {
+				addProblem(name, "invalid secret-key: %s", err)
+			}
+			if _, err := resolveNetworkProfiles(cfg.networks); err != nil {
+				addProblem(name, "%s", err)
+			}
+			for _, profile := range cfg.networks {
+				if _, err := common.NewEthNetworkDetails(profile.Network); err != nil {
+					addProblem(name, "networks: %s", err)
+				}
+			}
+			if apiAdminAddr != "" {
+				if err := common.CheckListenAddr(apiAdminAddr); err != nil {
+					addProblem(name, "invalid admin-listen-addr: %s", err)
+				} else if apiAdminAddr == apiListenAddr {
+					addProblem(name, "admin-listen-addr is the same as listen-addr")
+				}
+				if apiAdminToken == "" && len(apiAdminOpTokens) == 0 {
+					addProblem(name, "admin-listen-addr requires an admin-token or admin-operator-tokens")
+				}
+			}
+		case housekeeperCmd:
+			service.beaconURIs = append([]string{}, beaconNodeURIs...)
+		case websiteCmd:
+			service.listenAddr = websiteListenAddr
+			if websiteRelayURL != "" {
+				if err := validateURL(websiteRelayURL); err != nil {
+					addProblem(name, "invalid relay-url %s: %s", websiteRelayURL, err)
+				}
+			}
+		case archiverCmd:
+			for _, uri := range archiverSinks {
+				if _, err := archiver.NewSink(uri, archiverSinkTimeout); err != nil {
+					addProblem(name, "invalid sink: %s", err)
+				}
+			}
+			if _, err := archiver.NewArchiver(&archiver.ArchiverOpts{Streams: archiverStreams}); err != nil { //nolint:exhaustruct
+				addProblem(name, "%s", err)
+			}
+		}
+		services = append(services, service)
+	}
+
+	for _, service := range services {
+		if service.hasFlag("network") {
+			if _, err := common.NewEthNetworkDetails(service.network); err != nil {
+				addProblem(service.name, "%s", err)
+			}
+		}
+		if cmd := findServiceCommand(service.name); cmd.Flags().Lookup("beacon-uris") != nil && len(service.beaconURIs) == 0 {
+			addProblem(service.name, "no beacon-uris")
+		}
+		for _, uri := range service.beaconURIs {
+			if err := validateURL(uri); err != nil {
+				addProblem(service.name, "invalid beacon URI %s: %s", uri, err)
+			}
+		}
+		if service.hasFlag("redis-uri") && service.redisURI == "" {
+			addProblem(service.name, "no redis-uri")
+		}
+		if service.postgresDSN == "" {
+			addProblem(service.name, "no db")
+		} else if _, err := url.Parse(service.postgresDSN); err != nil {
+			addProblem(service.name, "invalid db: %s", err)
+		}
+		if service.listenAddr != "" {
+			if err := common.CheckListenAddr(service.listenAddr); err != nil {
+				addProblem(service.name, "invalid listen-addr: %s", err)
+			}
+		}
+		if service.metricsAddr != "" {
+			if err := common.CheckListenAddr(service.metricsAddr); err != nil {
+				addProblem(service.name, "invalid metrics-addr: %s", err)
+			} else if service.metricsAddr == service.listenAddr {
+				addProblem(service.name, "metrics-addr is the same as listen-addr")
+			}
+		}
+	}
+
+	// the services share their state via redis and the database, so they have to agree on them and the network. The
+	// archiver only uses the database.
+	for i := 1; i < len(services); i++ {
+		service, first := services[i], services[0]
+		if service.hasFlag("network") && service.network != first.network {
+			addProblem(service.name, "network %s differs from %s of %s", service.network, first.network, first.name)
+		}
+		if service.hasFlag("redis-uri") && service.redisURI != first.redisURI {
+			addProblem(service.name, "redis-uri differs from %s", first.name)
+		}
+		if service.postgresDSN != first.postgresDSN {
+			addProblem(service.name, "db differs from %s", first.name)
+		}
+	}
+
+	if connect && len(problems) == 0 {
+		problems = append(problems, checkConnectivity(services)...)
+	}
+	return problems
+}
+
+// checkConnectivity connects to the beacon nodes, redis and the database of the services, and checks that the beacon
+// nodes are on the configured network
+func checkConnectivity(services []serviceConfig) (problems []string) {
+	log := common.LogSetup(logJSON, "error")
+	checked := make(map[string]bool)
+	for _, service := range services {
+		networkInfo, err := common.NewEthNetworkDetails(service.network)
+		if err != nil && service.hasFlag("network") {
+			problems = append(problems, fmt.Sprintf("%s: %s", service.name, err))
+			continue
+		}
+
+		for _, uri := range service.beaconURIs {
+			if checked[uri] {
+				continue
+			}
+			checked[uri] = true
+
+			genesis, err := beaconclient.NewProdBeaconInstance(log, uri).GetGenesis()
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: beacon node %s: %s", service.name, uri, err))
+			} else if !strings.EqualFold(genesis.Data.GenesisForkVersion, networkInfo.GenesisForkVersionHex) {
+				problems = append(problems, fmt.Sprintf("%s: beacon node %s has genesis fork version %s, expected %s for %s", service.name, uri, genesis.Data.GenesisForkVersion, networkInfo.GenesisForkVersionHex, service.network))
+			} else if !strings.EqualFold(genesis.Data.GenesisValidatorsRoot, networkInfo.GenesisValidatorsRootHex) {
+				problems = append(problems, fmt.Sprintf("%s: beacon node %s has genesis validators root %s, expected %s for %s", service.name, uri, genesis.Data.GenesisValidatorsRoot, networkInfo.GenesisValidatorsRootHex, service.network))
+			}
+		}
+
+		if service.hasFlag("redis-uri") && !checked[service.redisURI] {
+			checked[service.redisURI] = true
+			if _, err := datastore.NewRedisCache(service.redisURI, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: failed to connect to redis: %s", service.name, err))
+			}
+		}
+
+		if !checked[service.postgresDSN] {
+			checked[service.postgresDSN] = true
+			db, err := sqlx.Connect("postgres", service.postgresDSN)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: failed to connect to the database: %s", service.name, err))
+			} else {
+				_ = db.Close()
+			}
+		}
+	}
+	return problems
+}
+
+func validateURL(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrInvalidConfigValue)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: no host", ErrInvalidConfigValue)
+	}
+	return nil
+}
+
+// validateSecretKey checks the BLS secret key for signing bids, which is optional (without it the builder API is
+// disabled)
+func validateSecretKey(secretKey string) error {
+	if secretKey == "" {
+		return nil
+	}
+	skBytes, err := hexutil.Decode(secretKey)
+	if err != nil {
+		return err
+	}
+	_, err = bls.SecretKeyFromBytes(skBytes)
+	return err
+}
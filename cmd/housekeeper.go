@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"net/url"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/flashbots/go-utils/cli"
 	"github.com/flashbots/mev-boost-relay/beaconclient"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/mev-boost-relay/database"
@@ -12,6 +17,45 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	hkDefaultKnownValidatorsInterval     = time.Duration(cli.GetEnvInt("HOUSEKEEPER_KNOWN_VALIDATORS_INTERVAL_SEC", int(housekeeper.DefaultKnownValidatorsInterval.Seconds()))) * time.Second
+	hkDefaultKnownValidatorsFullSync     = time.Duration(cli.GetEnvInt("HOUSEKEEPER_KNOWN_VALIDATORS_FULL_SYNC_SEC", int(housekeeper.DefaultKnownValidatorsFullSync.Seconds()))) * time.Second
+	hkDefaultBuilderStatusInterval       = time.Duration(cli.GetEnvInt("HOUSEKEEPER_BUILDER_STATUS_INTERVAL_SEC", int(housekeeper.DefaultBuilderStatusInterval.Seconds()))) * time.Second
+	hkDefaultProposerDutiesIntervalSlots = uint64(cli.GetEnvInt("HOUSEKEEPER_PROPOSER_DUTIES_INTERVAL_SLOTS", int(housekeeper.DefaultProposerDutiesIntervalSlots)))
+	hkDefaultRedisGCInterval             = time.Duration(cli.GetEnvInt("HOUSEKEEPER_REDIS_GC_INTERVAL_SEC", int(housekeeper.DefaultRedisGCInterval.Seconds()))) * time.Second
+	hkDefaultBlocklistInterval           = time.Duration(cli.GetEnvInt("HOUSEKEEPER_BLOCKLIST_INTERVAL_SEC", int(housekeeper.DefaultBlocklistInterval.Seconds()))) * time.Second
+	hkDefaultProposerAllowlistInterval   = time.Duration(cli.GetEnvInt("HOUSEKEEPER_PROPOSER_ALLOWLIST_INTERVAL_SEC", int(housekeeper.DefaultProposerAllowlistInterval.Seconds()))) * time.Second
+	hkDefaultBuilderPromotionInterval    = time.Duration(cli.GetEnvInt("HOUSEKEEPER_BUILDER_PROMOTION_INTERVAL_SEC", int(housekeeper.DefaultBuilderPromotionInterval.Seconds()))) * time.Second
+	hkDefaultBuilderScoreboardInterval   = time.Duration(cli.GetEnvInt("HOUSEKEEPER_BUILDER_SCOREBOARD_INTERVAL_SEC", int(housekeeper.DefaultBuilderScoreboardInterval.Seconds()))) * time.Second
+	hkDefaultStatsViewsInterval          = time.Duration(cli.GetEnvInt("HOUSEKEEPER_STATS_VIEWS_INTERVAL_SEC", int(housekeeper.DefaultStatsViewsInterval.Seconds()))) * time.Second
+
+	hkDefaultLeaderElection  = os.Getenv("HOUSEKEEPER_LEADER_ELECTION") == "1"
+	hkDefaultBlocklistSource = os.Getenv("HOUSEKEEPER_BLOCKLIST_SOURCE")
+
+	hkDefaultProposerAllowlistSource = os.Getenv("HOUSEKEEPER_PROPOSER_ALLOWLIST_SOURCE")
+
+	hkDefaultPromotionMinSubmissions = uint64(cli.GetEnvInt("HOUSEKEEPER_PROMOTION_MIN_SUBMISSIONS", 0))
+	hkDefaultPromotionOnRefund       = os.Getenv("HOUSEKEEPER_PROMOTION_ON_REFUND") == "1"
+
+	hkKnownValidatorsInterval     time.Duration
+	hkKnownValidatorsFullSync     time.Duration
+	hkBuilderStatusInterval       time.Duration
+	hkProposerDutiesIntervalSlots uint64
+	hkRedisGCInterval             time.Duration
+	hkBlocklistInterval           time.Duration
+	hkBlocklistSource             string
+	hkProposerAllowlistInterval   time.Duration
+	hkProposerAllowlistSource     string
+	hkBuilderPromotionInterval    time.Duration
+	hkBuilderScoreboardInterval   time.Duration
+	hkStatsViewsInterval          time.Duration
+	hkPromotionMinSubmissions     uint64
+	hkPromotionOnRefund           bool
+	hkJitter                      float64
+	hkLeaderElection              bool
+	hkLeaderLockTTL               time.Duration
+)
+
 func init() {
 	rootCmd.AddCommand(housekeeperCmd)
 	housekeeperCmd.Flags().BoolVar(&logJSON, "json", defaultLogJSON, "log in JSON format instead of text")
@@ -21,7 +65,26 @@ func init() {
 	housekeeperCmd.Flags().StringVar(&redisURI, "redis-uri", defaultRedisURI, "redis uri")
 	housekeeperCmd.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
 
-	housekeeperCmd.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use")
+	housekeeperCmd.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use: a built-in network, the path of a network config file, or the URL of a beacon node to load the config from")
+	housekeeperCmd.Flags().StringVar(&metricsAddr, "metrics-addr", defaultMetricsAddr, "listen address for the prometheus metrics (/metrics), disabled if empty")
+
+	housekeeperCmd.Flags().DurationVar(&hkKnownValidatorsInterval, "known-validators-interval", hkDefaultKnownValidatorsInterval, "how often to fetch the known validators from the beacon node")
+	housekeeperCmd.Flags().DurationVar(&hkKnownValidatorsFullSync, "known-validators-full-sync-interval", hkDefaultKnownValidatorsFullSync, "how often to fetch all known validators, in between only new validators are fetched")
+	housekeeperCmd.Flags().DurationVar(&hkBuilderStatusInterval, "builder-status-interval", hkDefaultBuilderStatusInterval, "how often to sync the builder status from the database to redis")
+	housekeeperCmd.Flags().Uint64Var(&hkProposerDutiesIntervalSlots, "proposer-duties-interval", hkDefaultProposerDutiesIntervalSlots, "how often to update the proposer duties, in slots")
+	housekeeperCmd.Flags().DurationVar(&hkRedisGCInterval, "redis-gc-interval", hkDefaultRedisGCInterval, "how often to delete stale per-slot keys from redis")
+	housekeeperCmd.Flags().StringVar(&hkBlocklistSource, "blocklist-source", hkDefaultBlocklistSource, "file or http(s) URL of the address blocklist (JSON array, or one address per line), only loaded if set")
+	housekeeperCmd.Flags().DurationVar(&hkBlocklistInterval, "blocklist-interval", hkDefaultBlocklistInterval, "how often to reload the address blocklist")
+	housekeeperCmd.Flags().StringVar(&hkProposerAllowlistSource, "proposer-allowlist-source", hkDefaultProposerAllowlistSource, "file or http(s) URL of the allowed validator pubkeys (JSON array, or one pubkey per line), or 'db' for the proposer_allowlist table, only loaded if set")
+	housekeeperCmd.Flags().DurationVar(&hkProposerAllowlistInterval, "proposer-allowlist-interval", hkDefaultProposerAllowlistInterval, "how often to reload the proposer allowlist")
+	housekeeperCmd.Flags().Uint64Var(&hkPromotionMinSubmissions, "promotion-min-submissions", hkDefaultPromotionMinSubmissions, "re-enable optimistic mode for a demoted builder after this many successful non-optimistic submissions, 0 to disable")
+	housekeeperCmd.Flags().BoolVar(&hkPromotionOnRefund, "promotion-on-refund", hkDefaultPromotionOnRefund, "re-enable optimistic mode for a demoted builder once the refunds it owes are confirmed")
+	housekeeperCmd.Flags().DurationVar(&hkBuilderPromotionInterval, "builder-promotion-interval", hkDefaultBuilderPromotionInterval, "how often to apply the promotion policy to the demoted builders")
+	housekeeperCmd.Flags().DurationVar(&hkBuilderScoreboardInterval, "builder-scoreboard-interval", hkDefaultBuilderScoreboardInterval, "how often to aggregate the builder performance since the last aggregated day")
+	housekeeperCmd.Flags().DurationVar(&hkStatsViewsInterval, "stats-views-interval", hkDefaultStatsViewsInterval, "how often to refresh the materialized views of the data API and website stats")
+	housekeeperCmd.Flags().BoolVar(&hkLeaderElection, "leader-election", hkDefaultLeaderElection, "only run the jobs while holding the leader lock in redis, for running several instances")
+	housekeeperCmd.Flags().DurationVar(&hkLeaderLockTTL, "leader-lock-ttl", housekeeper.DefaultLeaderLockTTL, "how long the leader lock is valid without renewal, i.e. the maximum failover time")
+	housekeeperCmd.Flags().Float64Var(&hkJitter, "jitter", housekeeper.DefaultJitter, "extend the wait between periodic jobs by a random duration of up to this fraction of the interval")
 }
 
 var housekeeperCmd = &cobra.Command{
@@ -32,6 +95,7 @@ var housekeeperCmd = &cobra.Command{
 
 		log := common.LogSetup(logJSON, logLevel).WithField("service", "relay/housekeeper")
 		log.Infof("boost-relay %s", Version)
+		startMetricsServer(log)
 
 		networkInfo, err := common.NewEthNetworkDetails(network)
 		if err != nil {
@@ -72,10 +136,43 @@ var housekeeperCmd = &cobra.Command{
 			Redis:        redis,
 			DB:           db,
 			BeaconClient: beaconClient,
+
+			KnownValidatorsInterval:     hkKnownValidatorsInterval,
+			KnownValidatorsFullSync:     hkKnownValidatorsFullSync,
+			BuilderStatusInterval:       hkBuilderStatusInterval,
+			ProposerDutiesIntervalSlots: hkProposerDutiesIntervalSlots,
+			RedisGCInterval:             hkRedisGCInterval,
+			BlocklistInterval:           hkBlocklistInterval,
+			BlocklistSource:             hkBlocklistSource,
+			ProposerAllowlistInterval:   hkProposerAllowlistInterval,
+			ProposerAllowlistSource:     hkProposerAllowlistSource,
+			BuilderPromotionInterval:    hkBuilderPromotionInterval,
+			BuilderScoreboardInterval:   hkBuilderScoreboardInterval,
+			StatsViewsInterval:          hkStatsViewsInterval,
+			BuilderPromotionPolicy: housekeeper.BuilderPromotionPolicy{
+				MinSuccessfulSubmissions: hkPromotionMinSubmissions,
+				OnRefundConfirmed:        hkPromotionOnRefund,
+			},
+			Jitter:         hkJitter,
+			LeaderElection: hkLeaderElection,
+			LeaderLockTTL:  hkLeaderLockTTL,
 		}
 		service := housekeeper.NewHousekeeper(opts)
+
+		// Stop the jobs and hand over the leadership on shutdown
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-sigs
+			log.Infof("signal received: %s", sig)
+			service.Stop()
+		}()
+
 		log.Info("Starting housekeeper service...")
 		err = service.Start()
-		log.WithError(err).Fatalf("Failed to start housekeeper")
+		if err != nil {
+			log.WithError(err).Fatalf("Failed to start housekeeper")
+		}
+		log.Info("bye")
 	},
 }
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// startMetricsServer serves the prometheus metrics in the background, if a listen address is set
+func startMetricsServer(log *logrus.Entry) {
+	if metricsAddr == "" {
+		return
+	}
+	go func() {
+		err := common.StartMetricsServer(log, metricsAddr)
+		log.WithError(err).Fatal("metrics server failed")
+	}()
+}
@@ -12,6 +12,13 @@ func init() {
 	toolCmd.AddCommand(tool.DataAPIExportBids)
 	toolCmd.AddCommand(tool.ArchiveExecutionPayloads)
 	toolCmd.AddCommand(tool.Migrate)
+	toolCmd.AddCommand(tool.Backfill)
+	toolCmd.AddCommand(tool.ExportValidatorRegistrations)
+	toolCmd.AddCommand(tool.ImportValidatorRegistrations)
+	toolCmd.AddCommand(tool.Loadtest)
+	toolCmd.AddCommand(tool.VerifyPayloads)
+	toolCmd.AddCommand(tool.RedisMigrate)
+	toolCmd.AddCommand(tool.ReplayRequests)
 	rootCmd.AddCommand(toolCmd)
 }
 
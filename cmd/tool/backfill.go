@@ -0,0 +1,204 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	backfillTypePayloadDelivered     = "payload_delivered"
+	backfillTypeBuilderBlockReceived = "builder_block_received"
+
+	// maximum page sizes of the data API spec
+	backfillLimitPayloadDelivered     = 200
+	backfillLimitBuilderBlockReceived = 500
+)
+
+var (
+	backfillSourceURL    string
+	backfillSlotFrom     uint64
+	backfillSlotTo       uint64
+	backfillTypes        []string
+	backfillRequestDelay time.Duration
+)
+
+func init() {
+	Backfill.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
+	Backfill.Flags().StringVar(&backfillSourceURL, "source-url", "", "URL of the relay to import from, e.g. https://boost-relay.flashbots.net")
+	Backfill.Flags().Uint64Var(&backfillSlotFrom, "slot-from", 0, "first slot to import (inclusive)")
+	Backfill.Flags().Uint64Var(&backfillSlotTo, "slot-to", 0, "last slot to import (inclusive)")
+	Backfill.Flags().StringSliceVar(&backfillTypes, "type", []string{backfillTypePayloadDelivered, backfillTypeBuilderBlockReceived}, "what to import: payload_delivered, builder_block_received")
+	Backfill.Flags().DurationVar(&backfillRequestDelay, "request-delay", 100*time.Millisecond, "delay between the requests to the source relay, to stay below its rate limits")
+	_ = Backfill.MarkFlagRequired("source-url")
+	_ = Backfill.MarkFlagRequired("slot-to")
+}
+
+var Backfill = &cobra.Command{
+	Use:   "backfill",
+	Short: "import the delivered payloads and received bids of a slot range from the data API of another relay",
+	Run: func(cmd *cobra.Command, args []string) {
+		if backfillSlotFrom > backfillSlotTo {
+			log.Fatal("--slot-from must not be after --slot-to")
+		}
+		for _, backfillType := range backfillTypes {
+			if backfillType != backfillTypePayloadDelivered && backfillType != backfillTypeBuilderBlockReceived {
+				log.Fatalf("invalid type: %s", backfillType)
+			}
+		}
+		sourceURL, err := url.Parse(strings.TrimSuffix(backfillSourceURL, "/"))
+		if err != nil || sourceURL.Host == "" {
+			log.Fatalf("invalid source URL: %s", backfillSourceURL)
+		}
+
+		// Connect to Postgres
+		dbURL, err := url.Parse(postgresDSN)
+		if err != nil {
+			log.WithError(err).Fatalf("couldn't read db URL")
+		}
+		log.Infof("Connecting to Postgres database at %s%s ...", dbURL.Host, dbURL.Path)
+		db, err := database.NewDatabaseService(postgresDSN)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed to connect to Postgres database at %s%s", dbURL.Host, dbURL.Path)
+		}
+
+		b := &backfiller{
+			client:    &http.Client{Timeout: 30 * time.Second},
+			sourceURL: sourceURL.String(),
+			db:        db,
+		}
+		log.Infof("importing slots %d to %d from %s", backfillSlotFrom, backfillSlotTo, b.sourceURL)
+		for _, backfillType := range backfillTypes {
+			switch backfillType {
+			case backfillTypePayloadDelivered:
+				err = b.importDeliveredPayloads(backfillSlotFrom, backfillSlotTo)
+			case backfillTypeBuilderBlockReceived:
+				err = b.importBuilderSubmissions(backfillSlotFrom, backfillSlotTo)
+			}
+			if err != nil {
+				log.WithError(err).Fatalf("failed to import %s", backfillType)
+			}
+		}
+	},
+}
+
+type backfiller struct {
+	client    *http.Client
+	sourceURL string
+	db        *database.DatabaseService
+}
+
+// importDeliveredPayloads pages backwards through the delivered payloads, with the slot as cursor
+func (b *backfiller) importDeliveredPayloads(slotFrom, slotTo uint64) error {
+	numImported, numExisting := 0, 0
+	cursor := slotTo
+	for {
+		entries := []*common.BidTraceV2JSON{}
+		query := fmt.Sprintf("?cursor=%d&limit=%d", cursor, backfillLimitPayloadDelivered)
+		if err := b.get("/relay/v1/data/bidtraces/proposer_payload_delivered"+query, &entries); err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry.Slot < slotFrom || entry.Slot > slotTo {
+				continue
+			}
+			inserted, err := b.db.ImportDeliveredPayload(database.BidTraceV2JSONToDeliveredPayloadEntry(entry))
+			if err != nil {
+				return fmt.Errorf("slot %d: %w", entry.Slot, err)
+			}
+			if inserted {
+				numImported++
+			} else {
+				numExisting++
+			}
+		}
+
+		if len(entries) == 0 {
+			break
+		}
+		lastSlot := entries[len(entries)-1].Slot
+		log.WithField("imported", numImported).Infof("imported delivered payloads down to slot %d", lastSlot)
+		if lastSlot <= slotFrom || lastSlot > cursor {
+			break
+		}
+		cursor = lastSlot - 1
+	}
+
+	log.WithFields(logrus.Fields{
+		"imported":        numImported,
+		"alreadyExisting": numExisting,
+	}).Info("delivered payloads imported")
+	return nil
+}
+
+// importBuilderSubmissions imports the received bids slot by slot, since the data API only returns all of them when
+// filtering by slot
+func (b *backfiller) importBuilderSubmissions(slotFrom, slotTo uint64) error {
+	numImported, numExisting := 0, 0
+	for slot := slotTo; slot >= slotFrom; slot-- {
+		entries := []*common.BidTraceV2WithTimestampJSON{}
+		query := fmt.Sprintf("?slot=%d&limit=%d", slot, backfillLimitBuilderBlockReceived)
+		if err := b.get("/relay/v1/data/bidtraces/builder_blocks_received"+query, &entries); err != nil {
+			return fmt.Errorf("slot %d: %w", slot, err)
+		}
+		if len(entries) >= backfillLimitBuilderBlockReceived {
+			log.Warnf("slot %d: got the maximum of %d bids, the source relay may have received more", slot, len(entries))
+		}
+
+		for _, entry := range entries {
+			inserted, err := b.db.ImportBuilderBlockSubmission(database.BidTraceV2WithTimestampJSONToBuilderSubmissionEntry(entry))
+			if err != nil {
+				return fmt.Errorf("slot %d: %w", slot, err)
+			}
+			if inserted {
+				numImported++
+			} else {
+				numExisting++
+			}
+		}
+
+		if slot%100 == 0 {
+			log.WithField("imported", numImported).Infof("imported received bids down to slot %d", slot)
+		}
+		if slot == 0 {
+			break
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"imported":        numImported,
+		"alreadyExisting": numExisting,
+	}).Info("received bids imported")
+	return nil
+}
+
+func (b *backfiller) get(path string, dst any) error {
+	time.Sleep(backfillRequestDelay)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, b.sourceURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %d / %s", common.ErrHTTPErrorResponse, resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
@@ -6,8 +6,12 @@ import "github.com/flashbots/mev-boost-relay/common"
 var (
 	log                = common.LogSetup(false, "info")
 	defaultPostgresDSN = common.GetEnv("POSTGRES_DSN", "")
+	defaultRedisURI    = common.GetEnv("REDIS_URI", "localhost:6379")
+	defaultNetwork     = common.GetEnv("NETWORK", "")
 
 	postgresDSN string
+	redisURI    string
+	network     string
 	outFiles    []string
 
 	idFirst   uint64
@@ -0,0 +1,358 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/bls"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/holiman/uint256"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loadtestTarget                 string
+	loadtestDuration               time.Duration
+	loadtestSubmissionsPerSec      float64
+	loadtestRegistrationsPerSec    float64
+	loadtestRegistrationsBatchSize int
+	loadtestNumBuilders            int
+	loadtestNumTxs                 int
+	loadtestSlot                   uint64
+	loadtestMaxInFlight            int
+)
+
+func init() {
+	Loadtest.Flags().StringVar(&loadtestTarget, "target", "", "URL of the relay to test, which must run with LOADTEST_MODE=1")
+	Loadtest.Flags().StringVar(&network, "network", defaultNetwork, "Which network the relay uses, for the signing domain")
+	Loadtest.Flags().DurationVar(&loadtestDuration, "duration", time.Minute, "how long to send requests")
+	Loadtest.Flags().Float64Var(&loadtestSubmissionsPerSec, "submissions-per-sec", 10, "builder block submissions per second, 0 to disable")
+	Loadtest.Flags().Float64Var(&loadtestRegistrationsPerSec, "registrations-per-sec", 1, "registerValidator requests per second, 0 to disable")
+	Loadtest.Flags().IntVar(&loadtestRegistrationsBatchSize, "registrations-batch-size", 100, "validator registrations per registerValidator request")
+	Loadtest.Flags().IntVar(&loadtestNumBuilders, "num-builders", 4, "number of builder keys the submissions are spread over")
+	Loadtest.Flags().IntVar(&loadtestNumTxs, "num-txs", 100, "number of (random) transactions per submitted block")
+	Loadtest.Flags().Uint64Var(&loadtestSlot, "slot", 1, "slot of the submitted blocks")
+	Loadtest.Flags().IntVar(&loadtestMaxInFlight, "max-in-flight", 200, "maximum concurrent requests per type, requests above it are dropped and counted")
+	_ = Loadtest.MarkFlagRequired("target")
+}
+
+var Loadtest = &cobra.Command{
+	Use:   "loadtest",
+	Short: "send signed synthetic block submissions and validator registrations to a relay in load test mode, and report the latencies",
+	Run: func(cmd *cobra.Command, args []string) {
+		targetURL, err := url.Parse(strings.TrimSuffix(loadtestTarget, "/"))
+		if err != nil || targetURL.Host == "" {
+			log.Fatalf("invalid target URL: %s", loadtestTarget)
+		}
+		networkInfo, err := common.NewEthNetworkDetails(network)
+		if err != nil {
+			log.WithError(err).Fatalf("error getting network details")
+		}
+		if loadtestNumBuilders < 1 || loadtestRegistrationsBatchSize < 1 || loadtestMaxInFlight < 1 {
+			log.Fatal("--num-builders, --registrations-batch-size and --max-in-flight must be at least 1")
+		}
+
+		lt, err := newLoadtester(targetURL.String(), networkInfo.DomainBuilder)
+		if err != nil {
+			log.WithError(err).Fatal("failed to set up the load test")
+		}
+
+		log.Infof("sending %.1f submissions/s and %.1f registerValidator requests/s (%d registrations each) to %s for %s", loadtestSubmissionsPerSec, loadtestRegistrationsPerSec, loadtestRegistrationsBatchSize, lt.targetURL, loadtestDuration)
+		ctx, cancel := context.WithTimeout(context.Background(), loadtestDuration)
+		defer cancel()
+
+		submissionStats := newLoadtestStats("submitNewBlock")
+		registrationStats := newLoadtestStats("registerValidator")
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			lt.run(ctx, loadtestSubmissionsPerSec, submissionStats, lt.submitBlock)
+		}()
+		go func() {
+			defer wg.Done()
+			lt.run(ctx, loadtestRegistrationsPerSec, registrationStats, lt.registerValidators)
+		}()
+		wg.Wait()
+
+		submissionStats.report(loadtestDuration)
+		registrationStats.report(loadtestDuration)
+	},
+}
+
+type loadtester struct {
+	client    *http.Client
+	targetURL string
+	domain    boostTypes.Domain
+
+	builderKeys    []*bls.SecretKey
+	builderPubkeys []phase0.BLSPubKey
+
+	validatorKeys    []*bls.SecretKey
+	validatorPubkeys []boostTypes.PublicKey
+
+	parentHash     phase0.Hash32
+	proposerPubkey phase0.BLSPubKey
+	feeRecipient   bellatrix.ExecutionAddress
+}
+
+func newLoadtester(targetURL string, domain boostTypes.Domain) (*loadtester, error) {
+	lt := &loadtester{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		targetURL: targetURL,
+		domain:    domain,
+	}
+	for i := 0; i < loadtestNumBuilders; i++ {
+		sk, pk, err := bls.GenerateNewKeypair()
+		if err != nil {
+			return nil, err
+		}
+		var pubkey phase0.BLSPubKey
+		copy(pubkey[:], pk.Compress())
+		lt.builderKeys = append(lt.builderKeys, sk)
+		lt.builderPubkeys = append(lt.builderPubkeys, pubkey)
+	}
+	for i := 0; i < loadtestRegistrationsBatchSize; i++ {
+		sk, pk, err := bls.GenerateNewKeypair()
+		if err != nil {
+			return nil, err
+		}
+		var pubkey boostTypes.PublicKey
+		if err := pubkey.FromSlice(pk.Compress()); err != nil {
+			return nil, err
+		}
+		lt.validatorKeys = append(lt.validatorKeys, sk)
+		lt.validatorPubkeys = append(lt.validatorPubkeys, pubkey)
+	}
+	if err := randomBytes(lt.parentHash[:], lt.proposerPubkey[:], lt.feeRecipient[:]); err != nil {
+		return nil, err
+	}
+	return lt, nil
+}
+
+// run sends the requests built by newRequest at the given rate until the context is done, dropping them while too
+// many are in flight so that a slow relay doesn't slow down the load generator. Only the HTTP request is timed.
+func (lt *loadtester) run(ctx context.Context, perSec float64, stats *loadtestStats, newRequest func(i int) (path string, payload any, err error)) {
+	if perSec <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / perSec))
+	defer ticker.Stop()
+
+	inFlight := make(chan struct{}, loadtestMaxInFlight)
+	var wg sync.WaitGroup
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			stats.addDropped()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-inFlight
+				wg.Done()
+			}()
+			path, payload, err := newRequest(i)
+			if err != nil {
+				log.WithError(err).Fatalf("failed to create %s request", stats.name)
+			}
+			body, err := json.Marshal(payload)
+			if err != nil {
+				log.WithError(err).Fatalf("failed to encode %s request", stats.name)
+			}
+			start := time.Now()
+			statusCode, err := lt.post(path, body)
+			stats.add(time.Since(start), statusCode, err)
+		}(i)
+	}
+}
+
+// submitBlock returns a signed block submission with random contents, the i-th one of the run
+func (lt *loadtester) submitBlock(i int) (string, any, error) {
+	builderIdx := i % len(lt.builderKeys)
+	payload := &consensuscapella.ExecutionPayload{
+		ParentHash:    lt.parentHash,
+		FeeRecipient:  lt.feeRecipient,
+		BlockNumber:   loadtestSlot,
+		GasLimit:      30_000_000,
+		GasUsed:       15_000_000,
+		Timestamp:     uint64(time.Now().Unix()),
+		ExtraData:     []byte("loadtest"),
+		Transactions:  make([]bellatrix.Transaction, loadtestNumTxs),
+		Withdrawals:   []*consensuscapella.Withdrawal{},
+		BaseFeePerGas: [32]byte{0x01},
+	}
+	if err := randomBytes(payload.StateRoot[:], payload.ReceiptsRoot[:], payload.PrevRandao[:], payload.BlockHash[:]); err != nil {
+		return "", nil, err
+	}
+	for j := range payload.Transactions {
+		payload.Transactions[j] = make([]byte, 200)
+		if err := randomBytes(payload.Transactions[j]); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// increasing values, so that every submission becomes the top bid of its builder
+	value := uint256.NewInt(uint64(i+1) * 1_000_000_000)
+	bidTrace := &apiv1.BidTrace{
+		Slot:                 loadtestSlot,
+		ParentHash:           payload.ParentHash,
+		BlockHash:            payload.BlockHash,
+		BuilderPubkey:        lt.builderPubkeys[builderIdx],
+		ProposerPubkey:       lt.proposerPubkey,
+		ProposerFeeRecipient: payload.FeeRecipient,
+		GasLimit:             payload.GasLimit,
+		GasUsed:              payload.GasUsed,
+		Value:                value,
+	}
+	sig, err := boostTypes.SignMessage(bidTrace, lt.domain, lt.builderKeys[builderIdx])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "/relay/v1/builder/blocks", &capella.SubmitBlockRequest{
+		Message:          bidTrace,
+		ExecutionPayload: payload,
+		Signature:        phase0.BLSSignature(sig),
+	}, nil
+}
+
+// registerValidators returns a batch of new registrations for the synthetic validators
+func (lt *loadtester) registerValidators(i int) (string, any, error) {
+	// the timestamp only changes every second, repeated registrations are skipped by the relay just like in production
+	timestamp := uint64(time.Now().Unix())
+	registrations := make([]*boostTypes.SignedValidatorRegistration, len(lt.validatorKeys))
+	for j, sk := range lt.validatorKeys {
+		msg := &boostTypes.RegisterValidatorRequestMessage{
+			FeeRecipient: boostTypes.Address(lt.feeRecipient),
+			GasLimit:     30_000_000,
+			Timestamp:    timestamp,
+			Pubkey:       lt.validatorPubkeys[j],
+		}
+		sig, err := boostTypes.SignMessage(msg, lt.domain, sk)
+		if err != nil {
+			return "", nil, err
+		}
+		registrations[j] = &boostTypes.SignedValidatorRegistration{Message: msg, Signature: sig}
+	}
+	return "/eth/v1/builder/validators", registrations, nil
+}
+
+func (lt *loadtester) post(path string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, lt.targetURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := lt.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+func randomBytes(bufs ...[]byte) error {
+	for _, buf := range bufs {
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadtestStats collects the latencies and responses of one request type
+type loadtestStats struct {
+	name string
+
+	lock        sync.Mutex
+	latencies   []time.Duration
+	statusCodes map[int]int
+	numErrors   int
+	numDropped  int
+}
+
+func newLoadtestStats(name string) *loadtestStats {
+	return &loadtestStats{
+		name:        name,
+		statusCodes: make(map[int]int),
+	}
+}
+
+func (s *loadtestStats) add(latency time.Duration, statusCode int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err != nil {
+		log.WithError(err).Debugf("%s request failed", s.name)
+		s.numErrors++
+		return
+	}
+	s.latencies = append(s.latencies, latency)
+	s.statusCodes[statusCode]++
+}
+
+func (s *loadtestStats) addDropped() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.numDropped++
+}
+
+func (s *loadtestStats) report(duration time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.latencies) == 0 && s.numErrors == 0 && s.numDropped == 0 {
+		return
+	}
+
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	percentile := func(p float64) time.Duration {
+		if len(s.latencies) == 0 {
+			return 0
+		}
+		return s.latencies[int(p*float64(len(s.latencies)-1))]
+	}
+	statusCodes := make([]string, 0, len(s.statusCodes))
+	for code, cnt := range s.statusCodes {
+		statusCodes = append(statusCodes, fmt.Sprintf("%d:%d", code, cnt))
+	}
+	sort.Strings(statusCodes)
+
+	log.WithFields(logrus.Fields{
+		"responses":   len(s.latencies),
+		"perSec":      fmt.Sprintf("%.1f", float64(len(s.latencies))/duration.Seconds()),
+		"statusCodes": strings.Join(statusCodes, " "),
+		"errors":      s.numErrors,
+		"dropped":     s.numDropped,
+		"p50":         percentile(0.5),
+		"p90":         percentile(0.9),
+		"p99":         percentile(0.99),
+		"max":         percentile(1),
+	}).Infof("%s results", s.name)
+}
@@ -0,0 +1,71 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redisMigrateToURI      string
+	redisMigrateFromPrefix string
+	redisMigrateToPrefix   string
+)
+
+func init() {
+	RedisMigrate.Flags().StringVar(&redisURI, "from-redis-uri", defaultRedisURI, "redis uri to copy from")
+	RedisMigrate.Flags().StringVar(&redisMigrateToURI, "to-redis-uri", "", "redis uri to copy to")
+	RedisMigrate.Flags().StringVar(&redisMigrateFromPrefix, "from-prefix", defaultNetwork, "key prefix in the source, i.e. the network name the relay uses")
+	RedisMigrate.Flags().StringVar(&redisMigrateToPrefix, "to-prefix", "", "key prefix in the destination (default: same as --from-prefix)")
+	_ = RedisMigrate.MarkFlagRequired("to-redis-uri")
+}
+
+var RedisMigrate = &cobra.Command{
+	Use:   "redis-migrate",
+	Short: "copy the known validators, registration timestamps, proposer duties and builder state to another redis, and verify the counts",
+	Run: func(cmd *cobra.Command, args []string) {
+		if redisMigrateToPrefix == "" {
+			redisMigrateToPrefix = redisMigrateFromPrefix
+		}
+		if redisURI == redisMigrateToURI && redisMigrateFromPrefix == redisMigrateToPrefix {
+			log.Fatal("source and destination are the same")
+		}
+
+		src, err := datastore.NewRedisCache(redisURI, redisMigrateFromPrefix)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed to connect to Redis at %s", redisURI)
+		}
+		dst, err := datastore.NewRedisCache(redisMigrateToURI, redisMigrateToPrefix)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed to connect to Redis at %s", redisMigrateToURI)
+		}
+
+		log.Infof("copying the relay state from %s (prefix %q) to %s (prefix %q)", redisURI, redisMigrateFromPrefix, redisMigrateToURI, redisMigrateToPrefix)
+		results, err := src.MigrateTo(context.Background(), dst)
+		if err != nil {
+			log.WithError(err).Fatal("migration failed")
+		}
+
+		numMismatches := 0
+		for _, result := range results {
+			resultLog := log.WithFields(logrus.Fields{
+				"key":       result.Key,
+				"numSource": result.NumSource,
+				"numDest":   result.NumDest,
+			})
+			if result.NumSource != result.NumDest {
+				// e.g. because the relay updated the source in the meantime
+				resultLog.Warn("entry counts don't match")
+				numMismatches++
+			} else {
+				resultLog.Info("key migrated")
+			}
+		}
+		if numMismatches > 0 {
+			log.Fatalf("%d keys have mismatching counts, the source might have changed during the migration, run it again", numMismatches)
+		}
+		log.Info("migration complete")
+	},
+}
@@ -0,0 +1,128 @@
+package tool
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/services/api"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayTarget    string
+	replayDir       string
+	replaySlot      uint64
+	replayEndpoints []string
+	replayHeaders   []string
+	replayKeepPace  bool
+)
+
+func init() {
+	ReplayRequests.Flags().StringVar(&replayTarget, "target", "", "URL of the test relay to send the requests to")
+	ReplayRequests.Flags().StringVar(&replayDir, "dir", "", "directory of the captured requests (REQUEST_CAPTURE_DIR of the relay)")
+	ReplayRequests.Flags().Uint64Var(&replaySlot, "slot", 0, "only replay the requests of this slot")
+	ReplayRequests.Flags().StringSliceVar(&replayEndpoints, "endpoint", []string{}, "only replay the requests of these endpoints (submitBlock, getPayload)")
+	ReplayRequests.Flags().StringArrayVar(&replayHeaders, "header", []string{}, "header to add to every request, e.g. 'X-Builder-Api-Key: key' since the captured credentials are dropped")
+	ReplayRequests.Flags().BoolVar(&replayKeepPace, "keep-pace", false, "wait between the requests as long as between their captures")
+	_ = ReplayRequests.MarkFlagRequired("target")
+	_ = ReplayRequests.MarkFlagRequired("dir")
+}
+
+var ReplayRequests = &cobra.Command{
+	Use:   "replay-requests",
+	Short: "send the requests captured by a relay in capture mode again, to reproduce decoding and validation bugs against a test relay",
+	Run: func(cmd *cobra.Command, args []string) {
+		targetURL, err := url.Parse(strings.TrimSuffix(replayTarget, "/"))
+		if err != nil || targetURL.Host == "" {
+			log.Fatalf("invalid target URL: %s", replayTarget)
+		}
+		header := make(http.Header)
+		for _, h := range replayHeaders {
+			name, value, found := strings.Cut(h, ":")
+			if !found {
+				log.Fatalf("invalid header: %s", h)
+			}
+			header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+
+		dir := replayDir
+		if replaySlot > 0 {
+			dir = filepath.Join(replayDir, strconv.FormatUint(replaySlot, 10))
+		}
+		requests, err := api.ReadCapturedRequests(dir)
+		if err != nil {
+			log.WithError(err).Fatal("failed to read the captured requests")
+		}
+		log.Infof("replaying %d captured requests to %s", len(requests), targetURL.String())
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		numOK := 0
+		var prevReceivedAt time.Time
+		for _, captured := range requests {
+			if len(replayEndpoints) > 0 && !containsString(replayEndpoints, captured.Endpoint) {
+				continue
+			}
+			if replayKeepPace && !prevReceivedAt.IsZero() {
+				time.Sleep(captured.ReceivedAt.Sub(prevReceivedAt))
+			}
+			prevReceivedAt = captured.ReceivedAt
+
+			reqLog := log.WithFields(logrus.Fields{
+				"requestID": captured.RequestID,
+				"endpoint":  captured.Endpoint,
+				"slot":      captured.Slot,
+			})
+			status, body, err := replayRequest(client, targetURL.String(), captured, header)
+			if err != nil {
+				reqLog.WithError(err).Error("failed to send the request")
+				continue
+			}
+			reqLog = reqLog.WithField("status", status)
+			if status >= http.StatusBadRequest {
+				reqLog.WithField("response", string(body)).Warn("request failed")
+				continue
+			}
+			numOK++
+			reqLog.Info("request succeeded")
+		}
+		log.Infof("done, %d requests succeeded", numOK)
+	},
+}
+
+// replayRequest sends the captured request to the target, and returns the status and body of the response
+func replayRequest(client *http.Client, targetURL string, captured *api.CapturedRequest, header http.Header) (int, []byte, error) {
+	req, err := http.NewRequest(captured.Method, targetURL+captured.Path, bytes.NewReader(captured.Body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for name, values := range captured.Header {
+		req.Header[name] = values
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+func containsString(list []string, s string) bool {
+	for _, entry := range list {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
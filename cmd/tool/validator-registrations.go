@@ -0,0 +1,190 @@
+package tool
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	uberatomic "go.uber.org/atomic"
+)
+
+var (
+	registrationsFile       string
+	registrationsSkipVerify bool
+	registrationsNumWorkers int
+)
+
+func init() {
+	ExportValidatorRegistrations.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
+	ExportValidatorRegistrations.Flags().StringVar(&registrationsFile, "out", "", "output filename, gzipped if it ends with .gz")
+	_ = ExportValidatorRegistrations.MarkFlagRequired("out")
+
+	ImportValidatorRegistrations.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
+	ImportValidatorRegistrations.Flags().StringVar(&redisURI, "redis-uri", defaultRedisURI, "redis uri")
+	ImportValidatorRegistrations.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use")
+	ImportValidatorRegistrations.Flags().StringVar(&registrationsFile, "in", "", "input filename, gzipped if it ends with .gz")
+	ImportValidatorRegistrations.Flags().BoolVar(&registrationsSkipVerify, "skip-verify", false, "don't verify the signatures of the registrations")
+	ImportValidatorRegistrations.Flags().IntVar(&registrationsNumWorkers, "workers", 8, "number of registrations saved concurrently")
+	_ = ImportValidatorRegistrations.MarkFlagRequired("in")
+}
+
+var ExportValidatorRegistrations = &cobra.Command{
+	Use:   "export-validator-registrations",
+	Short: "export the latest signed registration of every validator from the DB to a newline-delimited JSON file",
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDatabase()
+		registrations, err := db.GetLatestValidatorRegistrations(false)
+		if err != nil {
+			log.WithError(err).Fatal("failed to get validator registrations")
+		}
+		log.Infof("exporting %d validator registrations to %s", len(registrations), registrationsFile)
+
+		f, err := os.Create(registrationsFile)
+		if err != nil {
+			log.WithError(err).Fatal("failed to open file")
+		}
+		defer f.Close()
+
+		var w io.Writer = f
+		var gz *gzip.Writer
+		if strings.HasSuffix(registrationsFile, ".gz") {
+			gz = gzip.NewWriter(f)
+			w = gz
+		}
+		bw := bufio.NewWriter(w)
+
+		encoder := json.NewEncoder(bw)
+		for _, entry := range registrations {
+			signedRegistration, err := entry.ToSignedValidatorRegistration()
+			if err != nil {
+				log.WithError(err).WithField("pubkey", entry.Pubkey).Fatal("invalid validator registration in the DB")
+			}
+			if err := encoder.Encode(signedRegistration); err != nil {
+				log.WithError(err).Fatal("failed to write to file")
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			log.WithError(err).Fatal("failed to write to file")
+		}
+		if gz != nil {
+			if err := gz.Close(); err != nil {
+				log.WithError(err).Fatal("failed to write to file")
+			}
+		}
+		log.Infof("Wrote %d validator registrations to %s", len(registrations), registrationsFile)
+	},
+}
+
+var ImportValidatorRegistrations = &cobra.Command{
+	Use:   "import-validator-registrations",
+	Short: "import validator registrations exported from another relay into the DB and redis, so validators don't have to re-register",
+	Run: func(cmd *cobra.Command, args []string) {
+		networkInfo, err := common.NewEthNetworkDetails(network)
+		if err != nil {
+			log.WithError(err).Fatalf("error getting network details")
+		}
+		log.Infof("Using network: %s", networkInfo.Name)
+
+		redis, err := datastore.NewRedisCache(redisURI, networkInfo.Name)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed to connect to Redis at %s", redisURI)
+		}
+		db := connectDatabase()
+		ds, err := datastore.NewDatastore(log, redis, db)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed setting up datastore")
+		}
+
+		f, err := os.Open(registrationsFile)
+		if err != nil {
+			log.WithError(err).Fatal("failed to open file")
+		}
+		defer f.Close()
+
+		var r io.Reader = f
+		if strings.HasSuffix(registrationsFile, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				log.WithError(err).Fatal("failed to read gzipped file")
+			}
+			defer gz.Close()
+			r = gz
+		}
+
+		// the registrations are verified and saved by a pool of workers, invalid ones are skipped
+		var numImported, numInvalid, numFailed uberatomic.Uint64
+		regC := make(chan *types.SignedValidatorRegistration, registrationsNumWorkers)
+		var wg sync.WaitGroup
+		for i := 0; i < registrationsNumWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for reg := range regC {
+					regLog := log.WithField("pubkey", reg.Message.Pubkey.String())
+					if !registrationsSkipVerify {
+						ok, err := types.VerifySignature(reg.Message, networkInfo.DomainBuilder, reg.Message.Pubkey[:], reg.Signature[:])
+						if err != nil || !ok {
+							regLog.WithError(err).Warn("invalid signature, skipping registration")
+							numInvalid.Inc()
+							continue
+						}
+					}
+					if err := ds.SaveValidatorRegistration(*reg); err != nil {
+						regLog.WithError(err).Error("failed to save registration")
+						numFailed.Inc()
+						continue
+					}
+					if n := numImported.Inc(); n%10_000 == 0 {
+						log.Infof("imported %d validator registrations", n)
+					}
+				}
+			}()
+		}
+
+		decoder := json.NewDecoder(bufio.NewReader(r))
+		for decoder.More() {
+			reg := new(types.SignedValidatorRegistration)
+			if err := decoder.Decode(reg); err != nil {
+				log.WithError(err).Fatal("failed to decode validator registration")
+			} else if reg.Message == nil {
+				log.Fatal("validator registration without message")
+			}
+			regC <- reg
+		}
+		close(regC)
+		wg.Wait()
+
+		log.WithFields(logrus.Fields{
+			"imported": numImported.Load(),
+			"invalid":  numInvalid.Load(),
+			"failed":   numFailed.Load(),
+		}).Info("validator registrations imported")
+		if numFailed.Load() > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func connectDatabase() *database.DatabaseService {
+	dbURL, err := url.Parse(postgresDSN)
+	if err != nil {
+		log.WithError(err).Fatalf("couldn't read db URL")
+	}
+	log.Infof("Connecting to Postgres database at %s%s ...", dbURL.Host, dbURL.Path)
+	db, err := database.NewDatabaseService(postgresDSN)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to connect to Postgres database at %s%s", dbURL.Host, dbURL.Path)
+	}
+	return db
+}
@@ -0,0 +1,204 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/services/api"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// number of execution payloads loaded from the DB at once
+const verifyPayloadsBatchSize = 1000
+
+var (
+	verifyBlockSimURL string
+	verifySimTimeout  time.Duration
+	verifyOutFile     string
+)
+
+var payloadDiscrepancyCSVHeader = []string{"execution_payload_id", "slot", "block_hash", "builder_pubkey", "check", "recorded", "actual"}
+
+func init() {
+	VerifyPayloads.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
+	VerifyPayloads.Flags().StringVar(&verifyBlockSimURL, "blocksim", common.GetEnv("BLOCKSIM_URI", "http://localhost:8545"), "URL of the block simulation node, which needs the state of the historical blocks")
+	VerifyPayloads.Flags().DurationVar(&verifySimTimeout, "sim-timeout", 10*time.Second, "timeout of a single simulation")
+	VerifyPayloads.Flags().Uint64Var(&idFirst, "id-from", 0, "first execution payload id (inclusive)")
+	VerifyPayloads.Flags().Uint64Var(&idLast, "id-to", 0, "last execution payload id (inclusive)")
+	VerifyPayloads.Flags().StringVar(&verifyOutFile, "out", "", "CSV file to write the discrepancies to (optional)")
+	_ = VerifyPayloads.MarkFlagRequired("id-to")
+}
+
+var VerifyPayloads = &cobra.Command{
+	Use:   "verify-payloads",
+	Short: "simulate stored execution payloads again and cross-check the recorded block hash, gas used and bid value",
+	Run: func(cmd *cobra.Command, args []string) {
+		if idFirst > idLast {
+			log.Fatal("--id-from must not be after --id-to")
+		}
+
+		v := &payloadVerifier{
+			db:      connectDatabase(),
+			client:  &http.Client{Timeout: verifySimTimeout},
+			simURL:  verifyBlockSimURL,
+			results: make(map[string]int),
+		}
+
+		if verifyOutFile != "" {
+			f, err := os.Create(verifyOutFile)
+			if err != nil {
+				log.WithError(err).Fatal("failed to open file")
+			}
+			defer f.Close()
+			v.out = csv.NewWriter(f)
+			if err := v.out.Write(payloadDiscrepancyCSVHeader); err != nil {
+				log.WithError(err).Fatal("failed to write to file")
+			}
+		}
+
+		log.Infof("verifying execution payloads %d to %d with the simulation node at %s", idFirst, idLast, v.simURL)
+		for from := idFirst; from <= idLast; from += verifyPayloadsBatchSize {
+			to := from + verifyPayloadsBatchSize - 1
+			if to > idLast {
+				to = idLast
+			}
+			entries, err := v.db.GetExecutionPayloads(from, to)
+			if err != nil {
+				log.WithError(err).Fatal("error getting execution payloads")
+			}
+			for _, entry := range entries {
+				v.verify(entry)
+			}
+			log.WithFields(logrus.Fields{
+				"payloads":      v.results["payloads"],
+				"discrepancies": v.results["discrepancies"],
+				"skipped":       v.results["skipped"],
+			}).Infof("verified execution payloads up to id %d", to)
+		}
+
+		if v.out != nil {
+			v.out.Flush()
+			if err := v.out.Error(); err != nil {
+				log.WithError(err).Fatal("failed to write to file")
+			}
+		}
+		log.WithFields(logrus.Fields{
+			"payloads":      v.results["payloads"],
+			"discrepancies": v.results["discrepancies"],
+			"skipped":       v.results["skipped"],
+			"unverified":    v.results["unverified"],
+		}).Info("execution payloads verified")
+		if v.results["discrepancies"] > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+type payloadVerifier struct {
+	db     *database.DatabaseService
+	client *http.Client
+	simURL string
+	out    *csv.Writer
+
+	results map[string]int
+}
+
+// verify simulates a stored payload again, and reports where the result doesn't match what the relay recorded
+func (v *payloadVerifier) verify(entry *database.ExecutionPayloadEntry) {
+	v.results["payloads"]++
+	log := log.WithFields(logrus.Fields{
+		"executionPayloadID": entry.ID,
+		"slot":               entry.Slot,
+		"blockHash":          entry.BlockHash,
+	})
+
+	submission, err := v.db.GetBlockSubmissionEntry(entry.Slot, entry.ProposerPubkey, entry.BlockHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Warn("no recorded submission for the payload, skipping")
+		v.results["skipped"]++
+		return
+	} else if err != nil {
+		log.WithError(err).Fatal("failed to get the recorded submission")
+	}
+	log = log.WithField("builderPubkey", submission.BuilderPubkey)
+
+	payload, err := database.ExecutionPayloadEntryToSubmitBlockRequest(entry, submission)
+	if err != nil {
+		log.WithError(err).Warn("failed to reconstruct the submission, skipping")
+		v.results["skipped"]++
+		return
+	}
+
+	report := func(check, recorded, actual string) {
+		v.results["discrepancies"]++
+		log.WithFields(logrus.Fields{
+			"check":    check,
+			"recorded": recorded,
+			"actual":   actual,
+		}).Warn("discrepancy found")
+		if v.out != nil {
+			record := []string{strconv.FormatInt(entry.ID, 10), strconv.FormatUint(entry.Slot, 10), entry.BlockHash, submission.BuilderPubkey, check, recorded, actual}
+			if err := v.out.Write(record); err != nil {
+				log.WithError(err).Fatal("failed to write to file")
+			}
+		}
+	}
+
+	if blockHash := payload.ExecutionPayloadBlockHash(); !strings.EqualFold(blockHash, submission.BlockHash) {
+		report("block_hash", submission.BlockHash, blockHash)
+	}
+	if payload.GasUsed() != submission.GasUsed {
+		report("gas_used", strconv.FormatUint(submission.GasUsed, 10), strconv.FormatUint(payload.GasUsed(), 10))
+	}
+
+	// the proposer's registered gas limit isn't stored with the payload, the one of the block was accepted back then
+	simResult, err := api.SimulateBlock(context.Background(), v.client, v.simURL, &api.BuilderBlockValidationRequest{
+		BuilderSubmitBlockRequest: *payload,
+		RegisteredGasLimit:        payload.GasLimit(),
+	})
+	if err != nil && !errors.Is(err, api.ErrSimulationFailed) {
+		log.WithError(err).Fatal("failed to simulate the payload")
+	}
+
+	recordedSimResult := "success"
+	if !submission.SimSuccess {
+		recordedSimResult = submission.SimError
+	}
+	if err != nil {
+		if submission.SimSuccess {
+			report("simulation", recordedSimResult, err.Error())
+		}
+		return
+	} else if !submission.SimSuccess {
+		report("simulation", recordedSimResult, "success")
+		return
+	}
+
+	if simResult.ProposerPayment == "" {
+		v.results["unverified"]++
+		return
+	}
+	payment, ok := new(big.Int).SetString(simResult.ProposerPayment, 10)
+	if !ok {
+		report("proposer_payment", submission.Value, simResult.ProposerPayment)
+		return
+	}
+	if value, ok := new(big.Int).SetString(submission.Value, 10); ok && payment.Cmp(value) < 0 {
+		report("value", submission.Value, fmt.Sprintf("%s (proposer payment)", payment.String()))
+	}
+	if submission.VerifiedValue.Valid && submission.VerifiedValue.String != payment.String() {
+		report("verified_value", submission.VerifiedValue.String, payment.String())
+	}
+}
@@ -13,6 +13,7 @@ var (
 	defaultPostgresDSN = common.GetEnv("POSTGRES_DSN", "")
 	defaultLogJSON     = os.Getenv("LOG_JSON") != ""
 	defaultLogLevel    = common.GetEnv("LOG_LEVEL", "info")
+	defaultMetricsAddr = common.GetEnv("METRICS_ADDR", "")
 
 	beaconNodeURIs []string
 	redisURI       string
@@ -22,4 +23,6 @@ var (
 	logLevel string
 
 	network string
+
+	metricsAddr string
 )
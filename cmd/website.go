@@ -37,7 +37,8 @@ func init() {
 	websiteCmd.Flags().StringVar(&postgresDSN, "db", defaultPostgresDSN, "PostgreSQL DSN")
 	websiteCmd.Flags().StringVar(&websitePubkeyOverride, "pubkey-override", os.Getenv("PUBKEY_OVERRIDE"), "override for public key")
 
-	websiteCmd.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use")
+	websiteCmd.Flags().StringVar(&network, "network", defaultNetwork, "Which network to use: a built-in network, the path of a network config file, or the URL of a beacon node to load the config from")
+	websiteCmd.Flags().StringVar(&metricsAddr, "metrics-addr", defaultMetricsAddr, "listen address for the prometheus metrics (/metrics), disabled if empty")
 	websiteCmd.Flags().BoolVar(&websiteShowConfigDetails, "show-config-details", websiteDefaultShowConfigDetails, "show config details")
 	websiteCmd.Flags().StringVar(&websiteLinkBeaconchain, "link-beaconchain", websiteDefaultLinkBeaconchain, "url for beaconcha.in")
 	websiteCmd.Flags().StringVar(&websiteLinkEtherscan, "link-etherscan", websiteDefaultLinkEtherscan, "url for etherscan")
@@ -52,6 +53,7 @@ var websiteCmd = &cobra.Command{
 
 		log := common.LogSetup(logJSON, logLevel).WithField("service", "relay/website")
 		log.Infof("boost-relay %s", Version)
+		startMetricsServer(log)
 
 		networkInfo, err := common.NewEthNetworkDetails(network)
 		if err != nil {
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs of the execution payloads stored in redis and the database. The codec is recorded with each entry, so entries
+// written with another codec, or uncompressed ones, can always be read.
+const (
+	PayloadCodecNone   = ""
+	PayloadCodecSnappy = "snappy"
+	PayloadCodecZstd   = "zstd"
+)
+
+var (
+	ErrUnknownPayloadCodec = errors.New("unknown payload codec")
+
+	// PayloadCompression is the codec new execution payloads are written with, uncompressed by default
+	PayloadCompression = GetEnv("PAYLOAD_COMPRESSION", PayloadCodecNone)
+
+	// EncodeAll and DecodeAll of the zstd encoder and decoder can be used concurrently
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// CheckPayloadCodec returns an error if the codec isn't supported
+func CheckPayloadCodec(codec string) error {
+	switch codec {
+	case PayloadCodecNone, PayloadCodecSnappy, PayloadCodecZstd:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownPayloadCodec, codec)
+}
+
+// CompressPayload compresses the payload with the codec, PayloadCodecNone returns it as it is
+func CompressPayload(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case PayloadCodecNone:
+		return data, nil
+	case PayloadCodecSnappy:
+		return snappy.Encode(nil, data), nil
+	case PayloadCodecZstd:
+		return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownPayloadCodec, codec)
+}
+
+// DecompressPayload decompresses a payload compressed with the codec
+func DecompressPayload(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case PayloadCodecNone:
+		return data, nil
+	case PayloadCodecSnappy:
+		return snappy.Decode(nil, data)
+	case PayloadCodecZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownPayloadCodec, codec)
+}
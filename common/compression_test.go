@@ -0,0 +1,34 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadCompression(t *testing.T) {
+	payload := testCapellaSubmitBlockRequestJSON(t, randomTransactions(10, 200))
+	// the random transactions barely compress, the zero padding does
+	payload = append(payload, bytes.Repeat([]byte("0x00"), 1000)...)
+
+	for _, codec := range []string{PayloadCodecNone, PayloadCodecSnappy, PayloadCodecZstd} {
+		require.NoError(t, CheckPayloadCodec(codec))
+		compressed, err := CompressPayload(codec, payload)
+		require.NoError(t, err)
+		if codec != PayloadCodecNone {
+			require.Less(t, len(compressed), len(payload), codec)
+		}
+		decompressed, err := DecompressPayload(codec, compressed)
+		require.NoError(t, err)
+		require.Equal(t, payload, decompressed, codec)
+	}
+
+	require.ErrorIs(t, CheckPayloadCodec("gzip"), ErrUnknownPayloadCodec)
+	_, err := CompressPayload("gzip", payload)
+	require.ErrorIs(t, err, ErrUnknownPayloadCodec)
+	_, err = DecompressPayload("gzip", payload)
+	require.ErrorIs(t, err, ErrUnknownPayloadCodec)
+	_, err = DecompressPayload(PayloadCodecZstd, payload)
+	require.Error(t, err)
+}
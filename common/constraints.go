@@ -0,0 +1,163 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	ssz "github.com/ferranbt/fastssz"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+)
+
+const (
+	// MaxInclusionConstraints is the SSZ limit of the transaction hashes, and of the raw transactions, of the constraints
+	// of a slot
+	MaxInclusionConstraints = 256
+
+	// SSZ limit of the bytes of a transaction, as in the execution payload
+	maxBytesPerTransaction = 1073741824
+)
+
+var (
+	ErrTooManyInclusionConstraints = errors.New("too many inclusion constraints")
+	ErrNoInclusionConstraints      = errors.New("no inclusion constraints")
+)
+
+// InclusionConstraints are the transactions the proposer of a slot requires in its block, e.g. preconfirmed
+// transactions. Transactions are referenced by hash, or given raw for the builders which don't have them.
+type InclusionConstraints struct {
+	Slot              uint64               `json:"slot,string"`
+	ProposerPubkey    boostTypes.PublicKey `json:"proposer_pubkey"`
+	TransactionHashes []boostTypes.Hash    `json:"transaction_hashes"`
+	Transactions      []hexutil.Bytes      `json:"transactions"`
+}
+
+// SignedInclusionConstraints are signed by the proposer, or the delegate registered for it, with the builder domain
+type SignedInclusionConstraints struct {
+	Message   *InclusionConstraints `json:"message"`
+	Signature boostTypes.Signature  `json:"signature"`
+}
+
+// Check returns an error if there are no constraints, or more than the SSZ limits allow
+func (c *InclusionConstraints) Check() error {
+	if len(c.TransactionHashes) == 0 && len(c.Transactions) == 0 {
+		return ErrNoInclusionConstraints
+	}
+	if len(c.TransactionHashes) > MaxInclusionConstraints || len(c.Transactions) > MaxInclusionConstraints {
+		return fmt.Errorf("%w: max %d", ErrTooManyInclusionConstraints, MaxInclusionConstraints)
+	}
+	return nil
+}
+
+// RequiredTransactionHashes returns the lowercase hashes of all the constrained transactions, including the hashes of
+// the raw transactions
+func (c *InclusionConstraints) RequiredTransactionHashes() map[string]bool {
+	hashes := make(map[string]bool, len(c.TransactionHashes)+len(c.Transactions))
+	for _, hash := range c.TransactionHashes {
+		hashes[strings.ToLower(hash.String())] = true
+	}
+	for _, tx := range c.Transactions {
+		hashes[TransactionHash(tx)] = true
+	}
+	return hashes
+}
+
+// MissingTransactions returns the hashes of the constrained transactions which aren't in the list of RLP-encoded
+// transactions of a payload
+func (c *InclusionConstraints) MissingTransactions(txs [][]byte) []string {
+	required := c.RequiredTransactionHashes()
+	for _, tx := range txs {
+		delete(required, TransactionHash(tx))
+		if len(required) == 0 {
+			return nil
+		}
+	}
+	missing := make([]string, 0, len(required))
+	for hash := range required {
+		missing = append(missing, hash)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// TransactionHash returns the lowercase hex hash of an RLP-encoded (typed) transaction, i.e. the keccak256 of its bytes
+func TransactionHash(tx []byte) string {
+	return strings.ToLower(crypto.Keccak256Hash(tx).Hex())
+}
+
+// HashTreeRoot is the signing root of the constraints, hashed as the SSZ container
+// (slot, proposer_pubkey, List[Bytes32, 256], List[ByteList[1073741824], 256])
+func (c *InclusionConstraints) HashTreeRoot() ([32]byte, error) {
+	hh := ssz.DefaultHasherPool.Get()
+	defer ssz.DefaultHasherPool.Put(hh)
+	if err := c.HashTreeRootWith(hh); err != nil {
+		return [32]byte{}, err
+	}
+	return hh.HashRoot()
+}
+
+func (c *InclusionConstraints) HashTreeRootWith(hh ssz.HashWalker) error {
+	if err := c.Check(); err != nil {
+		return err
+	}
+	indx := hh.Index()
+	hh.PutUint64(c.Slot)
+	hh.PutBytes(c.ProposerPubkey[:])
+
+	subIndx := hh.Index()
+	for _, hash := range c.TransactionHashes {
+		hh.Append(hash[:])
+	}
+	hh.MerkleizeWithMixin(subIndx, uint64(len(c.TransactionHashes)), MaxInclusionConstraints)
+
+	subIndx = hh.Index()
+	for _, tx := range c.Transactions {
+		if len(tx) > maxBytesPerTransaction {
+			return ssz.ErrIncorrectListSize
+		}
+		elemIndx := hh.Index()
+		hh.AppendBytes32(tx)
+		hh.MerkleizeWithMixin(elemIndx, uint64(len(tx)), (maxBytesPerTransaction+31)/32)
+	}
+	hh.MerkleizeWithMixin(subIndx, uint64(len(c.Transactions)), MaxInclusionConstraints)
+
+	hh.Merkleize(indx)
+	return nil
+}
+
+// ConstraintsDelegation allows another key to sign the inclusion constraints of a validator, e.g. the key of a
+// preconfirmation service. Like validator registrations, a delegation only replaces one with an older timestamp.
+type ConstraintsDelegation struct {
+	ValidatorPubkey boostTypes.PublicKey `json:"validator_pubkey"`
+	DelegatePubkey  boostTypes.PublicKey `json:"delegate_pubkey"`
+	Timestamp       uint64               `json:"timestamp,string"`
+}
+
+// SignedConstraintsDelegation is signed by the validator with the builder domain
+type SignedConstraintsDelegation struct {
+	Message   *ConstraintsDelegation `json:"message"`
+	Signature boostTypes.Signature   `json:"signature"`
+}
+
+// HashTreeRoot is the signing root of the delegation, hashed as the SSZ container (validator_pubkey, delegate_pubkey,
+// timestamp)
+func (d *ConstraintsDelegation) HashTreeRoot() ([32]byte, error) {
+	hh := ssz.DefaultHasherPool.Get()
+	defer ssz.DefaultHasherPool.Put(hh)
+	if err := d.HashTreeRootWith(hh); err != nil {
+		return [32]byte{}, err
+	}
+	return hh.HashRoot()
+}
+
+func (d *ConstraintsDelegation) HashTreeRootWith(hh ssz.HashWalker) error {
+	indx := hh.Index()
+	hh.PutBytes(d.ValidatorPubkey[:])
+	hh.PutBytes(d.DelegatePubkey[:])
+	hh.PutUint64(d.Timestamp)
+	hh.Merkleize(indx)
+	return nil
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/flashbots/go-boost-utils/bls"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInclusionConstraintsMissingTransactions(t *testing.T) {
+	tx1, tx2, tx3 := []byte{0x01}, []byte{0x02}, []byte{0x03}
+	hash2 := boostTypes.Hash(crypto.Keccak256Hash(tx2))
+
+	constraints := &InclusionConstraints{
+		TransactionHashes: []boostTypes.Hash{hash2},
+		Transactions:      []hexutil.Bytes{tx1},
+	}
+	require.NoError(t, constraints.Check())
+	require.Len(t, constraints.RequiredTransactionHashes(), 2)
+
+	require.Nil(t, constraints.MissingTransactions([][]byte{tx3, tx2, tx1}))
+	require.Equal(t, []string{TransactionHash(tx2)}, constraints.MissingTransactions([][]byte{tx1, tx3}))
+	require.Len(t, constraints.MissingTransactions(nil), 2)
+
+	require.ErrorIs(t, (&InclusionConstraints{}).Check(), ErrNoInclusionConstraints)
+	tooMany := &InclusionConstraints{TransactionHashes: make([]boostTypes.Hash, MaxInclusionConstraints+1)}
+	require.ErrorIs(t, tooMany.Check(), ErrTooManyInclusionConstraints)
+	_, err := tooMany.HashTreeRoot()
+	require.ErrorIs(t, err, ErrTooManyInclusionConstraints)
+}
+
+func TestInclusionConstraintsSignature(t *testing.T) {
+	sk, _, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	pubkey, err := boostTypes.BlsPublicKeyToPublicKey(bls.PublicKeyFromSecretKey(sk))
+	require.NoError(t, err)
+
+	constraints := &InclusionConstraints{
+		Slot:           10,
+		ProposerPubkey: pubkey,
+		Transactions:   []hexutil.Bytes{make([]byte, 100)},
+	}
+	domain := boostTypes.DomainBuilder
+	sig, err := boostTypes.SignMessage(constraints, domain, sk)
+	require.NoError(t, err)
+
+	ok, err := boostTypes.VerifySignature(constraints, domain, pubkey[:], sig[:])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the signature covers the transactions
+	constraints.Transactions[0][0] = 0x01
+	ok, err = boostTypes.VerifySignature(constraints, domain, pubkey[:], sig[:])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	delegation := &ConstraintsDelegation{ValidatorPubkey: pubkey}
+	sig, err = boostTypes.SignMessage(delegation, domain, sk)
+	require.NoError(t, err)
+	ok, err = boostTypes.VerifySignature(delegation, domain, pubkey[:], sig[:])
+	require.NoError(t, err)
+	require.True(t, ok)
+}
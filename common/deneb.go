@@ -0,0 +1,770 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	apiv1capella "github.com/attestantio/go-eth2-client/api/v1/capella"
+	"github.com/attestantio/go-eth2-client/spec/altair"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ssz "github.com/ferranbt/fastssz"
+	"github.com/holiman/uint256"
+)
+
+// The pinned go-builder-client and go-eth2-client have no deneb types, so the ones the relay needs are defined here.
+// They are the capella types with the fields deneb adds, and reuse the capella types for the JSON of the shared fields.
+
+const (
+	// MaxBlobCommitmentsPerBlock is the limit of the blob KZG commitments list in the SSZ of bids and blocks
+	MaxBlobCommitmentsPerBlock = 4096
+	// MaxBlobsPerBlock is the number of blobs a deneb block can carry
+	MaxBlobsPerBlock = 6
+	// BlobLength is the size of a blob in bytes
+	BlobLength = 131072
+)
+
+var (
+	ErrMissingBlobGasFields = errors.New("missing blob gas fields")
+	ErrMissingBlobsBundle   = errors.New("missing blobs bundle")
+	ErrInvalidBlobsBundle   = errors.New("invalid blobs bundle")
+)
+
+// KZGCommitment is the commitment of a blob, as hex in JSON
+type KZGCommitment [48]byte
+
+func (c KZGCommitment) String() string { return hexutil.Encode(c[:]) }
+
+func (c KZGCommitment) MarshalText() ([]byte, error) { return []byte(c.String()), nil }
+
+func (c *KZGCommitment) UnmarshalText(input []byte) error {
+	return unmarshalFixedHex("KZG commitment", input, c[:])
+}
+
+// KZGProof is the proof of a blob for its commitment, as hex in JSON
+type KZGProof [48]byte
+
+func (p KZGProof) String() string { return hexutil.Encode(p[:]) }
+
+func (p KZGProof) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
+
+func (p *KZGProof) UnmarshalText(input []byte) error {
+	return unmarshalFixedHex("KZG proof", input, p[:])
+}
+
+func unmarshalFixedHex(name string, input, out []byte) error {
+	b, err := hexutil.Decode(string(input))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if len(b) != len(out) {
+		return fmt.Errorf("invalid %s: %d bytes instead of %d", name, len(b), len(out))
+	}
+	copy(out, b)
+	return nil
+}
+
+// BlobsBundle has the blobs of the blob transactions of a block, with their commitments and proofs
+type BlobsBundle struct {
+	Commitments []KZGCommitment `json:"commitments"`
+	Proofs      []KZGProof      `json:"proofs"`
+	Blobs       []hexutil.Bytes `json:"blobs"`
+}
+
+// Validate checks the shape of the bundle: a commitment and a proof for each blob, at most MaxBlobsPerBlock blobs of
+// BlobLength bytes. Whether the proofs are valid and the commitments match the blob transactions of the block is
+// checked by the simulation.
+func (b *BlobsBundle) Validate() error {
+	if len(b.Commitments) != len(b.Blobs) || len(b.Proofs) != len(b.Blobs) {
+		return fmt.Errorf("%w: %d commitments and %d proofs for %d blobs", ErrInvalidBlobsBundle, len(b.Commitments), len(b.Proofs), len(b.Blobs))
+	}
+	if len(b.Blobs) > MaxBlobsPerBlock {
+		return fmt.Errorf("%w: %d blobs, at most %d allowed", ErrInvalidBlobsBundle, len(b.Blobs), MaxBlobsPerBlock)
+	}
+	for i, blob := range b.Blobs {
+		if len(blob) != BlobLength {
+			return fmt.Errorf("%w: blob %d has %d bytes", ErrInvalidBlobsBundle, i, len(blob))
+		}
+	}
+	return nil
+}
+
+// mergeJSON marshals base, and sets the fields of extra on top of it
+func mergeJSON(base, extra any) ([]byte, error) {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// blobGasJSON has the fields deneb adds to the execution payload and its header
+type blobGasJSON struct {
+	BlobGasUsed   string `json:"blob_gas_used"`
+	ExcessBlobGas string `json:"excess_blob_gas"`
+}
+
+func (j *blobGasJSON) unpack() (blobGasUsed, excessBlobGas uint64, err error) {
+	if j.BlobGasUsed == "" || j.ExcessBlobGas == "" {
+		return 0, 0, ErrMissingBlobGasFields
+	}
+	if blobGasUsed, err = strconv.ParseUint(j.BlobGasUsed, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid blob_gas_used: %w", err)
+	}
+	if excessBlobGas, err = strconv.ParseUint(j.ExcessBlobGas, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid excess_blob_gas: %w", err)
+	}
+	return blobGasUsed, excessBlobGas, nil
+}
+
+func newBlobGasJSON(blobGasUsed, excessBlobGas uint64) *blobGasJSON {
+	return &blobGasJSON{
+		BlobGasUsed:   strconv.FormatUint(blobGasUsed, 10),
+		ExcessBlobGas: strconv.FormatUint(excessBlobGas, 10),
+	}
+}
+
+// DenebExecutionPayload is the capella execution payload with the blob gas fields
+type DenebExecutionPayload struct {
+	Capella       *consensuscapella.ExecutionPayload
+	BlobGasUsed   uint64
+	ExcessBlobGas uint64
+}
+
+func (e *DenebExecutionPayload) MarshalJSON() ([]byte, error) {
+	if e.Capella == nil {
+		return nil, ErrEmptyPayload
+	}
+	return mergeJSON(e.Capella, newBlobGasJSON(e.BlobGasUsed, e.ExcessBlobGas))
+}
+
+func (e *DenebExecutionPayload) UnmarshalJSON(data []byte) error {
+	blobGas := new(blobGasJSON)
+	if err := json.Unmarshal(data, blobGas); err != nil {
+		return err
+	}
+	blobGasUsed, excessBlobGas, err := blobGas.unpack()
+	if err != nil {
+		return err
+	}
+	payload := new(consensuscapella.ExecutionPayload)
+	if err := json.Unmarshal(data, payload); err != nil {
+		return err
+	}
+	*e = DenebExecutionPayload{Capella: payload, BlobGasUsed: blobGasUsed, ExcessBlobGas: excessBlobGas}
+	return nil
+}
+
+// DenebExecutionPayloadHeader is the capella execution payload header with the blob gas fields
+type DenebExecutionPayloadHeader struct {
+	Capella       *consensuscapella.ExecutionPayloadHeader
+	BlobGasUsed   uint64
+	ExcessBlobGas uint64
+}
+
+func (e *DenebExecutionPayloadHeader) MarshalJSON() ([]byte, error) {
+	if e.Capella == nil {
+		return nil, ErrEmptyPayload
+	}
+	return mergeJSON(e.Capella, newBlobGasJSON(e.BlobGasUsed, e.ExcessBlobGas))
+}
+
+func (e *DenebExecutionPayloadHeader) UnmarshalJSON(data []byte) error {
+	blobGas := new(blobGasJSON)
+	if err := json.Unmarshal(data, blobGas); err != nil {
+		return err
+	}
+	blobGasUsed, excessBlobGas, err := blobGas.unpack()
+	if err != nil {
+		return err
+	}
+	header := new(consensuscapella.ExecutionPayloadHeader)
+	if err := json.Unmarshal(data, header); err != nil {
+		return err
+	}
+	*e = DenebExecutionPayloadHeader{Capella: header, BlobGasUsed: blobGasUsed, ExcessBlobGas: excessBlobGas}
+	return nil
+}
+
+func (e *DenebExecutionPayloadHeader) HashTreeRoot() ([32]byte, error) {
+	hh := ssz.DefaultHasherPool.Get()
+	defer ssz.DefaultHasherPool.Put(hh)
+	if err := e.HashTreeRootWith(hh); err != nil {
+		return [32]byte{}, err
+	}
+	return hh.HashRoot()
+}
+
+func (e *DenebExecutionPayloadHeader) HashTreeRootWith(hh ssz.HashWalker) error {
+	if e.Capella == nil {
+		return ErrEmptyPayload
+	}
+	h := e.Capella
+	indx := hh.Index()
+	hh.PutBytes(h.ParentHash[:])
+	hh.PutBytes(h.FeeRecipient[:])
+	hh.PutBytes(h.StateRoot[:])
+	hh.PutBytes(h.ReceiptsRoot[:])
+	hh.PutBytes(h.LogsBloom[:])
+	hh.PutBytes(h.PrevRandao[:])
+	hh.PutUint64(h.BlockNumber)
+	hh.PutUint64(h.GasLimit)
+	hh.PutUint64(h.GasUsed)
+	hh.PutUint64(h.Timestamp)
+	{
+		if len(h.ExtraData) > 32 {
+			return ssz.ErrIncorrectListSize
+		}
+		elemIndx := hh.Index()
+		hh.PutBytes(h.ExtraData)
+		hh.MerkleizeWithMixin(elemIndx, uint64(len(h.ExtraData)), (32+31)/32)
+	}
+	hh.PutBytes(h.BaseFeePerGas[:])
+	hh.PutBytes(h.BlockHash[:])
+	hh.PutBytes(h.TransactionsRoot[:])
+	hh.PutBytes(h.WithdrawalsRoot[:])
+	hh.PutUint64(e.BlobGasUsed)
+	hh.PutUint64(e.ExcessBlobGas)
+	hh.Merkleize(indx)
+	return nil
+}
+
+// putKZGCommitments hashes the commitments as List[KZGCommitment, MaxBlobCommitmentsPerBlock]
+func putKZGCommitments(hh ssz.HashWalker, commitments []KZGCommitment) error {
+	if len(commitments) > MaxBlobCommitmentsPerBlock {
+		return ssz.ErrIncorrectListSize
+	}
+	subIndx := hh.Index()
+	for _, commitment := range commitments {
+		hh.PutBytes(commitment[:])
+	}
+	hh.MerkleizeWithMixin(subIndx, uint64(len(commitments)), MaxBlobCommitmentsPerBlock)
+	return nil
+}
+
+// DenebBuilderBid is the bid returned by getHeader, with the commitments of the blobs of the block
+type DenebBuilderBid struct {
+	Header             *DenebExecutionPayloadHeader
+	BlobKZGCommitments []KZGCommitment
+	Value              *uint256.Int
+	Pubkey             phase0.BLSPubKey
+}
+
+type denebBuilderBidJSON struct {
+	Header             *DenebExecutionPayloadHeader `json:"header"`
+	BlobKZGCommitments []KZGCommitment              `json:"blob_kzg_commitments"`
+	Value              string                       `json:"value"`
+	Pubkey             string                       `json:"pubkey"`
+}
+
+func (b *DenebBuilderBid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebBuilderBidJSON{
+		Header:             b.Header,
+		BlobKZGCommitments: b.BlobKZGCommitments,
+		Value:              b.Value.ToBig().String(),
+		Pubkey:             b.Pubkey.String(),
+	})
+}
+
+func (b *DenebBuilderBid) UnmarshalJSON(input []byte) error {
+	data := new(denebBuilderBidJSON)
+	if err := json.Unmarshal(input, data); err != nil {
+		return err
+	}
+	if data.Header == nil || data.BlobKZGCommitments == nil {
+		return ErrEmptyPayload
+	}
+	value, ok := new(big.Int).SetString(data.Value, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid value: %s", data.Value)
+	}
+	u256Value, overflow := uint256.FromBig(value)
+	if overflow {
+		return fmt.Errorf("invalid value: %s", data.Value)
+	}
+	var pubkey phase0.BLSPubKey
+	if err := unmarshalFixedHex("pubkey", []byte(data.Pubkey), pubkey[:]); err != nil {
+		return err
+	}
+	*b = DenebBuilderBid{
+		Header:             data.Header,
+		BlobKZGCommitments: data.BlobKZGCommitments,
+		Value:              u256Value,
+		Pubkey:             pubkey,
+	}
+	return nil
+}
+
+// HashTreeRoot is the signing root of the bid, hashed as the SSZ container (header, blob_kzg_commitments, value,
+// pubkey)
+func (b *DenebBuilderBid) HashTreeRoot() ([32]byte, error) {
+	hh := ssz.DefaultHasherPool.Get()
+	defer ssz.DefaultHasherPool.Put(hh)
+	if err := b.HashTreeRootWith(hh); err != nil {
+		return [32]byte{}, err
+	}
+	return hh.HashRoot()
+}
+
+func (b *DenebBuilderBid) HashTreeRootWith(hh ssz.HashWalker) error {
+	if b.Header == nil || b.Value == nil {
+		return ErrEmptyPayload
+	}
+	indx := hh.Index()
+	if err := b.Header.HashTreeRootWith(hh); err != nil {
+		return err
+	}
+	if err := putKZGCommitments(hh, b.BlobKZGCommitments); err != nil {
+		return err
+	}
+	value := b.Value.Bytes32()
+	for i, j := 0, len(value)-1; i < j; i, j = i+1, j-1 {
+		value[i], value[j] = value[j], value[i]
+	}
+	hh.PutBytes(value[:])
+	hh.PutBytes(b.Pubkey[:])
+	hh.Merkleize(indx)
+	return nil
+}
+
+type DenebSignedBuilderBid struct {
+	Message   *DenebBuilderBid
+	Signature phase0.BLSSignature
+}
+
+type denebSignedBuilderBidJSON struct {
+	Message   *DenebBuilderBid `json:"message"`
+	Signature string           `json:"signature"`
+}
+
+func (b *DenebSignedBuilderBid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebSignedBuilderBidJSON{Message: b.Message, Signature: b.Signature.String()})
+}
+
+func (b *DenebSignedBuilderBid) UnmarshalJSON(input []byte) error {
+	data := new(denebSignedBuilderBidJSON)
+	if err := json.Unmarshal(input, data); err != nil {
+		return err
+	}
+	if data.Message == nil {
+		return ErrEmptyPayload
+	}
+	b.Message = data.Message
+	return unmarshalFixedHex("signature", []byte(data.Signature), b.Signature[:])
+}
+
+// DenebGetHeaderResponse is the getHeader response of a deneb bid
+type DenebGetHeaderResponse struct {
+	Version string                 `json:"version"`
+	Data    *DenebSignedBuilderBid `json:"data"`
+}
+
+// ExecutionPayloadAndBlobsBundle is what the proposer needs to publish a deneb block, returned by getPayload
+type ExecutionPayloadAndBlobsBundle struct {
+	ExecutionPayload *DenebExecutionPayload `json:"execution_payload"`
+	BlobsBundle      *BlobsBundle           `json:"blobs_bundle"`
+}
+
+// DenebGetPayloadResponse is the getPayload response of a deneb block
+type DenebGetPayloadResponse struct {
+	Version string                          `json:"version"`
+	Data    *ExecutionPayloadAndBlobsBundle `json:"data"`
+}
+
+// DenebSubmitBlockRequest is the block submission of deneb, the capella one with the blob gas fields in the execution
+// payload and the blobs bundle
+type DenebSubmitBlockRequest struct {
+	Message          *apiv1.BidTrace
+	ExecutionPayload *DenebExecutionPayload
+	BlobsBundle      *BlobsBundle
+	Signature        phase0.BLSSignature
+}
+
+type denebSubmitBlockRequestJSON struct {
+	Message          *apiv1.BidTrace        `json:"message"`
+	ExecutionPayload *DenebExecutionPayload `json:"execution_payload"`
+	BlobsBundle      *BlobsBundle           `json:"blobs_bundle"`
+	Signature        string                 `json:"signature"`
+}
+
+func (r *DenebSubmitBlockRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebSubmitBlockRequestJSON{
+		Message:          r.Message,
+		ExecutionPayload: r.ExecutionPayload,
+		BlobsBundle:      r.BlobsBundle,
+		Signature:        r.Signature.String(),
+	})
+}
+
+func (r *DenebSubmitBlockRequest) UnmarshalJSON(input []byte) error {
+	data := new(denebSubmitBlockRequestJSON)
+	if err := json.Unmarshal(input, data); err != nil {
+		return err
+	}
+	if data.BlobsBundle == nil {
+		return ErrMissingBlobsBundle
+	}
+	if data.Message == nil || data.ExecutionPayload == nil {
+		return ErrEmptyPayload
+	}
+	*r = DenebSubmitBlockRequest{
+		Message:          data.Message,
+		ExecutionPayload: data.ExecutionPayload,
+		BlobsBundle:      data.BlobsBundle,
+	}
+	return unmarshalFixedHex("signature", []byte(data.Signature), r.Signature[:])
+}
+
+func (r *DenebSubmitBlockRequest) capella() *capella.SubmitBlockRequest {
+	return &capella.SubmitBlockRequest{
+		Message:          r.Message,
+		ExecutionPayload: r.ExecutionPayload.Capella,
+		Signature:        r.Signature,
+	}
+}
+
+// denebSubmission reads the fields deneb shares with capella from the capella submission
+type denebSubmission struct {
+	capellaSubmission
+	deneb *DenebSubmitBlockRequest
+}
+
+func (s denebSubmission) Version() string { return ForkVersionDeneb }
+
+// ExecutionPayloadJSON includes the blobs bundle, which is returned by getPayload together with the payload
+func (s denebSubmission) ExecutionPayloadJSON() ([]byte, error) {
+	return json.Marshal(&ExecutionPayloadAndBlobsBundle{
+		ExecutionPayload: s.deneb.ExecutionPayload,
+		BlobsBundle:      s.deneb.BlobsBundle,
+	})
+}
+
+// DenebSignedBlindedBeaconBlock is the getPayload request of deneb
+type DenebSignedBlindedBeaconBlock struct {
+	Message   *DenebBlindedBeaconBlock
+	Signature phase0.BLSSignature
+}
+
+type denebSignedBlockJSON[T any] struct {
+	Message   T      `json:"message"`
+	Signature string `json:"signature"`
+}
+
+func (s *DenebSignedBlindedBeaconBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebSignedBlockJSON[*DenebBlindedBeaconBlock]{Message: s.Message, Signature: s.Signature.String()})
+}
+
+func (s *DenebSignedBlindedBeaconBlock) UnmarshalJSON(input []byte) error {
+	data := new(denebSignedBlockJSON[*DenebBlindedBeaconBlock])
+	if err := json.Unmarshal(input, data); err != nil {
+		return err
+	}
+	if data.Message == nil {
+		return ErrEmptyPayload
+	}
+	s.Message = data.Message
+	return unmarshalFixedHex("signature", []byte(data.Signature), s.Signature[:])
+}
+
+type DenebBlindedBeaconBlock struct {
+	Slot          phase0.Slot
+	ProposerIndex phase0.ValidatorIndex
+	ParentRoot    phase0.Root
+	StateRoot     phase0.Root
+	Body          *DenebBlindedBeaconBlockBody
+}
+
+type denebBeaconBlockJSON[T any] struct {
+	Slot          string `json:"slot"`
+	ProposerIndex string `json:"proposer_index"`
+	ParentRoot    string `json:"parent_root"`
+	StateRoot     string `json:"state_root"`
+	Body          T      `json:"body"`
+}
+
+func (b *DenebBlindedBeaconBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebBeaconBlockJSON[*DenebBlindedBeaconBlockBody]{
+		Slot:          strconv.FormatUint(uint64(b.Slot), 10),
+		ProposerIndex: strconv.FormatUint(uint64(b.ProposerIndex), 10),
+		ParentRoot:    b.ParentRoot.String(),
+		StateRoot:     b.StateRoot.String(),
+		Body:          b.Body,
+	})
+}
+
+func (b *DenebBlindedBeaconBlock) UnmarshalJSON(input []byte) error {
+	data := new(denebBeaconBlockJSON[*DenebBlindedBeaconBlockBody])
+	if err := json.Unmarshal(input, data); err != nil {
+		return err
+	}
+	if data.Body == nil {
+		return ErrEmptyPayload
+	}
+	slot, err := strconv.ParseUint(data.Slot, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid slot: %w", err)
+	}
+	proposerIndex, err := strconv.ParseUint(data.ProposerIndex, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid proposer index: %w", err)
+	}
+	*b = DenebBlindedBeaconBlock{Slot: phase0.Slot(slot), ProposerIndex: phase0.ValidatorIndex(proposerIndex), Body: data.Body}
+	if err := unmarshalFixedHex("parent root", []byte(data.ParentRoot), b.ParentRoot[:]); err != nil {
+		return err
+	}
+	return unmarshalFixedHex("state root", []byte(data.StateRoot), b.StateRoot[:])
+}
+
+// HashTreeRoot is the signing root of the block, which the proposer signs
+func (b *DenebBlindedBeaconBlock) HashTreeRoot() ([32]byte, error) {
+	hh := ssz.DefaultHasherPool.Get()
+	defer ssz.DefaultHasherPool.Put(hh)
+	if err := b.HashTreeRootWith(hh); err != nil {
+		return [32]byte{}, err
+	}
+	return hh.HashRoot()
+}
+
+func (b *DenebBlindedBeaconBlock) HashTreeRootWith(hh ssz.HashWalker) error {
+	if b.Body == nil {
+		return ErrEmptyPayload
+	}
+	indx := hh.Index()
+	hh.PutUint64(uint64(b.Slot))
+	hh.PutUint64(uint64(b.ProposerIndex))
+	hh.PutBytes(b.ParentRoot[:])
+	hh.PutBytes(b.StateRoot[:])
+	if err := b.Body.HashTreeRootWith(hh); err != nil {
+		return err
+	}
+	hh.Merkleize(indx)
+	return nil
+}
+
+// DenebBlindedBeaconBlockBody is the capella blinded beacon block body with the deneb execution payload header and the
+// commitments of the blobs
+type DenebBlindedBeaconBlockBody struct {
+	RANDAOReveal           phase0.BLSSignature
+	ETH1Data               *phase0.ETH1Data
+	Graffiti               [32]byte
+	ProposerSlashings      []*phase0.ProposerSlashing
+	AttesterSlashings      []*phase0.AttesterSlashing
+	Attestations           []*phase0.Attestation
+	Deposits               []*phase0.Deposit
+	VoluntaryExits         []*phase0.SignedVoluntaryExit
+	SyncAggregate          *altair.SyncAggregate
+	ExecutionPayloadHeader *DenebExecutionPayloadHeader
+	BLSToExecutionChanges  []*consensuscapella.SignedBLSToExecutionChange
+	BlobKZGCommitments     []KZGCommitment
+}
+
+type denebBlindedBeaconBlockBodyJSON struct {
+	ExecutionPayloadHeader *DenebExecutionPayloadHeader `json:"execution_payload_header"`
+	BlobKZGCommitments     []KZGCommitment              `json:"blob_kzg_commitments"`
+}
+
+// capella returns the body with the capella part of the execution payload header
+func (b *DenebBlindedBeaconBlockBody) capella() *apiv1capella.BlindedBeaconBlockBody {
+	return &apiv1capella.BlindedBeaconBlockBody{
+		RANDAOReveal:           b.RANDAOReveal,
+		ETH1Data:               b.ETH1Data,
+		Graffiti:               b.Graffiti,
+		ProposerSlashings:      b.ProposerSlashings,
+		AttesterSlashings:      b.AttesterSlashings,
+		Attestations:           b.Attestations,
+		Deposits:               b.Deposits,
+		VoluntaryExits:         b.VoluntaryExits,
+		SyncAggregate:          b.SyncAggregate,
+		ExecutionPayloadHeader: b.ExecutionPayloadHeader.Capella,
+		BLSToExecutionChanges:  b.BLSToExecutionChanges,
+	}
+}
+
+func (b *DenebBlindedBeaconBlockBody) MarshalJSON() ([]byte, error) {
+	if b.ExecutionPayloadHeader == nil {
+		return nil, ErrEmptyPayload
+	}
+	return mergeJSON(b.capella(), &denebBlindedBeaconBlockBodyJSON{
+		ExecutionPayloadHeader: b.ExecutionPayloadHeader,
+		BlobKZGCommitments:     b.BlobKZGCommitments,
+	})
+}
+
+func (b *DenebBlindedBeaconBlockBody) UnmarshalJSON(input []byte) error {
+	data := new(denebBlindedBeaconBlockBodyJSON)
+	if err := json.Unmarshal(input, data); err != nil {
+		return err
+	}
+	if data.ExecutionPayloadHeader == nil || data.BlobKZGCommitments == nil {
+		return ErrEmptyPayload
+	}
+	body := new(apiv1capella.BlindedBeaconBlockBody)
+	if err := json.Unmarshal(input, body); err != nil {
+		return err
+	}
+	*b = DenebBlindedBeaconBlockBody{
+		RANDAOReveal:           body.RANDAOReveal,
+		ETH1Data:               body.ETH1Data,
+		Graffiti:               body.Graffiti,
+		ProposerSlashings:      body.ProposerSlashings,
+		AttesterSlashings:      body.AttesterSlashings,
+		Attestations:           body.Attestations,
+		Deposits:               body.Deposits,
+		VoluntaryExits:         body.VoluntaryExits,
+		SyncAggregate:          body.SyncAggregate,
+		ExecutionPayloadHeader: data.ExecutionPayloadHeader,
+		BLSToExecutionChanges:  body.BLSToExecutionChanges,
+		BlobKZGCommitments:     data.BlobKZGCommitments,
+	}
+	return nil
+}
+
+func (b *DenebBlindedBeaconBlockBody) HashTreeRootWith(hh ssz.HashWalker) error {
+	if b.ETH1Data == nil || b.SyncAggregate == nil || b.ExecutionPayloadHeader == nil {
+		return ErrEmptyPayload
+	}
+	indx := hh.Index()
+	hh.PutBytes(b.RANDAOReveal[:])
+	if err := b.ETH1Data.HashTreeRootWith(hh); err != nil {
+		return err
+	}
+	hh.PutBytes(b.Graffiti[:])
+	if err := putList(hh, b.ProposerSlashings, 16); err != nil {
+		return err
+	}
+	if err := putList(hh, b.AttesterSlashings, 2); err != nil {
+		return err
+	}
+	if err := putList(hh, b.Attestations, 128); err != nil {
+		return err
+	}
+	if err := putList(hh, b.Deposits, 16); err != nil {
+		return err
+	}
+	if err := putList(hh, b.VoluntaryExits, 16); err != nil {
+		return err
+	}
+	if err := b.SyncAggregate.HashTreeRootWith(hh); err != nil {
+		return err
+	}
+	if err := b.ExecutionPayloadHeader.HashTreeRootWith(hh); err != nil {
+		return err
+	}
+	if err := putList(hh, b.BLSToExecutionChanges, 16); err != nil {
+		return err
+	}
+	if err := putKZGCommitments(hh, b.BlobKZGCommitments); err != nil {
+		return err
+	}
+	hh.Merkleize(indx)
+	return nil
+}
+
+// putList hashes the elements as an SSZ list of containers with the given limit
+func putList[T interface{ HashTreeRootWith(ssz.HashWalker) error }](hh ssz.HashWalker, elems []T, limit uint64) error {
+	if uint64(len(elems)) > limit {
+		return ssz.ErrIncorrectListSize
+	}
+	subIndx := hh.Index()
+	for _, elem := range elems {
+		if err := elem.HashTreeRootWith(hh); err != nil {
+			return err
+		}
+	}
+	hh.MerkleizeWithMixin(subIndx, uint64(len(elems)), limit)
+	return nil
+}
+
+// DenebSignedBlockContents is the signed deneb block with its blobs, as published to the beacon node
+type DenebSignedBlockContents struct {
+	SignedBlock *DenebSignedBeaconBlock `json:"signed_block"`
+	KZGProofs   []KZGProof              `json:"kzg_proofs"`
+	Blobs       []hexutil.Bytes         `json:"blobs"`
+}
+
+type DenebSignedBeaconBlock struct {
+	Message   *DenebBeaconBlock
+	Signature phase0.BLSSignature
+}
+
+func (s *DenebSignedBeaconBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebSignedBlockJSON[*DenebBeaconBlock]{Message: s.Message, Signature: s.Signature.String()})
+}
+
+type DenebBeaconBlock struct {
+	Slot          phase0.Slot
+	ProposerIndex phase0.ValidatorIndex
+	ParentRoot    phase0.Root
+	StateRoot     phase0.Root
+	Body          *DenebBeaconBlockBody
+}
+
+func (b *DenebBeaconBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&denebBeaconBlockJSON[*DenebBeaconBlockBody]{
+		Slot:          strconv.FormatUint(uint64(b.Slot), 10),
+		ProposerIndex: strconv.FormatUint(uint64(b.ProposerIndex), 10),
+		ParentRoot:    b.ParentRoot.String(),
+		StateRoot:     b.StateRoot.String(),
+		Body:          b.Body,
+	})
+}
+
+// DenebBeaconBlockBody is the body of the blinded block with the execution payload in place of its header
+type DenebBeaconBlockBody struct {
+	Blinded          *DenebBlindedBeaconBlockBody
+	ExecutionPayload *DenebExecutionPayload
+}
+
+type denebBeaconBlockBodyJSON struct {
+	ExecutionPayload   *DenebExecutionPayload `json:"execution_payload"`
+	BlobKZGCommitments []KZGCommitment        `json:"blob_kzg_commitments"`
+}
+
+func (b *DenebBeaconBlockBody) MarshalJSON() ([]byte, error) {
+	if b.Blinded == nil || b.ExecutionPayload == nil {
+		return nil, ErrEmptyPayload
+	}
+	blinded := b.Blinded
+	body := &consensuscapella.BeaconBlockBody{
+		RANDAOReveal:          blinded.RANDAOReveal,
+		ETH1Data:              blinded.ETH1Data,
+		Graffiti:              blinded.Graffiti,
+		ProposerSlashings:     blinded.ProposerSlashings,
+		AttesterSlashings:     blinded.AttesterSlashings,
+		Attestations:          blinded.Attestations,
+		Deposits:              blinded.Deposits,
+		VoluntaryExits:        blinded.VoluntaryExits,
+		SyncAggregate:         blinded.SyncAggregate,
+		ExecutionPayload:      b.ExecutionPayload.Capella,
+		BLSToExecutionChanges: blinded.BLSToExecutionChanges,
+	}
+	return mergeJSON(body, &denebBeaconBlockBodyJSON{
+		ExecutionPayload:   b.ExecutionPayload,
+		BlobKZGCommitments: blinded.BlobKZGCommitments,
+	})
+}
+
+// isDenebVersion returns whether the versioned response has the deneb version
+func isDenebVersion(data []byte) bool {
+	var versioned struct {
+		Version string `json:"version"`
+	}
+	return json.Unmarshal(data, &versioned) == nil && versioned.Version == ForkVersionDeneb
+}
@@ -0,0 +1,145 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func testBlobsBundle(numBlobs int) *BlobsBundle {
+	bundle := &BlobsBundle{
+		Commitments: make([]KZGCommitment, numBlobs),
+		Proofs:      make([]KZGProof, numBlobs),
+		Blobs:       make([]hexutil.Bytes, numBlobs),
+	}
+	for i := range bundle.Blobs {
+		bundle.Commitments[i][0] = byte(i + 1)
+		bundle.Proofs[i][0] = byte(i + 1)
+		bundle.Blobs[i] = make([]byte, BlobLength)
+	}
+	return bundle
+}
+
+func TestBlobsBundleValidate(t *testing.T) {
+	require.NoError(t, testBlobsBundle(0).Validate())
+	require.NoError(t, testBlobsBundle(MaxBlobsPerBlock).Validate())
+	require.ErrorIs(t, testBlobsBundle(MaxBlobsPerBlock+1).Validate(), ErrInvalidBlobsBundle)
+
+	bundle := testBlobsBundle(2)
+	bundle.Proofs = bundle.Proofs[:1]
+	require.ErrorIs(t, bundle.Validate(), ErrInvalidBlobsBundle)
+
+	bundle = testBlobsBundle(2)
+	bundle.Blobs[1] = bundle.Blobs[1][:100]
+	require.ErrorIs(t, bundle.Validate(), ErrInvalidBlobsBundle)
+}
+
+func TestDenebSubmitBlockRequest(t *testing.T) {
+	submission := &DenebSubmitBlockRequest{
+		Message: &apiv1.BidTrace{Slot: 123, Value: uint256.NewInt(456)},
+		ExecutionPayload: &DenebExecutionPayload{
+			Capella: &consensuscapella.ExecutionPayload{
+				GasUsed:      100,
+				ExtraData:    []byte{},
+				Transactions: []bellatrix.Transaction{},
+				Withdrawals:  []*consensuscapella.Withdrawal{},
+			},
+			BlobGasUsed:   131072,
+			ExcessBlobGas: 7,
+		},
+		BlobsBundle: testBlobsBundle(1),
+	}
+	denebJSON, err := json.Marshal(submission)
+	require.NoError(t, err)
+
+	fields := make(map[string]json.RawMessage)
+	require.NoError(t, json.Unmarshal(denebJSON, &fields))
+	require.Contains(t, fields, "blobs_bundle")
+	payloadFields := make(map[string]json.RawMessage)
+	require.NoError(t, json.Unmarshal(fields["execution_payload"], &payloadFields))
+	require.Equal(t, `"131072"`, string(payloadFields["blob_gas_used"]))
+	require.Equal(t, `"7"`, string(payloadFields["excess_blob_gas"]))
+
+	t.Run("decoded as deneb", func(t *testing.T) {
+		payload := new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(denebJSON, payload))
+		require.NotNil(t, payload.Deneb)
+		require.Nil(t, payload.Capella)
+		require.Equal(t, ForkVersionDeneb, payload.Versioned().Version())
+		require.Equal(t, uint64(123), payload.Slot())
+		require.Equal(t, "456", payload.Value().String())
+		require.Equal(t, uint64(100), payload.GasUsed())
+		require.Equal(t, submission.ExecutionPayload.BlobGasUsed, payload.Deneb.ExecutionPayload.BlobGasUsed)
+		require.Equal(t, submission.BlobsBundle.Commitments, payload.Deneb.BlobsBundle.Commitments)
+
+		// getPayload returns the blobs bundle together with the payload
+		payloadJSON, err := payload.Versioned().ExecutionPayloadJSON()
+		require.NoError(t, err)
+		payloadAndBlobs := new(ExecutionPayloadAndBlobsBundle)
+		require.NoError(t, json.Unmarshal(payloadJSON, payloadAndBlobs))
+		require.Len(t, payloadAndBlobs.BlobsBundle.Blobs, 1)
+		require.Equal(t, uint64(7), payloadAndBlobs.ExecutionPayload.ExcessBlobGas)
+	})
+
+	t.Run("capella submission isn't decoded as deneb", func(t *testing.T) {
+		capellaJSON, err := json.Marshal(submission.capella())
+		require.NoError(t, err)
+		payload := new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(capellaJSON, payload))
+		require.Nil(t, payload.Deneb)
+		require.Equal(t, ForkVersionCapella, payload.Versioned().Version())
+	})
+
+	t.Run("deneb payload without blob gas fields", func(t *testing.T) {
+		capellaJSON, err := json.Marshal(&capella.SubmitBlockRequest{
+			Message:          submission.Message,
+			ExecutionPayload: submission.ExecutionPayload.Capella,
+		})
+		require.NoError(t, err)
+		fields := make(map[string]json.RawMessage)
+		require.NoError(t, json.Unmarshal(capellaJSON, &fields))
+		fields["blobs_bundle"] = json.RawMessage(`{"commitments":[],"proofs":[],"blobs":[]}`)
+		withoutBlobGasJSON, err := json.Marshal(fields)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, json.Unmarshal(withoutBlobGasJSON, new(DenebSubmitBlockRequest)), ErrMissingBlobGasFields)
+		require.ErrorIs(t, json.Unmarshal(withoutBlobGasJSON, new(BuilderSubmitBlockRequest)), ErrMissingBlobGasFields)
+	})
+}
+
+func TestDenebExecutionPayloadHeaderHashTreeRoot(t *testing.T) {
+	header := &DenebExecutionPayloadHeader{
+		Capella: &consensuscapella.ExecutionPayloadHeader{ExtraData: []byte{}},
+	}
+	root, err := header.HashTreeRoot()
+	require.NoError(t, err)
+
+	// the blob gas fields are part of the root
+	header.ExcessBlobGas = 1
+	root2, err := header.HashTreeRoot()
+	require.NoError(t, err)
+	require.NotEqual(t, root, root2)
+
+	// and it differs from the capella root of the same header
+	capellaRoot, err := header.Capella.HashTreeRoot()
+	require.NoError(t, err)
+	require.NotEqual(t, capellaRoot, root)
+}
+
+func TestKZGCommitmentJSON(t *testing.T) {
+	commitment := KZGCommitment{1, 2, 3}
+	data, err := json.Marshal(commitment)
+	require.NoError(t, err)
+	decoded := KZGCommitment{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, commitment, decoded)
+
+	require.Error(t, json.Unmarshal([]byte(`"0x0102"`), &decoded))
+}
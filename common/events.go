@@ -0,0 +1,14 @@
+package common
+
+// Event types of the relay, shared by the event bus and the webhook notifications, which each handle a subset of them
+const (
+	EventSubmissionAccepted    = "submission_accepted"
+	EventNewTopBid             = "new_top_bid"
+	EventPayloadDelivered      = "payload_delivered"
+	EventPayloadDeliveryFailed = "payload_delivery_failed"
+	EventBeaconPublishFailed   = "beacon_publish_failed"
+	EventBuilderDemoted        = "builder_demoted"
+	EventDependencyDown        = "dependency_down"
+	EventDependencyRecovered   = "dependency_recovered"
+	EventFeeRecipientChanged   = "fee_recipient_changed"
+)
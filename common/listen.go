@@ -0,0 +1,39 @@
+package common
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixSocketPrefix marks a listen address as the path of a Unix domain socket, e.g. unix:/run/relay/api.sock
+const unixSocketPrefix = "unix:"
+
+var ErrInvalidListenAddr = errors.New("invalid listen address")
+
+// Listen listens on a TCP address, e.g. localhost:9062 or [::1]:9062, or on a Unix domain socket given as unix:<path>.
+// The socket file of a previous run is removed first.
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, unixSocketPrefix); ok {
+		if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// CheckListenAddr returns an error if addr is neither a host:port nor a unix:<path> listen address
+func CheckListenAddr(addr string) error {
+	if path, ok := strings.CutPrefix(addr, unixSocketPrefix); ok {
+		if path == "" {
+			return ErrInvalidListenAddr
+		}
+		return nil
+	}
+	_, _, err := net.SplitHostPort(addr)
+	return err
+}
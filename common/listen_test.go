@@ -0,0 +1,33 @@
+package common
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckListenAddr(t *testing.T) {
+	for _, addr := range []string{"localhost:9062", "0.0.0.0:9062", "[::1]:9062", "[::]:9062", ":9062", "unix:/run/relay/api.sock"} {
+		require.NoError(t, CheckListenAddr(addr), addr)
+	}
+	for _, addr := range []string{"localhost", "::1:9062", "unix:", ""} {
+		require.Error(t, CheckListenAddr(addr), addr)
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relay.sock")
+	ln, err := Listen(unixSocketPrefix + path)
+	require.NoError(t, err)
+	require.Equal(t, "unix", ln.Addr().Network())
+
+	// the socket file left behind by a previous run is replaced
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+	require.FileExists(t, path)
+	ln, err = Listen(unixSocketPrefix + path)
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
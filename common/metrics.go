@@ -0,0 +1,288 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const metricsNamespace = "mevboostrelay"
+
+// MetricsRegistry holds the metrics of all services, only the ones a service updates are non-zero. The metrics of the
+// relay API have a network label, as one process can serve several networks.
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	// APIRequestDuration is the latency of the relay API endpoints, by endpoint and response status code
+	APIRequestDuration = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of relay API requests",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2, 3, 5, 10},
+	}, []string{"network", "endpoint", "status"})
+
+	// RateLimitedRequestsTotal counts the requests and validator registrations rejected by a rate limit, by limiter
+	RateLimitedRequestsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests rejected by a rate limit",
+	}, []string{"network", "limiter"})
+
+	// BuilderAPIKeyChecksTotal counts the API key checks of builder submissions, by builder (only for builders with a key)
+	// and result
+	BuilderAPIKeyChecksTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "builder_api_key_checks_total",
+		Help:      "Number of builder API key checks of block submissions",
+	}, []string{"network", "builder", "result"})
+
+	// BlockSimQueueDepth is the number of active and waiting block simulations, by queue tier
+	BlockSimQueueDepth = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocksim_queue_depth",
+		Help:      "Number of active and waiting block simulation requests",
+	}, []string{"network", "tier", "state"})
+
+	// BlockSimUnverifiedPaymentsTotal is the number of simulated blocks whose proposer payment the node didn't report
+	BlockSimUnverifiedPaymentsTotal = promauto.With(MetricsRegistry).NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocksim_unverified_payments_total",
+		Help:      "Number of simulated blocks without a reported proposer payment",
+	})
+
+	// RedisOperationDuration is the latency of redis commands, by command name (pipelines count as one operation)
+	RedisOperationDuration = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "redis_operation_duration_seconds",
+		Help:      "Duration of redis commands",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+
+	// GetPayloadLookupDuration is the latency of looking up the payload of a getPayload request, by source (memory,
+	// redis, database) and result (hit, miss, error)
+	GetPayloadLookupDuration = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "getpayload_lookup_duration_seconds",
+		Help:      "Duration of getPayload response lookups",
+		Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"source", "result"})
+
+	// DBOperationDuration is the latency of database operations, by method of the database service
+	DBOperationDuration = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_operation_duration_seconds",
+		Help:      "Duration of database operations",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"operation"})
+
+	// DBSubmissionQueueLength is the number of builder submissions waiting to be saved to the database
+	DBSubmissionQueueLength = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_submission_queue_length",
+		Help:      "Number of builder submissions waiting to be saved to the database",
+	}, []string{"network"})
+
+	// DutyLookupTotal counts the lookups of the in-memory proposer duties, by table (slot or pubkey) and result (hit or
+	// miss)
+	DutyLookupTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "duty_lookup_total",
+		Help:      "Number of lookups of the in-memory proposer duties",
+	}, []string{"network", "table", "result"})
+
+	// DutyLookupStalenessSlots is the number of slots since the in-memory proposer duties were refreshed from redis
+	DutyLookupStalenessSlots = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "duty_lookup_staleness_slots",
+		Help:      "Number of slots since the in-memory proposer duties were refreshed",
+	}, []string{"network"})
+
+	// DutyLookupAge is the time since the in-memory proposer duties were refreshed from redis
+	DutyLookupAge = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "duty_lookup_age_seconds",
+		Help:      "Seconds since the in-memory proposer duties were refreshed",
+	}, []string{"network"})
+
+	// ArchiverEntriesTotal counts the entries the archiver exported, by sink and stream
+	ArchiverEntriesTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "archiver_entries_total",
+		Help:      "Number of entries exported by the archiver",
+	}, []string{"sink", "stream"})
+
+	// ArchiverErrorsTotal counts the failed exports of the archiver, by sink and stream
+	ArchiverErrorsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "archiver_errors_total",
+		Help:      "Number of failed exports of the archiver",
+	}, []string{"sink", "stream"})
+
+	// ArchiverCheckpointSlot is the highest slot the archiver exported to the sink, to alert on a sink falling behind
+	ArchiverCheckpointSlot = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "archiver_checkpoint_slot",
+		Help:      "Highest slot of the latest batch exported by the archiver",
+	}, []string{"sink", "stream"})
+
+	// SubmissionsShedTotal counts the builder submissions rejected while the relay is overloaded, by the overloaded queue
+	// and the reason the submission was shed
+	SubmissionsShedTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "submissions_shed_total",
+		Help:      "Number of builder submissions shed while the relay is overloaded",
+	}, []string{"network", "overload", "reason"})
+
+	// ForkReady is 1 while the relay is ready for the forks of the network, 0 if an unsupported fork is active or about
+	// to be, or the beacon nodes disagree about the fork epochs
+	ForkReady = promauto.With(MetricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "fork_ready",
+		Help:      "Whether the relay is ready for the forks of the network",
+	}, []string{"network"})
+
+	// BlockPolicyViolationsTotal counts the builder submissions rejected by the block policy, by error code
+	BlockPolicyViolationsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "block_policy_violations_total",
+		Help:      "Number of builder submissions rejected by the block policy before simulation",
+	}, []string{"network", "code"})
+
+	// DBSubmissionQueueOverflowTotal counts the builder submissions which didn't fit in the queue, by what happened to
+	// them (dropped or spilled to disk)
+	DBSubmissionQueueOverflowTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_submission_queue_overflow_total",
+		Help:      "Number of builder submissions which didn't fit in the database write queue",
+	}, []string{"network", "policy"})
+
+	// EventBusEventsTotal counts the events of the event bus, by event type and result (published, failed or dropped)
+	EventBusEventsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_bus_events_total",
+		Help:      "Number of events sent to the event bus",
+	}, []string{"type", "result"})
+
+	// WebhookNotificationsTotal counts the webhook notifications, by event type and result
+	WebhookNotificationsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "webhook_notifications_total",
+		Help:      "Number of webhook notifications",
+	}, []string{"event", "result"})
+
+	// SubmissionMirrorTotal counts the builder submissions mirrored to the secondary relay, by result (forwarded, failed
+	// or dropped)
+	SubmissionMirrorTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "submission_mirror_total",
+		Help:      "Number of builder submissions mirrored to the secondary relay",
+	}, []string{"result"})
+
+	// RequestCaptureTotal counts the requests sampled in capture mode, by endpoint and result (captured, failed or
+	// dropped)
+	RequestCaptureTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "request_capture_total",
+		Help:      "Number of requests sampled in capture mode",
+	}, []string{"endpoint", "result"})
+
+	// DuplicateSubmissionsTotal counts the builder submissions answered right away as exact duplicates of an accepted one
+	DuplicateSubmissionsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "duplicate_submissions_total",
+		Help:      "Number of duplicate builder submissions skipped",
+	}, []string{"network"})
+
+	// BeaconPublishTotal counts the block publish attempts on the beacon nodes, by method and result
+	BeaconPublishTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "beacon_publish_total",
+		Help:      "Number of blocks published to beacon nodes",
+	}, []string{"method", "result"})
+
+	// BeaconCrossCheckDisagreementsTotal counts the cross-checks of the beacon nodes with a disagreeing node, by attribute
+	BeaconCrossCheckDisagreementsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "beacon_cross_check_disagreements_total",
+		Help:      "Number of cross-checks of randao or withdrawals where a beacon node disagreed",
+	}, []string{"attribute"})
+
+	// SlotEventsTotal counts the slot lifecycle events of the API instance, by event
+	SlotEventsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "slot_events_total",
+		Help:      "Number of slot lifecycle events",
+	}, []string{"network", "event"})
+
+	// HousekeeperJobDuration is the duration of the housekeeper jobs, by job name
+	HousekeeperJobDuration = promauto.With(MetricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "housekeeper_job_duration_seconds",
+		Help:      "Duration of housekeeper jobs",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 120, 300},
+	}, []string{"job"})
+
+	// DataAPIKeyRequestsTotal counts the data API requests made with an API key, by key name, tier and result
+	DataAPIKeyRequestsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "data_api_key_requests_total",
+		Help:      "Number of data API requests made with an API key",
+	}, []string{"key", "tier", "result"})
+
+	// FeeRecipientChangesTotal counts the validator registrations changing to a fee recipient the validator never
+	// registered before, by whether the fee recipient is allowlisted
+	FeeRecipientChangesTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "fee_recipient_changes_total",
+		Help:      "Number of validator registrations changing to a new fee recipient",
+	}, []string{"network", "allowlisted"})
+
+	// ValidatorRegistrationsTotal counts the registrations of the registerValidator calls of known validators, by whether
+	// they are new, unchanged re-submissions of the stored registration, or older than it
+	ValidatorRegistrationsTotal = promauto.With(MetricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "validator_registrations_total",
+		Help:      "Number of validator registrations received, by result",
+	}, []string{"network", "result"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}), //nolint:exhaustruct
+	)
+}
+
+// ObserveDuration records the time since start in the histogram, meant to be deferred
+func ObserveDuration(histogram prometheus.Observer, start time.Time) {
+	histogram.Observe(time.Since(start).Seconds())
+}
+
+// StartMetricsServer serves the metrics on /metrics of listenAddr. Only returns if the server fails.
+func StartMetricsServer(log *logrus.Entry, listenAddr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{})) //nolint:exhaustruct
+
+	srv := &http.Server{
+		Addr:              listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ln, err := Listen(listenAddr)
+	if err != nil {
+		return err
+	}
+	log.Infof("metrics server listening on %s", listenAddr)
+	err = srv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,209 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FarFutureEpoch is the epoch of forks that aren't scheduled
+const FarFutureEpoch = uint64(math.MaxUint64)
+
+// EthNetworkCustom is the name of custom networks whose config has no CONFIG_NAME
+const EthNetworkCustom = "custom"
+
+var (
+	ErrInvalidNetworkConfig = errors.New("invalid network config")
+	ErrInvalidDomainType    = errors.New("invalid domain type")
+)
+
+// NetworkConfig is the part of the consensus layer config that the relay needs. The keys are the ones of the config.yaml
+// of the consensus specs and of the spec endpoint of beacon nodes. The genesis validators root isn't part of the spec
+// config, it's read from the GENESIS_VALIDATORS_ROOT key of config files, or from the genesis endpoint of beacon nodes.
+type NetworkConfig struct {
+	ConfigName               string `json:"CONFIG_NAME" yaml:"CONFIG_NAME"`                               //nolint:tagliatelle
+	GenesisForkVersion       string `json:"GENESIS_FORK_VERSION" yaml:"GENESIS_FORK_VERSION"`             //nolint:tagliatelle
+	GenesisValidatorsRoot    string `json:"GENESIS_VALIDATORS_ROOT" yaml:"GENESIS_VALIDATORS_ROOT"`       //nolint:tagliatelle
+	BellatrixForkVersion     string `json:"BELLATRIX_FORK_VERSION" yaml:"BELLATRIX_FORK_VERSION"`         //nolint:tagliatelle
+	BellatrixForkEpoch       string `json:"BELLATRIX_FORK_EPOCH" yaml:"BELLATRIX_FORK_EPOCH"`             //nolint:tagliatelle
+	CapellaForkVersion       string `json:"CAPELLA_FORK_VERSION" yaml:"CAPELLA_FORK_VERSION"`             //nolint:tagliatelle
+	CapellaForkEpoch         string `json:"CAPELLA_FORK_EPOCH" yaml:"CAPELLA_FORK_EPOCH"`                 //nolint:tagliatelle
+	DenebForkVersion         string `json:"DENEB_FORK_VERSION" yaml:"DENEB_FORK_VERSION"`                 //nolint:tagliatelle
+	DenebForkEpoch           string `json:"DENEB_FORK_EPOCH" yaml:"DENEB_FORK_EPOCH"`                     //nolint:tagliatelle
+	DomainBeaconProposer     string `json:"DOMAIN_BEACON_PROPOSER" yaml:"DOMAIN_BEACON_PROPOSER"`         //nolint:tagliatelle
+	DomainApplicationBuilder string `json:"DOMAIN_APPLICATION_BUILDER" yaml:"DOMAIN_APPLICATION_BUILDER"` //nolint:tagliatelle
+	SecondsPerSlot           string `json:"SECONDS_PER_SLOT" yaml:"SECONDS_PER_SLOT"`                     //nolint:tagliatelle
+	SlotsPerEpoch            string `json:"SLOTS_PER_EPOCH" yaml:"SLOTS_PER_EPOCH"`                       //nolint:tagliatelle
+}
+
+// IsNetworkConfigLocation returns whether the network is given as the URL of a beacon node or the path of a config file,
+// rather than the name of a built-in network
+func IsNetworkConfigLocation(network string) bool {
+	if strings.HasPrefix(network, "http://") || strings.HasPrefix(network, "https://") {
+		return true
+	}
+	switch filepath.Ext(network) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return strings.ContainsRune(network, os.PathSeparator)
+}
+
+// LoadNetworkConfig reads the network config from the spec and genesis endpoints of a beacon node if location is a URL,
+// and from a YAML or JSON file otherwise
+func LoadNetworkConfig(location string) (*NetworkConfig, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return fetchNetworkConfig(strings.TrimSuffix(location, "/"))
+	}
+
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	config := new(NetworkConfig)
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidNetworkConfig, err)
+	}
+	return config, nil
+}
+
+func fetchNetworkConfig(beaconURI string) (*NetworkConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	spec := new(struct {
+		Data *NetworkConfig `json:"data"`
+	})
+	if err := fetchJSON(ctx, beaconURI+"/eth/v1/config/spec", spec); err != nil {
+		return nil, err
+	} else if spec.Data == nil {
+		return nil, fmt.Errorf("%w: empty spec response", ErrInvalidNetworkConfig)
+	}
+
+	if spec.Data.GenesisValidatorsRoot == "" {
+		genesis := new(struct {
+			Data struct {
+				GenesisValidatorsRoot string `json:"genesis_validators_root"`
+			} `json:"data"`
+		})
+		if err := fetchJSON(ctx, beaconURI+"/eth/v1/beacon/genesis", genesis); err != nil {
+			return nil, err
+		}
+		spec.Data.GenesisValidatorsRoot = genesis.Data.GenesisValidatorsRoot
+	}
+	return spec.Data, nil
+}
+
+func fetchJSON(ctx context.Context, url string, dst any) error {
+	resp, err := makeRequest(ctx, *http.DefaultClient, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// EthNetworkDetails computes the signing domains of the network. Forks without version are left out, and the domain
+// types default to the ones of mainnet. Networks with other slot timings than DurationPerSlot and SlotsPerEpoch are
+// rejected, the relay's timings (e.g. of the bid cutoffs and the housekeeper jobs) are derived from them.
+func (c *NetworkConfig) EthNetworkDetails() (*EthNetworkDetails, error) {
+	if c.GenesisForkVersion == "" || c.BellatrixForkVersion == "" {
+		return nil, fmt.Errorf("%w: missing genesis or bellatrix fork version", ErrInvalidNetworkConfig)
+	}
+	if c.GenesisValidatorsRoot == "" {
+		return nil, fmt.Errorf("%w: missing genesis validators root", ErrInvalidNetworkConfig)
+	}
+	if c.SecondsPerSlot != "" && c.SecondsPerSlot != strconv.Itoa(int(DurationPerSlot.Seconds())) {
+		return nil, fmt.Errorf("%w: SECONDS_PER_SLOT %s is not supported, only %d", ErrInvalidNetworkConfig, c.SecondsPerSlot, int(DurationPerSlot.Seconds()))
+	}
+	if c.SlotsPerEpoch != "" && c.SlotsPerEpoch != strconv.Itoa(SlotsPerEpoch) {
+		return nil, fmt.Errorf("%w: SLOTS_PER_EPOCH %s is not supported, only %d", ErrInvalidNetworkConfig, c.SlotsPerEpoch, SlotsPerEpoch)
+	}
+	name := c.ConfigName
+	if name == "" {
+		name = EthNetworkCustom
+	}
+
+	domainTypeBuilder, err := parseDomainType(c.DomainApplicationBuilder, boostTypes.DomainTypeAppBuilder)
+	if err != nil {
+		return nil, err
+	}
+	domainTypeBeaconProposer, err := parseDomainType(c.DomainBeaconProposer, boostTypes.DomainTypeBeaconProposer)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &EthNetworkDetails{
+		Name:                     name,
+		GenesisForkVersionHex:    c.GenesisForkVersion,
+		GenesisValidatorsRootHex: c.GenesisValidatorsRoot,
+		BellatrixForkVersionHex:  c.BellatrixForkVersion,
+		CapellaForkVersionHex:    c.CapellaForkVersion,
+		DenebForkVersionHex:      c.DenebForkVersion,
+		ForkEpochs:               make(map[string]uint64),
+	}
+
+	details.DomainBuilder, err = ComputeDomain(domainTypeBuilder, c.GenesisForkVersion, boostTypes.Root{}.String())
+	if err != nil {
+		return nil, err
+	}
+	details.DomainBeaconProposerBellatrix, err = ComputeDomain(domainTypeBeaconProposer, c.BellatrixForkVersion, c.GenesisValidatorsRoot)
+	if err != nil {
+		return nil, err
+	}
+	if c.CapellaForkVersion != "" {
+		details.DomainBeaconProposerCapella, err = ComputeDomain(domainTypeBeaconProposer, c.CapellaForkVersion, c.GenesisValidatorsRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.DenebForkVersion != "" {
+		details.DomainBeaconProposerDeneb, err = ComputeDomain(domainTypeBeaconProposer, c.DenebForkVersion, c.GenesisValidatorsRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	forks := []struct{ version, epoch string }{
+		{c.BellatrixForkVersion, c.BellatrixForkEpoch},
+		{c.CapellaForkVersion, c.CapellaForkEpoch},
+		{c.DenebForkVersion, c.DenebForkEpoch},
+	}
+	for _, fork := range forks {
+		if fork.version == "" || fork.epoch == "" {
+			continue
+		}
+		epoch, err := strconv.ParseUint(fork.epoch, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid epoch %s of fork %s", ErrInvalidNetworkConfig, fork.epoch, fork.version)
+		}
+		if epoch != FarFutureEpoch {
+			details.ForkEpochs[fork.version] = epoch
+		}
+	}
+	return details, nil
+}
+
+func parseDomainType(domainTypeHex string, defaultDomainType boostTypes.DomainType) (domainType boostTypes.DomainType, err error) {
+	if domainTypeHex == "" {
+		return defaultDomainType, nil
+	}
+	b, err := hexutil.Decode(domainTypeHex)
+	if err != nil || len(b) != len(domainType) {
+		return domainType, fmt.Errorf("%w: %s", ErrInvalidDomainType, domainTypeHex)
+	}
+	copy(domainType[:], b)
+	return domainType, nil
+}
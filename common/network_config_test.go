@@ -0,0 +1,91 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+func requireGoerliDetails(t *testing.T, details *EthNetworkDetails) {
+	t.Helper()
+	goerli, err := NewEthNetworkDetails(EthNetworkGoerli)
+	require.NoError(t, err)
+	require.Equal(t, EthNetworkGoerli, details.Name)
+	require.Equal(t, goerli.DomainBuilder, details.DomainBuilder)
+	require.Equal(t, goerli.DomainBeaconProposerBellatrix, details.DomainBeaconProposerBellatrix)
+	require.Equal(t, goerli.DomainBeaconProposerCapella, details.DomainBeaconProposerCapella)
+	require.Equal(t, goerli.DenebForkVersionHex, details.DenebForkVersionHex)
+	require.Equal(t, map[string]uint64{
+		boostTypes.BellatrixForkVersionGoerli: 112260,
+		CapellaForkVersionGoerli:              162304,
+	}, details.ForkEpochs)
+}
+
+func TestNetworkConfigFile(t *testing.T) {
+	// the format of the config.yaml of the consensus specs, with unquoted fork versions
+	config := `
+CONFIG_NAME: goerli
+GENESIS_FORK_VERSION: ` + boostTypes.GenesisForkVersionGoerli + `
+GENESIS_VALIDATORS_ROOT: ` + boostTypes.GenesisValidatorsRootGoerli + `
+BELLATRIX_FORK_VERSION: ` + boostTypes.BellatrixForkVersionGoerli + `
+BELLATRIX_FORK_EPOCH: 112260
+CAPELLA_FORK_VERSION: ` + CapellaForkVersionGoerli + `
+CAPELLA_FORK_EPOCH: 162304
+DENEB_FORK_VERSION: ` + DenebForkVersionGoerli + `
+DENEB_FORK_EPOCH: 18446744073709551615
+SECONDS_PER_SLOT: 12
+SLOTS_PER_EPOCH: 32
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+
+	details, err := NewEthNetworkDetails(path)
+	require.NoError(t, err)
+	requireGoerliDetails(t, details)
+
+	// networks with other slot timings are rejected
+	for _, timing := range [][2]string{{"SECONDS_PER_SLOT: 12", "SECONDS_PER_SLOT: 6"}, {"SLOTS_PER_EPOCH: 32", "SLOTS_PER_EPOCH: 8"}} {
+		require.NoError(t, os.WriteFile(path, []byte(strings.Replace(config, timing[0], timing[1], 1)), 0o600))
+		_, err = NewEthNetworkDetails(path)
+		require.ErrorIs(t, err, ErrInvalidNetworkConfig)
+	}
+
+	_, err = NewEthNetworkDetails(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+
+	_, err = NewEthNetworkDetails("devnet")
+	require.ErrorIs(t, err, ErrUnknownNetwork)
+}
+
+func TestNetworkConfigFromBeaconNode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eth/v1/config/spec", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {
+			"CONFIG_NAME": "goerli",
+			"GENESIS_FORK_VERSION": "` + boostTypes.GenesisForkVersionGoerli + `",
+			"BELLATRIX_FORK_VERSION": "` + boostTypes.BellatrixForkVersionGoerli + `",
+			"BELLATRIX_FORK_EPOCH": "112260",
+			"CAPELLA_FORK_VERSION": "` + CapellaForkVersionGoerli + `",
+			"CAPELLA_FORK_EPOCH": "162304",
+			"DENEB_FORK_VERSION": "` + DenebForkVersionGoerli + `",
+			"DENEB_FORK_EPOCH": "18446744073709551615",
+			"DOMAIN_BEACON_PROPOSER": "0x00000000",
+			"SECONDS_PER_SLOT": "12"
+		}}`))
+	})
+	mux.HandleFunc("/eth/v1/beacon/genesis", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"genesis_validators_root": "` + boostTypes.GenesisValidatorsRootGoerli + `"}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	details, err := NewEthNetworkDetails(server.URL)
+	require.NoError(t, err)
+	requireGoerliDetails(t, details)
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// MaxPooledBufferSize is the largest buffer capacity that is returned to the pool. Larger buffers are left to the GC,
+// so a single huge request doesn't stay in memory for good.
+const MaxPooledBufferSize = 16 * 1024 * 1024
+
+var (
+	bufferPool     = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	gzipReaderPool sync.Pool
+)
+
+// GetBuffer returns an empty buffer from the pool. Return it with PutBuffer once nothing refers to its bytes anymore.
+func GetBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer) //nolint:forcetypeassert
+	buf.Reset()
+	return buf
+}
+
+func PutBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > MaxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// GetGzipReader returns a gzip reader of r from the pool, which saves the allocation of the decompression state. Return
+// it with PutGzipReader.
+func GetGzipReader(r io.Reader) (*gzip.Reader, error) {
+	zr, ok := gzipReaderPool.Get().(*gzip.Reader)
+	if !ok {
+		return gzip.NewReader(r)
+	}
+	if err := zr.Reset(r); err != nil {
+		gzipReaderPool.Put(zr)
+		return nil, err
+	}
+	return zr, nil
+}
+
+func PutGzipReader(zr *gzip.Reader) {
+	_ = zr.Close()
+	gzipReaderPool.Put(zr)
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferPool(t *testing.T) {
+	buf := GetBuffer()
+	buf.WriteString("foo")
+	PutBuffer(buf)
+
+	// buffers from the pool are always empty
+	require.Equal(t, 0, GetBuffer().Len())
+}
+
+func TestGzipReaderPool(t *testing.T) {
+	compress := func(data string) *bytes.Buffer {
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		_, err := zw.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf
+	}
+
+	// a reused reader decompresses the new input
+	for _, data := range []string{"foo", "bar"} {
+		zr, err := GetGzipReader(compress(data))
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.Equal(t, data, string(decompressed))
+		PutGzipReader(zr)
+	}
+
+	_, err := GetGzipReader(bytes.NewReader([]byte("not gzip")))
+	require.Error(t, err)
+}
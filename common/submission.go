@@ -0,0 +1,318 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+)
+
+const (
+	ForkVersionBellatrix = "bellatrix"
+	ForkVersionCapella   = "capella"
+	ForkVersionDeneb     = "deneb"
+	ForkVersionElectra   = "electra"
+)
+
+var (
+	// ElectraEnabled makes block submissions decode as electra submissions when they have execution requests. The electra
+	// types are placeholders, see ElectraSubmitBlockRequest.
+	ElectraEnabled = os.Getenv("ENABLE_ELECTRA") == "1"
+
+	ErrMissingExecutionRequests = errors.New("missing execution requests")
+)
+
+// ForkSubmitBlockRequest is the block submission of a single fork. BuilderSubmitBlockRequest delegates all accessors to
+// it, so supporting the submissions of a new fork means implementing this interface and adding the fork to the JSON
+// decoding of BuilderSubmitBlockRequest.
+type ForkSubmitBlockRequest interface {
+	// Version is the name of the fork, as stored with the execution payloads in the database
+	Version() string
+	HasExecutionPayload() bool
+	// ExecutionPayloadJSON is the JSON of the bare execution payload, from deneb on together with the blobs bundle
+	ExecutionPayloadJSON() ([]byte, error)
+
+	Message() *apiv1.BidTrace
+	Signature() phase0.BLSSignature
+	Slot() uint64
+	BlockHash() string
+	ParentHash() string
+	BuilderPubkey() phase0.BLSPubKey
+	ProposerPubkey() string
+	ProposerFeeRecipient() string
+	Value() *big.Int
+
+	ExecutionPayloadBlockHash() string
+	ExecutionPayloadParentHash() string
+	ExecutionPayloadFeeRecipient() string
+	Timestamp() uint64
+	BlockNumber() uint64
+	GasUsed() uint64
+	GasLimit() uint64
+	Random() string
+	NumTx() int
+	Transactions() [][]byte
+	Withdrawals() []*consensuscapella.Withdrawal
+}
+
+// Versioned returns the submission of the fork that is set
+func (b *BuilderSubmitBlockRequest) Versioned() ForkSubmitBlockRequest {
+	if b.Electra != nil {
+		return electraSubmission{capellaSubmission{b.Electra.capella()}}
+	}
+	if b.Deneb != nil {
+		return denebSubmission{capellaSubmission{b.Deneb.capella()}, b.Deneb}
+	}
+	if b.Capella != nil {
+		return capellaSubmission{b.Capella}
+	}
+	if b.Bellatrix != nil {
+		return bellatrixSubmission{b.Bellatrix}
+	}
+	return emptySubmission{}
+}
+
+type bellatrixSubmission struct {
+	r *boostTypes.BuilderSubmitBlockRequest
+}
+
+func (s bellatrixSubmission) Version() string { return ForkVersionBellatrix }
+
+func (s bellatrixSubmission) HasExecutionPayload() bool { return s.r.ExecutionPayload != nil }
+
+func (s bellatrixSubmission) ExecutionPayloadJSON() ([]byte, error) {
+	return json.Marshal(s.r.ExecutionPayload)
+}
+
+func (s bellatrixSubmission) Message() *apiv1.BidTrace { return BoostBidToBidTrace(s.r.Message) }
+
+func (s bellatrixSubmission) Signature() phase0.BLSSignature {
+	return phase0.BLSSignature(s.r.Signature)
+}
+
+func (s bellatrixSubmission) Slot() uint64 { return s.r.Message.Slot }
+
+func (s bellatrixSubmission) BlockHash() string { return s.r.Message.BlockHash.String() }
+
+func (s bellatrixSubmission) ParentHash() string { return s.r.Message.ParentHash.String() }
+
+func (s bellatrixSubmission) BuilderPubkey() phase0.BLSPubKey {
+	return phase0.BLSPubKey(s.r.Message.BuilderPubkey)
+}
+
+func (s bellatrixSubmission) ProposerPubkey() string { return s.r.Message.ProposerPubkey.String() }
+
+func (s bellatrixSubmission) ProposerFeeRecipient() string {
+	return s.r.Message.ProposerFeeRecipient.String()
+}
+
+func (s bellatrixSubmission) Value() *big.Int { return s.r.Message.Value.BigInt() }
+
+func (s bellatrixSubmission) ExecutionPayloadBlockHash() string {
+	return s.r.ExecutionPayload.BlockHash.String()
+}
+
+func (s bellatrixSubmission) ExecutionPayloadParentHash() string {
+	return s.r.ExecutionPayload.ParentHash.String()
+}
+
+func (s bellatrixSubmission) ExecutionPayloadFeeRecipient() string {
+	return s.r.ExecutionPayload.FeeRecipient.String()
+}
+
+func (s bellatrixSubmission) Timestamp() uint64 { return s.r.ExecutionPayload.Timestamp }
+
+func (s bellatrixSubmission) BlockNumber() uint64 { return s.r.ExecutionPayload.BlockNumber }
+
+func (s bellatrixSubmission) GasUsed() uint64 { return s.r.ExecutionPayload.GasUsed }
+
+func (s bellatrixSubmission) GasLimit() uint64 { return s.r.ExecutionPayload.GasLimit }
+
+func (s bellatrixSubmission) Random() string { return s.r.ExecutionPayload.Random.String() }
+
+func (s bellatrixSubmission) NumTx() int { return len(s.r.ExecutionPayload.Transactions) }
+
+func (s bellatrixSubmission) Transactions() [][]byte {
+	txs := make([][]byte, 0, len(s.r.ExecutionPayload.Transactions))
+	for _, tx := range s.r.ExecutionPayload.Transactions {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func (s bellatrixSubmission) Withdrawals() []*consensuscapella.Withdrawal { return nil }
+
+type capellaSubmission struct {
+	r *capella.SubmitBlockRequest
+}
+
+func (s capellaSubmission) Version() string { return ForkVersionCapella }
+
+func (s capellaSubmission) HasExecutionPayload() bool { return s.r.ExecutionPayload != nil }
+
+func (s capellaSubmission) ExecutionPayloadJSON() ([]byte, error) {
+	return json.Marshal(s.r.ExecutionPayload)
+}
+
+func (s capellaSubmission) Message() *apiv1.BidTrace { return s.r.Message }
+
+func (s capellaSubmission) Signature() phase0.BLSSignature { return s.r.Signature }
+
+func (s capellaSubmission) Slot() uint64 { return s.r.Message.Slot }
+
+func (s capellaSubmission) BlockHash() string { return s.r.Message.BlockHash.String() }
+
+func (s capellaSubmission) ParentHash() string { return s.r.Message.ParentHash.String() }
+
+func (s capellaSubmission) BuilderPubkey() phase0.BLSPubKey { return s.r.Message.BuilderPubkey }
+
+func (s capellaSubmission) ProposerPubkey() string { return s.r.Message.ProposerPubkey.String() }
+
+func (s capellaSubmission) ProposerFeeRecipient() string {
+	return s.r.Message.ProposerFeeRecipient.String()
+}
+
+func (s capellaSubmission) Value() *big.Int { return s.r.Message.Value.ToBig() }
+
+func (s capellaSubmission) ExecutionPayloadBlockHash() string {
+	return s.r.ExecutionPayload.BlockHash.String()
+}
+
+func (s capellaSubmission) ExecutionPayloadParentHash() string {
+	return s.r.ExecutionPayload.ParentHash.String()
+}
+
+func (s capellaSubmission) ExecutionPayloadFeeRecipient() string {
+	return s.r.ExecutionPayload.FeeRecipient.String()
+}
+
+func (s capellaSubmission) Timestamp() uint64 { return s.r.ExecutionPayload.Timestamp }
+
+func (s capellaSubmission) BlockNumber() uint64 { return s.r.ExecutionPayload.BlockNumber }
+
+func (s capellaSubmission) GasUsed() uint64 { return s.r.ExecutionPayload.GasUsed }
+
+func (s capellaSubmission) GasLimit() uint64 { return s.r.ExecutionPayload.GasLimit }
+
+func (s capellaSubmission) Random() string {
+	return fmt.Sprintf("%#x", s.r.ExecutionPayload.PrevRandao)
+}
+
+func (s capellaSubmission) NumTx() int { return len(s.r.ExecutionPayload.Transactions) }
+
+func (s capellaSubmission) Transactions() [][]byte {
+	txs := make([][]byte, 0, len(s.r.ExecutionPayload.Transactions))
+	for _, tx := range s.r.ExecutionPayload.Transactions {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func (s capellaSubmission) Withdrawals() []*consensuscapella.Withdrawal {
+	return s.r.ExecutionPayload.Withdrawals
+}
+
+// ElectraSubmitBlockRequest is a placeholder for the electra block submission, until the pinned go-builder-client and
+// go-eth2-client have the deneb and electra types. It carries the capella execution payload, so the blob gas fields of
+// the payload are dropped, and keeps the blobs bundle and execution requests as raw JSON. Only decoded when
+// ElectraEnabled is set, and not meant for production.
+type ElectraSubmitBlockRequest struct {
+	Message           *apiv1.BidTrace
+	ExecutionPayload  *consensuscapella.ExecutionPayload
+	BlobsBundle       json.RawMessage
+	ExecutionRequests json.RawMessage
+	Signature         phase0.BLSSignature
+}
+
+// electraSubmitBlockRequestJSON has the fields an electra submission adds to the capella one
+type electraSubmitBlockRequestJSON struct {
+	BlobsBundle       json.RawMessage `json:"blobs_bundle,omitempty"`
+	ExecutionRequests json.RawMessage `json:"execution_requests"`
+}
+
+func (e *ElectraSubmitBlockRequest) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.capella())
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["execution_requests"] = e.ExecutionRequests
+	if len(e.BlobsBundle) > 0 {
+		fields["blobs_bundle"] = e.BlobsBundle
+	}
+	return json.Marshal(fields)
+}
+
+func (e *ElectraSubmitBlockRequest) UnmarshalJSON(data []byte) error {
+	electra := new(electraSubmitBlockRequestJSON)
+	if err := json.Unmarshal(data, electra); err != nil {
+		return err
+	}
+	if len(electra.ExecutionRequests) == 0 || string(electra.ExecutionRequests) == "null" {
+		return ErrMissingExecutionRequests
+	}
+	capella := new(capella.SubmitBlockRequest)
+	if err := json.Unmarshal(data, capella); err != nil {
+		return err
+	}
+	*e = ElectraSubmitBlockRequest{
+		Message:           capella.Message,
+		ExecutionPayload:  capella.ExecutionPayload,
+		BlobsBundle:       electra.BlobsBundle,
+		ExecutionRequests: electra.ExecutionRequests,
+		Signature:         capella.Signature,
+	}
+	return nil
+}
+
+func (e *ElectraSubmitBlockRequest) capella() *capella.SubmitBlockRequest {
+	return &capella.SubmitBlockRequest{
+		Message:          e.Message,
+		ExecutionPayload: e.ExecutionPayload,
+		Signature:        e.Signature,
+	}
+}
+
+// electraSubmission reads the fields electra shares with capella from the capella submission
+type electraSubmission struct {
+	capellaSubmission
+}
+
+func (s electraSubmission) Version() string { return ForkVersionElectra }
+
+// emptySubmission is used when no fork is set, and returns zero values
+type emptySubmission struct{}
+
+func (emptySubmission) Version() string                             { return "" }
+func (emptySubmission) HasExecutionPayload() bool                   { return false }
+func (emptySubmission) ExecutionPayloadJSON() ([]byte, error)       { return nil, ErrEmptyPayload }
+func (emptySubmission) Message() *apiv1.BidTrace                    { return nil }
+func (emptySubmission) Signature() phase0.BLSSignature              { return phase0.BLSSignature{} }
+func (emptySubmission) Slot() uint64                                { return 0 }
+func (emptySubmission) BlockHash() string                           { return "" }
+func (emptySubmission) ParentHash() string                          { return "" }
+func (emptySubmission) BuilderPubkey() phase0.BLSPubKey             { return phase0.BLSPubKey{} }
+func (emptySubmission) ProposerPubkey() string                      { return "" }
+func (emptySubmission) ProposerFeeRecipient() string                { return "" }
+func (emptySubmission) Value() *big.Int                             { return nil }
+func (emptySubmission) ExecutionPayloadBlockHash() string           { return "" }
+func (emptySubmission) ExecutionPayloadParentHash() string          { return "" }
+func (emptySubmission) ExecutionPayloadFeeRecipient() string        { return "" }
+func (emptySubmission) Timestamp() uint64                           { return 0 }
+func (emptySubmission) BlockNumber() uint64                         { return 0 }
+func (emptySubmission) GasUsed() uint64                             { return 0 }
+func (emptySubmission) GasLimit() uint64                            { return 0 }
+func (emptySubmission) Random() string                              { return "" }
+func (emptySubmission) NumTx() int                                  { return 0 }
+func (emptySubmission) Transactions() [][]byte                      { return [][]byte{} }
+func (emptySubmission) Withdrawals() []*consensuscapella.Withdrawal { return nil }
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/buger/jsonparser"
+)
+
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+// decodeCapellaSubmitBlockRequest decodes a capella block submission faster than encoding/json, which scans the
+// transactions once per nested type with a custom unmarshaller. The transactions, most of the size of a submission, are
+// hex-decoded here in a single pass, and the rest of the submission is decoded by the capella types, so the validation
+// is the same. The data has to be valid JSON, as encoding/json checks before calling UnmarshalJSON, so it isn't checked
+// again. Any error means the submission has to be decoded the regular way.
+func decodeCapellaSubmitBlockRequest(data []byte) (*capella.SubmitBlockRequest, error) {
+	txsJSON, dataType, end, err := jsonparser.Get(data, "execution_payload", "transactions")
+	if err != nil {
+		return nil, err
+	} else if dataType != jsonparser.Array {
+		return nil, ErrInvalidTransaction
+	}
+	start := end - len(txsJSON)
+
+	transactions := make([]bellatrix.Transaction, 0)
+	var txErr error
+	_, err = jsonparser.ArrayEach(txsJSON, func(value []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if txErr != nil {
+			return
+		}
+		// escaped strings are left to encoding/json
+		if dataType != jsonparser.String || len(value) == 0 || bytes.IndexByte(value, '\\') != -1 {
+			txErr = ErrInvalidTransaction
+			return
+		}
+		value = bytes.TrimPrefix(value, []byte("0x"))
+		tx := make([]byte, hex.DecodedLen(len(value)))
+		if _, err := hex.Decode(tx, value); err != nil {
+			txErr = err
+			return
+		}
+		transactions = append(transactions, tx)
+	})
+	if err != nil {
+		return nil, err
+	} else if txErr != nil {
+		return nil, txErr
+	}
+
+	// the submission without the transactions
+	trimmed := make([]byte, 0, len(data)-len(txsJSON)+2)
+	trimmed = append(trimmed, data[:start]...)
+	trimmed = append(trimmed, "[]"...)
+	trimmed = append(trimmed, data[end:]...)
+	request := new(capella.SubmitBlockRequest)
+	if err := json.Unmarshal(trimmed, request); err != nil {
+		return nil, err
+	}
+	request.ExecutionPayload.Transactions = transactions
+	return request, nil
+}
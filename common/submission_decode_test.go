@@ -0,0 +1,122 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func testCapellaSubmitBlockRequestJSON(t testing.TB, txs []bellatrix.Transaction) []byte {
+	t.Helper()
+	data, err := json.Marshal(&capella.SubmitBlockRequest{
+		Message: &apiv1.BidTrace{Slot: 123, Value: uint256.NewInt(456)},
+		ExecutionPayload: &consensuscapella.ExecutionPayload{
+			GasUsed:      100,
+			ExtraData:    []byte{},
+			Transactions: txs,
+			Withdrawals:  []*consensuscapella.Withdrawal{{Index: 1, Amount: 2}},
+		},
+		Signature: phase0.BLSSignature{1},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func randomTransactions(num, size int) []bellatrix.Transaction {
+	r := rand.New(rand.NewSource(1)) //nolint:gosec
+	txs := make([]bellatrix.Transaction, num)
+	for i := range txs {
+		txs[i] = make([]byte, size)
+		r.Read(txs[i])
+	}
+	return txs
+}
+
+func TestDecodeCapellaSubmitBlockRequest(t *testing.T) {
+	t.Run("same as encoding/json", func(t *testing.T) {
+		for _, txs := range [][]bellatrix.Transaction{{}, randomTransactions(10, 200)} {
+			data := testCapellaSubmitBlockRequestJSON(t, txs)
+			expected := new(capella.SubmitBlockRequest)
+			require.NoError(t, json.Unmarshal(data, expected))
+
+			request, err := decodeCapellaSubmitBlockRequest(data)
+			require.NoError(t, err)
+			require.Equal(t, expected, request)
+
+			payload := new(BuilderSubmitBlockRequest)
+			require.NoError(t, json.Unmarshal(data, payload))
+			require.Equal(t, expected, payload.Capella)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		data := testCapellaSubmitBlockRequestJSON(t, []bellatrix.Transaction{{1}})
+		invalid := map[string][]byte{
+			"empty transaction":   bytes.Replace(data, []byte(`"0x01"`), []byte(`""`), 1),
+			"invalid hex":         bytes.Replace(data, []byte(`"0x01"`), []byte(`"0x0g"`), 1),
+			"missing transaction": bytes.Replace(data, []byte(`["0x01"]`), []byte(`null`), 1),
+			"missing withdrawals": bytes.Replace(data, []byte(`"withdrawals"`), []byte(`"foo"`), 1),
+			"trailing data":       append(append([]byte{}, data...), "{}"...),
+			"trailing comma":      bytes.Replace(data, []byte(`["0x01"]`), []byte(`["0x01",]`), 1),
+			"missing comma":       bytes.Replace(data, []byte(`["0x01"]`), []byte(`["0x01" "0x01"]`), 1),
+		}
+		for name, data := range invalid {
+			require.Error(t, json.Unmarshal(data, new(capella.SubmitBlockRequest)), name)
+			if json.Valid(data) {
+				_, err := decodeCapellaSubmitBlockRequest(data)
+				require.Error(t, err, name)
+			} else {
+				// encoding/json rejects invalid JSON before calling the fast path
+				require.Error(t, json.Unmarshal(data, new(BuilderSubmitBlockRequest)), name)
+			}
+		}
+	})
+
+	t.Run("escaped transaction falls back to encoding/json", func(t *testing.T) {
+		data := testCapellaSubmitBlockRequestJSON(t, []bellatrix.Transaction{{1}})
+		data = bytes.Replace(data, []byte(`"0x01"`), []byte(`"\u0030x01"`), 1)
+		_, err := decodeCapellaSubmitBlockRequest(data)
+		require.ErrorIs(t, err, ErrInvalidTransaction)
+
+		payload := new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(data, payload))
+		require.Equal(t, [][]byte{{1}}, payload.Transactions())
+	})
+}
+
+// BenchmarkDecodeSubmitBlockRequest compares the decoding of submitBlock bodies with and without the fast path, both
+// through json.Unmarshal like the handler
+func BenchmarkDecodeSubmitBlockRequest(b *testing.B) {
+	// about 10 MB, a large block
+	data := testCapellaSubmitBlockRequestJSON(b, randomTransactions(1000, 5000))
+
+	b.Run("capella.SubmitBlockRequest", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if err := json.Unmarshal(data, new(capella.SubmitBlockRequest)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("BuilderSubmitBlockRequest", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			payload := new(BuilderSubmitBlockRequest)
+			if err := json.Unmarshal(data, payload); err != nil {
+				b.Fatal(err)
+			} else if payload.Capella == nil {
+				b.Fatal("not decoded as capella")
+			}
+		}
+	})
+}
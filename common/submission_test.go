@@ -0,0 +1,81 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderSubmitBlockRequestVersioned(t *testing.T) {
+	capellaJSON, err := json.Marshal(&capella.SubmitBlockRequest{
+		Message: &apiv1.BidTrace{Slot: 123, Value: uint256.NewInt(456)},
+		ExecutionPayload: &consensuscapella.ExecutionPayload{
+			GasUsed:      100,
+			ExtraData:    []byte{},
+			Transactions: []bellatrix.Transaction{},
+			Withdrawals:  []*consensuscapella.Withdrawal{},
+		},
+	})
+	require.NoError(t, err)
+
+	fields := make(map[string]json.RawMessage)
+	require.NoError(t, json.Unmarshal(capellaJSON, &fields))
+	fields["execution_requests"] = json.RawMessage(`{"deposits":[],"withdrawals":[],"consolidations":[]}`)
+	electraJSON, err := json.Marshal(fields)
+	require.NoError(t, err)
+
+	t.Run("empty", func(t *testing.T) {
+		payload := new(BuilderSubmitBlockRequest)
+		require.Equal(t, "", payload.Versioned().Version())
+		require.Equal(t, uint64(0), payload.Slot())
+		require.False(t, payload.HasExecutionPayload())
+		_, err := payload.Versioned().ExecutionPayloadJSON()
+		require.ErrorIs(t, err, ErrEmptyPayload)
+	})
+
+	t.Run("capella", func(t *testing.T) {
+		payload := new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(capellaJSON, payload))
+		require.NotNil(t, payload.Capella)
+		require.Equal(t, ForkVersionCapella, payload.Versioned().Version())
+		require.Equal(t, uint64(123), payload.Slot())
+		require.Equal(t, "456", payload.Value().String())
+		require.Equal(t, uint64(100), payload.GasUsed())
+	})
+
+	t.Run("electra submission without electra enabled", func(t *testing.T) {
+		payload := new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(electraJSON, payload))
+		require.Nil(t, payload.Electra)
+		require.Equal(t, ForkVersionCapella, payload.Versioned().Version())
+	})
+
+	t.Run("electra enabled", func(t *testing.T) {
+		ElectraEnabled = true
+		defer func() { ElectraEnabled = false }()
+
+		payload := new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(electraJSON, payload))
+		require.NotNil(t, payload.Electra)
+		require.Nil(t, payload.Capella)
+		require.Equal(t, ForkVersionElectra, payload.Versioned().Version())
+		require.Equal(t, uint64(123), payload.Slot())
+		require.Equal(t, uint64(100), payload.GasUsed())
+
+		data, err := json.Marshal(payload)
+		require.NoError(t, err)
+		require.JSONEq(t, string(electraJSON), string(data))
+
+		// capella submissions have no execution requests
+		payload = new(BuilderSubmitBlockRequest)
+		require.NoError(t, json.Unmarshal(capellaJSON, payload))
+		require.Nil(t, payload.Electra)
+		require.Equal(t, ForkVersionCapella, payload.Versioned().Version())
+	})
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	ssz "github.com/ferranbt/fastssz"
 	boostTypes "github.com/flashbots/go-boost-utils/types"
 	"github.com/holiman/uint256"
 )
@@ -65,10 +67,16 @@ type EthNetworkDetails struct {
 	GenesisValidatorsRootHex string
 	BellatrixForkVersionHex  string
 	CapellaForkVersionHex    string
+	DenebForkVersionHex      string // empty if not known for the network
 
 	DomainBuilder                 boostTypes.Domain
 	DomainBeaconProposerBellatrix boostTypes.Domain
 	DomainBeaconProposerCapella   boostTypes.Domain
+	DomainBeaconProposerDeneb     boostTypes.Domain // zero if the deneb fork version is not known
+
+	// ForkEpochs maps fork versions to the epochs from a network config. The fork schedule of the beacon node takes
+	// precedence, the built-in networks only use the fork schedule.
+	ForkEpochs map[string]uint64
 }
 
 var (
@@ -83,6 +91,10 @@ var (
 	CapellaForkVersionGoerli  = "0x03001020"
 	CapellaForkVersionMainnet = "0x03000000"
 
+	DenebForkVersionSepolia = "0x90000073"
+	DenebForkVersionGoerli  = "0x04001020"
+	DenebForkVersionMainnet = "0x04000000"
+
 	// Zhejiang details
 	GenesisForkVersionZhejiang    = "0x00000069"
 	GenesisValidatorsRootZhejiang = "0x53a92d8f2bb1d85f62d16a156e6ebcd1bcaba652d0900b2c2f387826f3481f6f"
@@ -95,9 +107,11 @@ func NewEthNetworkDetails(networkName string) (ret *EthNetworkDetails, err error
 	var genesisValidatorsRoot string
 	var bellatrixForkVersion string
 	var capellaForkVersion string
+	var denebForkVersion string
 	var domainBuilder boostTypes.Domain
 	var domainBeaconProposerBellatrix boostTypes.Domain
 	var domainBeaconProposerCapella boostTypes.Domain
+	var domainBeaconProposerDeneb boostTypes.Domain
 
 	switch networkName {
 	case EthNetworkRopsten:
@@ -110,23 +124,33 @@ func NewEthNetworkDetails(networkName string) (ret *EthNetworkDetails, err error
 		genesisValidatorsRoot = boostTypes.GenesisValidatorsRootSepolia
 		bellatrixForkVersion = boostTypes.BellatrixForkVersionSepolia
 		capellaForkVersion = CapellaForkVersionSepolia
+		denebForkVersion = DenebForkVersionSepolia
 	case EthNetworkGoerli:
 		genesisForkVersion = boostTypes.GenesisForkVersionGoerli
 		genesisValidatorsRoot = boostTypes.GenesisValidatorsRootGoerli
 		bellatrixForkVersion = boostTypes.BellatrixForkVersionGoerli
 		capellaForkVersion = CapellaForkVersionGoerli
+		denebForkVersion = DenebForkVersionGoerli
 	case EthNetworkMainnet:
 		genesisForkVersion = boostTypes.GenesisForkVersionMainnet
 		genesisValidatorsRoot = boostTypes.GenesisValidatorsRootMainnet
 		bellatrixForkVersion = boostTypes.BellatrixForkVersionMainnet
 		capellaForkVersion = CapellaForkVersionMainnet
+		denebForkVersion = DenebForkVersionMainnet
 	case EthNetworkZhejiang:
 		genesisForkVersion = GenesisForkVersionZhejiang
 		genesisValidatorsRoot = GenesisValidatorsRootZhejiang
 		bellatrixForkVersion = BellatrixForkVersionZhejiang
 		capellaForkVersion = CapellaForkVersionZhejiang
 	default:
-		return nil, fmt.Errorf("%w: %s", ErrUnknownNetwork, networkName)
+		if !IsNetworkConfigLocation(networkName) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNetwork, networkName)
+		}
+		config, err := LoadNetworkConfig(networkName)
+		if err != nil {
+			return nil, err
+		}
+		return config.EthNetworkDetails()
 	}
 
 	domainBuilder, err = ComputeDomain(boostTypes.DomainTypeAppBuilder, genesisForkVersion, boostTypes.Root{}.String())
@@ -144,18 +168,40 @@ func NewEthNetworkDetails(networkName string) (ret *EthNetworkDetails, err error
 		return nil, err
 	}
 
+	if denebForkVersion != "" {
+		domainBeaconProposerDeneb, err = ComputeDomain(boostTypes.DomainTypeBeaconProposer, denebForkVersion, genesisValidatorsRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &EthNetworkDetails{
 		Name:                          networkName,
 		GenesisForkVersionHex:         genesisForkVersion,
 		GenesisValidatorsRootHex:      genesisValidatorsRoot,
 		BellatrixForkVersionHex:       bellatrixForkVersion,
 		CapellaForkVersionHex:         capellaForkVersion,
+		DenebForkVersionHex:           denebForkVersion,
 		DomainBuilder:                 domainBuilder,
 		DomainBeaconProposerBellatrix: domainBeaconProposerBellatrix,
 		DomainBeaconProposerCapella:   domainBeaconProposerCapella,
+		DomainBeaconProposerDeneb:     domainBeaconProposerDeneb,
 	}, nil
 }
 
+// ValidatorPreferences are the preferences of an upcoming proposer, as registered with the relay
+type ValidatorPreferences struct {
+	FeeRecipient string `json:"fee_recipient"`
+	GasLimit     uint64 `json:"gas_limit,string"`
+}
+
+// BuilderGetValidatorsResponseEntryWithPreferences is a proposer duty along with the preferences of the proposer, so
+// builders don't need to read them from the signed registration
+type BuilderGetValidatorsResponseEntryWithPreferences struct {
+	boostTypes.BuilderGetValidatorsResponseEntry
+	Preferences ValidatorPreferences `json:"preferences"`
+}
+
 type BidTraceV2 struct {
 	apiv1.BidTrace
 	BlockNumber uint64 `json:"block_number,string" db:"block_number"`
@@ -245,10 +291,23 @@ func (b *BidTraceV2JSON) ToCSVRecord() []string {
 	}
 }
 
+// BidTraceV2WithTimingJSON is a delivered payload of the data API, with the timing of the delivery in ms. Unknown
+// timings are omitted, e.g. for payloads delivered before the timing was recorded.
+type BidTraceV2WithTimingJSON struct {
+	BidTraceV2JSON
+	GetHeaderMsIntoSlot  *int64 `json:"get_header_ms_into_slot,string,omitempty"`
+	GetPayloadMsIntoSlot *int64 `json:"get_payload_ms_into_slot,string,omitempty"`
+	PublishDurationMs    *int64 `json:"publish_duration_ms,string,omitempty"`
+	BroadcastDurationMs  *int64 `json:"broadcast_duration_ms,string,omitempty"`
+	GetHeaderPolicy      string `json:"get_header_policy,omitempty"` // e.g. "hold_until=1000ms", see the getHeader delay policy
+}
+
 type BidTraceV2WithTimestampJSON struct {
 	BidTraceV2JSON
-	Timestamp   int64 `json:"timestamp,string,omitempty"`
-	TimestampMs int64 `json:"timestamp_ms,string,omitempty"`
+	Timestamp            int64 `json:"timestamp,string,omitempty"`
+	TimestampMs          int64 `json:"timestamp_ms,string,omitempty"`
+	OptimisticSubmission bool  `json:"optimistic_submission"`
+	WasCancelled         bool  `json:"was_cancelled"`
 }
 
 func (b *BidTraceV2WithTimestampJSON) CSVHeader() []string {
@@ -266,6 +325,8 @@ func (b *BidTraceV2WithTimestampJSON) CSVHeader() []string {
 		"block_number",
 		"timestamp",
 		"timestamp_ms",
+		"optimistic_submission",
+		"was_cancelled",
 	}
 }
 
@@ -284,15 +345,21 @@ func (b *BidTraceV2WithTimestampJSON) ToCSVRecord() []string {
 		fmt.Sprint(b.BlockNumber),
 		fmt.Sprint(b.Timestamp),
 		fmt.Sprint(b.TimestampMs),
+		fmt.Sprint(b.OptimisticSubmission),
+		fmt.Sprint(b.WasCancelled),
 	}
 }
 
 type SignedBlindedBeaconBlock struct {
 	Bellatrix *boostTypes.SignedBlindedBeaconBlock
 	Capella   *apiv1capella.SignedBlindedBeaconBlock
+	Deneb     *DenebSignedBlindedBeaconBlock
 }
 
 func (s *SignedBlindedBeaconBlock) MarshalJSON() ([]byte, error) {
+	if s.Deneb != nil {
+		return json.Marshal(s.Deneb)
+	}
 	if s.Capella != nil {
 		return json.Marshal(s.Capella)
 	}
@@ -303,6 +370,9 @@ func (s *SignedBlindedBeaconBlock) MarshalJSON() ([]byte, error) {
 }
 
 func (s *SignedBlindedBeaconBlock) Slot() uint64 {
+	if s.Deneb != nil {
+		return uint64(s.Deneb.Message.Slot)
+	}
 	if s.Capella != nil {
 		return uint64(s.Capella.Message.Slot)
 	}
@@ -313,6 +383,9 @@ func (s *SignedBlindedBeaconBlock) Slot() uint64 {
 }
 
 func (s *SignedBlindedBeaconBlock) BlockHash() string {
+	if s.Deneb != nil {
+		return s.Deneb.Message.Body.ExecutionPayloadHeader.Capella.BlockHash.String()
+	}
 	if s.Capella != nil {
 		return s.Capella.Message.Body.ExecutionPayloadHeader.BlockHash.String()
 	}
@@ -323,6 +396,9 @@ func (s *SignedBlindedBeaconBlock) BlockHash() string {
 }
 
 func (s *SignedBlindedBeaconBlock) BlockNumber() uint64 {
+	if s.Deneb != nil {
+		return s.Deneb.Message.Body.ExecutionPayloadHeader.Capella.BlockNumber
+	}
 	if s.Capella != nil {
 		return s.Capella.Message.Body.ExecutionPayloadHeader.BlockNumber
 	}
@@ -333,6 +409,9 @@ func (s *SignedBlindedBeaconBlock) BlockNumber() uint64 {
 }
 
 func (s *SignedBlindedBeaconBlock) ProposerIndex() uint64 {
+	if s.Deneb != nil {
+		return uint64(s.Deneb.Message.ProposerIndex)
+	}
 	if s.Capella != nil {
 		return uint64(s.Capella.Message.ProposerIndex)
 	}
@@ -343,6 +422,9 @@ func (s *SignedBlindedBeaconBlock) ProposerIndex() uint64 {
 }
 
 func (s *SignedBlindedBeaconBlock) Signature() []byte {
+	if s.Deneb != nil {
+		return s.Deneb.Signature[:]
+	}
 	if s.Capella != nil {
 		return s.Capella.Signature[:]
 	}
@@ -354,6 +436,9 @@ func (s *SignedBlindedBeaconBlock) Signature() []byte {
 
 //nolint:nolintlint,ireturn
 func (s *SignedBlindedBeaconBlock) Message() boostTypes.HashTreeRoot {
+	if s.Deneb != nil {
+		return s.Deneb.Message
+	}
 	if s.Capella != nil {
 		return s.Capella.Message
 	}
@@ -366,9 +451,13 @@ func (s *SignedBlindedBeaconBlock) Message() boostTypes.HashTreeRoot {
 type SignedBeaconBlock struct {
 	Bellatrix *boostTypes.SignedBeaconBlock
 	Capella   *consensuscapella.SignedBeaconBlock
+	Deneb     *DenebSignedBlockContents
 }
 
 func (s *SignedBeaconBlock) MarshalJSON() ([]byte, error) {
+	if s.Deneb != nil {
+		return json.Marshal(s.Deneb)
+	}
 	if s.Capella != nil {
 		return json.Marshal(s.Capella)
 	}
@@ -379,6 +468,9 @@ func (s *SignedBeaconBlock) MarshalJSON() ([]byte, error) {
 }
 
 func (s *SignedBeaconBlock) Slot() uint64 {
+	if s.Deneb != nil {
+		return uint64(s.Deneb.SignedBlock.Message.Slot)
+	}
 	if s.Capella != nil {
 		return uint64(s.Capella.Message.Slot)
 	}
@@ -389,6 +481,9 @@ func (s *SignedBeaconBlock) Slot() uint64 {
 }
 
 func (s *SignedBeaconBlock) BlockHash() string {
+	if s.Deneb != nil {
+		return s.Deneb.SignedBlock.Message.Body.ExecutionPayload.Capella.BlockHash.String()
+	}
 	if s.Capella != nil {
 		return s.Capella.Message.Body.ExecutionPayload.BlockHash.String()
 	}
@@ -477,9 +572,13 @@ func (e *ExecutionPayload) Timestamp() uint64 {
 type VersionedExecutionPayload struct {
 	Bellatrix *boostTypes.GetPayloadResponse
 	Capella   *api.VersionedExecutionPayload
+	Deneb     *DenebGetPayloadResponse
 }
 
 func (e *VersionedExecutionPayload) MarshalJSON() ([]byte, error) {
+	if e.Deneb != nil {
+		return json.Marshal(e.Deneb)
+	}
 	if e.Capella != nil {
 		return json.Marshal(e.Capella)
 	}
@@ -491,6 +590,14 @@ func (e *VersionedExecutionPayload) MarshalJSON() ([]byte, error) {
 }
 
 func (e *VersionedExecutionPayload) UnmarshalJSON(data []byte) error {
+	if isDenebVersion(data) {
+		deneb := new(DenebGetPayloadResponse)
+		if err := json.Unmarshal(data, deneb); err != nil {
+			return err
+		}
+		e.Deneb = deneb
+		return nil
+	}
 	capella := new(api.VersionedExecutionPayload)
 	err := json.Unmarshal(data, capella)
 	if err == nil && capella.Capella != nil {
@@ -506,7 +613,29 @@ func (e *VersionedExecutionPayload) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Transactions returns the RLP-encoded transactions of the execution payload
+func (e *VersionedExecutionPayload) Transactions() [][]byte {
+	txs := [][]byte{}
+	if e.Deneb != nil && e.Deneb.Data != nil {
+		for _, tx := range e.Deneb.Data.ExecutionPayload.Capella.Transactions {
+			txs = append(txs, tx)
+		}
+	} else if e.Capella != nil && e.Capella.Capella != nil {
+		for _, tx := range e.Capella.Capella.Transactions {
+			txs = append(txs, tx)
+		}
+	} else if e.Bellatrix != nil && e.Bellatrix.Data != nil {
+		for _, tx := range e.Bellatrix.Data.Transactions {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
 func (e *VersionedExecutionPayload) NumTx() int {
+	if e.Deneb != nil {
+		return len(e.Deneb.Data.ExecutionPayload.Capella.Transactions)
+	}
 	if e.Capella != nil {
 		return len(e.Capella.Capella.Transactions)
 	}
@@ -529,9 +658,17 @@ func (e *ExecutionPayload) NumTx() int {
 type BuilderSubmitBlockRequest struct {
 	Bellatrix *boostTypes.BuilderSubmitBlockRequest
 	Capella   *capella.SubmitBlockRequest
+	Deneb     *DenebSubmitBlockRequest
+	Electra   *ElectraSubmitBlockRequest
 }
 
 func (b *BuilderSubmitBlockRequest) MarshalJSON() ([]byte, error) {
+	if b.Electra != nil {
+		return json.Marshal(b.Electra)
+	}
+	if b.Deneb != nil {
+		return json.Marshal(b.Deneb)
+	}
 	if b.Capella != nil {
 		return json.Marshal(b.Capella)
 	}
@@ -542,6 +679,27 @@ func (b *BuilderSubmitBlockRequest) MarshalJSON() ([]byte, error) {
 }
 
 func (b *BuilderSubmitBlockRequest) UnmarshalJSON(data []byte) error {
+	if ElectraEnabled {
+		electra := new(ElectraSubmitBlockRequest)
+		if err := json.Unmarshal(data, electra); err == nil {
+			b.Electra = electra
+			return nil
+		}
+	}
+	// the capella decoding ignores the blobs bundle, so deneb submissions have to be decoded first. Bodies with a blobs
+	// bundle are deneb submissions, the field name can't be part of the hex of the other fields.
+	if bytes.Contains(data, []byte(`"blobs_bundle"`)) {
+		deneb := new(DenebSubmitBlockRequest)
+		if err := json.Unmarshal(data, deneb); err != nil {
+			return err
+		}
+		b.Deneb = deneb
+		return nil
+	}
+	if capella, err := decodeCapellaSubmitBlockRequest(data); err == nil {
+		b.Capella = capella
+		return nil
+	}
 	capella := new(capella.SubmitBlockRequest)
 	err := json.Unmarshal(data, capella)
 	if err == nil {
@@ -558,183 +716,85 @@ func (b *BuilderSubmitBlockRequest) UnmarshalJSON(data []byte) error {
 }
 
 func (b *BuilderSubmitBlockRequest) HasExecutionPayload() bool {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload != nil
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload != nil
-	}
-	return false
+	return b.Versioned().HasExecutionPayload()
 }
 
 func (b *BuilderSubmitBlockRequest) Slot() uint64 {
-	if b.Capella != nil {
-		return b.Capella.Message.Slot
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.Message.Slot
-	}
-	return 0
+	return b.Versioned().Slot()
 }
 
 func (b *BuilderSubmitBlockRequest) BlockHash() string {
-	if b.Capella != nil {
-		return b.Capella.Message.BlockHash.String()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.Message.BlockHash.String()
-	}
-	return ""
+	return b.Versioned().BlockHash()
 }
 
 func (b *BuilderSubmitBlockRequest) ExecutionPayloadBlockHash() string {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.BlockHash.String()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.BlockHash.String()
-	}
-	return ""
+	return b.Versioned().ExecutionPayloadBlockHash()
 }
 
 func (b *BuilderSubmitBlockRequest) BuilderPubkey() phase0.BLSPubKey {
-	if b.Capella != nil {
-		return b.Capella.Message.BuilderPubkey
-	}
-	if b.Bellatrix != nil {
-		return phase0.BLSPubKey(b.Bellatrix.Message.BuilderPubkey)
-	}
-	return phase0.BLSPubKey{}
+	return b.Versioned().BuilderPubkey()
 }
 
 func (b *BuilderSubmitBlockRequest) ProposerFeeRecipient() string {
-	if b.Capella != nil {
-		return b.Capella.Message.ProposerFeeRecipient.String()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.Message.ProposerFeeRecipient.String()
-	}
-	return ""
+	return b.Versioned().ProposerFeeRecipient()
 }
 
 func (b *BuilderSubmitBlockRequest) Timestamp() uint64 {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.Timestamp
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.Timestamp
-	}
-	return 0
+	return b.Versioned().Timestamp()
 }
 
 func (b *BuilderSubmitBlockRequest) ProposerPubkey() string {
-	if b.Capella != nil {
-		return b.Capella.Message.ProposerPubkey.String()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.Message.ProposerPubkey.String()
-	}
-	return ""
+	return b.Versioned().ProposerPubkey()
 }
 
 func (b *BuilderSubmitBlockRequest) ParentHash() string {
-	if b.Capella != nil {
-		return b.Capella.Message.ParentHash.String()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.Message.ParentHash.String()
-	}
-	return ""
+	return b.Versioned().ParentHash()
 }
 
 func (b *BuilderSubmitBlockRequest) ExecutionPayloadParentHash() string {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.ParentHash.String()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.ParentHash.String()
-	}
-	return ""
+	return b.Versioned().ExecutionPayloadParentHash()
 }
 
 func (b *BuilderSubmitBlockRequest) Value() *big.Int {
-	if b.Capella != nil {
-		return b.Capella.Message.Value.ToBig()
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.Message.Value.BigInt()
-	}
-	return nil
+	return b.Versioned().Value()
 }
 
 func (b *BuilderSubmitBlockRequest) NumTx() int {
-	if b.Capella != nil {
-		return len(b.Capella.ExecutionPayload.Transactions)
-	}
-	if b.Bellatrix != nil {
-		return len(b.Bellatrix.ExecutionPayload.Transactions)
-	}
-	return 0
+	return b.Versioned().NumTx()
+}
+
+// Transactions returns the RLP-encoded transactions of the execution payload
+func (b *BuilderSubmitBlockRequest) Transactions() [][]byte {
+	return b.Versioned().Transactions()
+}
+
+// ExecutionPayloadFeeRecipient returns the coinbase of the block, i.e. usually the builder's address
+func (b *BuilderSubmitBlockRequest) ExecutionPayloadFeeRecipient() string {
+	return b.Versioned().ExecutionPayloadFeeRecipient()
 }
 
 func (b *BuilderSubmitBlockRequest) BlockNumber() uint64 {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.BlockNumber
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.BlockNumber
-	}
-	return 0
+	return b.Versioned().BlockNumber()
 }
 
 func (b *BuilderSubmitBlockRequest) GasUsed() uint64 {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.GasUsed
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.GasUsed
-	}
-	return 0
+	return b.Versioned().GasUsed()
 }
 
 func (b *BuilderSubmitBlockRequest) GasLimit() uint64 {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.GasLimit
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.GasLimit
-	}
-	return 0
+	return b.Versioned().GasLimit()
 }
 
 func (b *BuilderSubmitBlockRequest) Signature() phase0.BLSSignature {
-	if b.Capella != nil {
-		return b.Capella.Signature
-	}
-	if b.Bellatrix != nil {
-		return phase0.BLSSignature(b.Bellatrix.Signature)
-	}
-	return phase0.BLSSignature{}
+	return b.Versioned().Signature()
 }
 
 func (b *BuilderSubmitBlockRequest) Random() string {
-	if b.Capella != nil {
-		return fmt.Sprintf("%#x", b.Capella.ExecutionPayload.PrevRandao)
-	}
-	if b.Bellatrix != nil {
-		return b.Bellatrix.ExecutionPayload.Random.String()
-	}
-	return ""
+	return b.Versioned().Random()
 }
 
 func (b *BuilderSubmitBlockRequest) Message() *apiv1.BidTrace {
-	if b.Capella != nil {
-		return b.Capella.Message
-	}
-	if b.Bellatrix != nil {
-		return BoostBidToBidTrace(b.Bellatrix.Message)
-	}
-	return nil
+	return b.Versioned().Message()
 }
 
 func BidTraceToBoostBid(bidTrace *apiv1.BidTrace) *boostTypes.BidTrace {
@@ -768,9 +828,18 @@ func BoostBidToBidTrace(bidTrace *boostTypes.BidTrace) *apiv1.BidTrace {
 type GetPayloadResponse struct {
 	Bellatrix *boostTypes.GetPayloadResponse
 	Capella   *api.VersionedExecutionPayload
+	Deneb     *DenebGetPayloadResponse
 }
 
 func (p *GetPayloadResponse) UnmarshalJSON(data []byte) error {
+	if isDenebVersion(data) {
+		deneb := new(DenebGetPayloadResponse)
+		if err := json.Unmarshal(data, deneb); err != nil {
+			return err
+		}
+		p.Deneb = deneb
+		return nil
+	}
 	capella := new(api.VersionedExecutionPayload)
 	err := json.Unmarshal(data, capella)
 	if err == nil && capella.Capella != nil {
@@ -793,15 +862,27 @@ func (p *GetPayloadResponse) MarshalJSON() ([]byte, error) {
 	if p.Capella != nil {
 		return json.Marshal(p.Capella)
 	}
+	if p.Deneb != nil {
+		return json.Marshal(p.Deneb)
+	}
 	return nil, ErrEmptyPayload
 }
 
 type GetHeaderResponse struct {
 	Bellatrix *boostTypes.GetHeaderResponse
 	Capella   *spec.VersionedSignedBuilderBid
+	Deneb     *DenebGetHeaderResponse
 }
 
 func (p *GetHeaderResponse) UnmarshalJSON(data []byte) error {
+	if isDenebVersion(data) {
+		deneb := new(DenebGetHeaderResponse)
+		if err := json.Unmarshal(data, deneb); err != nil {
+			return err
+		}
+		p.Deneb = deneb
+		return nil
+	}
 	capella := new(spec.VersionedSignedBuilderBid)
 	err := json.Unmarshal(data, capella)
 	if err == nil && capella.Capella != nil {
@@ -818,6 +899,9 @@ func (p *GetHeaderResponse) UnmarshalJSON(data []byte) error {
 }
 
 func (p *GetHeaderResponse) MarshalJSON() ([]byte, error) {
+	if p.Deneb != nil {
+		return json.Marshal(p.Deneb)
+	}
 	if p.Capella != nil {
 		return json.Marshal(p.Capella)
 	}
@@ -828,6 +912,9 @@ func (p *GetHeaderResponse) MarshalJSON() ([]byte, error) {
 }
 
 func (p *GetHeaderResponse) Value() *big.Int {
+	if p.Deneb != nil {
+		return p.Deneb.Data.Message.Value.ToBig()
+	}
 	if p.Capella != nil {
 		return p.Capella.Capella.Message.Value.ToBig()
 	}
@@ -838,6 +925,9 @@ func (p *GetHeaderResponse) Value() *big.Int {
 }
 
 func (p *GetHeaderResponse) BlockHash() phase0.Hash32 {
+	if p.Deneb != nil {
+		return p.Deneb.Data.Message.Header.Capella.BlockHash
+	}
 	if p.Capella != nil {
 		return p.Capella.Capella.Message.Header.BlockHash
 	}
@@ -851,6 +941,9 @@ func (p *GetHeaderResponse) Empty() bool {
 	if p == nil {
 		return true
 	}
+	if p.Deneb != nil {
+		return p.Deneb.Data == nil || p.Deneb.Data.Message == nil
+	}
 	if p.Capella != nil {
 		return p.Capella.Capella == nil || p.Capella.Capella.Message == nil
 	}
@@ -861,8 +954,52 @@ func (p *GetHeaderResponse) Empty() bool {
 }
 
 func (b *BuilderSubmitBlockRequest) Withdrawals() []*consensuscapella.Withdrawal {
-	if b.Capella != nil {
-		return b.Capella.ExecutionPayload.Withdrawals
+	return b.Versioned().Withdrawals()
+}
+
+// SubmitHeaderRequest is a header-only (v3) block submission. The builder serves the full payload at PayloadURL once the bid wins.
+type SubmitHeaderRequest struct {
+	Message                *apiv1.BidTrace                          `json:"message"`
+	ExecutionPayloadHeader *consensuscapella.ExecutionPayloadHeader `json:"execution_payload_header"`
+	Signature              boostTypes.Signature                     `json:"signature"`
+	PayloadURL             string                                   `json:"payload_url"`
+}
+
+// PeerBidRequest is a bid shared by a peer relay. The bid is signed with the key of the peer relay, which serves the
+// payload on its getPayload endpoint once the bid wins.
+type PeerBidRequest struct {
+	Message                *PeerBid                                 `json:"message"`
+	ExecutionPayloadHeader *consensuscapella.ExecutionPayloadHeader `json:"execution_payload_header"`
+	RelayPubkey            boostTypes.PublicKey                     `json:"relay_pubkey"`
+	Signature              boostTypes.Signature                     `json:"signature"`
+}
+
+// PeerBid is the bid trace with the time the peer relay received the submission of the builder, which orders it among
+// the other bids of the builder and prevents replays
+type PeerBid struct {
+	BidTrace     *apiv1.BidTrace `json:"bid_trace"`
+	ReceivedAtMs uint64          `json:"received_at_ms,string"`
+}
+
+// HashTreeRoot is the signing root of the peer bid, hashed as the SSZ container (bid_trace, received_at_ms)
+func (b *PeerBid) HashTreeRoot() ([32]byte, error) {
+	hh := ssz.DefaultHasherPool.Get()
+	defer ssz.DefaultHasherPool.Put(hh)
+	if err := b.HashTreeRootWith(hh); err != nil {
+		return [32]byte{}, err
+	}
+	return hh.HashRoot()
+}
+
+func (b *PeerBid) HashTreeRootWith(hh ssz.HashWalker) error {
+	if b.BidTrace == nil {
+		return ErrEmptyPayload
+	}
+	indx := hh.Index()
+	if err := b.BidTrace.HashTreeRootWith(hh); err != nil {
+		return err
 	}
+	hh.PutUint64(b.ReceivedAtMs)
+	hh.Merkleize(indx)
 	return nil
 }
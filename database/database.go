@@ -3,6 +3,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,46 +14,99 @@ import (
 	"github.com/flashbots/mev-boost-relay/database/migrations"
 	"github.com/flashbots/mev-boost-relay/database/vars"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	migrate "github.com/rubenv/sql-migrate"
 )
 
 type IDatabaseService interface {
+	Ping(ctx context.Context) error
+
 	NumRegisteredValidators() (count uint64, err error)
 	SaveValidatorRegistration(entry ValidatorRegistrationEntry) error
+	SaveValidatorRegistrations(entries []ValidatorRegistrationEntry) error
 	GetLatestValidatorRegistrations(timestampOnly bool) ([]*ValidatorRegistrationEntry, error)
 	GetValidatorRegistration(pubkey string) (*ValidatorRegistrationEntry, error)
 	GetValidatorRegistrationsForPubkeys(pubkeys []string) ([]*ValidatorRegistrationEntry, error)
+	GetValidatorFeeRecipients(pubkeys []string) (map[string][]string, error)
 
-	SaveBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest, simError error, receivedAt time.Time) (entry *BuilderBlockSubmissionEntry, err error)
+	SaveBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest, simError error, receivedAt time.Time, verifiedValue string, optimisticSubmission bool) (entry *BuilderBlockSubmissionEntry, err error)
+	MarkCancelledBuilderSubmissions(entry *BuilderBlockSubmissionEntry) error
 	GetBlockSubmissionEntry(slot uint64, proposerPubkey, blockHash string) (entry *BuilderBlockSubmissionEntry, err error)
 	GetBuilderSubmissions(filters GetBuilderSubmissionsFilters) ([]*BuilderBlockSubmissionEntry, error)
 	GetBuilderSubmissionsBySlots(slotFrom, slotTo uint64) (entries []*BuilderBlockSubmissionEntry, err error)
+	StreamBuilderSubmissions(ctx context.Context, slotFrom, slotTo uint64, fn func(*BuilderBlockSubmissionEntry) error) error
 	GetExecutionPayloadEntryByID(executionPayloadID int64) (entry *ExecutionPayloadEntry, err error)
 	GetExecutionPayloadEntryBySlotPkHash(slot uint64, proposerPubkey, blockHash string) (entry *ExecutionPayloadEntry, err error)
 	GetExecutionPayloads(idFirst, idLast uint64) (entries []*ExecutionPayloadEntry, err error)
 	DeleteExecutionPayloads(idFirst, idLast uint64) error
 
-	SaveDeliveredPayload(bidTrace *common.BidTraceV2, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock) error
+	SaveDeliveredPayload(bidTrace *common.BidTraceV2, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock, timing DeliveredPayloadTiming) error
 	GetNumDeliveredPayloads() (uint64, error)
 	GetRecentDeliveredPayloads(filters GetPayloadsFilters) ([]*DeliveredPayloadEntry, error)
 	GetDeliveredPayloads(idFirst, idLast uint64) (entries []*DeliveredPayloadEntry, err error)
+	StreamDeliveredPayloads(ctx context.Context, slotFrom, slotTo uint64, fn func(*DeliveredPayloadEntry) error) error
+	SetDeliveredPayloadPublishStatus(slot uint64, proposerPubkey, blockHash string, confirmed bool, numAttempts uint64, publishDuration, broadcastDuration time.Duration) error
+	SaveGetPayloadFailure(entry GetPayloadFailureEntry) error
+	SaveBlocklistFiltered(entry BlocklistFilteredEntry) error
+	SavePeerBid(entry PeerBidEntry) error
+	SaveBidHistory(entries []*BidHistoryEntry) error
+	GetProposerAllowlist() ([]string, error)
 
 	GetBlockBuilders() ([]*BlockBuilderEntry, error)
 	GetBlockBuilderByPubkey(pubkey string) (*BlockBuilderEntry, error)
 	SetBlockBuilderStatus(pubkey string, isHighPrio, isBlacklisted bool) error
 	UpsertBlockBuilderEntryAfterSubmission(lastSubmission *BuilderBlockSubmissionEntry, isError bool) error
 	IncBlockBuilderStatsAfterGetPayload(builderPubkey string) error
+	SetBlockBuilderCollateral(pubkey, collateral string, isOptimistic bool) error
+	SetBlockBuilderAPIKeyHash(pubkey, apiKeyHash string) error
+
+	InsertBuilderDemotion(bidTrace *common.BidTraceV2, simError error) error
+	SetBuilderDemotionRefundRequired(slot uint64, blockHash string) (refundRequired bool, err error)
+	SetBuilderDemotionRefundConfirmed(builderPubkey string) (numDemotions int64, err error)
+	GetOpenBuilderDemotions() ([]*BuilderDemotionEntry, error)
+	GetNumSuccessfulBuilderSubmissions(builderPubkey string, since time.Time) (uint64, error)
+	PromoteBuilder(builderPubkey, reason string) error
+
+	InsertDataAPIKey(entry DataAPIKeyEntry) (*DataAPIKeyEntry, error)
+	GetDataAPIKeys() ([]*DataAPIKeyEntry, error)
+	RevokeDataAPIKey(name string) error
+
+	RefreshStatsViews() error
+	GetBuilderStats() ([]*BuilderStatsEntry, error)
+	GetBuilderStatsByPubkey(pubkey string) (*BuilderStatsEntry, error)
+	GetDailyStats() ([]*DailyStatsEntry, error)
+	GetEpochStats(numEpochs uint64) ([]*EpochStatsEntry, error)
+	GetTopBuilders(limit uint64) ([]*TopBuilderEntry, error)
+
+	UpdateDailyAggregates() error
+	GetDailyAggregates(numDays uint64) ([]*DailyAggregateEntry, error)
+
+	UpdateBuilderDailyAggregates(genesisTime uint64) error
+
+	GetDeliveredPayloadsAfterID(afterID, limit uint64) ([]*DeliveredPayloadEntry, error)
+	GetBuilderSubmissionsAfterID(afterID uint64, insertedBefore time.Time, limit uint64) ([]*BuilderBlockSubmissionEntry, error)
+	GetArchiverCheckpoint(sink, stream string) (*ArchiverCheckpointEntry, error)
+	SetArchiverCheckpoint(sink, stream string, lastID, lastSlot uint64) error
+
+	InsertAdminAuditLog(entry AdminAuditLogEntry) error
+	GetAdminAuditLog(target string, limit uint64) ([]*AdminAuditLogEntry, error)
 }
 
 type DatabaseService struct {
 	DB *sqlx.DB
 
+	// codec the execution payloads are written with
+	payloadCodec string
+
 	nstmtInsertExecutionPayload       *sqlx.NamedStmt
 	nstmtInsertBlockBuilderSubmission *sqlx.NamedStmt
 }
 
 func NewDatabaseService(dsn string) (*DatabaseService, error) {
+	if err := common.CheckPayloadCodec(common.PayloadCompression); err != nil {
+		return nil, err
+	}
+
 	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
 		return nil, err
@@ -70,7 +124,7 @@ func NewDatabaseService(dsn string) (*DatabaseService, error) {
 		}
 	}
 
-	dbService := &DatabaseService{DB: db} //nolint:exhaustruct
+	dbService := &DatabaseService{DB: db, payloadCodec: common.PayloadCompression} //nolint:exhaustruct
 	err = dbService.prepareNamedQueries()
 	return dbService, err
 }
@@ -78,8 +132,8 @@ func NewDatabaseService(dsn string) (*DatabaseService, error) {
 func (s *DatabaseService) prepareNamedQueries() (err error) {
 	// Insert execution payload
 	query := `INSERT INTO ` + vars.TableExecutionPayload + `
-	(slot, proposer_pubkey, block_hash, version, payload) VALUES
-	(:slot, :proposer_pubkey, :block_hash, :version, :payload)
+	(slot, proposer_pubkey, block_hash, version, payload, payload_codec, payload_compressed) VALUES
+	(:slot, :proposer_pubkey, :block_hash, :version, NULLIF(:payload, '')::json, :payload_codec, :payload_compressed)
 	ON CONFLICT (slot, proposer_pubkey, block_hash) DO UPDATE SET slot=:slot
 	RETURNING id`
 	s.nstmtInsertExecutionPayload, err = s.DB.PrepareNamed(query)
@@ -89,19 +143,32 @@ func (s *DatabaseService) prepareNamedQueries() (err error) {
 
 	// Insert block builder submission
 	query = `INSERT INTO ` + vars.TableBuilderBlockSubmission + `
-	(received_at, execution_payload_id, sim_success, sim_error, signature, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_used, gas_limit, num_tx, value, epoch, block_number) VALUES
-	(:received_at, :execution_payload_id, :sim_success, :sim_error, :signature, :slot, :parent_hash, :block_hash, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :gas_used, :gas_limit, :num_tx, :value, :epoch, :block_number)
+	(received_at, execution_payload_id, sim_success, sim_error, signature, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_used, gas_limit, num_tx, value, verified_value, optimistic_submission, epoch, block_number) VALUES
+	(:received_at, :execution_payload_id, :sim_success, :sim_error, :signature, :slot, :parent_hash, :block_hash, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :gas_used, :gas_limit, :num_tx, :value, :verified_value, :optimistic_submission, :epoch, :block_number)
 	RETURNING id`
 	s.nstmtInsertBlockBuilderSubmission, err = s.DB.PrepareNamed(query)
 	return err
 }
 
+// observeOperation records the latency of a database operation, meant to be deferred
+func observeOperation(operation string, start time.Time) {
+	common.DBOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
 func (s *DatabaseService) Close() error {
 	return s.DB.Close()
 }
 
+// Ping checks that the database is reachable
+func (s *DatabaseService) Ping(ctx context.Context) error {
+	defer observeOperation("Ping", time.Now())
+	return s.DB.PingContext(ctx)
+}
+
 // NumRegisteredValidators returns the number of unique pubkeys that have registered
 func (s *DatabaseService) NumRegisteredValidators() (count uint64, err error) {
+	defer observeOperation("NumRegisteredValidators", time.Now())
+
 	query := `SELECT COUNT(*) FROM (SELECT DISTINCT pubkey FROM ` + vars.TableValidatorRegistration + `) AS temp;`
 	row := s.DB.QueryRow(query)
 	err = row.Scan(&count)
@@ -109,6 +176,8 @@ func (s *DatabaseService) NumRegisteredValidators() (count uint64, err error) {
 }
 
 func (s *DatabaseService) NumValidatorRegistrationRows() (count uint64, err error) {
+	defer observeOperation("NumValidatorRegistrationRows", time.Now())
+
 	query := `SELECT COUNT(*) FROM ` + vars.TableValidatorRegistration + `;`
 	row := s.DB.QueryRow(query)
 	err = row.Scan(&count)
@@ -116,6 +185,8 @@ func (s *DatabaseService) NumValidatorRegistrationRows() (count uint64, err erro
 }
 
 func (s *DatabaseService) SaveValidatorRegistration(entry ValidatorRegistrationEntry) error {
+	defer observeOperation("SaveValidatorRegistration", time.Now())
+
 	query := `WITH latest_registration AS (
 		SELECT DISTINCT ON (pubkey) pubkey, fee_recipient, timestamp, gas_limit, signature FROM ` + vars.TableValidatorRegistration + ` WHERE pubkey=:pubkey ORDER BY pubkey, timestamp DESC limit 1
 	)
@@ -128,7 +199,50 @@ func (s *DatabaseService) SaveValidatorRegistration(entry ValidatorRegistrationE
 	return err
 }
 
+// SaveValidatorRegistrations saves a batch of registrations in a single statement, with the same rules as
+// SaveValidatorRegistration. Of several registrations of a validator only the latest one is saved.
+func (s *DatabaseService) SaveValidatorRegistrations(entries []ValidatorRegistrationEntry) error {
+	defer observeOperation("SaveValidatorRegistrations", time.Now())
+
+	latest := make(map[string]ValidatorRegistrationEntry, len(entries))
+	for _, entry := range entries {
+		if prev, ok := latest[entry.Pubkey]; !ok || entry.Timestamp > prev.Timestamp {
+			latest[entry.Pubkey] = entry
+		}
+	}
+	if len(latest) == 0 {
+		return nil
+	}
+
+	pubkeys := make([]string, 0, len(latest))
+	feeRecipients := make([]string, 0, len(latest))
+	timestamps := make([]int64, 0, len(latest))
+	gasLimits := make([]int64, 0, len(latest))
+	signatures := make([]string, 0, len(latest))
+	for _, entry := range latest {
+		pubkeys = append(pubkeys, entry.Pubkey)
+		feeRecipients = append(feeRecipients, entry.FeeRecipient)
+		timestamps = append(timestamps, int64(entry.Timestamp))
+		gasLimits = append(gasLimits, int64(entry.GasLimit))
+		signatures = append(signatures, entry.Signature)
+	}
+
+	query := `INSERT INTO ` + vars.TableValidatorRegistration + ` (pubkey, fee_recipient, timestamp, gas_limit, signature)
+	SELECT reg.pubkey, reg.fee_recipient, reg.timestamp, reg.gas_limit, reg.signature
+	FROM unnest($1::text[], $2::text[], $3::bigint[], $4::bigint[], $5::text[]) AS reg(pubkey, fee_recipient, timestamp, gas_limit, signature)
+	WHERE NOT EXISTS (
+		SELECT 1 FROM (
+			SELECT fee_recipient, timestamp, gas_limit FROM ` + vars.TableValidatorRegistration + ` WHERE pubkey=reg.pubkey ORDER BY timestamp DESC LIMIT 1
+		) latest_registration
+		WHERE reg.timestamp <= latest_registration.timestamp OR (reg.fee_recipient = latest_registration.fee_recipient AND reg.gas_limit = latest_registration.gas_limit)
+	);`
+	_, err := s.DB.Exec(query, pq.Array(pubkeys), pq.Array(feeRecipients), pq.Array(timestamps), pq.Array(gasLimits), pq.Array(signatures))
+	return err
+}
+
 func (s *DatabaseService) GetValidatorRegistration(pubkey string) (*ValidatorRegistrationEntry, error) {
+	defer observeOperation("GetValidatorRegistration", time.Now())
+
 	query := `SELECT DISTINCT ON (pubkey) pubkey, fee_recipient, timestamp, gas_limit, signature
 		FROM ` + vars.TableValidatorRegistration + `
 		WHERE pubkey=$1
@@ -139,6 +253,8 @@ func (s *DatabaseService) GetValidatorRegistration(pubkey string) (*ValidatorReg
 }
 
 func (s *DatabaseService) GetValidatorRegistrationsForPubkeys(pubkeys []string) (entries []*ValidatorRegistrationEntry, err error) {
+	defer observeOperation("GetValidatorRegistrationsForPubkeys", time.Now())
+
 	query := `SELECT DISTINCT ON (pubkey) pubkey, fee_recipient, timestamp, gas_limit, signature
 		FROM ` + vars.TableValidatorRegistration + `
 		WHERE pubkey IN (?)
@@ -152,7 +268,35 @@ func (s *DatabaseService) GetValidatorRegistrationsForPubkeys(pubkeys []string)
 	return entries, err
 }
 
+// GetValidatorFeeRecipients returns the fee recipients each of the validators ever registered, by pubkey. Validators
+// without registrations are missing.
+func (s *DatabaseService) GetValidatorFeeRecipients(pubkeys []string) (map[string][]string, error) {
+	defer observeOperation("GetValidatorFeeRecipients", time.Now())
+
+	query := `SELECT DISTINCT pubkey, fee_recipient
+		FROM ` + vars.TableValidatorRegistration + `
+		WHERE pubkey IN (?);`
+
+	q, args, err := sqlx.In(query, pubkeys)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*ValidatorRegistrationEntry{}
+	err = s.DB.Select(&entries, s.DB.Rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	feeRecipients := make(map[string][]string)
+	for _, entry := range entries {
+		feeRecipients[entry.Pubkey] = append(feeRecipients[entry.Pubkey], entry.FeeRecipient)
+	}
+	return feeRecipients, nil
+}
+
 func (s *DatabaseService) GetLatestValidatorRegistrations(timestampOnly bool) ([]*ValidatorRegistrationEntry, error) {
+	defer observeOperation("GetLatestValidatorRegistrations", time.Now())
+
 	// query details: https://stackoverflow.com/questions/3800551/select-first-row-in-each-group-by-group/7630564#7630564
 	query := `SELECT DISTINCT ON (pubkey) pubkey, fee_recipient, timestamp, gas_limit, signature`
 	if timestampOnly {
@@ -165,12 +309,20 @@ func (s *DatabaseService) GetLatestValidatorRegistrations(timestampOnly bool) ([
 	return registrations, err
 }
 
-func (s *DatabaseService) SaveBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest, simError error, receivedAt time.Time) (entry *BuilderBlockSubmissionEntry, err error) {
+// SaveBuilderBlockSubmission saves a submission with its simulation result. verifiedValue is the proposer payment
+// measured by the simulation, empty if unknown. optimisticSubmission is set for the submissions accepted before the
+// simulation.
+func (s *DatabaseService) SaveBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest, simError error, receivedAt time.Time, verifiedValue string, optimisticSubmission bool) (entry *BuilderBlockSubmissionEntry, err error) {
+	defer observeOperation("SaveBuilderBlockSubmission", time.Now())
+
 	// Save execution_payload: insert, or if already exists update to be able to return the id ('on conflict do nothing' doesn't return an id)
 	execPayloadEntry, err := PayloadToExecPayloadEntry(payload)
 	if err != nil {
 		return nil, err
 	}
+	if err := execPayloadEntry.compress(s.payloadCodec); err != nil {
+		return nil, err
+	}
 
 	err = s.nstmtInsertExecutionPayload.QueryRow(execPayloadEntry).Scan(&execPayloadEntry.ID)
 	if err != nil {
@@ -206,15 +358,42 @@ func (s *DatabaseService) SaveBuilderBlockSubmission(payload *common.BuilderSubm
 		NumTx: uint64(payload.NumTx()),
 		Value: payload.Value().String(),
 
+		OptimisticSubmission: optimisticSubmission,
+
 		Epoch:       payload.Slot() / uint64(common.SlotsPerEpoch),
 		BlockNumber: payload.BlockNumber(),
 	}
+	if verifiedValue != "" {
+		blockSubmissionEntry.VerifiedValue = NewNullString(verifiedValue)
+	}
 	err = s.nstmtInsertBlockBuilderSubmission.QueryRow(blockSubmissionEntry).Scan(&blockSubmissionEntry.ID)
 	return blockSubmissionEntry, err
 }
 
+// MarkCancelledBuilderSubmissions marks the submissions cancelled by the given one, i.e. the earlier submissions of the
+// builder for the same slot, parent hash and proposer with a higher value, which the lower bid replaced. The submission
+// itself is marked if a later one cancelled it already, as the submissions aren't necessarily saved in order.
+func (s *DatabaseService) MarkCancelledBuilderSubmissions(entry *BuilderBlockSubmissionEntry) error {
+	defer observeOperation("MarkCancelledBuilderSubmissions", time.Now())
+
+	query := `UPDATE ` + vars.TableBuilderBlockSubmission + ` SET was_cancelled=true
+		WHERE slot=:slot AND builder_pubkey=:builder_pubkey AND parent_hash=:parent_hash AND proposer_pubkey=:proposer_pubkey AND sim_success=true AND was_cancelled=false
+		AND (
+			(received_at < :received_at AND value > :value)
+			OR (id = :id AND EXISTS (
+				SELECT 1 FROM ` + vars.TableBuilderBlockSubmission + `
+				WHERE slot=:slot AND builder_pubkey=:builder_pubkey AND parent_hash=:parent_hash AND proposer_pubkey=:proposer_pubkey AND sim_success=true
+				AND received_at > :received_at AND value < :value
+			))
+		)`
+	_, err := s.DB.NamedExec(query, entry)
+	return err
+}
+
 func (s *DatabaseService) GetBlockSubmissionEntry(slot uint64, proposerPubkey, blockHash string) (entry *BuilderBlockSubmissionEntry, err error) {
-	query := `SELECT id, inserted_at, received_at, execution_payload_id, sim_success, sim_error, signature, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_used, gas_limit, num_tx, value, epoch, block_number
+	defer observeOperation("GetBlockSubmissionEntry", time.Now())
+
+	query := `SELECT id, inserted_at, received_at, execution_payload_id, sim_success, sim_error, signature, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_used, gas_limit, num_tx, value, verified_value, optimistic_submission, was_cancelled, epoch, block_number
 	FROM ` + vars.TableBuilderBlockSubmission + `
 	WHERE slot=$1 AND proposer_pubkey=$2 AND block_hash=$3
 	ORDER BY builder_pubkey ASC
@@ -225,22 +404,32 @@ func (s *DatabaseService) GetBlockSubmissionEntry(slot uint64, proposerPubkey, b
 }
 
 func (s *DatabaseService) GetExecutionPayloadEntryByID(executionPayloadID int64) (entry *ExecutionPayloadEntry, err error) {
-	query := `SELECT id, inserted_at, slot, proposer_pubkey, block_hash, version, payload FROM ` + vars.TableExecutionPayload + ` WHERE id=$1`
+	defer observeOperation("GetExecutionPayloadEntryByID", time.Now())
+
+	query := `SELECT id, inserted_at, slot, proposer_pubkey, block_hash, version, COALESCE(payload::text, '') AS payload, payload_codec, payload_compressed FROM ` + vars.TableExecutionPayload + ` WHERE id=$1`
 	entry = &ExecutionPayloadEntry{}
-	err = s.DB.Get(entry, query, executionPayloadID)
-	return entry, err
+	if err = s.DB.Get(entry, query, executionPayloadID); err != nil {
+		return entry, err
+	}
+	return entry, entry.decompress()
 }
 
 func (s *DatabaseService) GetExecutionPayloadEntryBySlotPkHash(slot uint64, proposerPubkey, blockHash string) (entry *ExecutionPayloadEntry, err error) {
-	query := `SELECT id, inserted_at, slot, proposer_pubkey, block_hash, version, payload
+	defer observeOperation("GetExecutionPayloadEntryBySlotPkHash", time.Now())
+
+	query := `SELECT id, inserted_at, slot, proposer_pubkey, block_hash, version, COALESCE(payload::text, '') AS payload, payload_codec, payload_compressed
 	FROM ` + vars.TableExecutionPayload + `
 	WHERE slot=$1 AND proposer_pubkey=$2 AND block_hash=$3`
 	entry = &ExecutionPayloadEntry{}
-	err = s.DB.Get(entry, query, slot, proposerPubkey, blockHash)
-	return entry, err
+	if err = s.DB.Get(entry, query, slot, proposerPubkey, blockHash); err != nil {
+		return entry, err
+	}
+	return entry, entry.decompress()
 }
 
-func (s *DatabaseService) SaveDeliveredPayload(bidTrace *common.BidTraceV2, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock) error {
+func (s *DatabaseService) SaveDeliveredPayload(bidTrace *common.BidTraceV2, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock, timing DeliveredPayloadTiming) error {
+	defer observeOperation("SaveDeliveredPayload", time.Now())
+
 	_signedBlindedBeaconBlock, err := json.Marshal(signedBlindedBeaconBlock)
 	if err != nil {
 		return err
@@ -265,17 +454,130 @@ func (s *DatabaseService) SaveDeliveredPayload(bidTrace *common.BidTraceV2, sign
 
 		NumTx: bidTrace.NumTx,
 		Value: bidTrace.Value.ToBig().String(),
+
+		GetHeaderMsIntoSlot:  timing.GetHeaderMsIntoSlot,
+		GetPayloadMsIntoSlot: timing.GetPayloadMsIntoSlot,
+		GetHeaderPolicy:      timing.GetHeaderPolicy,
 	}
 
 	query := `INSERT INTO ` + vars.TableDeliveredPayload + `
-		(signed_blinded_beacon_block, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, gas_used, gas_limit, num_tx, value) VALUES
-		(:signed_blinded_beacon_block, :slot, :epoch, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :parent_hash, :block_hash, :block_number, :gas_used, :gas_limit, :num_tx, :value)
+		(signed_blinded_beacon_block, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, gas_used, gas_limit, num_tx, value, get_header_ms_into_slot, get_payload_ms_into_slot, get_header_policy) VALUES
+		(:signed_blinded_beacon_block, :slot, :epoch, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :parent_hash, :block_hash, :block_number, :gas_used, :gas_limit, :num_tx, :value, :get_header_ms_into_slot, :get_payload_ms_into_slot, :get_header_policy)
 		ON CONFLICT DO NOTHING`
 	_, err = s.DB.NamedExec(query, deliveredPayloadEntry)
 	return err
 }
 
+// ImportDeliveredPayload saves a delivered payload of another relay, without the signed blinded beacon block. Returns
+// false if it was already saved.
+func (s *DatabaseService) ImportDeliveredPayload(entry *DeliveredPayloadEntry) (inserted bool, err error) {
+	defer observeOperation("ImportDeliveredPayload", time.Now())
+
+	query := `INSERT INTO ` + vars.TableDeliveredPayload + `
+		(slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, gas_used, gas_limit, num_tx, value) VALUES
+		(:slot, :epoch, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :parent_hash, :block_hash, :block_number, :gas_used, :gas_limit, :num_tx, :value)
+		ON CONFLICT DO NOTHING`
+	res, err := s.DB.NamedExec(query, entry)
+	if err != nil {
+		return false, err
+	}
+	numRows, err := res.RowsAffected()
+	return numRows > 0, err
+}
+
+// ImportBuilderBlockSubmission saves a block submission of another relay, without the execution payload and signature.
+// Returns false if a submission of the builder with the same slot and block hash was already saved.
+func (s *DatabaseService) ImportBuilderBlockSubmission(entry *BuilderBlockSubmissionEntry) (inserted bool, err error) {
+	defer observeOperation("ImportBuilderBlockSubmission", time.Now())
+
+	query := `INSERT INTO ` + vars.TableBuilderBlockSubmission + `
+		(received_at, sim_success, sim_error, signature, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_used, gas_limit, num_tx, value, optimistic_submission, was_cancelled, epoch, block_number)
+		SELECT :received_at, :sim_success, :sim_error, :signature, :slot, :parent_hash, :block_hash, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :gas_used, :gas_limit, :num_tx, :value, :optimistic_submission, :was_cancelled, :epoch, :block_number
+		WHERE NOT EXISTS (SELECT 1 FROM ` + vars.TableBuilderBlockSubmission + ` WHERE slot=:slot AND block_hash=:block_hash AND builder_pubkey=:builder_pubkey)`
+	res, err := s.DB.NamedExec(query, entry)
+	if err != nil {
+		return false, err
+	}
+	numRows, err := res.RowsAffected()
+	return numRows > 0, err
+}
+
+// SetDeliveredPayloadPublishStatus records the outcome of publishing a delivered payload, and returns sql.ErrNoRows if the
+// delivered payload isn't saved
+func (s *DatabaseService) SetDeliveredPayloadPublishStatus(slot uint64, proposerPubkey, blockHash string, confirmed bool, numAttempts uint64, publishDuration, broadcastDuration time.Duration) error {
+	defer observeOperation("SetDeliveredPayloadPublishStatus", time.Now())
+
+	query := `UPDATE ` + vars.TableDeliveredPayload + `
+		SET publish_confirmed=$1, publish_attempts=$2, publish_duration_ms=$3, broadcast_duration_ms=$4
+		WHERE slot=$5 AND proposer_pubkey=$6 AND block_hash=$7;`
+	res, err := s.DB.Exec(query, confirmed, numAttempts, publishDuration.Milliseconds(), broadcastDuration.Milliseconds(), slot, proposerPubkey, blockHash)
+	if err != nil {
+		return err
+	}
+	numRows, err := res.RowsAffected()
+	if err == nil && numRows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+func (s *DatabaseService) SaveGetPayloadFailure(entry GetPayloadFailureEntry) error {
+	defer observeOperation("SaveGetPayloadFailure", time.Now())
+
+	query := `INSERT INTO ` + vars.TableGetPayloadFailure + `
+		(slot, proposer_index, proposer_pubkey, block_hash, reason) VALUES
+		(:slot, :proposer_index, :proposer_pubkey, :block_hash, :reason);`
+	_, err := s.DB.NamedExec(query, entry)
+	return err
+}
+
+func (s *DatabaseService) SaveBlocklistFiltered(entry BlocklistFilteredEntry) error {
+	defer observeOperation("SaveBlocklistFiltered", time.Now())
+
+	query := `INSERT INTO ` + vars.TableBlocklistFiltered + `
+		(slot, builder_pubkey, proposer_pubkey, block_hash, address, reason) VALUES
+		(:slot, :builder_pubkey, :proposer_pubkey, :block_hash, :address, :reason);`
+	_, err := s.DB.NamedExec(query, entry)
+	return err
+}
+
+func (s *DatabaseService) SavePeerBid(entry PeerBidEntry) error {
+	defer observeOperation("SavePeerBid", time.Now())
+
+	query := `INSERT INTO ` + vars.TablePeerBid + `
+		(received_at, peer_relay_pubkey, slot, parent_hash, block_hash, builder_pubkey, proposer_pubkey, proposer_fee_recipient, gas_used, gas_limit, value) VALUES
+		(:received_at, :peer_relay_pubkey, :slot, :parent_hash, :block_hash, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :gas_used, :gas_limit, :value);`
+	_, err := s.DB.NamedExec(query, entry)
+	return err
+}
+
+// SaveBidHistory saves the top bid changes of a slot
+func (s *DatabaseService) SaveBidHistory(entries []*BidHistoryEntry) error {
+	defer observeOperation("SaveBidHistory", time.Now())
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO ` + vars.TableBidHistory + `
+		(changed_at, slot, parent_hash, proposer_pubkey, builder_pubkey, value) VALUES
+		(:changed_at, :slot, :parent_hash, :proposer_pubkey, :builder_pubkey, :value);`
+	_, err := s.DB.NamedExec(query, entries)
+	return err
+}
+
+// GetProposerAllowlist returns the pubkeys of the validators the relay serves, if the allowlist is kept in the database
+func (s *DatabaseService) GetProposerAllowlist() ([]string, error) {
+	defer observeOperation("GetProposerAllowlist", time.Now())
+
+	query := `SELECT pubkey FROM ` + vars.TableProposerAllowlist + ` ORDER BY pubkey ASC;`
+	pubkeys := []string{}
+	err := s.DB.Select(&pubkeys, query)
+	return pubkeys, err
+}
+
 func (s *DatabaseService) GetRecentDeliveredPayloads(queryArgs GetPayloadsFilters) ([]*DeliveredPayloadEntry, error) {
+	defer observeOperation("GetRecentDeliveredPayloads", time.Now())
+
 	arg := map[string]interface{}{
 		"limit":           queryArgs.Limit,
 		"slot":            queryArgs.Slot,
@@ -286,7 +588,7 @@ func (s *DatabaseService) GetRecentDeliveredPayloads(queryArgs GetPayloadsFilter
 		"builder_pubkey":  queryArgs.BuilderPubkey,
 	}
 
-	fields := "id, inserted_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit"
+	fields := "id, inserted_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, get_header_ms_into_slot, get_payload_ms_into_slot, publish_duration_ms, broadcast_duration_ms, get_header_policy"
 
 	whereConds := []string{}
 	if queryArgs.Slot > 0 {
@@ -294,6 +596,12 @@ func (s *DatabaseService) GetRecentDeliveredPayloads(queryArgs GetPayloadsFilter
 	} else if queryArgs.Cursor > 0 {
 		whereConds = append(whereConds, "slot <= :cursor")
 	}
+	if queryArgs.PageCursor != nil {
+		whereConds = append(whereConds, "(slot, id) < (:cursor_slot, :cursor_id)")
+		arg["cursor_slot"] = queryArgs.PageCursor.Slot
+		arg["cursor_id"] = queryArgs.PageCursor.ID
+	}
+	whereConds = append(whereConds, queryArgs.RangeFilters.whereConds("inserted_at", arg)...)
 	if queryArgs.BlockHash != "" {
 		whereConds = append(whereConds, "block_hash = :block_hash")
 	}
@@ -312,7 +620,7 @@ func (s *DatabaseService) GetRecentDeliveredPayloads(queryArgs GetPayloadsFilter
 		where = "WHERE " + strings.Join(whereConds, " AND ")
 	}
 
-	orderBy := "slot DESC"
+	orderBy := "slot DESC, id DESC"
 	if queryArgs.OrderByValue == 1 {
 		orderBy = "value ASC"
 	} else if queryArgs.OrderByValue == -1 {
@@ -340,6 +648,8 @@ func (s *DatabaseService) GetRecentDeliveredPayloads(queryArgs GetPayloadsFilter
 }
 
 func (s *DatabaseService) GetDeliveredPayloads(idFirst, idLast uint64) (entries []*DeliveredPayloadEntry, err error) {
+	defer observeOperation("GetDeliveredPayloads", time.Now())
+
 	query := `SELECT id, inserted_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit
 	FROM ` + vars.TableDeliveredPayload + `
 	WHERE id >= $1 AND id <= $2
@@ -349,13 +659,40 @@ func (s *DatabaseService) GetDeliveredPayloads(idFirst, idLast uint64) (entries
 	return entries, err
 }
 
+// StreamDeliveredPayloads calls fn for every delivered payload in the slot range, in slot order
+func (s *DatabaseService) StreamDeliveredPayloads(ctx context.Context, slotFrom, slotTo uint64, fn func(*DeliveredPayloadEntry) error) error {
+	defer observeOperation("StreamDeliveredPayloads", time.Now())
+
+	query := `SELECT id, inserted_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, get_header_ms_into_slot, get_payload_ms_into_slot, publish_confirmed, publish_duration_ms, broadcast_duration_ms, get_header_policy
+	FROM ` + vars.TableDeliveredPayload + `
+	WHERE slot >= $1 AND slot <= $2
+	ORDER BY slot ASC, id ASC`
+	return streamRows(ctx, s.DB, query, fn, slotFrom, slotTo)
+}
+
+// GetNumDeliveredPayloads returns the number of delivered payloads as of the latest refresh of the stats views. Until
+// the housekeeper first populates the view, the payloads are counted live.
 func (s *DatabaseService) GetNumDeliveredPayloads() (uint64, error) {
+	defer observeOperation("GetNumDeliveredPayloads", time.Now())
+
+	var isPopulated bool
+	err := s.DB.QueryRow("SELECT ispopulated FROM pg_matviews WHERE matviewname = $1", vars.ViewDeliveredPayloadCount).Scan(&isPopulated)
+	if err != nil {
+		return 0, err
+	}
+
+	query := "SELECT num_payloads_delivered FROM " + vars.ViewDeliveredPayloadCount
+	if !isPopulated {
+		query = "SELECT COUNT(*) FROM " + vars.TableDeliveredPayload
+	}
 	var count uint64
-	err := s.DB.QueryRow("SELECT COUNT(*) FROM " + vars.TableDeliveredPayload).Scan(&count)
+	err = s.DB.QueryRow(query).Scan(&count)
 	return count, err
 }
 
 func (s *DatabaseService) GetBuilderSubmissions(filters GetBuilderSubmissionsFilters) ([]*BuilderBlockSubmissionEntry, error) {
+	defer observeOperation("GetBuilderSubmissions", time.Now())
+
 	arg := map[string]interface{}{
 		"limit":          filters.Limit,
 		"slot":           filters.Slot,
@@ -364,7 +701,7 @@ func (s *DatabaseService) GetBuilderSubmissions(filters GetBuilderSubmissionsFil
 		"builder_pubkey": filters.BuilderPubkey,
 	}
 
-	fields := "id, inserted_at, received_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit"
+	fields := "id, inserted_at, received_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, optimistic_submission, was_cancelled"
 	limit := "LIMIT :limit"
 
 	whereConds := []string{
@@ -385,13 +722,25 @@ func (s *DatabaseService) GetBuilderSubmissions(filters GetBuilderSubmissionsFil
 	if filters.BuilderPubkey != "" {
 		whereConds = append(whereConds, "builder_pubkey = :builder_pubkey")
 	}
+	if filters.ExcludeCancelled {
+		whereConds = append(whereConds, "was_cancelled = false")
+	}
+	if filters.PageCursor != nil {
+		whereConds = append(whereConds, "(slot, id) < (:cursor_slot, :cursor_id)")
+		arg["cursor_slot"] = filters.PageCursor.Slot
+		arg["cursor_id"] = filters.PageCursor.ID
+	}
+	whereConds = append(whereConds, filters.RangeFilters.whereConds("received_at", arg)...)
+	if filters.Paginated {
+		limit = "LIMIT :limit"
+	}
 
 	where := ""
 	if len(whereConds) > 0 {
 		where = "WHERE " + strings.Join(whereConds, " AND ")
 	}
 
-	query := fmt.Sprintf("SELECT %s FROM %s %s ORDER BY slot DESC, inserted_at DESC %s", fields, vars.TableBuilderBlockSubmission, where, limit)
+	query := fmt.Sprintf("SELECT %s FROM %s %s ORDER BY slot DESC, id DESC %s", fields, vars.TableBuilderBlockSubmission, where, limit)
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -412,7 +761,9 @@ func (s *DatabaseService) GetBuilderSubmissions(filters GetBuilderSubmissionsFil
 }
 
 func (s *DatabaseService) GetBuilderSubmissionsBySlots(slotFrom, slotTo uint64) (entries []*BuilderBlockSubmissionEntry, err error) {
-	query := `SELECT id, inserted_at, received_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit
+	defer observeOperation("GetBuilderSubmissionsBySlots", time.Now())
+
+	query := `SELECT id, inserted_at, received_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, optimistic_submission, was_cancelled
 	FROM ` + vars.TableBuilderBlockSubmission + `
 	WHERE sim_success = true AND slot >= $1 AND slot <= $2
 	ORDER BY slot ASC, inserted_at ASC`
@@ -421,7 +772,20 @@ func (s *DatabaseService) GetBuilderSubmissionsBySlots(slotFrom, slotTo uint64)
 	return entries, err
 }
 
+// StreamBuilderSubmissions calls fn for every successfully simulated submission in the slot range, in slot order
+func (s *DatabaseService) StreamBuilderSubmissions(ctx context.Context, slotFrom, slotTo uint64, fn func(*BuilderBlockSubmissionEntry) error) error {
+	defer observeOperation("StreamBuilderSubmissions", time.Now())
+
+	query := `SELECT id, inserted_at, received_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, optimistic_submission, was_cancelled
+	FROM ` + vars.TableBuilderBlockSubmission + `
+	WHERE slot >= $1 AND slot <= $2 AND sim_success = true
+	ORDER BY slot ASC, id ASC`
+	return streamRows(ctx, s.DB, query, fn, slotFrom, slotTo)
+}
+
 func (s *DatabaseService) UpsertBlockBuilderEntryAfterSubmission(lastSubmission *BuilderBlockSubmissionEntry, isError bool) error {
+	defer observeOperation("UpsertBlockBuilderEntryAfterSubmission", time.Now())
+
 	entry := BlockBuilderEntry{
 		BuilderPubkey:          lastSubmission.BuilderPubkey,
 		LastSubmissionID:       NewNullInt64(lastSubmission.ID),
@@ -447,26 +811,34 @@ func (s *DatabaseService) UpsertBlockBuilderEntryAfterSubmission(lastSubmission
 }
 
 func (s *DatabaseService) GetBlockBuilders() ([]*BlockBuilderEntry, error) {
-	query := `SELECT id, inserted_at, builder_pubkey, description, is_high_prio, is_blacklisted, last_submission_id, last_submission_slot, num_submissions_total, num_submissions_simerror, num_sent_getpayload FROM ` + vars.TableBlockBuilder + ` ORDER BY id ASC;`
+	defer observeOperation("GetBlockBuilders", time.Now())
+
+	query := `SELECT id, inserted_at, builder_pubkey, description, is_high_prio, is_blacklisted, last_submission_id, last_submission_slot, num_submissions_total, num_submissions_simerror, num_sent_getpayload, collateral, is_optimistic, api_key_hash FROM ` + vars.TableBlockBuilder + ` ORDER BY id ASC;`
 	entries := []*BlockBuilderEntry{}
 	err := s.DB.Select(&entries, query)
 	return entries, err
 }
 
 func (s *DatabaseService) GetBlockBuilderByPubkey(pubkey string) (*BlockBuilderEntry, error) {
-	query := `SELECT id, inserted_at, builder_pubkey, description, is_high_prio, is_blacklisted, last_submission_id, last_submission_slot, num_submissions_total, num_submissions_simerror, num_sent_getpayload FROM ` + vars.TableBlockBuilder + ` WHERE builder_pubkey=$1;`
+	defer observeOperation("GetBlockBuilderByPubkey", time.Now())
+
+	query := `SELECT id, inserted_at, builder_pubkey, description, is_high_prio, is_blacklisted, last_submission_id, last_submission_slot, num_submissions_total, num_submissions_simerror, num_sent_getpayload, collateral, is_optimistic, api_key_hash FROM ` + vars.TableBlockBuilder + ` WHERE builder_pubkey=$1;`
 	entry := &BlockBuilderEntry{}
 	err := s.DB.Get(entry, query, pubkey)
 	return entry, err
 }
 
 func (s *DatabaseService) SetBlockBuilderStatus(pubkey string, isHighPrio, isBlacklisted bool) error {
+	defer observeOperation("SetBlockBuilderStatus", time.Now())
+
 	query := `UPDATE ` + vars.TableBlockBuilder + ` SET is_high_prio=$1, is_blacklisted=$2 WHERE builder_pubkey=$3;`
 	_, err := s.DB.Exec(query, isHighPrio, isBlacklisted, pubkey)
 	return err
 }
 
 func (s *DatabaseService) IncBlockBuilderStatsAfterGetPayload(builderPubkey string) error {
+	defer observeOperation("IncBlockBuilderStatsAfterGetPayload", time.Now())
+
 	query := `UPDATE ` + vars.TableBlockBuilder + `
 		SET num_sent_getpayload=num_sent_getpayload+1
 		WHERE builder_pubkey=$1;`
@@ -474,14 +846,472 @@ func (s *DatabaseService) IncBlockBuilderStatsAfterGetPayload(builderPubkey stri
 	return err
 }
 
-func (s *DatabaseService) GetExecutionPayloads(idFirst, idLast uint64) (entries []*ExecutionPayloadEntry, err error) {
-	query := `SELECT id, inserted_at, slot, proposer_pubkey, block_hash, version, payload FROM ` + vars.TableExecutionPayload + ` WHERE id >= $1 AND id <= $2 ORDER BY id ASC`
-	err = s.DB.Select(&entries, query, idFirst, idLast)
+// SetBlockBuilderCollateral sets the collateral and optimistic mode of a builder, sql.ErrNoRows if the builder is unknown
+func (s *DatabaseService) SetBlockBuilderCollateral(pubkey, collateral string, isOptimistic bool) error {
+	defer observeOperation("SetBlockBuilderCollateral", time.Now())
+
+	query := `UPDATE ` + vars.TableBlockBuilder + ` SET collateral=$1, is_optimistic=$2 WHERE builder_pubkey=$3;`
+	res, err := s.DB.Exec(query, collateral, isOptimistic, pubkey)
+	if err != nil {
+		return err
+	}
+	numRows, err := res.RowsAffected()
+	if err == nil && numRows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// SetBlockBuilderAPIKeyHash sets the hash of the builder's API key, an empty hash removes the key
+func (s *DatabaseService) SetBlockBuilderAPIKeyHash(pubkey, apiKeyHash string) error {
+	defer observeOperation("SetBlockBuilderAPIKeyHash", time.Now())
+
+	query := `UPDATE ` + vars.TableBlockBuilder + ` SET api_key_hash=$1 WHERE builder_pubkey=$2;`
+	res, err := s.DB.Exec(query, sql.NullString{String: apiKeyHash, Valid: apiKeyHash != ""}, pubkey)
+	if err != nil {
+		return err
+	}
+	numRows, err := res.RowsAffected()
+	if err == nil && numRows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// InsertBuilderDemotion records an optimistic block that failed simulation (or whose payload couldn't be fetched), and disables optimistic mode for the builder
+func (s *DatabaseService) InsertBuilderDemotion(bidTrace *common.BidTraceV2, simError error) error {
+	defer observeOperation("InsertBuilderDemotion", time.Now())
+
+	simErrStr := ""
+	if simError != nil {
+		simErrStr = simError.Error()
+	}
+
+	entry := BuilderDemotionEntry{
+		Slot:  bidTrace.Slot,
+		Epoch: bidTrace.Slot / uint64(common.SlotsPerEpoch),
+
+		BuilderPubkey:        bidTrace.BuilderPubkey.String(),
+		ProposerPubkey:       bidTrace.ProposerPubkey.String(),
+		ProposerFeeRecipient: bidTrace.ProposerFeeRecipient.String(),
+
+		BlockHash: bidTrace.BlockHash.String(),
+		Value:     bidTrace.Value.ToBig().String(),
+
+		SimError: simErrStr,
+	}
+
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `INSERT INTO ` + vars.TableBuilderDemotions + `
+		(slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, block_hash, value, sim_error) VALUES
+		(:slot, :epoch, :builder_pubkey, :proposer_pubkey, :proposer_fee_recipient, :block_hash, :value, :sim_error)
+		ON CONFLICT DO NOTHING`
+	if _, err = tx.NamedExec(query, entry); err != nil {
+		return err
+	}
+
+	query = `UPDATE ` + vars.TableBlockBuilder + ` SET is_optimistic=false WHERE builder_pubkey=$1;`
+	if _, err = tx.Exec(query, entry.BuilderPubkey); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetBuilderDemotionRefundRequired marks a demotion as owing the proposer a refund, if the payload of the failed block was delivered.
+// It is called both after a demotion and after a payload delivery, since either can happen first.
+func (s *DatabaseService) SetBuilderDemotionRefundRequired(slot uint64, blockHash string) (refundRequired bool, err error) {
+	defer observeOperation("SetBuilderDemotionRefundRequired", time.Now())
+
+	query := `UPDATE ` + vars.TableBuilderDemotions + ` AS demotion
+		SET refund_required=true, signed_blinded_beacon_block=delivered.signed_blinded_beacon_block
+		FROM ` + vars.TableDeliveredPayload + ` AS delivered
+		WHERE demotion.slot=$1 AND demotion.block_hash=$2 AND delivered.slot=demotion.slot AND delivered.block_hash=demotion.block_hash;`
+	res, err := s.DB.Exec(query, slot, blockHash)
+	if err != nil {
+		return false, err
+	}
+	numRows, err := res.RowsAffected()
+	return numRows > 0, err
+}
+
+// SetBuilderDemotionRefundConfirmed records that the builder paid the refunds of its open demotions, and returns the
+// number of demotions confirmed
+func (s *DatabaseService) SetBuilderDemotionRefundConfirmed(builderPubkey string) (numDemotions int64, err error) {
+	defer observeOperation("SetBuilderDemotionRefundConfirmed", time.Now())
+
+	query := `UPDATE ` + vars.TableBuilderDemotions + ` SET refund_confirmed=true
+		WHERE builder_pubkey=$1 AND refund_required AND NOT refund_confirmed AND promoted_at IS NULL;`
+	res, err := s.DB.Exec(query, builderPubkey)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetOpenBuilderDemotions returns the demotions of the builders which weren't promoted since, oldest first
+func (s *DatabaseService) GetOpenBuilderDemotions() (entries []*BuilderDemotionEntry, err error) {
+	defer observeOperation("GetOpenBuilderDemotions", time.Now())
+
+	query := `SELECT id, inserted_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, block_hash, value, sim_error, refund_required, refund_confirmed, promoted_at, promotion_reason
+		FROM ` + vars.TableBuilderDemotions + ` WHERE promoted_at IS NULL ORDER BY id ASC;`
+	err = s.DB.Select(&entries, query)
 	return entries, err
 }
 
+// GetNumSuccessfulBuilderSubmissions returns the number of non-optimistic submissions of the builder which were
+// simulated successfully since the given time
+func (s *DatabaseService) GetNumSuccessfulBuilderSubmissions(builderPubkey string, since time.Time) (num uint64, err error) {
+	defer observeOperation("GetNumSuccessfulBuilderSubmissions", time.Now())
+
+	query := `SELECT COUNT(*) FROM ` + vars.TableBuilderBlockSubmission + `
+		WHERE builder_pubkey=$1 AND inserted_at > $2 AND sim_success AND NOT optimistic_submission;`
+	err = s.DB.QueryRow(query, builderPubkey, since.UTC()).Scan(&num)
+	return num, err
+}
+
+// PromoteBuilder enables optimistic mode again for a demoted builder with collateral, and records the reason in its open
+// demotions
+func (s *DatabaseService) PromoteBuilder(builderPubkey, reason string) error {
+	defer observeOperation("PromoteBuilder", time.Now())
+
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := `UPDATE ` + vars.TableBuilderDemotions + ` SET promoted_at=now(), promotion_reason=$1 WHERE builder_pubkey=$2 AND promoted_at IS NULL;`
+	if _, err = tx.Exec(query, reason, builderPubkey); err != nil {
+		return err
+	}
+
+	query = `UPDATE ` + vars.TableBlockBuilder + ` SET is_optimistic=true WHERE builder_pubkey=$1 AND collateral > 0;`
+	if _, err = tx.Exec(query, builderPubkey); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *DatabaseService) GetExecutionPayloads(idFirst, idLast uint64) (entries []*ExecutionPayloadEntry, err error) {
+	defer observeOperation("GetExecutionPayloads", time.Now())
+
+	query := `SELECT id, inserted_at, slot, proposer_pubkey, block_hash, version, COALESCE(payload::text, '') AS payload, payload_codec, payload_compressed FROM ` + vars.TableExecutionPayload + ` WHERE id >= $1 AND id <= $2 ORDER BY id ASC`
+	if err = s.DB.Select(&entries, query, idFirst, idLast); err != nil {
+		return entries, err
+	}
+	for _, entry := range entries {
+		if err := entry.decompress(); err != nil {
+			return entries, fmt.Errorf("execution payload %d: %w", entry.ID, err)
+		}
+	}
+	return entries, nil
+}
+
 func (s *DatabaseService) DeleteExecutionPayloads(idFirst, idLast uint64) error {
+	defer observeOperation("DeleteExecutionPayloads", time.Now())
+
 	query := `DELETE FROM ` + vars.TableExecutionPayload + ` WHERE id >= $1 AND id <= $2`
 	_, err := s.DB.Exec(query, idFirst, idLast)
 	return err
 }
+
+// InsertDataAPIKey records a new data API key, failing if an active key with the same name exists
+func (s *DatabaseService) InsertDataAPIKey(entry DataAPIKeyEntry) (*DataAPIKeyEntry, error) {
+	defer observeOperation("InsertDataAPIKey", time.Now())
+
+	query := `INSERT INTO ` + vars.TableDataAPIKey + `
+		(name, key_hash, tier, rate_limit_per_sec, rate_limit_burst) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, inserted_at;`
+	err := s.DB.QueryRow(query, entry.Name, entry.KeyHash, entry.Tier, entry.RateLimitPerSec, entry.RateLimitBurst).Scan(&entry.ID, &entry.InsertedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetDataAPIKeys returns the data API keys which aren't revoked
+func (s *DatabaseService) GetDataAPIKeys() (entries []*DataAPIKeyEntry, err error) {
+	defer observeOperation("GetDataAPIKeys", time.Now())
+
+	query := `SELECT id, inserted_at, revoked_at, name, key_hash, tier, rate_limit_per_sec, rate_limit_burst
+		FROM ` + vars.TableDataAPIKey + ` WHERE revoked_at IS NULL ORDER BY id ASC;`
+	err = s.DB.Select(&entries, query)
+	return entries, err
+}
+
+// RevokeDataAPIKey revokes the active data API key with the name, returns sql.ErrNoRows if there is none
+func (s *DatabaseService) RevokeDataAPIKey(name string) error {
+	defer observeOperation("RevokeDataAPIKey", time.Now())
+
+	query := `UPDATE ` + vars.TableDataAPIKey + ` SET revoked_at=NOW() WHERE name=$1 AND revoked_at IS NULL;`
+	res, err := s.DB.Exec(query, name)
+	if err != nil {
+		return err
+	}
+	numRows, err := res.RowsAffected()
+	if err == nil && numRows == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// statsViews are the materialized views of the aggregates served by the data API and the website, refreshed by the
+// housekeeper
+var statsViews = []string{
+	vars.ViewBuilderStats,
+	vars.ViewDailyStats,
+	vars.ViewDeliveredPayloadCount,
+	vars.ViewEpochStats,
+	vars.ViewTopBuilders,
+}
+
+// RefreshStatsViews recomputes the stats views. The first refresh populates them, later ones run concurrently so
+// readers aren't blocked.
+func (s *DatabaseService) RefreshStatsViews() error {
+	defer observeOperation("RefreshStatsViews", time.Now())
+
+	for _, view := range statsViews {
+		var isPopulated bool
+		err := s.DB.QueryRow("SELECT ispopulated FROM pg_matviews WHERE matviewname = $1", view).Scan(&isPopulated)
+		if err != nil {
+			return err
+		}
+
+		query := "REFRESH MATERIALIZED VIEW CONCURRENTLY " + view
+		if !isPopulated {
+			query = "REFRESH MATERIALIZED VIEW " + view
+		}
+		if _, err := s.DB.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DatabaseService) GetBuilderStats() (entries []*BuilderStatsEntry, err error) {
+	defer observeOperation("GetBuilderStats", time.Now())
+
+	query := `SELECT builder_pubkey, num_submissions, num_sim_errors, num_slots_submitted, num_blocks_delivered, total_value, avg_top_bid_gap, avg_submission_latency_ms
+	FROM ` + vars.ViewBuilderStats + `
+	ORDER BY num_blocks_delivered DESC, builder_pubkey ASC`
+	err = s.DB.Select(&entries, query)
+	return entries, err
+}
+
+func (s *DatabaseService) GetBuilderStatsByPubkey(pubkey string) (*BuilderStatsEntry, error) {
+	defer observeOperation("GetBuilderStatsByPubkey", time.Now())
+
+	query := `SELECT builder_pubkey, num_submissions, num_sim_errors, num_slots_submitted, num_blocks_delivered, total_value, avg_top_bid_gap, avg_submission_latency_ms
+	FROM ` + vars.ViewBuilderStats + `
+	WHERE builder_pubkey = $1`
+	entry := &BuilderStatsEntry{}
+	err := s.DB.Get(entry, query, pubkey)
+	return entry, err
+}
+
+func (s *DatabaseService) GetDailyStats() (entries []*DailyStatsEntry, err error) {
+	defer observeOperation("GetDailyStats", time.Now())
+
+	query := `SELECT day, num_submissions, num_sim_errors, num_blocks_delivered, num_builders, total_value
+	FROM ` + vars.ViewDailyStats + `
+	ORDER BY day DESC`
+	err = s.DB.Select(&entries, query)
+	return entries, err
+}
+
+// GetEpochStats returns the delivered payload aggregates of the latest numEpochs epochs of the last 7 days, latest first
+func (s *DatabaseService) GetEpochStats(numEpochs uint64) (entries []*EpochStatsEntry, err error) {
+	defer observeOperation("GetEpochStats", time.Now())
+
+	query := `SELECT epoch, num_payloads_delivered, num_builders, total_value
+	FROM ` + vars.ViewEpochStats + `
+	ORDER BY epoch DESC
+	LIMIT $1`
+	err = s.DB.Select(&entries, query, numEpochs)
+	return entries, err
+}
+
+// GetTopBuilders returns the builders with the most delivered payloads in the last 7 days
+func (s *DatabaseService) GetTopBuilders(limit uint64) (entries []*TopBuilderEntry, err error) {
+	defer observeOperation("GetTopBuilders", time.Now())
+
+	query := `SELECT builder_pubkey, num_blocks_delivered, total_value
+	FROM ` + vars.ViewTopBuilders + `
+	ORDER BY num_blocks_delivered DESC, builder_pubkey ASC
+	LIMIT $1`
+	err = s.DB.Select(&entries, query, limit)
+	return entries, err
+}
+
+// UpdateDailyAggregates aggregates the delivered payloads of all completed days since the last aggregated one. The last
+// aggregated day is recomputed, in case payloads were inserted after it was aggregated.
+func (s *DatabaseService) UpdateDailyAggregates() error {
+	defer observeOperation("UpdateDailyAggregates", time.Now())
+
+	query := `INSERT INTO ` + vars.TableDailyAggregates + ` (day, num_payloads_delivered, total_value, num_builders)
+	SELECT date_trunc('day', inserted_at)::date AS day, COUNT(*), SUM(value), COUNT(DISTINCT builder_pubkey)
+	FROM ` + vars.TableDeliveredPayload + `
+	WHERE inserted_at >= COALESCE((SELECT MAX(day) FROM ` + vars.TableDailyAggregates + `), '-infinity'::timestamp)
+		AND inserted_at < date_trunc('day', now())
+	GROUP BY 1
+	ON CONFLICT (day) DO UPDATE SET
+		updated_at = now(),
+		num_payloads_delivered = EXCLUDED.num_payloads_delivered,
+		total_value = EXCLUDED.total_value,
+		num_builders = EXCLUDED.num_builders;`
+	_, err := s.DB.Exec(query)
+	return err
+}
+
+// GetDailyAggregates returns the aggregates of the last numDays days, oldest first
+func (s *DatabaseService) GetDailyAggregates(numDays uint64) (entries []*DailyAggregateEntry, err error) {
+	defer observeOperation("GetDailyAggregates", time.Now())
+
+	query := `SELECT day, num_payloads_delivered, total_value, num_builders FROM (
+		SELECT day, num_payloads_delivered, total_value, num_builders
+		FROM ` + vars.TableDailyAggregates + `
+		ORDER BY day DESC
+		LIMIT $1
+	) AS recent ORDER BY day ASC`
+	err = s.DB.Select(&entries, query, numDays)
+	return entries, err
+}
+
+// UpdateBuilderDailyAggregates aggregates the performance of the builders per day, for all days since the last
+// aggregated one. Like UpdateDailyAggregates, the last aggregated day is recomputed, which includes the current day, so
+// the builder stats view is up to date after its next refresh. Submissions count towards the day of their slot, so the
+// top bid of a slot is computed from all its submissions. The submission latency is measured from the start of the slot
+// before the submitted one, when the parent block can be built on.
+func (s *DatabaseService) UpdateBuilderDailyAggregates(genesisTime uint64) error {
+	defer observeOperation("UpdateBuilderDailyAggregates", time.Now())
+
+	query := `INSERT INTO ` + vars.TableBuilderDailyAggregates + ` (day, builder_pubkey, num_submissions, num_sim_errors, num_slots_submitted, sum_top_bid_gap, sum_submission_latency_ms)
+	WITH submissions AS (
+		SELECT builder_pubkey, slot, value, sim_success, received_at,
+			(to_timestamp($1::bigint + slot * $2::bigint) AT TIME ZONE 'UTC')::date AS day
+		FROM ` + vars.TableBuilderBlockSubmission + `
+		WHERE slot >= COALESCE((SELECT (EXTRACT(EPOCH FROM MAX(day)::timestamp) - $1) / $2 FROM ` + vars.TableBuilderDailyAggregates + `), 0)
+	), builder_slots AS (
+		SELECT day, builder_pubkey, slot, MAX(value) AS best_value
+		FROM submissions
+		WHERE sim_success
+		GROUP BY day, builder_pubkey, slot
+	), top_bids AS (
+		SELECT slot, MAX(best_value) AS top_value
+		FROM builder_slots
+		GROUP BY slot
+	), gaps AS (
+		SELECT b.day, b.builder_pubkey, COUNT(*) AS num_slots_submitted, SUM(t.top_value - b.best_value) AS sum_top_bid_gap
+		FROM builder_slots b
+		JOIN top_bids t ON t.slot = b.slot
+		GROUP BY b.day, b.builder_pubkey
+	), totals AS (
+		SELECT day, builder_pubkey,
+			COUNT(*) AS num_submissions,
+			COUNT(*) FILTER (WHERE NOT sim_success) AS num_sim_errors,
+			SUM(EXTRACT(EPOCH FROM received_at) * 1000 - ($1 + (slot - 1) * $2) * 1000) AS sum_submission_latency_ms
+		FROM submissions
+		GROUP BY day, builder_pubkey
+	)
+	SELECT t.day,
+		t.builder_pubkey,
+		t.num_submissions,
+		t.num_sim_errors,
+		COALESCE(g.num_slots_submitted, 0),
+		COALESCE(g.sum_top_bid_gap, 0),
+		ROUND(t.sum_submission_latency_ms)
+	FROM totals t
+	LEFT JOIN gaps g ON g.day = t.day AND g.builder_pubkey = t.builder_pubkey
+	ON CONFLICT (day, builder_pubkey) DO UPDATE SET
+		updated_at = now(),
+		num_submissions = EXCLUDED.num_submissions,
+		num_sim_errors = EXCLUDED.num_sim_errors,
+		num_slots_submitted = EXCLUDED.num_slots_submitted,
+		sum_top_bid_gap = EXCLUDED.sum_top_bid_gap,
+		sum_submission_latency_ms = EXCLUDED.sum_submission_latency_ms;`
+	_, err := s.DB.Exec(query, genesisTime, uint64(common.DurationPerSlot.Seconds()))
+	return err
+}
+
+// GetDeliveredPayloadsAfterID returns the next delivered payloads after the id, in id order, with their publish status.
+// The archiver tails the table with it.
+func (s *DatabaseService) GetDeliveredPayloadsAfterID(afterID, limit uint64) (entries []*DeliveredPayloadEntry, err error) {
+	defer observeOperation("GetDeliveredPayloadsAfterID", time.Now())
+
+	query := `SELECT id, inserted_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, get_header_ms_into_slot, get_payload_ms_into_slot, publish_confirmed, publish_duration_ms, broadcast_duration_ms, get_header_policy
+	FROM ` + vars.TableDeliveredPayload + `
+	WHERE id > $1
+	ORDER BY id ASC
+	LIMIT $2`
+	err = s.DB.Select(&entries, query, afterID, limit)
+	return entries, err
+}
+
+// GetBuilderSubmissionsAfterID returns the next successfully simulated submissions after the id which were inserted
+// before the time, in id order. The archiver tails the table with it.
+func (s *DatabaseService) GetBuilderSubmissionsAfterID(afterID uint64, insertedBefore time.Time, limit uint64) (entries []*BuilderBlockSubmissionEntry, err error) {
+	defer observeOperation("GetBuilderSubmissionsAfterID", time.Now())
+
+	query := `SELECT id, inserted_at, received_at, slot, epoch, builder_pubkey, proposer_pubkey, proposer_fee_recipient, parent_hash, block_hash, block_number, num_tx, value, gas_used, gas_limit, optimistic_submission, was_cancelled
+	FROM ` + vars.TableBuilderBlockSubmission + `
+	WHERE id > $1 AND inserted_at < $2 AND sim_success = true
+	ORDER BY id ASC
+	LIMIT $3`
+	err = s.DB.Select(&entries, query, afterID, insertedBefore.UTC(), limit)
+	return entries, err
+}
+
+// GetArchiverCheckpoint returns the checkpoint of the stream of the sink, sql.ErrNoRows if nothing was exported yet
+func (s *DatabaseService) GetArchiverCheckpoint(sink, stream string) (*ArchiverCheckpointEntry, error) {
+	defer observeOperation("GetArchiverCheckpoint", time.Now())
+
+	query := `SELECT sink, stream, updated_at, last_id, last_slot
+	FROM ` + vars.TableArchiverCheckpoint + `
+	WHERE sink = $1 AND stream = $2`
+	entry := &ArchiverCheckpointEntry{}
+	err := s.DB.Get(entry, query, sink, stream)
+	return entry, err
+}
+
+// SetArchiverCheckpoint saves the last entry of the stream exported to the sink
+func (s *DatabaseService) SetArchiverCheckpoint(sink, stream string, lastID, lastSlot uint64) error {
+	defer observeOperation("SetArchiverCheckpoint", time.Now())
+
+	query := `INSERT INTO ` + vars.TableArchiverCheckpoint + `
+		(sink, stream, last_id, last_slot) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (sink, stream) DO UPDATE SET
+			updated_at = now(),
+			last_id = EXCLUDED.last_id,
+			last_slot = EXCLUDED.last_slot;`
+	_, err := s.DB.Exec(query, sink, stream, lastID, lastSlot)
+	return err
+}
+
+// InsertAdminAuditLog records a change of the relay configuration
+func (s *DatabaseService) InsertAdminAuditLog(entry AdminAuditLogEntry) error {
+	defer observeOperation("InsertAdminAuditLog", time.Now())
+
+	query := `INSERT INTO ` + vars.TableAdminAuditLog + `
+		(operator, remote_addr, action, target, old_value, new_value) VALUES ($1, $2, $3, $4, $5, $6);`
+	_, err := s.DB.Exec(query, entry.Operator, entry.RemoteAddr, entry.Action, entry.Target, entry.OldValue, entry.NewValue)
+	return err
+}
+
+// GetAdminAuditLog returns the latest changes of the relay configuration, newest first, only those of the target if
+// it isn't empty
+func (s *DatabaseService) GetAdminAuditLog(target string, limit uint64) (entries []*AdminAuditLogEntry, err error) {
+	defer observeOperation("GetAdminAuditLog", time.Now())
+
+	query := `SELECT id, inserted_at, operator, remote_addr, action, target, old_value, new_value
+	FROM ` + vars.TableAdminAuditLog + `
+	WHERE $1 = '' OR target = $1
+	ORDER BY id DESC
+	LIMIT $2`
+	err = s.DB.Select(&entries, query, target, limit)
+	return entries, err
+}
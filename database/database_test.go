@@ -1,12 +1,22 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/mev-boost-relay/database/migrations"
 	"github.com/flashbots/mev-boost-relay/database/vars"
+	"github.com/holiman/uint256"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/require"
 )
@@ -129,6 +139,60 @@ func TestSaveValidatorRegistration(t *testing.T) {
 	require.Equal(t, uint64(3), cnt)
 }
 
+func TestSaveValidatorRegistrations(t *testing.T) {
+	db := resetDatabase(t)
+	pubkey1 := "0x8996515293fcd87ca09b5c6ffe5c17f043c6a1a3639cc9494a82ec8eb50a9b55c34b47675e573be40d9be308b1ca2908"
+	pubkey2 := "0x9996515293fcd87ca09b5c6ffe5c17f043c6a1a3639cc9494a82ec8eb50a9b55c34b47675e573be40d9be308b1ca2908"
+
+	reg1 := createValidatorRegistration(pubkey1)
+	reg2 := createValidatorRegistration(pubkey2)
+	require.NoError(t, db.SaveValidatorRegistrations([]ValidatorRegistrationEntry{reg1, reg2}))
+	cnt, err := db.NumValidatorRegistrationRows()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), cnt)
+
+	// same rules as a single registration: newer with the same preferences, and older ones aren't inserted. Of several
+	// registrations of a validator only the latest one is.
+	reg1Newer := createValidatorRegistration(pubkey1)
+	reg1Newer.Timestamp++
+	reg2Older := createValidatorRegistration(pubkey2)
+	reg2Older.Timestamp--
+	reg2Older.GasLimit++
+	reg2Newer := createValidatorRegistration(pubkey2)
+	reg2Newer.Timestamp += 2
+	reg2Newer.GasLimit++
+	reg2Latest := createValidatorRegistration(pubkey2)
+	reg2Latest.Timestamp += 3
+	reg2Latest.GasLimit += 2
+	require.NoError(t, db.SaveValidatorRegistrations([]ValidatorRegistrationEntry{reg1Newer, reg2Older, reg2Latest, reg2Newer}))
+	cnt, err = db.NumValidatorRegistrationRows()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), cnt)
+
+	regX2, err := db.GetValidatorRegistration(pubkey2)
+	require.NoError(t, err)
+	require.Equal(t, reg2Latest.Timestamp, regX2.Timestamp)
+	require.Equal(t, reg2Latest.GasLimit, regX2.GasLimit)
+}
+
+func TestGetValidatorFeeRecipients(t *testing.T) {
+	db := resetDatabase(t)
+	pubkey1 := "0x8996515293fcd87ca09b5c6ffe5c17f043c6a1a3639cc9494a82ec8eb50a9b55c34b47675e573be40d9be308b1ca2908"
+	pubkey2 := "0x9996515293fcd87ca09b5c6ffe5c17f043c6a1a3639cc9494a82ec8eb50a9b55c34b47675e573be40d9be308b1ca2908"
+
+	reg1 := createValidatorRegistration(pubkey1)
+	reg1Rotated := createValidatorRegistration(pubkey1)
+	reg1Rotated.Timestamp++
+	reg1Rotated.FeeRecipient = "0xffbb8996515293fcd87ca09b5c6ffe5c17f043c7"
+	require.NoError(t, db.SaveValidatorRegistrations([]ValidatorRegistrationEntry{reg1}))
+	require.NoError(t, db.SaveValidatorRegistrations([]ValidatorRegistrationEntry{reg1Rotated}))
+
+	feeRecipients, err := db.GetValidatorFeeRecipients([]string{pubkey1, pubkey2})
+	require.NoError(t, err)
+	require.Len(t, feeRecipients, 1)
+	require.ElementsMatch(t, []string{reg1.FeeRecipient, reg1Rotated.FeeRecipient}, feeRecipients[pubkey1])
+}
+
 func TestMigrations(t *testing.T) {
 	db := resetDatabase(t)
 	query := `SELECT COUNT(*) FROM ` + vars.TableMigrations + `;`
@@ -137,3 +201,375 @@ func TestMigrations(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, len(migrations.Migrations.Migrations), rowCount)
 }
+
+func TestImportBidTraces(t *testing.T) {
+	db := resetDatabase(t)
+	bidTrace := common.BidTraceV2JSON{
+		Slot:                 100,
+		ParentHash:           "0x01",
+		BlockHash:            "0x02",
+		BuilderPubkey:        "0x03",
+		ProposerPubkey:       "0x04",
+		ProposerFeeRecipient: "0x05",
+		Value:                "1000",
+	}
+
+	// importing again doesn't create duplicates
+	for _, expectInserted := range []bool{true, false} {
+		inserted, err := db.ImportDeliveredPayload(BidTraceV2JSONToDeliveredPayloadEntry(&bidTrace))
+		require.NoError(t, err)
+		require.Equal(t, expectInserted, inserted)
+
+		inserted, err = db.ImportBuilderBlockSubmission(BidTraceV2WithTimestampJSONToBuilderSubmissionEntry(&common.BidTraceV2WithTimestampJSON{BidTraceV2JSON: bidTrace, TimestampMs: 1}))
+		require.NoError(t, err)
+		require.Equal(t, expectInserted, inserted)
+	}
+
+	submissions, err := db.GetBuilderSubmissions(GetBuilderSubmissionsFilters{Slot: 100})
+	require.NoError(t, err)
+	require.Len(t, submissions, 1)
+	require.Equal(t, "1000", submissions[0].Value)
+}
+
+func TestSetDeliveredPayloadPublishStatus(t *testing.T) {
+	db := resetDatabase(t)
+
+	// the delivered payload isn't saved yet
+	err := db.SetDeliveredPayloadPublishStatus(100, "0x04", "0x02", true, 1, time.Second, time.Second)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = db.ImportDeliveredPayload(BidTraceV2JSONToDeliveredPayloadEntry(&common.BidTraceV2JSON{
+		Slot:           100,
+		BlockHash:      "0x02",
+		ProposerPubkey: "0x04",
+		Value:          "1000",
+	}))
+	require.NoError(t, err)
+	err = db.SetDeliveredPayloadPublishStatus(100, "0x04", "0x02", true, 1, time.Second, time.Second)
+	require.NoError(t, err)
+}
+
+func TestExecutionPayloadEntryToSubmitBlockRequest(t *testing.T) {
+	db := resetDatabase(t)
+	payload := &common.BuilderSubmitBlockRequest{
+		Capella: &capella.SubmitBlockRequest{
+			Message: &apiv1.BidTrace{
+				Slot:       100,
+				ParentHash: phase0.Hash32{0x01},
+				BlockHash:  phase0.Hash32{0x02},
+				GasLimit:   30_000_000,
+				GasUsed:    15_000_000,
+				Value:      uint256.NewInt(1000),
+			},
+			ExecutionPayload: &consensuscapella.ExecutionPayload{
+				ParentHash:   phase0.Hash32{0x01},
+				BlockHash:    phase0.Hash32{0x02},
+				GasLimit:     30_000_000,
+				GasUsed:      15_000_000,
+				ExtraData:    []byte{0x01},
+				Transactions: []bellatrix.Transaction{{0x01}},
+				Withdrawals:  []*consensuscapella.Withdrawal{},
+			},
+			Signature: phase0.BLSSignature{0x03},
+		},
+	}
+	submission, err := db.SaveBuilderBlockSubmission(payload, nil, time.Now(), "", false)
+	require.NoError(t, err)
+	execPayload, err := db.GetExecutionPayloadEntryByID(submission.ExecutionPayloadID.Int64)
+	require.NoError(t, err)
+
+	// the submission can be reconstructed from the DB
+	reconstructed, err := ExecutionPayloadEntryToSubmitBlockRequest(execPayload, submission)
+	require.NoError(t, err)
+	require.NotNil(t, reconstructed.Capella)
+	require.Equal(t, payload.Capella.Message, reconstructed.Capella.Message)
+	require.Equal(t, payload.Capella.Signature, reconstructed.Capella.Signature)
+	require.Equal(t, payload.BlockHash(), reconstructed.ExecutionPayloadBlockHash())
+	require.Equal(t, payload.GasUsed(), reconstructed.Capella.ExecutionPayload.GasUsed)
+}
+
+func TestExecutionPayloadCompression(t *testing.T) {
+	db := resetDatabase(t)
+	payload := &common.BuilderSubmitBlockRequest{
+		Capella: &capella.SubmitBlockRequest{
+			Message: &apiv1.BidTrace{Slot: 100, BlockHash: phase0.Hash32{0x02}, Value: uint256.NewInt(1000)},
+			ExecutionPayload: &consensuscapella.ExecutionPayload{
+				BlockHash:    phase0.Hash32{0x02},
+				ExtraData:    []byte{0x01},
+				Transactions: []bellatrix.Transaction{{0x01}},
+				Withdrawals:  []*consensuscapella.Withdrawal{},
+			},
+		},
+	}
+	expected, err := PayloadToExecPayloadEntry(payload)
+	require.NoError(t, err)
+
+	for i, codec := range []string{common.PayloadCodecSnappy, common.PayloadCodecZstd, common.PayloadCodecNone} {
+		payload.Capella.Message.Slot = uint64(100 + i)
+		db.payloadCodec = codec
+		submission, err := db.SaveBuilderBlockSubmission(payload, nil, time.Now(), "", false)
+		require.NoError(t, err)
+
+		entry, err := db.GetExecutionPayloadEntryByID(submission.ExecutionPayloadID.Int64)
+		require.NoError(t, err)
+		require.JSONEq(t, expected.Payload, entry.Payload, codec)
+		entry, err = db.GetExecutionPayloadEntryBySlotPkHash(payload.Slot(), payload.ProposerPubkey(), payload.BlockHash())
+		require.NoError(t, err)
+		require.JSONEq(t, expected.Payload, entry.Payload, codec)
+	}
+
+	entries, err := db.GetExecutionPayloads(0, 1000)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for _, entry := range entries {
+		require.JSONEq(t, expected.Payload, entry.Payload)
+	}
+}
+
+func TestMarkCancelledBuilderSubmissions(t *testing.T) {
+	db := resetDatabase(t)
+	receivedAt := time.Now()
+	save := func(blockHash byte, value uint64, receivedAt time.Time, optimistic bool) *BuilderBlockSubmissionEntry {
+		payload := &common.BuilderSubmitBlockRequest{
+			Capella: &capella.SubmitBlockRequest{
+				Message: &apiv1.BidTrace{Slot: 100, ParentHash: phase0.Hash32{0x01}, BlockHash: phase0.Hash32{blockHash}, Value: uint256.NewInt(value)},
+				ExecutionPayload: &consensuscapella.ExecutionPayload{
+					ParentHash:   phase0.Hash32{0x01},
+					BlockHash:    phase0.Hash32{blockHash},
+					Transactions: []bellatrix.Transaction{},
+					Withdrawals:  []*consensuscapella.Withdrawal{},
+				},
+			},
+		}
+		submission, err := db.SaveBuilderBlockSubmission(payload, nil, receivedAt, "", optimistic)
+		require.NoError(t, err)
+		require.NoError(t, db.MarkCancelledBuilderSubmissions(submission))
+		return submission
+	}
+
+	// the bid of 1000 is cancelled by the later bid of 900, even if saved first
+	save(0x03, 900, receivedAt.Add(2*time.Second), false)
+	save(0x02, 1000, receivedAt.Add(time.Second), true)
+	save(0x04, 950, receivedAt.Add(3*time.Second), false)
+
+	submissions, err := db.GetBuilderSubmissions(GetBuilderSubmissionsFilters{Slot: 100})
+	require.NoError(t, err)
+	require.Len(t, submissions, 3)
+	cancelled := make(map[string]bool)
+	for _, submission := range submissions {
+		cancelled[submission.Value] = submission.WasCancelled
+		require.Equal(t, submission.Value == "1000", submission.OptimisticSubmission)
+	}
+	require.Equal(t, map[string]bool{"1000": true, "900": false, "950": false}, cancelled)
+
+	submissions, err = db.GetBuilderSubmissions(GetBuilderSubmissionsFilters{Slot: 100, ExcludeCancelled: true})
+	require.NoError(t, err)
+	require.Len(t, submissions, 2)
+}
+
+func TestPromoteBuilder(t *testing.T) {
+	db := resetDatabase(t)
+	bidTrace := &common.BidTraceV2{BidTrace: apiv1.BidTrace{Slot: 100, BlockHash: phase0.Hash32{0x02}, Value: uint256.NewInt(1000)}}
+	builderPubkey := bidTrace.BuilderPubkey.String()
+
+	payload := &common.BuilderSubmitBlockRequest{
+		Capella: &capella.SubmitBlockRequest{
+			Message: &bidTrace.BidTrace,
+			ExecutionPayload: &consensuscapella.ExecutionPayload{
+				BlockHash:    bidTrace.BlockHash,
+				Transactions: []bellatrix.Transaction{},
+				Withdrawals:  []*consensuscapella.Withdrawal{},
+			},
+		},
+	}
+	submission, err := db.SaveBuilderBlockSubmission(payload, nil, time.Now(), "", true)
+	require.NoError(t, err)
+	require.NoError(t, db.UpsertBlockBuilderEntryAfterSubmission(submission, false))
+	require.NoError(t, db.SetBlockBuilderCollateral(builderPubkey, "1000", true))
+
+	// the demotion disables optimistic mode, and stays open until the builder is promoted
+	require.NoError(t, db.InsertBuilderDemotion(bidTrace, nil))
+	numConfirmed, err := db.SetBuilderDemotionRefundConfirmed(builderPubkey)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), numConfirmed)
+	demotions, err := db.GetOpenBuilderDemotions()
+	require.NoError(t, err)
+	require.Len(t, demotions, 1)
+	numSuccessful, err := db.GetNumSuccessfulBuilderSubmissions(builderPubkey, demotions[0].InsertedAt.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), numSuccessful)
+
+	require.NoError(t, db.PromoteBuilder(builderPubkey, "successful_submissions"))
+	builder, err := db.GetBlockBuilderByPubkey(builderPubkey)
+	require.NoError(t, err)
+	require.True(t, builder.IsOptimistic)
+	demotions, err = db.GetOpenBuilderDemotions()
+	require.NoError(t, err)
+	require.Empty(t, demotions)
+}
+
+func TestDataAPIKeys(t *testing.T) {
+	db := resetDatabase(t)
+
+	entry, err := db.InsertDataAPIKey(DataAPIKeyEntry{Name: "partner-a", KeyHash: "0a", Tier: "partner", RateLimitPerSec: 20})
+	require.NoError(t, err)
+	require.NotZero(t, entry.ID)
+
+	// only one active key per name
+	_, err = db.InsertDataAPIKey(DataAPIKeyEntry{Name: "partner-a", KeyHash: "0b", Tier: "partner"})
+	require.Error(t, err)
+
+	keys, err := db.GetDataAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, "0a", keys[0].KeyHash)
+	require.Equal(t, 20, keys[0].RateLimitPerSec)
+
+	// revoked keys aren't returned, and the name can be issued a new key
+	require.NoError(t, db.RevokeDataAPIKey("partner-a"))
+	require.ErrorIs(t, db.RevokeDataAPIKey("partner-a"), sql.ErrNoRows)
+	keys, err = db.GetDataAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 0)
+	_, err = db.InsertDataAPIKey(DataAPIKeyEntry{Name: "partner-a", KeyHash: "0b", Tier: "partner"})
+	require.NoError(t, err)
+}
+
+func TestBuilderDailyAggregates(t *testing.T) {
+	db := resetDatabase(t)
+	slot := uint64(100)
+	slotDuration := uint64(common.DurationPerSlot.Seconds())
+	genesisTime := uint64(time.Now().Unix()) - (slot+1)*slotDuration
+	parentSlotStart := time.Unix(int64(genesisTime+(slot-1)*slotDuration), 0)
+
+	save := func(builderPubkey byte, blockHash byte, value uint64, simErr error) {
+		payload := &common.BuilderSubmitBlockRequest{
+			Capella: &capella.SubmitBlockRequest{
+				Message: &apiv1.BidTrace{Slot: slot, BuilderPubkey: phase0.BLSPubKey{builderPubkey}, BlockHash: phase0.Hash32{blockHash}, Value: uint256.NewInt(value)},
+				ExecutionPayload: &consensuscapella.ExecutionPayload{
+					BlockHash:    phase0.Hash32{blockHash},
+					Transactions: []bellatrix.Transaction{},
+					Withdrawals:  []*consensuscapella.Withdrawal{},
+				},
+			},
+		}
+		_, err := db.SaveBuilderBlockSubmission(payload, simErr, parentSlotStart.Add(time.Second), "", false)
+		require.NoError(t, err)
+	}
+	save(0x01, 0x01, 1000, nil)
+	save(0x02, 0x02, 900, nil)
+	require.NoError(t, db.UpdateBuilderDailyAggregates(genesisTime))
+
+	// the last day is recomputed
+	save(0x02, 0x03, 800, errors.New("simulation failed")) //nolint:goerr113
+	require.NoError(t, db.UpdateBuilderDailyAggregates(genesisTime))
+
+	require.NoError(t, db.RefreshStatsViews())
+	stats, err := db.GetBuilderStatsByPubkey(phase0.BLSPubKey{0x02}.String())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), stats.NumSubmissions)
+	require.Equal(t, uint64(1), stats.NumSimErrors)
+	require.Equal(t, "100", stats.AvgTopBidGap)
+	require.Equal(t, int64(1000), stats.AvgSubmissionLatencyMs)
+
+	stats, err = db.GetBuilderStatsByPubkey(phase0.BLSPubKey{0x01}.String())
+	require.NoError(t, err)
+	require.Equal(t, "0", stats.AvgTopBidGap)
+}
+
+func TestStatsViews(t *testing.T) {
+	db := resetDatabase(t)
+	for i, builderPubkey := range []string{"0x01", "0x01", "0x02"} {
+		_, err := db.ImportDeliveredPayload(BidTraceV2JSONToDeliveredPayloadEntry(&common.BidTraceV2JSON{
+			Slot:          uint64(i) * 32,
+			BlockHash:     fmt.Sprintf("0x%02d", i),
+			BuilderPubkey: builderPubkey,
+			Value:         "1000",
+		}))
+		require.NoError(t, err)
+	}
+
+	// counted live before the first refresh
+	num, err := db.GetNumDeliveredPayloads()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), num)
+
+	// populated on the first refresh
+	require.NoError(t, db.RefreshStatsViews())
+	num, err = db.GetNumDeliveredPayloads()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), num)
+
+	epochs, err := db.GetEpochStats(2)
+	require.NoError(t, err)
+	require.Len(t, epochs, 2)
+	require.Equal(t, uint64(2), epochs[0].Epoch)
+	require.Equal(t, uint64(1), epochs[0].NumPayloadsDelivered)
+
+	builders, err := db.GetTopBuilders(10)
+	require.NoError(t, err)
+	require.Len(t, builders, 2)
+	require.Equal(t, "0x01", builders[0].BuilderPubkey)
+	require.Equal(t, uint64(2), builders[0].NumBlocksDelivered)
+	require.Equal(t, "2000", builders[0].TotalValue)
+
+	// and concurrently afterwards
+	require.NoError(t, db.RefreshStatsViews())
+}
+
+func TestArchiverTail(t *testing.T) {
+	db := resetDatabase(t)
+	for i := 0; i < 3; i++ {
+		_, err := db.ImportDeliveredPayload(BidTraceV2JSONToDeliveredPayloadEntry(&common.BidTraceV2JSON{
+			Slot:      uint64(100 - i), // the ids don't follow the slots
+			BlockHash: fmt.Sprintf("0x%02d", i),
+			Value:     "1000",
+		}))
+		require.NoError(t, err)
+	}
+
+	// in id order, following the id
+	entries, err := db.GetDeliveredPayloadsAfterID(0, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(100), entries[0].Slot)
+	require.False(t, entries[0].PublishConfirmed.Valid)
+	entries, err = db.GetDeliveredPayloadsAfterID(uint64(entries[1].ID), 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, uint64(98), entries[0].Slot)
+
+	// with the publish status once it's recorded
+	require.NoError(t, db.SetDeliveredPayloadPublishStatus(98, entries[0].ProposerPubkey, entries[0].BlockHash, true, 1, time.Second, 0))
+	entries, err = db.GetDeliveredPayloadsAfterID(uint64(entries[0].ID)-1, 2)
+	require.NoError(t, err)
+	require.True(t, entries[0].PublishConfirmed.Valid && entries[0].PublishConfirmed.Bool)
+
+	_, err = db.GetArchiverCheckpoint("file:///archive", "payload_delivered")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, db.SetArchiverCheckpoint("file:///archive", "payload_delivered", 2, 99))
+	require.NoError(t, db.SetArchiverCheckpoint("file:///archive", "payload_delivered", 3, 98))
+	checkpoint, err := db.GetArchiverCheckpoint("file:///archive", "payload_delivered")
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), checkpoint.LastID)
+	require.Equal(t, uint64(98), checkpoint.LastSlot)
+}
+
+func TestAdminAuditLog(t *testing.T) {
+	db := resetDatabase(t)
+	require.NoError(t, db.InsertAdminAuditLog(AdminAuditLogEntry{Operator: "alice", Action: AdminAuditActionBuilderStatus, Target: "0xaa", OldValue: "low-prio", NewValue: "high-prio"}))
+	require.NoError(t, db.InsertAdminAuditLog(AdminAuditLogEntry{Operator: "bob", Action: AdminAuditActionFeatureFlag, Target: "min_bid", OldValue: "0", NewValue: "1000"}))
+	require.NoError(t, db.InsertAdminAuditLog(AdminAuditLogEntry{Operator: "alice", Action: AdminAuditActionBuilderCollateral, Target: "0xaa", OldValue: "0", NewValue: "1000"}))
+
+	// newest first
+	entries, err := db.GetAdminAuditLog("", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, AdminAuditActionBuilderCollateral, entries[0].Action)
+
+	entries, err = db.GetAdminAuditLog("0xaa", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "alice", entries[0].Operator)
+	require.Equal(t, "1000", entries[0].NewValue)
+}
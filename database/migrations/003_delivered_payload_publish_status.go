@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration003DeliveredPayloadPublishStatus = &migrate.Migration{
+	Id: "003-delivered-payload-publish-status",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD publish_confirmed boolean;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD publish_attempts int NOT NULL DEFAULT 0;
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN publish_confirmed;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN publish_attempts;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration004GetPayloadFailure = &migrate.Migration{
+	Id: "004-getpayload-failure",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableGetPayloadFailure + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+
+			slot            bigint NOT NULL,
+			proposer_index  bigint NOT NULL,
+			proposer_pubkey varchar(98) NOT NULL,
+			block_hash      varchar(66) NOT NULL,
+
+			reason text NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + vars.TableGetPayloadFailure + `_slot_idx ON ` + vars.TableGetPayloadFailure + `("slot");
+		CREATE INDEX IF NOT EXISTS ` + vars.TableGetPayloadFailure + `_proposerpubkey_idx ON ` + vars.TableGetPayloadFailure + `("proposer_pubkey");
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableGetPayloadFailure + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration005OptimisticBuilders = &migrate.Migration{
+	Id: "005-optimistic-builders",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableBlockBuilder + ` ADD collateral NUMERIC(48, 0) NOT NULL DEFAULT 0;
+		ALTER TABLE ` + vars.TableBlockBuilder + ` ADD is_optimistic boolean NOT NULL DEFAULT false;
+
+		CREATE TABLE IF NOT EXISTS ` + vars.TableBuilderDemotions + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+
+			slot  bigint NOT NULL,
+			epoch bigint NOT NULL,
+
+			builder_pubkey         varchar(98) NOT NULL,
+			proposer_pubkey        varchar(98) NOT NULL,
+			proposer_fee_recipient varchar(42) NOT NULL,
+
+			block_hash varchar(66) NOT NULL,
+			value      NUMERIC(48, 0),
+
+			sim_error text NOT NULL,
+
+			-- set if the payload of the failed block was delivered, and the builder owes the proposer a refund
+			refund_required             boolean NOT NULL DEFAULT false,
+			signed_blinded_beacon_block json,
+
+			UNIQUE (slot, builder_pubkey, block_hash)
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + vars.TableBuilderDemotions + `_slot_idx ON ` + vars.TableBuilderDemotions + `("slot");
+		CREATE INDEX IF NOT EXISTS ` + vars.TableBuilderDemotions + `_builderpubkey_idx ON ` + vars.TableBuilderDemotions + `("builder_pubkey");
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableBuilderDemotions + `;
+		ALTER TABLE ` + vars.TableBlockBuilder + ` DROP COLUMN collateral;
+		ALTER TABLE ` + vars.TableBlockBuilder + ` DROP COLUMN is_optimistic;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
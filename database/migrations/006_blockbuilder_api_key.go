@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration006BlockBuilderAPIKey = &migrate.Migration{
+	Id: "006-blockbuilder-api-key",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableBlockBuilder + ` ADD api_key_hash varchar(64);
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableBlockBuilder + ` DROP COLUMN api_key_hash;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
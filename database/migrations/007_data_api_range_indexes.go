@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration007DataAPIRangeIndexes = &migrate.Migration{
+	Id: "007-data-api-range-indexes",
+	Up: []string{`
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS ` + vars.TableBuilderBlockSubmission + `_value_idx ON ` + vars.TableBuilderBlockSubmission + `("value");
+	`, `
+		CREATE INDEX CONCURRENTLY IF NOT EXISTS ` + vars.TableDeliveredPayload + `_insertedat_idx ON ` + vars.TableDeliveredPayload + `(inserted_at DESC);
+	`},
+	Down: []string{`
+		DROP INDEX IF EXISTS ` + vars.TableBuilderBlockSubmission + `_value_idx;
+	`, `
+		DROP INDEX IF EXISTS ` + vars.TableDeliveredPayload + `_insertedat_idx;
+	`},
+
+	DisableTransactionUp:   true, // cannot create index concurrently inside a transaction
+	DisableTransactionDown: true,
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration008StatsViews = &migrate.Migration{
+	Id: "008-stats-views",
+	// the views are created empty and populated by the housekeeper, to not block startup on a large submissions table
+	Up: []string{`
+		CREATE MATERIALIZED VIEW IF NOT EXISTS ` + vars.ViewBuilderStats + ` AS
+		WITH submissions AS (
+			SELECT builder_pubkey,
+				COUNT(*) AS num_submissions,
+				COUNT(*) FILTER (WHERE NOT sim_success) AS num_sim_errors,
+				COUNT(DISTINCT slot) FILTER (WHERE sim_success) AS num_slots_submitted
+			FROM ` + vars.TableBuilderBlockSubmission + `
+			WHERE received_at > now() - interval '30 days'
+			GROUP BY builder_pubkey
+		), delivered AS (
+			SELECT builder_pubkey,
+				COUNT(*) AS num_blocks_delivered,
+				SUM(value) AS total_value
+			FROM ` + vars.TableDeliveredPayload + `
+			WHERE inserted_at > now() - interval '30 days'
+			GROUP BY builder_pubkey
+		)
+		SELECT s.builder_pubkey,
+			s.num_submissions,
+			s.num_sim_errors,
+			s.num_slots_submitted,
+			COALESCE(d.num_blocks_delivered, 0) AS num_blocks_delivered,
+			COALESCE(d.total_value, 0) AS total_value
+		FROM submissions s
+		LEFT JOIN delivered d ON d.builder_pubkey = s.builder_pubkey
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewBuilderStats + `_builderpubkey_uidx ON ` + vars.ViewBuilderStats + `(builder_pubkey);
+
+		CREATE MATERIALIZED VIEW IF NOT EXISTS ` + vars.ViewDailyStats + ` AS
+		WITH submissions AS (
+			SELECT date_trunc('day', received_at) AS day,
+				COUNT(*) AS num_submissions,
+				COUNT(*) FILTER (WHERE NOT sim_success) AS num_sim_errors
+			FROM ` + vars.TableBuilderBlockSubmission + `
+			WHERE received_at > now() - interval '30 days'
+			GROUP BY 1
+		), delivered AS (
+			SELECT date_trunc('day', inserted_at) AS day,
+				COUNT(*) AS num_blocks_delivered,
+				COUNT(DISTINCT builder_pubkey) AS num_builders,
+				SUM(value) AS total_value
+			FROM ` + vars.TableDeliveredPayload + `
+			WHERE inserted_at > now() - interval '30 days'
+			GROUP BY 1
+		)
+		SELECT COALESCE(d.day, s.day) AS day,
+			COALESCE(s.num_submissions, 0) AS num_submissions,
+			COALESCE(s.num_sim_errors, 0) AS num_sim_errors,
+			COALESCE(d.num_blocks_delivered, 0) AS num_blocks_delivered,
+			COALESCE(d.num_builders, 0) AS num_builders,
+			COALESCE(d.total_value, 0) AS total_value
+		FROM delivered d
+		FULL OUTER JOIN submissions s ON s.day = d.day
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewDailyStats + `_day_uidx ON ` + vars.ViewDailyStats + `(day);
+	`},
+	Down: []string{`
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewBuilderStats + `;
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewDailyStats + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
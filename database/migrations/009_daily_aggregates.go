@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration009DailyAggregates = &migrate.Migration{
+	Id: "009-daily-aggregates",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableDailyAggregates + ` (
+			day        date PRIMARY KEY,
+			updated_at timestamp NOT NULL default current_timestamp,
+
+			num_payloads_delivered bigint NOT NULL,
+			total_value            NUMERIC(48, 0) NOT NULL,
+			num_builders           bigint NOT NULL
+		);
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableDailyAggregates + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration010BlocklistFiltered = &migrate.Migration{
+	Id: "010-blocklist-filtered",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableBlocklistFiltered + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+
+			slot            bigint NOT NULL,
+			builder_pubkey  varchar(98) NOT NULL,
+			proposer_pubkey varchar(98) NOT NULL,
+			block_hash      varchar(66) NOT NULL,
+
+			address varchar(42) NOT NULL,
+			reason  text NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + vars.TableBlocklistFiltered + `_slot_idx ON ` + vars.TableBlocklistFiltered + `("slot");
+		CREATE INDEX IF NOT EXISTS ` + vars.TableBlocklistFiltered + `_builderpubkey_idx ON ` + vars.TableBlocklistFiltered + `("builder_pubkey");
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableBlocklistFiltered + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
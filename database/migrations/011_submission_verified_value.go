@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration011SubmissionVerifiedValue = &migrate.Migration{
+	Id: "011-submission-verified-value",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableBuilderBlockSubmission + ` ADD verified_value NUMERIC(48, 0);
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableBuilderBlockSubmission + ` DROP COLUMN verified_value;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
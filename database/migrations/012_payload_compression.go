@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// Migration012PayloadCompression stores compressed execution payloads in payload_compressed, with the codec in
+// payload_codec. Uncompressed payloads stay in the payload column, which is empty for compressed ones.
+var Migration012PayloadCompression = &migrate.Migration{
+	Id: "012-payload-compression",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableExecutionPayload + ` ALTER COLUMN payload DROP NOT NULL;
+		ALTER TABLE ` + vars.TableExecutionPayload + ` ADD payload_codec text NOT NULL DEFAULT '';
+		ALTER TABLE ` + vars.TableExecutionPayload + ` ADD payload_compressed bytea;
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableExecutionPayload + ` DROP COLUMN payload_compressed;
+		ALTER TABLE ` + vars.TableExecutionPayload + ` DROP COLUMN payload_codec;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
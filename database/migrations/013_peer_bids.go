@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration013PeerBids = &migrate.Migration{
+	Id: "013-peer-bids",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TablePeerBid + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+			received_at timestamp NOT NULL,
+
+			peer_relay_pubkey varchar(98) NOT NULL,
+
+			slot                   bigint NOT NULL,
+			parent_hash            varchar(66) NOT NULL,
+			block_hash             varchar(66) NOT NULL,
+			builder_pubkey         varchar(98) NOT NULL,
+			proposer_pubkey        varchar(98) NOT NULL,
+			proposer_fee_recipient varchar(42) NOT NULL,
+
+			gas_used  bigint NOT NULL,
+			gas_limit bigint NOT NULL,
+			value     NUMERIC(48, 0)
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + vars.TablePeerBid + `_slot_idx ON ` + vars.TablePeerBid + `("slot");
+		CREATE INDEX IF NOT EXISTS ` + vars.TablePeerBid + `_blockhash_idx ON ` + vars.TablePeerBid + `("block_hash");
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TablePeerBid + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
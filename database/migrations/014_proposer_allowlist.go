@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration014ProposerAllowlist = &migrate.Migration{
+	Id: "014-proposer-allowlist",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableProposerAllowlist + ` (
+			pubkey      varchar(98) PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+			description text NOT NULL default ''
+		);
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableProposerAllowlist + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration015SubmissionCancellations = &migrate.Migration{
+	Id: "015-submission-cancellations",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableBuilderBlockSubmission + ` ADD optimistic_submission boolean NOT NULL default false;
+		ALTER TABLE ` + vars.TableBuilderBlockSubmission + ` ADD was_cancelled boolean NOT NULL default false;
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableBuilderBlockSubmission + ` DROP COLUMN optimistic_submission;
+		ALTER TABLE ` + vars.TableBuilderBlockSubmission + ` DROP COLUMN was_cancelled;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
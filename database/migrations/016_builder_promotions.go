@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration016BuilderPromotions = &migrate.Migration{
+	Id: "016-builder-promotions",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableBuilderDemotions + ` ADD refund_confirmed boolean NOT NULL DEFAULT false;
+		ALTER TABLE ` + vars.TableBuilderDemotions + ` ADD promoted_at timestamp;
+		ALTER TABLE ` + vars.TableBuilderDemotions + ` ADD promotion_reason text NOT NULL DEFAULT '';
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableBuilderDemotions + ` DROP COLUMN refund_confirmed;
+		ALTER TABLE ` + vars.TableBuilderDemotions + ` DROP COLUMN promoted_at;
+		ALTER TABLE ` + vars.TableBuilderDemotions + ` DROP COLUMN promotion_reason;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration017DataAPIKeys = &migrate.Migration{
+	Id: "017-data-api-keys",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableDataAPIKey + ` (
+			id          bigserial PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+			revoked_at  timestamp,
+
+			name     text NOT NULL,
+			key_hash varchar(64) NOT NULL,
+			tier     text NOT NULL,
+
+			rate_limit_per_sec integer NOT NULL default 0,
+			rate_limit_burst   integer NOT NULL default 0,
+
+			UNIQUE (key_hash)
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.TableDataAPIKey + `_name_active_idx ON ` + vars.TableDataAPIKey + `(name) WHERE revoked_at IS NULL;
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableDataAPIKey + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
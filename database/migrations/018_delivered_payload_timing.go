@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration018DeliveredPayloadTiming = &migrate.Migration{
+	Id: "018-delivered-payload-timing",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD get_header_ms_into_slot bigint;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD get_payload_ms_into_slot bigint;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD publish_duration_ms bigint;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD broadcast_duration_ms bigint;
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN get_header_ms_into_slot;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN get_payload_ms_into_slot;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN publish_duration_ms;
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN broadcast_duration_ms;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration019BuilderScoreboard = &migrate.Migration{
+	Id: "019-builder-scoreboard",
+	// the builder performance is aggregated per day by the housekeeper, and summed up in the builder stats view. The
+	// sums are stored rather than the averages, so the days can be combined.
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableBuilderDailyAggregates + ` (
+			day            date NOT NULL,
+			builder_pubkey varchar(98) NOT NULL,
+			updated_at     timestamp NOT NULL default current_timestamp,
+
+			num_submissions     bigint NOT NULL,
+			num_sim_errors      bigint NOT NULL,
+			num_slots_submitted bigint NOT NULL,
+
+			sum_top_bid_gap           NUMERIC(48, 0) NOT NULL,
+			sum_submission_latency_ms bigint NOT NULL,
+
+			PRIMARY KEY (day, builder_pubkey)
+		);
+
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewBuilderStats + `;
+
+		CREATE MATERIALIZED VIEW ` + vars.ViewBuilderStats + ` AS
+		WITH submissions AS (
+			SELECT builder_pubkey,
+				COUNT(*) AS num_submissions,
+				COUNT(*) FILTER (WHERE NOT sim_success) AS num_sim_errors,
+				COUNT(DISTINCT slot) FILTER (WHERE sim_success) AS num_slots_submitted
+			FROM ` + vars.TableBuilderBlockSubmission + `
+			WHERE received_at > now() - interval '30 days'
+			GROUP BY builder_pubkey
+		), delivered AS (
+			SELECT builder_pubkey,
+				COUNT(*) AS num_blocks_delivered,
+				SUM(value) AS total_value
+			FROM ` + vars.TableDeliveredPayload + `
+			WHERE inserted_at > now() - interval '30 days'
+			GROUP BY builder_pubkey
+		), performance AS (
+			SELECT builder_pubkey,
+				SUM(sum_top_bid_gap) / NULLIF(SUM(num_slots_submitted), 0) AS avg_top_bid_gap,
+				SUM(sum_submission_latency_ms) / NULLIF(SUM(num_submissions), 0) AS avg_submission_latency_ms
+			FROM ` + vars.TableBuilderDailyAggregates + `
+			WHERE day > (now() - interval '30 days')::date
+			GROUP BY builder_pubkey
+		)
+		SELECT s.builder_pubkey,
+			s.num_submissions,
+			s.num_sim_errors,
+			s.num_slots_submitted,
+			COALESCE(d.num_blocks_delivered, 0) AS num_blocks_delivered,
+			COALESCE(d.total_value, 0) AS total_value,
+			COALESCE(ROUND(p.avg_top_bid_gap), 0) AS avg_top_bid_gap,
+			COALESCE(ROUND(p.avg_submission_latency_ms), 0)::bigint AS avg_submission_latency_ms
+		FROM submissions s
+		LEFT JOIN delivered d ON d.builder_pubkey = s.builder_pubkey
+		LEFT JOIN performance p ON p.builder_pubkey = s.builder_pubkey
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewBuilderStats + `_builderpubkey_uidx ON ` + vars.ViewBuilderStats + `(builder_pubkey);
+	`},
+	Down: []string{`
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewBuilderStats + `;
+
+		CREATE MATERIALIZED VIEW ` + vars.ViewBuilderStats + ` AS
+		WITH submissions AS (
+			SELECT builder_pubkey,
+				COUNT(*) AS num_submissions,
+				COUNT(*) FILTER (WHERE NOT sim_success) AS num_sim_errors,
+				COUNT(DISTINCT slot) FILTER (WHERE sim_success) AS num_slots_submitted
+			FROM ` + vars.TableBuilderBlockSubmission + `
+			WHERE received_at > now() - interval '30 days'
+			GROUP BY builder_pubkey
+		), delivered AS (
+			SELECT builder_pubkey,
+				COUNT(*) AS num_blocks_delivered,
+				SUM(value) AS total_value
+			FROM ` + vars.TableDeliveredPayload + `
+			WHERE inserted_at > now() - interval '30 days'
+			GROUP BY builder_pubkey
+		)
+		SELECT s.builder_pubkey,
+			s.num_submissions,
+			s.num_sim_errors,
+			s.num_slots_submitted,
+			COALESCE(d.num_blocks_delivered, 0) AS num_blocks_delivered,
+			COALESCE(d.total_value, 0) AS total_value
+		FROM submissions s
+		LEFT JOIN delivered d ON d.builder_pubkey = s.builder_pubkey
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewBuilderStats + `_builderpubkey_uidx ON ` + vars.ViewBuilderStats + `(builder_pubkey);
+
+		DROP TABLE IF EXISTS ` + vars.TableBuilderDailyAggregates + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration020BidHistory = &migrate.Migration{
+	Id: "020-bid-history",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableBidHistory + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+			changed_at  timestamp NOT NULL,
+
+			slot            bigint NOT NULL,
+			parent_hash     varchar(66) NOT NULL,
+			proposer_pubkey varchar(98) NOT NULL,
+			builder_pubkey  varchar(98) NOT NULL,
+			value           NUMERIC(48, 0) NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + vars.TableBidHistory + `_slot_idx ON ` + vars.TableBidHistory + `("slot");
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableBidHistory + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
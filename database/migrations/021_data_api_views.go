@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration021DataAPIViews = &migrate.Migration{
+	Id: "021-data-api-views",
+	// like the stats views, the views are created empty and populated by the housekeeper. The unique indexes allow
+	// refreshing them concurrently.
+	Up: []string{`
+		CREATE MATERIALIZED VIEW IF NOT EXISTS ` + vars.ViewDeliveredPayloadCount + ` AS
+		SELECT 1 AS id, COUNT(*) AS num_payloads_delivered
+		FROM ` + vars.TableDeliveredPayload + `
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewDeliveredPayloadCount + `_id_uidx ON ` + vars.ViewDeliveredPayloadCount + `(id);
+
+		CREATE MATERIALIZED VIEW IF NOT EXISTS ` + vars.ViewEpochStats + ` AS
+		SELECT epoch,
+			COUNT(*) AS num_payloads_delivered,
+			COUNT(DISTINCT builder_pubkey) AS num_builders,
+			SUM(value) AS total_value
+		FROM ` + vars.TableDeliveredPayload + `
+		WHERE inserted_at > now() - interval '7 days'
+		GROUP BY epoch
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewEpochStats + `_epoch_uidx ON ` + vars.ViewEpochStats + `(epoch);
+
+		CREATE MATERIALIZED VIEW IF NOT EXISTS ` + vars.ViewTopBuilders + ` AS
+		SELECT builder_pubkey,
+			COUNT(*) AS num_blocks_delivered,
+			SUM(value) AS total_value
+		FROM ` + vars.TableDeliveredPayload + `
+		WHERE inserted_at > now() - interval '7 days'
+		GROUP BY builder_pubkey
+		WITH NO DATA;
+
+		CREATE UNIQUE INDEX IF NOT EXISTS ` + vars.ViewTopBuilders + `_builderpubkey_uidx ON ` + vars.ViewTopBuilders + `(builder_pubkey);
+	`},
+	Down: []string{`
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewDeliveredPayloadCount + `;
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewEpochStats + `;
+		DROP MATERIALIZED VIEW IF EXISTS ` + vars.ViewTopBuilders + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
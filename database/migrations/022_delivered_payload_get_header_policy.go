@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration022DeliveredPayloadGetHeaderPolicy = &migrate.Migration{
+	Id: "022-delivered-payload-get-header-policy",
+	Up: []string{`
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` ADD get_header_policy text NOT NULL default '';
+	`},
+	Down: []string{`
+		ALTER TABLE ` + vars.TableDeliveredPayload + ` DROP COLUMN get_header_policy;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration023ArchiverCheckpoints = &migrate.Migration{
+	Id: "023-archiver-checkpoints",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableArchiverCheckpoint + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+			updated_at  timestamp NOT NULL default current_timestamp,
+
+			sink   text NOT NULL,
+			stream text NOT NULL,
+
+			last_id   bigint NOT NULL,
+			last_slot bigint NOT NULL,
+
+			UNIQUE (sink, stream)
+		);
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableArchiverCheckpoint + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/flashbots/mev-boost-relay/database/vars"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+var Migration024AdminAuditLog = &migrate.Migration{
+	Id: "024-admin-audit-log",
+	Up: []string{`
+		CREATE TABLE IF NOT EXISTS ` + vars.TableAdminAuditLog + ` (
+			id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			inserted_at timestamp NOT NULL default current_timestamp,
+
+			operator    text NOT NULL,
+			remote_addr text NOT NULL default '',
+
+			action    text NOT NULL,
+			target    text NOT NULL,
+			old_value text NOT NULL default '',
+			new_value text NOT NULL default ''
+		);
+	`, `
+		CREATE INDEX IF NOT EXISTS ` + vars.TableAdminAuditLog + `_target_idx ON ` + vars.TableAdminAuditLog + `("target", "id");
+	`},
+	Down: []string{`
+		DROP TABLE IF EXISTS ` + vars.TableAdminAuditLog + `;
+	`},
+	DisableTransactionUp:   false,
+	DisableTransactionDown: false,
+}
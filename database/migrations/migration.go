@@ -9,5 +9,27 @@ var Migrations = migrate.MemoryMigrationSource{
 	Migrations: []*migrate.Migration{
 		Migration001InitDatabase,
 		Migration002RemoveIsBestAddReceivedAt,
+		Migration003DeliveredPayloadPublishStatus,
+		Migration004GetPayloadFailure,
+		Migration005OptimisticBuilders,
+		Migration006BlockBuilderAPIKey,
+		Migration007DataAPIRangeIndexes,
+		Migration008StatsViews,
+		Migration009DailyAggregates,
+		Migration010BlocklistFiltered,
+		Migration011SubmissionVerifiedValue,
+		Migration012PayloadCompression,
+		Migration013PeerBids,
+		Migration014ProposerAllowlist,
+		Migration015SubmissionCancellations,
+		Migration016BuilderPromotions,
+		Migration017DataAPIKeys,
+		Migration018DeliveredPayloadTiming,
+		Migration019BuilderScoreboard,
+		Migration020BidHistory,
+		Migration021DataAPIViews,
+		Migration022DeliveredPayloadGetHeaderPolicy,
+		Migration023ArchiverCheckpoints,
+		Migration024AdminAuditLog,
 	},
 }
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"github.com/flashbots/mev-boost-relay/common"
@@ -8,6 +10,10 @@ import (
 
 type MockDB struct{}
 
+func (db MockDB) Ping(ctx context.Context) error {
+	return nil
+}
+
 func (db MockDB) NumRegisteredValidators() (count uint64, err error) {
 	return 0, nil
 }
@@ -16,6 +22,10 @@ func (db MockDB) SaveValidatorRegistration(entry ValidatorRegistrationEntry) err
 	return nil
 }
 
+func (db MockDB) SaveValidatorRegistrations(entries []ValidatorRegistrationEntry) error {
+	return nil
+}
+
 func (db MockDB) GetValidatorRegistration(pubkey string) (*ValidatorRegistrationEntry, error) {
 	return nil, nil
 }
@@ -24,14 +34,22 @@ func (db MockDB) GetValidatorRegistrationsForPubkeys(pubkeys []string) (entries
 	return nil, nil
 }
 
+func (db MockDB) GetValidatorFeeRecipients(pubkeys []string) (map[string][]string, error) {
+	return nil, nil
+}
+
 func (db MockDB) GetLatestValidatorRegistrations(timestampOnly bool) ([]*ValidatorRegistrationEntry, error) {
 	return nil, nil
 }
 
-func (db MockDB) SaveBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest, simError error, receivedAt time.Time) (entry *BuilderBlockSubmissionEntry, err error) {
+func (db MockDB) SaveBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest, simError error, receivedAt time.Time, verifiedValue string, optimisticSubmission bool) (entry *BuilderBlockSubmissionEntry, err error) {
 	return nil, nil
 }
 
+func (db MockDB) MarkCancelledBuilderSubmissions(entry *BuilderBlockSubmissionEntry) error {
+	return nil
+}
+
 func (db MockDB) GetExecutionPayloadEntryByID(executionPayloadID int64) (entry *ExecutionPayloadEntry, err error) {
 	return nil, nil
 }
@@ -60,6 +78,30 @@ func (db MockDB) GetDeliveredPayloads(idFirst, idLast uint64) (entries []*Delive
 	return nil, nil
 }
 
+func (db MockDB) SetDeliveredPayloadPublishStatus(slot uint64, proposerPubkey, blockHash string, confirmed bool, numAttempts uint64, publishDuration, broadcastDuration time.Duration) error {
+	return nil
+}
+
+func (db MockDB) SaveGetPayloadFailure(entry GetPayloadFailureEntry) error {
+	return nil
+}
+
+func (db MockDB) SaveBlocklistFiltered(entry BlocklistFilteredEntry) error {
+	return nil
+}
+
+func (db MockDB) SavePeerBid(entry PeerBidEntry) error {
+	return nil
+}
+
+func (db MockDB) SaveBidHistory(entries []*BidHistoryEntry) error {
+	return nil
+}
+
+func (db MockDB) GetProposerAllowlist() ([]string, error) {
+	return nil, nil
+}
+
 func (db MockDB) GetNumDeliveredPayloads() (uint64, error) {
 	return 0, nil
 }
@@ -72,7 +114,7 @@ func (db MockDB) GetBuilderSubmissionsBySlots(slotFrom, slotTo uint64) (entries
 	return nil, nil
 }
 
-func (db MockDB) SaveDeliveredPayload(bidTrace *common.BidTraceV2, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock) error {
+func (db MockDB) SaveDeliveredPayload(bidTrace *common.BidTraceV2, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock, timing DeliveredPayloadTiming) error {
 	return nil
 }
 
@@ -99,3 +141,115 @@ func (db MockDB) IncBlockBuilderStatsAfterGetHeader(slot uint64, blockhash strin
 func (db MockDB) IncBlockBuilderStatsAfterGetPayload(builderPubkey string) error {
 	return nil
 }
+
+func (db MockDB) SetBlockBuilderCollateral(pubkey, collateral string, isOptimistic bool) error {
+	return nil
+}
+
+func (db MockDB) SetBlockBuilderAPIKeyHash(pubkey, apiKeyHash string) error {
+	return nil
+}
+
+func (db MockDB) InsertBuilderDemotion(bidTrace *common.BidTraceV2, simError error) error {
+	return nil
+}
+
+func (db MockDB) SetBuilderDemotionRefundRequired(slot uint64, blockHash string) (refundRequired bool, err error) {
+	return false, nil
+}
+
+func (db MockDB) SetBuilderDemotionRefundConfirmed(builderPubkey string) (numDemotions int64, err error) {
+	return 0, nil
+}
+
+func (db MockDB) GetOpenBuilderDemotions() ([]*BuilderDemotionEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetNumSuccessfulBuilderSubmissions(builderPubkey string, since time.Time) (uint64, error) {
+	return 0, nil
+}
+
+func (db MockDB) PromoteBuilder(builderPubkey, reason string) error {
+	return nil
+}
+
+func (db MockDB) InsertDataAPIKey(entry DataAPIKeyEntry) (*DataAPIKeyEntry, error) {
+	return &entry, nil
+}
+
+func (db MockDB) GetDataAPIKeys() ([]*DataAPIKeyEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) RevokeDataAPIKey(name string) error {
+	return nil
+}
+
+func (db MockDB) RefreshStatsViews() error {
+	return nil
+}
+
+func (db MockDB) GetBuilderStats() ([]*BuilderStatsEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetBuilderStatsByPubkey(pubkey string) (*BuilderStatsEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetDailyStats() ([]*DailyStatsEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetEpochStats(numEpochs uint64) ([]*EpochStatsEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetTopBuilders(limit uint64) ([]*TopBuilderEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) StreamDeliveredPayloads(ctx context.Context, slotFrom, slotTo uint64, fn func(*DeliveredPayloadEntry) error) error {
+	return nil
+}
+
+func (db MockDB) StreamBuilderSubmissions(ctx context.Context, slotFrom, slotTo uint64, fn func(*BuilderBlockSubmissionEntry) error) error {
+	return nil
+}
+
+func (db MockDB) UpdateDailyAggregates() error {
+	return nil
+}
+
+func (db MockDB) GetDailyAggregates(numDays uint64) ([]*DailyAggregateEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) UpdateBuilderDailyAggregates(genesisTime uint64) error {
+	return nil
+}
+
+func (db MockDB) GetDeliveredPayloadsAfterID(afterID, limit uint64) ([]*DeliveredPayloadEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetBuilderSubmissionsAfterID(afterID uint64, insertedBefore time.Time, limit uint64) ([]*BuilderBlockSubmissionEntry, error) {
+	return nil, nil
+}
+
+func (db MockDB) GetArchiverCheckpoint(sink, stream string) (*ArchiverCheckpointEntry, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (db MockDB) SetArchiverCheckpoint(sink, stream string, lastID, lastSlot uint64) error {
+	return nil
+}
+
+func (db MockDB) InsertAdminAuditLog(entry AdminAuditLogEntry) error {
+	return nil
+}
+
+func (db MockDB) GetAdminAuditLog(target string, limit uint64) ([]*AdminAuditLogEntry, error) {
+	return nil, nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/jmoiron/sqlx"
+)
+
+// number of rows fetched from the server-side cursor at a time
+var streamFetchSize = cli.GetEnvInt("DB_STREAM_FETCH_SIZE", 1000)
+
+// streamRows runs the query through a server-side cursor and calls fn for every row, so that large results are never
+// held in memory at once. Stops at the first error returned by fn.
+func streamRows[T any](ctx context.Context, db *sqlx.DB, query string, fn func(*T) error, args ...any) error {
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true}) //nolint:exhaustruct
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = tx.ExecContext(ctx, "DECLARE stream_cursor NO SCROLL CURSOR FOR "+query, args...)
+	if err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM stream_cursor", streamFetchSize)
+	for {
+		numRows, err := fetchRows(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		} else if numRows < streamFetchSize {
+			return nil
+		}
+	}
+}
+
+func fetchRows[T any](ctx context.Context, tx *sqlx.Tx, fetch string, fn func(*T) error) (numRows int, err error) {
+	rows, err := tx.QueryxContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := new(T)
+		if err := rows.StructScan(entry); err != nil {
+			return numRows, err
+		}
+		numRows++
+		if err := fn(entry); err != nil {
+			return numRows, err
+		}
+	}
+	return numRows, rows.Err()
+}
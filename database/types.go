@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
 )
 
 func NewNullInt64(i int64) sql.NullInt64 {
@@ -29,15 +30,53 @@ func NewNullTime(t time.Time) sql.NullTime {
 	}
 }
 
+// PageCursor marks the last entry of a page: the next page starts with the entries before it, ordered by slot and id
+type PageCursor struct {
+	Slot uint64
+	ID   int64
+}
+
+// RangeFilters limits data API queries to a value (in wei) and time range. Empty values and zero times are ignored.
+type RangeFilters struct {
+	ValueMin string
+	ValueMax string
+	FromTime time.Time // inclusive
+	ToTime   time.Time // exclusive
+}
+
+// whereConds returns the conditions for the set filters, and adds their query arguments
+func (f RangeFilters) whereConds(timeColumn string, arg map[string]interface{}) []string {
+	conds := []string{}
+	if f.ValueMin != "" {
+		conds = append(conds, "value >= :value_min")
+		arg["value_min"] = f.ValueMin
+	}
+	if f.ValueMax != "" {
+		conds = append(conds, "value <= :value_max")
+		arg["value_max"] = f.ValueMax
+	}
+	if !f.FromTime.IsZero() {
+		conds = append(conds, timeColumn+" >= :from_time")
+		arg["from_time"] = f.FromTime.UTC()
+	}
+	if !f.ToTime.IsZero() {
+		conds = append(conds, timeColumn+" < :to_time")
+		arg["to_time"] = f.ToTime.UTC()
+	}
+	return conds
+}
+
 type GetPayloadsFilters struct {
 	Slot           uint64
 	Cursor         uint64
+	PageCursor     *PageCursor
 	Limit          uint64
 	BlockHash      string
 	BlockNumber    uint64
 	ProposerPubkey string
 	BuilderPubkey  string
 	OrderByValue   int8
+	RangeFilters
 }
 
 type GetBuilderSubmissionsFilters struct {
@@ -47,6 +86,12 @@ type GetBuilderSubmissionsFilters struct {
 	BlockNumber uint64
 	// Cursor      uint64
 	BuilderPubkey string
+
+	ExcludeCancelled bool
+
+	PageCursor *PageCursor
+	Paginated  bool // keeps the limit when filtering by slot, block_number or block_hash
+	RangeFilters
 }
 
 type ValidatorRegistrationEntry struct {
@@ -106,7 +151,40 @@ type ExecutionPayloadEntry struct {
 	BlockHash      string `db:"block_hash"`
 
 	Version string `db:"version"`
-	Payload string `db:"payload"`
+	Payload string `db:"payload"` // JSON, also once a compressed payload is read
+
+	PayloadCodec      string `db:"payload_codec"`
+	PayloadCompressed []byte `db:"payload_compressed"`
+}
+
+// compress moves the payload to PayloadCompressed, compressed with the codec. Nothing changes without codec.
+func (e *ExecutionPayloadEntry) compress(codec string) error {
+	if codec == common.PayloadCodecNone {
+		return nil
+	}
+	compressed, err := common.CompressPayload(codec, []byte(e.Payload))
+	if err != nil {
+		return err
+	}
+	e.Payload = ""
+	e.PayloadCodec = codec
+	e.PayloadCompressed = compressed
+	return nil
+}
+
+// decompress sets the payload of an entry read from the database, if it's compressed
+func (e *ExecutionPayloadEntry) decompress() error {
+	if e.PayloadCodec == common.PayloadCodecNone {
+		return nil
+	}
+	payload, err := common.DecompressPayload(e.PayloadCodec, e.PayloadCompressed)
+	if err != nil {
+		return err
+	}
+	e.Payload = string(payload)
+	e.PayloadCodec = common.PayloadCodecNone
+	e.PayloadCompressed = nil
+	return nil
 }
 
 var ExecutionPayloadEntryCSVHeader = []string{"id", "inserted_at", "slot", "proposer_pubkey", "block_hash", "version", "payload"}
@@ -152,6 +230,13 @@ type BuilderBlockSubmissionEntry struct {
 	NumTx uint64 `db:"num_tx"`
 	Value string `db:"value"`
 
+	// Payment to the proposer as measured by the simulation, null if not simulated or not reported
+	VerifiedValue sql.NullString `db:"verified_value"`
+
+	// Accepted before the simulation, and cancelled by a later lower bid of the builder
+	OptimisticSubmission bool `db:"optimistic_submission"`
+	WasCancelled         bool `db:"was_cancelled"`
+
 	// Helpers
 	Epoch       uint64 `db:"epoch"`
 	BlockNumber uint64 `db:"block_number"`
@@ -179,6 +264,85 @@ type DeliveredPayloadEntry struct {
 
 	NumTx uint64 `db:"num_tx"`
 	Value string `db:"value"`
+
+	// Publishing outcome, set after the relay tried to confirm the block became part of the chain
+	PublishConfirmed sql.NullBool `db:"publish_confirmed"`
+	PublishAttempts  uint64       `db:"publish_attempts"`
+
+	// Timing of the delivery in ms, null if unknown (e.g. no getHeader call seen, or publishing disabled)
+	GetHeaderMsIntoSlot  sql.NullInt64 `db:"get_header_ms_into_slot"`  // latest getHeader call of the proposer returning a bid
+	GetPayloadMsIntoSlot sql.NullInt64 `db:"get_payload_ms_into_slot"` // arrival of the getPayload call
+	PublishDurationMs    sql.NullInt64 `db:"publish_duration_ms"`      // publishing the block on the beacon node
+	BroadcastDurationMs  sql.NullInt64 `db:"broadcast_duration_ms"`    // from the arrival of the getPayload call until the block was published
+
+	GetHeaderPolicy string `db:"get_header_policy"` // delay policy of the getHeader responses, empty if none
+}
+
+// DeliveredPayloadTiming is the timing of the proposer calls of a delivered payload, relative to the slot start
+type DeliveredPayloadTiming struct {
+	GetHeaderMsIntoSlot  sql.NullInt64
+	GetPayloadMsIntoSlot sql.NullInt64
+	GetHeaderPolicy      string
+}
+
+// GetPayloadFailureEntry records why a getPayload request was rejected
+type GetPayloadFailureEntry struct {
+	ID         int64     `db:"id"`
+	InsertedAt time.Time `db:"inserted_at"`
+
+	Slot           uint64 `db:"slot"`
+	ProposerIndex  uint64 `db:"proposer_index"`
+	ProposerPubkey string `db:"proposer_pubkey"`
+	BlockHash      string `db:"block_hash"`
+
+	Reason string `db:"reason"`
+}
+
+// PeerBidEntry records a bid received from a peer relay, whose payload is held by the peer
+type PeerBidEntry struct {
+	ID         int64     `db:"id"`
+	InsertedAt time.Time `db:"inserted_at"`
+	ReceivedAt time.Time `db:"received_at"`
+
+	PeerRelayPubkey string `db:"peer_relay_pubkey"`
+
+	Slot                 uint64 `db:"slot"`
+	ParentHash           string `db:"parent_hash"`
+	BlockHash            string `db:"block_hash"`
+	BuilderPubkey        string `db:"builder_pubkey"`
+	ProposerPubkey       string `db:"proposer_pubkey"`
+	ProposerFeeRecipient string `db:"proposer_fee_recipient"`
+
+	GasUsed  uint64 `db:"gas_used"`
+	GasLimit uint64 `db:"gas_limit"`
+	Value    string `db:"value"`
+}
+
+// BidHistoryEntry records a change of the top bid of a slot, as observed by a relay instance
+type BidHistoryEntry struct {
+	ID         int64     `db:"id"          json:"-"`
+	InsertedAt time.Time `db:"inserted_at" json:"-"`
+	ChangedAt  time.Time `db:"changed_at"  json:"changed_at"`
+
+	Slot           uint64 `db:"slot"            json:"slot"`
+	ParentHash     string `db:"parent_hash"     json:"parent_hash"`
+	ProposerPubkey string `db:"proposer_pubkey" json:"proposer_pubkey"`
+	BuilderPubkey  string `db:"builder_pubkey"  json:"builder_pubkey"`
+	Value          string `db:"value"           json:"value"`
+}
+
+// BlocklistFilteredEntry records a block submission rejected because it involves a blocklisted address
+type BlocklistFilteredEntry struct {
+	ID         int64     `db:"id"`
+	InsertedAt time.Time `db:"inserted_at"`
+
+	Slot           uint64 `db:"slot"`
+	BuilderPubkey  string `db:"builder_pubkey"`
+	ProposerPubkey string `db:"proposer_pubkey"`
+	BlockHash      string `db:"block_hash"`
+
+	Address string `db:"address"`
+	Reason  string `db:"reason"`
 }
 
 type BlockBuilderEntry struct {
@@ -198,4 +362,137 @@ type BlockBuilderEntry struct {
 	NumSubmissionsSimError uint64 `db:"num_submissions_simerror" json:"num_submissions_simerror"`
 
 	NumSentGetPayload uint64 `db:"num_sent_getpayload" json:"num_sent_getpayload"`
+
+	// Optimistic relaying: blocks up to the collateral value are accepted before simulation
+	Collateral   string `db:"collateral"    json:"collateral"`
+	IsOptimistic bool   `db:"is_optimistic" json:"is_optimistic"`
+
+	// sha256 of the builder's API key, if one was issued
+	APIKeyHash sql.NullString `db:"api_key_hash" json:"-"`
+}
+
+// BuilderDemotionEntry records an optimistic block which failed simulation, whether a refund is owed to the proposer, and
+// when the builder was promoted again
+type BuilderDemotionEntry struct {
+	ID         int64     `db:"id"`
+	InsertedAt time.Time `db:"inserted_at"`
+
+	Slot  uint64 `db:"slot"`
+	Epoch uint64 `db:"epoch"`
+
+	BuilderPubkey        string `db:"builder_pubkey"`
+	ProposerPubkey       string `db:"proposer_pubkey"`
+	ProposerFeeRecipient string `db:"proposer_fee_recipient"`
+
+	BlockHash string `db:"block_hash"`
+	Value     string `db:"value"`
+
+	SimError string `db:"sim_error"`
+
+	RefundRequired           bool           `db:"refund_required"`
+	RefundConfirmed          bool           `db:"refund_confirmed"`
+	SignedBlindedBeaconBlock sql.NullString `db:"signed_blinded_beacon_block"`
+
+	// set once optimistic mode is enabled again for the builder, manually or by the promotion policy of the housekeeper
+	PromotedAt      sql.NullTime `db:"promoted_at"`
+	PromotionReason string       `db:"promotion_reason"`
+}
+
+// BuilderStatsEntry is a row of the per-builder stats view, covering the last 30 days. The top bid gap is in wei,
+// averaged over the slots the builder submitted a valid block for.
+type BuilderStatsEntry struct {
+	BuilderPubkey      string `db:"builder_pubkey"`
+	NumSubmissions     uint64 `db:"num_submissions"`
+	NumSimErrors       uint64 `db:"num_sim_errors"`
+	NumSlotsSubmitted  uint64 `db:"num_slots_submitted"`
+	NumBlocksDelivered uint64 `db:"num_blocks_delivered"`
+	TotalValue         string `db:"total_value"`
+
+	AvgTopBidGap           string `db:"avg_top_bid_gap"`
+	AvgSubmissionLatencyMs int64  `db:"avg_submission_latency_ms"`
+}
+
+// DailyStatsEntry is a row of the per-day stats view, covering the last 30 days
+type DailyStatsEntry struct {
+	Day                time.Time `db:"day"`
+	NumSubmissions     uint64    `db:"num_submissions"`
+	NumSimErrors       uint64    `db:"num_sim_errors"`
+	NumBlocksDelivered uint64    `db:"num_blocks_delivered"`
+	NumBuilders        uint64    `db:"num_builders"`
+	TotalValue         string    `db:"total_value"`
+}
+
+// EpochStatsEntry is a row of the per-epoch stats view, covering the last 7 days
+type EpochStatsEntry struct {
+	Epoch                uint64 `db:"epoch"`
+	NumPayloadsDelivered uint64 `db:"num_payloads_delivered"`
+	NumBuilders          uint64 `db:"num_builders"`
+	TotalValue           string `db:"total_value"`
+}
+
+// TopBuilderEntry is a row of the top builders view, covering the last 7 days
+type TopBuilderEntry struct {
+	BuilderPubkey      string `db:"builder_pubkey"`
+	NumBlocksDelivered uint64 `db:"num_blocks_delivered"`
+	TotalValue         string `db:"total_value"`
+}
+
+// DailyAggregateEntry holds the delivered payload aggregates of one completed day
+type DailyAggregateEntry struct {
+	Day                  time.Time `db:"day"`
+	NumPayloadsDelivered uint64    `db:"num_payloads_delivered"`
+	TotalValue           string    `db:"total_value"`
+	NumBuilders          uint64    `db:"num_builders"`
+}
+
+// DataAPIKeyEntry is an API key issued to a data API consumer, only the hash of the key is stored
+type DataAPIKeyEntry struct {
+	ID         int64        `db:"id"          json:"id"`
+	InsertedAt time.Time    `db:"inserted_at" json:"inserted_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at"  json:"-"`
+
+	Name    string `db:"name"     json:"name"`
+	KeyHash string `db:"key_hash" json:"-"`
+	Tier    string `db:"tier"     json:"tier"`
+
+	// overrides of the rate limit of the tier, 0 for the tier default
+	RateLimitPerSec int `db:"rate_limit_per_sec" json:"rate_limit_per_sec"`
+	RateLimitBurst  int `db:"rate_limit_burst"   json:"rate_limit_burst"`
+}
+
+// ArchiverCheckpointEntry is the last entry of a stream the archiver exported to a sink, it continues after it
+type ArchiverCheckpointEntry struct {
+	Sink      string    `db:"sink"`
+	Stream    string    `db:"stream"`
+	UpdatedAt time.Time `db:"updated_at"`
+
+	LastID   uint64 `db:"last_id"`
+	LastSlot uint64 `db:"last_slot"`
+}
+
+// The actions recorded in the admin audit log
+const (
+	AdminAuditActionBuilderStatus          = "builder_status"
+	AdminAuditActionBuilderCollateral      = "builder_collateral"
+	AdminAuditActionBuilderRefundConfirmed = "builder_refund_confirmed"
+	AdminAuditActionFeatureFlag            = "feature_flag"
+	AdminAuditActionDataAPIKeyIssued       = "data_api_key_issued"
+	AdminAuditActionDataAPIKeyRevoked      = "data_api_key_revoked"
+	AdminAuditActionBlocklist              = "blocklist"
+	AdminAuditActionProposerAllowlist      = "proposer_allowlist"
+)
+
+// AdminAuditLogEntry is a change of the relay configuration, by whom and from which value to which. The log is append
+// only.
+type AdminAuditLogEntry struct {
+	ID         int64     `db:"id"          json:"id"`
+	InsertedAt time.Time `db:"inserted_at" json:"inserted_at"`
+
+	Operator   string `db:"operator"    json:"operator"`
+	RemoteAddr string `db:"remote_addr" json:"remote_addr"`
+
+	Action   string `db:"action"    json:"action"`
+	Target   string `db:"target"    json:"target"`
+	OldValue string `db:"old_value" json:"old_value"`
+	NewValue string `db:"new_value" json:"new_value"`
 }
@@ -1,35 +1,34 @@
 package database
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/common"
 )
 
+var ErrUnknownPayloadVersion = errors.New("unknown execution payload version")
+
 func PayloadToExecPayloadEntry(payload *common.BuilderSubmitBlockRequest) (*ExecutionPayloadEntry, error) {
-	var _payload []byte
-	var version string
-	var err error
-	if payload.Bellatrix != nil {
-		_payload, err = json.Marshal(payload.Bellatrix.ExecutionPayload)
-		if err != nil {
-			return nil, err
-		}
-		version = "bellatrix"
-	}
-	if payload.Capella != nil {
-		_payload, err = json.Marshal(payload.Capella.ExecutionPayload)
-		if err != nil {
-			return nil, err
-		}
-		version = "capella"
+	versioned := payload.Versioned()
+	_payload, err := versioned.ExecutionPayloadJSON()
+	if err != nil {
+		return nil, err
 	}
 	return &ExecutionPayloadEntry{
 		Slot:           payload.Slot(),
 		ProposerPubkey: payload.ProposerPubkey(),
 		BlockHash:      payload.BlockHash(),
 
-		Version: version,
+		Version: versioned.Version(),
 		Payload: string(_payload),
 	}, nil
 }
@@ -50,6 +49,25 @@ func DeliveredPayloadEntryToBidTraceV2JSON(payload *DeliveredPayloadEntry) commo
 	}
 }
 
+// DeliveredPayloadEntryToBidTraceV2WithTimingJSON converts a delivered payload for the data API, with its timing
+func DeliveredPayloadEntryToBidTraceV2WithTimingJSON(payload *DeliveredPayloadEntry) common.BidTraceV2WithTimingJSON {
+	return common.BidTraceV2WithTimingJSON{
+		BidTraceV2JSON:       DeliveredPayloadEntryToBidTraceV2JSON(payload),
+		GetHeaderMsIntoSlot:  nullInt64Ptr(payload.GetHeaderMsIntoSlot),
+		GetPayloadMsIntoSlot: nullInt64Ptr(payload.GetPayloadMsIntoSlot),
+		PublishDurationMs:    nullInt64Ptr(payload.PublishDurationMs),
+		BroadcastDurationMs:  nullInt64Ptr(payload.BroadcastDurationMs),
+		GetHeaderPolicy:      payload.GetHeaderPolicy,
+	}
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
 func BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(payload *BuilderBlockSubmissionEntry) common.BidTraceV2WithTimestampJSON {
 	timestamp := payload.InsertedAt
 	if payload.ReceivedAt.Valid {
@@ -57,8 +75,10 @@ func BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(payload *BuilderBlockSu
 	}
 
 	return common.BidTraceV2WithTimestampJSON{
-		Timestamp:   timestamp.Unix(),
-		TimestampMs: timestamp.UnixMilli(),
+		Timestamp:            timestamp.Unix(),
+		TimestampMs:          timestamp.UnixMilli(),
+		OptimisticSubmission: payload.OptimisticSubmission,
+		WasCancelled:         payload.WasCancelled,
 		BidTraceV2JSON: common.BidTraceV2JSON{
 			Slot:                 payload.Slot,
 			ParentHash:           payload.ParentHash,
@@ -74,3 +94,108 @@ func BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(payload *BuilderBlockSu
 		},
 	}
 }
+
+// BidTraceV2JSONToDeliveredPayloadEntry converts a delivered payload of the data API, e.g. of another relay
+func BidTraceV2JSONToDeliveredPayloadEntry(bidTrace *common.BidTraceV2JSON) *DeliveredPayloadEntry {
+	return &DeliveredPayloadEntry{
+		Slot:                 bidTrace.Slot,
+		Epoch:                bidTrace.Slot / uint64(common.SlotsPerEpoch),
+		BuilderPubkey:        bidTrace.BuilderPubkey,
+		ProposerPubkey:       bidTrace.ProposerPubkey,
+		ProposerFeeRecipient: bidTrace.ProposerFeeRecipient,
+		ParentHash:           bidTrace.ParentHash,
+		BlockHash:            bidTrace.BlockHash,
+		BlockNumber:          bidTrace.BlockNumber,
+		GasUsed:              bidTrace.GasUsed,
+		GasLimit:             bidTrace.GasLimit,
+		NumTx:                bidTrace.NumTx,
+		Value:                bidTrace.Value,
+	}
+}
+
+// BidTraceV2WithTimestampJSONToBuilderSubmissionEntry converts a received block submission of the data API, e.g. of
+// another relay. The data API only returns successfully simulated submissions.
+func BidTraceV2WithTimestampJSONToBuilderSubmissionEntry(bidTrace *common.BidTraceV2WithTimestampJSON) *BuilderBlockSubmissionEntry {
+	entry := &BuilderBlockSubmissionEntry{
+		SimSuccess:           true,
+		Slot:                 bidTrace.Slot,
+		ParentHash:           bidTrace.ParentHash,
+		BlockHash:            bidTrace.BlockHash,
+		BuilderPubkey:        bidTrace.BuilderPubkey,
+		ProposerPubkey:       bidTrace.ProposerPubkey,
+		ProposerFeeRecipient: bidTrace.ProposerFeeRecipient,
+		GasUsed:              bidTrace.GasUsed,
+		GasLimit:             bidTrace.GasLimit,
+		NumTx:                bidTrace.NumTx,
+		Value:                bidTrace.Value,
+		OptimisticSubmission: bidTrace.OptimisticSubmission,
+		WasCancelled:         bidTrace.WasCancelled,
+		Epoch:                bidTrace.Slot / uint64(common.SlotsPerEpoch),
+		BlockNumber:          bidTrace.BlockNumber,
+	}
+	if bidTrace.TimestampMs > 0 {
+		entry.ReceivedAt = NewNullTime(time.UnixMilli(bidTrace.TimestampMs))
+	} else if bidTrace.Timestamp > 0 {
+		entry.ReceivedAt = NewNullTime(time.Unix(bidTrace.Timestamp, 0))
+	}
+	return entry
+}
+
+// ExecutionPayloadEntryToSubmitBlockRequest reconstructs the builder submission of a stored execution payload from
+// the payload and the recorded submission, e.g. to simulate it again
+func ExecutionPayloadEntryToSubmitBlockRequest(payload *ExecutionPayloadEntry, submission *BuilderBlockSubmissionEntry) (*common.BuilderSubmitBlockRequest, error) {
+	bidTraceJSON, err := json.Marshal(BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(submission).BidTraceV2JSON)
+	if err != nil {
+		return nil, err
+	}
+	bidTrace := new(apiv1.BidTrace)
+	if err := json.Unmarshal(bidTraceJSON, bidTrace); err != nil {
+		return nil, fmt.Errorf("invalid bid trace: %w", err)
+	}
+	var signature boostTypes.Signature
+	if err := signature.UnmarshalText([]byte(submission.Signature)); err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	switch payload.Version {
+	case common.ForkVersionDeneb:
+		payloadAndBlobs := new(common.ExecutionPayloadAndBlobsBundle)
+		if err := json.Unmarshal([]byte(payload.Payload), payloadAndBlobs); err != nil {
+			return nil, fmt.Errorf("invalid execution payload: %w", err)
+		}
+		return &common.BuilderSubmitBlockRequest{
+			Deneb: &common.DenebSubmitBlockRequest{
+				Message:          bidTrace,
+				ExecutionPayload: payloadAndBlobs.ExecutionPayload,
+				BlobsBundle:      payloadAndBlobs.BlobsBundle,
+				Signature:        phase0.BLSSignature(signature),
+			},
+		}, nil
+	case common.ForkVersionCapella:
+		executionPayload := new(consensuscapella.ExecutionPayload)
+		if err := json.Unmarshal([]byte(payload.Payload), executionPayload); err != nil {
+			return nil, fmt.Errorf("invalid execution payload: %w", err)
+		}
+		return &common.BuilderSubmitBlockRequest{
+			Capella: &capella.SubmitBlockRequest{
+				Message:          bidTrace,
+				ExecutionPayload: executionPayload,
+				Signature:        phase0.BLSSignature(signature),
+			},
+		}, nil
+	case common.ForkVersionBellatrix:
+		executionPayload := new(boostTypes.ExecutionPayload)
+		if err := json.Unmarshal([]byte(payload.Payload), executionPayload); err != nil {
+			return nil, fmt.Errorf("invalid execution payload: %w", err)
+		}
+		return &common.BuilderSubmitBlockRequest{
+			Bellatrix: &boostTypes.BuilderSubmitBlockRequest{
+				Message:          common.BidTraceToBoostBid(bidTrace),
+				ExecutionPayload: executionPayload,
+				Signature:        signature,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPayloadVersion, payload.Version)
+	}
+}
@@ -12,4 +12,21 @@ var (
 	TableBuilderBlockSubmission = tableBase + "_builder_block_submission"
 	TableDeliveredPayload       = tableBase + "_payload_delivered"
 	TableBlockBuilder           = tableBase + "_blockbuilder"
+	TableGetPayloadFailure      = tableBase + "_getpayload_failure"
+	TableBuilderDemotions       = tableBase + "_builder_demotions"
+	TableDailyAggregates        = tableBase + "_daily_aggregates"
+	TableBlocklistFiltered      = tableBase + "_blocklist_filtered"
+	TablePeerBid                = tableBase + "_peer_bid"
+	TableProposerAllowlist      = tableBase + "_proposer_allowlist"
+	TableDataAPIKey             = tableBase + "_data_api_key"
+	TableBuilderDailyAggregates = tableBase + "_builder_daily_aggregates"
+	TableBidHistory             = tableBase + "_bid_history"
+	TableArchiverCheckpoint     = tableBase + "_archiver_checkpoint"
+	TableAdminAuditLog          = tableBase + "_admin_audit_log"
+
+	ViewBuilderStats          = tableBase + "_builder_stats"
+	ViewDailyStats            = tableBase + "_daily_stats"
+	ViewDeliveredPayloadCount = tableBase + "_delivered_payload_count"
+	ViewEpochStats            = tableBase + "_epoch_stats"
+	ViewTopBuilders           = tableBase + "_top_builders_7d"
 )
@@ -2,9 +2,13 @@
 package datastore
 
 import (
+	"database/sql"
 	"encoding/json"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/attestantio/go-builder-client/api"
 	consensusspec "github.com/attestantio/go-eth2-client/spec"
@@ -16,6 +20,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// the getPayload responses are served from redis or the database only, instead of memory first
+var disableBidMemoryCache = os.Getenv("DISABLE_BID_MEMORY_CACHE") == "1"
+
+// Sources of the getPayload responses, in the order they are looked up
+const (
+	PayloadSourceMemory   = "memory"
+	PayloadSourceRedis    = "redis"
+	PayloadSourceDatabase = "database"
+)
+
 type GetHeaderResponseKey struct {
 	Slot           uint64
 	ParentHash     string
@@ -38,6 +52,9 @@ type Datastore struct {
 	knownValidatorsByPubkey map[types.PubkeyHex]uint64
 	knownValidatorsByIndex  map[uint64]types.PubkeyHex
 	knownValidatorsLock     sync.RWMutex
+
+	// nil if disabled
+	payloadCache *payloadCache
 }
 
 func NewDatastore(log *logrus.Entry, redisCache *RedisCache, db database.IDatabaseService) (ds *Datastore, err error) {
@@ -48,6 +65,9 @@ func NewDatastore(log *logrus.Entry, redisCache *RedisCache, db database.IDataba
 		knownValidatorsByPubkey: make(map[types.PubkeyHex]uint64),
 		knownValidatorsByIndex:  make(map[uint64]types.PubkeyHex),
 	}
+	if !disableBidMemoryCache {
+		ds.payloadCache = newPayloadCache()
+	}
 
 	return ds, err
 }
@@ -113,30 +133,113 @@ func (ds *Datastore) SaveValidatorRegistration(entry types.SignedValidatorRegist
 	return nil
 }
 
-// GetGetPayloadResponse returns the getPayload response from memory or Redis or Database
+// SaveValidatorRegistrations saves a batch of validator registrations into both Redis and the database, with one
+// database statement and two Redis commands
+func (ds *Datastore) SaveValidatorRegistrations(entries []types.SignedValidatorRegistration) error {
+	dbEntries := make([]database.ValidatorRegistrationEntry, len(entries))
+	latest := make(map[types.PubkeyHex]ValidatorRegistrationMessage, len(entries))
+	for i, entry := range entries {
+		dbEntries[i] = database.SignedValidatorRegistrationToEntry(entry)
+		pk := types.NewPubkeyHex(entry.Message.Pubkey.String())
+		if entry.Message.Timestamp > latest[pk].Timestamp {
+			latest[pk] = ValidatorRegistrationMessage{
+				Timestamp:    entry.Message.Timestamp,
+				FeeRecipient: entry.Message.FeeRecipient.String(),
+				GasLimit:     entry.Message.GasLimit,
+			}
+		}
+	}
+
+	err := ds.db.SaveValidatorRegistrations(dbEntries)
+	if err != nil {
+		return errors.Wrap(err, "failed saving validator registrations to database")
+	}
+
+	err = ds.redis.SetValidatorRegistrationsIfNewer(latest)
+	if err != nil {
+		return errors.Wrap(err, "failed saving validator registrations to redis")
+	}
+
+	return nil
+}
+
+// SaveExecutionPayload saves the getPayload response of a builder's bid to redis, and keeps it in memory as the latest
+// payload of the builder for the slot
+func (ds *Datastore) SaveExecutionPayload(slot uint64, proposerPubkey, builderPubkey, blockHash string, resp *common.GetPayloadResponse) error {
+	if ds.payloadCache != nil {
+		key := GetPayloadResponseKey{
+			Slot:           slot,
+			ProposerPubkey: strings.ToLower(proposerPubkey),
+			BlockHash:      strings.ToLower(blockHash),
+		}
+		ds.payloadCache.set(key, strings.ToLower(builderPubkey), &common.VersionedExecutionPayload{
+			Bellatrix: resp.Bellatrix,
+			Capella:   resp.Capella,
+			Deneb:     resp.Deneb,
+		})
+	}
+	return ds.redis.SaveExecutionPayload(slot, proposerPubkey, blockHash, resp)
+}
+
+// GetGetPayloadResponse returns the getPayload response from memory or Redis or Database. Errors of memory and Redis
+// fall through to the next source, the duration and result of each lookup is recorded. Returns nil if not found.
 func (ds *Datastore) GetGetPayloadResponse(slot uint64, proposerPubkey, blockHash string) (*common.VersionedExecutionPayload, error) {
 	_proposerPubkey := strings.ToLower(proposerPubkey)
 	_blockHash := strings.ToLower(blockHash)
+	log := ds.log.WithField("slot", slot)
 
-	// 1. try to get from Redis
+	// 1. try to get from memory
+	if ds.payloadCache != nil {
+		start := time.Now()
+		resp := ds.payloadCache.get(GetPayloadResponseKey{Slot: slot, ProposerPubkey: _proposerPubkey, BlockHash: _blockHash})
+		observePayloadLookup(PayloadSourceMemory, resp != nil, nil, start)
+		if resp != nil {
+			log.Debug("getPayload response from memory")
+			return resp, nil
+		}
+	}
+
+	// 2. try to get from Redis
+	start := time.Now()
 	resp, err := ds.redis.GetExecutionPayload(slot, _proposerPubkey, _blockHash)
+	observePayloadLookup(PayloadSourceRedis, resp != nil, err, start)
 	if err != nil {
-		ds.log.WithError(err).Error("error getting getPayload response from redis")
-	} else {
-		ds.log.Debug("getPayload response from redis")
+		log.WithError(err).Error("error getting getPayload response from redis")
+	} else if resp != nil {
+		log.Debug("getPayload response from redis")
 		return resp, nil
 	}
 
-	// 2. try to get from database
+	// 3. try to get from database
+	start = time.Now()
 	blockSubEntry, err := ds.db.GetExecutionPayloadEntryBySlotPkHash(slot, proposerPubkey, blockHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		observePayloadLookup(PayloadSourceDatabase, false, nil, start)
+	} else {
+		observePayloadLookup(PayloadSourceDatabase, blockSubEntry != nil, err, start)
+	}
 	if err != nil {
 		return nil, err
+	} else if blockSubEntry == nil {
+		return nil, nil
 	}
 
-	ds.log.Debug("getPayload response from database")
-	// deserialize execution payload
+	log.Debug("getPayload response from database")
+	// deserialize execution payload, stored with the blobs bundle from deneb on
+	if blockSubEntry.Version == common.ForkVersionDeneb {
+		payloadAndBlobs := new(common.ExecutionPayloadAndBlobsBundle)
+		if err := json.Unmarshal([]byte(blockSubEntry.Payload), payloadAndBlobs); err != nil {
+			return nil, err
+		}
+		return &common.VersionedExecutionPayload{
+			Deneb: &common.DenebGetPayloadResponse{
+				Version: common.ForkVersionDeneb,
+				Data:    payloadAndBlobs,
+			},
+		}, nil
+	}
 	var res consensusspec.DataVersion
-	err = json.Unmarshal([]byte(blockSubEntry.Version), &res)
+	err = json.Unmarshal([]byte(strconv.Quote(blockSubEntry.Version)), &res)
 	if err != nil {
 		ds.log.Debug("invalid getPayload version from database")
 		return nil, err
@@ -177,3 +280,14 @@ func (ds *Datastore) GetGetPayloadResponse(slot uint64, proposerPubkey, blockHas
 		return nil, errors.New("unknown execution payload version")
 	}
 }
+
+// observePayloadLookup records the duration of a getPayload response lookup, by source and result
+func observePayloadLookup(source string, found bool, err error, start time.Time) {
+	result := "miss"
+	if err != nil {
+		result = "error"
+	} else if found {
+		result = "hit"
+	}
+	common.GetPayloadLookupDuration.WithLabelValues(source, result).Observe(time.Since(start).Seconds())
+}
@@ -1,9 +1,11 @@
 package datastore
 
 import (
+	"context"
 	"testing"
 
 	"github.com/alicebob/miniredis/v2"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/mev-boost-relay/database"
@@ -51,3 +53,42 @@ func TestProdProposerValidatorRegistration(t *testing.T) {
 	err = copier.Copy(&reg2, &reg1)
 	require.NoError(t, err)
 }
+
+func TestGetPayloadResponseSources(t *testing.T) {
+	ds := setupTestDatastore(t)
+	require.NotNil(t, ds.payloadCache)
+
+	newPayload := func(blockNumber uint64) *common.GetPayloadResponse {
+		return &common.GetPayloadResponse{
+			Bellatrix: &types.GetPayloadResponse{
+				Version: types.VersionString(consensusspec.DataVersionBellatrix.String()),
+				Data:    &types.ExecutionPayload{BlockNumber: blockNumber},
+			},
+		}
+	}
+
+	// served from memory, even if redis lost the payload
+	require.NoError(t, ds.SaveExecutionPayload(10, "0xProposer", "0xbuilder", "0xAA", newPayload(1)))
+	ds.redis.client.FlushAll(context.Background())
+	resp, err := ds.GetGetPayloadResponse(10, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.Bellatrix.Data.BlockNumber)
+
+	// a newer submission of the builder replaces its previous payload in memory, which is then served from redis
+	require.NoError(t, ds.SaveExecutionPayload(10, "0xproposer", "0xbuilder", "0xbb", newPayload(2)))
+	require.Nil(t, ds.payloadCache.get(GetPayloadResponseKey{Slot: 10, ProposerPubkey: "0xproposer", BlockHash: "0xaa"}))
+	require.NoError(t, ds.redis.SaveExecutionPayload(10, "0xproposer", "0xaa", newPayload(1)))
+	resp, err = ds.GetGetPayloadResponse(10, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.Bellatrix.Data.BlockNumber)
+
+	// older slots are evicted from memory
+	require.NoError(t, ds.SaveExecutionPayload(10+payloadCacheSlots, "0xproposer", "0xbuilder", "0xcc", newPayload(3)))
+	require.Nil(t, ds.payloadCache.get(GetPayloadResponseKey{Slot: 10, ProposerPubkey: "0xproposer", BlockHash: "0xbb"}))
+	require.NotNil(t, ds.payloadCache.get(GetPayloadResponseKey{Slot: 10 + payloadCacheSlots, ProposerPubkey: "0xproposer", BlockHash: "0xcc"}))
+
+	// not found anywhere
+	resp, err = ds.GetGetPayloadResponse(11, "0xproposer", "0xdd")
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
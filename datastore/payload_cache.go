@@ -0,0 +1,68 @@
+package datastore
+
+import (
+	"sync"
+
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+// payloadCacheSlots is the number of most recent slots the payloads are kept in memory for. Submissions for the next
+// slot arrive while the payload of the current one is still being requested.
+const payloadCacheSlots = 3
+
+type payloadCacheEntry struct {
+	key     GetPayloadResponseKey
+	payload *common.VersionedExecutionPayload
+}
+
+// payloadCache keeps the latest payload of each builder for the recent slots in memory. A builder's newer submission
+// replaces its previous one, which bounds the memory use by the number of builders. Payloads which were replaced or
+// evicted are still served from redis or the database.
+type payloadCache struct {
+	lock  sync.RWMutex
+	slots map[uint64]map[string]payloadCacheEntry // slot -> builder pubkey -> latest payload
+}
+
+func newPayloadCache() *payloadCache {
+	return &payloadCache{
+		slots: make(map[uint64]map[string]payloadCacheEntry),
+	}
+}
+
+// set caches the payload as the builder's latest one, and evicts the slots no longer among the most recent ones
+func (c *payloadCache) set(key GetPayloadResponseKey, builderPubkey string, payload *common.VersionedExecutionPayload) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	builders, found := c.slots[key.Slot]
+	if !found {
+		builders = make(map[string]payloadCacheEntry)
+		c.slots[key.Slot] = builders
+	}
+	builders[builderPubkey] = payloadCacheEntry{key: key, payload: payload}
+
+	var maxSlot uint64
+	for slot := range c.slots {
+		if slot > maxSlot {
+			maxSlot = slot
+		}
+	}
+	for slot := range c.slots {
+		if slot+payloadCacheSlots <= maxSlot {
+			delete(c.slots, slot)
+		}
+	}
+}
+
+// get returns the cached payload, or nil if it isn't cached
+func (c *payloadCache) get(key GetPayloadResponseKey) *common.VersionedExecutionPayload {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, entry := range c.slots[key.Slot] {
+		if entry.key == key {
+			return entry.payload
+		}
+	}
+	return nil
+}
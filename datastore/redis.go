@@ -1,18 +1,22 @@
 package datastore
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/attestantio/go-eth2-client/spec/capella"
 	boostTypes "github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/go-utils/cli"
 	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
 	"github.com/go-redis/redis/v9"
 )
 
@@ -21,6 +25,12 @@ var (
 
 	expiryBidCache = 45 * time.Second
 
+	// the block hash a proposer requested the payload for is kept until well after the slot, to detect equivocations
+	expiryGetPayloadBlockHash = common.DurationPerEpoch
+
+	// the top bid changes of a slot are kept until they are saved to the database, at the latest when the slot passes
+	expiryBidHistory = common.DurationPerEpoch
+
 	activeValidatorsHours  = cli.GetEnvInt("ACTIVE_VALIDATOR_HOURS", 3)
 	expiryActiveValidators = time.Duration(activeValidatorsHours) * time.Hour // careful with this setting - for each hour a hash set is created with each active proposer as field. for a lot of hours this can take a lot of space in redis.
 
@@ -30,6 +40,12 @@ var (
 	RedisStatsFieldSlotLastPayloadDelivered = "slot-last-payload-delivered"
 
 	ErrFailedUpdatingTopBidNoBids = errors.New("failed to update top bid because no bids were found")
+
+	// the first byte of compressed values, which records the codec. JSON values never start with these bytes.
+	redisCodecMarkers = map[string]byte{
+		common.PayloadCodecSnappy: 0x01,
+		common.PayloadCodecZstd:   0x02,
+	}
 )
 
 type BlockBuilderStatus string
@@ -40,13 +56,75 @@ var (
 	RedisBlockBuilderStatusBlacklisted BlockBuilderStatus = "blacklisted"
 )
 
+// TopBidUpdate is published whenever the top bid for a slot changes
+type TopBidUpdate struct {
+	Slot           uint64 `json:"slot,string"`
+	ParentHash     string `json:"parent_hash"`
+	ProposerPubkey string `json:"proposer_pubkey"`
+	BuilderPubkey  string `json:"builder_pubkey"`
+	Value          string `json:"value"`
+}
+
+// BuilderStatusUpdate is published whenever the status of a builder is set
+type BuilderStatusUpdate struct {
+	BuilderPubkey string             `json:"builder_pubkey"`
+	Status        BlockBuilderStatus `json:"status"`
+}
+
+// FeatureFlagUpdate is published whenever a feature flag is set through the admin API. An empty value resets the flag to
+// the default of each instance.
+type FeatureFlagUpdate struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BuilderBidValue is the value of the latest bid of a builder for a slot, parent hash and proposer
+type BuilderBidValue struct {
+	Slot           uint64 `json:"slot,string"`
+	ParentHash     string `json:"parent_hash"`
+	ProposerPubkey string `json:"proposer_pubkey"`
+	BuilderPubkey  string `json:"builder_pubkey"`
+	Value          string `json:"value"`
+}
+
+// TopBid is the current top bid of a slot, parent hash and proposer, with the latest bid of the builder
+type TopBid struct {
+	Slot           uint64 `json:"slot,string"`
+	ParentHash     string `json:"parent_hash"`
+	ProposerPubkey string `json:"proposer_pubkey"`
+	BuilderPubkey  string `json:"builder_pubkey"`
+	BlockHash      string `json:"block_hash"`
+	Value          string `json:"value"`
+	ReceivedAt     int64  `json:"received_at_ms,string"`
+}
+
+// Types of the events published on the data stream
+const (
+	DataStreamEventPayloadDelivered     = "payload_delivered"
+	DataStreamEventBuilderBlockReceived = "builder_block_received"
+)
+
+// DataStreamEvent is published for every delivered payload and accepted builder submission
+type DataStreamEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// SimResult is the cached verdict of a block simulation. Fingerprint identifies the bid the block was simulated for,
+// since the same block could be resubmitted with a different bid.
+type SimResult struct {
+	Fingerprint     string `json:"fingerprint"`
+	Error           string `json:"error"`                      // empty if the block is valid
+	ProposerPayment string `json:"proposer_payment,omitempty"` // as measured by the simulation, for valid blocks
+}
+
 func PubkeyHexToLowerStr(pk boostTypes.PubkeyHex) string {
 	return strings.ToLower(string(pk))
 }
 
 func connectRedis(redisURI string) (*redis.Client, error) {
-	// Handle both URIs and full URLs, assume unencrypted connections
-	if !strings.HasPrefix(redisURI, "redis://") && !strings.HasPrefix(redisURI, "rediss://") {
+	// Handle both URIs and full URLs, assume unencrypted connections. Unix sockets are given as unix:///path/redis.sock
+	if !strings.HasPrefix(redisURI, "redis://") && !strings.HasPrefix(redisURI, "rediss://") && !strings.HasPrefix(redisURI, "unix://") {
 		redisURI = "redis://" + redisURI
 	}
 	opt, err := redis.ParseURL(redisURI)
@@ -54,6 +132,7 @@ func connectRedis(redisURI string) (*redis.Client, error) {
 		return nil, err
 	}
 	redisClient := redis.NewClient(opt)
+	redisClient.AddHook(metricsHook{})
 	if _, err := redisClient.Ping(context.Background()).Result(); err != nil {
 		// unable to connect to redis
 		return nil, err
@@ -64,50 +143,107 @@ func connectRedis(redisURI string) (*redis.Client, error) {
 type RedisCache struct {
 	client *redis.Client
 
+	// codec the execution payloads are written with
+	payloadCodec string
+
 	// prefixes (keys generated with a function)
 	prefixGetHeaderResponse           string
 	prefixGetPayloadResponse          string
 	prefixBidTrace                    string
+	prefixDeferredPayloadURL          string // where to fetch the payload of a header-only submission
+	prefixDeferredPayloadHeader       string // header of a header-only submission, which the fetched payload has to match
+	prefixPeerBidRelay                string // pubkey of the peer relay a bid was received from
 	prefixActiveValidators            string
 	prefixBlockBuilderLatestBids      string // latest bid for a given slot
 	prefixBlockBuilderLatestBidsValue string // value of latest bid for a given slot
 	prefixBlockBuilderLatestBidsTime  string // when the request was received, to avoid older requests overwriting newer ones after a slot validation
+	prefixBlockBuilderSubmissionCount string // number of submissions by a builder for a given slot, for rate limiting
+	prefixSimResult                   string // simulation verdicts for a given slot, to skip simulating resubmitted blocks
+	prefixAcceptedSubmissions         string // latest submission accepted from each builder in a given slot, to answer exact duplicates right away
+	prefixGetPayloadBlockHash         string // block hash a proposer requested the payload for in a given slot
+	prefixGetHeaderRequestTime        string // time of the latest getHeader call of a proposer in a given slot which returned a bid
+	prefixInclusionConstraints        string // transactions the proposer of a given slot requires in the block
+	prefixBidHistory                  string // top bid changes of a given slot, not yet saved to the database
 
 	// keys
 	keyKnownValidators                string
 	keyValidatorRegistrationTimestamp string
-
-	keyRelayConfig        string
-	keyStats              string
-	keyProposerDuties     string
-	keyBlockBuilderStatus string
+	keyValidatorRegistrationMessage   string // fee recipient and gas limit of the latest registration of each validator
+
+	keyRelayConfig            string
+	keyStats                  string
+	keyProposerDuties         string
+	keyBlockBuilderStatus     string
+	keyBlockBuilderCollateral string
+	keyBlockBuilderAPIKeyHash string
+	keyHousekeeperLeader      string
+	keyBlocklist              string
+	keyFeatureFlags           string
+	keyConstraintsDelegates   string
+	keyConstraintsTimestamps  string
+	keyProposerAllowlist      string
+
+	// pub/sub channels
+	channelTopBidUpdates        string
+	channelDataStream           string
+	channelBuilderStatusUpdates string
+	channelFeatureFlagUpdates   string
 }
 
 func NewRedisCache(redisURI, prefix string) (*RedisCache, error) {
+	if err := common.CheckPayloadCodec(common.PayloadCompression); err != nil {
+		return nil, err
+	}
+
 	client, err := connectRedis(redisURI)
 	if err != nil {
 		return nil, err
 	}
 
 	return &RedisCache{
-		client: client,
+		client:       client,
+		payloadCodec: common.PayloadCompression,
 
-		prefixGetHeaderResponse:  fmt.Sprintf("%s/%s:cache-gethead-response", redisPrefix, prefix),
-		prefixGetPayloadResponse: fmt.Sprintf("%s/%s:cache-getpayload-response", redisPrefix, prefix),
-		prefixBidTrace:           fmt.Sprintf("%s/%s:cache-bid-trace", redisPrefix, prefix),
-		prefixActiveValidators:   fmt.Sprintf("%s/%s:active-validators", redisPrefix, prefix), // one entry per hour
+		prefixGetHeaderResponse:     fmt.Sprintf("%s/%s:cache-gethead-response", redisPrefix, prefix),
+		prefixGetPayloadResponse:    fmt.Sprintf("%s/%s:cache-getpayload-response", redisPrefix, prefix),
+		prefixBidTrace:              fmt.Sprintf("%s/%s:cache-bid-trace", redisPrefix, prefix),
+		prefixDeferredPayloadURL:    fmt.Sprintf("%s/%s:deferred-payload-url", redisPrefix, prefix),
+		prefixDeferredPayloadHeader: fmt.Sprintf("%s/%s:deferred-payload-header", redisPrefix, prefix),
+		prefixPeerBidRelay:          fmt.Sprintf("%s/%s:peer-bid-relay", redisPrefix, prefix),
+		prefixActiveValidators:      fmt.Sprintf("%s/%s:active-validators", redisPrefix, prefix), // one entry per hour
 
 		prefixBlockBuilderLatestBids:      fmt.Sprintf("%s/%s:block-builder-latest-bid", redisPrefix, prefix),       // hashmap for slot+parentHash+proposerPubkey with builderPubkey as field
 		prefixBlockBuilderLatestBidsValue: fmt.Sprintf("%s/%s:block-builder-latest-bid-value", redisPrefix, prefix), // hashmap for slot+parentHash+proposerPubkey with builderPubkey as field
 		prefixBlockBuilderLatestBidsTime:  fmt.Sprintf("%s/%s:block-builder-latest-bid-time", redisPrefix, prefix),  // hashmap for slot+parentHash+proposerPubkey with builderPubkey as field
+		prefixBlockBuilderSubmissionCount: fmt.Sprintf("%s/%s:block-builder-submission-count", redisPrefix, prefix), // hashmap for slot with builderPubkey as field
+		prefixSimResult:                   fmt.Sprintf("%s/%s:block-sim-result", redisPrefix, prefix),               // hashmap for slot with blockHash as field
+		prefixAcceptedSubmissions:         fmt.Sprintf("%s/%s:accepted-submissions", redisPrefix, prefix),           // hashmap for slot with builderPubkey as field
+		prefixGetPayloadBlockHash:         fmt.Sprintf("%s/%s:getpayload-block-hash", redisPrefix, prefix),
+		prefixGetHeaderRequestTime:        fmt.Sprintf("%s/%s:getheader-request-time", redisPrefix, prefix),
+		prefixInclusionConstraints:        fmt.Sprintf("%s/%s:inclusion-constraints", redisPrefix, prefix),
+		prefixBidHistory:                  fmt.Sprintf("%s/%s:bid-history", redisPrefix, prefix), // list for slot with the JSON top bid changes
 
 		keyKnownValidators:                fmt.Sprintf("%s/%s:known-validators", redisPrefix, prefix),
 		keyValidatorRegistrationTimestamp: fmt.Sprintf("%s/%s:validator-registration-timestamp", redisPrefix, prefix),
+		keyValidatorRegistrationMessage:   fmt.Sprintf("%s/%s:validator-registration-message", redisPrefix, prefix),
 		keyRelayConfig:                    fmt.Sprintf("%s/%s:relay-config", redisPrefix, prefix),
 
-		keyStats:              fmt.Sprintf("%s/%s:stats", redisPrefix, prefix),
-		keyProposerDuties:     fmt.Sprintf("%s/%s:proposer-duties", redisPrefix, prefix),
-		keyBlockBuilderStatus: fmt.Sprintf("%s/%s:block-builder-status", redisPrefix, prefix),
+		keyStats:                  fmt.Sprintf("%s/%s:stats", redisPrefix, prefix),
+		keyProposerDuties:         fmt.Sprintf("%s/%s:proposer-duties", redisPrefix, prefix),
+		keyBlockBuilderStatus:     fmt.Sprintf("%s/%s:block-builder-status", redisPrefix, prefix),
+		keyBlockBuilderCollateral: fmt.Sprintf("%s/%s:block-builder-collateral", redisPrefix, prefix),   // only set for builders in optimistic mode
+		keyBlockBuilderAPIKeyHash: fmt.Sprintf("%s/%s:block-builder-api-key-hash", redisPrefix, prefix), // only set for builders with an API key
+		keyHousekeeperLeader:      fmt.Sprintf("%s/%s:housekeeper-leader", redisPrefix, prefix),         // id of the housekeeper instance doing the work
+		keyBlocklist:              fmt.Sprintf("%s/%s:blocklist", redisPrefix, prefix),                  // set of lowercase addresses, only used with the blocklist enabled
+		keyFeatureFlags:           fmt.Sprintf("%s/%s:feature-flags", redisPrefix, prefix),              // flags set through the admin API, overriding the defaults of the instances
+		keyConstraintsDelegates:   fmt.Sprintf("%s/%s:constraints-delegates", redisPrefix, prefix),      // hashmap with the validator pubkey as field, the delegate signing its inclusion constraints as value
+		keyConstraintsTimestamps:  fmt.Sprintf("%s/%s:constraints-timestamps", redisPrefix, prefix),     // hashmap with the validator pubkey as field, the timestamp of its latest delegation as value
+		keyProposerAllowlist:      fmt.Sprintf("%s/%s:proposer-allowlist", redisPrefix, prefix),         // set of lowercase validator pubkeys, only used with the proposer allowlist enabled
+
+		channelTopBidUpdates:        fmt.Sprintf("%s/%s:top-bid-updates", redisPrefix, prefix),
+		channelDataStream:           fmt.Sprintf("%s/%s:data-stream", redisPrefix, prefix),
+		channelBuilderStatusUpdates: fmt.Sprintf("%s/%s:builder-status-updates", redisPrefix, prefix),
+		channelFeatureFlagUpdates:   fmt.Sprintf("%s/%s:feature-flag-updates", redisPrefix, prefix),
 	}, nil
 }
 
@@ -123,6 +259,18 @@ func (r *RedisCache) keyCacheBidTrace(slot uint64, proposerPubkey, blockHash str
 	return fmt.Sprintf("%s:%d_%s_%s", r.prefixBidTrace, slot, proposerPubkey, blockHash)
 }
 
+func (r *RedisCache) keyDeferredPayloadURL(slot uint64, proposerPubkey, blockHash string) string {
+	return fmt.Sprintf("%s:%d_%s_%s", r.prefixDeferredPayloadURL, slot, proposerPubkey, blockHash)
+}
+
+func (r *RedisCache) keyDeferredPayloadHeader(slot uint64, proposerPubkey, blockHash string) string {
+	return fmt.Sprintf("%s:%d_%s_%s", r.prefixDeferredPayloadHeader, slot, proposerPubkey, blockHash)
+}
+
+func (r *RedisCache) keyPeerBidRelay(slot uint64, proposerPubkey, blockHash string) string {
+	return fmt.Sprintf("%s:%d_%s_%s", r.prefixPeerBidRelay, slot, proposerPubkey, blockHash)
+}
+
 // keyActiveValidators returns the key for the date + hour of the given time
 func (r *RedisCache) keyActiveValidators(t time.Time) string {
 	return fmt.Sprintf("%s:%s", r.prefixActiveValidators, t.UTC().Format("2006-01-02T15"))
@@ -143,6 +291,42 @@ func (r *RedisCache) keyBlockBuilderLatestBidsTime(slot uint64, parentHash, prop
 	return fmt.Sprintf("%s:%d_%s_%s", r.prefixBlockBuilderLatestBidsTime, slot, parentHash, proposerPubkey)
 }
 
+// keyBlockBuilderSubmissionCount returns the hashmap key for the number of submissions by each builder in a slot
+func (r *RedisCache) keyBlockBuilderSubmissionCount(slot uint64) string {
+	return fmt.Sprintf("%s:%d", r.prefixBlockBuilderSubmissionCount, slot)
+}
+
+// keySimResult returns the hashmap key for the simulation verdicts of the blocks in a slot
+func (r *RedisCache) keySimResult(slot uint64) string {
+	return fmt.Sprintf("%s:%d", r.prefixSimResult, slot)
+}
+
+// keyAcceptedSubmissions returns the hashmap key for the latest submission accepted from each builder in a slot
+func (r *RedisCache) keyAcceptedSubmissions(slot uint64) string {
+	return fmt.Sprintf("%s:%d", r.prefixAcceptedSubmissions, slot)
+}
+
+func (r *RedisCache) keyGetPayloadBlockHash(slot uint64, proposerPubkey string) string {
+	return fmt.Sprintf("%s:%d_%s", r.prefixGetPayloadBlockHash, slot, proposerPubkey)
+}
+
+func (r *RedisCache) keyGetHeaderRequestTime(slot uint64, proposerPubkey string) string {
+	return fmt.Sprintf("%s:%d_%s", r.prefixGetHeaderRequestTime, slot, strings.ToLower(proposerPubkey))
+}
+
+func (r *RedisCache) keyInclusionConstraints(slot uint64) string {
+	return fmt.Sprintf("%s:%d", r.prefixInclusionConstraints, slot)
+}
+
+func (r *RedisCache) keyBidHistory(slot uint64) string {
+	return fmt.Sprintf("%s:%d", r.prefixBidHistory, slot)
+}
+
+// Ping checks that redis is reachable
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (r *RedisCache) GetObj(key string, obj any) (err error) {
 	value, err := r.client.Get(context.Background(), key).Result()
 	if err != nil {
@@ -153,21 +337,35 @@ func (r *RedisCache) GetObj(key string, obj any) (err error) {
 }
 
 func (r *RedisCache) SetObj(key string, value any, expiration time.Duration) (err error) {
-	marshalledValue, err := json.Marshal(value)
+	buf, err := marshalToBuffer(value)
 	if err != nil {
 		return err
 	}
+	defer common.PutBuffer(buf)
 
-	return r.client.Set(context.Background(), key, marshalledValue, expiration).Err()
+	return r.client.Set(context.Background(), key, buf.Bytes(), expiration).Err()
+}
+
+// marshalToBuffer encodes the value like json.Marshal, into a pooled buffer since execution payloads are several MB.
+// The buffer can be returned once the command is sent.
+func marshalToBuffer(value any) (*bytes.Buffer, error) {
+	buf := common.GetBuffer()
+	if err := json.NewEncoder(buf).Encode(value); err != nil {
+		common.PutBuffer(buf)
+		return nil, err
+	}
+	buf.Truncate(buf.Len() - 1) // the newline added by Encode
+	return buf, nil
 }
 
 func (r *RedisCache) HSetObj(key, field string, value any, expiration time.Duration) (err error) {
-	marshalledValue, err := json.Marshal(value)
+	buf, err := marshalToBuffer(value)
 	if err != nil {
 		return err
 	}
+	defer common.PutBuffer(buf)
 
-	err = r.client.HSet(context.Background(), key, field, marshalledValue).Err()
+	err = r.client.HSet(context.Background(), key, field, buf.Bytes()).Err()
 	if err != nil {
 		return err
 	}
@@ -194,6 +392,88 @@ func (r *RedisCache) SetKnownValidator(pubkeyHex boostTypes.PubkeyHex, proposerI
 	return r.client.HSet(context.Background(), r.keyKnownValidators, PubkeyHexToLowerStr(pubkeyHex), proposerIndex).Err()
 }
 
+// SetKnownValidators writes the validators in batches, overwriting existing entries
+func (r *RedisCache) SetKnownValidators(validators map[boostTypes.PubkeyHex]uint64) error {
+	const batchSize = 10000
+	values := make([]any, 0, 2*batchSize)
+	for pubkeyHex, proposerIndex := range validators {
+		values = append(values, PubkeyHexToLowerStr(pubkeyHex), proposerIndex)
+		if len(values) == 2*batchSize {
+			if err := r.client.HSet(context.Background(), r.keyKnownValidators, values...).Err(); err != nil {
+				return err
+			}
+			values = values[:0]
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return r.client.HSet(context.Background(), r.keyKnownValidators, values...).Err()
+}
+
+// SetBlocklist replaces the blocklisted addresses in one transaction, so the API never sees a partial list
+func (r *RedisCache) SetBlocklist(addresses []string) error {
+	return r.replaceSet(r.keyBlocklist, addresses)
+}
+
+// GetBlocklist returns the blocklisted addresses (lowercase), or nil if no blocklist was loaded yet
+func (r *RedisCache) GetBlocklist() (map[string]bool, error) {
+	return r.getSet(r.keyBlocklist)
+}
+
+// SetProposerAllowlist replaces the allowed validator pubkeys in one transaction, so the API never sees a partial list
+func (r *RedisCache) SetProposerAllowlist(pubkeys []string) error {
+	return r.replaceSet(r.keyProposerAllowlist, pubkeys)
+}
+
+// GetProposerAllowlist returns the allowed validator pubkeys (lowercase), an empty map if the allowlist is empty, or nil
+// if no allowlist was loaded yet
+func (r *RedisCache) GetProposerAllowlist() (map[string]bool, error) {
+	return r.getSet(r.keyProposerAllowlist)
+}
+
+// loadedSetSuffix is appended to the key of a set replaced by replaceSet, for a marker telling an empty set apart from
+// one which was never loaded, as redis deletes empty sets
+const loadedSetSuffix = ":loaded"
+
+// replaceSet replaces the members of a set with the lowercase values in one transaction
+func (r *RedisCache) replaceSet(key string, values []string) error {
+	members := make([]any, len(values))
+	for i, value := range values {
+		members[i] = strings.ToLower(value)
+	}
+
+	_, err := r.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Del(context.Background(), key)
+		if len(members) > 0 {
+			pipe.SAdd(context.Background(), key, members...)
+		}
+		pipe.Set(context.Background(), key+loadedSetSuffix, "1", 0)
+		return nil
+	})
+	return err
+}
+
+// getSet returns the members of a set replaced by replaceSet, an empty map if it's empty, or nil if it was never loaded
+func (r *RedisCache) getSet(key string) (map[string]bool, error) {
+	var members *redis.StringSliceCmd
+	var loaded *redis.IntCmd
+	_, err := r.client.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		members = pipe.SMembers(context.Background(), key)
+		loaded = pipe.Exists(context.Background(), key+loadedSetSuffix)
+		return nil
+	})
+	if err != nil || (len(members.Val()) == 0 && loaded.Val() == 0) {
+		return nil, err
+	}
+
+	set := make(map[string]bool, len(members.Val()))
+	for _, member := range members.Val() {
+		set[member] = true
+	}
+	return set, nil
+}
+
 func (r *RedisCache) SetKnownValidatorNX(pubkeyHex boostTypes.PubkeyHex, proposerIndex uint64) error {
 	return r.client.HSetNX(context.Background(), r.keyKnownValidators, PubkeyHexToLowerStr(pubkeyHex), proposerIndex).Err()
 }
@@ -221,6 +501,150 @@ func (r *RedisCache) SetValidatorRegistrationTimestamp(proposerPubkey boostTypes
 	return r.client.HSet(context.Background(), r.keyValidatorRegistrationTimestamp, proposerPubkey.String(), timestamp).Err()
 }
 
+// GetValidatorRegistrationTimestamps returns the registration timestamps of the validators in a single command, 0 for
+// validators without registration
+func (r *RedisCache) GetValidatorRegistrationTimestamps(proposerPubkeys []boostTypes.PubkeyHex) ([]uint64, error) {
+	timestamps := make([]uint64, len(proposerPubkeys))
+	if len(proposerPubkeys) == 0 {
+		return timestamps, nil
+	}
+
+	fields := make([]string, len(proposerPubkeys))
+	for i, pubkey := range proposerPubkeys {
+		fields[i] = strings.ToLower(pubkey.String())
+	}
+	values, err := r.client.HMGet(context.Background(), r.keyValidatorRegistrationTimestamp, fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue // no registration
+		}
+		timestamps[i], err = strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return timestamps, nil
+}
+
+// SetValidatorRegistrationTimestampsIfNewer is SetValidatorRegistrationTimestampIfNewer for a batch of validators, with
+// one command to read the known timestamps and one to write the newer ones
+func (r *RedisCache) SetValidatorRegistrationTimestampsIfNewer(timestamps map[boostTypes.PubkeyHex]uint64) error {
+	pubkeys := make([]boostTypes.PubkeyHex, 0, len(timestamps))
+	for pubkey := range timestamps {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	knownTimestamps, err := r.GetValidatorRegistrationTimestamps(pubkeys)
+	if err != nil {
+		return err
+	}
+
+	values := make([]any, 0, 2*len(pubkeys))
+	for i, pubkey := range pubkeys {
+		if knownTimestamps[i] < timestamps[pubkey] {
+			values = append(values, pubkey.String(), timestamps[pubkey])
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return r.client.HSet(context.Background(), r.keyValidatorRegistrationTimestamp, values...).Err()
+}
+
+// ValidatorRegistrationMessage are the fields of the latest registration of a validator which re-registrations are
+// compared against. The fee recipient is empty if only the timestamp is known.
+type ValidatorRegistrationMessage struct {
+	Timestamp    uint64
+	FeeRecipient string
+	GasLimit     uint64
+}
+
+// GetValidatorRegistrationMessages returns the latest registrations of the validators in a single round trip, the zero
+// value for validators without registration
+func (r *RedisCache) GetValidatorRegistrationMessages(proposerPubkeys []boostTypes.PubkeyHex) ([]ValidatorRegistrationMessage, error) {
+	messages := make([]ValidatorRegistrationMessage, len(proposerPubkeys))
+	if len(proposerPubkeys) == 0 {
+		return messages, nil
+	}
+
+	fields := make([]string, len(proposerPubkeys))
+	for i, pubkey := range proposerPubkeys {
+		fields[i] = strings.ToLower(pubkey.String())
+	}
+	pipe := r.client.Pipeline()
+	timestampsCmd := pipe.HMGet(context.Background(), r.keyValidatorRegistrationTimestamp, fields...)
+	messagesCmd := pipe.HMGet(context.Background(), r.keyValidatorRegistrationMessage, fields...)
+	if _, err := pipe.Exec(context.Background()); err != nil {
+		return nil, err
+	}
+
+	timestamps, messageValues := timestampsCmd.Val(), messagesCmd.Val()
+	for i := range messages {
+		str, ok := timestamps[i].(string)
+		if !ok {
+			continue // no registration
+		}
+		timestamp, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		messages[i].Timestamp = timestamp
+
+		str, ok = messageValues[i].(string)
+		if !ok {
+			continue // registered before the messages were stored
+		}
+		feeRecipient, gasLimit, found := strings.Cut(str, ",")
+		if !found {
+			continue
+		}
+		messages[i].FeeRecipient = feeRecipient
+		messages[i].GasLimit, err = strconv.ParseUint(gasLimit, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// SetValidatorRegistrationsIfNewer stores the timestamps, fee recipients and gas limits of the registrations which are
+// newer than the known ones
+func (r *RedisCache) SetValidatorRegistrationsIfNewer(registrations map[boostTypes.PubkeyHex]ValidatorRegistrationMessage) error {
+	pubkeys := make([]boostTypes.PubkeyHex, 0, len(registrations))
+	for pubkey := range registrations {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	knownTimestamps, err := r.GetValidatorRegistrationTimestamps(pubkeys)
+	if err != nil {
+		return err
+	}
+
+	timestamps := make([]any, 0, 2*len(pubkeys))
+	messages := make([]any, 0, 2*len(pubkeys))
+	for i, pubkey := range pubkeys {
+		registration := registrations[pubkey]
+		if knownTimestamps[i] >= registration.Timestamp {
+			continue
+		}
+		field := strings.ToLower(pubkey.String())
+		timestamps = append(timestamps, field, registration.Timestamp)
+		messages = append(messages, field, fmt.Sprintf("%s,%d", strings.ToLower(registration.FeeRecipient), registration.GasLimit))
+	}
+	if len(timestamps) == 0 {
+		return nil
+	}
+
+	_, err = r.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.Background(), r.keyValidatorRegistrationTimestamp, timestamps...)
+		pipe.HSet(context.Background(), r.keyValidatorRegistrationMessage, messages...)
+		return nil
+	})
+	return err
+}
+
 func (r *RedisCache) SetActiveValidator(pubkeyHex boostTypes.PubkeyHex) error {
 	key := r.keyActiveValidators(time.Now())
 	err := r.client.HSet(context.Background(), key, PubkeyHexToLowerStr(pubkeyHex), "1").Err()
@@ -283,6 +707,35 @@ func (r *RedisCache) GetRelayConfig(field string) (string, error) {
 	return res, err
 }
 
+// SetFeatureFlag overrides a feature flag on all API instances
+func (r *RedisCache) SetFeatureFlag(name, value string) error {
+	update, err := json.Marshal(FeatureFlagUpdate{Name: name, Value: value})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		if value == "" {
+			pipe.HDel(context.Background(), r.keyFeatureFlags, name)
+		} else {
+			pipe.HSet(context.Background(), r.keyFeatureFlags, name, value)
+		}
+		pipe.Publish(context.Background(), r.channelFeatureFlagUpdates, update)
+		return nil
+	})
+	return err
+}
+
+// GetFeatureFlags returns the overridden feature flags, by name
+func (r *RedisCache) GetFeatureFlags() (map[string]string, error) {
+	return r.client.HGetAll(context.Background(), r.keyFeatureFlags).Result()
+}
+
+// SubscribeFeatureFlagUpdates returns a channel receiving all feature flag updates, until the context is cancelled
+func (r *RedisCache) SubscribeFeatureFlagUpdates(ctx context.Context) <-chan *FeatureFlagUpdate {
+	return subscribe[FeatureFlagUpdate](ctx, r.client, r.channelFeatureFlagUpdates)
+}
+
 func (r *RedisCache) GetBestBid(slot uint64, parentHash, proposerPubkey string) (*common.GetHeaderResponse, error) {
 	key := r.keyCacheGetHeaderResponse(slot, parentHash, proposerPubkey)
 	resp := new(common.GetHeaderResponse)
@@ -295,19 +748,62 @@ func (r *RedisCache) GetBestBid(slot uint64, parentHash, proposerPubkey string)
 
 func (r *RedisCache) SaveExecutionPayload(slot uint64, proposerPubkey, blockHash string, resp *common.GetPayloadResponse) (err error) {
 	key := r.keyCacheGetPayloadResponse(slot, proposerPubkey, blockHash)
-	return r.SetObj(key, resp, expiryBidCache)
+	buf, err := marshalToBuffer(resp)
+	if err != nil {
+		return err
+	}
+	defer common.PutBuffer(buf)
+
+	value, err := compressValue(r.payloadCodec, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), key, value, expiryBidCache).Err()
 }
 
 func (r *RedisCache) GetExecutionPayload(slot uint64, proposerPubkey, blockHash string) (*common.VersionedExecutionPayload, error) {
 	key := r.keyCacheGetPayloadResponse(slot, proposerPubkey, blockHash)
-	resp := new(common.VersionedExecutionPayload)
-	err := r.GetObj(key, resp)
+	value, err := r.client.Get(context.Background(), key).Bytes()
 	if errors.Is(err, redis.Nil) {
 		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
+
+	data, err := decompressValue(value)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(common.VersionedExecutionPayload)
+	err = json.Unmarshal(data, resp)
 	return resp, err
 }
 
+// compressValue compresses the JSON value with the codec, prefixed with the marker of the codec
+func compressValue(codec string, value []byte) ([]byte, error) {
+	if codec == common.PayloadCodecNone {
+		return value, nil
+	}
+	compressed, err := common.CompressPayload(codec, value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{redisCodecMarkers[codec]}, compressed...), nil
+}
+
+// decompressValue returns the JSON of a value written by compressValue, with any codec
+func decompressValue(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+	for codec, marker := range redisCodecMarkers {
+		if value[0] == marker {
+			return common.DecompressPayload(codec, value[1:])
+		}
+	}
+	return value, nil
+}
+
 func (r *RedisCache) SaveBidTrace(trace *common.BidTraceV2) (err error) {
 	key := r.keyCacheBidTrace(trace.Slot, trace.ProposerPubkey.String(), trace.BlockHash.String())
 	return r.SetObj(key, trace, expiryBidCache)
@@ -323,8 +819,180 @@ func (r *RedisCache) GetBidTrace(slot uint64, proposerPubkey, blockHash string)
 	return resp, err
 }
 
+// CheckAndSetGetPayloadBlockHash records the block hash of the first getPayload request of a proposer in a slot. If a
+// request for a different block hash was recorded before, i.e. the proposer signed two blocks for the slot, that block
+// hash is returned.
+func (r *RedisCache) CheckAndSetGetPayloadBlockHash(slot uint64, proposerPubkey, blockHash string) (prevBlockHash string, err error) {
+	ctx := context.Background()
+	key := r.keyGetPayloadBlockHash(slot, proposerPubkey)
+	isFirst, err := r.client.SetNX(ctx, key, strings.ToLower(blockHash), expiryGetPayloadBlockHash).Result()
+	if err != nil || isFirst {
+		return "", err
+	}
+
+	// the value is never overwritten, so there is no race with other instances here
+	prevBlockHash, err = r.client.Get(ctx, key).Result()
+	if err != nil || strings.EqualFold(prevBlockHash, blockHash) {
+		return "", err
+	}
+	return prevBlockHash, nil
+}
+
+// SetGetHeaderRequestTime records the time of a getHeader call of the proposer which returned a bid, the latest one wins
+func (r *RedisCache) SetGetHeaderRequestTime(slot uint64, proposerPubkey string, requestTime time.Time) error {
+	return r.client.Set(context.Background(), r.keyGetHeaderRequestTime(slot, proposerPubkey), requestTime.UnixMilli(), expiryBidCache).Err()
+}
+
+// GetGetHeaderRequestTime returns the time of the latest getHeader call of the proposer which returned a bid, or the
+// zero time if there was none
+func (r *RedisCache) GetGetHeaderRequestTime(slot uint64, proposerPubkey string) (time.Time, error) {
+	timestampMs, err := r.client.Get(context.Background(), r.keyGetHeaderRequestTime(slot, proposerPubkey)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(timestampMs).UTC(), nil
+}
+
+// SaveDeferredPayloadURL saves the URL the payload of a header-only submission can be fetched from
+func (r *RedisCache) SaveDeferredPayloadURL(slot uint64, proposerPubkey, blockHash, payloadURL string) (err error) {
+	return r.client.Set(context.Background(), r.keyDeferredPayloadURL(slot, proposerPubkey, blockHash), payloadURL, expiryBidCache).Err()
+}
+
+// GetDeferredPayloadURL returns the payload URL of a header-only submission, or an empty string if the block wasn't submitted header-only
+func (r *RedisCache) GetDeferredPayloadURL(slot uint64, proposerPubkey, blockHash string) (string, error) {
+	payloadURL, err := r.client.Get(context.Background(), r.keyDeferredPayloadURL(slot, proposerPubkey, blockHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return payloadURL, err
+}
+
+// SaveDeferredPayloadHeader saves the header of a header-only submission, which its payload is checked against once fetched
+func (r *RedisCache) SaveDeferredPayloadHeader(slot uint64, proposerPubkey, blockHash string, header *capella.ExecutionPayloadHeader) (err error) {
+	return r.SetObj(r.keyDeferredPayloadHeader(slot, proposerPubkey, blockHash), header, expiryBidCache)
+}
+
+// GetDeferredPayloadHeader returns the header of a header-only submission, or nil if the block wasn't submitted header-only
+func (r *RedisCache) GetDeferredPayloadHeader(slot uint64, proposerPubkey, blockHash string) (*capella.ExecutionPayloadHeader, error) {
+	header := new(capella.ExecutionPayloadHeader)
+	err := r.GetObj(r.keyDeferredPayloadHeader(slot, proposerPubkey, blockHash), header)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return header, err
+}
+
+// SavePeerBidRelay saves the pubkey of the peer relay a bid was received from, which holds the payload
+func (r *RedisCache) SavePeerBidRelay(slot uint64, proposerPubkey, blockHash, peerRelayPubkey string) (err error) {
+	return r.client.Set(context.Background(), r.keyPeerBidRelay(slot, proposerPubkey, blockHash), peerRelayPubkey, expiryBidCache).Err()
+}
+
+// GetPeerBidRelay returns the pubkey of the peer relay a bid was received from, or an empty string if the bid wasn't
+// received from a peer relay
+func (r *RedisCache) GetPeerBidRelay(slot uint64, proposerPubkey, blockHash string) (string, error) {
+	peerRelayPubkey, err := r.client.Get(context.Background(), r.keyPeerBidRelay(slot, proposerPubkey, blockHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return peerRelayPubkey, err
+}
+
+// SaveInclusionConstraints saves the signed inclusion constraints of a slot, replacing previous ones
+func (r *RedisCache) SaveInclusionConstraints(slot uint64, constraints *common.SignedInclusionConstraints) error {
+	return r.SetObj(r.keyInclusionConstraints(slot), constraints, expiryBidCache)
+}
+
+// GetInclusionConstraints returns the signed inclusion constraints of a slot, or nil if there are none
+func (r *RedisCache) GetInclusionConstraints(slot uint64) (*common.SignedInclusionConstraints, error) {
+	constraints := new(common.SignedInclusionConstraints)
+	err := r.GetObj(r.keyInclusionConstraints(slot), constraints)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return constraints, err
+}
+
+// sets the delegate of a validator, but only if the timestamp is newer than the one of the current delegation
+var scriptSetConstraintsDelegate = redis.NewScript(`
+	local timestamp = redis.call("HGET", KEYS[2], ARGV[1])
+	if timestamp and tonumber(timestamp) >= tonumber(ARGV[3]) then
+		return 0
+	end
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+	redis.call("HSET", KEYS[2], ARGV[1], ARGV[3])
+	return 1`)
+
+// SetConstraintsDelegate sets the pubkey allowed to sign the inclusion constraints of a validator, if the delegation is
+// newer than the current one. Returns whether the delegate was set.
+func (r *RedisCache) SetConstraintsDelegate(validatorPubkey, delegatePubkey string, timestamp uint64) (bool, error) {
+	keys := []string{r.keyConstraintsDelegates, r.keyConstraintsTimestamps}
+	set, err := scriptSetConstraintsDelegate.Run(context.Background(), r.client, keys, strings.ToLower(validatorPubkey), strings.ToLower(delegatePubkey), timestamp).Int()
+	return set == 1, err
+}
+
+// GetConstraintsDelegate returns the delegate of a validator, or an empty string if it has none
+func (r *RedisCache) GetConstraintsDelegate(validatorPubkey string) (string, error) {
+	delegatePubkey, err := r.client.HGet(context.Background(), r.keyConstraintsDelegates, strings.ToLower(validatorPubkey)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return delegatePubkey, err
+}
+
+// SetBlockBuilderStatus sets the status of a builder, and notifies the API instances caching the statuses
 func (r *RedisCache) SetBlockBuilderStatus(builderPubkey string, status BlockBuilderStatus) (err error) {
-	return r.client.HSet(context.Background(), r.keyBlockBuilderStatus, builderPubkey, string(status)).Err()
+	update, err := json.Marshal(BuilderStatusUpdate{BuilderPubkey: builderPubkey, Status: status})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.Background(), r.keyBlockBuilderStatus, builderPubkey, string(status))
+		pipe.Publish(context.Background(), r.channelBuilderStatusUpdates, update)
+		return nil
+	})
+	return err
+}
+
+// GetBlockBuilderStatuses returns the statuses of all builders with a status set, by builder pubkey
+func (r *RedisCache) GetBlockBuilderStatuses() (map[string]BlockBuilderStatus, error) {
+	res, err := r.client.HGetAll(context.Background(), r.keyBlockBuilderStatus).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]BlockBuilderStatus, len(res))
+	for builderPubkey, status := range res {
+		statuses[builderPubkey] = BlockBuilderStatus(status)
+	}
+	return statuses, nil
+}
+
+// UpdateBlockBuilderStatuses sets the statuses which differ from the ones in redis, and returns the pubkeys of the
+// builders whose status changed
+func (r *RedisCache) UpdateBlockBuilderStatuses(statuses map[string]BlockBuilderStatus) (changed []string, err error) {
+	currentStatuses, err := r.GetBlockBuilderStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	for builderPubkey, status := range statuses {
+		if currentStatus, found := currentStatuses[builderPubkey]; found && currentStatus == status {
+			continue
+		}
+		if err := r.SetBlockBuilderStatus(builderPubkey, status); err != nil {
+			return changed, err
+		}
+		changed = append(changed, builderPubkey)
+	}
+	return changed, nil
+}
+
+// SubscribeBuilderStatusUpdates returns a channel receiving all builder status updates, until the context is cancelled
+func (r *RedisCache) SubscribeBuilderStatusUpdates(ctx context.Context) <-chan *BuilderStatusUpdate {
+	return subscribe[BuilderStatusUpdate](ctx, r.client, r.channelBuilderStatusUpdates)
 }
 
 func (r *RedisCache) GetBlockBuilderStatus(builderPubkey string) (isHighPrio, isBlacklisted bool, err error) {
@@ -337,6 +1005,148 @@ func (r *RedisCache) GetBlockBuilderStatus(builderPubkey string) (isHighPrio, is
 	return isHighPrio, isBlacklisted, err
 }
 
+// SetBlockBuilderCollateral enables optimistic mode for a builder, with the collateral (in wei) as upper bound for the value of optimistic blocks
+func (r *RedisCache) SetBlockBuilderCollateral(builderPubkey, collateral string) (err error) {
+	return r.client.HSet(context.Background(), r.keyBlockBuilderCollateral, builderPubkey, collateral).Err()
+}
+
+// GetBlockBuilderCollateral returns the collateral of a builder in optimistic mode, or an empty string if the builder isn't optimistic
+func (r *RedisCache) GetBlockBuilderCollateral(builderPubkey string) (collateral string, err error) {
+	collateral, err = r.client.HGet(context.Background(), r.keyBlockBuilderCollateral, builderPubkey).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return collateral, err
+}
+
+// DeleteBlockBuilderCollateral disables optimistic mode for a builder
+func (r *RedisCache) DeleteBlockBuilderCollateral(builderPubkey string) (err error) {
+	return r.client.HDel(context.Background(), r.keyBlockBuilderCollateral, builderPubkey).Err()
+}
+
+// IncBuilderSubmissionCount increments and returns the number of submissions by the builder for the slot
+func (r *RedisCache) IncBuilderSubmissionCount(slot uint64, builderPubkey string) (int64, error) {
+	key := r.keyBlockBuilderSubmissionCount(slot)
+	pipe := r.client.TxPipeline()
+	cnt := pipe.HIncrBy(context.Background(), key, builderPubkey, 1)
+	pipe.Expire(context.Background(), key, expiryBidCache)
+	_, err := pipe.Exec(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return cnt.Val(), nil
+}
+
+// GetBuilderSubmissionCount returns the number of submissions by the builder for the slot, without counting one
+func (r *RedisCache) GetBuilderSubmissionCount(slot uint64, builderPubkey string) (int64, error) {
+	cnt, err := r.client.HGet(context.Background(), r.keyBlockBuilderSubmissionCount(slot), builderPubkey).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return cnt, err
+}
+
+// AppendBidHistory appends a top bid change to the history of its slot, which keeps the latest maxLen changes
+func (r *RedisCache) AppendBidHistory(entry *database.BidHistoryEntry, maxLen int) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	key := r.keyBidHistory(entry.Slot)
+	pipe := r.client.TxPipeline()
+	pipe.RPush(context.Background(), key, value)
+	pipe.LTrim(context.Background(), key, int64(-maxLen), -1)
+	pipe.Expire(context.Background(), key, expiryBidHistory)
+	_, err = pipe.Exec(context.Background())
+	return err
+}
+
+// TakeBidHistory returns the top bid changes of the slot in order, and deletes them, so that only one relay instance
+// saves them
+func (r *RedisCache) TakeBidHistory(slot uint64) ([]*database.BidHistoryEntry, error) {
+	key := r.keyBidHistory(slot)
+	pipe := r.client.TxPipeline()
+	values := pipe.LRange(context.Background(), key, 0, -1)
+	pipe.Del(context.Background(), key)
+	if _, err := pipe.Exec(context.Background()); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*database.BidHistoryEntry, 0, len(values.Val()))
+	for _, value := range values.Val() {
+		entry := new(database.BidHistoryEntry)
+		if err := json.Unmarshal([]byte(value), entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// SaveSimResult caches the simulation verdict for a block
+func (r *RedisCache) SaveSimResult(slot uint64, blockHash string, result *SimResult) (err error) {
+	return r.HSetObj(r.keySimResult(slot), blockHash, result, expiryBidCache)
+}
+
+// GetSimResult returns the cached simulation verdict for a block, or nil if the block wasn't simulated yet
+func (r *RedisCache) GetSimResult(slot uint64, blockHash string) (*SimResult, error) {
+	value, err := r.client.HGet(context.Background(), r.keySimResult(slot), blockHash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := new(SimResult)
+	err = json.Unmarshal([]byte(value), result)
+	return result, err
+}
+
+// acceptedSubmission identifies a submission by its block hash and value
+func acceptedSubmission(blockHash, value string) string {
+	return strings.ToLower(blockHash) + "_" + value
+}
+
+// SaveAcceptedSubmission records the latest submission accepted from the builder in the slot
+func (r *RedisCache) SaveAcceptedSubmission(slot uint64, builderPubkey, blockHash, value string) error {
+	key := r.keyAcceptedSubmissions(slot)
+	pipe := r.client.TxPipeline()
+	pipe.HSet(context.Background(), key, strings.ToLower(builderPubkey), acceptedSubmission(blockHash, value))
+	pipe.Expire(context.Background(), key, expiryBidCache)
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+// IsAcceptedSubmission returns whether the block with the value is the latest submission accepted from the builder in
+// the slot. Earlier submissions aren't, since resubmitting them makes them the latest bid of the builder again.
+func (r *RedisCache) IsAcceptedSubmission(slot uint64, builderPubkey, blockHash, value string) (bool, error) {
+	accepted, err := r.client.HGet(context.Background(), r.keyAcceptedSubmissions(slot), strings.ToLower(builderPubkey)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return accepted == acceptedSubmission(blockHash, value), err
+}
+
+// SetBlockBuilderAPIKeyHash sets the hash of the builder's API key, which is then required on submissions
+func (r *RedisCache) SetBlockBuilderAPIKeyHash(builderPubkey, apiKeyHash string) (err error) {
+	return r.client.HSet(context.Background(), r.keyBlockBuilderAPIKeyHash, builderPubkey, apiKeyHash).Err()
+}
+
+// GetBlockBuilderAPIKeyHash returns the hash of the builder's API key, or an empty string if the builder has none
+func (r *RedisCache) GetBlockBuilderAPIKeyHash(builderPubkey string) (apiKeyHash string, err error) {
+	apiKeyHash, err = r.client.HGet(context.Background(), r.keyBlockBuilderAPIKeyHash, builderPubkey).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return apiKeyHash, err
+}
+
+// DeleteBlockBuilderAPIKeyHash revokes the builder's API key
+func (r *RedisCache) DeleteBlockBuilderAPIKeyHash(builderPubkey string) (err error) {
+	return r.client.HDel(context.Background(), r.keyBlockBuilderAPIKeyHash, builderPubkey).Err()
+}
+
 func (r *RedisCache) GetBuilderLatestPayloadReceivedAt(slot uint64, builderPubkey, parentHash, proposerPubkey string) (int64, error) {
 	keyLatestBidsTime := r.keyBlockBuilderLatestBidsTime(slot, parentHash, proposerPubkey)
 	timestamp, err := r.client.HGet(context.Background(), keyLatestBidsTime, builderPubkey).Int64()
@@ -374,12 +1184,122 @@ func (r *RedisCache) SaveLatestBuilderBid(slot uint64, builderPubkey, parentHash
 	return r.client.Expire(context.Background(), keyLatestBidsValue, expiryBidCache).Err()
 }
 
-func (r *RedisCache) UpdateTopBid(slot uint64, parentHash, proposerPubkey string) (err error) {
+// GetBuilderBidValues returns the value of the latest bid of every builder in the slot, highest first
+func (r *RedisCache) GetBuilderBidValues(slot uint64) ([]*BuilderBidValue, error) {
+	keyPrefix := fmt.Sprintf("%s:%d_", r.prefixBlockBuilderLatestBidsValue, slot)
+	bids := []*BuilderBidValue{}
+	iter := r.client.Scan(context.Background(), 0, keyPrefix+"*", 0).Iterator()
+	for iter.Next(context.Background()) {
+		key := iter.Val()
+		parentHash, proposerPubkey, found := strings.Cut(strings.TrimPrefix(key, keyPrefix), "_")
+		if !found {
+			continue
+		}
+
+		bidValues, err := r.client.HGetAll(context.Background(), key).Result()
+		if err != nil {
+			return nil, err
+		}
+		for builderPubkey, value := range bidValues {
+			bids = append(bids, &BuilderBidValue{
+				Slot:           slot,
+				ParentHash:     parentHash,
+				ProposerPubkey: proposerPubkey,
+				BuilderPubkey:  builderPubkey,
+				Value:          value,
+			})
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make(map[*BuilderBidValue]*big.Int, len(bids))
+	for _, bid := range bids {
+		values[bid] = new(big.Int)
+		values[bid].SetString(bid.Value, 10)
+	}
+	sort.Slice(bids, func(i, j int) bool {
+		return values[bids[i]].Cmp(values[bids[j]]) > 0
+	})
+	return bids, nil
+}
+
+// GetTopBids returns the top bid of the slot for every parent hash and proposer with bids, highest first. Like
+// UpdateTopBid, the top bid is the highest of the latest bids of all builders.
+func (r *RedisCache) GetTopBids(slot uint64) ([]*TopBid, error) {
+	bids, err := r.GetBuilderBidValues(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	topBids := []*TopBid{}
+	seen := make(map[string]bool)
+	for _, bid := range bids {
+		key := bid.ParentHash + "_" + bid.ProposerPubkey
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		bidStr, err := r.client.HGet(context.Background(), r.keyBlockBuilderLatestBids(slot, bid.ParentHash, bid.ProposerPubkey), bid.BuilderPubkey).Result()
+		if errors.Is(err, redis.Nil) {
+			continue // expired in the meantime
+		} else if err != nil {
+			return nil, err
+		}
+		headerResp := new(common.GetHeaderResponse)
+		if err := json.Unmarshal([]byte(bidStr), headerResp); err != nil {
+			return nil, err
+		}
+		receivedAt, err := r.GetBuilderLatestPayloadReceivedAt(slot, bid.BuilderPubkey, bid.ParentHash, bid.ProposerPubkey)
+		if err != nil {
+			return nil, err
+		}
+
+		topBids = append(topBids, &TopBid{
+			Slot:           slot,
+			ParentHash:     bid.ParentHash,
+			ProposerPubkey: bid.ProposerPubkey,
+			BuilderPubkey:  bid.BuilderPubkey,
+			BlockHash:      headerResp.BlockHash().String(),
+			Value:          bid.Value,
+			ReceivedAt:     receivedAt,
+		})
+	}
+	return topBids, nil
+}
+
+// GetLatestBuilderBids returns the latest bid of every builder for the slot, parent hash and proposer, highest first
+func (r *RedisCache) GetLatestBuilderBids(slot uint64, parentHash, proposerPubkey string) ([]*common.GetHeaderResponse, error) {
+	bidStrs, err := r.client.HGetAll(context.Background(), r.keyBlockBuilderLatestBids(slot, parentHash, proposerPubkey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	bids := make([]*common.GetHeaderResponse, 0, len(bidStrs))
+	for _, bidStr := range bidStrs {
+		headerResp := new(common.GetHeaderResponse)
+		if err := json.Unmarshal([]byte(bidStr), headerResp); err != nil {
+			return nil, err
+		}
+		if !headerResp.Empty() {
+			bids = append(bids, headerResp)
+		}
+	}
+	sort.Slice(bids, func(i, j int) bool {
+		return bids[i].Value().Cmp(bids[j].Value()) > 0
+	})
+	return bids, nil
+}
+
+// UpdateTopBid selects the highest of the latest bids of all builders as top bid, and returns it
+func (r *RedisCache) UpdateTopBid(slot uint64, parentHash, proposerPubkey string) (*TopBidUpdate, error) {
 	// Get all builder's latest submission values
 	keyBidValues := r.keyBlockBuilderLatestBidsValue(slot, parentHash, proposerPubkey)
 	bidValueMap, err := r.client.HGetAll(context.Background(), keyBidValues).Result()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Find bid with highest value among all the latest bids
@@ -395,17 +1315,120 @@ func (r *RedisCache) UpdateTopBid(slot uint64, parentHash, proposerPubkey string
 	}
 
 	if topBidBuilderPubkey == "" {
-		return ErrFailedUpdatingTopBidNoBids
+		return nil, ErrFailedUpdatingTopBidNoBids
 	}
 
 	// Get the actual bid
 	keyBid := r.keyBlockBuilderLatestBids(slot, parentHash, proposerPubkey)
 	bidStr, err := r.client.HGet(context.Background(), keyBid, topBidBuilderPubkey).Result()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Save the top bid
 	keyTopBid := r.keyCacheGetHeaderResponse(slot, parentHash, proposerPubkey)
-	return r.client.Set(context.Background(), keyTopBid, bidStr, expiryBidCache).Err()
+	err = r.client.Set(context.Background(), keyTopBid, bidStr, expiryBidCache).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify subscribers of the top bid stream
+	topBid := &TopBidUpdate{
+		Slot:           slot,
+		ParentHash:     parentHash,
+		ProposerPubkey: proposerPubkey,
+		BuilderPubkey:  topBidBuilderPubkey,
+		Value:          topBidValue.String(),
+	}
+	update, err := json.Marshal(topBid)
+	if err != nil {
+		return nil, err
+	}
+	return topBid, r.client.Publish(context.Background(), r.channelTopBidUpdates, update).Err()
+}
+
+// SubscribeTopBidUpdates returns a channel receiving all top bid updates, until the context is cancelled
+func (r *RedisCache) SubscribeTopBidUpdates(ctx context.Context) <-chan *TopBidUpdate {
+	return subscribe[TopBidUpdate](ctx, r.client, r.channelTopBidUpdates)
+}
+
+// PublishDataStreamEvent notifies the data stream subscribers of all relay instances
+func (r *RedisCache) PublishDataStreamEvent(eventType string, data any) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	event, err := json.Marshal(DataStreamEvent{Type: eventType, Data: dataBytes})
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(context.Background(), r.channelDataStream, event).Err()
+}
+
+// SubscribeDataStreamEvents returns a channel receiving all data stream events, until the context is cancelled
+func (r *RedisCache) SubscribeDataStreamEvents(ctx context.Context) <-chan *DataStreamEvent {
+	return subscribe[DataStreamEvent](ctx, r.client, r.channelDataStream)
+}
+
+// subscribe returns a channel receiving the JSON-decoded messages of a pub/sub channel, until the context is cancelled
+func subscribe[T any](ctx context.Context, client *redis.Client, channel string) <-chan *T {
+	pubsub := client.Subscribe(ctx, channel)
+	updates := make(chan *T, 100)
+
+	go func() {
+		defer close(updates)
+		defer pubsub.Close()
+
+		msgC := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgC:
+				if !ok {
+					return
+				}
+				update := new(T)
+				if err := json.Unmarshal([]byte(msg.Payload), update); err != nil {
+					continue
+				}
+				updates <- update
+			}
+		}
+	}()
+
+	return updates
+}
+
+// renews or releases the leader lock, but only if it is still held by the given instance
+var (
+	scriptRenewLeaderLock = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0`)
+	scriptReleaseLeaderLock = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0`)
+)
+
+// AcquireOrRenewLeaderLock makes the instance the housekeeper leader for the given TTL, if no other instance holds the
+// lock. Returns whether the instance holds the lock.
+func (r *RedisCache) AcquireOrRenewLeaderLock(instanceID string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	renewed, err := scriptRenewLeaderLock.Run(ctx, r.client, []string{r.keyHousekeeperLeader}, instanceID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	} else if renewed == 1 {
+		return true, nil
+	}
+	return r.client.SetNX(ctx, r.keyHousekeeperLeader, instanceID, ttl).Result()
+}
+
+// ReleaseLeaderLock gives up the leadership, so that a standby instance can take over without waiting for the TTL
+func (r *RedisCache) ReleaseLeaderLock(instanceID string) error {
+	return scriptReleaseLeaderLock.Run(context.Background(), r.client, []string{r.keyHousekeeperLeader}, instanceID).Err()
 }
@@ -0,0 +1,109 @@
+package datastore
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// number of keys inspected and deleted per round trip
+const redisGCBatchSize = 1000
+
+// GarbageCollectionResult summarizes a CollectGarbage run
+type GarbageCollectionResult struct {
+	NumKeysScanned int
+	NumKeysDeleted int
+	BytesReclaimed int64 // as reported by MEMORY USAGE, so an estimate
+}
+
+// CollectGarbage deletes the per-slot keys (bids, payloads, bid traces, submission counts, simulation results, ...) of slots
+// before minSlot, and the active validator sets older than their expiry. These all have TTLs, so this only finds keys
+// which were orphaned, e.g. because setting the TTL failed.
+func (r *RedisCache) CollectGarbage(ctx context.Context, minSlot uint64, now time.Time) (*GarbageCollectionResult, error) {
+	result := new(GarbageCollectionResult)
+
+	perSlotPrefixes := []string{
+		r.prefixGetHeaderResponse,
+		r.prefixGetPayloadResponse,
+		r.prefixBidTrace,
+		r.prefixDeferredPayloadURL,
+		r.prefixDeferredPayloadHeader,
+		r.prefixPeerBidRelay,
+		r.prefixBlockBuilderLatestBids,
+		r.prefixBlockBuilderLatestBidsValue,
+		r.prefixBlockBuilderLatestBidsTime,
+		r.prefixBlockBuilderSubmissionCount,
+		r.prefixSimResult,
+		r.prefixAcceptedSubmissions,
+		r.prefixGetPayloadBlockHash,
+		r.prefixGetHeaderRequestTime,
+		r.prefixInclusionConstraints,
+		r.prefixBidHistory,
+	}
+	for _, prefix := range perSlotPrefixes {
+		err := r.collectGarbage(ctx, prefix, result, func(suffix string) bool {
+			slotStr, _, _ := strings.Cut(suffix, "_")
+			slot, err := strconv.ParseUint(slotStr, 10, 64)
+			return err == nil && slot < minSlot
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// the active validator sets are per hour, and are kept for expiryActiveValidators
+	minHour := now.Add(-expiryActiveValidators - time.Hour).UTC()
+	err := r.collectGarbage(ctx, r.prefixActiveValidators, result, func(suffix string) bool {
+		hour, err := time.Parse("2006-01-02T15", suffix)
+		return err == nil && hour.Before(minHour)
+	})
+	return result, err
+}
+
+// collectGarbage scans the keys with the given prefix and deletes those for which isStale returns true
+func (r *RedisCache) collectGarbage(ctx context.Context, prefix string, result *GarbageCollectionResult, isStale func(suffix string) bool) error {
+	staleKeys := make([]string, 0, redisGCBatchSize)
+	iter := r.client.Scan(ctx, 0, prefix+":*", redisGCBatchSize).Iterator()
+	for iter.Next(ctx) {
+		result.NumKeysScanned++
+		if isStale(strings.TrimPrefix(iter.Val(), prefix+":")) {
+			staleKeys = append(staleKeys, iter.Val())
+		}
+		if len(staleKeys) == redisGCBatchSize {
+			if err := r.deleteKeys(ctx, staleKeys, result); err != nil {
+				return err
+			}
+			staleKeys = staleKeys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return r.deleteKeys(ctx, staleKeys, result)
+}
+
+func (r *RedisCache) deleteKeys(ctx context.Context, keys []string, result *GarbageCollectionResult) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// the memory usage is only informational, so errors (e.g. if the command isn't supported) are ignored
+	pipe := r.client.Pipeline()
+	memoryUsageCmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		memoryUsageCmds[i] = pipe.MemoryUsage(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range memoryUsageCmds {
+		if cmd.Err() == nil {
+			result.BytesReclaimed += cmd.Val()
+		}
+	}
+
+	numDeleted, err := r.client.Unlink(ctx, keys...).Result()
+	result.NumKeysDeleted += int(numDeleted)
+	return err
+}
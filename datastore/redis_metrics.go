@@ -0,0 +1,33 @@
+package datastore
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/go-redis/redis/v9"
+)
+
+// metricsHook records the latency of the redis commands
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		defer common.ObserveDuration(common.RedisOperationDuration.WithLabelValues(cmd.Name()), time.Now())
+		return next(ctx, cmd)
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		defer common.ObserveDuration(common.RedisOperationDuration.WithLabelValues("pipeline"), time.Now())
+		return next(ctx, cmds)
+	}
+}
@@ -0,0 +1,154 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// number of fields or members copied per round trip
+const redisMigrateBatchSize = 1000
+
+var ErrUnsupportedRedisType = errors.New("unsupported redis type")
+
+// RedisMigrationResult is the number of entries (hash fields, set members, or 1 for strings) of a migrated key in the
+// source and in the destination after the migration
+type RedisMigrationResult struct {
+	Key       string
+	NumSource int64
+	NumDest   int64
+}
+
+// migratedKeys returns the keys with the long-lived state of the relay. The per-slot caches, active validators and the
+// housekeeper lock are rebuilt by the relay, and aren't migrated.
+func (r *RedisCache) migratedKeys() []string {
+	return []string{
+		r.keyKnownValidators,
+		r.keyValidatorRegistrationTimestamp,
+		r.keyValidatorRegistrationMessage,
+		r.keyProposerDuties,
+		r.keyRelayConfig,
+		r.keyStats,
+		r.keyBlockBuilderStatus,
+		r.keyBlockBuilderCollateral,
+		r.keyBlockBuilderAPIKeyHash,
+		r.keyBlocklist,
+		r.keyFeatureFlags,
+	}
+}
+
+// MigrateTo copies the long-lived state (known validators, registration timestamps, proposer duties, builder statuses,
+// ...) to another redis, whose keys can have a different prefix. The keys are replaced in the destination. Each key is
+// written to a temporary key first and renamed when complete, so the destination never sees partial state.
+func (r *RedisCache) MigrateTo(ctx context.Context, dst *RedisCache) ([]RedisMigrationResult, error) {
+	srcKeys := r.migratedKeys()
+	dstKeys := dst.migratedKeys()
+	results := make([]RedisMigrationResult, len(srcKeys))
+	for i, srcKey := range srcKeys {
+		keyType, err := r.client.Type(ctx, srcKey).Result()
+		if err != nil {
+			return results, err
+		}
+		if err := migrateKey(ctx, r.client, dst.client, keyType, srcKey, dstKeys[i]); err != nil {
+			return results, fmt.Errorf("failed to migrate %s: %w", srcKey, err)
+		}
+
+		results[i].Key = dstKeys[i]
+		if results[i].NumSource, err = countKeyEntries(ctx, r.client, keyType, srcKey); err != nil {
+			return results, err
+		}
+		if results[i].NumDest, err = countKeyEntries(ctx, dst.client, keyType, dstKeys[i]); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func migrateKey(ctx context.Context, src, dst *redis.Client, keyType, srcKey, dstKey string) error {
+	tmpKey := dstKey + ":migrating"
+	if err := dst.Del(ctx, tmpKey).Err(); err != nil {
+		return err
+	}
+
+	switch keyType {
+	case "none":
+		return dst.Del(ctx, dstKey).Err()
+	case "string":
+		value, err := src.Get(ctx, srcKey).Result()
+		if err != nil {
+			return err
+		}
+		ttl, err := src.PTTL(ctx, srcKey).Result()
+		if err != nil {
+			return err
+		} else if ttl < 0 {
+			ttl = 0 // no expiry
+		}
+		return dst.Set(ctx, dstKey, value, ttl).Err()
+	case "hash":
+		iter := src.HScan(ctx, srcKey, 0, "", redisMigrateBatchSize).Iterator()
+		values := make([]any, 0, 2*redisMigrateBatchSize)
+		for iter.Next(ctx) {
+			values = append(values, iter.Val()) // alternating fields and values
+			if len(values) >= 2*redisMigrateBatchSize {
+				if err := dst.HSet(ctx, tmpKey, values...).Err(); err != nil {
+					return err
+				}
+				values = values[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			if err := dst.HSet(ctx, tmpKey, values...).Err(); err != nil {
+				return err
+			}
+		}
+	case "set":
+		iter := src.SScan(ctx, srcKey, 0, "", redisMigrateBatchSize).Iterator()
+		members := make([]any, 0, redisMigrateBatchSize)
+		for iter.Next(ctx) {
+			members = append(members, iter.Val())
+			if len(members) >= redisMigrateBatchSize {
+				if err := dst.SAdd(ctx, tmpKey, members...).Err(); err != nil {
+					return err
+				}
+				members = members[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			if err := dst.SAdd(ctx, tmpKey, members...).Err(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedRedisType, keyType)
+	}
+
+	// an empty hash or set doesn't exist, so there's nothing to rename
+	exists, err := dst.Exists(ctx, tmpKey).Result()
+	if err != nil {
+		return err
+	} else if exists == 0 {
+		return dst.Del(ctx, dstKey).Err()
+	}
+	return dst.Rename(ctx, tmpKey, dstKey).Err()
+}
+
+// countKeyEntries returns the number of hash fields or set members of a key, or 1 for an existing string
+func countKeyEntries(ctx context.Context, client *redis.Client, keyType, key string) (int64, error) {
+	switch keyType {
+	case "hash":
+		return client.HLen(ctx, key).Result()
+	case "set":
+		return client.SCard(ctx, key).Result()
+	default:
+		return client.Exists(ctx, key).Result()
+	}
+}
@@ -1,12 +1,19 @@
 package datastore
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
 	"github.com/stretchr/testify/require"
 )
 
@@ -91,6 +98,19 @@ func TestRedisKnownValidators(t *testing.T) {
 		require.Contains(t, knownVals, key1)
 		require.Contains(t, knownVals, key2)
 	})
+
+	t.Run("Can save known validators in bulk", func(t *testing.T) {
+		key1 := types.NewPubkeyHex("0x1a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+		key3 := types.NewPubkeyHex("0x3a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+		require.NoError(t, cache.SetKnownValidators(map[types.PubkeyHex]uint64{key1: 10, key3: 3}))
+		require.NoError(t, cache.SetKnownValidators(map[types.PubkeyHex]uint64{}))
+
+		knownVals, err := cache.GetKnownValidators()
+		require.NoError(t, err)
+		require.Equal(t, 3, len(knownVals))
+		require.Equal(t, uint64(10), knownVals[key1])
+		require.Equal(t, uint64(3), knownVals[key3])
+	})
 }
 
 func TestRedisValidatorRegistrations(t *testing.T) {
@@ -128,6 +148,43 @@ func TestRedisValidatorRegistrations(t *testing.T) {
 	})
 }
 
+func TestRedisValidatorRegistrationTimestamps(t *testing.T) {
+	cache := setupTestRedis(t)
+	pubkeys := []types.PubkeyHex{"0xa1", "0xa2", "0xa3"}
+	require.NoError(t, cache.SetValidatorRegistrationTimestamp(pubkeys[0], 10))
+
+	timestamps, err := cache.GetValidatorRegistrationTimestamps(pubkeys)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{10, 0, 0}, timestamps)
+
+	err = cache.SetValidatorRegistrationTimestampsIfNewer(map[types.PubkeyHex]uint64{pubkeys[0]: 9, pubkeys[1]: 11})
+	require.NoError(t, err)
+	timestamps, err = cache.GetValidatorRegistrationTimestamps(pubkeys)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{10, 11, 0}, timestamps)
+}
+
+func TestRedisValidatorRegistrationMessages(t *testing.T) {
+	cache := setupTestRedis(t)
+	pubkeys := []types.PubkeyHex{"0xa1", "0xa2", "0xa3"}
+	require.NoError(t, cache.SetValidatorRegistrationTimestamp(pubkeys[0], 10))
+
+	err := cache.SetValidatorRegistrationsIfNewer(map[types.PubkeyHex]ValidatorRegistrationMessage{
+		pubkeys[0]: {Timestamp: 9, FeeRecipient: "0xF1", GasLimit: 30_000_000},
+		pubkeys[1]: {Timestamp: 11, FeeRecipient: "0xF2", GasLimit: 30_000_000},
+	})
+	require.NoError(t, err)
+
+	// only the timestamp is known of the registration stored before
+	messages, err := cache.GetValidatorRegistrationMessages(pubkeys)
+	require.NoError(t, err)
+	require.Equal(t, []ValidatorRegistrationMessage{
+		{Timestamp: 10},
+		{Timestamp: 11, FeeRecipient: "0xf2", GasLimit: 30_000_000},
+		{},
+	}, messages)
+}
+
 func TestRedisProposerDuties(t *testing.T) {
 	cache := setupTestRedis(t)
 	duties := []types.BuilderGetValidatorsResponseEntry{
@@ -154,6 +211,170 @@ func TestRedisProposerDuties(t *testing.T) {
 	require.Equal(t, duties[0].Entry.Message.FeeRecipient, duties2[0].Entry.Message.FeeRecipient)
 }
 
+func TestBuilderCollateral(t *testing.T) {
+	cache := setupTestRedis(t)
+	builderPubkey := "0xb67a5148a03229926e34b190af81a82a81c4df66831c98c03a139778418dd09a3b542ced0022620d19f35781ece6dc36"
+
+	collateral, err := cache.GetBlockBuilderCollateral(builderPubkey)
+	require.NoError(t, err)
+	require.Equal(t, "", collateral)
+
+	err = cache.SetBlockBuilderCollateral(builderPubkey, "1000000000000000000")
+	require.NoError(t, err)
+	collateral, err = cache.GetBlockBuilderCollateral(builderPubkey)
+	require.NoError(t, err)
+	require.Equal(t, "1000000000000000000", collateral)
+
+	err = cache.DeleteBlockBuilderCollateral(builderPubkey)
+	require.NoError(t, err)
+	collateral, err = cache.GetBlockBuilderCollateral(builderPubkey)
+	require.NoError(t, err)
+	require.Equal(t, "", collateral)
+}
+
+func TestBuilderStatusUpdates(t *testing.T) {
+	cache := setupTestRedis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := cache.SubscribeBuilderStatusUpdates(ctx)
+
+	// the subscription is set up asynchronously, so set statuses until an update is received
+	var update *BuilderStatusUpdate
+	require.Eventually(t, func() bool {
+		if err := cache.SetBlockBuilderStatus("0x02", RedisBlockBuilderStatusBlacklisted); err != nil {
+			return false
+		}
+		select {
+		case update = <-updates:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	require.Equal(t, &BuilderStatusUpdate{BuilderPubkey: "0x02", Status: RedisBlockBuilderStatusBlacklisted}, update)
+	require.NoError(t, cache.SetBlockBuilderStatus("0x01", RedisBlockBuilderStatusHighPrio))
+
+	// only the changed statuses are set
+	changed, err := cache.UpdateBlockBuilderStatuses(map[string]BlockBuilderStatus{
+		"0x01": RedisBlockBuilderStatusHighPrio,
+		"0x02": RedisBlockBuilderStatusLowPrio,
+		"0x03": RedisBlockBuilderStatusLowPrio,
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"0x02", "0x03"}, changed)
+
+	statuses, err := cache.GetBlockBuilderStatuses()
+	require.NoError(t, err)
+	require.Equal(t, map[string]BlockBuilderStatus{
+		"0x01": RedisBlockBuilderStatusHighPrio,
+		"0x02": RedisBlockBuilderStatusLowPrio,
+		"0x03": RedisBlockBuilderStatusLowPrio,
+	}, statuses)
+}
+
+func TestBuilderSubmissionCount(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	for i := int64(1); i <= 3; i++ {
+		cnt, err := cache.IncBuilderSubmissionCount(1, "0xb1")
+		require.NoError(t, err)
+		require.Equal(t, i, cnt)
+	}
+
+	// counted per builder and slot
+	cnt, err := cache.IncBuilderSubmissionCount(1, "0xb2")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), cnt)
+	cnt, err = cache.IncBuilderSubmissionCount(2, "0xb1")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), cnt)
+
+	// reading the count doesn't increment it
+	cnt, err = cache.GetBuilderSubmissionCount(1, "0xb1")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cnt)
+	cnt, err = cache.GetBuilderSubmissionCount(1, "0xb3")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), cnt)
+}
+
+func TestSimResult(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	result, err := cache.GetSimResult(1, "0xaa")
+	require.NoError(t, err)
+	require.Nil(t, result)
+
+	expected := &SimResult{Fingerprint: "fp", Error: "simulation failed: invalid block"}
+	err = cache.SaveSimResult(1, "0xaa", expected)
+	require.NoError(t, err)
+
+	result, err = cache.GetSimResult(1, "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+
+	// cached per slot
+	result, err = cache.GetSimResult(2, "0xaa")
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
+
+func TestAcceptedSubmission(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	isAccepted, err := cache.IsAcceptedSubmission(1, "0xb1", "0xaa", "100")
+	require.NoError(t, err)
+	require.False(t, isAccepted)
+
+	require.NoError(t, cache.SaveAcceptedSubmission(1, "0xB1", "0xAA", "100"))
+	isAccepted, err = cache.IsAcceptedSubmission(1, "0xb1", "0xaa", "100")
+	require.NoError(t, err)
+	require.True(t, isAccepted)
+
+	// only the same value, and the same slot
+	isAccepted, err = cache.IsAcceptedSubmission(1, "0xb1", "0xaa", "101")
+	require.NoError(t, err)
+	require.False(t, isAccepted)
+	isAccepted, err = cache.IsAcceptedSubmission(2, "0xb1", "0xaa", "100")
+	require.NoError(t, err)
+	require.False(t, isAccepted)
+
+	// a later submission of the builder replaces it
+	require.NoError(t, cache.SaveAcceptedSubmission(1, "0xb1", "0xbb", "90"))
+	isAccepted, err = cache.IsAcceptedSubmission(1, "0xb1", "0xaa", "100")
+	require.NoError(t, err)
+	require.False(t, isAccepted)
+}
+
+func TestDataStreamEvents(t *testing.T) {
+	cache := setupTestRedis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := cache.SubscribeDataStreamEvents(ctx)
+	data := map[string]string{"slot": "1"}
+
+	// the subscription is set up asynchronously, so publish until the event is received
+	var event *DataStreamEvent
+	require.Eventually(t, func() bool {
+		if err := cache.PublishDataStreamEvent(DataStreamEventPayloadDelivered, data); err != nil {
+			return false
+		}
+		select {
+		case event = <-events:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, DataStreamEventPayloadDelivered, event.Type)
+	expectedData, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedData), string(event.Data))
+}
+
 func TestActiveValidators(t *testing.T) {
 	pk1 := types.NewPubkeyHex("0x8016d3229030424cfeff6c5b813970ea193f8d012cfa767270ca9057d58eddc556e96c14544bf4c038dbed5f24aa8da0")
 	cache := setupTestRedis(t)
@@ -199,8 +420,9 @@ func TestBuilderBids(t *testing.T) {
 	require.NoError(t, err)
 	err = cache.SaveLatestBuilderBid(slot, builder2pk, parentHash, proposerPk, receivedAt, _buildGetHeaderResponse(99))
 	require.NoError(t, err)
-	err = cache.UpdateTopBid(slot, parentHash, proposerPk)
+	update, err := cache.UpdateTopBid(slot, parentHash, proposerPk)
 	require.NoError(t, err)
+	require.Equal(t, &TopBidUpdate{Slot: slot, ParentHash: parentHash, ProposerPubkey: proposerPk, BuilderPubkey: builder1pk, Value: "100"}, update)
 	topBid, err := cache.GetBestBid(slot, parentHash, proposerPk)
 	require.NoError(t, err)
 	require.Equal(t, "100", topBid.Value().String())
@@ -208,8 +430,9 @@ func TestBuilderBids(t *testing.T) {
 	// new top bid by builder3: 101
 	err = cache.SaveLatestBuilderBid(slot, builder3pk, parentHash, proposerPk, receivedAt, _buildGetHeaderResponse(101))
 	require.NoError(t, err)
-	err = cache.UpdateTopBid(slot, parentHash, proposerPk)
+	update, err = cache.UpdateTopBid(slot, parentHash, proposerPk)
 	require.NoError(t, err)
+	require.Equal(t, builder3pk, update.BuilderPubkey)
 	topBid, err = cache.GetBestBid(slot, parentHash, proposerPk)
 	require.NoError(t, err)
 	require.Equal(t, "101", topBid.Value().String())
@@ -217,7 +440,7 @@ func TestBuilderBids(t *testing.T) {
 	// builder3 cancels 101 bid, by sending 100 value
 	err = cache.SaveLatestBuilderBid(slot, builder3pk, parentHash, proposerPk, receivedAt, _buildGetHeaderResponse(99))
 	require.NoError(t, err)
-	err = cache.UpdateTopBid(slot, parentHash, proposerPk)
+	_, err = cache.UpdateTopBid(slot, parentHash, proposerPk)
 	require.NoError(t, err)
 	topBid, err = cache.GetBestBid(slot, parentHash, proposerPk)
 	require.NoError(t, err)
@@ -227,6 +450,29 @@ func TestBuilderBids(t *testing.T) {
 	ts, err := cache.GetBuilderLatestPayloadReceivedAt(slot, builder3pk, parentHash, proposerPk)
 	require.NoError(t, err)
 	require.Equal(t, receivedAt.UnixMilli(), ts)
+
+	// the latest bids of all builders in the slot, highest first
+	err = cache.SaveLatestBuilderBid(slot*10, builder1pk, parentHash, proposerPk, receivedAt, _buildGetHeaderResponse(200))
+	require.NoError(t, err)
+	bids, err := cache.GetBuilderBidValues(slot)
+	require.NoError(t, err)
+	require.Len(t, bids, 3)
+	require.Equal(t, &BuilderBidValue{Slot: slot, ParentHash: parentHash, ProposerPubkey: proposerPk, BuilderPubkey: builder1pk, Value: "100"}, bids[0])
+	require.Equal(t, "99", bids[1].Value)
+	require.Equal(t, "99", bids[2].Value)
+
+	// the top bid of the slot
+	topBids, err := cache.GetTopBids(slot)
+	require.NoError(t, err)
+	require.Len(t, topBids, 1)
+	require.Equal(t, builder1pk, topBids[0].BuilderPubkey)
+	require.Equal(t, "100", topBids[0].Value)
+	require.Equal(t, receivedAt.UnixMilli(), topBids[0].ReceivedAt)
+	require.Equal(t, _buildGetHeaderResponse(100).BlockHash().String(), topBids[0].BlockHash)
+
+	topBids, err = cache.GetTopBids(slot + 1)
+	require.NoError(t, err)
+	require.Len(t, topBids, 0)
 }
 
 func TestRedisURIs(t *testing.T) {
@@ -258,3 +504,334 @@ func TestRedisURIs(t *testing.T) {
 	_, err = NewRedisCache(malformURL, "")
 	require.Error(t, err)
 }
+
+func TestLeaderLock(t *testing.T) {
+	cache := setupTestRedis(t)
+	ttl := 10 * time.Second
+
+	isLeader, err := cache.AcquireOrRenewLeaderLock("a", ttl)
+	require.NoError(t, err)
+	require.True(t, isLeader)
+
+	// renewed by the leader, but not acquired by others
+	isLeader, err = cache.AcquireOrRenewLeaderLock("a", ttl)
+	require.NoError(t, err)
+	require.True(t, isLeader)
+	isLeader, err = cache.AcquireOrRenewLeaderLock("b", ttl)
+	require.NoError(t, err)
+	require.False(t, isLeader)
+
+	// only released by the leader
+	require.NoError(t, cache.ReleaseLeaderLock("b"))
+	isLeader, err = cache.AcquireOrRenewLeaderLock("b", ttl)
+	require.NoError(t, err)
+	require.False(t, isLeader)
+
+	require.NoError(t, cache.ReleaseLeaderLock("a"))
+	isLeader, err = cache.AcquireOrRenewLeaderLock("b", ttl)
+	require.NoError(t, err)
+	require.True(t, isLeader)
+}
+
+func TestCollectGarbage(t *testing.T) {
+	cache := setupTestRedis(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, slot := range []uint64{1, 2, 100} {
+		_, err := cache.IncBuilderSubmissionCount(slot, "0xb1")
+		require.NoError(t, err)
+		err = cache.SaveSimResult(slot, "0xaa", &SimResult{Fingerprint: "fp"})
+		require.NoError(t, err)
+	}
+
+	// orphaned keys without a TTL
+	err := cache.client.Set(ctx, cache.keyCacheGetHeaderResponse(1, "0xparent", "0xproposer"), "bid", 0).Err()
+	require.NoError(t, err)
+	err = cache.client.HSet(ctx, cache.keyActiveValidators(now.Add(-24*time.Hour)), "0xproposer", "1").Err()
+	require.NoError(t, err)
+	err = cache.SetActiveValidator("0xproposer")
+	require.NoError(t, err)
+
+	result, err := cache.CollectGarbage(ctx, 50, now)
+	require.NoError(t, err)
+	require.Equal(t, 9, result.NumKeysScanned)
+	require.Equal(t, 6, result.NumKeysDeleted)
+
+	// the current slot and hour are kept
+	simResult, err := cache.GetSimResult(100, "0xaa")
+	require.NoError(t, err)
+	require.NotNil(t, simResult)
+	simResult, err = cache.GetSimResult(1, "0xaa")
+	require.NoError(t, err)
+	require.Nil(t, simResult)
+
+	activeValidators, err := cache.GetActiveValidators()
+	require.NoError(t, err)
+	require.Len(t, activeValidators, 1)
+
+	// nothing left to delete
+	result, err = cache.CollectGarbage(ctx, 50, now)
+	require.NoError(t, err)
+	require.Equal(t, 0, result.NumKeysDeleted)
+}
+
+func TestGetPayloadBlockHash(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	prevBlockHash, err := cache.CheckAndSetGetPayloadBlockHash(1, "0xproposer", "0xAA")
+	require.NoError(t, err)
+	require.Equal(t, "", prevBlockHash)
+
+	// retries for the same block are fine
+	prevBlockHash, err = cache.CheckAndSetGetPayloadBlockHash(1, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, "", prevBlockHash)
+
+	// a different block in the same slot is an equivocation
+	prevBlockHash, err = cache.CheckAndSetGetPayloadBlockHash(1, "0xproposer", "0xbb")
+	require.NoError(t, err)
+	require.Equal(t, "0xaa", prevBlockHash)
+
+	prevBlockHash, err = cache.CheckAndSetGetPayloadBlockHash(2, "0xproposer", "0xbb")
+	require.NoError(t, err)
+	require.Equal(t, "", prevBlockHash)
+}
+
+func TestGetHeaderRequestTime(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	requestTime, err := cache.GetGetHeaderRequestTime(1, "0xproposer")
+	require.NoError(t, err)
+	require.True(t, requestTime.IsZero())
+
+	// the latest call wins
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+	require.NoError(t, cache.SetGetHeaderRequestTime(1, "0xPROPOSER", now.Add(-time.Second)))
+	require.NoError(t, cache.SetGetHeaderRequestTime(1, "0xproposer", now))
+	requestTime, err = cache.GetGetHeaderRequestTime(1, "0xproposer")
+	require.NoError(t, err)
+	require.Equal(t, now, requestTime)
+
+	requestTime, err = cache.GetGetHeaderRequestTime(2, "0xproposer")
+	require.NoError(t, err)
+	require.True(t, requestTime.IsZero())
+}
+
+func TestPeerBidRelay(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	peerRelayPubkey, err := cache.GetPeerBidRelay(1, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, "", peerRelayPubkey)
+
+	err = cache.SavePeerBidRelay(1, "0xproposer", "0xaa", "0xpeer")
+	require.NoError(t, err)
+	peerRelayPubkey, err = cache.GetPeerBidRelay(1, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, "0xpeer", peerRelayPubkey)
+}
+
+func TestDeferredPayloadHeader(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	header, err := cache.GetDeferredPayloadHeader(1, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Nil(t, header)
+
+	saved := &capella.ExecutionPayloadHeader{BlockNumber: 5, GasLimit: 30_000_000, ExtraData: []byte{}, BaseFeePerGas: [32]byte{0x01}}
+	err = cache.SaveDeferredPayloadHeader(1, "0xproposer", "0xaa", saved)
+	require.NoError(t, err)
+	header, err = cache.GetDeferredPayloadHeader(1, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, saved, header)
+}
+
+func TestExecutionPayloadCompression(t *testing.T) {
+	cache := setupTestRedis(t)
+	payload := &common.GetPayloadResponse{
+		Bellatrix: &types.GetPayloadResponse{
+			Version: types.VersionString(consensusspec.DataVersionBellatrix.String()),
+			Data: &types.ExecutionPayload{
+				BlockNumber:   12,
+				ExtraData:     []byte{0x01},
+				Transactions:  []hexutil.Bytes{{0x01, 0x02}},
+				BaseFeePerGas: types.U256Str{0x01},
+			},
+		},
+	}
+
+	require.NoError(t, cache.SaveExecutionPayload(1, "0xproposer", "0xaa", payload))
+	expected, err := cache.GetExecutionPayload(1, "0xproposer", "0xaa")
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), expected.Bellatrix.Data.BlockNumber)
+
+	for codec, marker := range redisCodecMarkers {
+		cache.payloadCodec = codec
+		require.NoError(t, cache.SaveExecutionPayload(2, "0xproposer", "0xaa", payload))
+		value, err := cache.client.Get(context.Background(), cache.keyCacheGetPayloadResponse(2, "0xproposer", "0xaa")).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, marker, value[0], codec)
+
+		// read back whatever the codec of the reader
+		cache.payloadCodec = common.PayloadCodecNone
+		resp, err := cache.GetExecutionPayload(2, "0xproposer", "0xaa")
+		require.NoError(t, err)
+		require.Equal(t, expected, resp, codec)
+	}
+}
+
+func TestBidHistory(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	for i := 1; i <= 3; i++ {
+		entry := &database.BidHistoryEntry{Slot: 10, BuilderPubkey: "0xbuilder", Value: fmt.Sprint(i)} //nolint:exhaustruct
+		require.NoError(t, cache.AppendBidHistory(entry, 2))
+	}
+
+	// only the latest changes are kept, and only the first instance taking them gets them
+	entries, err := cache.TakeBidHistory(10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "2", entries[0].Value)
+	require.Equal(t, "3", entries[1].Value)
+
+	entries, err = cache.TakeBidHistory(10)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestBlocklist(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	blocklist, err := cache.GetBlocklist()
+	require.NoError(t, err)
+	require.Nil(t, blocklist)
+
+	err = cache.SetBlocklist([]string{"0xAA00000000000000000000000000000000000001", "0xaa00000000000000000000000000000000000002"})
+	require.NoError(t, err)
+	blocklist, err = cache.GetBlocklist()
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{
+		"0xaa00000000000000000000000000000000000001": true,
+		"0xaa00000000000000000000000000000000000002": true,
+	}, blocklist)
+
+	// replaced, not merged
+	err = cache.SetBlocklist([]string{"0xaa00000000000000000000000000000000000003"})
+	require.NoError(t, err)
+	blocklist, err = cache.GetBlocklist()
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"0xaa00000000000000000000000000000000000003": true}, blocklist)
+}
+
+func TestMigrateTo(t *testing.T) {
+	src := setupTestRedis(t)
+	dstServer, err := miniredis.Run()
+	require.NoError(t, err)
+	dst, err := NewRedisCache(dstServer.Addr(), "other-prefix")
+	require.NoError(t, err)
+
+	pubkey := types.NewPubkeyHex("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	require.NoError(t, src.SetKnownValidator(pubkey, 1))
+	require.NoError(t, src.SetValidatorRegistrationTimestamp(pubkey, 1000))
+	require.NoError(t, src.SetProposerDuties([]types.BuilderGetValidatorsResponseEntry{{Slot: 1}}))
+	require.NoError(t, src.SetBlocklist([]string{"0xaa00000000000000000000000000000000000001"}))
+
+	// stale state in the destination is replaced
+	require.NoError(t, dst.SetKnownValidator(types.NewPubkeyHex("0x01"), 2))
+	require.NoError(t, dst.SetBlockBuilderCollateral("0x02", "1000"))
+
+	results, err := src.MigrateTo(context.Background(), dst)
+	require.NoError(t, err)
+	for _, result := range results {
+		require.Equal(t, result.NumSource, result.NumDest, result.Key)
+	}
+
+	knownValidators, err := dst.GetKnownValidators()
+	require.NoError(t, err)
+	require.Equal(t, map[types.PubkeyHex]uint64{types.PubkeyHex(PubkeyHexToLowerStr(pubkey)): 1}, knownValidators)
+	timestamp, err := dst.GetValidatorRegistrationTimestamp(pubkey)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), timestamp)
+	duties, err := dst.GetProposerDuties()
+	require.NoError(t, err)
+	require.Len(t, duties, 1)
+	blocklist, err := dst.GetBlocklist()
+	require.NoError(t, err)
+	require.Len(t, blocklist, 1)
+	collateral, err := dst.GetBlockBuilderCollateral("0x02")
+	require.NoError(t, err)
+	require.Equal(t, "", collateral)
+}
+
+func TestInclusionConstraints(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	constraints, err := cache.GetInclusionConstraints(1)
+	require.NoError(t, err)
+	require.Nil(t, constraints)
+
+	signed := &common.SignedInclusionConstraints{
+		Message: &common.InclusionConstraints{
+			Slot:         1,
+			Transactions: []hexutil.Bytes{{0x01, 0x02}},
+		},
+	}
+	err = cache.SaveInclusionConstraints(1, signed)
+	require.NoError(t, err)
+	constraints, err = cache.GetInclusionConstraints(1)
+	require.NoError(t, err)
+	require.Equal(t, signed, constraints)
+
+	delegate, err := cache.GetConstraintsDelegate("0xValidator")
+	require.NoError(t, err)
+	require.Equal(t, "", delegate)
+
+	set, err := cache.SetConstraintsDelegate("0xValidator", "0xDelegate", 10)
+	require.NoError(t, err)
+	require.True(t, set)
+	delegate, err = cache.GetConstraintsDelegate("0xvalidator")
+	require.NoError(t, err)
+	require.Equal(t, "0xdelegate", delegate)
+
+	// only newer delegations replace the delegate
+	for _, timestamp := range []uint64{9, 10} {
+		set, err = cache.SetConstraintsDelegate("0xvalidator", "0xother", timestamp)
+		require.NoError(t, err)
+		require.False(t, set)
+	}
+	set, err = cache.SetConstraintsDelegate("0xvalidator", "0xother", 11)
+	require.NoError(t, err)
+	require.True(t, set)
+	delegate, err = cache.GetConstraintsDelegate("0xvalidator")
+	require.NoError(t, err)
+	require.Equal(t, "0xother", delegate)
+}
+
+func TestProposerAllowlist(t *testing.T) {
+	cache := setupTestRedis(t)
+
+	allowlist, err := cache.GetProposerAllowlist()
+	require.NoError(t, err)
+	require.Nil(t, allowlist)
+
+	err = cache.SetProposerAllowlist([]string{"0xAB01", "0xab02"})
+	require.NoError(t, err)
+	allowlist, err = cache.GetProposerAllowlist()
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"0xab01": true, "0xab02": true}, allowlist)
+
+	// an empty allowlist isn't mistaken for one which was never loaded
+	err = cache.SetProposerAllowlist(nil)
+	require.NoError(t, err)
+	allowlist, err = cache.GetProposerAllowlist()
+	require.NoError(t, err)
+	require.NotNil(t, allowlist)
+	require.Empty(t, allowlist)
+
+	// separate from the blocklist
+	blocklist, err := cache.GetBlocklist()
+	require.NoError(t, err)
+	require.Nil(t, blocklist)
+}
@@ -0,0 +1,233 @@
+// Package eventbus publishes the events of the relay (accepted submissions, top bids, delivered payloads, demoted
+// builders) to Kafka or NATS, for analytics and alerting.
+package eventbus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypes are the event types published to the event bus
+var EventTypes = []string{common.EventSubmissionAccepted, common.EventNewTopBid, common.EventPayloadDelivered, common.EventBuilderDemoted}
+
+var (
+	ErrUnknownEventType    = errors.New("unknown event type")
+	ErrInvalidSubject      = errors.New("invalid event subject")
+	ErrInvalidCAFile       = errors.New("no certificates found in the CA file")
+	ErrUnsupportedEventBus = errors.New("unsupported event bus, expected nats://, tls://, kafka:// or kafka+tls://")
+)
+
+// Event is the message published for every event, the data depends on the type
+type Event struct {
+	Type      string          `json:"type"`
+	Timestamp int64           `json:"timestamp_ms,string"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// BuilderDemotion is the data of the builder_demoted event, published when an optimistic builder is demoted for an
+// invalid block or a payload it failed to deliver
+type BuilderDemotion struct {
+	Slot          uint64 `json:"slot,string"`
+	BuilderPubkey string `json:"builder_pubkey"`
+	BlockHash     string `json:"block_hash"`
+	Value         string `json:"value"`
+	Reason        string `json:"reason"`
+}
+
+// Publisher sends messages to a subject (NATS) or topic (Kafka)
+type Publisher interface {
+	Publish(subject string, data []byte) error
+	Close() error
+}
+
+// Opts configure the connection to the event bus
+type Opts struct {
+	// CAFile, CertFile and KeyFile are the CA certificates of the servers and the client certificate, for TLS
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	// NATSCredentialsFile is the credentials file (JWT and nkey seed) of the NATS user
+	NATSCredentialsFile string
+}
+
+// tlsConfig loads the certificates, nil if none are set
+func (o Opts) tlsConfig() (*tls.Config, error) {
+	if o.CAFile == "" && o.CertFile == "" {
+		return nil, nil //nolint:nilnil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.CAFile != "" {
+		caPEM, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCAFile, o.CAFile)
+		}
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewPublisher returns the publisher for the URL of the event bus: nats://[user:password@]host:port for NATS (tls://
+// for NATS over TLS), with several comma-separated server URLs for a cluster, or
+// kafka[+tls]://[user:password@]host:port[,host:port...] of the Kafka brokers
+func NewPublisher(log *logrus.Entry, busURL string, opts Opts) (Publisher, error) {
+	scheme, _, _ := strings.Cut(busURL, "://")
+	switch scheme {
+	case "nats", "tls":
+		return newNATSPublisher(log, busURL, opts)
+	case "kafka", "kafka+tls":
+		u, err := url.Parse(busURL)
+		if err != nil {
+			return nil, err
+		}
+		return newKafkaPublisher(u, opts)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedEventBus, scheme)
+}
+
+// DefaultSubjects publishes every event type to a subject named after it
+func DefaultSubjects(prefix string) map[string]string {
+	subjects := make(map[string]string, len(EventTypes))
+	for _, eventType := range EventTypes {
+		subjects[eventType] = prefix + "." + eventType
+	}
+	return subjects
+}
+
+// ParseSubjects parses the comma-separated eventType=subject pairs. Only the listed event types are published.
+func ParseSubjects(s string) (map[string]string, error) {
+	subjects := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		eventType, subject, found := strings.Cut(entry, "=")
+		eventType, subject = strings.TrimSpace(eventType), strings.TrimSpace(subject)
+		if !found || subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSubject, entry)
+		}
+		if !isEventType(eventType) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+		}
+		subjects[eventType] = subject
+	}
+	return subjects, nil
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// EventBus queues the events and publishes them in the background, so the requests never wait for the event bus. Events
+// which don't fit in the queue are dropped.
+type EventBus struct {
+	log       *logrus.Entry
+	publisher Publisher
+	subjects  map[string]string // by event type, the event types without subject aren't published
+
+	queue    chan *Event
+	done     chan struct{}
+	isClosed bool
+	lock     sync.RWMutex
+}
+
+func New(log *logrus.Entry, publisher Publisher, subjects map[string]string, queueSize int) *EventBus {
+	bus := &EventBus{
+		log:       log.WithField("component", "eventBus"),
+		publisher: publisher,
+		subjects:  subjects,
+		queue:     make(chan *Event, queueSize),
+		done:      make(chan struct{}),
+	}
+	go bus.run()
+	return bus
+}
+
+func (b *EventBus) run() {
+	defer close(b.done)
+	for event := range b.queue {
+		data, err := json.Marshal(event)
+		if err == nil {
+			err = b.publisher.Publish(b.subjects[event.Type], data)
+		}
+		if err != nil {
+			common.EventBusEventsTotal.WithLabelValues(event.Type, "failed").Inc()
+			b.log.WithError(err).WithField("eventType", event.Type).Warn("failed to publish event")
+			continue
+		}
+		common.EventBusEventsTotal.WithLabelValues(event.Type, "published").Inc()
+	}
+}
+
+// Publish queues the event, if its type has a subject
+func (b *EventBus) Publish(eventType string, data any) {
+	if _, ok := b.subjects[eventType]; !ok {
+		return
+	}
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		b.log.WithError(err).WithField("eventType", eventType).Error("failed to encode event")
+		return
+	}
+	event := &Event{
+		Type:      eventType,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      dataBytes,
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.isClosed {
+		return
+	}
+	select {
+	case b.queue <- event:
+	default:
+		common.EventBusEventsTotal.WithLabelValues(eventType, "dropped").Inc()
+		b.log.WithField("eventType", eventType).Warn("event queue full, dropped the event")
+	}
+}
+
+// Close stops queueing events, and publishes the queued ones until the timeout, before closing the publisher
+func (b *EventBus) Close(timeout time.Duration) error {
+	b.lock.Lock()
+	if !b.isClosed {
+		b.isClosed = true
+		close(b.queue)
+	}
+	b.lock.Unlock()
+
+	select {
+	case <-b.done:
+	case <-time.After(timeout):
+		b.log.Warnf("closing the event bus with %d events left in the queue", len(b.queue))
+	}
+	return b.publisher.Close()
+}
@@ -0,0 +1,113 @@
+package eventbus
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const kafkaTimeout = 5 * time.Second
+
+var ErrUnsupportedSASLMechanism = errors.New("unsupported SASL mechanism, expected plain, scram-sha-256 or scram-sha-512")
+
+// kafkaPublisher produces the messages to the brokers, with one writer per topic since a writer of the Kafka client
+// is bound to its topic
+type kafkaPublisher struct {
+	brokers []string
+	dialer  *kafka.Dialer
+
+	writers map[string]*kafka.Writer // by topic
+	lock    sync.Mutex
+}
+
+// newKafkaPublisher parses kafka[+tls]://[user:password@]host:port[,host:port...][?sasl=mechanism]. The kafka+tls
+// scheme enables TLS, the user and password authenticate with SASL (PLAIN unless the sasl parameter sets another
+// mechanism).
+func newKafkaPublisher(u *url.URL, opts Opts) (*kafkaPublisher, error) {
+	dialer := &kafka.Dialer{
+		ClientID:  "mev-boost-relay",
+		Timeout:   kafkaTimeout,
+		DualStack: true,
+	}
+	if u.Scheme == "kafka+tls" {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		dialer.TLS = tlsConfig
+	}
+	if u.User != nil {
+		mechanism, err := saslMechanism(u.Query().Get("sasl"), u.User)
+		if err != nil {
+			return nil, err
+		}
+		dialer.SASLMechanism = mechanism
+	}
+
+	return &kafkaPublisher{
+		brokers: strings.Split(u.Host, ","),
+		dialer:  dialer,
+		writers: make(map[string]*kafka.Writer),
+	}, nil
+}
+
+func saslMechanism(name string, user *url.Userinfo) (sasl.Mechanism, error) {
+	password, _ := user.Password()
+	switch strings.ToLower(name) {
+	case "", "plain":
+		return plain.Mechanism{Username: user.Username(), Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, user.Username(), password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, user.Username(), password)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedSASLMechanism, name)
+}
+
+func (p *kafkaPublisher) writer(topic string) *kafka.Writer {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	w, ok := p.writers[topic]
+	if !ok {
+		w = kafka.NewWriter(kafka.WriterConfig{
+			Brokers:     p.brokers,
+			Topic:       topic,
+			Dialer:      p.dialer,
+			MaxAttempts: 3,
+			// the events are published one at a time, a batch would only wait for the batch timeout
+			BatchSize:    1,
+			WriteTimeout: kafkaTimeout,
+		})
+		p.writers[topic] = w
+	}
+	return w
+}
+
+func (p *kafkaPublisher) Publish(topic string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+	return p.writer(topic).WriteMessages(ctx, kafka.Message{Value: data})
+}
+
+func (p *kafkaPublisher) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var errs []error
+	for _, w := range p.writers {
+		errs = append(errs, w.Close())
+	}
+	return errors.Join(errs...)
+}
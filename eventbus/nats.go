@@ -0,0 +1,59 @@
+package eventbus
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+const natsTimeout = 5 * time.Second
+
+// natsPublisher publishes with the NATS client, which reconnects in the background and buffers the messages published
+// while disconnected
+type natsPublisher struct {
+	conn *nats.Conn
+}
+
+// newNATSPublisher connects to the comma-separated server URLs. The tls:// scheme and the CA or client certificate of
+// the options enable TLS, the users and tokens of the URLs or the credentials file of the options authenticate.
+func newNATSPublisher(log *logrus.Entry, servers string, opts Opts) (*natsPublisher, error) {
+	log = log.WithField("component", "natsPublisher")
+	options := []nats.Option{
+		nats.Name("mev-boost-relay"),
+		nats.Timeout(natsTimeout),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			log.WithError(err).Warn("disconnected from NATS")
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.WithField("server", conn.ConnectedUrl()).Info("reconnected to NATS")
+		}),
+	}
+	if opts.CAFile != "" {
+		options = append(options, nats.RootCAs(opts.CAFile))
+	}
+	if opts.CertFile != "" {
+		options = append(options, nats.ClientCert(opts.CertFile, opts.KeyFile))
+	}
+	if opts.NATSCredentialsFile != "" {
+		options = append(options, nats.UserCredentials(opts.NATSCredentialsFile))
+	}
+
+	conn, err := nats.Connect(servers, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &natsPublisher{conn: conn}, nil
+}
+
+func (p *natsPublisher) Publish(subject string, data []byte) error {
+	return p.conn.Publish(subject, data)
+}
+
+// Close flushes the buffered messages before closing the connection
+func (p *natsPublisher) Close() error {
+	err := p.conn.FlushTimeout(natsTimeout)
+	p.conn.Close()
+	return err
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Admin API, served on a separate listen address and authenticated with a bearer token
+const (
+	pathAdminBuilders          = "/admin/v1/builders"
+	pathAdminBuilder           = "/admin/v1/builders/{pubkey:0x[a-fA-F0-9]+}"
+	pathAdminBuilderStatus     = "/admin/v1/builders/{pubkey:0x[a-fA-F0-9]+}/status"
+	pathAdminBuilderCollateral = "/admin/v1/builders/{pubkey:0x[a-fA-F0-9]+}/collateral"
+	pathAdminBuilderRefund     = "/admin/v1/builders/{pubkey:0x[a-fA-F0-9]+}/refund-confirmed"
+	pathAdminBids              = "/admin/v1/bids/{slot:[0-9]+}"
+	pathAdminTopBids           = "/admin/v1/bids/{slot:[0-9]+}/top"
+	pathAdminRefreshValidators = "/admin/v1/validators/refresh"
+	pathAdminFeatureFlags      = "/admin/v1/feature-flags"
+	pathAdminFeatureFlag       = "/admin/v1/feature-flags/{name}"
+	pathAdminDataAPIKeys       = "/admin/v1/data-api-keys"
+	pathAdminDataAPIKey        = "/admin/v1/data-api-keys/{name}"
+	pathAdminAuditLog          = "/admin/v1/audit-log"
+)
+
+type AdminBuilderStatusRequest struct {
+	IsHighPrio    bool `json:"high_prio"`
+	IsBlacklisted bool `json:"blacklisted"`
+}
+
+type AdminBuilderCollateralRequest struct {
+	Collateral string `json:"collateral"` // in wei
+}
+
+type AdminFeatureFlagRequest struct {
+	Value string `json:"value"`
+}
+
+func (api *RelayAPI) getAdminRouter() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc(pathAdminBuilders, api.handleAdminGetBuilders).Methods(http.MethodGet)
+	r.HandleFunc(pathAdminBuilder, api.handleAdminGetBuilder).Methods(http.MethodGet)
+	r.HandleFunc(pathAdminBuilderStatus, api.handleAdminSetBuilderStatus).Methods(http.MethodPost, http.MethodPut)
+	r.HandleFunc(pathAdminBuilderCollateral, api.handleAdminSetBuilderCollateral).Methods(http.MethodPost, http.MethodPut)
+	r.HandleFunc(pathAdminBuilderRefund, api.handleAdminConfirmBuilderRefund).Methods(http.MethodPost)
+	r.HandleFunc(pathAdminBids, api.handleAdminGetBids).Methods(http.MethodGet)
+	r.HandleFunc(pathAdminTopBids, api.handleAdminGetTopBids).Methods(http.MethodGet)
+	r.HandleFunc(pathAdminRefreshValidators, api.handleAdminRefreshValidators).Methods(http.MethodPost)
+	r.HandleFunc(pathAdminFeatureFlags, api.handleAdminGetFeatureFlags).Methods(http.MethodGet)
+	r.HandleFunc(pathAdminFeatureFlag, api.handleAdminSetFeatureFlag).Methods(http.MethodPut, http.MethodDelete)
+	r.HandleFunc(pathAdminDataAPIKeys, api.handleAdminGetDataAPIKeys).Methods(http.MethodGet)
+	r.HandleFunc(pathAdminDataAPIKeys, api.handleAdminIssueDataAPIKey).Methods(http.MethodPost)
+	r.HandleFunc(pathAdminDataAPIKey, api.handleAdminRevokeDataAPIKey).Methods(http.MethodDelete)
+	r.HandleFunc(pathAdminAuditLog, api.handleAdminGetAuditLog).Methods(http.MethodGet)
+	return api.checkAdminToken(r)
+}
+
+// startAdminServer serves the admin API until the relay is stopped
+func (api *RelayAPI) startAdminServer() {
+	api.log.Infof("admin API starting on %s ...", api.opts.AdminListenAddr)
+	ln, err := common.Listen(api.opts.AdminListenAddr)
+	if err == nil && api.tlsConfig != nil {
+		err = api.adminSrv.ServeTLS(ln, "", "")
+	} else if err == nil {
+		err = api.adminSrv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		api.log.WithError(err).Error("admin API failed")
+	}
+}
+
+// checkAdminToken rejects requests without the shared admin token or an operator token as bearer token, and passes on
+// the operator of the token
+func (api *RelayAPI) checkAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		operator, ok := api.adminOperatorOfToken(token)
+		if !found || !ok {
+			api.RespondErrorWithCode(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "invalid admin token")
+			return
+		}
+		next.ServeHTTP(w, withAdminOperator(req, operator))
+	})
+}
+
+// adminLogger returns the logger for the audit trail of the admin API
+func (api *RelayAPI) adminLogger(req *http.Request) *logrus.Entry {
+	return api.log.WithFields(logrus.Fields{
+		"admin":      true,
+		"operator":   adminOperator(req),
+		"method":     req.Method,
+		"path":       req.URL.Path,
+		"remoteAddr": req.RemoteAddr,
+	})
+}
+
+func (api *RelayAPI) handleAdminGetBuilders(w http.ResponseWriter, req *http.Request) {
+	builders, err := api.db.GetBlockBuilders()
+	if err != nil {
+		api.log.WithError(err).Error("could not get block builders")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get block builders")
+		return
+	}
+	api.RespondOK(w, builders)
+}
+
+func (api *RelayAPI) handleAdminGetBuilder(w http.ResponseWriter, req *http.Request) {
+	builder, err := api.db.GetBlockBuilderByPubkey(mux.Vars(req)["pubkey"])
+	if errors.Is(err, sql.ErrNoRows) {
+		api.RespondErrorWithCode(w, http.StatusNotFound, ErrorCodeUnknownBuilder, "builder not found")
+		return
+	} else if err != nil {
+		api.log.WithError(err).Error("could not get block builder")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get block builder")
+		return
+	}
+	api.RespondOK(w, builder)
+}
+
+func (api *RelayAPI) handleAdminSetBuilderStatus(w http.ResponseWriter, req *http.Request) {
+	builderPubkey := mux.Vars(req)["pubkey"]
+	payload := new(AdminBuilderStatusRequest)
+	if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	api.adminLogger(req).WithFields(logrus.Fields{
+		"builderPubkey": builderPubkey,
+		"isHighPrio":    payload.IsHighPrio,
+		"isBlacklisted": payload.IsBlacklisted,
+	}).Info("admin: setting builder status")
+
+	oldStatus, _ := api.builderAuditValues(builderPubkey)
+	newStatus, err := api.setBuilderStatus(builderPubkey, payload.IsHighPrio, payload.IsBlacklisted)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not set builder status")
+		return
+	}
+	api.auditAdminChange(req, database.AdminAuditActionBuilderStatus, builderPubkey, oldStatus, builderStatusAuditValue(newStatus))
+	api.RespondOK(w, struct {
+		Status string `json:"status"`
+	}{
+		Status: string(newStatus),
+	})
+}
+
+func (api *RelayAPI) handleAdminSetBuilderCollateral(w http.ResponseWriter, req *http.Request) {
+	builderPubkey := mux.Vars(req)["pubkey"]
+	payload := new(AdminBuilderCollateralRequest)
+	if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	api.adminLogger(req).WithFields(logrus.Fields{
+		"builderPubkey": builderPubkey,
+		"collateral":    payload.Collateral,
+	}).Info("admin: setting builder collateral")
+	api.updateBuilderCollateral(w, req, builderPubkey, payload.Collateral)
+}
+
+// handleAdminConfirmBuilderRefund records that the builder paid the refunds owed for its demotions, which lets the
+// housekeeper promote it again if its promotion policy allows
+func (api *RelayAPI) handleAdminConfirmBuilderRefund(w http.ResponseWriter, req *http.Request) {
+	builderPubkey := strings.ToLower(mux.Vars(req)["pubkey"])
+	log := api.adminLogger(req).WithField("builderPubkey", builderPubkey)
+
+	numDemotions, err := api.db.SetBuilderDemotionRefundConfirmed(builderPubkey)
+	if err != nil {
+		log.WithError(err).Error("could not confirm builder refund")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not confirm builder refund")
+		return
+	}
+	log.WithField("numDemotions", numDemotions).Info("admin: confirmed builder refund")
+	api.auditAdminChange(req, database.AdminAuditActionBuilderRefundConfirmed, builderPubkey, "", fmt.Sprintf("%d demotions", numDemotions))
+	api.RespondOK(w, struct {
+		NumDemotions int64 `json:"num_demotions"`
+	}{
+		NumDemotions: numDemotions,
+	})
+}
+
+// handleAdminGetBids returns the latest bid of every builder in the slot, highest first
+func (api *RelayAPI) handleAdminGetBids(w http.ResponseWriter, req *http.Request) {
+	slot, err := strconv.ParseUint(mux.Vars(req)["slot"], 10, 64)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid slot")
+		return
+	}
+
+	bids, err := api.redis.GetBuilderBidValues(slot)
+	if err != nil {
+		api.log.WithError(err).Error("could not get bids")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not get bids")
+		return
+	}
+	api.RespondOK(w, bids)
+}
+
+// handleAdminGetTopBids returns the current top bid of the slot, for every parent hash and proposer with bids
+func (api *RelayAPI) handleAdminGetTopBids(w http.ResponseWriter, req *http.Request) {
+	slot, err := strconv.ParseUint(mux.Vars(req)["slot"], 10, 64)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid slot")
+		return
+	}
+
+	topBids, err := api.redis.GetTopBids(slot)
+	if err != nil {
+		api.log.WithError(err).Error("could not get top bids")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not get top bids")
+		return
+	}
+	api.RespondOK(w, topBids)
+}
+
+// handleAdminRefreshValidators reloads the known validators right away, instead of waiting for the next refresh
+func (api *RelayAPI) handleAdminRefreshValidators(w http.ResponseWriter, req *http.Request) {
+	api.adminLogger(req).Info("admin: refreshing known validators")
+	cnt, err := api.datastore.RefreshKnownValidators()
+	if err != nil {
+		api.log.WithError(err).Error("error getting known validators")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not refresh known validators")
+		return
+	}
+	api.RespondOK(w, struct {
+		NumValidators int `json:"num_validators"`
+	}{
+		NumValidators: cnt,
+	})
+}
+
+func (api *RelayAPI) handleAdminGetFeatureFlags(w http.ResponseWriter, req *http.Request) {
+	api.RespondOK(w, api.getFeatureFlags())
+}
+
+// handleAdminSetFeatureFlag sets a feature flag on all instances (PUT), or resets it to the defaults of the instances
+// (DELETE)
+func (api *RelayAPI) handleAdminSetFeatureFlag(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	payload := new(AdminFeatureFlagRequest)
+	if req.Method == http.MethodPut {
+		if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+			return
+		} else if payload.Value == "" {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing value")
+			return
+		}
+	}
+
+	// apply it right away, which also validates the value, the other instances get it through redis
+	oldValue := api.getFeatureFlags()[name]
+	err := api.setFeatureFlag(name, payload.Value)
+	if errors.Is(err, ErrUnknownFeatureFlag) {
+		api.RespondErrorWithCode(w, http.StatusNotFound, ErrorCodeUnknownFeatureFlag, err.Error())
+		return
+	} else if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	api.adminLogger(req).WithField("value", payload.Value).Warnf("admin: setting feature flag %s", name)
+	if err := api.redis.SetFeatureFlag(name, payload.Value); err != nil {
+		api.log.WithError(err).Error("could not set feature flag in redis")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not set feature flag")
+		return
+	}
+	flags := api.getFeatureFlags()
+	api.auditAdminChange(req, database.AdminAuditActionFeatureFlag, name, oldValue, flags[name])
+	api.RespondOK(w, flags)
+}
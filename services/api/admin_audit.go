@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/datastore"
+)
+
+var ErrInvalidAdminOperatorToken = errors.New("invalid admin operator token")
+
+const (
+	// adminOperatorShared is the operator recorded for changes made with the shared admin token
+	adminOperatorShared = "admin"
+
+	// adminOperatorInternalAPI is the operator recorded for changes made through the unauthenticated internal API
+	adminOperatorInternalAPI = "internal-api"
+
+	defaultAdminAuditLogLimit = 100
+	maxAdminAuditLogLimit     = 1000
+)
+
+type adminOperatorKey struct{}
+
+// adminOperatorToken is the bearer token of a named operator of the admin API
+type adminOperatorToken struct {
+	operator string
+	token    []byte
+}
+
+// parseAdminOperatorTokens parses the operator tokens in the name:token form of ADMIN_API_OPERATOR_TOKENS
+func parseAdminOperatorTokens(entries []string) ([]adminOperatorToken, error) {
+	tokens := make([]adminOperatorToken, 0, len(entries))
+	operators := make(map[string]bool)
+	for _, entry := range entries {
+		operator, token, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || operator == "" || token == "" {
+			return nil, fmt.Errorf("%w: expected name:token", ErrInvalidAdminOperatorToken)
+		} else if operators[operator] || operator == adminOperatorShared || operator == adminOperatorInternalAPI {
+			return nil, fmt.Errorf("%w: duplicate or reserved operator %s", ErrInvalidAdminOperatorToken, operator)
+		}
+		operators[operator] = true
+		tokens = append(tokens, adminOperatorToken{operator: operator, token: []byte(token)})
+	}
+	return tokens, nil
+}
+
+// adminOperatorOfToken returns the operator the bearer token belongs to, or false if it's no admin token. All tokens
+// are compared, so the time taken doesn't tell which one matched.
+func (api *RelayAPI) adminOperatorOfToken(token string) (operator string, ok bool) {
+	if api.opts.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(api.opts.AdminToken)) == 1 {
+		operator, ok = adminOperatorShared, true
+	}
+	for _, operatorToken := range api.adminOperatorTokens {
+		if subtle.ConstantTimeCompare([]byte(token), operatorToken.token) == 1 {
+			operator, ok = operatorToken.operator, true
+		}
+	}
+	return operator, ok
+}
+
+// adminOperator returns the operator who made the request, the internal API for requests without admin token
+func adminOperator(req *http.Request) string {
+	if operator, ok := req.Context().Value(adminOperatorKey{}).(string); ok {
+		return operator
+	}
+	return adminOperatorInternalAPI
+}
+
+func withAdminOperator(req *http.Request, operator string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), adminOperatorKey{}, operator))
+}
+
+// auditAdminChange records a change of the relay configuration in the admin audit log. The change is already applied
+// at this point, so a failure to record it is only logged.
+func (api *RelayAPI) auditAdminChange(req *http.Request, action, target, oldValue, newValue string) {
+	entry := database.AdminAuditLogEntry{ //nolint:exhaustruct
+		Operator:   adminOperator(req),
+		RemoteAddr: req.RemoteAddr,
+		Action:     action,
+		Target:     target,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+	}
+	if err := api.db.InsertAdminAuditLog(entry); err != nil {
+		api.adminLogger(req).WithError(err).WithField("action", action).Error("could not record the change in the admin audit log")
+	}
+}
+
+// builderAuditValues returns the status and collateral of the builder to record as the old values of a change, empty
+// if the builder is unknown
+func (api *RelayAPI) builderAuditValues(builderPubkey string) (status, collateral string) {
+	builder, err := api.db.GetBlockBuilderByPubkey(builderPubkey)
+	if err != nil || builder == nil {
+		return "", ""
+	}
+	return builderStatusAuditValue(datastore.MakeBlockBuilderStatus(builder.IsHighPrio, builder.IsBlacklisted)), builder.Collateral
+}
+
+// builderStatusAuditValue names the low-prio status, which is empty in redis
+func builderStatusAuditValue(status datastore.BlockBuilderStatus) string {
+	if status == datastore.RedisBlockBuilderStatusLowPrio {
+		return "low-prio"
+	}
+	return string(status)
+}
+
+// handleAdminGetAuditLog returns the latest changes of the relay configuration, newest first, optionally only those of
+// a target like a builder pubkey or feature flag
+func (api *RelayAPI) handleAdminGetAuditLog(w http.ResponseWriter, req *http.Request) {
+	args := req.URL.Query()
+	limit := uint64(defaultAdminAuditLogLimit)
+	if args.Get("limit") != "" {
+		var err error
+		limit, err = strconv.ParseUint(args.Get("limit"), 10, 64)
+		if err != nil || limit == 0 || limit > maxAdminAuditLogLimit {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, fmt.Sprintf("limit must be between 1 and %d", maxAdminAuditLogLimit))
+			return
+		}
+	}
+
+	entries, err := api.db.GetAdminAuditLog(args.Get("target"), limit)
+	if err != nil {
+		api.log.WithError(err).Error("could not get admin audit log")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get admin audit log")
+		return
+	}
+	if entries == nil {
+		entries = []*database.AdminAuditLogEntry{}
+	}
+	api.RespondOK(w, entries)
+}
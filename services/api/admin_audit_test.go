@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+// auditDB records the admin audit log in memory
+type auditDB struct {
+	database.MockDB
+	builders map[string]*database.BlockBuilderEntry
+	entries  []database.AdminAuditLogEntry
+}
+
+func (db *auditDB) GetBlockBuilderByPubkey(pubkey string) (*database.BlockBuilderEntry, error) {
+	return db.builders[pubkey], nil
+}
+
+func (db *auditDB) InsertAdminAuditLog(entry database.AdminAuditLogEntry) error {
+	db.entries = append(db.entries, entry)
+	return nil
+}
+
+func TestParseAdminOperatorTokens(t *testing.T) {
+	tokens, err := parseAdminOperatorTokens([]string{"alice:secret-a", " bob:secret:b"})
+	require.NoError(t, err)
+	require.Equal(t, []adminOperatorToken{{operator: "alice", token: []byte("secret-a")}, {operator: "bob", token: []byte("secret:b")}}, tokens)
+
+	for _, entries := range [][]string{{"alice"}, {"alice:"}, {":secret"}, {"alice:a", "alice:b"}, {"admin:secret"}} {
+		_, err = parseAdminOperatorTokens(entries)
+		require.ErrorIs(t, err, ErrInvalidAdminOperatorToken, entries)
+	}
+}
+
+func TestAdminAuditLog(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	db := &auditDB{builders: map[string]*database.BlockBuilderEntry{"0xb1": {Collateral: "0"}}}
+	backend.relay.db = db
+	backend.relay.opts.AdminToken = ""
+	backend.relay.adminOperatorTokens = []adminOperatorToken{{operator: "alice", token: []byte("secret-a")}}
+	router := backend.relay.getAdminRouter()
+
+	request := func(method, path, token string, payload any) *httptest.ResponseRecorder {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// an empty shared token never matches
+	rr := request(http.MethodGet, pathAdminFeatureFlags, "", nil)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Empty(t, db.entries)
+
+	rr = request(http.MethodPost, "/admin/v1/builders/0xb1/status", "secret-a", AdminBuilderStatusRequest{IsBlacklisted: true})
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = request(http.MethodPost, "/admin/v1/builders/0xb1/collateral", "secret-a", AdminBuilderCollateralRequest{Collateral: "1000"})
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = request(http.MethodPut, "/admin/v1/feature-flags/min-bid", "secret-a", AdminFeatureFlagRequest{Value: "1000"})
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// rejected changes aren't recorded
+	rr = request(http.MethodPut, "/admin/v1/feature-flags/min-bid", "secret-a", AdminFeatureFlagRequest{Value: "-1"})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	require.Len(t, db.entries, 3)
+	for _, entry := range db.entries {
+		require.Equal(t, "alice", entry.Operator)
+	}
+	require.Equal(t, database.AdminAuditLogEntry{Operator: "alice", Action: database.AdminAuditActionBuilderStatus, Target: "0xb1", OldValue: "low-prio", NewValue: "blacklisted"}, db.entries[0])
+	require.Equal(t, [2]string{"0", "1000"}, [2]string{db.entries[1].OldValue, db.entries[1].NewValue})
+	require.Equal(t, database.AdminAuditActionFeatureFlag, db.entries[2].Action)
+	require.Equal(t, [2]string{"0", "1000"}, [2]string{db.entries[2].OldValue, db.entries[2].NewValue})
+
+	// changes through the internal API are recorded as such
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/internal/v1/builder/collateral/0xb1?collateral=0", nil), map[string]string{"pubkey": "0xb1"})
+	backend.relay.handleInternalBuilderCollateral(httptest.NewRecorder(), req)
+	require.Len(t, db.entries, 4)
+	require.Equal(t, adminOperatorInternalAPI, db.entries[3].Operator)
+
+	rr = request(http.MethodGet, pathAdminAuditLog+"?limit=0", "secret-a", nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = request(http.MethodGet, pathAdminAuditLog+"?target=0xb1", "secret-a", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, "[]", rr.Body.String())
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAPI(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.opts.AdminToken = "secret"
+	router := backend.relay.getAdminRouter()
+
+	request := func(method, path, token string, payload any) *httptest.ResponseRecorder {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("requires the token", func(t *testing.T) {
+		rr := request(http.MethodGet, pathAdminFeatureFlags, "wrong", nil)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("sets the builder status", func(t *testing.T) {
+		rr := request(http.MethodPost, "/admin/v1/builders/0xb1/status", "secret", AdminBuilderStatusRequest{IsHighPrio: true})
+		require.Equal(t, http.StatusOK, rr.Code)
+		statuses, err := backend.redis.GetBlockBuilderStatuses()
+		require.NoError(t, err)
+		require.Equal(t, datastore.RedisBlockBuilderStatusHighPrio, statuses["0xb1"])
+	})
+
+	t.Run("confirms the refunds of a builder", func(t *testing.T) {
+		rr := request(http.MethodPost, "/admin/v1/builders/0xb1/refund-confirmed", "secret", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"num_demotions":0}`, rr.Body.String())
+	})
+
+	t.Run("returns the top bids of a slot", func(t *testing.T) {
+		rr := request(http.MethodGet, "/admin/v1/bids/1/top", "secret", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, "[]", rr.Body.String())
+	})
+
+	t.Run("issues and revokes data api keys", func(t *testing.T) {
+		rr := request(http.MethodPost, pathAdminDataAPIKeys, "secret", AdminDataAPIKeyRequest{Name: "partner-a", Tier: DataAPIKeyTierPartner})
+		require.Equal(t, http.StatusOK, rr.Code)
+		resp := struct {
+			Name   string `json:"name"`
+			Tier   string `json:"tier"`
+			APIKey string `json:"api_key"`
+		}{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, "partner-a", resp.Name)
+		require.Equal(t, DataAPIKeyTierPartner, resp.Tier)
+		require.Len(t, resp.APIKey, 64)
+
+		rr = request(http.MethodPost, pathAdminDataAPIKeys, "secret", AdminDataAPIKeyRequest{Name: "partner-a", Tier: "gold"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = request(http.MethodGet, pathAdminDataAPIKeys, "secret", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, "[]", rr.Body.String())
+		rr = request(http.MethodDelete, "/admin/v1/data-api-keys/partner-a", "secret", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("sets and resets feature flags", func(t *testing.T) {
+		rr := request(http.MethodPut, "/admin/v1/feature-flags/min-bid", "secret", AdminFeatureFlagRequest{Value: "1000"})
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "1000", backend.relay.ffMinBidValue.Load().String())
+		require.True(t, backend.relay.isBelowMinBid(big.NewInt(999)))
+		require.False(t, backend.relay.isBelowMinBid(big.NewInt(1000)))
+		overrides, err := backend.redis.GetFeatureFlags()
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{FeatureFlagMinBid: "1000"}, overrides)
+
+		rr = request(http.MethodPut, "/admin/v1/feature-flags/min-bid", "secret", AdminFeatureFlagRequest{Value: "-1"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = request(http.MethodPut, "/admin/v1/feature-flags/unknown", "secret", AdminFeatureFlagRequest{Value: "true"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = request(http.MethodDelete, "/admin/v1/feature-flags/min-bid", "secret", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Nil(t, backend.relay.ffMinBidValue.Load())
+		require.False(t, backend.relay.isBelowMinBid(big.NewInt(0)))
+		overrides, err = backend.redis.GetFeatureFlags()
+		require.NoError(t, err)
+		require.Empty(t, overrides)
+	})
+}
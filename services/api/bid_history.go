@@ -0,0 +1,189 @@
+package api
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// maximum number of top bid changes kept per slot, the oldest ones are dropped first
+	bidHistorySize = cli.GetEnvInt("BID_HISTORY_SIZE", 1000)
+
+	// whether the top bid changes are also kept in redis, which merges the changes seen by all instances and keeps them
+	// across restarts
+	bidHistoryRedis = os.Getenv("BID_HISTORY_REDIS") == "1"
+)
+
+// bidRing is a ring buffer of the top bid changes of a slot
+type bidRing struct {
+	entries []*database.BidHistoryEntry
+	next    int // where the next entry goes once the buffer is full
+}
+
+func (r *bidRing) push(entry *database.BidHistoryEntry, size int) {
+	if len(r.entries) < size {
+		r.entries = append(r.entries, entry)
+		return
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % size
+}
+
+// last returns the latest entry, or nil if the buffer is empty
+func (r *bidRing) last() *database.BidHistoryEntry {
+	if len(r.entries) == 0 {
+		return nil
+	}
+	return r.entries[(r.next+len(r.entries)-1)%len(r.entries)]
+}
+
+// ordered returns the entries from the oldest to the latest
+func (r *bidRing) ordered() []*database.BidHistoryEntry {
+	return append(append([]*database.BidHistoryEntry{}, r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// bidHistory records every change of the top bid per slot, until the changes are saved to the database for the post
+// mortem of a slot
+type bidHistory struct {
+	size int
+
+	lock  sync.Mutex
+	slots map[uint64]*bidRing
+}
+
+func newBidHistory(size int) *bidHistory {
+	return &bidHistory{
+		size:  size,
+		slots: make(map[uint64]*bidRing),
+	}
+}
+
+// record appends the top bid to the history of its slot, and returns false if the top bid didn't change
+func (h *bidHistory) record(entry *database.BidHistoryEntry) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	ring, found := h.slots[entry.Slot]
+	if !found {
+		ring = &bidRing{}
+		h.slots[entry.Slot] = ring
+	}
+	if last := ring.last(); last != nil && last.BuilderPubkey == entry.BuilderPubkey && last.Value == entry.Value && last.ParentHash == entry.ParentHash && last.ProposerPubkey == entry.ProposerPubkey {
+		return false
+	}
+	ring.push(entry, h.size)
+	return true
+}
+
+// take returns the top bid changes of the slot in order, and removes them
+func (h *bidHistory) take(slot uint64) []*database.BidHistoryEntry {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	ring, found := h.slots[slot]
+	if !found {
+		return nil
+	}
+	delete(h.slots, slot)
+	return ring.ordered()
+}
+
+// slotsUntil returns the slots with recorded changes up to and including the slot
+func (h *bidHistory) slotsUntil(slot uint64) []uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	slots := []uint64{}
+	for s := range h.slots {
+		if s <= slot {
+			slots = append(slots, s)
+		}
+	}
+	return slots
+}
+
+// recordTopBid records the top bid after a submission, if it changed
+func (api *RelayAPI) recordTopBid(log *logrus.Entry, topBid *datastore.TopBidUpdate) {
+	if api.bidHistory == nil {
+		return
+	}
+
+	entry := &database.BidHistoryEntry{ //nolint:exhaustruct
+		ChangedAt:      time.Now().UTC(),
+		Slot:           topBid.Slot,
+		ParentHash:     topBid.ParentHash,
+		ProposerPubkey: topBid.ProposerPubkey,
+		BuilderPubkey:  topBid.BuilderPubkey,
+		Value:          topBid.Value,
+	}
+	if !api.bidHistory.record(entry) || !bidHistoryRedis {
+		return
+	}
+	api.runInBackground(func() {
+		if err := api.redis.AppendBidHistory(entry, api.bidHistory.size); err != nil {
+			log.WithError(err).Error("failed to save top bid change to redis")
+		}
+	})
+}
+
+// saveBidHistory saves the top bid changes of the slot to the database. With the history in redis, the changes seen by
+// all instances are saved by the first instance taking them.
+func (api *RelayAPI) saveBidHistory(log *logrus.Entry, slot uint64) {
+	entries := api.bidHistory.take(slot)
+	if bidHistoryRedis {
+		redisEntries, err := api.redis.TakeBidHistory(slot)
+		if err != nil {
+			log.WithError(err).Error("failed to get bid history from redis, saving the top bid changes of this instance")
+		} else {
+			entries = redisEntries
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ChangedAt.Before(entries[j].ChangedAt)
+	})
+	if err := api.db.SaveBidHistory(entries); err != nil {
+		log.WithError(err).Error("failed to save bid history to database")
+		return
+	}
+	log.WithField("numTopBidChanges", len(entries)).Debug("saved bid history")
+}
+
+// registerBidHistoryHooks saves the bid history of a slot once its payload is delivered, and when the slot passed
+func (api *RelayAPI) registerBidHistoryHooks() {
+	api.slots.on(slotEventPayloadDelivered, func(event *slotEvent) {
+		api.runInBackground(func() { api.saveBidHistory(event.log, event.slot) })
+	})
+
+	api.slots.on(slotEventStarted, func(event *slotEvent) {
+		headSlot := event.headSlot()
+		slots := api.bidHistory.slotsUntil(headSlot)
+		if bidHistoryRedis && !containsSlot(slots, headSlot) {
+			slots = append(slots, headSlot)
+		}
+		api.runInBackground(func() {
+			for _, slot := range slots {
+				api.saveBidHistory(api.log.WithField("slot", slot), slot)
+			}
+		})
+	})
+}
+
+func containsSlot(slots []uint64, slot uint64) bool {
+	for _, s := range slots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
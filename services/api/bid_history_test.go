@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidHistory(t *testing.T) {
+	h := newBidHistory(3)
+	newEntry := func(slot uint64, builderPubkey, value string) *database.BidHistoryEntry {
+		return &database.BidHistoryEntry{Slot: slot, ParentHash: "0xparent", ProposerPubkey: "0xproposer", BuilderPubkey: builderPubkey, Value: value} //nolint:exhaustruct
+	}
+
+	require.True(t, h.record(newEntry(10, "0xa", "1")))
+	require.False(t, h.record(newEntry(10, "0xa", "1")), "unchanged top bid")
+	require.True(t, h.record(newEntry(10, "0xb", "2")))
+	require.True(t, h.record(newEntry(10, "0xa", "3")))
+	require.True(t, h.record(newEntry(10, "0xb", "4")))
+	require.False(t, h.record(newEntry(10, "0xb", "4")), "unchanged top bid after wrapping around")
+	require.True(t, h.record(newEntry(11, "0xa", "1")))
+	require.Equal(t, []uint64{10}, h.slotsUntil(10))
+
+	// the oldest change is dropped
+	values := []string{}
+	for _, entry := range h.take(10) {
+		values = append(values, entry.Value)
+	}
+	require.Equal(t, []string{"2", "3", "4"}, values)
+	require.Nil(t, h.take(10))
+	require.Len(t, h.take(11), 1)
+}
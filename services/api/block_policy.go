@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+var ErrInvalidBlockPolicy = errors.New("invalid block policy")
+
+// Error codes of the submissions rejected by the block policy
+const (
+	SubmissionErrGasLimitDeviation   = "gas_limit_deviation"
+	SubmissionErrPayloadTooLarge     = "payload_too_large"
+	SubmissionErrTooManyTransactions = "too_many_transactions"
+)
+
+// blockPolicyLimits are the caps on the blocks of a builder, 0 for no cap
+type blockPolicyLimits struct {
+	// maximum difference between the gas limit of the block and the one registered by the proposer
+	MaxGasLimitDeviation uint64 `json:"max_gas_limit_deviation"`
+
+	// maximum total size of the transactions of the block
+	MaxPayloadBytes uint64 `json:"max_payload_bytes"`
+
+	// maximum number of transactions of the block
+	MaxTxCount uint64 `json:"max_tx_count"`
+}
+
+func (l blockPolicyLimits) isEmpty() bool {
+	return l.MaxGasLimitDeviation == 0 && l.MaxPayloadBytes == 0 && l.MaxTxCount == 0
+}
+
+// builderBlockPolicyLimits are the overrides of the global limits for a builder, the limits not set are inherited
+type builderBlockPolicyLimits struct {
+	MaxGasLimitDeviation *uint64 `json:"max_gas_limit_deviation"`
+	MaxPayloadBytes      *uint64 `json:"max_payload_bytes"`
+	MaxTxCount           *uint64 `json:"max_tx_count"`
+}
+
+// blockPolicy caps the blocks before they are simulated, so that pathological blocks are rejected without taking up a
+// simulation slot. The global limits apply to all builders, unless overridden for a builder.
+type blockPolicy struct {
+	global   blockPolicyLimits
+	builders map[string]blockPolicyLimits // by lowercase builder pubkey
+}
+
+// parseBlockPolicy parses the global limits of the BLOCK_POLICY_MAX_* settings, which are disabled if empty, and the
+// per-builder overrides of the JSON file, e.g. {"0xabc...": {"max_tx_count": 500}}
+func parseBlockPolicy(maxGasLimitDeviation, maxPayloadBytes, maxTxCount, buildersFile string) (policy blockPolicy, err error) {
+	for _, setting := range []struct {
+		name  string
+		value string
+		limit *uint64
+	}{
+		{"max gas limit deviation", maxGasLimitDeviation, &policy.global.MaxGasLimitDeviation},
+		{"max payload bytes", maxPayloadBytes, &policy.global.MaxPayloadBytes},
+		{"max tx count", maxTxCount, &policy.global.MaxTxCount},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		*setting.limit, err = strconv.ParseUint(strings.TrimSpace(setting.value), 10, 64)
+		if err != nil {
+			return policy, fmt.Errorf("%w: %s %s", ErrInvalidBlockPolicy, setting.name, setting.value)
+		}
+	}
+
+	if buildersFile == "" {
+		return policy, nil
+	}
+	data, err := os.ReadFile(buildersFile)
+	if err != nil {
+		return policy, err
+	}
+	overrides := make(map[string]builderBlockPolicyLimits)
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return policy, fmt.Errorf("%w: %s: %s", ErrInvalidBlockPolicy, buildersFile, err.Error())
+	}
+	policy.builders = make(map[string]blockPolicyLimits, len(overrides))
+	for pubkey, override := range overrides {
+		if _, err := boostTypes.HexToPubkey(pubkey); err != nil {
+			return policy, fmt.Errorf("%w: invalid builder pubkey %s", ErrInvalidBlockPolicy, pubkey)
+		}
+		limits := policy.global
+		if override.MaxGasLimitDeviation != nil {
+			limits.MaxGasLimitDeviation = *override.MaxGasLimitDeviation
+		}
+		if override.MaxPayloadBytes != nil {
+			limits.MaxPayloadBytes = *override.MaxPayloadBytes
+		}
+		if override.MaxTxCount != nil {
+			limits.MaxTxCount = *override.MaxTxCount
+		}
+		policy.builders[strings.ToLower(pubkey)] = limits
+	}
+	return policy, nil
+}
+
+// isEnabled returns whether any block of any builder is capped
+func (p blockPolicy) isEnabled() bool {
+	return !p.global.isEmpty() || len(p.builders) > 0
+}
+
+// limitsOf returns the limits of the builder
+func (p blockPolicy) limitsOf(builderPubkey string) blockPolicyLimits {
+	if limits, found := p.builders[strings.ToLower(builderPubkey)]; found {
+		return limits
+	}
+	return p.global
+}
+
+// String summarizes the policy for the startup log
+func (p blockPolicy) String() string {
+	return fmt.Sprintf("max_gas_limit_deviation=%d,max_payload_bytes=%d,max_tx_count=%d,builder_overrides=%d",
+		p.global.MaxGasLimitDeviation, p.global.MaxPayloadBytes, p.global.MaxTxCount, len(p.builders))
+}
+
+// check returns the rejection of a block exceeding the limits of its builder, or nil. The registered gas limit is the
+// one of the proposer of the slot.
+func (p blockPolicy) check(payload *common.BuilderSubmitBlockRequest, registeredGasLimit uint64) *submissionError {
+	limits := p.limitsOf(payload.BuilderPubkey().String())
+
+	if limits.MaxTxCount > 0 && uint64(payload.NumTx()) > limits.MaxTxCount {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrTooManyTransactions, "block has %d transactions, the limit is %d", payload.NumTx(), limits.MaxTxCount)
+	}
+
+	if limits.MaxPayloadBytes > 0 {
+		payloadBytes := uint64(0)
+		for _, tx := range payload.Transactions() {
+			payloadBytes += uint64(len(tx))
+		}
+		if payloadBytes > limits.MaxPayloadBytes {
+			return newSubmissionError(http.StatusBadRequest, SubmissionErrPayloadTooLarge, "block transactions take %d bytes, the limit is %d", payloadBytes, limits.MaxPayloadBytes)
+		}
+	}
+
+	if limits.MaxGasLimitDeviation > 0 {
+		gasLimit := payload.GasLimit()
+		deviation := gasLimit - registeredGasLimit
+		if registeredGasLimit > gasLimit {
+			deviation = registeredGasLimit - gasLimit
+		}
+		if deviation > limits.MaxGasLimitDeviation {
+			return newSubmissionError(http.StatusBadRequest, SubmissionErrGasLimitDeviation, "block gas limit %d deviates from the registered gas limit %d by more than %d", gasLimit, registeredGasLimit, limits.MaxGasLimitDeviation)
+		}
+	}
+	return nil
+}
+
+// blockPolicyFilter rejects the blocks exceeding the block policy of their builder, if one is configured. The rejected
+// blocks aren't saved, they can be large, only counted by error code.
+type blockPolicyFilter struct {
+	NoopSubmissionFilter
+	api *RelayAPI
+}
+
+func (f *blockPolicyFilter) Name() string {
+	return "block-policy"
+}
+
+func (f *blockPolicyFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	if !f.api.blockPolicy.isEnabled() || s.SlotDuty == nil {
+		return nil
+	}
+	if rejection := f.api.blockPolicy.check(s.Payload, s.SlotDuty.GasLimit); rejection != nil {
+		common.BlockPolicyViolationsTotal.WithLabelValues(f.api.networkName(), rejection.code).Inc()
+		return rejection
+	}
+	return nil
+}
+
+var _ SubmissionFilter = (*blockPolicyFilter)(nil)
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlockPolicy(t *testing.T) {
+	policy, err := parseBlockPolicy("", "", "", "")
+	require.NoError(t, err)
+	require.False(t, policy.isEnabled())
+
+	_, err = parseBlockPolicy("-1", "", "", "")
+	require.ErrorIs(t, err, ErrInvalidBlockPolicy)
+
+	buildersFile := filepath.Join(t.TempDir(), "builders.json")
+	builderPubkey := "0xA1885d66bef164889a2e35845c3b626545d7b0e513efe335e97c3a45e534013fa3bc38c3b7e6143695aecc4872ac52c4"
+	require.NoError(t, os.WriteFile(buildersFile, []byte(`{"`+builderPubkey+`": {"max_tx_count": 0, "max_payload_bytes": 1000}}`), 0o600))
+	policy, err = parseBlockPolicy("1000000", "", "500", buildersFile)
+	require.NoError(t, err)
+	require.True(t, policy.isEnabled())
+	require.Equal(t, blockPolicyLimits{MaxGasLimitDeviation: 1000000, MaxTxCount: 500}, policy.limitsOf("0xb2"))
+
+	// the overrides inherit the limits they don't set
+	require.Equal(t, blockPolicyLimits{MaxGasLimitDeviation: 1000000, MaxPayloadBytes: 1000}, policy.limitsOf(builderPubkey))
+
+	require.NoError(t, os.WriteFile(buildersFile, []byte(`{"0xb2": {}}`), 0o600))
+	_, err = parseBlockPolicy("", "", "", buildersFile)
+	require.ErrorIs(t, err, ErrInvalidBlockPolicy)
+}
+
+func TestBlockPolicyFilter(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	submission := testCapellaSubmission(1)
+	submission.ExecutionPayload.GasLimit = 30_000_000
+	submission.ExecutionPayload.Transactions = []bellatrix.Transaction{make([]byte, 100), make([]byte, 100)}
+	s := &FilteredSubmission{
+		Log:      common.TestLog,
+		Payload:  &common.BuilderSubmitBlockRequest{Capella: submission},
+		SlotDuty: &boostTypes.RegisterValidatorRequestMessage{GasLimit: 30_000_000},
+	}
+	filter := &blockPolicyFilter{api: backend.relay}
+
+	cases := []struct {
+		limits blockPolicyLimits
+		code   string
+	}{
+		{blockPolicyLimits{}, ""},
+		{blockPolicyLimits{MaxTxCount: 2, MaxPayloadBytes: 200, MaxGasLimitDeviation: 1}, ""},
+		{blockPolicyLimits{MaxTxCount: 1}, SubmissionErrTooManyTransactions},
+		{blockPolicyLimits{MaxPayloadBytes: 199}, SubmissionErrPayloadTooLarge},
+	}
+	for _, c := range cases {
+		backend.relay.blockPolicy = blockPolicy{global: c.limits}
+		err := filter.BeforeSimulation(context.Background(), s)
+		if c.code == "" {
+			require.NoError(t, err)
+			continue
+		}
+		var rejection *submissionError
+		require.ErrorAs(t, err, &rejection)
+		require.Equal(t, c.code, rejection.code)
+	}
+
+	// the gas limit deviates in both directions
+	backend.relay.blockPolicy = blockPolicy{global: blockPolicyLimits{MaxGasLimitDeviation: 1_000_000}}
+	for _, registeredGasLimit := range []uint64{28_000_000, 32_000_000} {
+		s.SlotDuty.GasLimit = registeredGasLimit
+		var rejection *submissionError
+		require.ErrorAs(t, filter.BeforeSimulation(context.Background(), s), &rejection)
+		require.Equal(t, SubmissionErrGasLimitDeviation, rejection.code)
+	}
+	s.SlotDuty.GasLimit = 29_500_000
+	require.NoError(t, filter.BeforeSimulation(context.Background(), s))
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrBlocklistUnavailable = errors.New("blocklist is not loaded")
+
+	blocklistRefreshInterval = time.Duration(cli.GetEnvInt("BLOCKLIST_REFRESH_INTERVAL_SEC", 60)) * time.Second
+)
+
+// Where a blocklisted address was found in a submission, recorded with the rejection in the database
+const (
+	blocklistReasonProposerFeeRecipient = "proposer_fee_recipient"
+	blocklistReasonBlockFeeRecipient    = "block_fee_recipient"
+	blocklistReasonTxSender             = "tx_sender"
+	blocklistReasonTxRecipient          = "tx_recipient"
+)
+
+type blocklistMatch struct {
+	address string
+	reason  string
+}
+
+// startBlocklistUpdates periodically reloads the blocklist the housekeeper saved to redis
+func (api *RelayAPI) startBlocklistUpdates() {
+	ticker := time.NewTicker(blocklistRefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		api.updateBlocklist()
+	}
+}
+
+// updateBlocklist keeps the previous blocklist if it can't be loaded from redis
+func (api *RelayAPI) updateBlocklist() {
+	blocklist, err := api.redis.GetBlocklist()
+	if err != nil {
+		api.log.WithError(err).Error("failed to get blocklist from redis")
+		return
+	} else if blocklist == nil {
+		api.log.Warn("no blocklist in redis, submissions are rejected until the housekeeper loads one (--blocklist-source)")
+		return
+	}
+
+	api.blocklistLock.Lock()
+	api.blocklist = blocklist
+	api.blocklistLock.Unlock()
+	api.log.WithField("numAddresses", len(blocklist)).Debug("updated blocklist")
+}
+
+// checkBlocklist returns the first blocklisted address involved in the submission, or nil if there is none
+func (api *RelayAPI) checkBlocklist(payload *common.BuilderSubmitBlockRequest) (*blocklistMatch, error) {
+	api.blocklistLock.RLock()
+	blocklist := api.blocklist
+	api.blocklistLock.RUnlock()
+	if blocklist == nil {
+		return nil, ErrBlocklistUnavailable
+	}
+	return matchBlocklist(blocklist, payload.ProposerFeeRecipient(), payload.ExecutionPayloadFeeRecipient(), payload.Transactions())
+}
+
+// matchBlocklist checks the fee recipients, and the sender and recipient of every transaction. Addresses only touched
+// within the execution of a transaction aren't found, that would require tracing the block.
+func matchBlocklist(blocklist map[string]bool, proposerFeeRecipient, blockFeeRecipient string, txs [][]byte) (*blocklistMatch, error) {
+	if address := strings.ToLower(proposerFeeRecipient); blocklist[address] {
+		return &blocklistMatch{address: address, reason: blocklistReasonProposerFeeRecipient}, nil
+	}
+	if address := strings.ToLower(blockFeeRecipient); blocklist[address] {
+		return &blocklistMatch{address: address, reason: blocklistReasonBlockFeeRecipient}, nil
+	}
+
+	for _, txBytes := range txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(txBytes); err != nil {
+			return nil, err
+		}
+
+		if to := tx.To(); to != nil {
+			if address := strings.ToLower(to.Hex()); blocklist[address] {
+				return &blocklistMatch{address: address, reason: blocklistReasonTxRecipient}, nil
+			}
+		}
+
+		sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, err
+		}
+		if address := strings.ToLower(sender.Hex()); blocklist[address] {
+			return &blocklistMatch{address: address, reason: blocklistReasonTxSender}, nil
+		}
+	}
+	return nil, nil
+}
+
+// saveBlocklistFiltered records the rejection of a submission because of a blocklisted address
+func (api *RelayAPI) saveBlocklistFiltered(log *logrus.Entry, payload *common.BuilderSubmitBlockRequest, match *blocklistMatch) {
+	err := api.db.SaveBlocklistFiltered(database.BlocklistFilteredEntry{
+		Slot:           payload.Slot(),
+		BuilderPubkey:  payload.BuilderPubkey().String(),
+		ProposerPubkey: payload.ProposerPubkey(),
+		BlockHash:      payload.BlockHash(),
+		Address:        match.address,
+		Reason:         match.reason,
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to save blocklist filtering decision")
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchBlocklist(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	recipient := ethcommon.HexToAddress("0xaa00000000000000000000000000000000000002")
+	feeRecipient := "0xaa00000000000000000000000000000000000001"
+
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		To:        &recipient,
+		Gas:       21000,
+		GasFeeCap: big.NewInt(1),
+		Value:     big.NewInt(1),
+	})
+	require.NoError(t, err)
+	txBytes, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	txs := [][]byte{txBytes}
+
+	match, err := matchBlocklist(map[string]bool{}, feeRecipient, feeRecipient, txs)
+	require.NoError(t, err)
+	require.Nil(t, match)
+
+	match, err = matchBlocklist(map[string]bool{feeRecipient: true}, strings.ToUpper(feeRecipient), feeRecipient, txs)
+	require.NoError(t, err)
+	require.Equal(t, &blocklistMatch{address: feeRecipient, reason: blocklistReasonProposerFeeRecipient}, match)
+
+	match, err = matchBlocklist(map[string]bool{strings.ToLower(recipient.Hex()): true}, feeRecipient, feeRecipient, txs)
+	require.NoError(t, err)
+	require.Equal(t, &blocklistMatch{address: strings.ToLower(recipient.Hex()), reason: blocklistReasonTxRecipient}, match)
+
+	match, err = matchBlocklist(map[string]bool{sender: true}, feeRecipient, feeRecipient, txs)
+	require.NoError(t, err)
+	require.Equal(t, &blocklistMatch{address: sender, reason: blocklistReasonTxSender}, match)
+
+	_, err = matchBlocklist(map[string]bool{sender: true}, feeRecipient, feeRecipient, [][]byte{{0x01, 0x02}})
+	require.Error(t, err)
+}
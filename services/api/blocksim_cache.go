@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var disableSimResultCache = os.Getenv("DISABLE_SIM_RESULT_CACHE") == "1"
+
+// simResultFingerprint identifies everything the simulation checks besides the block itself, so a cached verdict is only
+// reused for a resubmission of the same block with the same bid
+func simResultFingerprint(req *BuilderBlockValidationRequest) string {
+	return fmt.Sprintf("%s_%s_%s_%s_%d", req.BuilderPubkey().String(), req.ProposerPubkey(), req.ProposerFeeRecipient(), req.Value().String(), req.RegisteredGasLimit)
+}
+
+// cachedSimError is the cached verdict of a block rejected by the simulator
+type cachedSimError string
+
+func (e cachedSimError) Error() string {
+	return string(e)
+}
+
+func (e cachedSimError) Unwrap() error {
+	return ErrSimulationFailed
+}
+
+// simulateBlock simulates the block, or returns the cached verdict if the same bid was already simulated in this slot.
+// Only valid blocks and blocks rejected by the simulator are cached, not timeouts or other errors. The result is only
+// set for valid blocks.
+func (api *RelayAPI) simulateBlock(ctx context.Context, log *logrus.Entry, req *BuilderBlockValidationRequest, isHighPrio, canShed bool) (simResult *BlockSimulationResult, cached bool, err error) {
+	ctx, span := common.Tracer.Start(ctx, "simulate")
+	defer func() {
+		span.SetAttributes(attribute.Bool("cached", cached))
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if api.ffLoadTestMode {
+		return loadTestSimResult(req), false, nil
+	}
+
+	slot := req.Slot()
+	blockHash := req.BlockHash()
+	fingerprint := simResultFingerprint(req)
+
+	if !disableSimResultCache {
+		result, err := api.redis.GetSimResult(slot, blockHash)
+		if err != nil {
+			log.WithError(err).Error("failed to get cached simulation result")
+		} else if result != nil && result.Fingerprint == fingerprint {
+			if result.Error != "" {
+				return nil, true, cachedSimError(result.Error)
+			}
+			return &BlockSimulationResult{ProposerPayment: result.ProposerPayment}, true, nil
+		}
+	}
+
+	simResult, simErr := api.blockSimQueue.send(ctx, req, isHighPrio, canShed)
+	if simErr != nil {
+		simResult = nil
+	}
+	if disableSimResultCache || (simErr != nil && !errors.Is(simErr, ErrSimulationFailed)) {
+		return simResult, false, simErr
+	}
+
+	result := &datastore.SimResult{Fingerprint: fingerprint}
+	if simErr != nil {
+		result.Error = simErr.Error()
+	} else {
+		result.ProposerPayment = simResult.ProposerPayment
+	}
+	if err := api.redis.SaveSimResult(slot, blockHash, result); err != nil {
+		log.WithError(err).Error("failed to cache simulation result")
+	}
+	return simResult, false, simErr
+}
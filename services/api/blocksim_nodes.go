@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/go-utils/jsonrpc"
+	"github.com/sirupsen/logrus"
+	uberatomic "go.uber.org/atomic"
+)
+
+var (
+	ErrNoBlockSimNodes = errors.New("no block simulation nodes configured")
+
+	// consecutive request errors or timeouts after which a node is considered unhealthy
+	blockSimMaxFailures = int64(cli.GetEnvInt("BLOCKSIM_MAX_FAILURES", 3))
+
+	blockSimHealthCheckInterval = time.Duration(cli.GetEnvInt("BLOCKSIM_HEALTHCHECK_INTERVAL_MS", 5000)) * time.Millisecond
+)
+
+// blockSimNode is a single block simulation endpoint
+type blockSimNode struct {
+	url                 string
+	consecutiveFailures uberatomic.Int64
+	isHealthy           uberatomic.Bool
+}
+
+func newBlockSimNode(url string) *blockSimNode {
+	node := &blockSimNode{url: url}
+	node.isHealthy.Store(true)
+	return node
+}
+
+// recordResult tracks the request errors of the node. Only transport errors and timeouts count, invalid blocks don't.
+func (n *blockSimNode) recordResult(log *logrus.Entry, err error) {
+	if err == nil {
+		n.consecutiveFailures.Store(0)
+		if !n.isHealthy.Swap(true) {
+			log.WithField("blockSimURL", n.url).Info("block simulation node is healthy again")
+		}
+		return
+	}
+
+	failures := n.consecutiveFailures.Inc()
+	if failures >= blockSimMaxFailures && n.isHealthy.Swap(false) {
+		log.WithError(err).WithFields(logrus.Fields{
+			"blockSimURL": n.url,
+			"failures":    failures,
+		}).Warn("block simulation node marked unhealthy")
+	}
+}
+
+// blockSimNodePool round-robins the simulation requests across the healthy nodes. High-prio requests go to the
+// dedicated high-prio node if there is a healthy one.
+type blockSimNodePool struct {
+	log          *logrus.Entry
+	nodes        []*blockSimNode
+	highPrioNode *blockSimNode
+	next         uberatomic.Uint64
+	client       http.Client
+}
+
+func newBlockSimNodePool(log *logrus.Entry, blockSimURLs []string, blockSimHighPrioURL string) *blockSimNodePool {
+	pool := &blockSimNodePool{
+		log: log.WithField("module", "blocksim"),
+		client: http.Client{ //nolint:exhaustruct
+			Timeout: simRequestTimeout,
+		},
+	}
+	for _, url := range blockSimURLs {
+		if url = strings.TrimSpace(url); url != "" {
+			pool.nodes = append(pool.nodes, newBlockSimNode(url))
+		}
+	}
+	if blockSimHighPrioURL != "" {
+		pool.highPrioNode = newBlockSimNode(blockSimHighPrioURL)
+	}
+	return pool
+}
+
+// pick returns the node for the next request. If all nodes are unhealthy, requests still go round-robin to all of them.
+func (p *blockSimNodePool) pick(isHighPrio bool) *blockSimNode {
+	if isHighPrio && p.highPrioNode != nil && p.highPrioNode.isHealthy.Load() {
+		return p.highPrioNode
+	}
+
+	if len(p.nodes) == 0 {
+		return p.highPrioNode
+	}
+
+	start := p.next.Inc()
+	for i := uint64(0); i < uint64(len(p.nodes)); i++ {
+		node := p.nodes[(start+i)%uint64(len(p.nodes))]
+		if node.isHealthy.Load() {
+			return node
+		}
+	}
+	return p.nodes[start%uint64(len(p.nodes))]
+}
+
+// allNodes returns the round-robin nodes and the high-prio node
+func (p *blockSimNodePool) allNodes() []*blockSimNode {
+	if p.highPrioNode == nil {
+		return p.nodes
+	}
+	return append([]*blockSimNode{p.highPrioNode}, p.nodes...)
+}
+
+// send sends the simulation request to the next node, and records whether the node responded
+func (p *blockSimNodePool) send(ctx context.Context, req jsonrpc.JSONRPCRequest, isHighPrio bool) (*jsonrpc.JSONRPCResponse, error) {
+	node := p.pick(isHighPrio)
+	if node == nil {
+		return nil, ErrNoBlockSimNodes
+	}
+
+	resp, err := SendJSONRPCRequest(ctx, &p.client, req, node.url, isHighPrio)
+	node.recordResult(p.log, err)
+	return resp, err
+}
+
+// startHealthChecks periodically checks all nodes, so that unhealthy ones get back into rotation once they recover
+func (p *blockSimNodePool) startHealthChecks() {
+	ticker := time.NewTicker(blockSimHealthCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, node := range p.allNodes() {
+			node.recordResult(p.log, p.checkHealth(node))
+		}
+	}
+}
+
+// checkHealth returns an error if the node doesn't respond or is still syncing
+func (p *blockSimNodePool) checkHealth(node *blockSimNode) error {
+	req := jsonrpc.JSONRPCRequest{ID: "1", Method: "eth_syncing", Params: []any{}, Version: "2.0"}
+	resp, err := SendJSONRPCRequest(context.Background(), &p.client, req, node.url, false)
+	if err != nil {
+		return err
+	} else if resp.Error != nil {
+		return fmt.Errorf("health check failed: %s", resp.Error.Message)
+	} else if string(resp.Result) != "false" {
+		return fmt.Errorf("node is syncing: %s", resp.Result)
+	}
+	return nil
+}
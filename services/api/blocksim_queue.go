@@ -0,0 +1,322 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/go-utils/jsonrpc"
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+var (
+	ErrRequestClosed    = errors.New("request context closed")
+	ErrSimulationFailed = errors.New("simulation failed")
+	ErrSimQueueFull     = errors.New("block simulation queue is full")
+
+	ErrProposerPaymentUnverified = errors.New("simulation did not report the proposer payment")
+
+	// accept blocks if the simulation node doesn't report the proposer payment, e.g. while upgrading the nodes
+	allowUnverifiedPayment = os.Getenv("BLOCKSIM_ALLOW_UNVERIFIED_PAYMENT") == "1"
+
+	// concurrency and queue depth per tier, 0 for no maximum
+	maxConcurrentBlocks         = int64(cli.GetEnvInt("BLOCKSIM_MAX_CONCURRENT", 4))
+	maxConcurrentBlocksHighPrio = int64(cli.GetEnvInt("BLOCKSIM_MAX_CONCURRENT_HIGHPRIO", 4))
+	maxQueuedBlocks             = cli.GetEnvInt("BLOCKSIM_MAX_QUEUED", 50)
+	maxQueuedBlocksHighPrio     = cli.GetEnvInt("BLOCKSIM_MAX_QUEUED_HIGHPRIO", 100)
+
+	simRequestTimeout  = time.Duration(cli.GetEnvInt("BLOCKSIM_TIMEOUT_MS", 3000)) * time.Millisecond
+	simQueueRetryAfter = cli.GetEnvInt("BLOCKSIM_RETRY_AFTER_SEC", 1)
+)
+
+// BlockSimulationResult is the result of a successful block simulation
+type BlockSimulationResult struct {
+	// balance increase of the proposer fee recipient through the block in wei, as measured by the simulation node
+	ProposerPayment string `json:"proposer_payment"`
+}
+
+// verifyProposerPayment checks that the simulation measured a payment to the proposer of at least the bid value. Blocks
+// without a reported payment are rejected unless BLOCKSIM_ALLOW_UNVERIFIED_PAYMENT is set. They are unverified rather
+// than invalid, so they're never rejected with ErrSimulationFailed and the builder isn't demoted for them.
+func verifyProposerPayment(result *BlockSimulationResult, bidValue *big.Int) error {
+	if result.ProposerPayment == "" {
+		common.BlockSimUnverifiedPaymentsTotal.Inc()
+		if allowUnverifiedPayment {
+			return nil
+		}
+		return ErrProposerPaymentUnverified
+	}
+
+	payment, ok := new(big.Int).SetString(result.ProposerPayment, 10)
+	if !ok {
+		return fmt.Errorf("invalid proposer payment in simulation result: %s", result.ProposerPayment)
+	} else if payment.Cmp(bidValue) < 0 {
+		return fmt.Errorf("%w: proposer payment %s is less than the bid value %s", ErrSimulationFailed, payment.String(), bidValue.String())
+	}
+	return nil
+}
+
+type simTier int
+
+const (
+	simTierLowPrio simTier = iota
+	simTierHighPrio
+)
+
+func (t simTier) String() string {
+	if t == simTierHighPrio {
+		return "highprio"
+	}
+	return "lowprio"
+}
+
+type simQueueTier struct {
+	maxConcurrent int64
+	maxQueued     int
+	active        int64
+	waiting       []chan simTier // receives the tier of the slot granted to the waiter
+}
+
+func (t *simQueueTier) hasFreeSlot() bool {
+	return t.maxConcurrent == 0 || t.active < t.maxConcurrent
+}
+
+// BlockSimulationQueue limits the concurrent requests to the block simulator. High-prio and low-prio submissions queue
+// separately, each with its own concurrency limit and queue depth. Waiting high-prio submissions jump ahead of low-prio
+// ones by also taking free low-prio slots, never the other way around. Submissions are shed once their queue is full.
+type BlockSimulationQueue struct {
+	mu      sync.Mutex
+	tiers   [2]*simQueueTier
+	nodes   *blockSimNodePool
+	network string // label of the queue depth metric
+}
+
+func NewBlockSimulationQueue(network string, nodes *blockSimNodePool) *BlockSimulationQueue {
+	return &BlockSimulationQueue{
+		network: network,
+		tiers: [2]*simQueueTier{
+			simTierLowPrio:  {maxConcurrent: maxConcurrentBlocks, maxQueued: maxQueuedBlocks},
+			simTierHighPrio: {maxConcurrent: maxConcurrentBlocksHighPrio, maxQueued: maxQueuedBlocksHighPrio},
+		},
+		nodes: nodes,
+	}
+}
+
+func tierFor(isHighPrio bool) simTier {
+	if isHighPrio {
+		return simTierHighPrio
+	}
+	return simTierLowPrio
+}
+
+// freeSlot returns a free slot for the given tier. Must be called with the lock held.
+func (q *BlockSimulationQueue) freeSlot(tier simTier) (simTier, bool) {
+	if q.tiers[tier].hasFreeSlot() {
+		return tier, true
+	} else if tier == simTierHighPrio && q.tiers[simTierLowPrio].hasFreeSlot() {
+		return simTierLowPrio, true
+	}
+	return 0, false
+}
+
+// dispatch hands out free slots to the waiting submissions, high-prio first. Must be called with the lock held.
+func (q *BlockSimulationQueue) dispatch() {
+	for _, tier := range []simTier{simTierHighPrio, simTierLowPrio} {
+		t := q.tiers[tier]
+		for len(t.waiting) > 0 {
+			slot, ok := q.freeSlot(tier)
+			if !ok {
+				break
+			}
+			q.tiers[slot].active++
+			t.waiting[0] <- slot
+			t.waiting = t.waiting[1:]
+		}
+	}
+}
+
+// updateQueueDepthMetric exports the number of active and waiting requests per tier. Must be called with the lock held.
+func (q *BlockSimulationQueue) updateQueueDepthMetric() {
+	for tier, t := range q.tiers {
+		common.BlockSimQueueDepth.WithLabelValues(q.network, simTier(tier).String(), "active").Set(float64(t.active))
+		common.BlockSimQueueDepth.WithLabelValues(q.network, simTier(tier).String(), "waiting").Set(float64(len(t.waiting)))
+	}
+}
+
+// acquire waits for a simulation slot and returns its tier, which has to be released afterwards. If canShed is set,
+// the submission is rejected right away when its queue is full.
+func (q *BlockSimulationQueue) acquire(ctx context.Context, isHighPrio, canShed bool) (simTier, error) {
+	tier := tierFor(isHighPrio)
+	t := q.tiers[tier]
+
+	q.mu.Lock()
+	if slot, ok := q.freeSlot(tier); ok {
+		q.tiers[slot].active++
+		q.updateQueueDepthMetric()
+		q.mu.Unlock()
+		return slot, nil
+	} else if canShed && t.maxQueued > 0 && len(t.waiting) >= t.maxQueued {
+		q.mu.Unlock()
+		return 0, ErrSimQueueFull
+	}
+	c := make(chan simTier, 1)
+	t.waiting = append(t.waiting, c)
+	q.updateQueueDepthMetric()
+	q.mu.Unlock()
+
+	select {
+	case slot := <-c:
+		return slot, nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for i, w := range t.waiting {
+			if w == c {
+				t.waiting = append(t.waiting[:i], t.waiting[i+1:]...)
+				q.updateQueueDepthMetric()
+				return 0, ErrRequestClosed
+			}
+		}
+		// a slot was granted in the meantime, pass it on
+		q.tiers[<-c].active--
+		q.dispatch()
+		q.updateQueueDepthMetric()
+		return 0, ErrRequestClosed
+	}
+}
+
+func (q *BlockSimulationQueue) release(slot simTier) {
+	q.mu.Lock()
+	q.tiers[slot].active--
+	q.dispatch()
+	q.updateQueueDepthMetric()
+	q.mu.Unlock()
+}
+
+// send simulates the block once a slot is free, and verifies the proposer payment. Submissions whose queue is full are
+// shed with ErrSimQueueFull, unless canShed is false (for blocks which were already accepted optimistically).
+func (q *BlockSimulationQueue) send(ctx context.Context, payload *BuilderBlockValidationRequest, isHighPrio, canShed bool) (*BlockSimulationResult, error) {
+	slot, err := q.acquire(ctx, isHighPrio, canShed)
+	if err != nil {
+		return nil, err
+	}
+	defer q.release(slot)
+
+	if err := ctx.Err(); err != nil {
+		return nil, ErrRequestClosed
+	}
+
+	simResp, err := q.nodes.send(ctx, *newSimRequest(payload), isHighPrio)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseSimResponse(simResp)
+	if err != nil {
+		return nil, err
+	}
+	return result, verifyProposerPayment(result, payload.Value())
+}
+
+// SimulateBlock sends a single simulation request straight to the node at url, without queueing and without verifying
+// the proposer payment. Returns ErrSimulationFailed if the node rejected the block.
+func SimulateBlock(ctx context.Context, client *http.Client, url string, payload *BuilderBlockValidationRequest) (*BlockSimulationResult, error) {
+	simResp, err := SendJSONRPCRequest(ctx, client, *newSimRequest(payload), url, false)
+	if err != nil {
+		return nil, err
+	}
+	return parseSimResponse(simResp)
+}
+
+// newSimRequest returns the simulation request for the fork of the payload
+func newSimRequest(payload *BuilderBlockValidationRequest) *jsonrpc.JSONRPCRequest {
+	switch payload.Versioned().Version() {
+	case common.ForkVersionElectra:
+		return jsonrpc.NewJSONRPCRequest("1", "flashbots_validateBuilderSubmissionV4", payload)
+	case common.ForkVersionDeneb:
+		return jsonrpc.NewJSONRPCRequest("1", "flashbots_validateBuilderSubmissionV3", payload)
+	case common.ForkVersionCapella:
+		return jsonrpc.NewJSONRPCRequest("1", "flashbots_validateBuilderSubmissionV2", payload)
+	default:
+		return jsonrpc.NewJSONRPCRequest("1", "flashbots_validateBuilderSubmissionV1", payload)
+	}
+}
+
+func parseSimResponse(simResp *jsonrpc.JSONRPCResponse) (*BlockSimulationResult, error) {
+	if simResp.Error != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSimulationFailed, simResp.Error.Message)
+	}
+
+	// nodes which don't report the payment return a null result
+	result := new(BlockSimulationResult)
+	if len(simResp.Result) > 0 && string(simResp.Result) != "null" {
+		if err := json.Unmarshal(simResp.Result, result); err != nil {
+			return nil, fmt.Errorf("invalid simulation result: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// currentCounter returns the number of waiting and active requests
+func (q *BlockSimulationQueue) currentCounter() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var cnt int64
+	for _, t := range q.tiers {
+		cnt += t.active + int64(len(t.waiting))
+	}
+	return cnt
+}
+
+// numWaiting returns the number of requests waiting for a simulation slot
+func (q *BlockSimulationQueue) numWaiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	num := 0
+	for _, t := range q.tiers {
+		num += len(t.waiting)
+	}
+	return num
+}
+
+// SendJSONRPCRequest sends the request to URL and returns the general JsonRpcResponse, or an error (note: not the JSONRPCError).
+// The trace context of ctx is propagated to the node.
+func SendJSONRPCRequest(ctx context.Context, client *http.Client, req jsonrpc.JSONRPCRequest, url string, isHighPrio bool) (res *jsonrpc.JSONRPCResponse, err error) {
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	// set request headers
+	httpReq.Header.Add("Content-Type", "application/json")
+	if isHighPrio {
+		httpReq.Header.Add("X-High-Priority", "true")
+	}
+	common.InjectTraceContext(ctx, httpReq.Header)
+
+	// execute request
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res = new(jsonrpc.JSONRPCResponse)
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBlockSimulationQueue(maxConcurrent, maxConcurrentHighPrio int64, maxQueued, maxQueuedHighPrio int) *BlockSimulationQueue {
+	q := NewBlockSimulationQueue("", newBlockSimNodePool(common.TestLog, nil, ""))
+	q.tiers[simTierLowPrio].maxConcurrent = maxConcurrent
+	q.tiers[simTierLowPrio].maxQueued = maxQueued
+	q.tiers[simTierHighPrio].maxConcurrent = maxConcurrentHighPrio
+	q.tiers[simTierHighPrio].maxQueued = maxQueuedHighPrio
+	return q
+}
+
+func TestBlockSimulationQueueHighPrioJumpsAhead(t *testing.T) {
+	q := newTestBlockSimulationQueue(1, 1, 10, 10)
+	ctx := context.Background()
+
+	lowSlot, err := q.acquire(ctx, false, true)
+	require.NoError(t, err)
+	require.Equal(t, simTierLowPrio, lowSlot)
+	highSlot, err := q.acquire(ctx, true, true)
+	require.NoError(t, err)
+	require.Equal(t, simTierHighPrio, highSlot)
+
+	// both tiers are busy: queue a low-prio submission first, then a high-prio one
+	lowGranted := make(chan simTier, 1)
+	highGranted := make(chan simTier, 1)
+	go func() {
+		slot, err := q.acquire(ctx, false, true)
+		require.NoError(t, err)
+		lowGranted <- slot
+	}()
+	require.Eventually(t, func() bool { return q.currentCounter() == 3 }, time.Second, time.Millisecond)
+	go func() {
+		slot, err := q.acquire(ctx, true, true)
+		require.NoError(t, err)
+		highGranted <- slot
+	}()
+	require.Eventually(t, func() bool { return q.currentCounter() == 4 }, time.Second, time.Millisecond)
+
+	// the freed low-prio slot goes to the high-prio submission, a free high-prio slot is never used by low-prio ones
+	q.release(lowSlot)
+	require.Equal(t, simTierLowPrio, <-highGranted)
+	q.release(highSlot)
+	require.Empty(t, lowGranted)
+
+	q.release(simTierLowPrio)
+	require.Equal(t, simTierLowPrio, <-lowGranted)
+}
+
+func TestBlockSimulationQueueShedding(t *testing.T) {
+	q := newTestBlockSimulationQueue(1, 1, 1, 1)
+	ctx := context.Background()
+
+	_, err := q.acquire(ctx, false, true)
+	require.NoError(t, err)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	errC := make(chan error, 1)
+	go func() {
+		_, err := q.acquire(cancelCtx, false, true)
+		errC <- err
+	}()
+	require.Eventually(t, func() bool { return q.currentCounter() == 2 }, time.Second, time.Millisecond)
+
+	// low-prio queue is full, the high-prio one isn't
+	_, err = q.acquire(ctx, false, true)
+	require.ErrorIs(t, err, ErrSimQueueFull)
+	_, err = q.acquire(ctx, true, true)
+	require.NoError(t, err)
+
+	// cancelled submissions leave the queue
+	cancel()
+	require.ErrorIs(t, <-errC, ErrRequestClosed)
+	require.Equal(t, int64(2), q.currentCounter())
+}
+
+func TestBlockSimNodePool(t *testing.T) {
+	pool := newBlockSimNodePool(common.TestLog, []string{"http://sim1", " http://sim2", ""}, "http://sim-highprio")
+	require.Len(t, pool.nodes, 2)
+
+	// round-robin across healthy nodes
+	first := pool.pick(false)
+	require.NotEqual(t, first, pool.pick(false))
+	require.Equal(t, first, pool.pick(false))
+
+	// unhealthy after repeated errors, skipped until it recovers
+	for i := int64(0); i < blockSimMaxFailures; i++ {
+		first.recordResult(pool.log, ErrRequestClosed)
+	}
+	require.False(t, first.isHealthy.Load())
+	require.NotEqual(t, first, pool.pick(false))
+	require.NotEqual(t, first, pool.pick(false))
+	first.recordResult(pool.log, nil)
+	require.True(t, first.isHealthy.Load())
+
+	// high-prio requests go to the dedicated node while it's healthy
+	require.Equal(t, pool.highPrioNode, pool.pick(true))
+	pool.highPrioNode.isHealthy.Store(false)
+	require.NotEqual(t, pool.highPrioNode, pool.pick(true))
+}
+
+func TestVerifyProposerPayment(t *testing.T) {
+	bidValue := big.NewInt(1000)
+
+	err := verifyProposerPayment(&BlockSimulationResult{ProposerPayment: "1000"}, bidValue)
+	require.NoError(t, err)
+	err = verifyProposerPayment(&BlockSimulationResult{ProposerPayment: "1001"}, bidValue)
+	require.NoError(t, err)
+
+	err = verifyProposerPayment(&BlockSimulationResult{ProposerPayment: "999"}, bidValue)
+	require.ErrorIs(t, err, ErrSimulationFailed)
+
+	err = verifyProposerPayment(&BlockSimulationResult{ProposerPayment: "0x3e8"}, bidValue)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrSimulationFailed)
+
+	// an unreported payment is rejected, without counting as a validation failure, unless explicitly allowed
+	err = verifyProposerPayment(&BlockSimulationResult{}, bidValue)
+	require.ErrorIs(t, err, ErrProposerPaymentUnverified)
+	require.False(t, isValidationFailure(err))
+	defer func() { allowUnverifiedPayment = false }()
+	allowUnverifiedPayment = true
+	err = verifyProposerPayment(&BlockSimulationResult{}, bidValue)
+	require.NoError(t, err)
+}
+
+func TestIsValidationFailure(t *testing.T) {
+	require.True(t, isValidationFailure(fmt.Errorf("%w: invalid block", ErrSimulationFailed)))
+	require.True(t, isValidationFailure(cachedSimError("simulation failed: invalid block")))
+	require.True(t, isValidationFailure(newSubmissionError(http.StatusBadRequest, SubmissionErrBlocklisted, "blocklisted")))
+
+	require.False(t, isValidationFailure(ErrRequestClosed))
+	require.False(t, isValidationFailure(ErrSimQueueFull))
+	require.False(t, isValidationFailure(context.DeadlineExceeded))
+	require.False(t, isValidationFailure(newSubmissionError(http.StatusServiceUnavailable, SubmissionErrBlocklistUnavailable, "blocklist unavailable")))
+}
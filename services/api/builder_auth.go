@@ -0,0 +1,207 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrMissingAPIKey     = errors.New("missing builder api key")
+	ErrInvalidAPIKey     = errors.New("invalid builder api key")
+	ErrRevokedAPIKey     = errors.New("builder api key was revoked")
+	ErrAPIKeyUnavailable = errors.New("builder api key could not be checked")
+	ErrAPIKeyRateLimited = errors.New("builder api key rate limit exceeded")
+
+	// if set, builders without an API key can't submit at all
+	requireBuilderAPIKey = os.Getenv("REQUIRE_BUILDER_API_KEY") == "1"
+)
+
+const (
+	// HeaderBuilderAPIKey is the request header builders send their API key in
+	HeaderBuilderAPIKey = "X-Builder-Api-Key"
+
+	// revokedAPIKeyHash is stored instead of the key hash of builders whose key was revoked, so that they can't fall back
+	// to submitting without a key
+	revokedAPIKeyHash = "revoked"
+)
+
+// hashAPIKey returns the hex-encoded sha256 of an API key, which is what gets stored
+func hashAPIKey(apiKey string) string {
+	h := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(h[:])
+}
+
+// generateAPIKey returns a new random API key
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkBuilderAPIKey validates the API key of the request against the one issued to the builder. Builders who never had
+// a key are only accepted if REQUIRE_BUILDER_API_KEY isn't set, builders whose key was revoked never. With mTLS, a client
+// certificate authenticates the builder instead, and certificates of other builders are rejected. Submissions are
+// rejected with ErrAPIKeyUnavailable if the key can't be looked up, and with ErrAPIKeyRateLimited if a valid key exceeds
+// its rate limit. Every check is counted by builder (for builders with a key) and result.
+func (api *RelayAPI) checkBuilderAPIKey(req *http.Request, builderPubkey string) error {
+	builderPubkey = strings.ToLower(builderPubkey)
+	apiKeyHash, err := api.verifyBuilderAPIKey(req, builderPubkey)
+	if err == nil && apiKeyHash != "" && !api.apiKeyRateLimiter.Allow(apiKeyHash) {
+		common.RateLimitedRequestsTotal.WithLabelValues(api.networkName(), "api_key").Inc()
+		err = ErrAPIKeyRateLimited
+	}
+
+	// only builders with a key are labeled, the pubkeys of other submissions are arbitrary
+	builderLabel := ""
+	if apiKeyHash != "" {
+		builderLabel = builderPubkey
+	}
+	common.BuilderAPIKeyChecksTotal.WithLabelValues(api.networkName(), builderLabel, builderAPIKeyCheckResult(err)).Inc()
+	return err
+}
+
+// verifyBuilderAPIKey does the checks of checkBuilderAPIKey, and returns the stored key hash of the builder if it has one
+func (api *RelayAPI) verifyBuilderAPIKey(req *http.Request, builderPubkey string) (apiKeyHash string, err error) {
+	certBuilderPubkey, err := clientCertBuilderPubkey(req)
+	if err != nil {
+		return "", err
+	} else if certBuilderPubkey != "" {
+		if certBuilderPubkey != builderPubkey {
+			return "", ErrClientCertMismatch
+		}
+		return "", nil
+	}
+
+	apiKeyHash, err = api.redis.GetBlockBuilderAPIKeyHash(builderPubkey)
+	if err != nil {
+		api.log.WithError(err).Error("could not get builder api key")
+		return "", ErrAPIKeyUnavailable
+	}
+
+	apiKey := req.Header.Get(HeaderBuilderAPIKey)
+	if apiKeyHash == "" {
+		if requireBuilderAPIKey {
+			return "", ErrMissingAPIKey
+		}
+		return "", nil
+	} else if apiKeyHash == revokedAPIKeyHash {
+		return apiKeyHash, ErrRevokedAPIKey
+	} else if apiKey == "" {
+		return apiKeyHash, ErrMissingAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(apiKey)), []byte(apiKeyHash)) != 1 {
+		return apiKeyHash, ErrInvalidAPIKey
+	}
+	return apiKeyHash, nil
+}
+
+// builderAPIKeyCheckResult is the metrics label of the result of checkBuilderAPIKey
+func builderAPIKeyCheckResult(err error) string {
+	switch {
+	case err == nil:
+		return "accepted"
+	case errors.Is(err, ErrMissingAPIKey):
+		return "missing"
+	case errors.Is(err, ErrInvalidAPIKey):
+		return "invalid"
+	case errors.Is(err, ErrRevokedAPIKey):
+		return "revoked"
+	case errors.Is(err, ErrAPIKeyRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrAPIKeyUnavailable):
+		return "unavailable"
+	}
+	return "client_cert_rejected"
+}
+
+// respondBuilderAPIKeyError responds to a submission rejected by checkBuilderAPIKey
+func (api *RelayAPI) respondBuilderAPIKeyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrAPIKeyUnavailable) {
+		api.RespondErrorWithCode(w, http.StatusServiceUnavailable, ErrorCodeUnavailable, err.Error())
+		return
+	} else if errors.Is(err, ErrAPIKeyRateLimited) {
+		api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeRateLimited, "too many requests")
+		return
+	}
+	api.RespondErrorWithCode(w, http.StatusUnauthorized, ErrorCodeInvalidAPIKey, err.Error())
+}
+
+// handleInternalBuilderAPIKey issues a new API key for a builder (replacing any previous one) on POST, and revokes it on
+// DELETE. Revoked builders can't submit until they are issued a new key.
+func (api *RelayAPI) handleInternalBuilderAPIKey(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	builderPubkey := strings.ToLower(vars["pubkey"])
+	log := api.requestLogger(req).WithFields(logrus.Fields{
+		"builderPubkey": builderPubkey,
+		"method":        req.Method,
+	})
+
+	if req.Method == http.MethodDelete {
+		log.Info("revoking builder api key")
+		err := api.db.SetBlockBuilderAPIKeyHash(builderPubkey, revokedAPIKeyHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnknownBuilder, "builder not found")
+			return
+		} else if err != nil {
+			log.WithError(err).Error("could not revoke builder api key in database")
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not revoke builder api key")
+			return
+		}
+
+		err = api.redis.SetBlockBuilderAPIKeyHash(builderPubkey, revokedAPIKeyHash)
+		if err != nil {
+			log.WithError(err).Error("could not revoke builder api key in redis")
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not revoke builder api key")
+			return
+		}
+		api.RespondOK(w, NilResponse)
+		return
+	}
+
+	apiKey, err := generateAPIKey()
+	if err != nil {
+		log.WithError(err).Error("could not generate builder api key")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeInternal, "could not generate builder api key")
+		return
+	}
+
+	log.Info("issuing builder api key")
+	apiKeyHash := hashAPIKey(apiKey)
+	err = api.db.SetBlockBuilderAPIKeyHash(builderPubkey, apiKeyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnknownBuilder, "builder not found")
+		return
+	} else if err != nil {
+		log.WithError(err).Error("could not set builder api key in database")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not set builder api key")
+		return
+	}
+
+	err = api.redis.SetBlockBuilderAPIKeyHash(builderPubkey, apiKeyHash)
+	if err != nil {
+		log.WithError(err).Error("could not set builder api key in redis")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not set builder api key")
+		return
+	}
+
+	// the key is only ever returned here, only its hash is stored
+	api.RespondOK(w, struct {
+		APIKey string `json:"api_key"`
+	}{
+		APIKey: apiKey,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/datastore"
+)
+
+// builderStatusReloadInterval is how often all builder statuses are reloaded from redis, in case an update was missed
+var builderStatusReloadInterval = time.Duration(cli.GetEnvInt("BUILDER_STATUS_RELOAD_INTERVAL_SEC", 60)) * time.Second
+
+// startBuilderStatusUpdates keeps the builder statuses up to date with the updates published by the housekeeper and the
+// other instances, and reloads them periodically
+func (api *RelayAPI) startBuilderStatusUpdates() {
+	updates := api.redis.SubscribeBuilderStatusUpdates(api.shutdownCtx)
+	ticker := time.NewTicker(builderStatusReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				if api.shutdownCtx.Err() != nil {
+					return
+				}
+				api.log.Error("builder status subscription closed, only reloading periodically")
+				updates = nil
+				continue
+			}
+			api.builderStatusesLock.Lock()
+			if api.builderStatuses != nil {
+				api.builderStatuses[update.BuilderPubkey] = update.Status
+			}
+			api.builderStatusesLock.Unlock()
+			api.log.WithField("builderPubkey", update.BuilderPubkey).Infof("builder status updated: %s", update.Status)
+		case <-ticker.C:
+			api.loadBuilderStatuses()
+		}
+	}
+}
+
+// loadBuilderStatuses keeps the previous statuses if they can't be loaded from redis
+func (api *RelayAPI) loadBuilderStatuses() {
+	statuses, err := api.redis.GetBlockBuilderStatuses()
+	if err != nil {
+		api.log.WithError(err).Error("failed to get builder statuses from redis")
+		return
+	}
+
+	api.builderStatusesLock.Lock()
+	api.builderStatuses = statuses
+	api.builderStatusesLock.Unlock()
+	api.log.WithField("numBuilders", len(statuses)).Debug("loaded builder statuses")
+}
+
+// getBlockBuilderStatus returns the status of the builder from memory, or from redis until the statuses are loaded
+func (api *RelayAPI) getBlockBuilderStatus(builderPubkey string) (isHighPrio, isBlacklisted bool, err error) {
+	api.builderStatusesLock.RLock()
+	statuses := api.builderStatuses
+	status := statuses[builderPubkey]
+	api.builderStatusesLock.RUnlock()
+	if statuses == nil {
+		return api.redis.GetBlockBuilderStatus(builderPubkey)
+	}
+	return status == datastore.RedisBlockBuilderStatusHighPrio, status == datastore.RedisBlockBuilderStatusBlacklisted, nil
+}
+
+// ReloadBuilderStatuses applies the builder statuses of the database right away, instead of waiting for the housekeeper.
+// Changed statuses are pushed to all instances.
+func (api *RelayAPI) ReloadBuilderStatuses() error {
+	builders, err := api.db.GetBlockBuilders()
+	if err != nil {
+		return err
+	}
+
+	statuses := make(map[string]datastore.BlockBuilderStatus, len(builders))
+	for _, builder := range builders {
+		statuses[builder.BuilderPubkey] = datastore.MakeBlockBuilderStatus(builder.IsHighPrio, builder.IsBlacklisted)
+	}
+	changed, err := api.redis.UpdateBlockBuilderStatuses(statuses)
+	if err != nil {
+		return err
+	}
+
+	api.loadBuilderStatuses()
+	api.log.WithField("numChanged", len(changed)).Info("reloaded builder statuses from the database")
+	return nil
+}
+
+func (api *RelayAPI) handleInternalReloadBuilderStatuses(w http.ResponseWriter, req *http.Request) {
+	if err := api.ReloadBuilderStatuses(); err != nil {
+		api.log.WithError(err).Error("failed to reload builder statuses")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not reload builder statuses")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// handleSubmitInclusionConstraints accepts the inclusion constraints of an upcoming slot, signed by its proposer or the
+// delegate of the proposer. Newer constraints replace the previous ones of the slot.
+func (api *RelayAPI) handleSubmitInclusionConstraints(w http.ResponseWriter, req *http.Request) {
+	log := api.requestLogger(req).WithFields(logrus.Fields{
+		"method":        "submitInclusionConstraints",
+		"contentLength": req.ContentLength,
+	})
+
+	payload := new(common.SignedInclusionConstraints)
+	if err := json.NewDecoder(req.Body).Decode(payload); isBodyTooLarge(err) {
+		log.WithError(err).Warn("inclusion constraints too large")
+		api.RespondErrorWithCode(w, http.StatusRequestEntityTooLarge, ErrorCodeRequestTooLarge, "request body too large")
+		return
+	} else if err != nil {
+		log.WithError(err).Warn("could not decode inclusion constraints")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	constraints := payload.Message
+	if constraints == nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing message")
+		return
+	}
+	log = log.WithFields(logrus.Fields{
+		"slot":           constraints.Slot,
+		"proposerPubkey": constraints.ProposerPubkey.String(),
+		"numTxHashes":    len(constraints.TransactionHashes),
+		"numTxs":         len(constraints.Transactions),
+	})
+
+	if err := constraints.Check(); err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidConstraints, err.Error())
+		return
+	}
+
+	if constraints.Slot <= api.headSlot.Load() {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeSlotTooOld, "slot is too old")
+		return
+	}
+
+	slotDuty := api.proposerDuties.dutyForSlot(constraints.Slot)
+	if slotDuty == nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeNoProposerDuty, "no proposer duty for this slot")
+		return
+	} else if !strings.EqualFold(slotDuty.Pubkey.String(), constraints.ProposerPubkey.String()) {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeNotSlotProposer, "not the proposer of this slot")
+		return
+	}
+
+	ok, err := boostTypes.VerifySignature(constraints, api.opts.EthNetDetails.DomainBuilder, constraints.ProposerPubkey[:], payload.Signature[:])
+	if err != nil {
+		log.WithError(err).Info("could not verify inclusion constraints signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "invalid signature")
+		return
+	}
+	if !ok {
+		// not signed by the proposer, it may be signed by its delegate
+		delegate, err := api.redis.GetConstraintsDelegate(constraints.ProposerPubkey.String())
+		if err != nil {
+			log.WithError(err).Error("could not get constraints delegate")
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not get constraints delegate")
+			return
+		}
+		if delegate != "" {
+			delegatePubkey, err := boostTypes.HexToPubkey(delegate)
+			if err == nil {
+				ok, err = boostTypes.VerifySignature(constraints, api.opts.EthNetDetails.DomainBuilder, delegatePubkey[:], payload.Signature[:])
+			}
+			if err != nil {
+				log.WithError(err).Error("could not verify inclusion constraints signature of the delegate")
+			}
+			log = log.WithField("delegatePubkey", delegate)
+		}
+	}
+	if !ok {
+		log.Info("invalid inclusion constraints signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "invalid signature")
+		return
+	}
+
+	if err := api.redis.SaveInclusionConstraints(constraints.Slot, payload); err != nil {
+		log.WithError(err).Error("could not save inclusion constraints")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save inclusion constraints")
+		return
+	}
+
+	log.Info("inclusion constraints received")
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleConstraintsDelegation registers the key which may sign the inclusion constraints of a validator, in addition to
+// the validator key
+func (api *RelayAPI) handleConstraintsDelegation(w http.ResponseWriter, req *http.Request) {
+	log := api.requestLogger(req).WithField("method", "constraintsDelegation")
+
+	payload := new(common.SignedConstraintsDelegation)
+	if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+		log.WithError(err).Warn("could not decode constraints delegation")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	delegation := payload.Message
+	if delegation == nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing message")
+		return
+	}
+	log = log.WithFields(logrus.Fields{
+		"validatorPubkey": delegation.ValidatorPubkey.String(),
+		"delegatePubkey":  delegation.DelegatePubkey.String(),
+		"timestamp":       delegation.Timestamp,
+	})
+
+	if !api.datastore.IsKnownValidator(boostTypes.PubkeyHex(delegation.ValidatorPubkey.String())) {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnknownValidator, "not a known validator")
+		return
+	}
+
+	if delegation.Timestamp > uint64(time.Now().Add(10*time.Second).Unix()) {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeRegistrationTooNew, "timestamp too far in the future")
+		return
+	}
+
+	ok, err := boostTypes.VerifySignature(delegation, api.opts.EthNetDetails.DomainBuilder, delegation.ValidatorPubkey[:], payload.Signature[:])
+	if !ok || err != nil {
+		log.WithError(err).Info("invalid constraints delegation signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "invalid signature")
+		return
+	}
+
+	set, err := api.redis.SetConstraintsDelegate(delegation.ValidatorPubkey.String(), delegation.DelegatePubkey.String(), delegation.Timestamp)
+	if err != nil {
+		log.WithError(err).Error("could not save constraints delegate")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save constraints delegate")
+		return
+	} else if !set {
+		log.Info("constraints delegation is not newer than the registered one")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "a delegation with the same or a newer timestamp is registered")
+		return
+	}
+
+	log.Info("constraints delegate registered")
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleBuilderGetInclusionConstraints returns the signed inclusion constraints of a slot, so builders can include the
+// transactions, or no content if the slot has none
+func (api *RelayAPI) handleBuilderGetInclusionConstraints(w http.ResponseWriter, req *http.Request) {
+	slot, err := strconv.ParseUint(req.URL.Query().Get("slot"), 10, 64)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, common.ErrInvalidSlot.Error())
+		return
+	}
+
+	constraints, err := api.redis.GetInclusionConstraints(slot)
+	if err != nil {
+		api.log.WithError(err).Error("could not get inclusion constraints")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not get inclusion constraints")
+		return
+	} else if constraints == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	api.RespondOK(w, constraints)
+}
+
+// checkInclusionConstraints returns the hashes of the constrained transactions of the slot which are missing from the
+// transactions of a payload
+func (api *RelayAPI) checkInclusionConstraints(slot uint64, txs [][]byte) ([]string, error) {
+	constraints, err := api.redis.GetInclusionConstraints(slot)
+	if err != nil || constraints == nil || constraints.Message == nil {
+		return nil, err
+	}
+	return constraints.Message.MissingTransactions(txs), nil
+}
+
+// hasInclusionConstraints returns whether the slot has inclusion constraints. The transactions of header-only
+// submissions and peer bids are unknown, so they aren't accepted for such slots.
+func (api *RelayAPI) hasInclusionConstraints(slot uint64) (bool, error) {
+	constraints, err := api.redis.GetInclusionConstraints(slot)
+	return constraints != nil, err
+}
+
+// bestBidMeetingInclusionConstraints returns the best bid whose payload contains the constrained transactions of the
+// slot, or nil if there is none. The top bid is checked first, then the latest bids of the other builders, highest first.
+// Submissions are checked when the slot has constraints, so only bids received before the constraints can miss them.
+func (api *RelayAPI) bestBidMeetingInclusionConstraints(log *logrus.Entry, slot uint64, parentHash, proposerPubkey string, topBid *common.GetHeaderResponse) *common.GetHeaderResponse {
+	constraints, err := api.redis.GetInclusionConstraints(slot)
+	if err != nil {
+		log.WithError(err).Error("could not get inclusion constraints")
+		return nil
+	} else if constraints == nil || constraints.Message == nil {
+		return topBid
+	}
+
+	topBlockHash := topBid.BlockHash().String()
+	if api.payloadMeetsInclusionConstraints(log, constraints.Message, slot, proposerPubkey, topBlockHash) {
+		return topBid
+	}
+
+	bids, err := api.redis.GetLatestBuilderBids(slot, strings.ToLower(parentHash), strings.ToLower(proposerPubkey))
+	if err != nil {
+		log.WithError(err).Error("could not get the latest builder bids")
+		return nil
+	}
+	for _, bid := range bids {
+		blockHash := bid.BlockHash().String()
+		if blockHash == topBlockHash {
+			continue
+		}
+		if api.payloadMeetsInclusionConstraints(log, constraints.Message, slot, proposerPubkey, blockHash) {
+			log.WithFields(logrus.Fields{
+				"topBlockHash": topBlockHash,
+				"blockHash":    blockHash,
+			}).Info("top bid does not meet the inclusion constraints, serving the best bid that does")
+			return bid
+		}
+	}
+	return nil
+}
+
+func (api *RelayAPI) payloadMeetsInclusionConstraints(log *logrus.Entry, constraints *common.InclusionConstraints, slot uint64, proposerPubkey, blockHash string) bool {
+	log = log.WithField("blockHash", blockHash)
+
+	// the payloads of header-only submissions and peer bids aren't known until delivered, so they can't be checked
+	payload, err := api.redis.GetExecutionPayload(slot, strings.ToLower(proposerPubkey), strings.ToLower(blockHash))
+	if err != nil || payload == nil {
+		log.WithError(err).Info("payload of the bid unavailable to check the inclusion constraints")
+		return false
+	}
+	missing := constraints.MissingTransactions(payload.Transactions())
+	if len(missing) > 0 {
+		log.WithField("missingTxs", missing).Info("bid does not meet the inclusion constraints")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitInclusionConstraints(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.ffInclusionConstraints = true
+	backend.relay.headSlot.Store(10)
+
+	newKey := func() (*bls.SecretKey, types.PublicKey) {
+		sk, _, err := bls.GenerateNewKeypair()
+		require.NoError(t, err)
+		pubkey, err := types.BlsPublicKeyToPublicKey(bls.PublicKeyFromSecretKey(sk))
+		require.NoError(t, err)
+		return sk, pubkey
+	}
+	proposerSk, proposerPubkey := newKey()
+	delegateSk, delegatePubkey := newKey()
+	backend.relay.proposerDuties.set([]types.BuilderGetValidatorsResponseEntry{
+		{Slot: 11, Entry: &types.SignedValidatorRegistration{Message: &types.RegisterValidatorRequestMessage{Pubkey: proposerPubkey}}},
+	}, 10)
+
+	newConstraints := func(slot uint64, sk *bls.SecretKey) *common.SignedInclusionConstraints {
+		constraints := &common.InclusionConstraints{
+			Slot:           slot,
+			ProposerPubkey: proposerPubkey,
+			Transactions:   []hexutil.Bytes{{0x01, 0x02}},
+		}
+		sig, err := types.SignMessage(constraints, builderSigningDomain, sk)
+		require.NoError(t, err)
+		return &common.SignedInclusionConstraints{Message: constraints, Signature: sig}
+	}
+
+	t.Run("past slot", func(t *testing.T) {
+		rr := backend.request(http.MethodPost, pathInclusionConstraints, newConstraints(10, proposerSk))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "slot is too old")
+	})
+
+	t.Run("no proposer duty", func(t *testing.T) {
+		rr := backend.request(http.MethodPost, pathInclusionConstraints, newConstraints(12, proposerSk))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "no proposer duty")
+	})
+
+	t.Run("delegate not registered", func(t *testing.T) {
+		rr := backend.request(http.MethodPost, pathInclusionConstraints, newConstraints(11, delegateSk))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid signature")
+	})
+
+	t.Run("signed by the proposer", func(t *testing.T) {
+		rr := backend.request(http.MethodPost, pathInclusionConstraints, newConstraints(11, proposerSk))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = backend.request(http.MethodGet, pathBuilderGetConstraints+"?slot=11", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		rr = backend.request(http.MethodGet, pathBuilderGetConstraints+"?slot=12", nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("signed by the delegate", func(t *testing.T) {
+		err := backend.redis.SetKnownValidator(types.PubkeyHex(proposerPubkey.String()), 1)
+		require.NoError(t, err)
+		_, err = backend.datastore.RefreshKnownValidators()
+		require.NoError(t, err)
+
+		newDelegation := func(timestamp uint64) *common.SignedConstraintsDelegation {
+			delegation := &common.ConstraintsDelegation{
+				ValidatorPubkey: proposerPubkey,
+				DelegatePubkey:  delegatePubkey,
+				Timestamp:       timestamp,
+			}
+			sig, err := types.SignMessage(delegation, builderSigningDomain, proposerSk)
+			require.NoError(t, err)
+			return &common.SignedConstraintsDelegation{Message: delegation, Signature: sig}
+		}
+		timestamp := uint64(time.Now().Unix())
+		rr := backend.request(http.MethodPost, pathConstraintsDelegation, newDelegation(timestamp))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// replayed, older and future delegations are rejected
+		for _, ts := range []uint64{timestamp, timestamp - 1, timestamp + 60} {
+			rr = backend.request(http.MethodPost, pathConstraintsDelegation, newDelegation(ts))
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+
+		rr = backend.request(http.MethodPost, pathInclusionConstraints, newConstraints(11, delegateSk))
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	missing, err := backend.relay.checkInclusionConstraints(11, [][]byte{{0x03}})
+	require.NoError(t, err)
+	require.Equal(t, []string{common.TransactionHash([]byte{0x01, 0x02})}, missing)
+	missing, err = backend.relay.checkInclusionConstraints(11, [][]byte{{0x03}, {0x01, 0x02}})
+	require.NoError(t, err)
+	require.Len(t, missing, 0)
+}
+
+func TestGetHeaderInclusionConstraints(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.ffInclusionConstraints = true
+	backend.relay.headSlot.Store(10)
+
+	slot := uint64(11)
+	parentHash := "0x" + strings.Repeat("01", 32)
+	proposerPubkey := "0x" + strings.Repeat("02", 48)
+	constrainedTx := hexutil.Bytes{0x01, 0x02}
+	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", slot, parentHash, proposerPubkey)
+
+	saveBid := func(builderPubkey string, blockHash byte, value uint64, txs ...hexutil.Bytes) {
+		bid := &common.GetHeaderResponse{
+			Bellatrix: &types.GetHeaderResponse{
+				Version: "bellatrix",
+				Data: &types.SignedBuilderBid{
+					Message: &types.BuilderBid{
+						Header: &types.ExecutionPayloadHeader{BlockHash: types.Hash{blockHash}},
+						Value:  types.IntToU256(value),
+						Pubkey: types.PublicKey{0x01},
+					},
+				},
+			},
+		}
+		err := backend.redis.SaveLatestBuilderBid(slot, builderPubkey, parentHash, proposerPubkey, time.Now(), bid)
+		require.NoError(t, err)
+		payload := &common.GetPayloadResponse{
+			Bellatrix: &types.GetPayloadResponse{
+				Version: "bellatrix",
+				Data:    &types.ExecutionPayload{BlockHash: types.Hash{blockHash}, Transactions: txs},
+			},
+		}
+		err = backend.redis.SaveExecutionPayload(slot, proposerPubkey, bid.BlockHash().String(), payload)
+		require.NoError(t, err)
+		_, err = backend.redis.UpdateTopBid(slot, parentHash, proposerPubkey)
+		require.NoError(t, err)
+	}
+	getHeaderValue := func() string {
+		rr := backend.request(http.MethodGet, path, nil)
+		if rr.Code == http.StatusNoContent {
+			return ""
+		}
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		bid := new(common.GetHeaderResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		return bid.Value().String()
+	}
+
+	saveBid("0xb1", 0x11, 3)
+	require.Equal(t, "3", getHeaderValue())
+
+	constraints := &common.SignedInclusionConstraints{
+		Message: &common.InclusionConstraints{Slot: slot, Transactions: []hexutil.Bytes{constrainedTx}},
+	}
+	require.NoError(t, backend.redis.SaveInclusionConstraints(slot, constraints))
+	require.Equal(t, "", getHeaderValue(), "no bid meets the constraints")
+
+	// the best bid including the constrained transaction is served instead of the top bid
+	saveBid("0xb2", 0x22, 1, constrainedTx)
+	saveBid("0xb3", 0x33, 2, hexutil.Bytes{0x03}, constrainedTx)
+	require.Equal(t, "2", getHeaderValue())
+
+	// and the top bid is served once it includes it
+	saveBid("0xb1", 0x44, 4, constrainedTx)
+	require.Equal(t, "4", getHeaderValue())
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidDataAPIKey = errors.New("invalid data api key")
+
+	// per-key rate limits of the data API key tiers, a rate of 0 disables the limit
+	dataAPIKeyRatePerSec        = cli.GetEnvInt("DATA_API_KEY_RATE_LIMIT_PER_SEC", 10)
+	dataAPIKeyBurst             = cli.GetEnvInt("DATA_API_KEY_RATE_LIMIT_BURST", 100)
+	dataAPIPartnerKeyRatePerSec = cli.GetEnvInt("DATA_API_PARTNER_KEY_RATE_LIMIT_PER_SEC", 100)
+	dataAPIPartnerKeyBurst      = cli.GetEnvInt("DATA_API_PARTNER_KEY_RATE_LIMIT_BURST", 1000)
+
+	dataAPIKeysRefreshInterval = time.Duration(cli.GetEnvInt("DATA_API_KEYS_REFRESH_INTERVAL_SEC", 60)) * time.Second
+)
+
+// HeaderDataAPIKey is the request header data API consumers send their API key in
+const HeaderDataAPIKey = "X-Data-Api-Key"
+
+// Tiers of the data API keys, which set the default rate limit of the keys
+const (
+	DataAPIKeyTierStandard = "standard"
+	DataAPIKeyTierPartner  = "partner"
+)
+
+// dataAPIKeyTierRateLimit returns the rate limit of the keys of the tier, ok is false for unknown tiers
+func dataAPIKeyTierRateLimit(tier string) (ratePerSec, burst int, ok bool) {
+	switch tier {
+	case DataAPIKeyTierStandard:
+		return dataAPIKeyRatePerSec, dataAPIKeyBurst, true
+	case DataAPIKeyTierPartner:
+		return dataAPIPartnerKeyRatePerSec, dataAPIPartnerKeyBurst, true
+	}
+	return 0, 0, false
+}
+
+type AdminDataAPIKeyRequest struct {
+	Name string `json:"name"`
+	Tier string `json:"tier"`
+
+	// overrides of the rate limit of the tier, 0 for the tier default
+	RateLimitPerSec int `json:"rate_limit_per_sec"`
+	RateLimitBurst  int `json:"rate_limit_burst"`
+}
+
+// dataAPIKey is an active data API key, with the rate limiter shared by all its requests
+type dataAPIKey struct {
+	entry   *database.DataAPIKeyEntry
+	limiter *RateLimiter
+}
+
+func newDataAPIKey(entry *database.DataAPIKeyEntry) *dataAPIKey {
+	ratePerSec, burst, _ := dataAPIKeyTierRateLimit(entry.Tier)
+	if entry.RateLimitPerSec > 0 {
+		ratePerSec = entry.RateLimitPerSec
+	}
+	if entry.RateLimitBurst > 0 {
+		burst = entry.RateLimitBurst
+	}
+	return &dataAPIKey{
+		entry:   entry,
+		limiter: NewRateLimiter(ratePerSec, burst),
+	}
+}
+
+// startDataAPIKeyUpdates periodically reloads the data API keys, to apply the keys issued and revoked on other instances
+func (api *RelayAPI) startDataAPIKeyUpdates() {
+	ticker := time.NewTicker(dataAPIKeysRefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		api.updateDataAPIKeys()
+	}
+}
+
+// updateDataAPIKeys keeps the previous keys if they can't be loaded from the database
+func (api *RelayAPI) updateDataAPIKeys() {
+	entries, err := api.db.GetDataAPIKeys()
+	if err != nil {
+		api.log.WithError(err).Error("failed to get data api keys from db")
+		return
+	}
+	api.setDataAPIKeys(entries)
+	api.log.WithField("numKeys", len(entries)).Debug("updated data api keys")
+}
+
+// setDataAPIKeys replaces the active data API keys. Keys which stay active keep their rate limiter state.
+func (api *RelayAPI) setDataAPIKeys(entries []*database.DataAPIKeyEntry) {
+	api.dataAPIKeysLock.Lock()
+	defer api.dataAPIKeysLock.Unlock()
+
+	keys := make(map[string]*dataAPIKey, len(entries))
+	for _, entry := range entries {
+		if key, found := api.dataAPIKeys[entry.KeyHash]; found && key.entry.ID == entry.ID {
+			keys[entry.KeyHash] = key
+		} else {
+			keys[entry.KeyHash] = newDataAPIKey(entry)
+		}
+	}
+	api.dataAPIKeys = keys
+}
+
+// getDataAPIKey returns the active data API key, or nil if the key is unknown or revoked
+func (api *RelayAPI) getDataAPIKey(apiKey string) *dataAPIKey {
+	api.dataAPIKeysLock.RLock()
+	defer api.dataAPIKeysLock.RUnlock()
+	return api.dataAPIKeys[hashAPIKey(apiKey)]
+}
+
+// dataAPIRateLimitMiddleware applies the rate limit of the API key of the request, instead of the limit per client IP
+// of the anonymous requests. Requests with an unknown key are rejected, rather than served anonymously.
+func (api *RelayAPI) dataAPIRateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	anonymous := api.rateLimitMiddleware(next)
+	return func(w http.ResponseWriter, req *http.Request) {
+		apiKey := req.Header.Get(HeaderDataAPIKey)
+		if apiKey == "" {
+			anonymous(w, req)
+			return
+		}
+
+		key := api.getDataAPIKey(apiKey)
+		if key == nil {
+			api.RespondErrorWithCode(w, http.StatusUnauthorized, ErrorCodeInvalidAPIKey, ErrInvalidDataAPIKey.Error())
+			return
+		}
+
+		if !key.limiter.Allow(key.entry.Name) {
+			common.DataAPIKeyRequestsTotal.WithLabelValues(key.entry.Name, key.entry.Tier, "rate_limited").Inc()
+			api.log.WithFields(logrus.Fields{
+				"dataAPIKey":  key.entry.Name,
+				"path":        req.URL.Path,
+				"numRejected": key.limiter.NumRejected(),
+			}).Debug("request rate limited (data api key)")
+			api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeRateLimited, "too many requests")
+			return
+		}
+		common.DataAPIKeyRequestsTotal.WithLabelValues(key.entry.Name, key.entry.Tier, "allowed").Inc()
+		next(w, req)
+	}
+}
+
+// handleAdminGetDataAPIKeys returns the active data API keys, without the keys themselves
+func (api *RelayAPI) handleAdminGetDataAPIKeys(w http.ResponseWriter, req *http.Request) {
+	entries, err := api.db.GetDataAPIKeys()
+	if err != nil {
+		api.log.WithError(err).Error("could not get data api keys")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get data api keys")
+		return
+	}
+	if entries == nil {
+		entries = []*database.DataAPIKeyEntry{}
+	}
+	api.RespondOK(w, entries)
+}
+
+// handleAdminIssueDataAPIKey issues a new data API key. The other API instances apply it on their next refresh.
+func (api *RelayAPI) handleAdminIssueDataAPIKey(w http.ResponseWriter, req *http.Request) {
+	payload := new(AdminDataAPIKeyRequest)
+	if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if payload.Tier == "" {
+		payload.Tier = DataAPIKeyTierStandard
+	}
+	if payload.Name == "" {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing name")
+		return
+	} else if _, _, ok := dataAPIKeyTierRateLimit(payload.Tier); !ok {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "unknown tier")
+		return
+	} else if payload.RateLimitPerSec < 0 || payload.RateLimitBurst < 0 {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "invalid rate limit")
+		return
+	}
+
+	log := api.adminLogger(req).WithFields(logrus.Fields{
+		"name": payload.Name,
+		"tier": payload.Tier,
+	})
+
+	apiKey, err := generateAPIKey()
+	if err != nil {
+		log.WithError(err).Error("could not generate data api key")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeInternal, "could not generate data api key")
+		return
+	}
+
+	entry, err := api.db.InsertDataAPIKey(database.DataAPIKeyEntry{ //nolint:exhaustruct
+		Name:            payload.Name,
+		KeyHash:         hashAPIKey(apiKey),
+		Tier:            payload.Tier,
+		RateLimitPerSec: payload.RateLimitPerSec,
+		RateLimitBurst:  payload.RateLimitBurst,
+	})
+	if err != nil {
+		log.WithError(err).Error("could not save data api key")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not save data api key")
+		return
+	}
+	log.Info("admin: issued data api key")
+	api.auditAdminChange(req, database.AdminAuditActionDataAPIKeyIssued, entry.Name, "", entry.Tier)
+	api.updateDataAPIKeys()
+
+	// the key is only ever returned here, only its hash is stored
+	api.RespondOK(w, struct {
+		*database.DataAPIKeyEntry
+		APIKey string `json:"api_key"`
+	}{
+		DataAPIKeyEntry: entry,
+		APIKey:          apiKey,
+	})
+}
+
+// handleAdminRevokeDataAPIKey revokes the active data API key with the name
+func (api *RelayAPI) handleAdminRevokeDataAPIKey(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	log := api.adminLogger(req).WithField("name", name)
+
+	err := api.db.RevokeDataAPIKey(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.RespondErrorWithCode(w, http.StatusNotFound, ErrorCodeUnknownDataAPIKey, "data api key not found")
+		return
+	} else if err != nil {
+		log.WithError(err).Error("could not revoke data api key")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not revoke data api key")
+		return
+	}
+	log.Info("admin: revoked data api key")
+	api.auditAdminChange(req, database.AdminAuditActionDataAPIKeyRevoked, name, "active", "revoked")
+	api.updateDataAPIKeys()
+	api.RespondOK(w, NilResponse)
+}
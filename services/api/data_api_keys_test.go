@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataAPIRateLimitMiddleware(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.setDataAPIKeys([]*database.DataAPIKeyEntry{
+		{ID: 1, Name: "standard", KeyHash: hashAPIKey("key1"), Tier: DataAPIKeyTierStandard, RateLimitPerSec: 1, RateLimitBurst: 2},
+		{ID: 2, Name: "partner", KeyHash: hashAPIKey("key2"), Tier: DataAPIKeyTierPartner, RateLimitPerSec: 1, RateLimitBurst: 4},
+	})
+	handler := backend.relay.dataAPIRateLimitMiddleware(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, pathDataStats, nil)
+		if apiKey != "" {
+			req.Header.Set(HeaderDataAPIKey, apiKey)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	// each key has its own quota
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, request("key1"))
+	}
+	require.Equal(t, http.StatusTooManyRequests, request("key1"))
+	for i := 0; i < 4; i++ {
+		require.Equal(t, http.StatusOK, request("key2"))
+	}
+	require.Equal(t, http.StatusTooManyRequests, request("key2"))
+
+	// anonymous requests are only limited per IP, unknown keys are rejected
+	require.Equal(t, http.StatusOK, request(""))
+	require.Equal(t, http.StatusUnauthorized, request("unknown"))
+
+	// keys staying active keep their limiter, revoked ones are rejected
+	backend.relay.setDataAPIKeys([]*database.DataAPIKeyEntry{
+		{ID: 1, Name: "standard", KeyHash: hashAPIKey("key1"), Tier: DataAPIKeyTierStandard, RateLimitPerSec: 1, RateLimitBurst: 2},
+	})
+	require.Equal(t, http.StatusTooManyRequests, request("key1"))
+	require.Equal(t, http.StatusUnauthorized, request("key2"))
+}
+
+func TestNewDataAPIKey(t *testing.T) {
+	key := newDataAPIKey(&database.DataAPIKeyEntry{Tier: DataAPIKeyTierPartner})
+	require.Equal(t, float64(dataAPIPartnerKeyRatePerSec), key.limiter.ratePerSec)
+	require.Equal(t, float64(dataAPIPartnerKeyBurst), key.limiter.burst)
+
+	key = newDataAPIKey(&database.DataAPIKeyEntry{Tier: DataAPIKeyTierStandard, RateLimitPerSec: 5})
+	require.Equal(t, float64(5), key.limiter.ratePerSec)
+	require.Equal(t, float64(dataAPIKeyBurst), key.limiter.burst)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	dataExportTypePayloadDelivered     = "payload_delivered"
+	dataExportTypeBuilderBlockReceived = "builder_block_received"
+
+	// flush the response every this many entries
+	dataExportFlushInterval = 1000
+)
+
+var (
+	// maximum slot range of a single export (default: 4 weeks)
+	dataExportMaxSlots = uint64(cli.GetEnvInt("DATA_EXPORT_MAX_SLOTS", 4*7*7200))
+
+	// maximum number of concurrent exports per instance, 0 for no maximum
+	dataExportMaxConcurrent = int64(cli.GetEnvInt("DATA_EXPORT_MAX_CONCURRENT", 2))
+)
+
+// handleDataExport streams all delivered payloads or builder submissions in ?slot_from=...&slot_to=... (inclusive) as
+// newline-delimited JSON, in slot order.
+func (api *RelayAPI) handleDataExport(w http.ResponseWriter, req *http.Request) {
+	args := req.URL.Query()
+
+	exportType := args.Get("type")
+	if exportType != dataExportTypePayloadDelivered && exportType != dataExportTypeBuilderBlockReceived {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, fmt.Sprintf("invalid type argument, must be %s or %s", dataExportTypePayloadDelivered, dataExportTypeBuilderBlockReceived))
+		return
+	}
+
+	slotFrom, err := strconv.ParseUint(args.Get("slot_from"), 10, 64)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid slot_from argument")
+		return
+	}
+	slotTo, err := strconv.ParseUint(args.Get("slot_to"), 10, 64)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid slot_to argument")
+		return
+	}
+	if slotTo < slotFrom {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "slot_to must not be before slot_from")
+		return
+	} else if slotTo-slotFrom >= dataExportMaxSlots {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, fmt.Sprintf("slot range too large, maximum is %d slots", dataExportMaxSlots))
+		return
+	}
+
+	numExports := api.numDataExports.Inc()
+	defer api.numDataExports.Dec()
+	if dataExportMaxConcurrent > 0 && numExports > dataExportMaxConcurrent {
+		api.RespondErrorWithCode(w, http.StatusServiceUnavailable, ErrorCodeTooManyStreams, "too many concurrent exports")
+		return
+	}
+
+	log := api.log.WithFields(logrus.Fields{
+		"method":   "dataExport",
+		"type":     exportType,
+		"slotFrom": slotFrom,
+		"slotTo":   slotTo,
+		"ip":       common.GetIPXForwardedFor(req),
+	})
+
+	// the export can take a while, so lift the server write timeout for this request
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.WithError(err).Error("could not disable write deadline")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeStreamingUnsupported, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	// once the first entry is written the status can't change anymore, so errors only end the stream early
+	numEntries := 0
+	enc := json.NewEncoder(w)
+	write := func(entry any) error {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+		numEntries++
+		if numEntries%dataExportFlushInterval == 0 {
+			return rc.Flush()
+		}
+		return nil
+	}
+
+	ctx := req.Context()
+	if exportType == dataExportTypePayloadDelivered {
+		err = api.db.StreamDeliveredPayloads(ctx, slotFrom, slotTo, func(entry *database.DeliveredPayloadEntry) error {
+			return write(database.DeliveredPayloadEntryToBidTraceV2WithTimingJSON(entry))
+		})
+	} else {
+		err = api.db.StreamBuilderSubmissions(ctx, slotFrom, slotTo, func(entry *database.BuilderBlockSubmissionEntry) error {
+			return write(database.BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(entry))
+		})
+	}
+	if err != nil {
+		log.WithError(err).WithField("numEntries", numEntries).Warn("data export aborted")
+		return
+	}
+
+	_ = rc.Flush()
+	log.WithField("numEntries", numEntries).Info("data export done")
+}
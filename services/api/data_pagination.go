@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+var ErrInvalidPageCursor = errors.New("invalid cursor")
+
+// DataPageResponse is the response of a paginated data API request. NextCursor is empty on the last page.
+type DataPageResponse struct {
+	Data       any    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// encodePageCursor returns the opaque cursor for the page after the given entry
+func encodePageCursor(slot uint64, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", slot, id)))
+}
+
+func decodePageCursor(cursor string) (*database.PageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+
+	slotStr, idStr, found := strings.Cut(string(b), ":")
+	if !found {
+		return nil, ErrInvalidPageCursor
+	}
+	slot, err := strconv.ParseUint(slotStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	return &database.PageCursor{Slot: slot, ID: id}, nil
+}
+
+// nextPageCursor returns the cursor for the next page, or an empty string if this was the last one
+func nextPageCursor(numEntries int, limit uint64, lastSlot uint64, lastID int64) string {
+	if uint64(numEntries) < limit {
+		return ""
+	}
+	return encodePageCursor(lastSlot, lastID)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+const (
+	dataStatsNumEpochs      = 225 // one day
+	dataStatsNumTopBuilders = 10
+)
+
+// DataStatsResponse holds the aggregates as refreshed periodically by the housekeeper: the builders and days of the
+// last 30 days, the latest epochs and the top builders of the last 7 days.
+type DataStatsResponse struct {
+	NumPayloadsDelivered uint64            `json:"num_payloads_delivered,string"`
+	Builders             []BuilderStats    `json:"builders"`
+	Days                 []DailyStats      `json:"days"`
+	Epochs               []EpochStats      `json:"epochs"`
+	TopBuilders          []TopBuilderStats `json:"top_builders"`
+}
+
+type BuilderStats struct {
+	BuilderPubkey      string  `json:"builder_pubkey"`
+	NumSubmissions     uint64  `json:"num_submissions,string"`
+	NumBlocksDelivered uint64  `json:"num_blocks_delivered,string"`
+	TotalValue         string  `json:"total_value"`
+	AverageValue       string  `json:"average_value"`
+	WinRate            float64 `json:"win_rate"`
+	SimErrorRate       float64 `json:"sim_error_rate"`
+}
+
+type DailyStats struct {
+	Day                string  `json:"day"`
+	NumSubmissions     uint64  `json:"num_submissions,string"`
+	NumBlocksDelivered uint64  `json:"num_blocks_delivered,string"`
+	NumBuilders        uint64  `json:"num_builders,string"`
+	TotalValue         string  `json:"total_value"`
+	AverageValue       string  `json:"average_value"`
+	SimErrorRate       float64 `json:"sim_error_rate"`
+}
+
+type EpochStats struct {
+	Epoch                uint64 `json:"epoch,string"`
+	NumPayloadsDelivered uint64 `json:"num_payloads_delivered,string"`
+	NumBuilders          uint64 `json:"num_builders,string"`
+	TotalValue           string `json:"total_value"`
+}
+
+type TopBuilderStats struct {
+	BuilderPubkey      string `json:"builder_pubkey"`
+	NumBlocksDelivered uint64 `json:"num_blocks_delivered,string"`
+	TotalValue         string `json:"total_value"`
+}
+
+// averageValue returns the total value in wei divided by the number of blocks, rounded down
+func averageValue(totalValue string, numBlocks uint64) string {
+	total, ok := new(big.Int).SetString(totalValue, 10)
+	if !ok || numBlocks == 0 {
+		return "0"
+	}
+	return total.Div(total, new(big.Int).SetUint64(numBlocks)).String()
+}
+
+// rate returns n/total, or 0 if there is nothing to compare against
+func rate(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// builderStatsFromEntry computes the averages and rates of a stats row. The win rate is the share of slots the builder
+// submitted a valid block for that ended up with one of its blocks delivered.
+func builderStatsFromEntry(entry *database.BuilderStatsEntry) BuilderStats {
+	return BuilderStats{
+		BuilderPubkey:      entry.BuilderPubkey,
+		NumSubmissions:     entry.NumSubmissions,
+		NumBlocksDelivered: entry.NumBlocksDelivered,
+		TotalValue:         entry.TotalValue,
+		AverageValue:       averageValue(entry.TotalValue, entry.NumBlocksDelivered),
+		WinRate:            rate(entry.NumBlocksDelivered, entry.NumSlotsSubmitted),
+		SimErrorRate:       rate(entry.NumSimErrors, entry.NumSubmissions),
+	}
+}
+
+func dailyStatsFromEntry(entry *database.DailyStatsEntry) DailyStats {
+	return DailyStats{
+		Day:                entry.Day.UTC().Format("2006-01-02"),
+		NumSubmissions:     entry.NumSubmissions,
+		NumBlocksDelivered: entry.NumBlocksDelivered,
+		NumBuilders:        entry.NumBuilders,
+		TotalValue:         entry.TotalValue,
+		AverageValue:       averageValue(entry.TotalValue, entry.NumBlocksDelivered),
+		SimErrorRate:       rate(entry.NumSimErrors, entry.NumSubmissions),
+	}
+}
+
+func (api *RelayAPI) handleDataStats(w http.ResponseWriter, req *http.Request) {
+	builderEntries, err := api.db.GetBuilderStats()
+	if err != nil {
+		api.log.WithError(err).Error("error getting builder stats")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get builder stats")
+		return
+	}
+
+	dailyEntries, err := api.db.GetDailyStats()
+	if err != nil {
+		api.log.WithError(err).Error("error getting daily stats")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get daily stats")
+		return
+	}
+
+	epochEntries, err := api.db.GetEpochStats(dataStatsNumEpochs)
+	if err != nil {
+		api.log.WithError(err).Error("error getting epoch stats")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get epoch stats")
+		return
+	}
+
+	topBuilderEntries, err := api.db.GetTopBuilders(dataStatsNumTopBuilders)
+	if err != nil {
+		api.log.WithError(err).Error("error getting top builders")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get top builders")
+		return
+	}
+
+	numPayloadsDelivered, err := api.db.GetNumDeliveredPayloads()
+	if err != nil {
+		api.log.WithError(err).Error("error getting number of delivered payloads")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get number of delivered payloads")
+		return
+	}
+
+	response := DataStatsResponse{
+		NumPayloadsDelivered: numPayloadsDelivered,
+		Builders:             make([]BuilderStats, len(builderEntries)),
+		Days:                 make([]DailyStats, len(dailyEntries)),
+		Epochs:               make([]EpochStats, len(epochEntries)),
+		TopBuilders:          make([]TopBuilderStats, len(topBuilderEntries)),
+	}
+	for i, entry := range builderEntries {
+		response.Builders[i] = builderStatsFromEntry(entry)
+	}
+	for i, entry := range dailyEntries {
+		response.Days[i] = dailyStatsFromEntry(entry)
+	}
+	for i, entry := range epochEntries {
+		response.Epochs[i] = EpochStats{
+			Epoch:                entry.Epoch,
+			NumPayloadsDelivered: entry.NumPayloadsDelivered,
+			NumBuilders:          entry.NumBuilders,
+			TotalValue:           entry.TotalValue,
+		}
+	}
+	for i, entry := range topBuilderEntries {
+		response.TopBuilders[i] = TopBuilderStats{
+			BuilderPubkey:      entry.BuilderPubkey,
+			NumBlocksDelivered: entry.NumBlocksDelivered,
+			TotalValue:         entry.TotalValue,
+		}
+	}
+	api.RespondOK(w, response)
+}
+
+// BuilderScore is the performance of a builder over the window of the builder stats, as refreshed periodically by the
+// housekeeper
+type BuilderScore struct {
+	BuilderPubkey          string  `json:"builder_pubkey"`
+	NumSubmissions         uint64  `json:"num_submissions,string"`
+	NumSlotsSubmitted      uint64  `json:"num_slots_submitted,string"`
+	NumSlotsWon            uint64  `json:"num_slots_won,string"`
+	WinRate                float64 `json:"win_rate"`
+	AvgTopBidGap           string  `json:"avg_top_bid_gap"`
+	SimErrorRate           float64 `json:"sim_error_rate"`
+	AvgSubmissionLatencyMs int64   `json:"avg_submission_latency_ms,string"`
+}
+
+func builderScoreFromEntry(entry *database.BuilderStatsEntry) BuilderScore {
+	return BuilderScore{
+		BuilderPubkey:          entry.BuilderPubkey,
+		NumSubmissions:         entry.NumSubmissions,
+		NumSlotsSubmitted:      entry.NumSlotsSubmitted,
+		NumSlotsWon:            entry.NumBlocksDelivered,
+		WinRate:                rate(entry.NumBlocksDelivered, entry.NumSlotsSubmitted),
+		AvgTopBidGap:           entry.AvgTopBidGap,
+		SimErrorRate:           rate(entry.NumSimErrors, entry.NumSubmissions),
+		AvgSubmissionLatencyMs: entry.AvgSubmissionLatencyMs,
+	}
+}
+
+func (api *RelayAPI) handleDataBuilderScoreboard(w http.ResponseWriter, req *http.Request) {
+	entries, err := api.db.GetBuilderStats()
+	if err != nil {
+		api.log.WithError(err).Error("error getting builder scoreboard")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get builder scoreboard")
+		return
+	}
+
+	response := make([]BuilderScore, len(entries))
+	for i, entry := range entries {
+		response[i] = builderScoreFromEntry(entry)
+	}
+	api.RespondOK(w, response)
+}
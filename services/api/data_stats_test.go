@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderStatsFromEntry(t *testing.T) {
+	stats := builderStatsFromEntry(&database.BuilderStatsEntry{
+		BuilderPubkey:      "0xabc",
+		NumSubmissions:     100,
+		NumSimErrors:       5,
+		NumSlotsSubmitted:  40,
+		NumBlocksDelivered: 10,
+		TotalValue:         "1000000000000000001",
+	})
+	require.Equal(t, "100000000000000000", stats.AverageValue)
+	require.Equal(t, 0.25, stats.WinRate)
+	require.Equal(t, 0.05, stats.SimErrorRate)
+
+	// builder without delivered blocks or valid submissions
+	stats = builderStatsFromEntry(&database.BuilderStatsEntry{
+		BuilderPubkey:  "0xabc",
+		NumSubmissions: 3,
+		NumSimErrors:   3,
+		TotalValue:     "0",
+	})
+	require.Equal(t, "0", stats.AverageValue)
+	require.Equal(t, float64(0), stats.WinRate)
+	require.Equal(t, float64(1), stats.SimErrorRate)
+}
+
+func TestDailyStatsFromEntry(t *testing.T) {
+	stats := dailyStatsFromEntry(&database.DailyStatsEntry{
+		Day:                time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		NumBlocksDelivered: 3,
+		TotalValue:         "10",
+	})
+	require.Equal(t, "2023-03-01", stats.Day)
+	require.Equal(t, "3", stats.AverageValue)
+	require.Equal(t, float64(0), stats.SimErrorRate)
+}
+
+func TestBuilderScoreFromEntry(t *testing.T) {
+	score := builderScoreFromEntry(&database.BuilderStatsEntry{
+		BuilderPubkey:          "0xabc",
+		NumSubmissions:         200,
+		NumSimErrors:           2,
+		NumSlotsSubmitted:      50,
+		NumBlocksDelivered:     5,
+		AvgTopBidGap:           "12345",
+		AvgSubmissionLatencyMs: 10_500,
+	})
+	require.Equal(t, uint64(5), score.NumSlotsWon)
+	require.Equal(t, 0.1, score.WinRate)
+	require.Equal(t, 0.01, score.SimErrorRate)
+	require.Equal(t, "12345", score.AvgTopBidGap)
+	require.Equal(t, int64(10_500), score.AvgSubmissionLatencyMs)
+}
+
+func TestDataStats(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	rr := backend.request(http.MethodGet, pathDataStats, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	resp := DataStatsResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Epochs)
+	require.NotNil(t, resp.TopBuilders)
+	require.Equal(t, uint64(0), resp.NumPayloadsDelivered)
+}
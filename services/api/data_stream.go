@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// maximum number of concurrent data stream subscribers per instance, and per client IP, 0 for no maximum
+	dataStreamMaxSubscribers      = cli.GetEnvInt("DATA_STREAM_MAX_SUBSCRIBERS", 500)
+	dataStreamMaxSubscribersPerIP = cli.GetEnvInt("DATA_STREAM_MAX_SUBSCRIBERS_PER_IP", 5)
+)
+
+// handleDataStream streams delivered payloads and accepted builder submissions as server-sent events. The event types
+// can be limited with ?types=payload_delivered,builder_block_received
+func (api *RelayAPI) handleDataStream(w http.ResponseWriter, req *http.Request) {
+	eventTypes := make(map[string]bool)
+	if types := req.URL.Query().Get("types"); types != "" {
+		for _, eventType := range strings.Split(types, ",") {
+			if eventType != datastore.DataStreamEventPayloadDelivered && eventType != datastore.DataStreamEventBuilderBlockReceived {
+				api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, fmt.Sprintf("invalid event type: %s", eventType))
+				return
+			}
+			eventTypes[eventType] = true
+		}
+	}
+
+	ip := rateLimitIP(req, rateLimitTrustedProxies)
+	log := api.log.WithFields(logrus.Fields{
+		"method": "dataStream",
+		"ip":     ip,
+	})
+
+	events, err := api.dataStream.subscribeLimited(ip, dataStreamMaxSubscribers, dataStreamMaxSubscribersPerIP)
+	if errors.Is(err, ErrTooManySubscribersForKey) {
+		log.Info("too many data stream subscribers from this ip")
+		api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeTooManyStreams, err.Error())
+		return
+	} else if err != nil {
+		api.RespondErrorWithCode(w, http.StatusServiceUnavailable, ErrorCodeTooManyStreams, err.Error())
+		return
+	}
+	defer api.dataStream.unsubscribe(events)
+
+	rc, ok := api.startEventStream(w, log)
+	if !ok {
+		return
+	}
+	log.WithField("numSubscribers", api.dataStream.numSubscribers()).Info("subscribed to data stream")
+
+	keepalive := time.NewTicker(eventStreamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			log.Info("unsubscribed from data stream")
+			return
+		case <-api.shutdownCtx.Done():
+			log.Info("closing data stream, shutting down")
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			if len(eventTypes) > 0 && !eventTypes[event.Type] {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// publishEvent publishes the event to the event bus, if enabled
+func (api *RelayAPI) publishEvent(eventType string, data any) {
+	if api.opts.EventBus != nil {
+		api.opts.EventBus.Publish(eventType, data)
+	}
+}
+
+// publishDataStreamEvent publishes the event to the data stream subscribers of all instances
+func (api *RelayAPI) publishDataStreamEvent(log *logrus.Entry, eventType string, data any) {
+	if err := api.redis.PublishDataStreamEvent(eventType, data); err != nil {
+		log.WithError(err).Error("failed to publish data stream event")
+	}
+}
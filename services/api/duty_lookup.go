@@ -0,0 +1,109 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+// dutyLookup holds the proposer duties of the current and next epoch in memory, by slot and by the pubkey of the
+// proposer, so the hot paths never query redis for them. The tables are swapped as a whole when the duties are
+// refreshed from redis.
+type dutyLookup struct {
+	lock      sync.RWMutex
+	response  []boostTypes.BuilderGetValidatorsResponseEntry
+	bySlot    map[uint64]*boostTypes.RegisterValidatorRequestMessage
+	byPubkey  map[boostTypes.PubkeyHex]*boostTypes.SignedValidatorRegistration
+	headSlot  uint64    // head slot at the latest refresh
+	updatedAt time.Time // zero until the first refresh
+	network   string    // label of the metrics
+}
+
+func newDutyLookup(network string) *dutyLookup {
+	return &dutyLookup{
+		network:  network,
+		response: []boostTypes.BuilderGetValidatorsResponseEntry{},
+		bySlot:   make(map[uint64]*boostTypes.RegisterValidatorRequestMessage),
+		byPubkey: make(map[boostTypes.PubkeyHex]*boostTypes.SignedValidatorRegistration),
+	}
+}
+
+// set replaces the tables with the duties
+func (d *dutyLookup) set(duties []boostTypes.BuilderGetValidatorsResponseEntry, headSlot uint64) {
+	bySlot := make(map[uint64]*boostTypes.RegisterValidatorRequestMessage, len(duties))
+	byPubkey := make(map[boostTypes.PubkeyHex]*boostTypes.SignedValidatorRegistration, len(duties))
+	for _, duty := range duties {
+		if duty.Entry == nil || duty.Entry.Message == nil {
+			continue
+		}
+		bySlot[duty.Slot] = duty.Entry.Message
+		byPubkey[duty.Entry.Message.Pubkey.PubkeyHex()] = duty.Entry
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.response = duties
+	d.bySlot = bySlot
+	d.byPubkey = byPubkey
+	d.headSlot = headSlot
+	d.updatedAt = time.Now()
+}
+
+// lastUpdate returns the head slot and time of the latest refresh
+func (d *dutyLookup) lastUpdate() (headSlot uint64, updatedAt time.Time) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.headSlot, d.updatedAt
+}
+
+// duties returns the duties as served to the builders, which must not be modified
+func (d *dutyLookup) duties() []boostTypes.BuilderGetValidatorsResponseEntry {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.response
+}
+
+// dutyForSlot returns the registration of the proposer of the slot, or nil if the duty isn't known
+func (d *dutyLookup) dutyForSlot(slot uint64) *boostTypes.RegisterValidatorRequestMessage {
+	d.lock.RLock()
+	duty := d.bySlot[slot]
+	d.lock.RUnlock()
+
+	d.observeLookup("slot", duty != nil)
+	return duty
+}
+
+// registrationForPubkey returns the registration of an upcoming proposer, or nil if the validator has no duty in the
+// current or next epoch
+func (d *dutyLookup) registrationForPubkey(pubkey string) *boostTypes.SignedValidatorRegistration {
+	d.lock.RLock()
+	registration := d.byPubkey[boostTypes.NewPubkeyHex(pubkey)]
+	d.lock.RUnlock()
+
+	d.observeLookup("pubkey", registration != nil)
+	return registration
+}
+
+func (d *dutyLookup) observeLookup(table string, found bool) {
+	result := "hit"
+	if !found {
+		result = "miss"
+	}
+	common.DutyLookupTotal.WithLabelValues(d.network, table, result).Inc()
+}
+
+// observeStaleness records how far the tables are behind the head slot, once per slot
+func (d *dutyLookup) observeStaleness(headSlot uint64) {
+	updatedSlot, updatedAt := d.lastUpdate()
+	if updatedAt.IsZero() {
+		return
+	}
+	if headSlot > updatedSlot {
+		common.DutyLookupStalenessSlots.WithLabelValues(d.network).Set(float64(headSlot - updatedSlot))
+	} else {
+		common.DutyLookupStalenessSlots.WithLabelValues(d.network).Set(0)
+	}
+	common.DutyLookupAge.WithLabelValues(d.network).Set(time.Since(updatedAt).Seconds())
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDutyLookup(t *testing.T) {
+	d := newDutyLookup("")
+	require.Nil(t, d.dutyForSlot(11))
+	d.observeStaleness(10) // not refreshed yet
+
+	registration := common.ValidPayloadRegisterValidator
+	d.set([]types.BuilderGetValidatorsResponseEntry{
+		{Slot: 11, Entry: &registration},
+		{Slot: 12, Entry: nil}, // skipped
+	}, 10)
+	require.Len(t, d.duties(), 2)
+	require.Equal(t, registration.Message, d.dutyForSlot(11))
+	require.Nil(t, d.dutyForSlot(12))
+
+	// by pubkey, in any case
+	pubkey := registration.Message.Pubkey.String()
+	require.Equal(t, &registration, d.registrationForPubkey(pubkey))
+	require.Equal(t, &registration, d.registrationForPubkey("0x"+strings.ToUpper(pubkey[2:])))
+	require.Nil(t, d.registrationForPubkey("0x01"))
+
+	headSlot, updatedAt := d.lastUpdate()
+	require.Equal(t, uint64(10), headSlot)
+	require.False(t, updatedAt.IsZero())
+}
+
+func TestDutyLookupHandlers(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	registration := common.ValidPayloadRegisterValidator
+	backend.relay.proposerDuties.set([]types.BuilderGetValidatorsResponseEntry{{Slot: 1, Entry: &registration}}, 0)
+
+	// getHeader of another validator is answered without looking for bids
+	otherPubkey := "0x" + strings.Repeat("ab", 48)
+	rr := backend.request(http.MethodGet, "/eth/v1/builder/header/1/0x"+strings.Repeat("01", 32)+"/"+otherPubkey, nil)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	getRegistration := func() *types.SignedValidatorRegistration {
+		rr := backend.request(http.MethodGet, pathDataValidatorRegistration+"?pubkey="+registration.Message.Pubkey.String(), nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		resp := new(types.SignedValidatorRegistration)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		return resp
+	}
+
+	// the database has the re-registration of the upcoming proposer
+	reregistration := registration
+	reregistration.Message = &types.RegisterValidatorRequestMessage{Pubkey: registration.Message.Pubkey, FeeRecipient: types.Address{0x02}, Timestamp: registration.Message.Timestamp + 1}
+	db := &registrationDB{entry: database.SignedValidatorRegistrationToEntry(reregistration)}
+	backend.relay.db = db
+	require.Equal(t, reregistration.Message.FeeRecipient, getRegistration().Message.FeeRecipient)
+
+	// the registration of the duty is only served without the database
+	db.err = sql.ErrConnDone
+	require.Equal(t, registration.Message.FeeRecipient, getRegistration().Message.FeeRecipient)
+}
+
+// registrationDB returns the registration entry, or fails with err
+type registrationDB struct {
+	database.MockDB
+	entry database.ValidatorRegistrationEntry
+	err   error
+}
+
+func (db *registrationDB) GetValidatorRegistration(pubkey string) (*database.ValidatorRegistrationEntry, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	return &db.entry, nil
+}
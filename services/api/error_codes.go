@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error codes of the API error responses, returned in the error_code field next to the human-readable message. The
+// codes are stable, clients should branch on them instead of on the messages, which may change. Rejected builder
+// submissions have the more specific SubmissionErr* codes.
+const (
+	// Generic codes, used by status when no specific code applies
+	ErrorCodeBadRequest      = "bad_request"
+	ErrorCodeUnauthorized    = "unauthorized"
+	ErrorCodeNotFound        = "not_found"
+	ErrorCodeRequestTooLarge = "request_too_large"
+	ErrorCodeRateLimited     = "rate_limited"
+	ErrorCodeInternal        = "internal_error"
+	ErrorCodeUnavailable     = "unavailable"
+	ErrorCodeTimeout         = "timeout"
+
+	// Internal errors, the responses don't include the errors, which are logged instead
+	ErrorCodeDatabase             = "database_error"
+	ErrorCodeRedis                = "redis_error"
+	ErrorCodeDatastore            = "datastore_error"  // several stores are involved (redis, memcached, the database)
+	ErrorCodeBidBuildFailed       = "bid_build_failed" // the bid or payload response of a submission can't be built
+	ErrorCodeStreamingUnsupported = "streaming_unsupported"
+
+	// Requests
+	ErrorCodeInvalidRequest   = "invalid_request"  // the body can't be read or decoded, or misses fields
+	ErrorCodeInvalidArgument  = "invalid_argument" // a query or path argument is invalid
+	ErrorCodeInvalidSignature = "invalid_signature"
+	ErrorCodeInvalidAPIKey    = "invalid_api_key"
+	ErrorCodeUnsupportedFork  = "unsupported_fork" // the payload is of a fork the relay doesn't support at the slot
+	ErrorCodeSlotTooOld       = "slot_too_old"
+	ErrorCodeShuttingDown     = "shutting_down"
+	ErrorCodeTooManyStreams   = "too_many_streams" // the maximum of concurrent streams or exports is reached
+
+	// Admin and internal API
+	ErrorCodeUnknownFeatureFlag = "unknown_feature_flag"
+	ErrorCodeUnknownDataAPIKey  = "unknown_data_api_key"
+
+	// Proposer API
+	ErrorCodeUnknownValidator     = "unknown_validator"
+	ErrorCodeValidatorNotServed   = "validator_not_served" // not on the proposer allowlist
+	ErrorCodeRegistrationTooNew   = "registration_timestamp_in_future"
+	ErrorCodeNoRegistration       = "no_registration"
+	ErrorCodeNoProposerDuty       = "no_proposer_duty"
+	ErrorCodeNotSlotProposer      = "not_slot_proposer"
+	ErrorCodeGetPayloadTooLate    = "get_payload_too_late"
+	ErrorCodeProposerEquivocation = "proposer_equivocation"
+	ErrorCodePayloadNotFound      = "payload_not_found"
+	ErrorCodePayloadUnavailable   = "payload_unavailable" // shadow mode, the relay never delivers payloads
+	ErrorCodeInvalidConstraints   = "invalid_inclusion_constraints"
+
+	// Builder API
+	ErrorCodeUnknownBuilder         = "unknown_builder"
+	ErrorCodeNewerSubmission        = "newer_submission_exists"
+	ErrorCodeTooManySubmissions     = "too_many_submissions"
+	ErrorCodeSubmissionNotAccepted  = "submission_type_not_accepted" // e.g. header-only submissions without collateral
+	ErrorCodeSubmissionSanityFailed = "submission_sanity_check_failed"
+	ErrorCodeHeaderBidMismatch      = "header_bid_mismatch" // the bid of a header submission doesn't match the header
+	ErrorCodeZeroValueBid           = "zero_value_bid"
+	ErrorCodeUnknownPeerRelay       = "unknown_peer_relay"
+)
+
+// errorCodeForStatus returns the generic code of a response status
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorCodeUnauthorized
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusRequestEntityTooLarge:
+		return ErrorCodeRequestTooLarge
+	case http.StatusTooManyRequests:
+		return ErrorCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrorCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrorCodeTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrorCodeInternal
+	}
+	return ErrorCodeBadRequest
+}
+
+// registrationErrorCode returns the code of a rejected validator registration
+func registrationErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrUnknownValidator):
+		return ErrorCodeUnknownValidator
+	case errors.Is(err, ErrValidatorNotServed):
+		return ErrorCodeValidatorNotServed
+	case errors.Is(err, ErrInvalidRegistrationSignature):
+		return ErrorCodeInvalidSignature
+	}
+	return ErrorCodeInvalidRequest
+}
+
+// headerBidErrorCode returns the code of a header submission or peer bid rejected by checkHeaderBid
+func headerBidErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrHeaderBidBeforeCapella), errors.Is(err, ErrDenebNotSupported):
+		return ErrorCodeUnsupportedFork
+	case errors.Is(err, ErrHeaderBidHashMismatch), errors.Is(err, ErrHeaderBidGasMismatch):
+		return ErrorCodeHeaderBidMismatch
+	case errors.Is(err, ErrHeaderBidZeroValue):
+		return ErrorCodeZeroValueBid
+	}
+	return ErrorCodeInvalidRequest
+}
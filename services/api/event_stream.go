@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrTooManySubscribers       = errors.New("too many subscribers")
+	ErrTooManySubscribersForKey = errors.New("too many subscribers from this client")
+
+	eventStreamKeepaliveInterval = 15 * time.Second
+	eventStreamSubscriberBuffer  = 16
+)
+
+// broadcaster fans out updates from redis to all connected stream subscribers
+type broadcaster[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan T]string // the key the subscriber is limited by, e.g. its IP
+	numByKey    map[string]int
+}
+
+func newBroadcaster[T any]() *broadcaster[T] {
+	return &broadcaster[T]{
+		subscribers: make(map[chan T]string),
+		numByKey:    make(map[string]int),
+	}
+}
+
+func (b *broadcaster[T]) subscribe() chan T {
+	c, _ := b.subscribeLimited("", 0, 0)
+	return c
+}
+
+// subscribeLimited subscribes unless there are maxSubscribers already, or maxPerKey subscribers with the same key (0 for
+// no maximum). The limits are checked under the same lock as the subscription, so concurrent subscribers can't exceed
+// them.
+func (b *broadcaster[T]) subscribeLimited(key string, maxSubscribers, maxPerKey int) (chan T, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if maxSubscribers > 0 && len(b.subscribers) >= maxSubscribers {
+		return nil, ErrTooManySubscribers
+	} else if maxPerKey > 0 && b.numByKey[key] >= maxPerKey {
+		return nil, ErrTooManySubscribersForKey
+	}
+	c := make(chan T, eventStreamSubscriberBuffer)
+	b.subscribers[c] = key
+	b.numByKey[key]++
+	return c, nil
+}
+
+func (b *broadcaster[T]) unsubscribe(c chan T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key, ok := b.subscribers[c]
+	if !ok {
+		return
+	}
+	delete(b.subscribers, c)
+	if b.numByKey[key]--; b.numByKey[key] == 0 {
+		delete(b.numByKey, key)
+	}
+}
+
+func (b *broadcaster[T]) numSubscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// broadcast sends the update to all subscribers. Slow subscribers skip updates instead of blocking everyone else.
+func (b *broadcaster[T]) broadcast(update T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.subscribers {
+		select {
+		case c <- update:
+		default:
+		}
+	}
+}
+
+// run forwards the updates from redis, blocking until the updates channel is closed
+func (b *broadcaster[T]) run(updates <-chan T) {
+	for update := range updates {
+		b.broadcast(update)
+	}
+}
+
+// startEventStream sends the headers of a server-sent events response. Returns false if the response was already
+// written because streaming isn't possible.
+func (api *RelayAPI) startEventStream(w http.ResponseWriter, log *logrus.Entry) (*http.ResponseController, bool) {
+	// the stream is long-lived, so lift the server write timeout for this request
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.WithError(err).Error("could not disable write deadline")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeStreamingUnsupported, "streaming not supported")
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.WithError(err).Error("could not flush stream")
+		return nil, false
+	}
+	return rc, true
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	uberatomic "go.uber.org/atomic"
+)
+
+// Feature flags which can be changed at runtime through the admin API, for all instances
+const (
+	FeatureFlagForceGetHeader204      = "force-get-header-204"
+	FeatureFlagDisableBlockPublishing = "disable-block-publishing"
+	FeatureFlagDisableLowPrioBuilders = "disable-lowprio-builders"
+	FeatureFlagEnableOptimistic       = "enable-optimistic"
+	FeatureFlagMinBid                 = "min-bid" // in wei, 0 disables it
+)
+
+var (
+	ErrUnknownFeatureFlag      = errors.New("unknown feature flag")
+	ErrInvalidFeatureFlagValue = errors.New("invalid feature flag value")
+
+	// featureFlagReloadInterval is how often the feature flags are reloaded from redis, in case an update was missed
+	featureFlagReloadInterval = time.Minute
+)
+
+func (api *RelayAPI) boolFeatureFlags() map[string]*uberatomic.Bool {
+	return map[string]*uberatomic.Bool{
+		FeatureFlagForceGetHeader204:      &api.ffForceGetHeader204,
+		FeatureFlagDisableBlockPublishing: &api.ffDisableBlockPublishing,
+		FeatureFlagDisableLowPrioBuilders: &api.ffDisableLowPrioBuilders,
+		FeatureFlagEnableOptimistic:       &api.ffEnableOptimistic,
+	}
+}
+
+// getFeatureFlags returns the current values of the runtime feature flags
+func (api *RelayAPI) getFeatureFlags() map[string]string {
+	flags := make(map[string]string)
+	for name, flag := range api.boolFeatureFlags() {
+		flags[name] = strconv.FormatBool(flag.Load())
+	}
+	flags[FeatureFlagMinBid] = "0"
+	if minBid := api.ffMinBidValue.Load(); minBid != nil {
+		flags[FeatureFlagMinBid] = minBid.String()
+	}
+	return flags
+}
+
+// isBelowMinBid returns whether the value is below the minimum bid, if one is set
+func (api *RelayAPI) isBelowMinBid(value *big.Int) bool {
+	minBid := api.ffMinBidValue.Load()
+	return minBid != nil && value.Cmp(minBid) < 0
+}
+
+// setFeatureFlag applies the value of a runtime feature flag, an empty value resets it to the default from the
+// environment
+func (api *RelayAPI) setFeatureFlag(name, value string) error {
+	if value == "" {
+		defaultValue, found := api.featureFlagDefaults[name]
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUnknownFeatureFlag, name)
+		}
+		value = defaultValue
+	}
+
+	if name == FeatureFlagMinBid {
+		minBid, ok := new(big.Int).SetString(value, 10)
+		if !ok || minBid.Sign() < 0 {
+			return fmt.Errorf("%w: %s must be an amount in wei", ErrInvalidFeatureFlagValue, name)
+		}
+		if minBid.Sign() == 0 {
+			minBid = nil
+		}
+		api.ffMinBidValue.Store(minBid)
+		return nil
+	}
+
+	flag, found := api.boolFeatureFlags()[name]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownFeatureFlag, name)
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%w: %s must be true or false", ErrInvalidFeatureFlagValue, name)
+	}
+	flag.Store(enabled)
+	return nil
+}
+
+// loadFeatureFlags applies the feature flags set through the admin API, and resets the others to their defaults
+func (api *RelayAPI) loadFeatureFlags() {
+	overrides, err := api.redis.GetFeatureFlags()
+	if err != nil {
+		api.log.WithError(err).Error("failed to get feature flags from redis")
+		return
+	}
+
+	for name := range api.featureFlagDefaults {
+		if err := api.setFeatureFlag(name, overrides[name]); err != nil {
+			api.log.WithError(err).Error("failed to apply feature flag from redis")
+		}
+	}
+}
+
+// startFeatureFlagUpdates applies the feature flags set through the admin API of any instance, and reloads them
+// periodically
+func (api *RelayAPI) startFeatureFlagUpdates() {
+	updates := api.redis.SubscribeFeatureFlagUpdates(api.shutdownCtx)
+	ticker := time.NewTicker(featureFlagReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				if api.shutdownCtx.Err() != nil {
+					return
+				}
+				api.log.Error("feature flag subscription closed, only reloading periodically")
+				updates = nil
+				continue
+			}
+			if err := api.setFeatureFlag(update.Name, update.Value); err != nil {
+				api.log.WithError(err).Error("failed to apply feature flag update")
+				continue
+			}
+			api.log.WithField("value", update.Value).Warnf("feature flag %s updated", update.Name)
+		case <-ticker.C:
+			api.loadFeatureFlags()
+		}
+	}
+}
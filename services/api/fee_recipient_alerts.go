@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// comma-separated fee recipients validators are expected to rotate to, e.g. the addresses of a staking pool
+var feeRecipientAlertsAllowlistEnv = os.Getenv("FEE_RECIPIENT_ALERTS_ALLOWLIST")
+
+// parseFeeRecipientAllowlist parses the comma-separated fee recipients, returned lowercase
+func parseFeeRecipientAllowlist(s string) (map[string]bool, error) {
+	allowlist := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.HasPrefix(entry, "0x") || !ethcommon.IsHexAddress(entry) {
+			return nil, fmt.Errorf("invalid fee recipient: %s", entry) //nolint:goerr113
+		}
+		allowlist[strings.ToLower(entry)] = true
+	}
+	return allowlist, nil
+}
+
+// checkFeeRecipientChanges alerts on the registrations changing the fee recipient of a validator to an address it never
+// registered before, which can mean its keys were stolen. The first registration of a validator isn't a change, and
+// the changes to allowlisted fee recipients are expected rotations. Runs before the registrations are saved.
+func (api *RelayAPI) checkFeeRecipientChanges(registrations []boostTypes.SignedValidatorRegistration) {
+	pubkeys := make([]string, 0, len(registrations))
+	for _, registration := range registrations {
+		pubkeys = append(pubkeys, registration.Message.Pubkey.String())
+	}
+	knownFeeRecipients, err := api.db.GetValidatorFeeRecipients(pubkeys)
+	if err != nil {
+		api.log.WithError(err).Error("could not get the fee recipients of the validators")
+		return
+	}
+
+	for _, registration := range registrations {
+		pubkey := registration.Message.Pubkey.String()
+		feeRecipient := strings.ToLower(registration.Message.FeeRecipient.String())
+		known := knownFeeRecipients[pubkey]
+		if len(known) == 0 || containsFold(known, feeRecipient) {
+			continue
+		}
+		// several registrations of the validator in the batch only alert once
+		knownFeeRecipients[pubkey] = append(known, feeRecipient)
+
+		allowlisted := api.feeRecipientAlertsAllowlist[feeRecipient]
+		common.FeeRecipientChangesTotal.WithLabelValues(api.networkName(), fmt.Sprint(allowlisted)).Inc()
+		log := api.log.WithFields(logrus.Fields{
+			"pubkey":                pubkey,
+			"feeRecipient":          feeRecipient,
+			"previousFeeRecipients": strings.Join(known, ","),
+		})
+		if allowlisted {
+			log.Info("validator changed to an allowlisted fee recipient")
+			continue
+		}
+
+		log.Warn("validator changed to a new fee recipient")
+		api.notifyWebhooks(common.EventFeeRecipientChanged, fmt.Sprintf("relay: validator %s changed to the new fee recipient %s", pubkey, feeRecipient), map[string]string{
+			"pubkey":                  pubkey,
+			"fee_recipient":           feeRecipient,
+			"previous_fee_recipients": strings.Join(known, ","),
+		})
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+// feeRecipientsDB returns fixed fee recipients of the validators
+type feeRecipientsDB struct {
+	database.MockDB
+	feeRecipients map[string][]string
+}
+
+func (db feeRecipientsDB) GetValidatorFeeRecipients(pubkeys []string) (map[string][]string, error) {
+	feeRecipients := make(map[string][]string)
+	for _, pubkey := range pubkeys {
+		if known, ok := db.feeRecipients[pubkey]; ok {
+			feeRecipients[pubkey] = append([]string{}, known...)
+		}
+	}
+	return feeRecipients, nil
+}
+
+func TestParseFeeRecipientAllowlist(t *testing.T) {
+	allowlist, err := parseFeeRecipientAllowlist("")
+	require.NoError(t, err)
+	require.Len(t, allowlist, 0)
+
+	allowlist, err = parseFeeRecipientAllowlist("0xFFBB8996515293fcd87ca09b5c6ffe5c17f043c6, 0xffbb8996515293fcd87ca09b5c6ffe5c17f043c7")
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"0xffbb8996515293fcd87ca09b5c6ffe5c17f043c6": true, "0xffbb8996515293fcd87ca09b5c6ffe5c17f043c7": true}, allowlist)
+
+	_, err = parseFeeRecipientAllowlist("0x1234")
+	require.Error(t, err)
+}
+
+func TestCheckFeeRecipientChanges(t *testing.T) {
+	var lock sync.Mutex
+	notifications := []webhook.Notification{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var notification webhook.Notification
+		_ = json.NewDecoder(req.Body).Decode(&notification)
+		lock.Lock()
+		defer lock.Unlock()
+		notifications = append(notifications, notification)
+	}))
+	defer srv.Close()
+	notifier, err := webhook.NewNotifier(common.TestLog, webhook.Opts{URLs: []string{srv.URL}, Timeout: time.Second}) //nolint:exhaustruct
+	require.NoError(t, err)
+
+	backend := newTestBackend(t, 1)
+	backend.relay.opts.Webhooks = notifier
+	backend.relay.feeRecipientAlertsAllowlist = map[string]bool{"0x0000000000000000000000000000000000000003": true}
+
+	registration := func(pubkey, feeRecipient byte) boostTypes.SignedValidatorRegistration {
+		reg := boostTypes.SignedValidatorRegistration{Message: &boostTypes.RegisterValidatorRequestMessage{}} //nolint:exhaustruct
+		reg.Message.Pubkey[0] = pubkey
+		reg.Message.FeeRecipient[19] = feeRecipient
+		return reg
+	}
+	pubkey1 := registration(1, 0).Message.Pubkey.String()
+	pubkey2 := registration(2, 0).Message.Pubkey.String()
+	backend.relay.db = feeRecipientsDB{feeRecipients: map[string][]string{
+		pubkey1: {"0x0000000000000000000000000000000000000001"},
+		pubkey2: {"0x0000000000000000000000000000000000000001"},
+	}}
+
+	backend.relay.checkFeeRecipientChanges([]boostTypes.SignedValidatorRegistration{
+		registration(1, 1), // unchanged
+		registration(1, 2), // new fee recipient
+		registration(1, 2), // alerted once
+		registration(2, 3), // allowlisted
+		registration(3, 2), // first registration
+	})
+
+	notifier.Wait(time.Second)
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, notifications, 1)
+	require.Equal(t, common.EventFeeRecipientChanged, notifications[0].Event)
+	require.Equal(t, map[string]any{
+		"pubkey":                  pubkey1,
+		"fee_recipient":           "0x0000000000000000000000000000000000000002",
+		"previous_fee_recipients": "0x0000000000000000000000000000000000000001",
+	}, notifications[0].Data)
+}
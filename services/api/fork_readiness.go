@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+const pathForkReadiness = "/readyz/forks"
+
+// The forks of the relay, by the name of the beacon-APIs
+const (
+	forkBellatrix = "bellatrix"
+	forkCapella   = "capella"
+	forkDeneb     = "deneb"
+)
+
+// supportedForks are the forks this build can encode the bids and payloads of. From any other fork on, no bids are
+// accepted or returned.
+var supportedForks = map[string]bool{
+	forkBellatrix: true,
+	forkCapella:   true,
+	forkDeneb:     true,
+}
+
+// forkReadinessWindowEpochs is how long before an unsupported fork the relay stops being ready, by default a day
+var forkReadinessWindowEpochs = uint64(cli.GetEnvInt("FORK_READINESS_WINDOW_EPOCHS", 225))
+
+// ForkStatus is a fork of the network, as the relay knows it
+type ForkStatus struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// the epoch the relay switches at, from the fork schedule of the beacon node or the network config, nil if the fork
+	// isn't scheduled
+	Epoch *uint64 `json:"epoch"`
+
+	// the epoch of the network config, nil if it has none
+	ConfiguredEpoch *uint64 `json:"configured_epoch"`
+
+	Supported bool `json:"supported"`
+	Active    bool `json:"active"`
+}
+
+// BeaconNodeForkSchedule is the fork schedule advertised by a beacon node, by fork version, and whether it matches the
+// fork epochs of the relay
+type BeaconNodeForkSchedule struct {
+	Node    int               `json:"node"`
+	Error   string            `json:"error,omitempty"`
+	Epochs  map[string]uint64 `json:"epochs,omitempty"`
+	Matches bool              `json:"matches"`
+}
+
+// ForkReadinessResponse is returned by the fork readiness endpoint. The relay isn't ready while a fork this build
+// doesn't support is active or about to be, or while a beacon node disagrees with it about the fork epochs. Beacon
+// nodes which can't be asked for their fork schedule are only warned about, the beacon node health check covers them.
+type ForkReadinessResponse struct {
+	Ready          bool                     `json:"ready"`
+	CurrentEpoch   uint64                   `json:"current_epoch"`
+	SupportedForks []string                 `json:"supported_forks"`
+	Forks          []ForkStatus             `json:"forks"`
+	BeaconNodes    []BeaconNodeForkSchedule `json:"beacon_nodes"`
+	Problems       []string                 `json:"problems,omitempty"`
+	Warnings       []string                 `json:"warnings,omitempty"`
+}
+
+// forkScheduleCache has the fork schedules of the beacon nodes, which are fetched again once per epoch, so the
+// readiness checks don't query all beacon nodes every time
+type forkScheduleCache struct {
+	lock      sync.Mutex
+	epoch     uint64
+	schedules []beaconclient.NodeForkSchedule // nil until fetched
+}
+
+// get returns the schedules fetched in the epoch, and fetches them if there are none
+func (c *forkScheduleCache) get(epoch uint64, fetch func() []beaconclient.NodeForkSchedule) []beaconclient.NodeForkSchedule {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.schedules == nil || c.epoch != epoch {
+		c.schedules = fetch()
+		if c.schedules == nil {
+			c.schedules = []beaconclient.NodeForkSchedule{}
+		}
+		c.epoch = epoch
+	}
+	return c.schedules
+}
+
+// invalidate makes the next readiness check fetch the schedules again
+func (c *forkScheduleCache) invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.schedules = nil
+}
+
+// checkForkReadiness compares the forks this build supports and the fork epochs the relay uses with the fork schedules
+// of all beacon nodes, as fetched in the current epoch
+func (api *RelayAPI) checkForkReadiness(currentEpoch uint64) *ForkReadinessResponse {
+	response := &ForkReadinessResponse{
+		CurrentEpoch: currentEpoch,
+		Forks:        []ForkStatus{},
+		BeaconNodes:  []BeaconNodeForkSchedule{},
+	}
+	for name := range supportedForks {
+		response.SupportedForks = append(response.SupportedForks, name)
+	}
+	sort.Strings(response.SupportedForks)
+
+	netDetails := api.opts.EthNetDetails
+	knownForks := []struct {
+		name    string
+		version string
+		epoch   uint64
+	}{
+		{forkBellatrix, netDetails.BellatrixForkVersionHex, api.bellatrixEpoch},
+		{forkCapella, netDetails.CapellaForkVersionHex, api.capellaEpoch},
+		{forkDeneb, netDetails.DenebForkVersionHex, api.denebEpoch},
+	}
+
+	// the epochs the relay uses by fork version, the genesis version is known too
+	relayEpochs := map[string]uint64{netDetails.GenesisForkVersionHex: 0}
+	latestKnownEpoch := uint64(0)
+	for _, fork := range knownForks {
+		status := ForkStatus{Name: fork.name, Version: fork.version, Supported: supportedForks[fork.name]}
+		if fork.epoch != math.MaxUint64 {
+			epoch := fork.epoch
+			status.Epoch = &epoch
+			status.Active = epoch <= currentEpoch
+			if epoch > latestKnownEpoch {
+				latestKnownEpoch = epoch
+			}
+		}
+		if fork.version != "" {
+			relayEpochs[fork.version] = fork.epoch
+			if configuredEpoch, found := netDetails.ForkEpochs[fork.version]; found {
+				status.ConfiguredEpoch = &configuredEpoch
+				if fork.epoch != configuredEpoch {
+					response.Problems = append(response.Problems, fmt.Sprintf("%s fork is at epoch %d, but the network config has epoch %d", fork.name, fork.epoch, configuredEpoch))
+				}
+			}
+		}
+		if !status.Supported && status.Epoch != nil && *status.Epoch <= currentEpoch+forkReadinessWindowEpochs {
+			if status.Active {
+				response.Problems = append(response.Problems, fmt.Sprintf("%s fork is active since epoch %d, which this build doesn't support", fork.name, *status.Epoch))
+			} else {
+				response.Problems = append(response.Problems, fmt.Sprintf("%s fork at epoch %d is not supported by this build", fork.name, *status.Epoch))
+			}
+		}
+		response.Forks = append(response.Forks, status)
+	}
+
+	for _, nodeSchedule := range api.forkSchedules.get(currentEpoch, api.beaconClient.GetForkSchedules) {
+		node := BeaconNodeForkSchedule{Node: nodeSchedule.Index, Matches: true}
+		if nodeSchedule.Err != nil {
+			node.Error = nodeSchedule.Err.Error()
+			response.Warnings = append(response.Warnings, fmt.Sprintf("beacon node %d: could not get fork schedule: %s", node.Node, node.Error))
+			response.BeaconNodes = append(response.BeaconNodes, node)
+			continue
+		}
+
+		node.Epochs = make(map[string]uint64)
+		if nodeSchedule.Schedule == nil {
+			nodeSchedule.Schedule = new(beaconclient.GetForkScheduleResponse)
+		}
+		for _, fork := range nodeSchedule.Schedule.Data {
+			node.Epochs[fork.CurrentVersion] = fork.Epoch
+			if fork.Epoch == math.MaxUint64 {
+				continue // not scheduled
+			}
+
+			relayEpoch, known := relayEpochs[fork.CurrentVersion]
+			if known && relayEpoch != fork.Epoch {
+				node.Matches = false
+				response.Problems = append(response.Problems, fmt.Sprintf("beacon node %d: fork %s is at epoch %d, but the relay uses epoch %d", node.Node, fork.CurrentVersion, fork.Epoch, relayEpoch))
+			} else if !known && fork.Epoch > latestKnownEpoch && fork.Epoch <= currentEpoch+forkReadinessWindowEpochs {
+				// a fork newer than all the forks this build knows, which it can't handle for sure
+				node.Matches = false
+				response.Problems = append(response.Problems, fmt.Sprintf("beacon node %d: unknown fork %s at epoch %d", node.Node, fork.CurrentVersion, fork.Epoch))
+			}
+		}
+		response.BeaconNodes = append(response.BeaconNodes, node)
+	}
+
+	response.Ready = len(response.Problems) == 0
+	if response.Ready {
+		common.ForkReady.WithLabelValues(api.networkName()).Set(1)
+	} else {
+		common.ForkReady.WithLabelValues(api.networkName()).Set(0)
+	}
+	return response
+}
+
+// checkForkHealth is the fork check of the readiness endpoint
+func (api *RelayAPI) checkForkHealth(ctx context.Context) (any, error) {
+	resultC := make(chan *ForkReadinessResponse, 1)
+	go func() {
+		resultC <- api.checkForkReadiness(api.headSlot.Load() / uint64(common.SlotsPerEpoch))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ErrHealthCheckTimeout
+	case res := <-resultC:
+		if !res.Ready {
+			return nil, fmt.Errorf("%w: %s", ErrForkNotSupported, strings.Join(res.Problems, "; "))
+		}
+		return nil, nil
+	}
+}
+
+// handleForkReadiness reports the forks this build supports, the fork epochs of the relay and the fork schedules of the
+// beacon nodes, and responds with 503 if the relay isn't ready for them
+func (api *RelayAPI) handleForkReadiness(w http.ResponseWriter, req *http.Request) {
+	response := api.checkForkReadiness(api.headSlot.Load() / uint64(common.SlotsPerEpoch))
+
+	code := http.StatusOK
+	if !response.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		api.log.WithError(err).Error("failed to write fork readiness response")
+	}
+}
+
+// logForkReadiness logs the fork readiness at startup, loudly if the relay isn't ready for the forks
+func (api *RelayAPI) logForkReadiness(currentEpoch uint64) {
+	response := api.checkForkReadiness(currentEpoch)
+	if !response.Ready {
+		for _, problem := range response.Problems {
+			api.log.Error("fork readiness: " + problem)
+		}
+		return
+	}
+	api.log.Infof("fork readiness: ready, supported forks: %s", strings.Join(response.SupportedForks, ", "))
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkReadiness(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.opts.EthNetDetails.CapellaForkVersionHex = "0x03000000"
+	backend.relay.opts.EthNetDetails.DenebForkVersionHex = "0x04000000"
+	forks := []beaconclient.MockFork{
+		{Name: beaconclient.MockForkBellatrix, Version: "0x00000000", Epoch: 0},
+		{Name: beaconclient.MockForkCapella, Version: "0x03000000", Epoch: 10},
+	}
+	node1, node2 := beaconclient.NewMockBeaconInstance(), beaconclient.NewMockBeaconInstance()
+	node1.SetForks(forks...)
+	node2.SetForks(forks...)
+	backend.relay.beaconClient = beaconclient.NewMultiBeaconClient(common.TestLog, []beaconclient.IBeaconInstance{node1, node2})
+	backend.relay.blockSimQueue = NewBlockSimulationQueue("", newBlockSimNodePool(common.TestLog, []string{"http://localhost:8545"}, ""))
+	require.NoError(t, backend.relay.updateForkEpochs())
+
+	forkReadiness := func() (int, ForkReadinessResponse) {
+		rr := backend.request(http.MethodGet, pathForkReadiness, nil)
+		resp := ForkReadinessResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return rr.Code, resp
+	}
+
+	code, resp := forkReadiness()
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, resp.Ready)
+	require.Equal(t, []string{forkBellatrix, forkCapella, forkDeneb}, resp.SupportedForks)
+	require.Len(t, resp.Forks, 3)
+	require.Equal(t, uint64(10), *resp.Forks[1].Epoch)
+	require.Nil(t, resp.Forks[2].Epoch)
+	require.Len(t, resp.BeaconNodes, 2)
+	require.True(t, resp.BeaconNodes[1].Matches)
+
+	t.Run("schedules are cached for the epoch", func(t *testing.T) {
+		node2.SetForks(forks[0], beaconclient.MockFork{Name: beaconclient.MockForkCapella, Version: "0x03000000", Epoch: 11})
+		defer node2.SetForks(forks...)
+		require.True(t, backend.relay.checkForkReadiness(0).Ready)
+		require.False(t, backend.relay.checkForkReadiness(1).Ready)
+		backend.relay.forkSchedules.invalidate()
+	})
+
+	t.Run("not ready if a beacon node disagrees about a fork epoch", func(t *testing.T) {
+		node2.SetForks(forks[0], beaconclient.MockFork{Name: beaconclient.MockForkCapella, Version: "0x03000000", Epoch: 11})
+		defer node2.SetForks(forks...)
+		defer backend.relay.forkSchedules.invalidate()
+		backend.relay.forkSchedules.invalidate()
+
+		code, resp := forkReadiness()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.True(t, resp.BeaconNodes[0].Matches)
+		require.False(t, resp.BeaconNodes[1].Matches)
+		require.Len(t, resp.Problems, 1)
+	})
+
+	t.Run("ready if a beacon node is unavailable", func(t *testing.T) {
+		node2.MockForkScheduleErr = errors.New("connection refused")
+		defer func() { node2.MockForkScheduleErr = nil }()
+		defer backend.relay.forkSchedules.invalidate()
+		backend.relay.forkSchedules.invalidate()
+
+		code, resp := forkReadiness()
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, resp.Ready)
+		require.Len(t, resp.Warnings, 1)
+	})
+
+	t.Run("not ready if an unknown fork is about to start", func(t *testing.T) {
+		node1.SetForks(append(forks, beaconclient.MockFork{Name: "electra", Version: "0x05000000", Epoch: 20})...)
+		defer node1.SetForks(forks...)
+		defer backend.relay.forkSchedules.invalidate()
+		backend.relay.forkSchedules.invalidate()
+
+		resp := backend.relay.checkForkReadiness(0)
+		require.False(t, resp.Ready)
+		require.False(t, resp.BeaconNodes[0].Matches)
+
+		// far enough away, there's time to upgrade
+		forkReadinessWindowEpochs = 5
+		defer func() { forkReadinessWindowEpochs = 225 }()
+		resp = backend.relay.checkForkReadiness(0)
+		require.True(t, resp.Ready)
+	})
+
+	t.Run("not ready if an unsupported fork is about to start", func(t *testing.T) {
+		backend.relay.denebEpoch = 20
+		supportedForks[forkDeneb] = false
+		defer func() {
+			backend.relay.denebEpoch = math.MaxUint64
+			supportedForks[forkDeneb] = true
+		}()
+
+		resp := backend.relay.checkForkReadiness(0)
+		require.False(t, resp.Ready)
+		require.False(t, resp.Forks[2].Supported)
+
+		// and the readiness endpoint fails with it
+		rr := backend.request(http.MethodGet, pathReadyz, nil)
+		readyz := ReadinessResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &readyz))
+		require.False(t, readyz.Dependencies["forks"].OK)
+	})
+}
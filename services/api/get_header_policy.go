@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+var ErrInvalidGetHeaderPolicy = errors.New("invalid getHeader policy")
+
+// getHeaderPolicy is the configured timing of the getHeader responses, against timing games of the proposers. The
+// responses can be held until a time into the slot, and the bid served can be fixed to the best one received before a
+// cutoff time into the slot. The policy is recorded with the delivered payloads.
+type getHeaderPolicy struct {
+	hasHoldUntil bool
+	holdUntil    time.Duration // into the slot, getHeader responds no earlier
+
+	hasBidCutoff bool
+	bidCutoff    time.Duration // into the slot, later submissions are rejected
+}
+
+// parseGetHeaderPolicy parses the ms into the slot of the GET_HEADER_HOLD_UNTIL_MS and GET_HEADER_BID_CUTOFF_MS
+// settings, which are disabled if empty. Negative values are before the slot start.
+func parseGetHeaderPolicy(holdUntilMs, bidCutoffMs string) (policy getHeaderPolicy, err error) {
+	if holdUntilMs != "" {
+		ms, err := strconv.ParseInt(strings.TrimSpace(holdUntilMs), 10, 64)
+		if err != nil {
+			return policy, fmt.Errorf("%w: hold until %s", ErrInvalidGetHeaderPolicy, holdUntilMs)
+		}
+		policy.hasHoldUntil = true
+		policy.holdUntil = time.Duration(ms) * time.Millisecond
+	}
+	if bidCutoffMs != "" {
+		ms, err := strconv.ParseInt(strings.TrimSpace(bidCutoffMs), 10, 64)
+		if err != nil {
+			return policy, fmt.Errorf("%w: bid cutoff %s", ErrInvalidGetHeaderPolicy, bidCutoffMs)
+		}
+		policy.hasBidCutoff = true
+		policy.bidCutoff = time.Duration(ms) * time.Millisecond
+	}
+	return policy, nil
+}
+
+// String returns the policy as recorded with the delivered payloads, e.g. "hold_until=1000ms,bid_cutoff=500ms", or an
+// empty string if getHeader responds without a policy
+func (p getHeaderPolicy) String() string {
+	parts := []string{}
+	if p.hasHoldUntil {
+		parts = append(parts, fmt.Sprintf("hold_until=%dms", p.holdUntil.Milliseconds()))
+	}
+	if p.hasBidCutoff {
+		parts = append(parts, fmt.Sprintf("bid_cutoff=%dms", p.bidCutoff.Milliseconds()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// holdGetHeader waits until the hold time of the policy into the slot. Requests for slots more than a slot away aren't
+// held, there is no bid for them yet anyway. Returns false if the request ended while waiting.
+func (api *RelayAPI) holdGetHeader(ctx context.Context, slot uint64) bool {
+	if !api.getHeaderPolicy.hasHoldUntil {
+		return true
+	}
+	ms, ok := api.msIntoSlot(slot, time.Now())
+	if !ok {
+		return true
+	}
+	wait := api.getHeaderPolicy.holdUntil - time.Duration(ms)*time.Millisecond
+	if wait <= 0 || wait > common.DurationPerSlot {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-api.shutdownCtx.Done():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isAfterBidCutoff returns whether a submission received at t is too late to become the bid of the slot
+func (api *RelayAPI) isAfterBidCutoff(slot uint64, t time.Time) bool {
+	if !api.getHeaderPolicy.hasBidCutoff {
+		return false
+	}
+	ms, ok := api.msIntoSlot(slot, t)
+	return ok && ms > api.getHeaderPolicy.bidCutoff.Milliseconds()
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGetHeaderPolicy(t *testing.T) {
+	policy, err := parseGetHeaderPolicy("", "")
+	require.NoError(t, err)
+	require.Equal(t, "", policy.String())
+
+	policy, err = parseGetHeaderPolicy("1000", "-500")
+	require.NoError(t, err)
+	require.Equal(t, time.Second, policy.holdUntil)
+	require.Equal(t, -500*time.Millisecond, policy.bidCutoff)
+	require.Equal(t, "hold_until=1000ms,bid_cutoff=-500ms", policy.String())
+
+	// a cutoff at the slot start is a policy
+	policy, err = parseGetHeaderPolicy("", "0")
+	require.NoError(t, err)
+	require.Equal(t, "bid_cutoff=0ms", policy.String())
+
+	_, err = parseGetHeaderPolicy("1s", "")
+	require.ErrorIs(t, err, ErrInvalidGetHeaderPolicy)
+}
+
+func TestGetHeaderPolicy(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	relay := backend.relay
+	slot := uint64(10)
+	slotDuration := uint64(common.DurationPerSlot.Seconds())
+
+	// the slot started right now
+	slotStart := time.Now()
+	relay.genesisInfo = &beaconclient.GetGenesisResponse{}
+	relay.genesisInfo.Data.GenesisTime = uint64(slotStart.Unix()) - slot*slotDuration
+	slotStart = time.Unix(slotStart.Unix(), 0)
+
+	// without a policy
+	require.False(t, relay.isAfterBidCutoff(slot, slotStart.Add(time.Hour)))
+	start := time.Now()
+	require.True(t, relay.holdGetHeader(context.Background(), slot))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	relay.getHeaderPolicy = getHeaderPolicy{hasBidCutoff: true, bidCutoff: 500 * time.Millisecond}
+	require.False(t, relay.isAfterBidCutoff(slot, slotStart.Add(-time.Second)))
+	require.False(t, relay.isAfterBidCutoff(slot, slotStart.Add(500*time.Millisecond)))
+	require.True(t, relay.isAfterBidCutoff(slot, slotStart.Add(501*time.Millisecond)))
+
+	// held until the time into the slot
+	relay.getHeaderPolicy = getHeaderPolicy{hasHoldUntil: true, holdUntil: time.Since(slotStart) + 200*time.Millisecond}
+	start = time.Now()
+	require.True(t, relay.holdGetHeader(context.Background(), slot))
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	// not held past the end of the request
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	relay.getHeaderPolicy = getHeaderPolicy{hasHoldUntil: true, holdUntil: time.Since(slotStart) + time.Second}
+	require.False(t, relay.holdGetHeader(ctx, slot))
+
+	// nor for slots far ahead
+	start = time.Now()
+	require.True(t, relay.holdGetHeader(context.Background(), slot+5))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+}
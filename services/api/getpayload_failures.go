@@ -0,0 +1,64 @@
+package api
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+const (
+	// maxPendingGetPayloadFailures bounds the getPayload failures being saved at once, further ones are only logged
+	maxPendingGetPayloadFailures = 16
+
+	// the recorded failures are only pruned beyond this size
+	maxRecordedGetPayloadFailures = 1000
+)
+
+// getPayloadFailureRecorder decides which getPayload failures are saved to the database: only the first one of a
+// proposer with the same error code in a slot, and only while less than maxPendingGetPayloadFailures are being saved.
+// The zero value is ready to use.
+type getPayloadFailureRecorder struct {
+	mu         sync.Mutex
+	recorded   map[getPayloadFailureKey]bool
+	numPending int
+}
+
+type getPayloadFailureKey struct {
+	slot           uint64
+	proposerPubkey string
+	errorCode      string
+}
+
+// start returns whether the failure should be saved, done has to be called once it is
+func (r *getPayloadFailureRecorder) start(slot uint64, proposerPubkey, errorCode string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.numPending >= maxPendingGetPayloadFailures {
+		return false
+	}
+
+	key := getPayloadFailureKey{slot: slot, proposerPubkey: strings.ToLower(proposerPubkey), errorCode: errorCode}
+	if r.recorded[key] {
+		return false
+	}
+	if r.recorded == nil {
+		r.recorded = make(map[getPayloadFailureKey]bool)
+	} else if len(r.recorded) >= maxRecordedGetPayloadFailures {
+		for k := range r.recorded {
+			if k.slot+uint64(common.SlotsPerEpoch) < slot {
+				delete(r.recorded, k)
+			}
+		}
+	}
+	r.recorded[key] = true
+	r.numPending++
+	return true
+}
+
+func (r *getPayloadFailureRecorder) done() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.numPending--
+}
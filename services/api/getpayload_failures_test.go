@@ -0,0 +1,28 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPayloadFailureRecorder(t *testing.T) {
+	r := getPayloadFailureRecorder{}
+
+	// only the first failure per slot, proposer and error code is saved
+	require.True(t, r.start(1, "0xAB", ErrorCodePayloadNotFound))
+	require.False(t, r.start(1, "0xab", ErrorCodePayloadNotFound))
+	require.True(t, r.start(1, "0xab", ErrorCodeGetPayloadTooLate))
+	require.True(t, r.start(2, "0xab", ErrorCodePayloadNotFound))
+	r.done()
+	r.done()
+	r.done()
+
+	// failures are dropped while too many are being saved
+	for i := 0; i < maxPendingGetPayloadFailures; i++ {
+		require.True(t, r.start(uint64(10+i), "0xab", ErrorCodePayloadNotFound))
+	}
+	require.False(t, r.start(100, "0xab", ErrorCodePayloadNotFound))
+	r.done()
+	require.True(t, r.start(100, "0xab", ErrorCodePayloadNotFound))
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+)
+
+const (
+	pathLivez  = "/livez"
+	pathReadyz = "/readyz"
+)
+
+var (
+	ErrHealthCheckTimeout = errors.New("health check timed out")
+	ErrNoHealthyBlockSim  = errors.New("no healthy block simulation node")
+	ErrForkNotSupported   = errors.New("not ready for the forks")
+
+	// healthCheckTimeout bounds each dependency check of the readiness endpoint
+	healthCheckTimeout = time.Duration(cli.GetEnvInt("HEALTHCHECK_TIMEOUT_MS", 2000)) * time.Millisecond
+)
+
+// DependencyStatus is the result of checking a single dependency of the relay
+type DependencyStatus struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Detail any    `json:"detail,omitempty"`
+}
+
+// ReadinessResponse is returned by the readiness endpoint, with the status of every dependency
+type ReadinessResponse struct {
+	Ready        bool                        `json:"ready"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// handleLivez only reports whether the process is serving requests. It doesn't check the dependencies, since
+// restarting the relay wouldn't fix them.
+func (api *RelayAPI) handleLivez(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReadyz checks all dependencies and responds with 503 if any of them is unavailable, so that load balancers stop
+// routing requests to this instance
+func (api *RelayAPI) handleReadyz(w http.ResponseWriter, req *http.Request) {
+	if api.isShuttingDown.Load() {
+		api.RespondErrorWithCode(w, http.StatusServiceUnavailable, ErrorCodeShuttingDown, "relay is shutting down")
+		return
+	}
+
+	checks := map[string]func(ctx context.Context) (detail any, err error){
+		"beacon":   api.checkBeaconHealth,
+		"redis":    func(ctx context.Context) (any, error) { return nil, api.redis.Ping(ctx) },
+		"database": func(ctx context.Context) (any, error) { return nil, api.db.Ping(ctx) },
+		"forks":    api.checkForkHealth,
+	}
+	if api.opts.BlockBuilderAPI {
+		checks["blocksim"] = api.checkBlockSimHealth
+	}
+
+	response := ReadinessResponse{Ready: true, Dependencies: make(map[string]DependencyStatus, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) (any, error)) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(req.Context(), healthCheckTimeout)
+			defer cancel()
+
+			status := DependencyStatus{OK: true}
+			detail, err := check(ctx)
+			if err != nil {
+				status = DependencyStatus{OK: false, Error: err.Error()}
+			}
+			status.Detail = detail
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Dependencies[name] = status
+			response.Ready = response.Ready && status.OK
+		}(name, check)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if !response.Ready {
+		code = http.StatusServiceUnavailable
+		api.log.WithField("dependencies", response.Dependencies).Warn("relay is not ready")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		api.log.WithError(err).Error("failed to write readiness response")
+	}
+}
+
+// checkBeaconHealth returns an error if no beacon node is synced (unless syncing nodes are allowed)
+func (api *RelayAPI) checkBeaconHealth(ctx context.Context) (any, error) {
+	type result struct {
+		headSlot  uint64
+		isSyncing bool
+		err       error
+	}
+	resultC := make(chan result, 1)
+	go func() {
+		syncStatus, err := api.beaconClient.BestSyncStatus()
+		if err != nil {
+			resultC <- result{err: err}
+			return
+		}
+		resultC <- result{headSlot: syncStatus.HeadSlot, isSyncing: syncStatus.IsSyncing}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ErrHealthCheckTimeout
+	case res := <-resultC:
+		if res.err != nil {
+			return nil, res.err
+		}
+		return map[string]any{"headSlot": res.headSlot, "isSyncing": res.isSyncing}, nil
+	}
+}
+
+// checkBlockSimHealth returns an error if none of the block simulation nodes is healthy. The health of the nodes is
+// tracked by the simulation requests and the periodic health checks, they aren't queried here. The node URLs aren't
+// included, as they may contain credentials.
+func (api *RelayAPI) checkBlockSimHealth(ctx context.Context) (any, error) {
+	numHealthy := 0
+	nodes := api.blockSimQueue.nodes.allNodes()
+	for _, node := range nodes {
+		if node.isHealthy.Load() {
+			numHealthy++
+		}
+	}
+	detail := map[string]int{"numNodes": len(nodes), "numHealthy": numHealthy}
+
+	if numHealthy == 0 {
+		return detail, ErrNoHealthyBlockSim
+	}
+	return detail, nil
+}
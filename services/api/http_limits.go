@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Defaults of the HTTP server limits
+var (
+	DefaultReadTimeout              = 1500 * time.Millisecond
+	DefaultReadTimeoutRegistrations = 10 * time.Second
+	DefaultReadHeaderTimeout        = 600 * time.Millisecond
+	DefaultWriteTimeout             = 10 * time.Second
+	DefaultIdleTimeout              = 3 * time.Second
+	DefaultShutdownTimeout          = 30 * time.Second
+	DefaultMaxHeaderBytes           = 64 << 10
+
+	DefaultMaxBodyBytes              = int64(4 << 20)
+	DefaultMaxBodyBytesRegistrations = int64(64 << 20)
+	DefaultMaxBodyBytesSubmissions   = int64(16 << 20)
+)
+
+// HTTPServerOpts are the limits of the HTTP server, the defaults are used for zero values
+type HTTPServerOpts struct {
+	ReadTimeout              time.Duration
+	ReadTimeoutRegistrations time.Duration // registerValidator bodies can be much larger than the others
+	ReadHeaderTimeout        time.Duration
+	WriteTimeout             time.Duration
+	IdleTimeout              time.Duration
+	ShutdownTimeout          time.Duration // maximum duration for draining the requests and background work on shutdown
+	MaxHeaderBytes           int
+
+	// Maximum request body sizes, for gzipped requests both before and after decompression
+	MaxBodyBytes              int64
+	MaxBodyBytesRegistrations int64
+	MaxBodyBytesSubmissions   int64 // block and header submissions
+}
+
+func (o *HTTPServerOpts) setDefaults() {
+	if o.ReadTimeout == 0 {
+		o.ReadTimeout = DefaultReadTimeout
+	}
+	if o.ReadTimeoutRegistrations == 0 {
+		o.ReadTimeoutRegistrations = DefaultReadTimeoutRegistrations
+	}
+	if o.ReadHeaderTimeout == 0 {
+		o.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = DefaultWriteTimeout
+	}
+	if o.IdleTimeout == 0 {
+		o.IdleTimeout = DefaultIdleTimeout
+	}
+	if o.ShutdownTimeout == 0 {
+		o.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if o.MaxHeaderBytes == 0 {
+		o.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if o.MaxBodyBytesRegistrations == 0 {
+		o.MaxBodyBytesRegistrations = DefaultMaxBodyBytesRegistrations
+	}
+	if o.MaxBodyBytesSubmissions == 0 {
+		o.MaxBodyBytesSubmissions = DefaultMaxBodyBytesSubmissions
+	}
+}
+
+// maxBodyBytes returns the body size limit for a request path
+func (o *HTTPServerOpts) maxBodyBytes(path string) int64 {
+	switch path {
+	case pathRegisterValidator:
+		return o.MaxBodyBytesRegistrations
+	case pathSubmitNewBlock, pathSubmitNewHeader:
+		return o.MaxBodyBytesSubmissions
+	default:
+		return o.MaxBodyBytes
+	}
+}
+
+// limitRequests caps the request body sizes, and extends the read deadline of registrations. It has to wrap all other
+// middlewares, as the read deadline can only be changed on the connection's own ResponseWriter.
+func (api *RelayAPI) limitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == pathRegisterValidator {
+			err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(api.opts.HTTPServer.ReadTimeoutRegistrations))
+			if err != nil && !errors.Is(err, http.ErrNotSupported) { // not supported by test recorders
+				api.log.WithError(err).Error("failed to extend the read deadline of a registration request")
+			}
+		}
+
+		req.Body = http.MaxBytesReader(w, req.Body, api.opts.HTTPServer.maxBodyBytes(req.URL.Path))
+		next.ServeHTTP(w, req)
+	})
+}
+
+// limitDecompressedBody applies the body size limit of the request to the decompressed body as well
+func (api *RelayAPI) limitDecompressedBody(w http.ResponseWriter, req *http.Request, r io.ReadCloser) io.ReadCloser {
+	return http.MaxBytesReader(w, r, api.opts.HTTPServer.maxBodyBytes(req.URL.Path))
+}
+
+// isBodyTooLarge returns whether reading a request body failed because it exceeds the size limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// SubmissionErrOverloaded is the error code of the submissions shed while the relay is overloaded
+const SubmissionErrOverloaded = "overloaded"
+
+var (
+	// load shedding starts once this many submissions wait for a simulation or to be saved to the database, 0 to disable
+	loadShedSimQueueThreshold = cli.GetEnvInt("LOAD_SHED_SIM_QUEUE_THRESHOLD", 0)
+	loadShedDBQueueThreshold  = cli.GetEnvInt("LOAD_SHED_DB_QUEUE_THRESHOLD", 0)
+)
+
+// overloadReason returns why the relay is overloaded, or an empty string if it isn't
+func (api *RelayAPI) overloadReason() string {
+	if loadShedSimQueueThreshold > 0 && api.blockSimQueue.numWaiting() >= loadShedSimQueueThreshold {
+		return "sim_queue"
+	}
+	if loadShedDBQueueThreshold > 0 && api.blockSubmissionWriter.queueLength() >= loadShedDBQueueThreshold {
+		return "db_queue"
+	}
+	return ""
+}
+
+// loadSheddingFilter keeps the simulations and database writes for the bids which can still win while the relay is
+// overloaded: submissions for later slots than the imminent one, and the ones not above the current top bid, are
+// rejected with a 503 and Retry-After, instead of slowing down all builders including the eventual winner. The builder
+// of the top bid is exempt from the latter, so it can still lower or cancel its bid.
+type loadSheddingFilter struct {
+	NoopSubmissionFilter
+	api *RelayAPI
+}
+
+func (f *loadSheddingFilter) Name() string {
+	return "load-shedding"
+}
+
+func (f *loadSheddingFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	overload := f.api.overloadReason()
+	if overload == "" {
+		return nil
+	}
+
+	imminentSlot := f.api.headSlot.Load() + 1
+	if s.Payload.Slot() > imminentSlot {
+		return f.shed(s, overload, "future_slot", "relay is overloaded, only accepting submissions for slot %d", imminentSlot)
+	}
+
+	topBid, err := f.api.redis.GetBestBid(s.Payload.Slot(), s.Payload.ParentHash(), s.Payload.ProposerPubkey())
+	if err != nil {
+		s.Log.WithError(err).Error("could not get top bid for load shedding")
+		return nil
+	} else if topBid == nil || s.Payload.Value().Cmp(topBid.Value()) > 0 {
+		return nil
+	}
+
+	topBidTrace, err := f.api.redis.GetBidTrace(s.Payload.Slot(), s.Payload.ProposerPubkey(), topBid.BlockHash().String())
+	if err != nil {
+		s.Log.WithError(err).Error("could not get top bid trace for load shedding")
+		return nil
+	} else if topBidTrace != nil && strings.EqualFold(topBidTrace.BuilderPubkey.String(), s.Payload.BuilderPubkey().String()) {
+		return nil
+	}
+	return f.shed(s, overload, "below_top_bid", "relay is overloaded, only accepting bids above the top bid of %s wei", topBid.Value().String())
+}
+
+func (f *loadSheddingFilter) shed(s *FilteredSubmission, overload, reason, format string, args ...any) error {
+	common.SubmissionsShedTotal.WithLabelValues(f.api.networkName(), overload, reason).Inc()
+	s.Log.WithFields(logrus.Fields{
+		"overload":   overload,
+		"shedReason": reason,
+	}).Info("shedding submission")
+	rejection := newSubmissionError(http.StatusServiceUnavailable, SubmissionErrOverloaded, format, args...)
+	rejection.retryAfter = simQueueRetryAfter
+	return rejection
+}
+
+var _ SubmissionFilter = (*loadSheddingFilter)(nil)
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSheddingFilter(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	relay := backend.relay
+	relay.headSlot.Store(10)
+	filter := &loadSheddingFilter{api: relay}
+
+	newSubmission := func(slot uint64) *FilteredSubmission {
+		return &FilteredSubmission{
+			Log:     common.TestLog,
+			Payload: &common.BuilderSubmitBlockRequest{Capella: testCapellaSubmission(slot)},
+		}
+	}
+
+	// not overloaded
+	require.NoError(t, filter.BeforeSimulation(context.Background(), newSubmission(12)))
+
+	// overloaded by the database queue, the writers aren't started in the test
+	defer func(threshold int) { loadShedDBQueueThreshold = threshold }(loadShedDBQueueThreshold)
+	loadShedDBQueueThreshold = 1
+	relay.blockSubmissionWriter.enqueue(newTestBlockSubmissionWrite(11))
+	require.Equal(t, "db_queue", relay.overloadReason())
+
+	t.Run("future slots are shed", func(t *testing.T) {
+		err := filter.BeforeSimulation(context.Background(), newSubmission(12))
+		var rejection *submissionError
+		require.ErrorAs(t, err, &rejection)
+		require.Equal(t, http.StatusServiceUnavailable, rejection.status)
+		require.Equal(t, SubmissionErrOverloaded, rejection.code)
+
+		w := httptest.NewRecorder()
+		relay.respondSubmissionError(w, rejection)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		require.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("bids for the imminent slot are shed unless above the top bid", func(t *testing.T) {
+		s := newSubmission(11) // value 1
+		require.NoError(t, filter.BeforeSimulation(context.Background(), s))
+
+		topBid := &common.GetHeaderResponse{
+			Bellatrix: &types.GetHeaderResponse{
+				Version: "bellatrix",
+				Data: &types.SignedBuilderBid{
+					Message: &types.BuilderBid{
+						Header: &types.ExecutionPayloadHeader{},
+						Value:  types.IntToU256(1),
+						Pubkey: types.PublicKey{0x01},
+					},
+					Signature: types.Signature{0x01},
+				},
+			},
+		}
+		err := backend.redis.SaveLatestBuilderBid(11, "0xb1", s.Payload.ParentHash(), s.Payload.ProposerPubkey(), time.Now(), topBid)
+		require.NoError(t, err)
+		_, err = backend.redis.UpdateTopBid(11, s.Payload.ParentHash(), s.Payload.ProposerPubkey())
+		require.NoError(t, err)
+
+		require.Error(t, filter.BeforeSimulation(context.Background(), s))
+		s.Payload.Capella.Message.Value.SetUint64(2)
+		require.NoError(t, filter.BeforeSimulation(context.Background(), s))
+
+		// the builder of the top bid can still lower it
+		s.Payload.Capella.Message.Value.SetUint64(0)
+		require.Error(t, filter.BeforeSimulation(context.Background(), s))
+		bidTrace := &common.BidTraceV2{BidTrace: *s.Payload.Message()}
+		bidTrace.BlockHash = topBid.BlockHash()
+		require.NoError(t, backend.redis.SaveBidTrace(bidTrace))
+		require.NoError(t, filter.BeforeSimulation(context.Background(), s))
+	})
+}
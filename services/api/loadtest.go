@@ -0,0 +1,17 @@
+package api
+
+import (
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+)
+
+// Load test mode (LOADTEST_MODE=1) is for measuring the capacity of a relay with synthetic traffic, e.g. from
+// `relay tool loadtest`. Submissions skip the checks against the chain and the block simulation, and registrations of
+// unknown validators are accepted. It implies shadow mode, so a relay in load test mode never delivers a payload.
+
+// loadTestSlotDuty stands in for the proposer duty of synthetic submissions, which is only needed for the simulation
+var loadTestSlotDuty = &boostTypes.RegisterValidatorRequestMessage{}
+
+// loadTestSimResult stands in for the simulation of synthetic submissions, as if the bid value was paid to the proposer
+func loadTestSimResult(req *BuilderBlockValidationRequest) *BlockSimulationResult {
+	return &BlockSimulationResult{ProposerPayment: req.Value().String()}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the wrapped writer, so that http.ResponseController can flush and set deadlines through the recorder
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrumentMiddleware records the latency and response status of the endpoint, traces the request (continuing the
+// trace of the caller, if any) and applies the log sampling of the endpoint
+func (api *RelayAPI) instrumentMiddleware(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		if rl := requestLogFromContext(req.Context()); rl != nil {
+			rl.setEndpoint(endpoint)
+		}
+		ctx := common.ExtractTraceContext(req.Context(), req.Header)
+		ctx, span := common.Tracer.Start(ctx, endpoint, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, req.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+		common.APIRequestDuration.WithLabelValues(api.networkName(), endpoint, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	}
+}
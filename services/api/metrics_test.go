@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentMiddlewareResponseController(t *testing.T) {
+	// the handlers behind the middleware can still flush, e.g. for event streams
+	api := &RelayAPI{} //nolint:exhaustruct
+	handler := api.instrumentMiddleware("test", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		require.NoError(t, http.NewResponseController(w).Flush())
+	})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	require.True(t, rr.Flushed)
+}
+
+func TestMetricsNetworkLabel(t *testing.T) {
+	holesky := newTestBackend(t, 1)
+	holesky.relay.opts.EthNetDetails.Name = "holesky"
+	sepolia := newTestBackend(t, 1)
+	sepolia.relay.opts.EthNetDetails.Name = "sepolia"
+
+	numRequests := func(network string, status int) uint64 {
+		metric := &dto.Metric{}
+		histogram := common.APIRequestDuration.WithLabelValues(network, "getPayload", strconv.Itoa(status)).(prometheus.Histogram) //nolint:forcetypeassert
+		require.NoError(t, histogram.Write(metric))
+		return metric.GetHistogram().GetSampleCount()
+	}
+	status := holesky.request(http.MethodPost, pathGetPayload, nil).Code
+	holesky.request(http.MethodPost, pathGetPayload, nil)
+	sepolia.request(http.MethodPost, pathGetPayload, nil)
+	require.Equal(t, uint64(2), numRequests("holesky", status))
+	require.Equal(t, uint64(1), numRequests("sepolia", status))
+}
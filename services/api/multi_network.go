@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	uberatomic "go.uber.org/atomic"
+)
+
+var (
+	ErrNoNetworks        = errors.New("no networks to serve")
+	ErrDuplicateNetwork  = errors.New("network is served more than once")
+	ErrInvalidNetworkKey = errors.New("invalid network name")
+)
+
+// MultiNetworkAPIOpts are the settings of the HTTP server shared by the networks. The listen address, HTTP server and
+// TLS settings of the relays of the networks are ignored.
+type MultiNetworkAPIOpts struct {
+	Log        *logrus.Entry
+	ListenAddr string
+	HTTPServer HTTPServerOpts
+	TLS        TLSOpts
+}
+
+// MultiNetworkAPI serves the relays of several networks from one HTTP server. The API of each network is served on the
+// subdomain of the network name, e.g. holesky.relay.example.com, and under the network name, e.g.
+// /holesky/eth/v1/builder/status. Each relay has its own beacon nodes, redis prefix, database and admin server.
+type MultiNetworkAPI struct {
+	opts      MultiNetworkAPIOpts
+	log       *logrus.Entry
+	networks  []string
+	relays    map[string]*RelayAPI
+	tlsConfig *tls.Config
+
+	srv            *http.Server
+	srvStarted     uberatomic.Bool
+	isShuttingDown uberatomic.Bool
+	stoppedC       chan struct{}
+}
+
+// NewMultiNetworkAPI creates the server of the relays, which are served in the order given
+func NewMultiNetworkAPI(opts MultiNetworkAPIOpts, relays []*RelayAPI) (*MultiNetworkAPI, error) {
+	if len(relays) == 0 {
+		return nil, ErrNoNetworks
+	}
+
+	tlsConfig, err := opts.TLS.serverConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MultiNetworkAPI{
+		opts:      opts,
+		log:       opts.Log,
+		relays:    make(map[string]*RelayAPI, len(relays)),
+		tlsConfig: tlsConfig,
+		stoppedC:  make(chan struct{}),
+	}
+	for _, relay := range relays {
+		name := relay.opts.EthNetDetails.Name
+		if name == "" || strings.ContainsAny(name, "/.") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidNetworkKey, name)
+		} else if _, found := m.relays[name]; found {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateNetwork, name)
+		}
+		m.networks = append(m.networks, name)
+		m.relays[name] = relay
+	}
+	return m, nil
+}
+
+// getRouter routes the requests to the relay of their network. mev-boost replaces the path of the relay URLs, so
+// proposers reach a network by the first label of the host, e.g. holesky.relay.example.com. All other clients can use
+// the path prefix of the network name as well, e.g. /holesky/eth/v1/builder/status.
+func (m *MultiNetworkAPI) getRouter() http.Handler {
+	routers := make(map[string]http.Handler, len(m.networks))
+	for _, name := range m.networks {
+		routers[name] = m.relays[name].getRouter()
+	}
+
+	r := mux.NewRouter()
+	for _, name := range m.networks {
+		r.MatcherFunc(hostNetworkMatcher(name)).Handler(routers[name])
+	}
+	r.HandleFunc("/", m.handleRoot).Methods(http.MethodGet)
+	for _, name := range m.networks {
+		prefix := "/" + name
+		r.PathPrefix(prefix + "/").Handler(http.StripPrefix(prefix, routers[name]))
+	}
+	return r
+}
+
+// hostNetworkMatcher matches the requests whose host starts with the label of the network
+func hostNetworkMatcher(network string) mux.MatcherFunc {
+	return func(req *http.Request, _ *mux.RouteMatch) bool {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		label, _, found := strings.Cut(host, ".")
+		return found && strings.EqualFold(label, network)
+	}
+}
+
+// handleRoot lists the networks served
+func (m *MultiNetworkAPI) handleRoot(w http.ResponseWriter, req *http.Request) {
+	m.relays[m.networks[0]].RespondOK(w, struct {
+		Networks []string `json:"networks"`
+	}{
+		Networks: m.networks,
+	})
+}
+
+// StartServer starts the relays of all networks, and serves them until the shutdown is done
+func (m *MultiNetworkAPI) StartServer() (err error) {
+	if m.srvStarted.Swap(true) {
+		return ErrServerAlreadyStarted
+	}
+
+	for _, name := range m.networks {
+		m.log.WithField("network", name).Info("starting relay")
+		if err := m.relays[name].startServices(); err != nil {
+			return fmt.Errorf("network %s: %w", name, err)
+		}
+	}
+
+	m.srv = &http.Server{
+		Addr:    m.opts.ListenAddr,
+		Handler: m.getRouter(),
+
+		ReadTimeout:       m.opts.HTTPServer.ReadTimeout,
+		ReadHeaderTimeout: m.opts.HTTPServer.ReadHeaderTimeout,
+		WriteTimeout:      m.opts.HTTPServer.WriteTimeout,
+		IdleTimeout:       m.opts.HTTPServer.IdleTimeout,
+		MaxHeaderBytes:    m.opts.HTTPServer.MaxHeaderBytes,
+		TLSConfig:         m.tlsConfig,
+	}
+
+	ln, err := common.Listen(m.opts.ListenAddr)
+	if err != nil {
+		return err
+	}
+	if m.tlsConfig != nil {
+		err = m.srv.ServeTLS(ln, "", "")
+	} else {
+		err = m.srv.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		<-m.stoppedC
+		return nil
+	}
+	return err
+}
+
+// StopServer drains the relays of all networks like RelayAPI.StopServer. The shared server only stops accepting
+// connections once the getPayload grace period of every network is over.
+func (m *MultiNetworkAPI) StopServer() (err error) {
+	if m.isShuttingDown.Swap(true) {
+		return nil
+	}
+	defer close(m.stoppedC)
+
+	m.log.WithField("timeout", m.opts.HTTPServer.ShutdownTimeout.String()).Info("Stopping server...")
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	relays := make([]*RelayAPI, 0, len(m.networks))
+	for _, name := range m.networks {
+		if relay := m.relays[name]; !relay.isShuttingDown.Swap(true) {
+			relays = append(relays, relay)
+		}
+	}
+	m.forEachRelay(relays, func(relay *RelayAPI) {
+		relay.stopAcceptingWork(ctx)
+	})
+
+	// stop accepting connections, and wait for the requests in flight
+	if m.srv != nil {
+		if err = m.srv.Shutdown(ctx); err != nil {
+			m.log.WithError(err).Error("failed to wait for the requests in flight")
+		}
+	}
+	m.forEachRelay(relays, func(relay *RelayAPI) {
+		relay.drain(ctx, err == nil)
+		close(relay.stoppedC)
+	})
+	return err
+}
+
+// forEachRelay runs fn for the relays in parallel, and waits for all of them
+func (m *MultiNetworkAPI) forEachRelay(relays []*RelayAPI, fn func(relay *RelayAPI)) {
+	var wg sync.WaitGroup
+	for _, relay := range relays {
+		wg.Add(1)
+		go func(relay *RelayAPI) {
+			defer wg.Done()
+			fn(relay)
+		}(relay)
+	}
+	wg.Wait()
+}
+
+// ReloadBuilderStatuses reloads the builder statuses of all networks
+func (m *MultiNetworkAPI) ReloadBuilderStatuses() (err error) {
+	for _, name := range m.networks {
+		if reloadErr := m.relays[name].ReloadBuilderStatuses(); reloadErr != nil {
+			err = errors.Join(err, fmt.Errorf("network %s: %w", name, reloadErr))
+		}
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiNetworkAPI(t *testing.T) {
+	holesky := newTestBackend(t, 1)
+	holesky.relay.opts.EthNetDetails.Name = "holesky"
+	sepolia := newTestBackend(t, 1)
+	sepolia.relay.opts.EthNetDetails.Name = "sepolia"
+
+	t.Run("networks must be unique", func(t *testing.T) {
+		_, err := NewMultiNetworkAPI(MultiNetworkAPIOpts{Log: common.TestLog}, []*RelayAPI{holesky.relay, holesky.relay})
+		require.ErrorIs(t, err, ErrDuplicateNetwork)
+	})
+
+	m, err := NewMultiNetworkAPI(MultiNetworkAPIOpts{Log: common.TestLog}, []*RelayAPI{holesky.relay, sepolia.relay})
+	require.NoError(t, err)
+	router := m.getRouter()
+	requestHost := func(host, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		req.Host = host
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	request := func(path string) *httptest.ResponseRecorder {
+		return requestHost("relay.example.com", path)
+	}
+
+	t.Run("routes are scoped to the network", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request("/holesky"+pathStatus).Code)
+		require.Equal(t, http.StatusOK, request("/sepolia"+pathStatus).Code)
+		require.Equal(t, http.StatusNotFound, request(pathStatus).Code)
+		require.Equal(t, http.StatusNotFound, request("/mainnet"+pathStatus).Code)
+	})
+
+	t.Run("routes by the subdomain of the network", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, requestHost("holesky.relay.example.com", pathStatus).Code)
+		require.Equal(t, http.StatusOK, requestHost("Sepolia.relay.example.com:443", pathStatus).Code)
+		require.Equal(t, http.StatusNotFound, requestHost("mainnet.relay.example.com", pathStatus).Code)
+		require.Equal(t, http.StatusNotFound, requestHost("holesky", pathStatus).Code)
+
+		// the root of a network is its own
+		rr := requestHost("holesky.relay.example.com", "/")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NotContains(t, rr.Body.String(), "networks")
+	})
+
+	t.Run("root lists the networks", func(t *testing.T) {
+		rr := request("/")
+		require.Equal(t, http.StatusOK, rr.Code)
+		resp := struct {
+			Networks []string `json:"networks"`
+		}{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, []string{"holesky", "sepolia"}, resp.Networks)
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/eventbus"
+	"github.com/sirupsen/logrus"
+)
+
+// isCoveredByCollateral returns true if the builder is in optimistic mode and its collateral covers the bid value
+func (api *RelayAPI) isCoveredByCollateral(log *logrus.Entry, builderPubkey string, value *big.Int) bool {
+	collateralStr, err := api.redis.GetBlockBuilderCollateral(builderPubkey)
+	if err != nil {
+		log.WithError(err).Error("could not get builder collateral")
+		return false
+	} else if collateralStr == "" {
+		return false
+	}
+
+	collateral, ok := new(big.Int).SetString(collateralStr, 10)
+	if !ok {
+		log.Errorf("invalid builder collateral: %s", collateralStr)
+		return false
+	}
+	return value.Cmp(collateral) <= 0
+}
+
+// isValidationFailure returns whether the simulation error shows that the block is invalid, rather than that it couldn't
+// be validated (e.g. a timeout or an unavailable simulation node)
+func isValidationFailure(simErr error) bool {
+	var subErr *submissionError
+	if errors.As(simErr, &subErr) {
+		return subErr.status < http.StatusInternalServerError
+	}
+	return errors.Is(simErr, ErrSimulationFailed)
+}
+
+// simulateOptimisticBlock validates a block which was already accepted, and demotes the builder if the block is invalid.
+// ctx only carries the trace of the submission, the request context is gone by now (the simulation timeout still applies).
+func (api *RelayAPI) simulateOptimisticBlock(ctx context.Context, filtered *FilteredSubmission, validationRequestPayload *BuilderBlockValidationRequest) {
+	defer api.optimisticBlocksInFlight.Done()
+	log, payload := filtered.Log, filtered.Payload
+
+	t := time.Now()
+	simResult, simCached, simErr := api.simulateBlock(ctx, log, validationRequestPayload, true, false)
+	if simErr == nil {
+		if filterErr := api.filterSubmissionAfterSimulation(ctx, filtered, simResult); filterErr != nil {
+			simErr = filterErr
+		}
+	}
+	api.saveBlockSubmission(ctx, log, payload, simResult, simErr, filtered.ReceivedAt, true)
+
+	log = log.WithFields(logrus.Fields{
+		"simCached":  simCached,
+		"duration":   time.Since(t).Seconds(),
+		"numWaiting": api.blockSimQueue.currentCounter(),
+	})
+	if simErr == nil {
+		log.Info("optimistic block validation successful")
+		return
+	}
+
+	if !isValidationFailure(simErr) {
+		log.WithError(simErr).Error("optimistic block could not be validated")
+		return
+	}
+
+	log.WithError(simErr).Warn("optimistic block validation failed, demoting builder")
+	bidTrace := &common.BidTraceV2{
+		BidTrace:    *payload.Message(),
+		BlockNumber: payload.BlockNumber(),
+		NumTx:       uint64(payload.NumTx()),
+	}
+	api.demoteBuilder(log, bidTrace, simErr)
+}
+
+// demoteBuilder disables optimistic mode for the builder, and records the failed block along with any refund owed to the proposer
+func (api *RelayAPI) demoteBuilder(log *logrus.Entry, bidTrace *common.BidTraceV2, simErr error) {
+	// stop accepting optimistic blocks from this builder right away
+	err := api.redis.DeleteBlockBuilderCollateral(bidTrace.BuilderPubkey.String())
+	if err != nil {
+		log.WithError(err).Error("failed to remove builder collateral from redis")
+	}
+
+	err = api.db.InsertBuilderDemotion(bidTrace, simErr)
+	if err != nil {
+		log.WithError(err).Error("failed to save builder demotion")
+		return
+	}
+	api.publishEvent(common.EventBuilderDemoted, &eventbus.BuilderDemotion{
+		Slot:          bidTrace.Slot,
+		BuilderPubkey: bidTrace.BuilderPubkey.String(),
+		BlockHash:     bidTrace.BlockHash.String(),
+		Value:         bidTrace.Value.ToBig().String(),
+		Reason:        simErr.Error(),
+	})
+	api.notifyWebhooks(common.EventBuilderDemoted, fmt.Sprintf("relay: builder %s demoted in slot %d: %s", bidTrace.BuilderPubkey.String(), bidTrace.Slot, simErr.Error()), map[string]string{
+		"slot":           fmt.Sprint(bidTrace.Slot),
+		"builder_pubkey": bidTrace.BuilderPubkey.String(),
+		"block_hash":     bidTrace.BlockHash.String(),
+		"value":          bidTrace.Value.ToBig().String(),
+		"reason":         simErr.Error(),
+	})
+
+	// the payload might have been delivered already
+	api.checkOptimisticRefund(log, bidTrace.Slot, bidTrace.BlockHash.String())
+}
+
+// checkOptimisticRefund marks a refund as required if the block was both demoted and delivered
+func (api *RelayAPI) checkOptimisticRefund(log *logrus.Entry, slot uint64, blockHash string) {
+	refundRequired, err := api.db.SetBuilderDemotionRefundRequired(slot, blockHash)
+	if err != nil {
+		log.WithError(err).Error("failed to check builder demotion refund")
+	} else if refundRequired {
+		log.Warn("payload of a demoted optimistic block was delivered, builder owes the proposer a refund")
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+)
+
+// msIntoSlot returns the ms from the start of the slot until t, negative before the slot. ok is false if the genesis
+// time isn't known.
+func (api *RelayAPI) msIntoSlot(slot uint64, t time.Time) (ms int64, ok bool) {
+	if api.genesisInfo == nil {
+		return 0, false
+	}
+	slotStartTimestamp := api.genesisInfo.Data.GenesisTime + (slot * uint64(common.DurationPerSlot.Seconds()))
+	return t.UnixMilli() - int64(slotStartTimestamp*1000), true
+}
+
+// deliveredPayloadTiming returns when the proposer called getHeader and getPayload for the delivered payload, relative
+// to the slot start, and the getHeader policy in effect. The getHeader call can have been served by another instance,
+// so its time is taken from redis.
+func (api *RelayAPI) deliveredPayloadTiming(log *logrus.Entry, slot uint64, proposerPubkey string, getPayloadReceivedAt time.Time) database.DeliveredPayloadTiming {
+	timing := database.DeliveredPayloadTiming{GetHeaderPolicy: api.getHeaderPolicy.String()}
+	if ms, ok := api.msIntoSlot(slot, getPayloadReceivedAt); ok {
+		timing.GetPayloadMsIntoSlot = sql.NullInt64{Int64: ms, Valid: true}
+	}
+
+	getHeaderTime, err := api.redis.GetGetHeaderRequestTime(slot, proposerPubkey)
+	if err != nil {
+		log.WithError(err).Error("failed to get getHeader request time from redis")
+	} else if !getHeaderTime.IsZero() {
+		if ms, ok := api.msIntoSlot(slot, getHeaderTime); ok {
+			timing.GetHeaderMsIntoSlot = sql.NullInt64{Int64: ms, Valid: true}
+		}
+	}
+	return timing
+}
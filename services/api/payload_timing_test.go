@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveredPayloadTiming(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	relay := backend.relay
+	proposerPubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	slot := uint64(10)
+	slotStart := time.Unix(int64(slot*uint64(common.DurationPerSlot.Seconds())), 0)
+
+	// unknown genesis time
+	timing := relay.deliveredPayloadTiming(common.TestLog, slot, proposerPubkey, slotStart.Add(2*time.Second))
+	require.False(t, timing.GetHeaderMsIntoSlot.Valid)
+	require.False(t, timing.GetPayloadMsIntoSlot.Valid)
+
+	relay.genesisInfo = &beaconclient.GetGenesisResponse{}
+	timing = relay.deliveredPayloadTiming(common.TestLog, slot, proposerPubkey, slotStart.Add(2*time.Second))
+	require.False(t, timing.GetHeaderMsIntoSlot.Valid)
+	require.True(t, timing.GetPayloadMsIntoSlot.Valid)
+	require.Equal(t, int64(2000), timing.GetPayloadMsIntoSlot.Int64)
+
+	// getHeader is usually called just before the slot starts
+	err := backend.redis.SetGetHeaderRequestTime(slot, proposerPubkey, slotStart.Add(-150*time.Millisecond))
+	require.NoError(t, err)
+	timing = relay.deliveredPayloadTiming(common.TestLog, slot, proposerPubkey, slotStart.Add(2*time.Second))
+	require.True(t, timing.GetHeaderMsIntoSlot.Valid)
+	require.Equal(t, int64(-150), timing.GetHeaderMsIntoSlot.Int64)
+	require.Empty(t, timing.GetHeaderPolicy)
+
+	// the getHeader policy is recorded
+	relay.getHeaderPolicy = getHeaderPolicy{hasHoldUntil: true, holdUntil: time.Second}
+	timing = relay.deliveredPayloadTiming(common.TestLog, slot, proposerPubkey, slotStart.Add(2*time.Second))
+	require.Equal(t, "hold_until=1000ms", timing.GetHeaderPolicy)
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidPeerRelay = errors.New("invalid peer relay")
+
+	// trusted relays whose bids are accepted, as comma-separated URLs with the relay pubkey as user, like mev-boost:
+	// https://0xpubkey@relay.example.com
+	peerRelaysEnv = os.Getenv("PEER_RELAYS")
+
+	// whether the simulated bids of this relay are forwarded to the peer relays
+	forwardBidsToPeers = os.Getenv("PEER_RELAYS_FORWARD_BIDS") == "1"
+	peerForwardTimeout = time.Duration(cli.GetEnvInt("PEER_RELAY_FORWARD_TIMEOUT_MS", 1000)) * time.Millisecond
+
+	// how far the time a peer relay received a bid may be off from the time it arrives here, covering the forwarding
+	// and the clock drift between the relays
+	peerBidMaxAge = time.Duration(cli.GetEnvInt("PEER_BID_MAX_AGE_MS", 2000)) * time.Millisecond
+)
+
+// peerRelay is a trusted relay exchanging bids with this one. The payloads of its bids are fetched from its getPayload
+// endpoint once they win.
+type peerRelay struct {
+	pubkey string
+	url    string // without the pubkey
+}
+
+// parsePeerRelays parses the comma-separated peer relay URLs, returning the peers by pubkey
+func parsePeerRelays(s string) (map[string]*peerRelay, error) {
+	peers := make(map[string]*peerRelay)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPeerRelay, entry)
+		}
+		pubkey, err := boostTypes.HexToPubkey(u.User.Username())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPeerRelay, entry)
+		}
+		u.User = nil
+		peers[pubkey.String()] = &peerRelay{
+			pubkey: pubkey.String(),
+			url:    strings.TrimSuffix(u.String(), "/"),
+		}
+	}
+	return peers, nil
+}
+
+// handlePeerBid accepts a bid from a trusted peer relay. The peer already simulated the block, so the bid only has to
+// match the expected chain state, and goes into the top bid selection like a header-only submission.
+func (api *RelayAPI) handlePeerBid(w http.ResponseWriter, req *http.Request) {
+	receivedAt := time.Now().UTC()
+	log := api.requestLogger(req).WithFields(logrus.Fields{
+		"method":        "peerBid",
+		"contentLength": req.ContentLength,
+	})
+
+	payload := new(common.PeerBidRequest)
+	if err := json.NewDecoder(req.Body).Decode(payload); isBodyTooLarge(err) {
+		log.WithError(err).Warn("peer bid too large")
+		api.RespondErrorWithCode(w, http.StatusRequestEntityTooLarge, ErrorCodeRequestTooLarge, "request body too large")
+		return
+	} else if err != nil {
+		log.WithError(err).Warn("could not decode peer bid")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if payload.Message == nil || payload.Message.BidTrace == nil || payload.ExecutionPayloadHeader == nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing parts of the payload")
+		return
+	}
+
+	bid := payload.Message.BidTrace
+	peerReceivedAt := time.UnixMilli(int64(payload.Message.ReceivedAtMs)).UTC()
+	header := payload.ExecutionPayloadHeader
+	log = log.WithFields(logrus.Fields{
+		"slot":            bid.Slot,
+		"builderPubkey":   bid.BuilderPubkey.String(),
+		"blockHash":       bid.BlockHash.String(),
+		"peerRelayPubkey": payload.RelayPubkey.String(),
+		"peerReceivedAt":  payload.Message.ReceivedAtMs,
+	})
+
+	peer, ok := api.peerRelays[payload.RelayPubkey.String()]
+	if !ok {
+		log.Info("bid from unknown peer relay")
+		api.RespondErrorWithCode(w, http.StatusUnauthorized, ErrorCodeUnknownPeerRelay, "unknown peer relay")
+		return
+	}
+
+	ok, err := api.verifyBuilderSignature(payload.Message, payload.RelayPubkey[:], payload.Signature[:])
+	if !ok || err != nil {
+		log.WithError(err).Warn("could not verify peer relay signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "invalid signature")
+		return
+	}
+
+	// stale bids, e.g. replays of old messages, are rejected
+	if age := receivedAt.Sub(peerReceivedAt); age > peerBidMaxAge || age < -peerBidMaxAge {
+		log.WithField("age", age.String()).Info("peer bid is too old or in the future")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "peer bid is too old or in the future")
+		return
+	}
+
+	if err := api.checkHeaderBid(bid, header); err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, headerBidErrorCode(err), err.Error())
+		return
+	}
+
+	preSimErr := api.filterSubmissionPreSim(&FilteredSubmission{Log: log, preSim: headerPreSimSubmission(bid, header)})
+	if preSimErr != nil {
+		api.respondSubmissionError(w, preSimErr)
+		return
+	}
+
+	_, builderIsBlacklisted, err := api.getBlockBuilderStatus(bid.BuilderPubkey.String())
+	if err != nil {
+		log.WithError(err).Error("could not get block builder status")
+	}
+	if builderIsBlacklisted {
+		log.Info("builder is blacklisted")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// the transactions of peer bids are unknown, so they can't be checked against the blocklist
+	if api.ffEnableBlocklist {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeSubmissionNotAccepted, "peer bids are not accepted with the blocklist enabled")
+		return
+	}
+
+	// the transactions of peer bids are unknown, so they can't be checked against the inclusion constraints
+	if api.ffInclusionConstraints {
+		hasConstraints, err := api.hasInclusionConstraints(bid.Slot)
+		if err != nil {
+			log.WithError(err).Error("could not get inclusion constraints")
+			api.RespondErrorWithCode(w, http.StatusServiceUnavailable, SubmissionErrInclusionConstraintsUnavailable, "inclusion constraints are unavailable")
+			return
+		} else if hasConstraints {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, SubmissionErrInclusionConstraints, "peer bids are not accepted for slots with inclusion constraints")
+			return
+		}
+	}
+
+	// Ensure this bid is newer than the latest one of the builder, by the time the peer relay received it, so it doesn't
+	// replace a newer submission to this relay (e.g. a cancellation), and a replayed peer bid is rejected
+	latestPayloadReceivedAt, err := api.redis.GetBuilderLatestPayloadReceivedAt(bid.Slot, bid.BuilderPubkey.String(), bid.ParentHash.String(), bid.ProposerPubkey.String())
+	if err != nil {
+		log.WithError(err).Error("failed getting latest payload receivedAt from redis")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not check the latest submission of the builder")
+		return
+	} else if peerReceivedAt.UnixMilli() <= latestPayloadReceivedAt {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeNewerSubmission, "already using a newer payload")
+		return
+	}
+
+	// the provenance is saved first, so a failed payload fetch is never blamed on the builder
+	err = api.redis.SavePeerBidRelay(bid.Slot, bid.ProposerPubkey.String(), bid.BlockHash.String(), peer.pubkey)
+	if err != nil {
+		log.WithError(err).Error("could not save peer bid relay")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save peer bid relay")
+		return
+	}
+
+	err = api.saveHeaderBid(bid, header, peer.url+pathGetPayload, peerReceivedAt)
+	if err != nil {
+		log.WithError(err).Error("could not save peer bid")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save peer bid")
+		return
+	}
+
+	api.runInBackground(func() {
+		err := api.db.SavePeerBid(database.PeerBidEntry{
+			ReceivedAt:           receivedAt,
+			PeerRelayPubkey:      peer.pubkey,
+			Slot:                 bid.Slot,
+			ParentHash:           bid.ParentHash.String(),
+			BlockHash:            bid.BlockHash.String(),
+			BuilderPubkey:        bid.BuilderPubkey.String(),
+			ProposerPubkey:       bid.ProposerPubkey.String(),
+			ProposerFeeRecipient: bid.ProposerFeeRecipient.String(),
+			GasUsed:              bid.GasUsed,
+			GasLimit:             bid.GasLimit,
+			Value:                bid.Value.ToBig().String(),
+		})
+		if err != nil {
+			log.WithError(err).Error("failed to save peer bid to database")
+		}
+	})
+
+	log.WithFields(logrus.Fields{
+		"proposerPubkey": bid.ProposerPubkey.String(),
+		"value":          bid.Value.String(),
+	}).Info("received bid from peer relay")
+	w.WriteHeader(http.StatusOK)
+}
+
+// forwardBidToPeers signs the bid of a simulated submission, with the time it was received, with the relay key and
+// sends it to the peer relays
+func (api *RelayAPI) forwardBidToPeers(log *logrus.Entry, payload *common.BuilderSubmitBlockRequest, getHeaderResponse *common.GetHeaderResponse, receivedAt time.Time) {
+	if getHeaderResponse.Capella == nil || getHeaderResponse.Capella.Capella == nil {
+		return
+	}
+	bid := &common.PeerBid{
+		BidTrace:     payload.Message(),
+		ReceivedAtMs: uint64(receivedAt.UnixMilli()),
+	}
+	sig, err := boostTypes.SignMessage(bid, api.opts.EthNetDetails.DomainBuilder, api.blsSk)
+	if err != nil {
+		log.WithError(err).Error("could not sign bid for peer relays")
+		return
+	}
+	reqBody, err := json.Marshal(&common.PeerBidRequest{
+		Message:                bid,
+		ExecutionPayloadHeader: getHeaderResponse.Capella.Capella.Message.Header,
+		RelayPubkey:            *api.publicKey,
+		Signature:              sig,
+	})
+	if err != nil {
+		log.WithError(err).Error("could not encode bid for peer relays")
+		return
+	}
+
+	for _, peer := range api.peerRelays {
+		peer := peer
+		api.runInBackground(func() {
+			log := log.WithField("peerRelayPubkey", peer.pubkey)
+			client := http.Client{Timeout: peerForwardTimeout}
+			resp, err := client.Post(peer.url+pathPeerBids, "application/json", bytes.NewReader(reqBody))
+			if err != nil {
+				log.WithError(err).Warn("failed to forward bid to peer relay")
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				log.WithField("statusCode", resp.StatusCode).Warn("peer relay rejected the bid")
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeerRelays(t *testing.T) {
+	peers, err := parsePeerRelays("")
+	require.NoError(t, err)
+	require.Len(t, peers, 0)
+
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	peers, err = parsePeerRelays("https://" + pubkey + "@relay.example.com/, http://" + pubkey + "@localhost:9062")
+	require.NoError(t, err)
+	require.Equal(t, map[string]*peerRelay{pubkey: {pubkey: pubkey, url: "http://localhost:9062"}}, peers)
+
+	for _, s := range []string{"https://relay.example.com", "https://0x1234@relay.example.com", "ftp://" + pubkey + "@relay.example.com", pubkey} {
+		_, err = parsePeerRelays(s)
+		require.ErrorIs(t, err, ErrInvalidPeerRelay, s)
+	}
+}
+
+func TestPeerBidAuthentication(t *testing.T) {
+	backend := newTestBackend(t, 1)
+
+	sk, _, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	pubkey, err := types.BlsPublicKeyToPublicKey(bls.PublicKeyFromSecretKey(sk))
+	require.NoError(t, err)
+
+	backend.relay.peerRelays = map[string]*peerRelay{pubkey.String(): {pubkey: pubkey.String(), url: "http://localhost:9062"}}
+
+	signedPeerBid := func(receivedAt time.Time) *common.PeerBidRequest {
+		bid := &common.PeerBid{
+			BidTrace:     &apiv1.BidTrace{Slot: 1, Value: uint256.NewInt(0)},
+			ReceivedAtMs: uint64(receivedAt.UnixMilli()),
+		}
+		sig, err := types.SignMessage(bid, builderSigningDomain, sk)
+		require.NoError(t, err)
+		return &common.PeerBidRequest{
+			Message:                bid,
+			ExecutionPayloadHeader: &consensuscapella.ExecutionPayloadHeader{},
+			RelayPubkey:            pubkey,
+			Signature:              sig,
+		}
+	}
+	payload := signedPeerBid(time.Now())
+
+	t.Run("unknown peer relay", func(t *testing.T) {
+		unknown := *payload
+		unknown.RelayPubkey = *backend.relay.publicKey
+		rr := backend.request(http.MethodPost, pathPeerBids, &unknown)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrorCodeUnknownPeerRelay)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		invalid := *payload
+		invalid.Signature = types.Signature{}
+		rr := backend.request(http.MethodPost, pathPeerBids, &invalid)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrorCodeInvalidSignature)
+	})
+
+	t.Run("signed timestamp", func(t *testing.T) {
+		changed := signedPeerBid(time.Now())
+		changed.Message.ReceivedAtMs++
+		rr := backend.request(http.MethodPost, pathPeerBids, changed)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid signature")
+	})
+
+	t.Run("stale bid", func(t *testing.T) {
+		for _, receivedAt := range []time.Time{time.Now().Add(-peerBidMaxAge - time.Second), time.Now().Add(peerBidMaxAge + time.Second)} {
+			rr := backend.request(http.MethodPost, pathPeerBids, signedPeerBid(receivedAt))
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), "too old")
+		}
+	})
+
+	t.Run("valid signature", func(t *testing.T) {
+		// passes the authentication, and is then rejected for its value
+		rr := backend.request(http.MethodPost, pathPeerBids, payload)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrHeaderBidZeroValue.Error())
+		require.Contains(t, rr.Body.String(), ErrorCodeZeroValueBid)
+	})
+}
+
+func TestPeerBidAccepted(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	relay := backend.relay
+	relay.genesisInfo = &beaconclient.GetGenesisResponse{}
+	relay.headSlot.Store(10)
+
+	sk, _, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	pubkey, err := types.BlsPublicKeyToPublicKey(bls.PublicKeyFromSecretKey(sk))
+	require.NoError(t, err)
+	relay.peerRelays = map[string]*peerRelay{pubkey.String(): {pubkey: pubkey.String(), url: "http://peer:9062"}}
+
+	// the expected chain state of slot 11
+	feeRecipient := types.Address{0xfe}
+	proposerPubkey := phase0.BLSPubKey{0x01}
+	parentHash := phase0.Hash32{0xaa}
+	relay.proposerDuties.set([]types.BuilderGetValidatorsResponseEntry{
+		{Slot: 11, Entry: &types.SignedValidatorRegistration{Message: &types.RegisterValidatorRequestMessage{FeeRecipient: feeRecipient}}},
+	}, 10)
+	relay.expectedPrevRandao = randaoHelper{slot: 11, prevRandao: fmt.Sprintf("%#x", phase0.Root{0x02})}
+	relay.expectedParentHash = parentHashHelper{slot: 11, parentHash: parentHash.String()}
+	relay.expectedWithdrawalsRoot = withdrawalsHelper{slot: 11, root: phase0.Root{0x03}}
+
+	signedPeerBid := func(builderPubkey phase0.BLSPubKey, blockHash phase0.Hash32, value uint64, receivedAt time.Time) *common.PeerBidRequest {
+		bid := &common.PeerBid{
+			BidTrace: &apiv1.BidTrace{
+				Slot:                 11,
+				ParentHash:           parentHash,
+				BlockHash:            blockHash,
+				BuilderPubkey:        builderPubkey,
+				ProposerPubkey:       proposerPubkey,
+				ProposerFeeRecipient: bellatrix.ExecutionAddress(feeRecipient),
+				GasLimit:             30_000_000,
+				GasUsed:              100,
+				Value:                uint256.NewInt(value),
+			},
+			ReceivedAtMs: uint64(receivedAt.UnixMilli()),
+		}
+		sig, err := types.SignMessage(bid, builderSigningDomain, sk)
+		require.NoError(t, err)
+		return &common.PeerBidRequest{
+			Message: bid,
+			ExecutionPayloadHeader: &consensuscapella.ExecutionPayloadHeader{
+				ParentHash:      parentHash,
+				BlockHash:       blockHash,
+				GasLimit:        30_000_000,
+				GasUsed:         100,
+				Timestamp:       11 * 12,
+				PrevRandao:      [32]byte{0x02},
+				WithdrawalsRoot: phase0.Root{0x03},
+				ExtraData:       []byte{},
+			},
+			RelayPubkey: pubkey,
+			Signature:   sig,
+		}
+	}
+	topBidValue := func() string {
+		bid, err := backend.redis.GetBestBid(11, parentHash.String(), proposerPubkey.String())
+		require.NoError(t, err)
+		require.NotNil(t, bid)
+		return bid.Value().String()
+	}
+
+	builder1, builder2 := phase0.BLSPubKey{0xb1}, phase0.BLSPubKey{0xb2}
+	now := time.Now()
+	rr := backend.request(http.MethodPost, pathPeerBids, signedPeerBid(builder1, phase0.Hash32{0x11}, 5, now))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "5", topBidValue())
+
+	// the bid is fetched from the peer once it wins
+	peerRelayPubkey, err := backend.redis.GetPeerBidRelay(11, proposerPubkey.String(), phase0.Hash32{0x11}.String())
+	require.NoError(t, err)
+	require.Equal(t, pubkey.String(), peerRelayPubkey)
+	payloadURL, err := backend.redis.GetDeferredPayloadURL(11, proposerPubkey.String(), phase0.Hash32{0x11}.String())
+	require.NoError(t, err)
+	require.Equal(t, "http://peer:9062"+pathGetPayload, payloadURL)
+
+	// a higher bid of another builder becomes the top bid
+	rr = backend.request(http.MethodPost, pathPeerBids, signedPeerBid(builder2, phase0.Hash32{0x21}, 7, now))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "7", topBidValue())
+
+	// a newer, lower bid replaces the builder's previous one
+	rr = backend.request(http.MethodPost, pathPeerBids, signedPeerBid(builder2, phase0.Hash32{0x22}, 3, now.Add(100*time.Millisecond)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "5", topBidValue())
+
+	// but not by a bid the peer received before it
+	rr = backend.request(http.MethodPost, pathPeerBids, signedPeerBid(builder2, phase0.Hash32{0x23}, 9, now.Add(50*time.Millisecond)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), ErrorCodeNewerSubmission)
+	require.Equal(t, "5", topBidValue())
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/go-redis/redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Error codes returned for submissions rejected before simulation. The stale ones mean the builder was building on
+// an outdated view of the chain, the others that the block can never be valid.
+const (
+	SubmissionErrSlotPast           = "slot_past"
+	SubmissionErrSlotDelivered      = "slot_delivered"
+	SubmissionErrParentHashMismatch = "parent_hash_mismatch"
+	SubmissionErrAfterBidCutoff     = "after_bid_cutoff"
+
+	SubmissionErrTimestampMismatch    = "timestamp_mismatch"
+	SubmissionErrUnknownSlotDuty      = "unknown_slot_duty"
+	SubmissionErrFeeRecipientMismatch = "fee_recipient_mismatch"
+	SubmissionErrPrevRandaoUnknown    = "prev_randao_unknown"
+	SubmissionErrPrevRandaoMismatch   = "prev_randao_mismatch"
+	SubmissionErrWithdrawalsUnknown   = "withdrawals_unknown"
+	SubmissionErrWithdrawalsMismatch  = "withdrawals_root_mismatch"
+	SubmissionErrParentRootUnknown    = "parent_beacon_block_root_unknown"
+	SubmissionErrSimulationFailed     = "simulation_failed"
+	SubmissionErrSimulationTimeout    = "simulation_timeout"
+	SubmissionErrSimulationQueueFull  = "simulation_queue_full"
+	SubmissionErrBelowMinBid          = "below_min_bid"
+
+	SubmissionErrBlocklisted          = "blocklisted_address"
+	SubmissionErrBlocklistUnavailable = "blocklist_unavailable"
+
+	SubmissionErrInclusionConstraints            = "inclusion_constraints_unmet"
+	SubmissionErrInclusionConstraintsUnavailable = "inclusion_constraints_unavailable"
+)
+
+// submissionError is a submission rejected by the local checks, with the status and error code to respond with
+type submissionError struct {
+	status     int
+	code       string
+	msg        string
+	retryAfter int // seconds, sent as Retry-After header if set
+}
+
+func (e *submissionError) Error() string {
+	return e.msg
+}
+
+func newSubmissionError(status int, code, format string, args ...any) *submissionError {
+	return &submissionError{status: status, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// respondSubmissionError responds with the status and error code of the rejected submission
+func (api *RelayAPI) respondSubmissionError(w http.ResponseWriter, e *submissionError) {
+	if e.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(e.retryAfter))
+	}
+	api.RespondErrorWithCode(w, e.status, e.code, e.msg)
+}
+
+// preSimSubmission holds the fields of a full or header-only submission which can be validated without the simulator
+type preSimSubmission struct {
+	slot                 uint64
+	parentHash           string
+	timestamp            uint64
+	prevRandao           string
+	proposerFeeRecipient string
+	withdrawalsRoot      *phase0.Root // nil for pre-capella submissions
+}
+
+// filterSubmissionPreSim applies the pre-simulation filters, which check the submission against the relay's view of the
+// chain, so that invalid or stale submissions are rejected before verifying their signature and spending a simulation
+// on them. They are the only filters applied to header submissions and peer bids, which have no payload. Sets the
+// proposer duty of the slot.
+func (api *RelayAPI) filterSubmissionPreSim(s *FilteredSubmission) *submissionError {
+	return api.filterSubmission(s, api.preSimFilters, func(filter SubmissionFilter) error {
+		return filter.BeforeSimulation(context.Background(), s)
+	})
+}
+
+// staleSubmissionFilter rejects the submissions of slots which are over, or whose bid is fixed already. The checks are
+// repeated after the simulation, which may take long enough for the payload of the slot to be delivered.
+type staleSubmissionFilter struct {
+	api *RelayAPI
+}
+
+func (f *staleSubmissionFilter) Name() string {
+	return "stale-submission"
+}
+
+func (f *staleSubmissionFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	if f.api.ffLoadTestMode {
+		return nil
+	}
+	if err := f.checkSlot(s.Log, s.preSim.slot); err != nil {
+		return err
+	}
+
+	// only checked if the head is already known, the simulation catches the rest
+	f.api.expectedParentHashLock.RLock()
+	expectedParentHash := f.api.expectedParentHash
+	f.api.expectedParentHashLock.RUnlock()
+	if expectedParentHash.slot == s.preSim.slot && !strings.EqualFold(expectedParentHash.parentHash, s.preSim.parentHash) {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrParentHashMismatch, "incorrect parent hash - got: %s, expected: %s", s.preSim.parentHash, expectedParentHash.parentHash)
+	}
+	return nil
+}
+
+// AfterSimulation skips the optimistic submissions, whose bids were accepted before the simulation
+func (f *staleSubmissionFilter) AfterSimulation(ctx context.Context, s *FilteredSubmission, simResult *BlockSimulationResult) error {
+	if f.api.ffLoadTestMode || s.Optimistic {
+		return nil
+	}
+	return f.checkSlot(s.Log, s.Payload.Slot())
+}
+
+func (f *staleSubmissionFilter) checkSlot(log *logrus.Entry, slot uint64) error {
+	if slot <= f.api.headSlot.Load() {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrSlotPast, "submission for past slot")
+	}
+
+	// the bid of the slot is fixed at the cutoff of the getHeader policy
+	if f.api.isAfterBidCutoff(slot, time.Now()) {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrAfterBidCutoff, "submission after the bid cutoff of %dms into the slot", f.api.getHeaderPolicy.bidCutoff.Milliseconds())
+	}
+
+	slotStr, err := f.api.redis.GetStats(datastore.RedisStatsFieldSlotLastPayloadDelivered)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.WithError(err).Error("failed to get delivered payload slot from redis")
+	} else if err == nil {
+		slotLastPayloadDelivered, err := strconv.ParseUint(slotStr, 10, 64)
+		if err != nil {
+			log.WithError(err).Errorf("failed to parse delivered payload slot from redis: %s", slotStr)
+		} else if slot <= slotLastPayloadDelivered {
+			return newSubmissionError(http.StatusBadRequest, SubmissionErrSlotDelivered, "payload for this slot was already delivered")
+		}
+	}
+	return nil
+}
+
+// slotAttributesFilter rejects the submissions which don't match the attributes of the slot (timestamp, proposer fee
+// recipient, prev_randao and withdrawals), and sets the proposer duty of the slot
+type slotAttributesFilter struct {
+	NoopSubmissionFilter
+	api *RelayAPI
+}
+
+func (f *slotAttributesFilter) Name() string {
+	return "slot-attributes"
+}
+
+func (f *slotAttributesFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	if f.api.ffLoadTestMode {
+		s.SlotDuty = loadTestSlotDuty
+		return nil
+	}
+
+	expectedTimestamp := f.api.genesisInfo.Data.GenesisTime + (s.preSim.slot * uint64(common.DurationPerSlot.Seconds()))
+	if s.preSim.timestamp != expectedTimestamp {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrTimestampMismatch, "incorrect timestamp. got %d, expected %d", s.preSim.timestamp, expectedTimestamp)
+	}
+
+	slotDuty := f.api.proposerDuties.dutyForSlot(s.preSim.slot)
+	if slotDuty == nil {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrUnknownSlotDuty, "could not find slot duty")
+	} else if !strings.EqualFold(slotDuty.FeeRecipient.String(), s.preSim.proposerFeeRecipient) {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrFeeRecipientMismatch, "fee recipient does not match")
+	}
+
+	f.api.expectedPrevRandaoLock.RLock()
+	expectedRandao := f.api.expectedPrevRandao
+	f.api.expectedPrevRandaoLock.RUnlock()
+	if expectedRandao.slot != s.preSim.slot { // we still don't have the prevrandao yet
+		return newSubmissionError(http.StatusInternalServerError, SubmissionErrPrevRandaoUnknown, "prev_randao is not known yet")
+	} else if expectedRandao.prevRandao != s.preSim.prevRandao {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrPrevRandaoMismatch, "incorrect prev_randao - got: %s, expected: %s", s.preSim.prevRandao, expectedRandao.prevRandao)
+	}
+
+	if s.preSim.withdrawalsRoot != nil {
+		f.api.expectedWithdrawalsLock.RLock()
+		expectedWithdrawalsRoot := f.api.expectedWithdrawalsRoot
+		f.api.expectedWithdrawalsLock.RUnlock()
+		if expectedWithdrawalsRoot.slot != s.preSim.slot { // we still don't have the withdrawals yet
+			return newSubmissionError(http.StatusInternalServerError, SubmissionErrWithdrawalsUnknown, "withdrawals are not known yet")
+		} else if expectedWithdrawalsRoot.root != *s.preSim.withdrawalsRoot {
+			return newSubmissionError(http.StatusBadRequest, SubmissionErrWithdrawalsMismatch, "incorrect withdrawals root - got: %s, expected: %s", s.preSim.withdrawalsRoot.String(), expectedWithdrawalsRoot.root.String())
+		}
+	}
+
+	s.SlotDuty = slotDuty
+	return nil
+}
+
+// ensure the pre-simulation filters implement the interface
+var (
+	_ SubmissionFilter = (*staleSubmissionFilter)(nil)
+	_ SubmissionFilter = (*slotAttributesFilter)(nil)
+)
+
+// updateExpectedParentHash stores the execution block hash of the new head, which submissions for the next slot have to build on
+func (api *RelayAPI) updateExpectedParentHash(headSlot uint64) {
+	log := api.log.WithField("slot", headSlot)
+	block, err := api.beaconClient.GetBlock(strconv.FormatUint(headSlot, 10))
+	if err != nil || block == nil {
+		log.WithError(err).Warn("failed to get head block from beacon node")
+		return
+	}
+
+	// deneb blocks commit to the root of their parent beacon block, which the simulation needs
+	var parentBeaconBlockRoot *phase0.Root
+	if api.isDeneb(headSlot + 1) {
+		parentBeaconBlockRoot, err = api.getBlockRoot(headSlot)
+		if err != nil {
+			log.WithError(err).Warn("failed to get head block root from beacon node")
+		}
+	}
+
+	blockHash := block.Data.Message.Body.ExecutionPayload.BlockHash.String()
+	api.expectedParentHashLock.Lock()
+	updated := headSlot+1 > api.expectedParentHash.slot
+	if updated {
+		api.expectedParentHash = parentHashHelper{
+			slot:                  headSlot + 1,
+			parentHash:            blockHash,
+			parentBeaconBlockRoot: parentBeaconBlockRoot,
+		}
+		log.Infof("updated expected parent hash to %s for slot %d", blockHash, headSlot+1)
+	}
+	api.expectedParentHashLock.Unlock()
+
+	if updated {
+		api.slots.attributeKnown(headSlot+1, slotAttributeParentHash)
+	}
+}
+
+// getBlockRoot returns the root of the block of the slot from the beacon node
+func (api *RelayAPI) getBlockRoot(slot uint64) (*phase0.Root, error) {
+	resp, err := api.beaconClient.GetBlockRoot(strconv.FormatUint(slot, 10))
+	if err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, ErrEmptyPayload
+	}
+	b, err := hexutil.Decode(resp.Data.Root)
+	if err != nil || len(b) != len(phase0.Root{}) {
+		return nil, fmt.Errorf("invalid block root: %s", resp.Data.Root)
+	}
+	root := phase0.Root(b)
+	return &root, nil
+}
+
+// expectedParentBeaconBlockRoot returns the parent beacon block root of the slot, with which deneb blocks are
+// simulated
+func (api *RelayAPI) expectedParentBeaconBlockRoot(slot uint64) (*phase0.Root, *submissionError) {
+	api.expectedParentHashLock.RLock()
+	expectedParentHash := api.expectedParentHash
+	api.expectedParentHashLock.RUnlock()
+	if expectedParentHash.slot != slot || expectedParentHash.parentBeaconBlockRoot == nil {
+		return nil, newSubmissionError(http.StatusInternalServerError, SubmissionErrParentRootUnknown, "parent beacon block root is not known yet")
+	}
+	return expectedParentHash.parentBeaconBlockRoot, nil
+}
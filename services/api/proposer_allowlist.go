@@ -0,0 +1,43 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+)
+
+var proposerAllowlistRefreshInterval = time.Duration(cli.GetEnvInt("PROPOSER_ALLOWLIST_REFRESH_INTERVAL_SEC", 60)) * time.Second
+
+// startProposerAllowlistUpdates periodically reloads the proposer allowlist the housekeeper saved to redis
+func (api *RelayAPI) startProposerAllowlistUpdates() {
+	ticker := time.NewTicker(proposerAllowlistRefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		api.updateProposerAllowlist()
+	}
+}
+
+// updateProposerAllowlist keeps the previous allowlist if it can't be loaded from redis
+func (api *RelayAPI) updateProposerAllowlist() {
+	allowlist, err := api.redis.GetProposerAllowlist()
+	if err != nil {
+		api.log.WithError(err).Error("failed to get proposer allowlist from redis")
+		return
+	} else if allowlist == nil {
+		api.log.Warn("no proposer allowlist in redis, no validator is served until the housekeeper loads one (--proposer-allowlist-source)")
+		return
+	}
+
+	api.proposerAllowlistLock.Lock()
+	api.proposerAllowlist = allowlist
+	api.proposerAllowlistLock.Unlock()
+	api.log.WithField("numPubkeys", len(allowlist)).Debug("updated proposer allowlist")
+}
+
+// isAllowedProposer returns whether the relay serves the validator, no validator is allowed until the allowlist is loaded
+func (api *RelayAPI) isAllowedProposer(pubkey string) bool {
+	api.proposerAllowlistLock.RLock()
+	defer api.proposerAllowlistLock.RUnlock()
+	return api.proposerAllowlist[strings.ToLower(pubkey)]
+}
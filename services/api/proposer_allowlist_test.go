@@ -0,0 +1,26 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposerAllowlist(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+	// nothing is served until the allowlist is loaded
+	backend.relay.updateProposerAllowlist()
+	require.False(t, backend.relay.isAllowedProposer(pubkey))
+
+	require.NoError(t, backend.redis.SetProposerAllowlist([]string{pubkey}))
+	backend.relay.updateProposerAllowlist()
+	require.True(t, backend.relay.isAllowedProposer(pubkey))
+	require.False(t, backend.relay.isAllowedProposer("0xb5246e299aeb782fbc7c91b41b3284245b1ed5206134b0028b81dfb974e5900616c67847c2354479934fc4bb75519ee1"))
+
+	// an emptied allowlist serves no validator
+	require.NoError(t, backend.redis.SetProposerAllowlist(nil))
+	backend.relay.updateProposerAllowlist()
+	require.False(t, backend.relay.isAllowedProposer(pubkey))
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	publishConfirmWindow   = time.Duration(cli.GetEnvInt("PUBLISH_CONFIRM_WINDOW_MS", 4000)) * time.Millisecond
+	publishConfirmInterval = time.Duration(cli.GetEnvInt("PUBLISH_CONFIRM_INTERVAL_MS", 500)) * time.Millisecond
+	publishMaxRetries      = cli.GetEnvInt("PUBLISH_MAX_RETRIES", 2)
+)
+
+// publishAndConfirmBlock publishes the block, waits for it to show up on the beacon node(s) and re-broadcasts it to all
+// beacon nodes if it wasn't seen within the confirmation window. The outcome is stored with the delivered payload, once
+// payloadSaved is closed after saving it.
+func (api *RelayAPI) publishAndConfirmBlock(ctx context.Context, log *logrus.Entry, block *common.SignedBeaconBlock, proposerPubkey string, getPayloadReceivedAt time.Time, payloadSaved <-chan struct{}) {
+	slot := block.Slot()
+	blockHash := strings.ToLower(block.BlockHash())
+
+	ctx, span := common.Tracer.Start(ctx, "publishBlock")
+	defer span.End()
+
+	publishStart := time.Now()
+	_, _ = api.beaconClient.PublishBlock(ctx, block) // errors are logged inside
+	publishDuration := time.Since(publishStart)
+	broadcastDuration := time.Since(getPayloadReceivedAt)
+	numAttempts := uint64(1)
+
+	confirmed := false
+	for {
+		confirmed = api.waitForBlockOnChain(slot, blockHash, publishConfirmWindow)
+		if confirmed || numAttempts > uint64(publishMaxRetries) {
+			break
+		}
+
+		log.WithField("numAttempts", numAttempts).Warn("published block not seen on beacon node, broadcasting to all beacon nodes")
+		_, _ = api.beaconClient.BroadcastBlock(ctx, block) // errors are logged inside
+		numAttempts++
+	}
+
+	span.SetAttributes(attribute.Bool("confirmed", confirmed), attribute.Int64("attempts", int64(numAttempts)))
+	log = log.WithFields(logrus.Fields{
+		"publishConfirmed":    confirmed,
+		"publishAttempts":     numAttempts,
+		"publishDurationMs":   publishDuration.Milliseconds(),
+		"broadcastDurationMs": broadcastDuration.Milliseconds(),
+	})
+	if confirmed {
+		log.Info("published block confirmed")
+	} else {
+		log.Error("published block could not be confirmed")
+		api.notifyWebhooks(common.EventBeaconPublishFailed, fmt.Sprintf("relay: published block of slot %d not seen on the beacon nodes after %d attempts", slot, numAttempts), map[string]string{
+			"slot":            fmt.Sprint(slot),
+			"proposer_pubkey": proposerPubkey,
+			"block_hash":      blockHash,
+			"attempts":        fmt.Sprint(numAttempts),
+		})
+	}
+
+	// the delivered payload is saved concurrently, the status can only be written to it afterwards
+	<-payloadSaved
+	err := api.db.SetDeliveredPayloadPublishStatus(slot, proposerPubkey, blockHash, confirmed, numAttempts, publishDuration, broadcastDuration)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Error("failed to save publish status, the delivered payload wasn't saved")
+	} else if err != nil {
+		log.WithError(err).Error("failed to save publish status of delivered payload")
+	}
+}
+
+// waitForBlockOnChain polls the beacon node for the block at the given slot until its execution block hash matches, or the timeout is reached
+func (api *RelayAPI) waitForBlockOnChain(slot uint64, blockHash string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		block, err := api.beaconClient.GetBlock(fmt.Sprint(slot))
+		if err == nil && block != nil && strings.EqualFold(block.Data.Message.Body.ExecutionPayload.BlockHash.String(), blockHash) {
+			return true
+		}
+
+		if time.Now().Add(publishConfirmInterval).After(deadline) {
+			return false
+		}
+		time.Sleep(publishConfirmInterval)
+	}
+}
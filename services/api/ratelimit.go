@@ -0,0 +1,286 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	uberatomic "go.uber.org/atomic"
+)
+
+var (
+	// token-bucket settings, a rate of 0 disables the respective limiter
+	rateLimitIPPerSec      = cli.GetEnvInt("RATE_LIMIT_IP_PER_SEC", 0)
+	rateLimitIPBurst       = cli.GetEnvInt("RATE_LIMIT_IP_BURST", 50)
+	rateLimitPubkeyPerSec  = cli.GetEnvInt("RATE_LIMIT_PUBKEY_PER_SEC", 0)
+	rateLimitPubkeyBurst   = cli.GetEnvInt("RATE_LIMIT_PUBKEY_BURST", 10)
+	rateLimitAPIKeyPerSec  = cli.GetEnvInt("RATE_LIMIT_BUILDER_API_KEY_PER_SEC", 0)
+	rateLimitAPIKeyBurst   = cli.GetEnvInt("RATE_LIMIT_BUILDER_API_KEY_BURST", 20)
+	rateLimitBucketExpiry  = 10 * time.Minute
+	rateLimitCleanupPeriod = time.Minute
+
+	// number of reverse proxies in front of the relay, which append the address of their client to X-Forwarded-For
+	rateLimitTrustedProxies = cli.GetEnvInt("RATE_LIMIT_TRUSTED_PROXIES", 0)
+
+	// maximum number of block submissions per builder and slot, 0 for no limit
+	builderSubmissionsPerSlot         = cli.GetEnvInt("BUILDER_SUBMISSIONS_PER_SLOT", 0)
+	builderSubmissionsPerSlotHighPrio = cli.GetEnvInt("BUILDER_SUBMISSIONS_PER_SLOT_HIGHPRIO", 0)
+)
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter is a keyed token-bucket rate limiter
+type RateLimiter struct {
+	ratePerSec float64
+	burst      float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+
+	numAllowed  uberatomic.Uint64
+	numRejected uberatomic.Uint64
+}
+
+// NewRateLimiter returns a rate limiter allowing ratePerSec requests per key with the given burst. Returns nil if ratePerSec is 0.
+func NewRateLimiter(ratePerSec, burst int) *RateLimiter {
+	if ratePerSec <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		ratePerSec: float64(ratePerSec),
+		burst:      float64(burst),
+		buckets:    make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes a token for the given key and returns false if there is none left
+func (r *RateLimiter) Allow(key string) bool {
+	if r == nil {
+		return true
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, found := r.buckets[key]
+	if !found {
+		bucket = &tokenBucket{tokens: r.burst, lastSeen: now}
+		r.buckets[key] = bucket
+	}
+
+	// refill since last request
+	bucket.tokens += now.Sub(bucket.lastSeen).Seconds() * r.ratePerSec
+	if bucket.tokens > r.burst {
+		bucket.tokens = r.burst
+	}
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		r.numRejected.Inc()
+		return false
+	}
+	bucket.tokens--
+	r.numAllowed.Inc()
+	return true
+}
+
+// NumRejected returns the total number of requests that were rate limited
+func (r *RateLimiter) NumRejected() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.numRejected.Load()
+}
+
+// NumAllowed returns the total number of requests that were allowed
+func (r *RateLimiter) NumAllowed() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.numAllowed.Load()
+}
+
+// cleanup removes buckets that were not used for longer than the expiry
+func (r *RateLimiter) cleanup(expiry time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, bucket := range r.buckets {
+		if time.Since(bucket.lastSeen) > expiry {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// startCleanupLoop regularly removes stale buckets, to avoid unbounded memory growth, and logs the limiter stats
+func (r *RateLimiter) startCleanupLoop(log *logrus.Entry) {
+	if r == nil {
+		return
+	}
+	for {
+		time.Sleep(rateLimitCleanupPeriod)
+		r.cleanup(rateLimitBucketExpiry)
+		log.WithFields(logrus.Fields{
+			"numAllowed":  r.NumAllowed(),
+			"numRejected": r.NumRejected(),
+		}).Info("rate limiter stats")
+	}
+}
+
+// rateLimitIP returns the client IP requests are limited by: the X-Forwarded-For entry appended by the outermost of the
+// trusted proxies, or the remote address without proxies. The entries before it are sent by the client, and can't be
+// used as they could be changed with every request. With fewer entries than trusted proxies, the request didn't pass
+// all of them, and the remote address is used.
+func rateLimitIP(req *http.Request, trustedProxies int) string {
+	ip := req.RemoteAddr
+	if trustedProxies > 0 {
+		entries := []string{}
+		for _, header := range req.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) >= trustedProxies {
+			ip = entries[len(entries)-trustedProxies]
+		}
+	}
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return ip
+}
+
+// rateLimitMiddleware rejects requests with 429 if the client IP, or the validator pubkey in the path, exceeds its rate limit
+func (api *RelayAPI) rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ip := rateLimitIP(req, rateLimitTrustedProxies)
+		if !api.ipRateLimiter.Allow(ip) {
+			common.RateLimitedRequestsTotal.WithLabelValues(api.networkName(), "ip").Inc()
+			api.log.WithFields(logrus.Fields{
+				"ip":          ip,
+				"path":        req.URL.Path,
+				"numRejected": api.ipRateLimiter.NumRejected(),
+			}).Debug("request rate limited (ip)")
+			api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeRateLimited, "too many requests")
+			return
+		}
+
+		if pubkey := mux.Vars(req)["pubkey"]; pubkey != "" && !api.pubkeyRateLimiter.Allow(strings.ToLower(pubkey)) {
+			common.RateLimitedRequestsTotal.WithLabelValues(api.networkName(), "pubkey").Inc()
+			api.log.WithFields(logrus.Fields{
+				"pubkey":      pubkey,
+				"path":        req.URL.Path,
+				"numRejected": api.pubkeyRateLimiter.NumRejected(),
+			}).Debug("request rate limited (pubkey)")
+			api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeRateLimited, "too many requests")
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+// allowValidatorRegistration takes a token of the validator's registration rate limit. Registrations are limited
+// separately from the getHeader requests of the validator.
+func (api *RelayAPI) allowValidatorRegistration(pubkey string) bool {
+	if api.pubkeyRateLimiter.Allow("registration/" + strings.ToLower(pubkey)) {
+		return true
+	}
+	common.RateLimitedRequestsTotal.WithLabelValues(api.networkName(), "registration").Inc()
+	return false
+}
+
+// peekBuilderSubmission extracts builder pubkey and slot from a raw block submission, without decoding the full payload
+func peekBuilderSubmission(body []byte) (builderPubkey string, slot uint64, ok bool) {
+	builderPubkey, err := jsonparser.GetString(body, "message", "builder_pubkey")
+	if err != nil {
+		return "", 0, false
+	}
+	slotStr, err := jsonparser.GetString(body, "message", "slot")
+	if err != nil {
+		return "", 0, false
+	}
+	slot, err = strconv.ParseUint(slotStr, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return builderPubkey, slot, true
+}
+
+// builderSubmissionLimit returns the per-slot submission limit of the builder, 0 for no limit
+func (api *RelayAPI) builderSubmissionLimit(log *logrus.Entry, builderPubkey string) int {
+	if builderSubmissionsPerSlot == 0 && builderSubmissionsPerSlotHighPrio == 0 {
+		return 0
+	}
+
+	builderIsHighPrio, _, err := api.getBlockBuilderStatus(builderPubkey)
+	if err != nil {
+		log.WithError(err).Error("could not get block builder status")
+	}
+	if builderIsHighPrio {
+		return builderSubmissionsPerSlotHighPrio
+	}
+	return builderSubmissionsPerSlot
+}
+
+// builderSubmissionLimitReached returns whether the builder already used up its per-slot limit, without counting the
+// submission. It's a cheap check of the pubkey claimed in the submission before decoding it and verifying the signature,
+// allowBuilderSubmission counts the submission once it's verified.
+func (api *RelayAPI) builderSubmissionLimitReached(log *logrus.Entry, slot uint64, builderPubkey string) bool {
+	builderPubkey = strings.ToLower(builderPubkey)
+	limit := api.builderSubmissionLimit(log, builderPubkey)
+	if limit == 0 {
+		return false
+	}
+
+	numSubmissions, err := api.redis.GetBuilderSubmissionCount(slot, builderPubkey)
+	if err != nil {
+		log.WithError(err).Error("could not get builder submission count")
+		return false
+	}
+	return numSubmissions >= int64(limit)
+}
+
+// allowBuilderSubmission counts the submission towards the builder's per-slot limit, and returns false if the limit is
+// exceeded
+func (api *RelayAPI) allowBuilderSubmission(log *logrus.Entry, slot uint64, builderPubkey string) bool {
+	builderPubkey = strings.ToLower(builderPubkey)
+	limit := api.builderSubmissionLimit(log, builderPubkey)
+	if limit == 0 {
+		return true
+	}
+
+	numSubmissions, err := api.redis.IncBuilderSubmissionCount(slot, builderPubkey)
+	if err != nil {
+		// don't reject submissions because of redis errors
+		log.WithError(err).Error("could not increment builder submission count")
+		return true
+	}
+
+	if numSubmissions > int64(limit) {
+		log.WithFields(logrus.Fields{
+			"slot":           slot,
+			"builderPubkey":  builderPubkey,
+			"numSubmissions": numSubmissions,
+			"limit":          limit,
+		}).Info("builder submission rate limited")
+		return false
+	}
+	return true
+}
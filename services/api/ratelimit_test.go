@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("disabled limiter allows everything", func(t *testing.T) {
+		r := NewRateLimiter(0, 10)
+		require.Nil(t, r)
+		for i := 0; i < 100; i++ {
+			require.True(t, r.Allow("a"))
+		}
+	})
+
+	t.Run("burst is enforced per key", func(t *testing.T) {
+		r := NewRateLimiter(1, 3)
+		for i := 0; i < 3; i++ {
+			require.True(t, r.Allow("a"))
+		}
+		require.False(t, r.Allow("a"))
+		require.True(t, r.Allow("b"))
+		require.Equal(t, uint64(1), r.NumRejected())
+		require.Equal(t, uint64(4), r.NumAllowed())
+	})
+
+	t.Run("cleanup removes stale buckets", func(t *testing.T) {
+		r := NewRateLimiter(1, 1)
+		require.True(t, r.Allow("a"))
+		r.cleanup(0)
+		require.Len(t, r.buckets, 0)
+	})
+
+	t.Run("tokens are refilled over time", func(t *testing.T) {
+		r := NewRateLimiter(100, 1)
+		require.True(t, r.Allow("a"))
+		require.False(t, r.Allow("a"))
+		time.Sleep(20 * time.Millisecond)
+		require.True(t, r.Allow("a"))
+	})
+}
+
+func TestRateLimitIP(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	req.RemoteAddr = "10.0.0.1:1234"
+	require.Equal(t, "10.0.0.1", rateLimitIP(req, 1))
+
+	// the entries sent by the client are ignored
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	req.Header.Add("X-Forwarded-For", "3.3.3.3")
+	require.Equal(t, "3.3.3.3", rateLimitIP(req, 1))
+	require.Equal(t, "2.2.2.2", rateLimitIP(req, 2))
+	require.Equal(t, "10.0.0.1", rateLimitIP(req, 5))
+	require.Equal(t, "10.0.0.1", rateLimitIP(req, 0))
+}
+
+func TestPeekBuilderSubmission(t *testing.T) {
+	body := []byte(`{"message":{"slot":"123","builder_pubkey":"0xb1"},"execution_payload":{}}`)
+	builderPubkey, slot, ok := peekBuilderSubmission(body)
+	require.True(t, ok)
+	require.Equal(t, "0xb1", builderPubkey)
+	require.Equal(t, uint64(123), slot)
+
+	_, _, ok = peekBuilderSubmission([]byte(`{"message":{"slot":"abc","builder_pubkey":"0xb1"}}`))
+	require.False(t, ok)
+	_, _, ok = peekBuilderSubmission([]byte(`{"message":{"slot":"123"}}`))
+	require.False(t, ok)
+}
+
+func TestAllowBuilderSubmission(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	defer func(limit int) { builderSubmissionsPerSlot = limit }(builderSubmissionsPerSlot)
+	builderSubmissionsPerSlot = 2
+
+	// the count is shared regardless of the case of the pubkey
+	pubkey := "0xAB" + strings.Repeat("cd", 47)
+	log := backend.relay.log
+	require.True(t, backend.relay.allowBuilderSubmission(log, 1, pubkey))
+	require.True(t, backend.relay.allowBuilderSubmission(log, 1, strings.ToLower(pubkey)))
+	require.False(t, backend.relay.allowBuilderSubmission(log, 1, pubkey))
+	require.True(t, backend.relay.allowBuilderSubmission(log, 2, pubkey))
+
+	// the pre-check doesn't count submissions
+	require.True(t, backend.relay.builderSubmissionLimitReached(log, 1, pubkey))
+	require.False(t, backend.relay.builderSubmissionLimitReached(log, 2, pubkey))
+	require.False(t, backend.relay.builderSubmissionLimitReached(log, 2, pubkey))
+	require.True(t, backend.relay.allowBuilderSubmission(log, 2, pubkey))
+	require.True(t, backend.relay.builderSubmissionLimitReached(log, 2, strings.ToLower(pubkey)))
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+// minRegistrationShardSize is the smallest number of registrations worth a worker, each shard costs a redis command
+const minRegistrationShardSize = 64
+
+// pendingRegistration is a registration of a registerValidator call whose message fields are parsed, the signed
+// registration is only decoded if its signature has to be verified
+type pendingRegistration struct {
+	value        []byte
+	pubkey       boostTypes.PubkeyHex
+	timestamp    uint64
+	feeRecipient string
+	gasLimit     uint64
+}
+
+// isUnchanged returns whether the registration is a re-submission of the stored one. Only the timestamp is known of
+// the registrations stored before their messages were, and those are assumed to be unchanged.
+func (reg pendingRegistration) isUnchanged(prev datastore.ValidatorRegistrationMessage) bool {
+	if prev.Timestamp != reg.timestamp {
+		return false
+	}
+	return prev.FeeRecipient == "" || (strings.EqualFold(prev.FeeRecipient, reg.feeRecipient) && prev.GasLimit == reg.gasLimit)
+}
+
+// registrationsResult is the outcome of processing the registrations of a registerValidator call
+type registrationsResult struct {
+	activeValidators []boostTypes.PubkeyHex
+	newRegistrations []*boostTypes.SignedValidatorRegistration
+	numNew           int
+	numUnchanged     int
+	numOutdated      int
+	numRateLimited   int // valid new registrations of validators over their rate limit
+
+	// the error of the first invalid registration
+	err      error
+	errIndex int
+}
+
+// processRegistrations shards the registrations across numWorkers workers, which check that the validators are known,
+// skip the registrations which are unchanged or older than the stored ones and verify the signatures of the others. The
+// registrations of a shard are processed until the first invalid one, and the error of the first invalid registration
+// of the call is returned.
+func (api *RelayAPI) processRegistrations(log *logrus.Entry, registrations []pendingRegistration, numWorkers int) (*registrationsResult, error) {
+	numShards := (len(registrations) + minRegistrationShardSize - 1) / minRegistrationShardSize
+	if numShards > numWorkers {
+		numShards = numWorkers
+	}
+	if numShards < 1 {
+		numShards = 1
+	}
+	shardSize := (len(registrations) + numShards - 1) / numShards
+
+	results := make([]*registrationsResult, numShards)
+	var wg sync.WaitGroup
+	for i := 0; i < numShards; i++ {
+		start := i * shardSize
+		end := start + shardSize
+		if end > len(registrations) {
+			end = len(registrations)
+		}
+		wg.Add(1)
+		go func(i, start, end int) {
+			defer wg.Done()
+			results[i] = api.processRegistrationShard(log, registrations[start:end], start)
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	result := new(registrationsResult)
+	for _, shard := range results {
+		result.activeValidators = append(result.activeValidators, shard.activeValidators...)
+		result.newRegistrations = append(result.newRegistrations, shard.newRegistrations...)
+		result.numNew += shard.numNew
+		result.numUnchanged += shard.numUnchanged
+		result.numOutdated += shard.numOutdated
+		result.numRateLimited += shard.numRateLimited
+		if shard.err != nil && (result.err == nil || shard.errIndex < result.errIndex) {
+			result.err = shard.err
+			result.errIndex = shard.errIndex
+		}
+	}
+	return result, result.err
+}
+
+// processRegistrationShard processes the registrations of a shard, offset is the index of its first registration in
+// the call
+func (api *RelayAPI) processRegistrationShard(log *logrus.Entry, registrations []pendingRegistration, offset int) *registrationsResult {
+	result := new(registrationsResult)
+	fail := func(i int, err error) *registrationsResult {
+		result.err = err
+		result.errIndex = offset + i
+		return result
+	}
+
+	// Check if real validators
+	for i, reg := range registrations {
+		if !api.datastore.IsKnownValidator(reg.pubkey) && !api.ffLoadTestMode {
+			registrations = registrations[:i]
+			fail(i, fmt.Errorf("%w: %s", ErrUnknownValidator, reg.pubkey.String()))
+			break
+		}
+		if api.ffProposerAllowlist && !api.isAllowedProposer(reg.pubkey.String()) {
+			registrations = registrations[:i]
+			fail(i, fmt.Errorf("%w: %s", ErrValidatorNotServed, reg.pubkey.String()))
+			break
+		}
+		result.activeValidators = append(result.activeValidators, reg.pubkey)
+	}
+
+	// Get the previous registrations of the shard at once. Registrations that are unchanged (the periodic re-submissions)
+	// or not newer than the stored one are skipped without decoding or verifying the signature, and aren't saved again.
+	prevRegistrations, err := api.redis.GetValidatorRegistrationMessages(result.activeValidators)
+	if err != nil {
+		log.WithError(err).Error("error getting last registrations")
+		prevRegistrations = make([]datastore.ValidatorRegistrationMessage, len(registrations))
+	}
+
+	for i, reg := range registrations {
+		if reg.isUnchanged(prevRegistrations[i]) {
+			result.numUnchanged += 1
+			continue
+		} else if prevRegistrations[i].Timestamp >= reg.timestamp {
+			result.numOutdated += 1
+			continue
+		}
+
+		// JSON-decode the registration now (needed for signature verification)
+		signedValidatorRegistration := new(boostTypes.SignedValidatorRegistration)
+		err = json.Unmarshal(reg.value, signedValidatorRegistration)
+		if err != nil {
+			log.WithError(err).WithField("pubkey", reg.pubkey.String()).Error("error unmarshalling signed validator registration")
+			return fail(i, fmt.Errorf("error unmarshalling signed validator registration: %w", err))
+		}
+
+		if err := verifyValidatorRegistration(signedValidatorRegistration, api.opts.EthNetDetails.DomainBuilder); err != nil {
+			return fail(i, err)
+		}
+
+		// Only validly signed registrations count towards the rate limit, else anyone could use up the limit of a
+		// validator. Limited registrations are skipped, mev-boost sends them again every epoch.
+		if !api.allowValidatorRegistration(reg.pubkey.String()) {
+			result.numRateLimited += 1
+			continue
+		}
+		result.numNew += 1
+		result.newRegistrations = append(result.newRegistrations, signedValidatorRegistration)
+	}
+	return result
+}
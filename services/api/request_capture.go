@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidCaptureSampleRate = errors.New("invalid request capture sample rate")
+	ErrRequestCaptureFull       = errors.New("request capture directory is full")
+	ErrInvalidCapturePath       = errors.New("invalid request capture path")
+
+	// the sampled builder submissions and getPayload requests are written to this directory, to reproduce decoding and
+	// validation bugs with the replay-requests tool
+	requestCaptureDir        = os.Getenv("REQUEST_CAPTURE_DIR")
+	requestCaptureSampleRate = common.GetEnv("REQUEST_CAPTURE_SAMPLE_RATE", "0.01")
+	requestCaptureQueueSize  = cli.GetEnvInt("REQUEST_CAPTURE_QUEUE_SIZE", 100)
+	requestCaptureMaxBytes   = int64(cli.GetEnvInt("REQUEST_CAPTURE_MAX_MB", 1024)) * 1024 * 1024
+)
+
+// captureUnknownSlotDir is the directory of the captured requests whose slot couldn't be read from the body
+const captureUnknownSlotDir = "unknown"
+
+// the headers which are never written to disk. The body is captured decompressed, so the encoding headers are dropped.
+var captureSkippedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	HeaderBuilderAPIKey:   true,
+	"Content-Encoding":    true,
+	"Content-Length":      true,
+	"Accept-Encoding":     true,
+	"X-Forwarded-For":     true,
+	"X-Real-Ip":           true,
+	"Proxy-Authorization": true,
+}
+
+// CapturedRequest is a request written to disk in capture mode, which the replay-requests tool sends again
+type CapturedRequest struct {
+	RequestID  string      `json:"request_id"`
+	Endpoint   string      `json:"endpoint"`
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Slot       uint64      `json:"slot"` // 0 if unknown
+	ReceivedAt time.Time   `json:"received_at"`
+}
+
+// parseCaptureSampleRate parses the fraction of the requests to capture
+func parseCaptureSampleRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || rate <= 0 || rate > 1 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCaptureSampleRate, s)
+	}
+	return rate, nil
+}
+
+// requestCapture writes a sampled fraction of the requests to a directory per slot through a bounded queue, so the
+// requests never wait for the disk. Requests which don't fit in the queue are dropped. Once the captures exceed the
+// maximum size, the directories of the oldest slots are removed.
+type requestCapture struct {
+	log        *logrus.Entry
+	dir        string
+	sampleRate float64
+	queue      chan *CapturedRequest
+	workers    sync.WaitGroup
+
+	maxBytes  int64
+	usedBytes int64 // only accessed by the worker
+}
+
+func newRequestCapture(log *logrus.Entry, dir string, sampleRate float64, queueSize int, maxBytes int64) (*requestCapture, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	usedBytes, err := dirSize(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &requestCapture{
+		log:        log.WithFields(logrus.Fields{"component": "requestCapture", "dir": dir}),
+		dir:        dir,
+		sampleRate: sampleRate,
+		queue:      make(chan *CapturedRequest, queueSize),
+		maxBytes:   maxBytes,
+		usedBytes:  usedBytes,
+	}, nil
+}
+
+// start starts the worker, which runs until the queue is closed and drained
+func (c *requestCapture) start() {
+	c.log.Infof("capturing %.4f of the requests, queue size %d", c.sampleRate, cap(c.queue))
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		for captured := range c.queue {
+			if err := c.write(captured); errors.Is(err, ErrRequestCaptureFull) {
+				common.RequestCaptureTotal.WithLabelValues(captured.Endpoint, "dropped").Inc()
+				continue
+			} else if err != nil {
+				common.RequestCaptureTotal.WithLabelValues(captured.Endpoint, "failed").Inc()
+				c.log.WithError(err).WithField("requestID", captured.RequestID).Warn("failed to capture the request")
+				continue
+			}
+			common.RequestCaptureTotal.WithLabelValues(captured.Endpoint, "captured").Inc()
+		}
+	}()
+}
+
+// capture queues a copy of the request if it's sampled, without waiting. The body buffer of the request is reused.
+func (c *requestCapture) capture(req *http.Request, endpoint string, body []byte, slot uint64, receivedAt time.Time) {
+	if mathrand.Float64() >= c.sampleRate { //nolint:gosec
+		return
+	}
+
+	header := make(http.Header, len(req.Header))
+	for name, values := range req.Header {
+		if !captureSkippedHeaders[http.CanonicalHeaderKey(name)] {
+			header[name] = append([]string{}, values...)
+		}
+	}
+	requestID := ""
+	if rl := requestLogFromContext(req.Context()); rl != nil {
+		requestID = rl.id
+	}
+
+	captured := &CapturedRequest{
+		RequestID:  requestID,
+		Endpoint:   endpoint,
+		Method:     req.Method,
+		Path:       req.URL.RequestURI(),
+		Header:     header,
+		Body:       bytes.Clone(body),
+		Slot:       slot,
+		ReceivedAt: receivedAt,
+	}
+	select {
+	case c.queue <- captured:
+	default:
+		common.RequestCaptureTotal.WithLabelValues(endpoint, "dropped").Inc()
+	}
+}
+
+// write writes the request to <dir>/<slot>/<endpoint>-<received at>-<random id>.json. The request ID can be set by the
+// client, so it's only written into the file.
+func (c *requestCapture) write(captured *CapturedRequest) error {
+	slotDir := captureUnknownSlotDir
+	if captured.Slot > 0 {
+		slotDir = strconv.FormatUint(captured.Slot, 10)
+	}
+	name := fmt.Sprintf("%s-%d-%s.json", captured.Endpoint, captured.ReceivedAt.UnixNano(), newRequestID())
+	path := filepath.Join(c.dir, slotDir, name)
+	if rel, err := filepath.Rel(c.dir, path); err != nil || rel != filepath.Join(slotDir, name) {
+		return fmt.Errorf("%w: %s", ErrInvalidCapturePath, path)
+	}
+
+	data, err := json.Marshal(captured)
+	if err != nil {
+		return err
+	}
+	if c.usedBytes+int64(len(data)) > c.maxBytes {
+		if err := c.prune(slotDir, int64(len(data))); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	c.usedBytes += int64(len(data))
+	return nil
+}
+
+// prune removes the directories of the oldest slots until the given number of bytes fits, except the one of the slot
+// being written. The directory of the requests without slot is removed last.
+func (c *requestCapture) prune(keepDir string, needed int64) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	slotDirs := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != keepDir {
+			slotDirs = append(slotDirs, entry.Name())
+		}
+	}
+	slotOrder := func(name string) uint64 {
+		if slot, err := strconv.ParseUint(name, 10, 64); err == nil {
+			return slot
+		}
+		return math.MaxUint64
+	}
+	sort.Slice(slotDirs, func(i, j int) bool {
+		return slotOrder(slotDirs[i]) < slotOrder(slotDirs[j])
+	})
+
+	for _, name := range slotDirs {
+		if c.usedBytes+needed <= c.maxBytes {
+			break
+		}
+		size, err := dirSize(filepath.Join(c.dir, name))
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(c.dir, name)); err != nil {
+			return err
+		}
+		c.usedBytes -= size
+		c.log.WithField("slotDir", name).Info("removed the oldest captured requests")
+	}
+	if c.usedBytes+needed > c.maxBytes {
+		return ErrRequestCaptureFull
+	}
+	return nil
+}
+
+// dirSize returns the size of the files in the directory and its subdirectories
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// stop closes the queue, once nothing sends to it anymore. The worker writes the remaining requests.
+func (c *requestCapture) stop() {
+	close(c.queue)
+}
+
+// ReadCapturedRequests reads the captured requests of the directory and its slot directories, in the order they were
+// received
+func ReadCapturedRequests(dir string) ([]*CapturedRequest, error) {
+	requests := []*CapturedRequest{}
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		captured := new(CapturedRequest)
+		if err := json.Unmarshal(data, captured); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		requests = append(requests, captured)
+		return nil
+	})
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].ReceivedAt.Before(requests[j].ReceivedAt)
+	})
+	return requests, err
+}
+
+// peekGetPayloadSlot reads the slot of a getPayload request without decoding it, which also works for the requests
+// failing to decode
+func peekGetPayloadSlot(body []byte, isSSZ bool) (uint64, bool) {
+	if !isSSZ {
+		slotStr, err := jsonparser.GetString(body, "message", "slot")
+		if err != nil {
+			return 0, false
+		}
+		slot, err := strconv.ParseUint(slotStr, 10, 64)
+		return slot, err == nil
+	}
+
+	// the signed block starts with the offset of the message, whose first field is the slot
+	if len(body) < 4 {
+		return 0, false
+	}
+	offset := uint64(binary.LittleEndian.Uint32(body[:4]))
+	if uint64(len(body)) < offset+8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(body[offset : offset+8]), true
+}
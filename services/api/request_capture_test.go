@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestCapture(t *testing.T) {
+	_, err := parseCaptureSampleRate("0")
+	require.ErrorIs(t, err, ErrInvalidCaptureSampleRate)
+	_, err = parseCaptureSampleRate("1.5")
+	require.ErrorIs(t, err, ErrInvalidCaptureSampleRate)
+
+	dir := t.TempDir()
+	c, err := newRequestCapture(common.TestLog, dir, 1, 2, 1024*1024)
+	require.NoError(t, err)
+
+	receivedAt := time.Now().UTC()
+	req := httptest.NewRequest(http.MethodPost, pathSubmitNewBlock, nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderBuilderAPIKey, "key")
+	req.Header.Set("Content-Encoding", "gzip")
+	body := []byte(`{"message":{"slot":"5"}}`)
+	c.capture(req, "submitBlock", body, 5, receivedAt)
+	c.capture(httptest.NewRequest(http.MethodPost, pathGetPayload, nil), "getPayload", []byte("invalid"), 0, receivedAt.Add(-time.Second))
+	c.capture(req, "submitBlock", body, 5, receivedAt) // queue full, dropped
+
+	// the body buffer of the request is reused after capturing
+	copy(body, "xxxx")
+
+	c.start()
+	c.stop()
+	c.workers.Wait()
+
+	// one directory per slot
+	entries, err := os.ReadDir(filepath.Join(dir, "5"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entries, err = os.ReadDir(filepath.Join(dir, captureUnknownSlotDir))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// in the order received, without the credentials and encoding
+	requests, err := ReadCapturedRequests(dir)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	require.Equal(t, "getPayload", requests[0].Endpoint)
+	require.Equal(t, []byte("invalid"), requests[0].Body)
+	require.Equal(t, "submitBlock", requests[1].Endpoint)
+	require.Equal(t, pathSubmitNewBlock, requests[1].Path)
+	require.Equal(t, uint64(5), requests[1].Slot)
+	require.Equal(t, `{"message":{"slot":"5"}}`, string(requests[1].Body))
+	require.Equal(t, "application/json", requests[1].Header.Get("Content-Type"))
+	require.Empty(t, requests[1].Header.Get(HeaderBuilderAPIKey))
+	require.Empty(t, requests[1].Header.Get("Content-Encoding"))
+}
+
+func TestRequestCaptureWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "captures")
+	c, err := newRequestCapture(common.TestLog, dir, 1, 1, 1024*1024)
+	require.NoError(t, err)
+
+	// the client-supplied request ID isn't used in the file name
+	err = c.write(&CapturedRequest{RequestID: "../../../evil", Endpoint: "submitBlock", Slot: 1, Body: []byte("x")})
+	require.NoError(t, err)
+	entries, err := os.ReadDir(filepath.Join(dir, "1"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotContains(t, entries[0].Name(), "evil")
+	entries, err = os.ReadDir(filepath.Dir(dir))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// above the maximum size, the oldest slots are removed
+	c.maxBytes = c.usedBytes * 2
+	require.NoError(t, c.write(&CapturedRequest{Endpoint: "submitBlock", Slot: 2, Body: []byte("x")}))
+	require.NoError(t, c.write(&CapturedRequest{Endpoint: "submitBlock", Slot: 3, Body: []byte("x")}))
+	_, err = os.Stat(filepath.Join(dir, "1"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "2"))
+	require.NoError(t, err)
+
+	// a request larger than the maximum is dropped
+	err = c.write(&CapturedRequest{Endpoint: "submitBlock", Slot: 3, Body: make([]byte, c.maxBytes)})
+	require.ErrorIs(t, err, ErrRequestCaptureFull)
+}
+
+func TestPeekGetPayloadSlot(t *testing.T) {
+	slot, ok := peekGetPayloadSlot([]byte(`{"message":{"slot":"123"}}`), false)
+	require.True(t, ok)
+	require.Equal(t, uint64(123), slot)
+
+	_, ok = peekGetPayloadSlot([]byte(`{"message":{}}`), false)
+	require.False(t, ok)
+
+	// the message offset, the signature, and the message starting with the slot
+	body := make([]byte, 4+96+8)
+	binary.LittleEndian.PutUint32(body, 100)
+	binary.LittleEndian.PutUint64(body[100:], 456)
+	slot, ok = peekGetPayloadSlot(body, true)
+	require.True(t, ok)
+	require.Equal(t, uint64(456), slot)
+
+	_, ok = peekGetPayloadSlot(body[:50], true)
+	require.False(t, ok)
+}
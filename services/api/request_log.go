@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidLogSampleRate = errors.New("invalid log sample rate")
+
+	// requests taking longer than this are always logged in full, regardless of the sample rate
+	logSlowRequestThreshold = time.Duration(cli.GetEnvInt("LOG_SLOW_REQUEST_MS", 1000)) * time.Millisecond
+)
+
+// HeaderRequestID carries the request ID, which is taken from the request if set and always returned in the response
+const HeaderRequestID = "X-Request-Id"
+
+type requestLogKey struct{}
+
+// parseLogSampleRates parses the per-endpoint sample rates, e.g. "getHeader=0.01,registerValidator=0.1". Endpoints
+// without a rate are always logged.
+func parseLogSampleRates(s string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		endpoint, rateStr, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLogSampleRate, entry)
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLogSampleRate, entry)
+		}
+		rates[strings.TrimSpace(endpoint)] = rate
+	}
+	return rates, nil
+}
+
+// requestLog is the logger of a single request. The log lines of requests which aren't sampled are buffered (except
+// warnings and errors), and only written if the request turns out to be slow or fails.
+type requestLog struct {
+	id          string
+	endpoint    string
+	sampleRates map[string]float64
+	entry       *logrus.Entry
+	buffer      *logBuffer // nil if sampled
+}
+
+// setEndpoint names the endpoint of the request and decides whether it is sampled. Must be called before the handler
+// gets the logger.
+func (rl *requestLog) setEndpoint(endpoint string) {
+	rl.endpoint = endpoint
+	rl.entry = rl.entry.WithField("endpoint", endpoint)
+
+	rate, found := rl.sampleRates[endpoint]
+	if !found || mathrand.Float64() < rate { //nolint:gosec
+		return
+	}
+
+	rl.buffer = &logBuffer{out: rl.entry.Logger}
+	logger := &logrus.Logger{
+		Out:       io.Discard,
+		Hooks:     make(logrus.LevelHooks),
+		Formatter: nopFormatter{},
+		Level:     rl.entry.Logger.GetLevel(),
+		ExitFunc:  rl.entry.Logger.ExitFunc,
+	}
+	logger.AddHook(rl.buffer)
+	rl.entry = logrus.NewEntry(logger).WithFields(rl.entry.Data)
+}
+
+// logBuffer writes warnings and errors right away, and holds back the other entries until flushed
+type logBuffer struct {
+	out *logrus.Logger
+
+	mu      sync.Mutex
+	entries []*logrus.Entry
+}
+
+func (b *logBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (b *logBuffer) Fire(entry *logrus.Entry) error {
+	if entry.Level <= logrus.WarnLevel {
+		return b.write(entry)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, entry)
+	return nil
+}
+
+func (b *logBuffer) write(entry *logrus.Entry) error {
+	line, err := b.out.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = b.out.Out.Write(line)
+	return err
+}
+
+func (b *logBuffer) flush() {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+
+	for _, entry := range entries {
+		_ = b.write(entry)
+	}
+}
+
+// nopFormatter skips formatting the entries of unsampled requests, the buffer formats them when they are written
+type nopFormatter struct{}
+
+func (nopFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func requestLogFromContext(ctx context.Context) *requestLog {
+	rl, _ := ctx.Value(requestLogKey{}).(*requestLog)
+	return rl
+}
+
+// requestLogger returns the logger of the request, which adds the request ID to all lines and respects the sampling
+func (api *RelayAPI) requestLogger(req *http.Request) *logrus.Entry {
+	if rl := requestLogFromContext(req.Context()); rl != nil {
+		return rl.entry
+	}
+	return api.log
+}
+
+// loggingMiddleware assigns the request ID and logs the request once it's done. Requests which aren't sampled are only
+// logged if they are slow or fail.
+func (api *RelayAPI) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		requestID := req.Header.Get(HeaderRequestID)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		rl := &requestLog{id: requestID, sampleRates: api.logSampleRates, entry: api.log.WithField("requestID", requestID)}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			if err := recover(); err != nil {
+				rec.WriteHeader(http.StatusInternalServerError)
+				rl.entry.WithFields(logrus.Fields{
+					"err":    err,
+					"trace":  string(debug.Stack()),
+					"method": req.Method,
+				}).Errorf("http request panic: %s %s", req.Method, req.URL.EscapedPath())
+			}
+
+			duration := time.Since(start)
+			isSlow := duration >= logSlowRequestThreshold
+			if rl.buffer != nil {
+				if !isSlow && rec.status < http.StatusInternalServerError {
+					return
+				}
+				rl.buffer.flush()
+			}
+
+			api.log.WithFields(logrus.Fields{
+				"requestID": requestID,
+				"endpoint":  rl.endpoint,
+				"status":    rec.status,
+				"method":    req.Method,
+				"path":      req.URL.EscapedPath(),
+				"duration":  fmt.Sprintf("%f", duration.Seconds()),
+				"slow":      isSlow,
+			}).Infof("http: %s %s %d", req.Method, req.URL.EscapedPath(), rec.status)
+		}()
+
+		next.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), requestLogKey{}, rl)))
+	})
+}
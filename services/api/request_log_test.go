@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogSampleRates(t *testing.T) {
+	rates, err := parseLogSampleRates("")
+	require.NoError(t, err)
+	require.Len(t, rates, 0)
+
+	rates, err = parseLogSampleRates("getHeader=0.01, getPayload=1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{"getHeader": 0.01, "getPayload": 1}, rates)
+
+	for _, s := range []string{"getHeader", "getHeader=x", "getHeader=1.5", "getHeader=-1"} {
+		_, err = parseLogSampleRates(s)
+		require.ErrorIs(t, err, ErrInvalidLogSampleRate, s)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.SetOutput(out)
+
+	api := &RelayAPI{log: logrus.NewEntry(logger), logSampleRates: map[string]float64{"getHeader": 0}} //nolint:exhaustruct
+	status := http.StatusOK
+	handler := api.loggingMiddleware(api.instrumentMiddleware("getHeader", func(w http.ResponseWriter, req *http.Request) {
+		api.requestLogger(req).Info("bid delivered")
+		w.WriteHeader(status)
+	}))
+
+	serve := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/eth/v1/builder/header/1/0x00/0x00", nil)
+		if requestID != "" {
+			req.Header.Set(HeaderRequestID, requestID)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("unsampled requests are not logged", func(t *testing.T) {
+		out.Reset()
+		rr := serve("")
+		require.NotEmpty(t, rr.Header().Get(HeaderRequestID))
+		require.Empty(t, out.String())
+	})
+
+	t.Run("failed requests are logged in full", func(t *testing.T) {
+		out.Reset()
+		status = http.StatusInternalServerError
+		defer func() { status = http.StatusOK }()
+
+		rr := serve("abc")
+		require.Equal(t, "abc", rr.Header().Get(HeaderRequestID))
+		require.Contains(t, out.String(), "bid delivered")
+		require.Contains(t, out.String(), "requestID=abc")
+		require.Contains(t, out.String(), "status=500")
+	})
+
+	t.Run("slow requests are logged in full", func(t *testing.T) {
+		out.Reset()
+		threshold := logSlowRequestThreshold
+		logSlowRequestThreshold = 0
+		defer func() { logSlowRequestThreshold = threshold }()
+
+		serve("")
+		require.Contains(t, out.String(), "bid delivered")
+		require.Contains(t, out.String(), "slow=true")
+	})
+
+	t.Run("endpoints without a sample rate are always logged", func(t *testing.T) {
+		out.Reset()
+		api.logSampleRates = nil
+		defer func() { api.logSampleRates = map[string]float64{"getHeader": 0} }()
+
+		serve("")
+		require.Contains(t, out.String(), "bid delivered")
+		require.Contains(t, out.String(), "endpoint=getHeader")
+	})
+}
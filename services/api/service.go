@@ -3,17 +3,19 @@ package api
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -27,12 +29,12 @@ import (
 	"github.com/flashbots/go-boost-utils/bls"
 	boostTypes "github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/go-utils/cli"
-	"github.com/flashbots/go-utils/httplogger"
 	"github.com/flashbots/mev-boost-relay/beaconclient"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/mev-boost-relay/database"
 	"github.com/flashbots/mev-boost-relay/datastore"
-	"github.com/go-redis/redis/v9"
+	"github.com/flashbots/mev-boost-relay/eventbus"
+	"github.com/flashbots/mev-boost-relay/webhook"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	uberatomic "go.uber.org/atomic"
@@ -46,6 +48,10 @@ var (
 	ErrServerAlreadyStarted       = errors.New("server was already started")
 	ErrBuilderAPIWithoutSecretKey = errors.New("cannot start builder API without secret key")
 	ErrMismatchedForkVersions     = errors.New("can not find matching fork versions as retrieved from beacon node")
+	ErrAdminAPIWithoutToken       = errors.New("cannot start admin API without token")
+	ErrDenebNotSupported          = errors.New("header-only and peer bids are not supported for deneb")
+	ErrDenebSSZNotSupported       = errors.New("SSZ-encoded deneb blocks are not supported")
+	ErrBidBelowMinimum            = errors.New("bid value below the relay minimum")
 )
 
 var (
@@ -55,35 +61,52 @@ var (
 	pathGetHeader         = "/eth/v1/builder/header/{slot:[0-9]+}/{parent_hash:0x[a-fA-F0-9]+}/{pubkey:0x[a-fA-F0-9]+}"
 	pathGetPayload        = "/eth/v1/builder/blinded_blocks"
 
+	// Inclusion constraints API
+	pathInclusionConstraints  = "/relay/v1/constraints"
+	pathConstraintsDelegation = "/relay/v1/constraints/delegate"
+
 	// Block builder API
-	pathBuilderGetValidators = "/relay/v1/builder/validators"
-	pathSubmitNewBlock       = "/relay/v1/builder/blocks"
+	pathBuilderGetValidators  = "/relay/v1/builder/validators"
+	pathSubmitNewBlock        = "/relay/v1/builder/blocks"
+	pathBuilderTopBidStream   = "/relay/v1/builder/top_bid_stream"
+	pathSubmitNewHeader       = "/relay/v3/builder/headers"
+	pathBuilderGetConstraints = "/relay/v1/builder/constraints"
+
+	// Peer relays API
+	pathPeerBids = "/relay/v1/peer/bids"
 
 	// Data API
 	pathDataProposerPayloadDelivered = "/relay/v1/data/bidtraces/proposer_payload_delivered"
 	pathDataBuilderBidsReceived      = "/relay/v1/data/bidtraces/builder_blocks_received"
 	pathDataValidatorRegistration    = "/relay/v1/data/validator_registration"
+	pathDataStream                   = "/relay/v1/data/stream"
+	pathDataStats                    = "/relay/v1/data/stats"
+	pathDataBuilderScoreboard        = "/relay/v1/data/builder_scoreboard"
+	pathDataExport                   = "/relay/v1/data/export"
 
 	// Internal API
-	pathInternalBuilderStatus = "/internal/v1/builder/{pubkey:0x[a-fA-F0-9]+}"
+	pathInternalBuilderStatus     = "/internal/v1/builder/{pubkey:0x[a-fA-F0-9]+}"
+	pathInternalBuilderCollateral = "/internal/v1/builder/collateral/{pubkey:0x[a-fA-F0-9]+}"
+	pathInternalBuilderAPIKey     = "/internal/v1/builder/api_key/{pubkey:0x[a-fA-F0-9]+}"
+	pathInternalBuilderReload     = "/internal/v1/builder/reload"
 
 	// number of goroutines to save active validator
 	numActiveValidatorProcessors = cli.GetEnvInt("NUM_ACTIVE_VALIDATOR_PROCESSORS", 10)
 	numValidatorRegProcessors    = cli.GetEnvInt("NUM_VALIDATOR_REG_PROCESSORS", 10)
+	validatorRegBatchSize        = cli.GetEnvInt("VALIDATOR_REG_BATCH_SIZE", 500)
+	numRegVerifyWorkers          = cli.GetEnvInt("NUM_REG_VERIFY_WORKERS", runtime.NumCPU())
 	timeoutGetPayloadRetryMs     = cli.GetEnvInt("GETPAYLOAD_RETRY_TIMEOUT_MS", 100)
-
-	apiReadTimeoutMs       = cli.GetEnvInt("API_TIMEOUT_READ_MS", 1500)
-	apiReadHeaderTimeoutMs = cli.GetEnvInt("API_TIMEOUT_READHEADER_MS", 600)
-	apiWriteTimeoutMs      = cli.GetEnvInt("API_TIMEOUT_WRITE_MS", 10000)
-	apiIdleTimeoutMs       = cli.GetEnvInt("API_TIMEOUT_IDLE_MS", 3000)
+	getPayloadRequestCutoffMs    = cli.GetEnvInt("GETPAYLOAD_REQUEST_CUTOFF_MS", 4000)
 )
 
 // RelayAPIOpts contains the options for a relay
 type RelayAPIOpts struct {
 	Log *logrus.Entry
 
-	ListenAddr  string
-	BlockSimURL string
+	ListenAddr          string
+	HTTPServer          HTTPServerOpts
+	BlockSimURLs        []string
+	BlockSimHighPrioURL string // optional node dedicated to high-prio builders
 
 	BeaconClient beaconclient.IMultiBeaconClient
 	Datastore    *datastore.Datastore
@@ -101,6 +124,26 @@ type RelayAPIOpts struct {
 	DataAPI         bool
 	PprofAPI        bool
 	InternalAPI     bool
+
+	// TLS of the API and admin listeners, disabled if no certificate is set
+	TLS TLSOpts
+
+	// Admin API, on a separate listen address and only enabled if set
+	AdminListenAddr string
+	AdminToken      string // bearer token required for all admin requests
+
+	// AdminOperatorTokens are bearer tokens of named operators in the name:token form, recorded with their changes in
+	// the admin audit log. Accepted in addition to the shared AdminToken, which can then be left empty.
+	AdminOperatorTokens []string
+
+	// EventBus publishes the events of the relay to Kafka or NATS, disabled if nil
+	EventBus *eventbus.EventBus
+
+	// Webhooks are notified of operational events like failed payload deliveries, disabled if nil
+	Webhooks *webhook.Notifier
+
+	// SubmissionFilters are applied to the builder submissions after the built-in filters, in order
+	SubmissionFilters []SubmissionFilter
 }
 
 type randaoHelper struct {
@@ -113,6 +156,12 @@ type withdrawalsHelper struct {
 	root phase0.Root
 }
 
+type parentHashHelper struct {
+	slot                  uint64
+	parentHash            string
+	parentBeaconBlockRoot *phase0.Root // the root of the head block, only fetched from deneb on
+}
+
 // RelayAPI represents a single Relay instance
 type RelayAPI struct {
 	opts RelayAPIOpts
@@ -123,6 +172,8 @@ type RelayAPI struct {
 
 	srv        *http.Server
 	srvStarted uberatomic.Bool
+	adminSrv   *http.Server
+	tlsConfig  *tls.Config
 
 	beaconClient beaconclient.IMultiBeaconClient
 	datastore    *datastore.Datastore
@@ -130,28 +181,112 @@ type RelayAPI struct {
 	db           database.IDatabaseService
 
 	headSlot       uberatomic.Uint64
+	slots          *slotLifecycle
 	genesisInfo    *beaconclient.GetGenesisResponse
 	bellatrixEpoch uint64
 	capellaEpoch   uint64
+	denebEpoch     uint64 // math.MaxUint64 while the fork isn't scheduled
+	forkSchedules  forkScheduleCache
 
-	proposerDutiesLock       sync.RWMutex
-	proposerDutiesResponse   []boostTypes.BuilderGetValidatorsResponseEntry
-	proposerDutiesMap        map[uint64]*boostTypes.RegisterValidatorRequestMessage
-	proposerDutiesSlot       uint64
+	proposerDuties           *dutyLookup
 	isUpdatingProposerDuties uberatomic.Bool
 
-	blockSimRateLimiter *BlockSimulationRateLimiter
+	blockSimQueue      *BlockSimulationQueue
+	builderSigVerifier *sigVerifier
+
+	topBidStream *broadcaster[*datastore.TopBidUpdate]
+	dataStream   *broadcaster[*datastore.DataStreamEvent]
+
+	numDataExports uberatomic.Int64
+
+	ipRateLimiter     *RateLimiter
+	pubkeyRateLimiter *RateLimiter
+	apiKeyRateLimiter *RateLimiter
 
 	activeValidatorC chan boostTypes.PubkeyHex
 	validatorRegC    chan boostTypes.SignedValidatorRegistration
 
+	// saves the builder submissions in the background, see submissionWriter
+	blockSubmissionWriter *submissionWriter
+	submissionMirror      *submissionMirror // nil if the submissions aren't mirrored
+	requestCapture        *requestCapture   // nil if the requests aren't captured
+
+	// the top bid changes of the recent slots, nil if they aren't recorded
+	bidHistory *bidHistory
+
+	// timing of the getHeader responses, see getHeaderPolicy
+	getHeaderPolicy getHeaderPolicy
+
+	// caps on the blocks of the builders, checked before simulation
+	blockPolicy blockPolicy
+
+	// policies applied to every builder submission, see SubmissionFilter
+	preSimFilters     []SubmissionFilter
+	submissionFilters []SubmissionFilter
+
+	// the getPayload failures saved to the database
+	getPayloadFailures getPayloadFailureRecorder
+
 	// used to wait on any active getPayload calls on shutdown
 	getPayloadCallsInFlight sync.WaitGroup
 
-	// Feature flags
-	ffForceGetHeader204      bool
-	ffDisableBlockPublishing bool
-	ffDisableLowPrioBuilders bool
+	// used to wait on any pending optimistic block simulations on shutdown
+	optimisticBlocksInFlight sync.WaitGroup
+
+	// used to wait on the work outliving the requests (see runInBackground), and the validator processors on shutdown
+	backgroundTasks     sync.WaitGroup
+	validatorProcessors sync.WaitGroup
+
+	// set once the shutdown started, the context ends the event streams and redis subscriptions
+	isShuttingDown    uberatomic.Bool
+	shutdownCtx       context.Context //nolint:containedctx
+	stopSubscriptions context.CancelFunc
+	stoppedC          chan struct{}
+
+	// Feature flags, the atomic ones can be changed at runtime through the admin API
+	ffForceGetHeader204      uberatomic.Bool
+	ffDisableBlockPublishing uberatomic.Bool
+	ffDisableLowPrioBuilders uberatomic.Bool
+	ffEnableOptimistic       uberatomic.Bool
+	ffMinBidValue            uberatomic.Pointer[big.Int] // getHeader returns no bid below it, nil if disabled
+	ffEnableBlocklist        bool
+	ffShadowMode             bool
+	ffLoadTestMode           bool
+	ffForwardBidsToPeers     bool
+	ffInclusionConstraints   bool
+	ffSaveBelowMinBids       bool
+	ffProposerAllowlist      bool
+	ffFeeRecipientAlerts     bool
+
+	// fee recipients (lowercase) the validators may change to without an alert
+	feeRecipientAlertsAllowlist map[string]bool
+
+	// trusted relays exchanging bids with this one, by pubkey
+	peerRelays map[string]*peerRelay
+
+	featureFlagDefaults map[string]string // values of the runtime feature flags from the environment
+
+	// per-endpoint sample rates of the request logs
+	logSampleRates map[string]float64
+
+	// blocklisted addresses (lowercase), nil until loaded from redis
+	blocklist     map[string]bool
+	blocklistLock sync.RWMutex
+
+	// validator pubkeys (lowercase) served in closed relay mode, nil until loaded from redis
+	proposerAllowlist     map[string]bool
+	proposerAllowlistLock sync.RWMutex
+
+	// bearer tokens of the named admin operators
+	adminOperatorTokens []adminOperatorToken
+
+	// active data API keys by key hash
+	dataAPIKeys     map[string]*dataAPIKey
+	dataAPIKeysLock sync.RWMutex
+
+	// builder statuses by pubkey, nil until loaded from redis
+	builderStatuses     map[string]datastore.BlockBuilderStatus
+	builderStatusesLock sync.RWMutex
 
 	expectedPrevRandao         randaoHelper
 	expectedPrevRandaoLock     sync.RWMutex
@@ -160,6 +295,9 @@ type RelayAPI struct {
 	expectedWithdrawalsRoot     withdrawalsHelper
 	expectedWithdrawalsLock     sync.RWMutex
 	expectedWithdrawalsUpdating uint64
+
+	expectedParentHash     parentHashHelper
+	expectedParentHashLock sync.RWMutex
 }
 
 // NewRelayAPI creates a new service. if builders is nil, allow any builder
@@ -176,6 +314,20 @@ func NewRelayAPI(opts RelayAPIOpts) (api *RelayAPI, err error) {
 		return nil, ErrMissingDatastoreOpt
 	}
 
+	adminOperatorTokens, err := parseAdminOperatorTokens(opts.AdminOperatorTokens)
+	if err != nil {
+		return nil, err
+	} else if opts.AdminListenAddr != "" && opts.AdminToken == "" && len(adminOperatorTokens) == 0 {
+		return nil, ErrAdminAPIWithoutToken
+	}
+
+	opts.HTTPServer.setDefaults()
+
+	tlsConfig, err := opts.TLS.serverConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	// If block-builder API is enabled, then ensure secret key is all set
 	var publicKey boostTypes.PublicKey
 	if opts.BlockBuilderAPI {
@@ -205,36 +357,169 @@ func NewRelayAPI(opts RelayAPIOpts) (api *RelayAPI, err error) {
 	}
 
 	api = &RelayAPI{
-		opts:                   opts,
-		log:                    opts.Log,
-		blsSk:                  opts.SecretKey,
-		publicKey:              &publicKey,
-		datastore:              opts.Datastore,
-		beaconClient:           opts.BeaconClient,
-		redis:                  opts.Redis,
-		db:                     opts.DB,
-		proposerDutiesResponse: []boostTypes.BuilderGetValidatorsResponseEntry{},
-		blockSimRateLimiter:    NewBlockSimulationRateLimiter(opts.BlockSimURL),
+		opts:                opts,
+		log:                 opts.Log,
+		blsSk:               opts.SecretKey,
+		publicKey:           &publicKey,
+		tlsConfig:           tlsConfig,
+		adminOperatorTokens: adminOperatorTokens,
+		datastore:           opts.Datastore,
+		beaconClient:        opts.BeaconClient,
+		redis:               opts.Redis,
+		db:                  opts.DB,
+		proposerDuties:      newDutyLookup(opts.EthNetDetails.Name),
+		slots:               newSlotLifecycle(opts.Log),
+		denebEpoch:          math.MaxUint64,
+		blockSimQueue:       NewBlockSimulationQueue(opts.EthNetDetails.Name, newBlockSimNodePool(opts.Log, opts.BlockSimURLs, opts.BlockSimHighPrioURL)),
+		builderSigVerifier:  newSigVerifier(builderSigVerifyWorkers, builderSigVerifyBatchSize),
+		topBidStream:        newBroadcaster[*datastore.TopBidUpdate](),
+		dataStream:          newBroadcaster[*datastore.DataStreamEvent](),
+		ipRateLimiter:       NewRateLimiter(rateLimitIPPerSec, rateLimitIPBurst),
+		pubkeyRateLimiter:   NewRateLimiter(rateLimitPubkeyPerSec, rateLimitPubkeyBurst),
+		apiKeyRateLimiter:   NewRateLimiter(rateLimitAPIKeyPerSec, rateLimitAPIKeyBurst),
 
 		activeValidatorC: make(chan boostTypes.PubkeyHex, 450_000),
 		validatorRegC:    make(chan boostTypes.SignedValidatorRegistration, 450_000),
+
+		stoppedC: make(chan struct{}),
+	}
+	api.shutdownCtx, api.stopSubscriptions = context.WithCancel(context.Background())
+
+	api.blockSubmissionWriter, err = newSubmissionWriter(api.log, api.networkName(), api.writeBlockSubmission, submissionQueueSize, submissionOverflowPolicy, submissionSpillFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BlockBuilderAPI && mirrorSubmissionsURL != "" {
+		api.submissionMirror, err = newSubmissionMirror(api.log, mirrorSubmissionsURL, submissionMirrorQueueSize, submissionMirrorTimeout)
+		if err != nil {
+			return nil, err
+		}
+		api.log.Warn("env: MIRROR_SUBMISSIONS_URL - forwarding the block submissions to a secondary relay")
+	}
+
+	if requestCaptureDir != "" {
+		sampleRate, err := parseCaptureSampleRate(requestCaptureSampleRate)
+		if err != nil {
+			return nil, err
+		}
+		api.requestCapture, err = newRequestCapture(api.log, requestCaptureDir, sampleRate, requestCaptureQueueSize, requestCaptureMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		api.log.Warnf("env: REQUEST_CAPTURE_DIR - capturing %.4f of the block submissions and getPayload requests to %s", sampleRate, requestCaptureDir)
 	}
 
 	if os.Getenv("FORCE_GET_HEADER_204") == "1" {
 		api.log.Warn("env: FORCE_GET_HEADER_204 - forcing getHeader to always return 204")
-		api.ffForceGetHeader204 = true
+		api.ffForceGetHeader204.Store(true)
 	}
 
 	if os.Getenv("DISABLE_BLOCK_PUBLISHING") == "1" {
 		api.log.Warn("env: DISABLE_BLOCK_PUBLISHING - disabling publishing blocks on getPayload")
-		api.ffDisableBlockPublishing = true
+		api.ffDisableBlockPublishing.Store(true)
 	}
 
 	if os.Getenv("DISABLE_LOWPRIO_BUILDERS") == "1" {
 		api.log.Warn("env: DISABLE_LOWPRIO_BUILDERS - allowing only high-level builders")
-		api.ffDisableLowPrioBuilders = true
+		api.ffDisableLowPrioBuilders.Store(true)
+	}
+
+	if os.Getenv("ENABLE_OPTIMISTIC_RELAYING") == "1" {
+		api.log.Warn("env: ENABLE_OPTIMISTIC_RELAYING - accepting blocks of collateralized builders before simulation")
+		api.ffEnableOptimistic.Store(true)
+	}
+
+	if minBid := os.Getenv("MIN_BID_WEI"); minBid != "" {
+		api.log.Warnf("env: MIN_BID_WEI - not returning bids below %s wei, and rejecting submissions below it without simulation", minBid)
+		if err := api.setFeatureFlag(FeatureFlagMinBid, minBid); err != nil {
+			return nil, err
+		}
+	}
+
+	if os.Getenv("MIN_BID_SAVE_SUBMISSIONS") == "1" {
+		api.log.Info("env: MIN_BID_SAVE_SUBMISSIONS - saving the submissions below the minimum bid to the database")
+		api.ffSaveBelowMinBids = true
+	}
+	api.featureFlagDefaults = api.getFeatureFlags()
+
+	api.getHeaderPolicy, err = parseGetHeaderPolicy(os.Getenv("GET_HEADER_HOLD_UNTIL_MS"), os.Getenv("GET_HEADER_BID_CUTOFF_MS"))
+	if err != nil {
+		return nil, err
+	} else if policy := api.getHeaderPolicy.String(); policy != "" {
+		api.log.Warnf("env: GET_HEADER_HOLD_UNTIL_MS / GET_HEADER_BID_CUTOFF_MS - getHeader responds with the policy %s", policy)
+	}
+
+	api.blockPolicy, err = parseBlockPolicy(os.Getenv("BLOCK_POLICY_MAX_GAS_LIMIT_DEVIATION"), os.Getenv("BLOCK_POLICY_MAX_PAYLOAD_BYTES"), os.Getenv("BLOCK_POLICY_MAX_TX_COUNT"), os.Getenv("BLOCK_POLICY_BUILDERS_FILE"))
+	if err != nil {
+		return nil, err
+	} else if api.blockPolicy.isEnabled() {
+		api.log.Infof("env: BLOCK_POLICY_* - rejecting the blocks exceeding the block policy before simulation: %s", api.blockPolicy)
+	}
+
+	api.logSampleRates, err = parseLogSampleRates(os.Getenv("LOG_SAMPLE_RATES"))
+	if err != nil {
+		return nil, err
+	} else if len(api.logSampleRates) > 0 {
+		api.log.Infof("env: LOG_SAMPLE_RATES - sampling the request logs: %v", api.logSampleRates)
+	}
+
+	if os.Getenv("SHADOW_MODE") == "1" {
+		api.log.Warn("env: SHADOW_MODE - accepting submissions and returning bids, but never delivering payloads")
+		api.ffShadowMode = true
+	}
+
+	if os.Getenv("LOADTEST_MODE") == "1" {
+		api.log.Warn("env: LOADTEST_MODE - accepting synthetic submissions and registrations without chain checks or simulation, never delivering payloads")
+		api.ffLoadTestMode = true
+		api.ffShadowMode = true
 	}
 
+	if os.Getenv("ENABLE_PROPOSER_ALLOWLIST") == "1" {
+		api.log.Warn("env: ENABLE_PROPOSER_ALLOWLIST - only accepting registrations and serving bids for the validators on the proposer allowlist")
+		api.ffProposerAllowlist = true
+	}
+
+	if os.Getenv("ENABLE_BLOCKLIST") == "1" {
+		api.log.Warn("env: ENABLE_BLOCKLIST - rejecting block submissions involving blocklisted addresses")
+		api.ffEnableBlocklist = true
+	}
+
+	if os.Getenv("ENABLE_FEE_RECIPIENT_ALERTS") == "1" {
+		api.log.Info("env: ENABLE_FEE_RECIPIENT_ALERTS - alerting on validators changing to a new fee recipient")
+		api.ffFeeRecipientAlerts = true
+	}
+
+	api.feeRecipientAlertsAllowlist, err = parseFeeRecipientAllowlist(feeRecipientAlertsAllowlistEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	api.peerRelays, err = parsePeerRelays(peerRelaysEnv)
+	if err != nil {
+		return nil, err
+	} else if len(api.peerRelays) > 0 {
+		api.log.Infof("env: PEER_RELAYS - accepting bids from %d peer relays", len(api.peerRelays))
+	}
+
+	if forwardBidsToPeers && len(api.peerRelays) > 0 {
+		api.log.Warn("env: PEER_RELAYS_FORWARD_BIDS - forwarding the simulated bids to the peer relays")
+		api.ffForwardBidsToPeers = true
+	}
+
+	if os.Getenv("ENABLE_INCLUSION_CONSTRAINTS") == "1" {
+		api.log.Warn("env: ENABLE_INCLUSION_CONSTRAINTS - accepting inclusion constraints of proposers, and only serving bids which include the constrained transactions")
+		api.ffInclusionConstraints = true
+	}
+
+	if os.Getenv("ENABLE_BID_HISTORY") == "1" && bidHistorySize > 0 {
+		api.log.Infof("env: ENABLE_BID_HISTORY - saving up to %d top bid changes per slot to the database (redis: %t)", bidHistorySize, bidHistoryRedis)
+		api.bidHistory = newBidHistory(bidHistorySize)
+	}
+
+	api.preSimFilters = api.defaultPreSimFilters()
+	api.submissionFilters = append(api.defaultSubmissionFilters(), opts.SubmissionFilters...)
+	api.registerSlotHooks()
 	return api, nil
 }
 
@@ -247,24 +532,37 @@ func (api *RelayAPI) getRouter() http.Handler {
 	if api.opts.ProposerAPI {
 		api.log.Info("proposer API enabled")
 		r.HandleFunc(pathStatus, api.handleStatus).Methods(http.MethodGet)
-		r.HandleFunc(pathRegisterValidator, api.handleRegisterValidator).Methods(http.MethodPost)
-		r.HandleFunc(pathGetHeader, api.handleGetHeader).Methods(http.MethodGet)
-		r.HandleFunc(pathGetPayload, api.handleGetPayload).Methods(http.MethodPost)
+		r.HandleFunc(pathRegisterValidator, api.instrumentMiddleware("registerValidator", api.rateLimitMiddleware(api.handleRegisterValidator))).Methods(http.MethodPost)
+		r.HandleFunc(pathGetHeader, api.instrumentMiddleware("getHeader", api.rateLimitMiddleware(api.handleGetHeader))).Methods(http.MethodGet)
+		r.HandleFunc(pathGetPayload, api.instrumentMiddleware("getPayload", api.handleGetPayload)).Methods(http.MethodPost)
+		if api.ffInclusionConstraints {
+			r.HandleFunc(pathInclusionConstraints, api.instrumentMiddleware("inclusionConstraints", api.rateLimitMiddleware(api.handleSubmitInclusionConstraints))).Methods(http.MethodPost)
+			r.HandleFunc(pathConstraintsDelegation, api.rateLimitMiddleware(api.handleConstraintsDelegation)).Methods(http.MethodPost)
+		}
 	}
 
 	// Builder API
 	if api.opts.BlockBuilderAPI {
 		api.log.Info("block builder API enabled")
 		r.HandleFunc(pathBuilderGetValidators, api.handleBuilderGetValidators).Methods(http.MethodGet)
-		r.HandleFunc(pathSubmitNewBlock, api.handleSubmitNewBlock).Methods(http.MethodPost)
+		r.HandleFunc(pathSubmitNewBlock, api.instrumentMiddleware("submitBlock", api.rejectWhenShuttingDown(api.handleSubmitNewBlock))).Methods(http.MethodPost)
+		r.HandleFunc(pathSubmitNewHeader, api.instrumentMiddleware("submitHeader", api.rejectWhenShuttingDown(api.handleSubmitNewHeader))).Methods(http.MethodPost)
+		if len(api.peerRelays) > 0 {
+			r.HandleFunc(pathPeerBids, api.instrumentMiddleware("peerBid", api.rejectWhenShuttingDown(api.handlePeerBid))).Methods(http.MethodPost)
+		}
+		if api.ffInclusionConstraints {
+			r.HandleFunc(pathBuilderGetConstraints, api.handleBuilderGetInclusionConstraints).Methods(http.MethodGet)
+		}
 	}
 
 	// Data API
 	if api.opts.DataAPI {
 		api.log.Info("data API enabled")
-		r.HandleFunc(pathDataProposerPayloadDelivered, api.handleDataProposerPayloadDelivered).Methods(http.MethodGet)
-		r.HandleFunc(pathDataBuilderBidsReceived, api.handleDataBuilderBidsReceived).Methods(http.MethodGet)
-		r.HandleFunc(pathDataValidatorRegistration, api.handleDataValidatorRegistration).Methods(http.MethodGet)
+		r.HandleFunc(pathDataProposerPayloadDelivered, api.dataAPIRateLimitMiddleware(api.handleDataProposerPayloadDelivered)).Methods(http.MethodGet)
+		r.HandleFunc(pathDataBuilderBidsReceived, api.dataAPIRateLimitMiddleware(api.handleDataBuilderBidsReceived)).Methods(http.MethodGet)
+		r.HandleFunc(pathDataValidatorRegistration, api.dataAPIRateLimitMiddleware(api.handleDataValidatorRegistration)).Methods(http.MethodGet)
+		r.HandleFunc(pathDataStats, api.dataAPIRateLimitMiddleware(api.handleDataStats)).Methods(http.MethodGet)
+		r.HandleFunc(pathDataBuilderScoreboard, api.dataAPIRateLimitMiddleware(api.handleDataBuilderScoreboard)).Methods(http.MethodGet)
 	}
 
 	// Pprof
@@ -277,12 +575,38 @@ func (api *RelayAPI) getRouter() http.Handler {
 	if api.opts.InternalAPI {
 		api.log.Info("internal API enabled")
 		r.HandleFunc(pathInternalBuilderStatus, api.handleInternalBuilderStatus).Methods(http.MethodGet, http.MethodPost, http.MethodPut)
+		r.HandleFunc(pathInternalBuilderCollateral, api.handleInternalBuilderCollateral).Methods(http.MethodPost, http.MethodPut)
+		r.HandleFunc(pathInternalBuilderAPIKey, api.handleInternalBuilderAPIKey).Methods(http.MethodPost, http.MethodDelete)
+		r.HandleFunc(pathInternalBuilderReload, api.handleInternalReloadBuilderStatuses).Methods(http.MethodPost)
 	}
 
 	// r.Use(mux.CORSMethodMiddleware(r))
-	loggedRouter := httplogger.LoggingMiddlewareLogrus(api.log, r)
+	loggedRouter := api.loggingMiddleware(r)
 	withGz := gziphandler.GzipHandler(loggedRouter)
-	return withGz
+
+	// the health checks are polled by the load balancers and aren't logged. The event streams and exports bypass the
+	// logging and gzip middlewares, which would buffer the responses and hide the write deadline.
+	root := mux.NewRouter()
+	root.HandleFunc(pathLivez, api.handleLivez).Methods(http.MethodGet)
+	root.HandleFunc(pathReadyz, api.handleReadyz).Methods(http.MethodGet)
+	root.HandleFunc(pathForkReadiness, api.handleForkReadiness).Methods(http.MethodGet)
+	if api.opts.BlockBuilderAPI {
+		root.HandleFunc(pathBuilderTopBidStream, api.handleBuilderTopBidStream).Methods(http.MethodGet)
+	}
+	if api.opts.DataAPI {
+		root.HandleFunc(pathDataStream, api.dataAPIRateLimitMiddleware(api.handleDataStream)).Methods(http.MethodGet)
+		root.HandleFunc(pathDataExport, api.dataAPIRateLimitMiddleware(api.handleDataExport)).Methods(http.MethodGet)
+	}
+	root.PathPrefix("/").Handler(withGz)
+	if api.ffShadowMode {
+		return markShadowMode(api.limitRequests(root))
+	}
+	return api.limitRequests(root)
+}
+
+// networkName returns the name of the network, the network label of the metrics
+func (api *RelayAPI) networkName() string {
+	return api.opts.EthNetDetails.Name
 }
 
 func (api *RelayAPI) isCapella(slot uint64) bool {
@@ -290,13 +614,87 @@ func (api *RelayAPI) isCapella(slot uint64) bool {
 	return epoch >= api.capellaEpoch
 }
 
+// isDeneb returns whether the slot is after the deneb fork, from which on submissions have to carry the blobs bundle
+func (api *RelayAPI) isDeneb(slot uint64) bool {
+	epoch := slot / uint64(common.SlotsPerEpoch)
+	return epoch >= api.denebEpoch
+}
+
 func (api *RelayAPI) isBellatrix(slot uint64) bool {
 	epoch := slot / uint64(common.SlotsPerEpoch)
 	return epoch >= api.bellatrixEpoch && epoch < api.capellaEpoch
 }
 
+// updateForkEpochs sets the fork epochs from the fork schedule of the beacon node. The fork epochs of a network config
+// apply where the fork schedule has no epoch.
+func (api *RelayAPI) updateForkEpochs() error {
+	forkSchedule, err := api.beaconClient.GetForkSchedule()
+	if err != nil {
+		return err
+	}
+
+	forkEpochs := make(map[string]uint64, len(api.opts.EthNetDetails.ForkEpochs)+len(forkSchedule.Data))
+	for version, epoch := range api.opts.EthNetDetails.ForkEpochs {
+		forkEpochs[version] = epoch
+	}
+	for _, fork := range forkSchedule.Data {
+		forkEpochs[fork.CurrentVersion] = fork.Epoch
+	}
+	for version, epoch := range forkEpochs {
+		switch version {
+		case api.opts.EthNetDetails.BellatrixForkVersionHex:
+			api.bellatrixEpoch = epoch
+		case api.opts.EthNetDetails.CapellaForkVersionHex:
+			api.capellaEpoch = epoch
+		case api.opts.EthNetDetails.DenebForkVersionHex:
+			api.denebEpoch = epoch
+		}
+	}
+	if api.denebEpoch != math.MaxUint64 {
+		api.log.Infof("deneb fork scheduled at epoch %d", api.denebEpoch)
+	}
+	api.forkSchedules.invalidate()
+	return nil
+}
+
 // StartServer starts the HTTP server for this instance
 func (api *RelayAPI) StartServer() (err error) {
+	if err := api.startServices(); err != nil {
+		return err
+	}
+
+	api.srv = &http.Server{
+		Addr:    api.opts.ListenAddr,
+		Handler: api.getRouter(),
+
+		ReadTimeout:       api.opts.HTTPServer.ReadTimeout,
+		ReadHeaderTimeout: api.opts.HTTPServer.ReadHeaderTimeout,
+		WriteTimeout:      api.opts.HTTPServer.WriteTimeout,
+		IdleTimeout:       api.opts.HTTPServer.IdleTimeout,
+		MaxHeaderBytes:    api.opts.HTTPServer.MaxHeaderBytes,
+		TLSConfig:         api.tlsConfig,
+	}
+
+	ln, err := common.Listen(api.opts.ListenAddr)
+	if err != nil {
+		return err
+	}
+	if api.tlsConfig != nil {
+		err = api.srv.ServeTLS(ln, "", "")
+	} else {
+		err = api.srv.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		// the server closes at the start of the shutdown, return once it's done draining
+		<-api.stoppedC
+		return nil
+	}
+	return err
+}
+
+// startServices loads the state of the instance and starts its background work and admin server, everything but the
+// HTTP server of the API
+func (api *RelayAPI) startServices() (err error) {
 	if api.srvStarted.Swap(true) {
 		return ErrServerAlreadyStarted
 	}
@@ -313,23 +711,16 @@ func (api *RelayAPI) StartServer() (err error) {
 	}
 	api.log.Infof("genesis info: %d", api.genesisInfo.Data.GenesisTime)
 
-	forkSchedule, err := api.beaconClient.GetForkSchedule()
-	if err != nil {
+	if err := api.updateForkEpochs(); err != nil {
 		return err
 	}
 
-	for _, fork := range forkSchedule.Data {
-		switch fork.CurrentVersion {
-		case api.opts.EthNetDetails.BellatrixForkVersionHex:
-			api.bellatrixEpoch = fork.Epoch
-		case api.opts.EthNetDetails.CapellaForkVersionHex:
-			api.capellaEpoch = fork.Epoch
-		}
-	}
-
 	currentSlot := bestSyncStatus.HeadSlot
 	currentEpoch := currentSlot / uint64(common.SlotsPerEpoch)
-	if api.isCapella(currentSlot) {
+	api.logForkReadiness(currentEpoch)
+	if api.isDeneb(currentSlot) {
+		api.log.Infof("deneb fork detected, startEpoch: %d / currentEpoch: %d", api.denebEpoch, currentEpoch)
+	} else if api.isCapella(currentSlot) {
 		api.log.Infof("capella fork detected, startEpoch: %d / currentEpoch: %d", api.capellaEpoch, currentEpoch)
 	} else if api.isBellatrix(currentSlot) {
 		api.log.Infof("bellatrix fork detected. capellaStartEpoch: %d / currentEpoch: %d", api.capellaEpoch, currentEpoch)
@@ -337,12 +728,52 @@ func (api *RelayAPI) StartServer() (err error) {
 		return ErrMismatchedForkVersions
 	}
 
-	// start things for the block-builder API
-	if api.opts.BlockBuilderAPI {
+	// Apply the feature flags set through the admin API, and keep them up to date
+	api.loadFeatureFlags()
+	go api.startFeatureFlagUpdates()
+
+	// proposer duties are used by the block-builder API and for getPayload validation
+	if api.opts.BlockBuilderAPI || api.opts.ProposerAPI {
 		// Get current proposer duties blocking before starting, to have them ready
 		api.updateProposerDuties(bestSyncStatus.HeadSlot)
 	}
 
+	// Load the proposer allowlist blocking before starting, and keep it up to date
+	if api.opts.ProposerAPI && api.ffProposerAllowlist {
+		api.updateProposerAllowlist()
+		go api.startProposerAllowlistUpdates()
+	}
+
+	// Load the data API keys, and keep them up to date
+	if api.opts.DataAPI {
+		api.updateDataAPIKeys()
+		go api.startDataAPIKeyUpdates()
+	}
+
+	// start things specific for the block-builder API
+	if api.opts.BlockBuilderAPI {
+		// Forward top bid updates of all relay instances to the stream subscribers
+		go api.topBidStream.run(api.redis.SubscribeTopBidUpdates(api.shutdownCtx))
+
+		// Take block simulation nodes out of and back into rotation
+		go api.blockSimQueue.nodes.startHealthChecks()
+
+		// Load the builder statuses blocking before starting, and apply the updates pushed by the housekeeper
+		api.loadBuilderStatuses()
+		go api.startBuilderStatusUpdates()
+
+		// Load the blocklist blocking before starting, and keep it up to date
+		if api.ffEnableBlocklist {
+			api.updateBlocklist()
+			go api.startBlocklistUpdates()
+		}
+	}
+
+	// Forward delivered payloads and accepted submissions of all relay instances to the data stream subscribers
+	if api.opts.DataAPI {
+		go api.dataStream.run(api.redis.SubscribeDataStreamEvents(api.shutdownCtx))
+	}
+
 	// start things specific for the proposer API
 	if api.opts.ProposerAPI {
 		// Update list of known validators, and start refresh loop
@@ -350,17 +781,49 @@ func (api *RelayAPI) StartServer() (err error) {
 
 		// Start the worker pool to process active validators
 		api.log.Infof("starting %d active validator processors", numActiveValidatorProcessors)
+		api.validatorProcessors.Add(numActiveValidatorProcessors)
 		for i := 0; i < numActiveValidatorProcessors; i++ {
 			go api.startActiveValidatorProcessor()
 		}
 
 		// Start the validator registration db-save processor
 		api.log.Infof("starting %d validator registration processors", numValidatorRegProcessors)
+		api.validatorProcessors.Add(numValidatorRegProcessors)
 		for i := 0; i < numValidatorRegProcessors; i++ {
 			go api.startValidatorRegistrationDBProcessor()
 		}
 	}
 
+	// start the builder submission db-save workers
+	if api.opts.BlockBuilderAPI {
+		api.blockSubmissionWriter.start(numSubmissionWriters)
+		go func() {
+			numReplayed, err := api.blockSubmissionWriter.replaySpill()
+			if err != nil {
+				api.log.WithError(err).Error("failed to replay the submission spill file")
+			} else if numReplayed > 0 {
+				api.log.Infof("saved %d submissions spilled by a previous run", numReplayed)
+			}
+		}()
+		if api.submissionMirror != nil {
+			api.submissionMirror.start(numSubmissionMirrorWorkers)
+		}
+	}
+
+	if api.requestCapture != nil {
+		api.requestCapture.start()
+	}
+
+	// Notify the webhooks of redis and database outages
+	if api.opts.Webhooks != nil {
+		go api.startDependencyMonitor()
+	}
+
+	// Regularly clean up the rate limiter buckets
+	go api.ipRateLimiter.startCleanupLoop(api.log.WithField("rateLimiter", "ip"))
+	go api.pubkeyRateLimiter.startCleanupLoop(api.log.WithField("rateLimiter", "pubkey"))
+	go api.apiKeyRateLimiter.startCleanupLoop(api.log.WithField("rateLimiter", "apiKey"))
+
 	// Process current slot
 	api.processNewSlot(bestSyncStatus.HeadSlot)
 
@@ -374,45 +837,21 @@ func (api *RelayAPI) StartServer() (err error) {
 		}
 	}()
 
-	api.srv = &http.Server{
-		Addr:    api.opts.ListenAddr,
-		Handler: api.getRouter(),
-
-		ReadTimeout:       time.Duration(apiReadTimeoutMs) * time.Millisecond,
-		ReadHeaderTimeout: time.Duration(apiReadHeaderTimeoutMs) * time.Millisecond,
-		WriteTimeout:      time.Duration(apiWriteTimeoutMs) * time.Millisecond,
-		IdleTimeout:       time.Duration(apiIdleTimeoutMs) * time.Millisecond,
-	}
-
-	err = api.srv.ListenAndServe()
-	if errors.Is(err, http.ErrServerClosed) {
-		return nil
-	}
-	return err
-}
-
-// StopServer disables sending any bids on getHeader calls, waits a few seconds to catch any remaining getPayload call, and then shuts down the webserver
-func (api *RelayAPI) StopServer() (err error) {
-	api.log.Info("Stopping server...")
-
-	if api.opts.ProposerAPI {
-		// stop sending bids
-		api.ffForceGetHeader204 = true
-		api.log.Info("Disabled sending bids, waiting a few seconds...")
-
-		// wait a few seconds, for any pending getPayload call to complete
-		time.Sleep(5 * time.Second)
-
-		// wait for any active getPayload call to finish
-		api.getPayloadCallsInFlight.Wait()
+	if api.opts.AdminListenAddr != "" {
+		api.adminSrv = &http.Server{
+			Addr:              api.opts.AdminListenAddr,
+			Handler:           api.getAdminRouter(),
+			ReadHeaderTimeout: api.opts.HTTPServer.ReadHeaderTimeout,
+			TLSConfig:         api.tlsConfig,
+		}
+		go api.startAdminServer()
 	}
-
-	// shutdown
-	return api.srv.Shutdown(context.Background())
+	return nil
 }
 
 // startActiveValidatorProcessor keeps listening on the channel and saving active validators to redis
 func (api *RelayAPI) startActiveValidatorProcessor() {
+	defer api.validatorProcessors.Done()
 	for pubkey := range api.activeValidatorC {
 		err := api.redis.SetActiveValidator(pubkey)
 		if err != nil {
@@ -421,17 +860,33 @@ func (api *RelayAPI) startActiveValidatorProcessor() {
 	}
 }
 
-// startActiveValidatorProcessor keeps listening on the channel and saving active validators to redis
+// startValidatorRegistrationDBProcessor keeps listening on the channel and saving validator registrations, with the ones
+// queued in the meantime saved together in one batch
 func (api *RelayAPI) startValidatorRegistrationDBProcessor() {
+	defer api.validatorProcessors.Done()
+	batch := make([]boostTypes.SignedValidatorRegistration, 0, validatorRegBatchSize)
 	for valReg := range api.validatorRegC {
-		err := api.datastore.SaveValidatorRegistration(valReg)
+		batch = append(batch[:0], valReg)
+	collect:
+		for len(batch) < validatorRegBatchSize {
+			select {
+			case valReg, ok := <-api.validatorRegC:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, valReg)
+			default:
+				break collect
+			}
+		}
+
+		if api.ffFeeRecipientAlerts {
+			api.checkFeeRecipientChanges(batch)
+		}
+
+		err := api.datastore.SaveValidatorRegistrations(batch)
 		if err != nil {
-			api.log.WithError(err).WithFields(logrus.Fields{
-				"reg_pubkey":       valReg.Message.Pubkey,
-				"reg_feeRecipient": valReg.Message.FeeRecipient,
-				"reg_gasLimit":     valReg.Message.GasLimit,
-				"reg_timestamp":    valReg.Message.Timestamp,
-			}).Error("error saving validator registration")
+			api.log.WithError(err).WithField("numRegistrations", len(batch)).Error("error saving validator registrations")
 		}
 	}
 }
@@ -448,28 +903,9 @@ func (api *RelayAPI) processNewSlot(headSlot uint64) {
 		}
 	}
 
-	// store the head slot
+	// store the head slot, and start the next slot. The subsystems update through the slot hooks.
 	api.headSlot.Store(headSlot)
-
-	// only for builder-api
-	if api.opts.BlockBuilderAPI {
-		// query the expected prev_randao field
-		go api.updatedExpectedRandao(headSlot)
-
-		// query expected withdrawals root
-		go api.updatedExpectedWithdrawals(headSlot)
-
-		// update proposer duties in the background
-		go api.updateProposerDuties(headSlot)
-	}
-
-	// log
-	epoch := headSlot / uint64(common.SlotsPerEpoch)
-	api.log.WithFields(logrus.Fields{
-		"epoch":              epoch,
-		"slotHead":           headSlot,
-		"slotStartNextEpoch": (epoch + 1) * uint64(common.SlotsPerEpoch),
-	}).Infof("updated headSlot to %d", headSlot)
+	api.slots.start(headSlot+1, api.requiredSlotAttributes(headSlot))
 }
 
 func (api *RelayAPI) updateProposerDuties(headSlot uint64) {
@@ -479,24 +915,17 @@ func (api *RelayAPI) updateProposerDuties(headSlot uint64) {
 	}
 	defer api.isUpdatingProposerDuties.Store(false)
 
-	// Update once every 8 slots (or more, if a slot was missed)
-	if headSlot%8 != 0 && headSlot-api.proposerDutiesSlot < 8 {
+	// Update every 8 slots, which includes the first slot of every epoch, to pick up the updates of the housekeeper. If
+	// that slot was missed, update with the next one.
+	updatedSlot, _ := api.proposerDuties.lastUpdate()
+	if headSlot%8 != 0 && headSlot-updatedSlot < 8 {
 		return
 	}
 
-	// Get duties from mem
+	// Get the duties from redis, the lookups of the hot paths only use the in-memory tables
 	duties, err := api.redis.GetProposerDuties()
-	dutiesMap := make(map[uint64]*boostTypes.RegisterValidatorRequestMessage)
-	for _, duty := range duties {
-		dutiesMap[duty.Slot] = duty.Entry.Message
-	}
-
 	if err == nil {
-		api.proposerDutiesLock.Lock()
-		api.proposerDutiesResponse = duties
-		api.proposerDutiesMap = dutiesMap
-		api.proposerDutiesSlot = headSlot
-		api.proposerDutiesLock.Unlock()
+		api.proposerDuties.set(duties, headSlot)
 
 		// pretty-print
 		_duties := make([]string, len(duties))
@@ -526,9 +955,18 @@ func (api *RelayAPI) startKnownValidatorUpdates() {
 }
 
 func (api *RelayAPI) RespondError(w http.ResponseWriter, code int, message string) {
+	api.RespondErrorWithCode(w, code, "", message)
+}
+
+// RespondErrorWithCode responds with an error that includes a machine-readable error code, the generic code of the
+// status if none is given
+func (api *RelayAPI) RespondErrorWithCode(w http.ResponseWriter, code int, errorCode, message string) {
+	if errorCode == "" {
+		errorCode = errorCodeForStatus(code)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	resp := HTTPErrorResp{code, message}
+	resp := HTTPErrorResp{Code: code, Message: message, ErrorCode: errorCode}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		api.log.WithField("response", resp).WithError(err).Error("Couldn't write error response")
 		http.Error(w, "", http.StatusInternalServerError)
@@ -554,12 +992,16 @@ func (api *RelayAPI) handleStatus(w http.ResponseWriter, req *http.Request) {
 
 func (api *RelayAPI) handleRoot(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
+	if api.ffShadowMode {
+		fmt.Fprintf(w, "MEV-Boost Relay API (shadow mode, payloads are not delivered)")
+		return
+	}
 	fmt.Fprintf(w, "MEV-Boost Relay API")
 }
 
 func (api *RelayAPI) handleRegisterValidator(w http.ResponseWriter, req *http.Request) {
 	ua := req.UserAgent()
-	log := api.log.WithFields(logrus.Fields{
+	log := api.requestLogger(req).WithFields(logrus.Fields{
 		"method":    "registerValidator",
 		"ua":        ua,
 		"mevBoostV": common.GetMevBoostVersionFromUserAgent(ua),
@@ -572,47 +1014,87 @@ func (api *RelayAPI) handleRegisterValidator(w http.ResponseWriter, req *http.Re
 	numRegProcessed := 0
 	numRegActive := 0
 	numRegNew := 0
+	numRegUnchanged := 0
+	numRegRateLimited := 0
 	processingStoppedByError := false
 
-	respondError := func(code int, msg string) {
+	respondError := func(code int, errorCode, msg string) {
 		processingStoppedByError = true
 		log.Warnf("error: %s", msg)
-		api.RespondError(w, code, msg)
+		api.RespondErrorWithCode(w, code, errorCode, msg)
 	}
 
 	if req.ContentLength == 0 {
-		respondError(http.StatusBadRequest, "empty request")
+		respondError(http.StatusBadRequest, ErrorCodeInvalidRequest, "empty request")
 		return
 	}
 
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
+	var r io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := common.GetGzipReader(req.Body)
+		if err != nil {
+			log.WithError(err).Warn("could not create gzip reader")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		defer common.PutGzipReader(gzipReader)
+		r = api.limitDecompressedBody(w, req, gzipReader)
+		log = log.WithField("gzip-req", true)
+	}
+
+	body, err := io.ReadAll(r)
+	if isBodyTooLarge(err) {
+		respondError(http.StatusRequestEntityTooLarge, ErrorCodeRequestTooLarge, "request body too large")
+		return
+	} else if err != nil {
 		log.WithError(err).WithField("contentLength", req.ContentLength).Warn("failed to read request body")
-		api.RespondError(w, http.StatusBadRequest, "failed to read request body")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "failed to read request body")
 		return
 	}
 	req.Body.Close()
 
-	parseRegistration := func(value []byte) (pkHex boostTypes.PubkeyHex, timestampInt int64, err error) {
+	parseRegistration := func(value []byte) (reg pendingRegistration, timestampInt int64, err error) {
 		pubkey, err := jsonparser.GetUnsafeString(value, "message", "pubkey")
 		if err != nil {
-			return pkHex, timestampInt, fmt.Errorf("registration message error (pubkey): %w", err)
+			return reg, timestampInt, fmt.Errorf("registration message error (pubkey): %w", err)
 		}
 
 		timestamp, err := jsonparser.GetUnsafeString(value, "message", "timestamp")
 		if err != nil {
-			return pkHex, timestampInt, fmt.Errorf("registration message error (timestamp): %w", err)
+			return reg, timestampInt, fmt.Errorf("registration message error (timestamp): %w", err)
 		}
 
 		timestampInt, err = strconv.ParseInt(timestamp, 10, 64)
 		if err != nil {
-			return pkHex, timestampInt, fmt.Errorf("invalid timestamp: %w", err)
+			return reg, timestampInt, fmt.Errorf("invalid timestamp: %w", err)
+		}
+
+		feeRecipient, err := jsonparser.GetUnsafeString(value, "message", "fee_recipient")
+		if err != nil {
+			return reg, timestampInt, fmt.Errorf("registration message error (fee_recipient): %w", err)
+		}
+
+		gasLimit, err := jsonparser.GetUnsafeString(value, "message", "gas_limit")
+		if err != nil {
+			return reg, timestampInt, fmt.Errorf("registration message error (gas_limit): %w", err)
+		}
+
+		gasLimitInt, err := strconv.ParseUint(gasLimit, 10, 64)
+		if err != nil {
+			return reg, timestampInt, fmt.Errorf("invalid gas limit: %w", err)
 		}
 
-		return boostTypes.PubkeyHex(pubkey), timestampInt, nil
+		return pendingRegistration{
+			value:        value,
+			pubkey:       boostTypes.PubkeyHex(pubkey),
+			timestamp:    uint64(timestampInt),
+			feeRecipient: feeRecipient,
+			gasLimit:     gasLimitInt,
+		}, timestampInt, nil
 	}
 
-	// Iterate over the registrations
+	// Iterate over the registrations, parsing only the fields needed for the checks
+	registrations := []pendingRegistration{}
 	_, err = jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, _err error) {
 		numRegTotal += 1
 		if processingStoppedByError {
@@ -621,80 +1103,64 @@ func (api *RelayAPI) handleRegisterValidator(w http.ResponseWriter, req *http.Re
 		numRegProcessed += 1
 
 		// Extract immediately necessary registration fields
-		pkHex, timestampInt, err := parseRegistration(value)
+		reg, timestampInt, err := parseRegistration(value)
 		if err != nil {
-			respondError(http.StatusBadRequest, err.Error())
+			respondError(http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
 			return
 		}
 
-		// Add validator pubkey to logs
-		regLog := api.log.WithField("pubkey", pkHex.String())
-
 		// Ensure registration is not too far in the future
 		registrationTime := time.Unix(timestampInt, 0)
 		if registrationTime.After(registrationTimeUpperBound) {
-			respondError(http.StatusBadRequest, "timestamp too far in the future")
+			respondError(http.StatusBadRequest, ErrorCodeRegistrationTooNew, "timestamp too far in the future")
 			return
 		}
 
-		// Check if a real validator
-		isKnownValidator := api.datastore.IsKnownValidator(pkHex)
-		if !isKnownValidator {
-			respondError(http.StatusBadRequest, fmt.Sprintf("not a known validator: %s", pkHex.String()))
-			return
-		}
+		registrations = append(registrations, reg)
+	})
+
+	if err != nil {
+		respondError(http.StatusBadRequest, ErrorCodeInvalidRequest, "error in traversing json")
+		return
+	}
+	// Check and verify the registrations in parallel, and queue the new ones for saving only if all are valid
+	if !processingStoppedByError && len(registrations) > 0 {
+		timeStartProcess := time.Now()
+		result, err := api.processRegistrations(log, registrations, numRegVerifyWorkers)
+		log = log.WithField("timeNeededProcessSec", time.Since(timeStartProcess).Seconds())
+		numRegActive = len(result.activeValidators)
+		numRegNew = result.numNew
+		numRegUnchanged = result.numUnchanged
+		numRegRateLimited = result.numRateLimited
+		common.ValidatorRegistrationsTotal.WithLabelValues(api.networkName(), "new").Add(float64(result.numNew))
+		common.ValidatorRegistrationsTotal.WithLabelValues(api.networkName(), "unchanged").Add(float64(result.numUnchanged))
+		common.ValidatorRegistrationsTotal.WithLabelValues(api.networkName(), "outdated").Add(float64(result.numOutdated))
 
 		// Track active validators here
-		numRegActive += 1
-		select {
-		case api.activeValidatorC <- pkHex:
-		default:
-			regLog.Error("active validator channel full")
+		for _, pkHex := range result.activeValidators {
+			select {
+			case api.activeValidatorC <- pkHex:
+			default:
+				log.WithField("pubkey", pkHex.String()).Error("active validator channel full")
+			}
 		}
 
-		// Check for a previous registration timestamp
-		prevTimestamp, err := api.redis.GetValidatorRegistrationTimestamp(pkHex)
 		if err != nil {
-			regLog.WithError(err).Error("error getting last registration timestamp")
-		} else if prevTimestamp >= uint64(timestampInt) {
-			// abort if the current registration timestamp is older or equal to the last known one
+			respondError(http.StatusBadRequest, registrationErrorCode(err), err.Error())
 			return
 		}
-
-		// Now we have a new registration to process
-		numRegNew += 1
-
-		// JSON-decode the registration now (needed for signature verification)
-		signedValidatorRegistration := new(boostTypes.SignedValidatorRegistration)
-		err = json.Unmarshal(value, signedValidatorRegistration)
-		if err != nil {
-			regLog.WithError(err).Error("error unmarshalling signed validator registration")
-			respondError(http.StatusBadRequest, fmt.Sprintf("error unmarshalling signed validator registration: %s", err.Error()))
+		if numRegRateLimited > 0 && numRegRateLimited == numRegProcessed {
+			respondError(http.StatusTooManyRequests, ErrorCodeRateLimited, "too many registrations")
 			return
 		}
 
-		// Verify the signature
-		ok, err := boostTypes.VerifySignature(signedValidatorRegistration.Message, api.opts.EthNetDetails.DomainBuilder, signedValidatorRegistration.Message.Pubkey[:], signedValidatorRegistration.Signature[:])
-		if err != nil {
-			regLog.WithError(err).Error("error verifying registerValidator signature")
-			respondError(http.StatusBadRequest, fmt.Sprintf("error verifying registerValidator signature: %s", err.Error()))
-			return
-		} else if !ok {
-			api.RespondError(w, http.StatusBadRequest, fmt.Sprintf("failed to verify validator signature for %s", signedValidatorRegistration.Message.Pubkey.String()))
-			return
-		}
-
-		// Save to database
-		select {
-		case api.validatorRegC <- *signedValidatorRegistration:
-		default:
-			regLog.Error("validator registration channel full")
+		for _, signedValidatorRegistration := range result.newRegistrations {
+			select {
+			case api.validatorRegC <- *signedValidatorRegistration:
+			default:
+				log.WithField("pubkey", signedValidatorRegistration.Message.Pubkey.String()).Error("validator registration channel full")
+			}
 		}
-	})
-
-	if err != nil {
-		respondError(http.StatusBadRequest, "error in traversing json")
-		return
 	}
 
 	log = log.WithFields(logrus.Fields{
@@ -703,6 +1169,8 @@ func (api *RelayAPI) handleRegisterValidator(w http.ResponseWriter, req *http.Re
 		"numRegistrationsActive":    numRegActive,
 		"numRegistrationsProcessed": numRegProcessed,
 		"numRegistrationsNew":       numRegNew,
+		"numRegistrationsUnchanged": numRegUnchanged,
+		"numRegistrationsLimited":   numRegRateLimited,
 		"processingStoppedByError":  processingStoppedByError,
 	})
 	log.Info("validator registrations call processed")
@@ -710,12 +1178,13 @@ func (api *RelayAPI) handleRegisterValidator(w http.ResponseWriter, req *http.Re
 }
 
 func (api *RelayAPI) handleGetHeader(w http.ResponseWriter, req *http.Request) {
+	receivedAt := time.Now().UTC()
 	vars := mux.Vars(req)
 	slotStr := vars["slot"]
 	parentHashHex := vars["parent_hash"]
 	proposerPubkeyHex := vars["pubkey"]
 	ua := req.UserAgent()
-	log := api.log.WithFields(logrus.Fields{
+	log := api.requestLogger(req).WithFields(logrus.Fields{
 		"method":     "getHeader",
 		"slot":       slotStr,
 		"parentHash": parentHashHex,
@@ -726,37 +1195,62 @@ func (api *RelayAPI) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 
 	slot, err := strconv.ParseUint(slotStr, 10, 64)
 	if err != nil {
-		api.RespondError(w, http.StatusBadRequest, common.ErrInvalidSlot.Error())
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, common.ErrInvalidSlot.Error())
 		return
 	}
 
 	if len(proposerPubkeyHex) != 98 {
-		api.RespondError(w, http.StatusBadRequest, common.ErrInvalidPubkey.Error())
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, common.ErrInvalidPubkey.Error())
 		return
 	}
 
 	if len(parentHashHex) != 66 {
-		api.RespondError(w, http.StatusBadRequest, common.ErrInvalidHash.Error())
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, common.ErrInvalidHash.Error())
 		return
 	}
 
 	if slot < api.headSlot.Load() {
-		api.RespondError(w, http.StatusBadRequest, "slot is too old")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeSlotTooOld, "slot is too old")
 		return
 	}
 
 	log.Debug("getHeader request received")
 
-	if api.ffForceGetHeader204 {
+	if api.ffProposerAllowlist && !api.isAllowedProposer(proposerPubkeyHex) {
+		log.Info("proposer not on the allowlist, no bid")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if api.ffForceGetHeader204.Load() {
 		log.Info("forced getHeader 204 response")
 		w.WriteHeader(http.StatusNoContent)
 		return
+	} else if api.isShuttingDown.Load() {
+		log.Info("shutting down, no bid")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// there are no bids for validators which aren't the proposer of the slot, which is answered without redis
+	if duty := api.proposerDuties.dutyForSlot(slot); duty != nil && !strings.EqualFold(duty.Pubkey.String(), proposerPubkeyHex) {
+		log.WithField("dutyPubkey", duty.Pubkey.String()).Info("not the proposer of the slot, no bid")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// the bid is read after the hold, to serve the best one at that time
+	if !api.holdGetHeader(req.Context(), slot) {
+		log.Info("getHeader request ended while held")
+		return
 	}
 
+	_, span := common.Tracer.Start(req.Context(), "getBestBid")
 	bid, err := api.redis.GetBestBid(slot, parentHashHex, proposerPubkeyHex)
+	span.End()
 	if err != nil {
 		log.WithError(err).Error("could not get bid")
-		api.RespondError(w, http.StatusBadRequest, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not get bid")
 		return
 	}
 
@@ -765,25 +1259,71 @@ func (api *RelayAPI) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// a top bid missing constrained transactions is replaced by the best bid including them
+	if api.ffInclusionConstraints {
+		bid = api.bestBidMeetingInclusionConstraints(log, slot, parentHashHex, proposerPubkeyHex, bid)
+		if bid == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
 	// Error on bid without value
 	if bid.Value().Cmp(big.NewInt(0)) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	if api.isBelowMinBid(bid.Value()) {
+		log.WithField("value", bid.Value().String()).Info("bid below the minimum, no bid")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	log.WithFields(logrus.Fields{
 		"value":     bid.Value().String(),
 		"blockHash": bid.BlockHash().String(),
 	}).Info("bid delivered")
 	api.RespondOK(w, bid)
+
+	// the timing of the calls is recorded with the delivered payload
+	api.runInBackground(func() {
+		if err := api.redis.SetGetHeaderRequestTime(slot, strings.ToLower(proposerPubkeyHex), receivedAt); err != nil {
+			log.WithError(err).Error("failed to save getHeader request time to redis")
+		}
+	})
+}
+
+// decodeSignedBlindedBeaconBlockSSZ decodes an SSZ-encoded getPayload request. SSZ doesn't carry the fork, so it's
+// inferred from the slot.
+func (api *RelayAPI) decodeSignedBlindedBeaconBlockSSZ(data []byte) (*common.SignedBlindedBeaconBlock, error) {
+	slot, err := sszSignedBlindedBeaconBlockSlot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := new(common.SignedBlindedBeaconBlock)
+	if api.isDeneb(slot) {
+		return nil, ErrDenebSSZNotSupported
+	} else if api.isCapella(slot) {
+		payload.Capella, err = unmarshalCapellaSignedBlindedBeaconBlockSSZ(data)
+	} else {
+		payload.Bellatrix = new(boostTypes.SignedBlindedBeaconBlock)
+		err = payload.Bellatrix.UnmarshalSSZ(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSSZ, err)
+	}
+	return payload, nil
 }
 
 func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request) {
+	receivedAt := time.Now().UTC()
 	api.getPayloadCallsInFlight.Add(1)
 	defer api.getPayloadCallsInFlight.Done()
 
 	ua := req.UserAgent()
-	log := api.log.WithFields(logrus.Fields{
+	log := api.requestLogger(req).WithFields(logrus.Fields{
 		"method":        "getPayload",
 		"ua":            ua,
 		"mevBoostV":     common.GetMevBoostVersionFromUserAgent(ua),
@@ -794,30 +1334,64 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 	// Read the body first, so we can decode it later
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			log.WithError(err).Warn("getPayload request body too large")
+			api.RespondErrorWithCode(w, http.StatusRequestEntityTooLarge, ErrorCodeRequestTooLarge, "request body too large")
+			return
+		}
+
 		if strings.Contains(err.Error(), "i/o timeout") {
 			log.WithError(err).Error("getPayload request failed to decode (i/o timeout)")
-			api.RespondError(w, http.StatusInternalServerError, err.Error())
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeTimeout, "timeout reading the request body")
 			return
 		}
 
 		log.WithError(err).Error("could not read body of request from the beacon node")
-		api.RespondError(w, http.StatusBadRequest, err.Error())
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
 		return
 	}
 
+	isSSZ := isSSZContentType(req.Header.Get("Content-Type"))
+	if api.requestCapture != nil {
+		slot, _ := peekGetPayloadSlot(body, isSSZ)
+		api.requestCapture.capture(req, "getPayload", body, slot, receivedAt)
+	}
+
 	payload := new(common.SignedBlindedBeaconBlock)
-	capellaPayload := new(capella.SignedBlindedBeaconBlock)
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(capellaPayload); err != nil {
-		log.WithError(err).Debug("capella getPayload request failed to decode")
-		bellatrixPayload := new(boostTypes.SignedBlindedBeaconBlock)
-		if err := json.NewDecoder(bytes.NewReader(body)).Decode(bellatrixPayload); err != nil {
-			log.WithError(err).Warn("bellatrix getPayload request failed to decode")
-			api.RespondError(w, http.StatusBadRequest, err.Error())
+	if isSSZ {
+		_, span := common.Tracer.Start(req.Context(), "decode")
+		payload, err = api.decodeSignedBlindedBeaconBlockSSZ(body)
+		span.End()
+		if err != nil {
+			log.WithError(err).Warn("SSZ getPayload request failed to decode")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
 			return
 		}
-		payload.Bellatrix = bellatrixPayload
-	} else {
-		payload.Capella = capellaPayload
+	} else {
+		// the capella decoding ignores the fields deneb adds, so deneb is tried first
+		denebPayload := new(common.DenebSignedBlindedBeaconBlock)
+		capellaPayload := new(capella.SignedBlindedBeaconBlock)
+		_, span := common.Tracer.Start(req.Context(), "decode")
+		if err = json.Unmarshal(body, denebPayload); err == nil {
+			payload.Deneb = denebPayload
+		} else {
+			err = json.NewDecoder(bytes.NewReader(body)).Decode(capellaPayload)
+		}
+		span.End()
+		if payload.Deneb != nil {
+			log.Debug("deneb getPayload request decoded")
+		} else if err != nil {
+			log.WithError(err).Debug("capella getPayload request failed to decode")
+			bellatrixPayload := new(boostTypes.SignedBlindedBeaconBlock)
+			if err := json.NewDecoder(bytes.NewReader(body)).Decode(bellatrixPayload); err != nil {
+				log.WithError(err).Warn("bellatrix getPayload request failed to decode")
+				api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+				return
+			}
+			payload.Bellatrix = bellatrixPayload
+		} else {
+			payload.Capella = capellaPayload
+		}
 	}
 
 	log = log.WithFields(logrus.Fields{
@@ -831,7 +1405,7 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 	proposerPubkey, found := api.datastore.GetKnownValidatorPubkeyByIndex(payload.ProposerIndex())
 	if !found {
 		log.Errorf("could not find proposer pubkey for index %d", payload.ProposerIndex())
-		api.RespondError(w, http.StatusBadRequest, "could not match proposer index to pubkey")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnknownValidator, "could not match proposer index to pubkey")
 		return
 	}
 
@@ -841,26 +1415,80 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 	pk, err := boostTypes.HexToPubkey(proposerPubkey.String())
 	if err != nil {
 		log.WithError(err).Warn("could not convert pubkey to types.PublicKey")
-		api.RespondError(w, http.StatusBadRequest, "could not convert pubkey to types.PublicKey")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "could not convert pubkey to types.PublicKey")
 		return
 	}
 
-	// Attempt verifying the signature for capella
-	ok, err := boostTypes.VerifySignature(payload.Message(), api.opts.EthNetDetails.DomainBeaconProposerCapella, pk[:], payload.Signature())
+	// Verify the signature with the domain of the fork at the slot's epoch. This comes before all other checks, so that
+	// only requests signed by the proposer are recorded as failures.
+	signingDomain := api.opts.EthNetDetails.DomainBeaconProposerBellatrix
+	if api.isDeneb(payload.Slot()) {
+		signingDomain = api.opts.EthNetDetails.DomainBeaconProposerDeneb
+	} else if api.isCapella(payload.Slot()) {
+		signingDomain = api.opts.EthNetDetails.DomainBeaconProposerCapella
+	}
+	_, span := common.Tracer.Start(req.Context(), "verifySignature")
+	ok, err := boostTypes.VerifySignature(payload.Message(), signingDomain, pk[:], payload.Signature())
+	span.End()
 	if !ok || err != nil {
-		log.WithError(err).Debug("could not verify capella payload signature, attempting to verify signature for bellatrix")
-		// Attempt verifying the signature for bellatrix
-		ok, err := boostTypes.VerifySignature(payload.Message(), api.opts.EthNetDetails.DomainBeaconProposerBellatrix, pk[:], payload.Signature())
-		if !ok || err != nil {
-			log.WithError(err).Warn("could not verify payload signature")
-			api.RespondError(w, http.StatusBadRequest, "could not verify payload signature")
+		log.WithError(err).Warn("could not verify payload signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "could not verify payload signature")
+		return
+	}
+
+	// Never deliver a payload in shadow mode, but record the request like any other failure
+	if api.ffShadowMode {
+		api.rejectGetPayload(w, log, payload, proposerPubkey.String(), ErrorCodePayloadUnavailable, ErrShadowMode.Error())
+		return
+	}
+
+	// Ensure the request arrives in time
+	if msIntoSlot, ok := api.msIntoSlot(payload.Slot(), time.Now()); ok {
+		log = log.WithField("msIntoSlot", msIntoSlot)
+		if msIntoSlot > int64(getPayloadRequestCutoffMs) {
+			api.rejectGetPayload(w, log, payload, proposerPubkey.String(), ErrorCodeGetPayloadTooLate, fmt.Sprintf("request too late: %d ms into slot", msIntoSlot))
 			return
 		}
 	}
 
+	// Ensure the proposer is the one scheduled for this slot
+	slotDuty := api.proposerDuties.dutyForSlot(payload.Slot())
+	if slotDuty == nil {
+		api.rejectGetPayload(w, log, payload, proposerPubkey.String(), ErrorCodeNoProposerDuty, "no proposer duty for this slot")
+		return
+	} else if !strings.EqualFold(slotDuty.Pubkey.String(), proposerPubkey.String()) {
+		api.rejectGetPayload(w, log, payload, proposerPubkey.String(), ErrorCodeNotSlotProposer, fmt.Sprintf("proposer index does not match duty, expected pubkey %s", slotDuty.Pubkey.String()))
+		return
+	}
+
+	// Refuse to reveal a second payload for the slot, which would allow the proposer to unbundle the first block
+	prevBlockHash, err := api.redis.CheckAndSetGetPayloadBlockHash(payload.Slot(), proposerPubkey.String(), payload.BlockHash())
+	if err != nil {
+		log.WithError(err).Error("failed to check for proposer equivocation")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not check for proposer equivocation")
+		return
+	} else if prevBlockHash != "" {
+		log.WithField("prevBlockHash", prevBlockHash).Error("proposer equivocation: getPayload for a different block in the same slot")
+		api.rejectGetPayload(w, log, payload, proposerPubkey.String(), ErrorCodeProposerEquivocation, fmt.Sprintf("proposer equivocation: payload already requested for block %s", prevBlockHash))
+		return
+	}
+
+	api.slots.payloadRequested(log, payload.Slot())
+
 	// Get the response - from memory, Redis or DB
 	// note that mev-boost might send getPayload for bids of other relays, thus this code wouldn't find anything
+	_, span = common.Tracer.Start(req.Context(), "getPayloadResponse")
 	getPayloadResp, err := api.datastore.GetGetPayloadResponse(payload.Slot(), proposerPubkey.String(), payload.BlockHash())
+	span.End()
+	if err != nil || getPayloadResp == nil {
+		// header-only submissions don't have a payload stored, it has to be fetched from the builder
+		payloadURL, urlErr := api.redis.GetDeferredPayloadURL(payload.Slot(), proposerPubkey.String(), payload.BlockHash())
+		if urlErr != nil {
+			log.WithError(urlErr).Error("failed getting deferred payload url from redis")
+		} else if payloadURL != "" {
+			getPayloadResp, err = api.fetchDeferredPayload(log, payload, proposerPubkey.String(), payloadURL)
+		}
+	}
 	if err != nil || getPayloadResp == nil {
 		log.WithError(err).Warn("failed getting execution payload (1/2)")
 		time.Sleep(time.Duration(timeoutGetPayloadRetryMs) * time.Millisecond)
@@ -869,11 +1497,13 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 		getPayloadResp, err = api.datastore.GetGetPayloadResponse(payload.Slot(), proposerPubkey.String(), payload.BlockHash())
 		if err != nil {
 			log.WithError(err).Error("failed getting execution payload (2/2) - due to error")
-			api.RespondError(w, http.StatusBadRequest, err.Error())
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not get the execution payload")
+			api.notifyPayloadDeliveryFailed(payload, proposerPubkey.String(), err.Error())
 			return
 		} else if getPayloadResp == nil {
 			log.Warn("failed getting execution payload (2/2)")
-			api.RespondError(w, http.StatusBadRequest, "no execution payload for this request")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodePayloadNotFound, "no execution payload for this request")
+			api.notifyPayloadDeliveryFailed(payload, proposerPubkey.String(), "no execution payload for this request")
 			return
 		}
 	}
@@ -886,7 +1516,8 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 	log.Info("execution payload delivered")
 
 	// Save information about delivered payload
-	go func() {
+	deliveredPayloadSaved := make(chan struct{})
+	api.runInBackground(func() {
 		err = api.redis.SetStats(datastore.RedisStatsFieldSlotLastPayloadDelivered, payload.Slot())
 		if err != nil {
 			log.WithError(err).Error("failed to save delivered payload slot to redis")
@@ -897,12 +1528,16 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 			log.WithError(err).Error("failed to get bidTrace for delivered payload from redis")
 		}
 
-		err = api.db.SaveDeliveredPayload(bidTrace, payload)
+		timing := api.deliveredPayloadTiming(log, payload.Slot(), proposerPubkey.String(), receivedAt)
+		err = api.db.SaveDeliveredPayload(bidTrace, payload, timing)
+		close(deliveredPayloadSaved)
 		if err != nil {
 			log.WithError(err).WithFields(logrus.Fields{
 				"bidTrace": bidTrace,
 				"payload":  payload,
 			}).Error("failed to save delivered payload")
+		} else if bidTrace != nil {
+			api.slots.payloadDelivered(log, bidTrace)
 		}
 
 		// Increment builder stats
@@ -910,17 +1545,45 @@ func (api *RelayAPI) handleGetPayload(w http.ResponseWriter, req *http.Request)
 		if err != nil {
 			log.WithError(err).Error("failed to increment builder-stats after getPayload")
 		}
-	}()
+
+		// The block might have been an optimistic one which already failed simulation
+		api.checkOptimisticRefund(log, payload.Slot(), payload.BlockHash())
+	})
 
 	// Publish the signed beacon block via beacon-node
-	go func() {
-		if api.ffDisableBlockPublishing {
+	publishCtx := common.DetachTraceContext(req.Context())
+	api.runInBackground(func() {
+		if api.ffDisableBlockPublishing.Load() {
 			log.Info("publishing the block is disabled")
 			return
 		}
 		signedBeaconBlock := SignedBlindedBeaconBlockToBeaconBlock(payload, getPayloadResp)
-		_, _ = api.beaconClient.PublishBlock(signedBeaconBlock) // errors are logged inside
-	}()
+		api.publishAndConfirmBlock(publishCtx, log, signedBeaconBlock, proposerPubkey.String(), receivedAt, deliveredPayloadSaved)
+	})
+}
+
+// rejectGetPayload responds with an error and records the rejection reason in the database, see
+// getPayloadFailureRecorder. Only requests with a valid proposer signature may be rejected with it.
+func (api *RelayAPI) rejectGetPayload(w http.ResponseWriter, log *logrus.Entry, payload *common.SignedBlindedBeaconBlock, proposerPubkey, errorCode, reason string) {
+	log.WithField("reason", reason).Warn("getPayload request rejected")
+	api.RespondErrorWithCode(w, http.StatusBadRequest, errorCode, reason)
+
+	if !api.getPayloadFailures.start(payload.Slot(), proposerPubkey, errorCode) {
+		return
+	}
+	api.runInBackground(func() {
+		defer api.getPayloadFailures.done()
+		err := api.db.SaveGetPayloadFailure(database.GetPayloadFailureEntry{
+			Slot:           payload.Slot(),
+			ProposerIndex:  payload.ProposerIndex(),
+			ProposerPubkey: proposerPubkey,
+			BlockHash:      payload.BlockHash(),
+			Reason:         reason,
+		})
+		if err != nil {
+			log.WithError(err).Error("failed to save getPayload failure")
+		}
+	})
 }
 
 // --------------------
@@ -954,18 +1617,23 @@ func (api *RelayAPI) updatedExpectedRandao(slot uint64) {
 
 	// after request, check if still the latest, then update
 	api.expectedPrevRandaoLock.Lock()
-	defer api.expectedPrevRandaoLock.Unlock()
 	targetSlot := slot + 1
 	log.Debugf("- after BN randao: targetSlot: %d latest: %d", targetSlot, api.expectedPrevRandao.slot)
 
 	// update if still the latest
-	if targetSlot >= api.expectedPrevRandao.slot {
+	updated := targetSlot >= api.expectedPrevRandao.slot
+	if updated {
 		api.expectedPrevRandao = randaoHelper{
 			slot:       targetSlot, // the retrieved prev_randao is for the next slot
 			prevRandao: randao.Data.Randao,
 		}
 		log.Infof("updated expected prev_randao to %s for slot %d", randao.Data.Randao, targetSlot)
 	}
+	api.expectedPrevRandaoLock.Unlock()
+
+	if updated {
+		api.slots.attributeKnown(targetSlot, slotAttributePrevRandao)
+	}
 }
 
 // updatedExpectedWithdrawals updates the withdrawals field we expect from builder block submissions
@@ -1003,35 +1671,67 @@ func (api *RelayAPI) updatedExpectedWithdrawals(slot uint64) {
 
 	// after request, check if still the latest, then update
 	api.expectedWithdrawalsLock.Lock()
-	defer api.expectedWithdrawalsLock.Unlock()
 	targetSlot := slot + 1
 	log.Debugf("- after BN withdrawals: targetSlot: %d latest: %d", targetSlot, api.expectedWithdrawalsRoot.slot)
 
 	// update if still the latest
+	updated := false
 	if targetSlot >= api.expectedWithdrawalsRoot.slot {
 		withdrawalsRoot, err := ComputeWithdrawalsRoot(withdrawals.Data.Withdrawals)
 		if err != nil {
 			log.WithError(err).Warn("failed to compute withdrawals root")
 			api.expectedWithdrawalsUpdating = 0
-			return
-		}
-		api.expectedWithdrawalsRoot = withdrawalsHelper{
-			slot: targetSlot, // the retrieved withdrawals is for the next slot
-			root: withdrawalsRoot,
+		} else {
+			api.expectedWithdrawalsRoot = withdrawalsHelper{
+				slot: targetSlot, // the retrieved withdrawals is for the next slot
+				root: withdrawalsRoot,
+			}
+			log.Infof("updated expected withdrawals root to %s for slot %d", withdrawalsRoot, targetSlot)
+			updated = true
 		}
-		log.Infof("updated expected withdrawals root to %s for slot %d", withdrawalsRoot, targetSlot)
+	}
+	api.expectedWithdrawalsLock.Unlock()
+
+	if updated {
+		api.slots.attributeKnown(targetSlot, slotAttributeWithdrawals)
 	}
 }
 
 func (api *RelayAPI) handleBuilderGetValidators(w http.ResponseWriter, req *http.Request) {
-	api.proposerDutiesLock.RLock()
-	defer api.proposerDutiesLock.RUnlock()
-	api.RespondOK(w, api.proposerDutiesResponse)
+	includePreferences := false
+	if arg := req.URL.Query().Get("include_preferences"); arg != "" {
+		var err error
+		includePreferences, err = strconv.ParseBool(arg)
+		if err != nil {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid include_preferences argument")
+			return
+		}
+	}
+
+	duties := api.proposerDuties.duties()
+	if !includePreferences {
+		api.RespondOK(w, duties)
+		return
+	}
+
+	response := make([]common.BuilderGetValidatorsResponseEntryWithPreferences, 0, len(duties))
+	for _, duty := range duties {
+		entry := common.BuilderGetValidatorsResponseEntryWithPreferences{BuilderGetValidatorsResponseEntry: duty}
+		if duty.Entry != nil && duty.Entry.Message != nil {
+			entry.Preferences = common.ValidatorPreferences{
+				FeeRecipient: duty.Entry.Message.FeeRecipient.String(),
+				GasLimit:     duty.Entry.Message.GasLimit,
+			}
+		}
+		response = append(response, entry)
+	}
+	api.RespondOK(w, response)
 }
 
 func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Request) {
 	receivedAt := time.Now().UTC()
-	log := api.log.WithFields(logrus.Fields{
+	ctx := req.Context()
+	log := api.requestLogger(req).WithFields(logrus.Fields{
 		"method":        "submitNewBlock",
 		"contentLength": req.ContentLength,
 	})
@@ -1039,34 +1739,93 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	var err error
 	var r io.Reader = req.Body
 	if req.Header.Get("Content-Encoding") == "gzip" {
-		r, err = gzip.NewReader(req.Body)
+		gzipReader, err := common.GetGzipReader(req.Body)
 		if err != nil {
 			log.WithError(err).Warn("could not create gzip reader")
-			api.RespondError(w, http.StatusBadRequest, err.Error())
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
 			return
 		}
+		defer common.PutGzipReader(gzipReader)
+		r = api.limitDecompressedBody(w, req, gzipReader)
 		log = log.WithField("gzip-req", true)
 	}
 
+	// the body is only read until the payload is decoded, so its buffer is reused for later submissions
+	bodyBuf := common.GetBuffer()
+	defer common.PutBuffer(bodyBuf)
+	_, span := common.Tracer.Start(ctx, "readBody")
+	_, err = bodyBuf.ReadFrom(r)
+	span.End()
+	body := bodyBuf.Bytes()
+	if isBodyTooLarge(err) {
+		log.WithError(err).Warn("block submission too large")
+		api.RespondErrorWithCode(w, http.StatusRequestEntityTooLarge, ErrorCodeRequestTooLarge, "request body too large")
+		return
+	} else if err != nil {
+		log.WithError(err).Warn("could not read payload")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Enforce the API key of the builder and drop duplicates before decoding the full payload
+	preChecked := false
+	builderPubkeyStr, slot, ok := peekBuilderSubmission(body)
+	if api.requestCapture != nil {
+		api.requestCapture.capture(req, "submitBlock", body, slot, receivedAt)
+	}
+	if ok {
+		preChecked = true
+		if err := api.checkBuilderAPIKey(req, builderPubkeyStr); err != nil {
+			log.WithError(err).WithField("builderPubkey", builderPubkeyStr).Info("builder api key check failed")
+			api.respondBuilderAPIKeyError(w, err)
+			return
+		}
+		if api.isDuplicateSubmission(log, slot, builderPubkeyStr, body) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if api.builderSubmissionLimitReached(log, slot, builderPubkeyStr) {
+			api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeTooManySubmissions, "too many submissions for this slot")
+			return
+		}
+	}
+
 	payload := new(common.BuilderSubmitBlockRequest)
-	if err := json.NewDecoder(r).Decode(payload); err != nil {
+	_, span = common.Tracer.Start(ctx, "decode")
+	err = json.Unmarshal(body, payload)
+	span.End()
+	if err != nil {
 		log.WithError(err).Warn("could not decode payload")
-		api.RespondError(w, http.StatusBadRequest, err.Error())
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
 		return
 	}
 
 	if payload.Message() == nil || !payload.HasExecutionPayload() {
-		api.RespondError(w, http.StatusBadRequest, "missing parts of the payload")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing parts of the payload")
 		return
 	}
 
-	currentSlot := api.headSlot.Load()
-	if api.isCapella(currentSlot) && payload.Capella == nil {
+	if !preChecked {
+		if err := api.checkBuilderAPIKey(req, payload.BuilderPubkey().String()); err != nil {
+			log.WithError(err).WithField("builderPubkey", payload.BuilderPubkey().String()).Info("builder api key check failed")
+			api.respondBuilderAPIKeyError(w, err)
+			return
+		}
+	}
+
+	// the submission has to be of the fork of its slot
+	if api.isDeneb(payload.Slot()) && payload.Deneb == nil {
+		log.Info("rejecting submission - non deneb payload for deneb fork")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnsupportedFork, "not deneb payload")
+		return
+	} else if !api.isDeneb(payload.Slot()) && api.isCapella(payload.Slot()) && payload.Capella == nil && payload.Electra == nil {
 		log.Info("rejecting submission - non capella payload for capella fork")
-		api.RespondError(w, http.StatusBadRequest, "not capella payload")
-	} else if api.isBellatrix(currentSlot) && payload.Bellatrix == nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnsupportedFork, "not capella payload")
+		return
+	} else if api.isBellatrix(payload.Slot()) && payload.Bellatrix == nil {
 		log.Info("rejecting submission - non bellatrix payload for bellatrix fork")
-		api.RespondError(w, http.StatusBadRequest, "not belltrix payload")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnsupportedFork, "not belltrix payload")
+		return
 	}
 
 	log = log.WithFields(logrus.Fields{
@@ -1075,22 +1834,7 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 		"blockHash":     payload.BlockHash(),
 	})
 
-	// Reject new submissions once the payload for this slot was delivered
-	slotStr, err := api.redis.GetStats(datastore.RedisStatsFieldSlotLastPayloadDelivered)
-	if err != nil && !errors.Is(err, redis.Nil) {
-		log.WithError(err).Error("failed to get delivered payload slot from redis")
-	} else {
-		slotLastPayloadDelivered, err := strconv.ParseUint(slotStr, 10, 64)
-		if err != nil {
-			log.WithError(err).Errorf("failed to parse delivered payload slot from redis: %s", slotStr)
-		} else if payload.Slot() <= slotLastPayloadDelivered {
-			log.Info("rejecting submission because payload for this slot was already delivered")
-			api.RespondError(w, http.StatusBadRequest, "payload for this slot was already delivered")
-			return
-		}
-	}
-
-	builderIsHighPrio, builderIsBlacklisted, err := api.redis.GetBlockBuilderStatus(payload.BuilderPubkey().String())
+	builderIsHighPrio, builderIsBlacklisted, err := api.getBlockBuilderStatus(payload.BuilderPubkey().String())
 	log = log.WithFields(logrus.Fields{
 		"builderIsHighPrio":    builderIsHighPrio,
 		"builderIsBlacklisted": builderIsBlacklisted,
@@ -1099,14 +1843,6 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 		log.WithError(err).Error("could not get block builder status")
 	}
 
-	// Timestamp check
-	expectedTimestamp := api.genesisInfo.Data.GenesisTime + (payload.Slot() * 12)
-	if payload.Timestamp() != expectedTimestamp {
-		log.Warnf("incorrect timestamp. got %d, expected %d", payload.Timestamp(), expectedTimestamp)
-		api.RespondError(w, http.StatusBadRequest, fmt.Sprintf("incorrect timestamp. got %d, expected %d", payload.Timestamp(), expectedTimestamp))
-		return
-	}
-
 	// randao check 1:
 	// - querying the randao from the BN if payload has a newer slot (might be faster than headSlot event)
 	// - check for validity happens later, again after validation (to use some time for BN request to finish...)
@@ -1125,20 +1861,6 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	}
 	api.expectedWithdrawalsLock.RUnlock()
 
-	// ensure correct feeRecipient is used
-	api.proposerDutiesLock.RLock()
-	slotDuty := api.proposerDutiesMap[payload.Slot()]
-	api.proposerDutiesLock.RUnlock()
-	if slotDuty == nil {
-		log.Warn("could not find slot duty")
-		api.RespondError(w, http.StatusBadRequest, "could not find slot duty")
-		return
-	} else if slotDuty.FeeRecipient.String() != payload.ProposerFeeRecipient() {
-		log.Info("fee recipient does not match")
-		api.RespondError(w, http.StatusBadRequest, "fee recipient does not match")
-		return
-	}
-
 	if builderIsBlacklisted {
 		log.Info("builder is blacklisted")
 		time.Sleep(200 * time.Millisecond)
@@ -1147,7 +1869,7 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	}
 
 	// In case only high-prio requests are accepted, fail others
-	if api.ffDisableLowPrioBuilders && !builderIsHighPrio {
+	if api.ffDisableLowPrioBuilders.Load() && !builderIsHighPrio {
 		log.Info("rejecting low-prio builder (ff-disable-low-prio-builders)")
 		time.Sleep(200 * time.Millisecond)
 		w.WriteHeader(http.StatusOK)
@@ -1162,15 +1884,9 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 		"tx":              payload.NumTx(),
 	})
 
-	if payload.Slot() <= api.headSlot.Load() {
-		api.log.Info("submitNewBlock failed: submission for past slot")
-		api.RespondError(w, http.StatusBadRequest, "submission for past slot")
-		return
-	}
-
 	// Don't accept blocks with 0 value
 	if payload.Value().Cmp(ZeroU256.BigInt()) == 0 || payload.NumTx() == 0 {
-		api.log.Info("submitNewBlock failed: block with 0 value or no txs")
+		log.Info("submitNewBlock failed: block with 0 value or no txs")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -1179,102 +1895,140 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	err = SanityCheckBuilderBlockSubmission(payload)
 	if err != nil {
 		log.WithError(err).Info("block submission sanity checks failed")
-		api.RespondError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	// get the latest randao and check again, it might have updated in the meantime)
-	api.expectedPrevRandaoLock.RLock()
-	expectedRandao := api.expectedPrevRandao
-	api.expectedPrevRandaoLock.RUnlock()
-	if expectedRandao.slot != payload.Slot() { // we still don't have the prevrandao yet
-		log.Warn("prev_randao is not known yet")
-		api.RespondError(w, http.StatusInternalServerError, "prev_randao is not known yet")
-		return
-	} else if expectedRandao.prevRandao != payload.Random() {
-		msg := fmt.Sprintf("incorrect prev_randao - got: %s, expected: %s", payload.Random(), expectedRandao.prevRandao)
-		log.Info(msg)
-		api.RespondError(w, http.StatusBadRequest, msg)
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeSubmissionSanityFailed, err.Error())
 		return
 	}
 
-	withdrawals := payload.Withdrawals()
-	if withdrawals != nil {
-		// get latest withdrawals and verify the roots match
-		api.expectedWithdrawalsLock.RLock()
-		expectedWithdrawalsRoot := api.expectedWithdrawalsRoot
-		api.expectedWithdrawalsLock.RUnlock()
-		withdrawalsRoot, err := ComputeWithdrawalsRoot(payload.Withdrawals())
+	// Validate against the relay's view of the chain before simulating (randao and withdrawals are checked last,
+	// to give the BN requests above some time to finish)
+	filtered := &FilteredSubmission{
+		Log:               log,
+		Payload:           payload,
+		ReceivedAt:        receivedAt,
+		BuilderIsHighPrio: builderIsHighPrio,
+		preSim: &preSimSubmission{
+			slot:                 payload.Slot(),
+			parentHash:           payload.ParentHash(),
+			timestamp:            payload.Timestamp(),
+			prevRandao:           payload.Random(),
+			proposerFeeRecipient: payload.ProposerFeeRecipient(),
+		},
+	}
+	if withdrawals := payload.Withdrawals(); withdrawals != nil {
+		withdrawalsRoot, err := ComputeWithdrawalsRoot(withdrawals)
 		if err != nil {
 			log.WithError(err).Warn("could not compute withdrawals root from payload")
-			api.RespondError(w, http.StatusBadRequest, "could not compute withdrawals root")
-			return
-		}
-		if expectedWithdrawalsRoot.slot != payload.Slot() { // we still don't have the withdrawals yet
-			log.Warn("withdrawals are not known yet")
-			api.RespondError(w, http.StatusInternalServerError, "withdrawals are not known yet")
-			return
-		} else if expectedWithdrawalsRoot.root != withdrawalsRoot {
-			msg := fmt.Sprintf("incorrect withdrawals root - got: %s, expected: %s", withdrawalsRoot.String(), expectedWithdrawalsRoot.root.String())
-			log.Info(msg)
-			api.RespondError(w, http.StatusBadRequest, msg)
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "could not compute withdrawals root")
 			return
 		}
+		filtered.preSim.withdrawalsRoot = &withdrawalsRoot
+	}
+	if preSimErr := api.filterSubmissionPreSim(filtered); preSimErr != nil {
+		api.respondSubmissionError(w, preSimErr)
+		return
 	}
 
 	// Verify the signature
 	builderPubkey := payload.BuilderPubkey()
 	signature := payload.Signature()
-	ok, err := boostTypes.VerifySignature(payload.Message(), api.opts.EthNetDetails.DomainBuilder, builderPubkey[:], signature[:])
+	_, span = common.Tracer.Start(ctx, "verifySignature")
+	ok, err = api.verifyBuilderSignature(payload.Message(), builderPubkey[:], signature[:])
+	span.End()
 	if !ok || err != nil {
 		log.WithError(err).Warn("could not verify builder signature")
-		api.RespondError(w, http.StatusBadRequest, "invalid signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "invalid signature")
 		return
 	}
 
-	var simErr error
+	// Only submissions signed by the builder count towards its per-slot limit
+	if !api.allowBuilderSubmission(log, payload.Slot(), builderPubkey.String()) {
+		api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeTooManySubmissions, "too many submissions for this slot")
+		return
+	}
 
-	// At end of this function, save builder submission to database (in the background)
-	defer func() {
-		submissionEntry, err := api.db.SaveBuilderBlockSubmission(payload, simErr, receivedAt)
-		if err != nil {
-			log.WithError(err).WithField("payload", payload).Error("saving builder block submission to database failed")
-			return
-		}
+	if api.submissionMirror != nil {
+		api.submissionMirror.enqueue(log, req, body)
+	}
 
-		err = api.db.UpsertBlockBuilderEntryAfterSubmission(submissionEntry, simErr != nil)
-		if err != nil {
-			log.WithError(err).Error("failed to upsert block-builder-entry")
-		}
-	}()
+	// Apply the policies of the submission filters before spending a simulation on the block
+	if filterErr := api.filterSubmissionBeforeSimulation(ctx, filtered); filterErr != nil {
+		api.respondSubmissionError(w, filterErr)
+		return
+	}
+
+	// Optimistic mode: blocks of collateralized high-prio builders are accepted before the simulation completes
+	isOptimistic := api.ffEnableOptimistic.Load() && builderIsHighPrio && api.isCoveredByCollateral(log, builderPubkey.String(), payload.Value())
+	log = log.WithField("optimistic", isOptimistic)
+	filtered.Optimistic = isOptimistic
 
-	// Simulate the block submission and save to db
-	t := time.Now()
 	validationRequestPayload := &BuilderBlockValidationRequest{
 		BuilderSubmitBlockRequest: *payload,
-		RegisteredGasLimit:        slotDuty.GasLimit,
+		RegisteredGasLimit:        filtered.SlotDuty.GasLimit,
 	}
-	simErr = api.blockSimRateLimiter.send(req.Context(), validationRequestPayload, builderIsHighPrio)
-
-	if simErr != nil {
-		log = log.WithField("simErr", simErr.Error())
-		log.WithError(simErr).WithFields(logrus.Fields{
-			"duration":   time.Since(t).Seconds(),
-			"numWaiting": api.blockSimRateLimiter.currentCounter(),
-		}).Info("block validation failed")
-
-		if os.IsTimeout(simErr) {
-			api.RespondError(w, http.StatusGatewayTimeout, "validation request timeout")
+	if payload.Deneb != nil && !api.ffLoadTestMode {
+		parentBeaconBlockRoot, rootErr := api.expectedParentBeaconBlockRoot(payload.Slot())
+		if rootErr != nil {
+			log.WithField("errorCode", rootErr.code).Info(rootErr.msg)
+			api.respondSubmissionError(w, rootErr)
 			return
 		}
+		validationRequestPayload.ParentBeaconBlockRoot = parentBeaconBlockRoot
+	}
 
-		api.RespondError(w, http.StatusBadRequest, simErr.Error())
-		return
+	if isOptimistic {
+		// Simulate in the background, the builder gets demoted if it fails
+		api.optimisticBlocksInFlight.Add(1)
+		go api.simulateOptimisticBlock(common.DetachTraceContext(ctx), filtered, validationRequestPayload)
 	} else {
-		log.WithFields(logrus.Fields{
-			"duration":   time.Since(t).Seconds(),
-			"numWaiting": api.blockSimRateLimiter.currentCounter(),
-		}).Info("block validation successful")
+		var simResult *BlockSimulationResult
+		var simErr error
+
+		// At end of this function, save builder submission to database
+		defer func() {
+			api.saveBlockSubmission(ctx, log, payload, simResult, simErr, receivedAt, false)
+		}()
+
+		// Simulate the block submission
+		t := time.Now()
+		var simCached bool
+		simResult, simCached, simErr = api.simulateBlock(ctx, log, validationRequestPayload, builderIsHighPrio, true)
+		log = log.WithField("simCached", simCached)
+
+		// the rejections of the filters are saved as the result of the submission
+		if simErr == nil {
+			if filterErr := api.filterSubmissionAfterSimulation(ctx, filtered, simResult); filterErr != nil {
+				simErr = filterErr
+				api.respondSubmissionError(w, filterErr)
+				return
+			}
+		}
+
+		if simErr != nil {
+			log = log.WithField("simErr", simErr.Error())
+			log.WithError(simErr).WithFields(logrus.Fields{
+				"duration":   time.Since(t).Seconds(),
+				"numWaiting": api.blockSimQueue.currentCounter(),
+			}).Info("block validation failed")
+
+			if errors.Is(simErr, ErrSimQueueFull) {
+				w.Header().Set("Retry-After", strconv.Itoa(simQueueRetryAfter))
+				api.RespondErrorWithCode(w, http.StatusServiceUnavailable, SubmissionErrSimulationQueueFull, simErr.Error())
+				return
+			}
+
+			if os.IsTimeout(simErr) {
+				api.RespondErrorWithCode(w, http.StatusGatewayTimeout, SubmissionErrSimulationTimeout, "validation request timeout")
+				return
+			}
+
+			api.RespondErrorWithCode(w, http.StatusBadRequest, SubmissionErrSimulationFailed, simErr.Error())
+			return
+		} else {
+			log.WithFields(logrus.Fields{
+				"duration":   time.Since(t).Seconds(),
+				"numWaiting": api.blockSimQueue.currentCounter(),
+			}).Info("block validation successful")
+		}
 	}
 
 	// Ensure this request is still the latest one
@@ -1283,7 +2037,7 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 		log.WithError(err).Error("failed getting latest payload receivedAt from redis")
 	} else if receivedAt.UnixMilli() < latestPayloadReceivedAt {
 		log.Infof("already have a newer payload: now=%d / prev=%d", receivedAt.UnixMilli(), latestPayloadReceivedAt)
-		api.RespondError(w, http.StatusBadRequest, "already using a newer payload")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeNewerSubmission, "already using a newer payload")
 		return
 	}
 
@@ -1291,14 +2045,14 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	getHeaderResponse, err := BuildGetHeaderResponse(payload, api.blsSk, api.publicKey, api.opts.EthNetDetails.DomainBuilder)
 	if err != nil {
 		log.WithError(err).Error("could not sign builder bid")
-		api.RespondError(w, http.StatusBadRequest, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeBidBuildFailed, "could not sign the builder bid")
 		return
 	}
 
 	getPayloadResponse, err := BuildGetPayloadResponse(payload)
 	if err != nil {
 		log.WithError(err).Error("could not build getPayload response")
-		api.RespondError(w, http.StatusBadRequest, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeBidBuildFailed, "could not build the getPayload response")
 		return
 	}
 
@@ -1311,19 +2065,22 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	//
 	// Save to Redis
 	//
+	_, span = common.Tracer.Start(ctx, "saveBid")
+	defer span.End()
+
 	// first the trace
 	err = api.redis.SaveBidTrace(&bidTrace)
 	if err != nil {
 		log.WithError(err).Error("failed saving bidTrace in redis")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save the bid trace")
 		return
 	}
 
 	// save execution payload (getPayload response)
-	err = api.redis.SaveExecutionPayload(payload.Slot(), payload.ProposerPubkey(), payload.BlockHash(), getPayloadResponse)
+	err = api.datastore.SaveExecutionPayload(payload.Slot(), payload.ProposerPubkey(), payload.BuilderPubkey().String(), payload.BlockHash(), getPayloadResponse)
 	if err != nil {
 		log.WithError(err).Error("failed saving execution payload in redis")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not save the execution payload")
 		return
 	}
 
@@ -1331,17 +2088,32 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	err = api.redis.SaveLatestBuilderBid(payload.Slot(), payload.BuilderPubkey().String(), payload.ParentHash(), payload.ProposerPubkey(), receivedAt, getHeaderResponse)
 	if err != nil {
 		log.WithError(err).Error("could not save latest builder bid")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save the latest builder bid")
 		return
 	}
 
 	// recalculate top bid
-	err = api.redis.UpdateTopBid(payload.Slot(), payload.ParentHash(), payload.ProposerPubkey())
+	topBid, err := api.redis.UpdateTopBid(payload.Slot(), payload.ParentHash(), payload.ProposerPubkey())
 	if err != nil {
 		log.WithError(err).Error("could not compute top bid")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not update the top bid")
 		return
 	}
+	api.publishEvent(common.EventNewTopBid, topBid)
+	api.recordTopBid(log, topBid)
+	api.publishEvent(common.EventSubmissionAccepted, &bidTrace)
+	api.slots.bidAccepted(log, &bidTrace)
+
+	// retries of this submission are answered right away
+	err = api.redis.SaveAcceptedSubmission(payload.Slot(), payload.BuilderPubkey().String(), payload.BlockHash(), payload.Value().String())
+	if err != nil {
+		log.WithError(err).Error("could not save accepted submission")
+	}
+
+	// only bids which passed the simulation are shared with the peer relays
+	if api.ffForwardBidsToPeers && !isOptimistic && !api.ffShadowMode {
+		api.forwardBidToPeers(log, payload, getHeaderResponse, receivedAt)
+	}
 
 	//
 	// all done
@@ -1356,6 +2128,67 @@ func (api *RelayAPI) handleSubmitNewBlock(w http.ResponseWriter, req *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// saveBlockSubmission queues the builder submission along with the simulation result, to be saved by the submission
+// writers without holding up the request
+func (api *RelayAPI) saveBlockSubmission(ctx context.Context, log *logrus.Entry, payload *common.BuilderSubmitBlockRequest, simResult *BlockSimulationResult, simErr error, receivedAt time.Time, optimistic bool) {
+	_, span := common.Tracer.Start(ctx, "saveSubmission")
+	defer span.End()
+
+	api.blockSubmissionWriter.enqueue(&blockSubmissionWrite{
+		log:        log,
+		payload:    payload,
+		simResult:  simResult,
+		simErr:     simErr,
+		receivedAt: receivedAt,
+		optimistic: optimistic,
+	})
+}
+
+// isFilterRejection returns whether the submission was rejected by a policy rather than by the simulation. Submissions
+// below the minimum bid weren't simulated, and the ones rejected by a filter after the simulation were valid, so they
+// don't count as simulation errors of the builder.
+func isFilterRejection(simErr error) bool {
+	var filterErr *submissionError
+	return errors.Is(simErr, ErrBidBelowMinimum) || errors.As(simErr, &filterErr)
+}
+
+// writeBlockSubmission saves the builder submission to the database, and updates the builder stats
+func (api *RelayAPI) writeBlockSubmission(write *blockSubmissionWrite) {
+	log, payload, simErr := write.log, write.payload, write.simErr
+
+	verifiedValue := ""
+	if write.simResult != nil {
+		verifiedValue = write.simResult.ProposerPayment
+	}
+
+	submissionEntry, err := api.db.SaveBuilderBlockSubmission(payload, simErr, write.receivedAt, verifiedValue, write.optimistic)
+	if err != nil {
+		log.WithError(err).WithField("payload", payload).Error("saving builder block submission to database failed")
+		return
+	}
+
+	// a lower bid of the builder cancels its previous, higher bids
+	if simErr == nil {
+		if err := api.db.MarkCancelledBuilderSubmissions(submissionEntry); err != nil {
+			log.WithError(err).Error("failed to mark cancelled builder submissions")
+		}
+	}
+
+	if isFilterRejection(simErr) {
+		return
+	}
+
+	err = api.db.UpsertBlockBuilderEntryAfterSubmission(submissionEntry, simErr != nil)
+	if err != nil {
+		log.WithError(err).Error("failed to upsert block-builder-entry")
+	}
+
+	// subscribers of the data stream only get the submissions as they are received
+	if simErr == nil && !write.replayed {
+		api.publishDataStreamEvent(log, datastore.DataStreamEventBuilderBlockReceived, database.BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(submissionEntry))
+	}
+}
+
 // ---------------
 //  INTERNAL APIS
 // ---------------
@@ -1368,12 +2201,12 @@ func (api *RelayAPI) handleInternalBuilderStatus(w http.ResponseWriter, req *htt
 		builderEntry, err := api.db.GetBlockBuilderByPubkey(builderPubkey)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				api.RespondError(w, http.StatusBadRequest, "builder not found")
+				api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnknownBuilder, "builder not found")
 				return
 			}
 
 			api.log.WithError(err).Error("could not get block builder")
-			api.RespondError(w, http.StatusInternalServerError, err.Error())
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get block builder")
 			return
 		}
 
@@ -1383,27 +2216,106 @@ func (api *RelayAPI) handleInternalBuilderStatus(w http.ResponseWriter, req *htt
 		args := req.URL.Query()
 		isHighPrio := args.Get("high_prio") == "true"
 		isBlacklisted := args.Get("blacklisted") == "true"
-		api.log.WithFields(logrus.Fields{
-			"builderPubkey": builderPubkey,
-			"isHighPrio":    isHighPrio,
-			"isBlacklisted": isBlacklisted,
-		}).Info("updating builder status")
-
-		newStatus := datastore.MakeBlockBuilderStatus(isHighPrio, isBlacklisted)
-		err := api.redis.SetBlockBuilderStatus(builderPubkey, newStatus)
+		oldStatus, _ := api.builderAuditValues(builderPubkey)
+		newStatus, err := api.setBuilderStatus(builderPubkey, isHighPrio, isBlacklisted)
 		if err != nil {
-			api.log.WithError(err).Error("could not set block builder status in redis")
-		}
-
-		err = api.db.SetBlockBuilderStatus(builderPubkey, isHighPrio, isBlacklisted)
-		if err != nil {
-			api.log.WithError(err).Error("could not set block builder status in database")
+			api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not set builder status")
+			return
 		}
+		api.auditAdminChange(req, database.AdminAuditActionBuilderStatus, builderPubkey, oldStatus, builderStatusAuditValue(newStatus))
 
 		api.RespondOK(w, struct{ newStatus string }{newStatus: string(newStatus)})
 	}
 }
 
+// setBuilderStatus sets the status of a builder in the database, and in redis which pushes it to all instances
+func (api *RelayAPI) setBuilderStatus(builderPubkey string, isHighPrio, isBlacklisted bool) (datastore.BlockBuilderStatus, error) {
+	log := api.log.WithFields(logrus.Fields{
+		"builderPubkey": builderPubkey,
+		"isHighPrio":    isHighPrio,
+		"isBlacklisted": isBlacklisted,
+	})
+	log.Info("updating builder status")
+
+	newStatus := datastore.MakeBlockBuilderStatus(isHighPrio, isBlacklisted)
+	err := api.db.SetBlockBuilderStatus(builderPubkey, isHighPrio, isBlacklisted)
+	if err != nil {
+		log.WithError(err).Error("could not set block builder status in database")
+		return newStatus, err
+	}
+
+	err = api.redis.SetBlockBuilderStatus(builderPubkey, newStatus)
+	if err != nil {
+		log.WithError(err).Error("could not set block builder status in redis")
+		return newStatus, err
+	}
+	return newStatus, nil
+}
+
+// handleInternalBuilderCollateral sets the collateral of a builder. A collateral above zero enables optimistic mode, zero disables it.
+func (api *RelayAPI) handleInternalBuilderCollateral(w http.ResponseWriter, req *http.Request) {
+	api.updateBuilderCollateral(w, req, mux.Vars(req)["pubkey"], req.URL.Query().Get("collateral"))
+}
+
+// updateBuilderCollateral sets the collateral (in wei) of a builder, audits the change and responds with the new
+// collateral. Shared by the internal and the admin API, which take the collateral from the query and the body.
+func (api *RelayAPI) updateBuilderCollateral(w http.ResponseWriter, req *http.Request, builderPubkey, collateralStr string) {
+	collateral, ok := new(big.Int).SetString(collateralStr, 10)
+	if !ok || collateral.Sign() < 0 {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "invalid collateral")
+		return
+	}
+
+	_, oldCollateral := api.builderAuditValues(builderPubkey)
+	resp, err := api.setBuilderCollateral(builderPubkey, collateral)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeUnknownBuilder, "builder not found")
+		return
+	} else if err != nil {
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatastore, "could not set builder collateral")
+		return
+	}
+	api.auditAdminChange(req, database.AdminAuditActionBuilderCollateral, builderPubkey, oldCollateral, resp.Collateral)
+	api.RespondOK(w, resp)
+}
+
+type BuilderCollateralResponse struct {
+	Collateral   string `json:"collateral"`
+	IsOptimistic bool   `json:"is_optimistic"`
+}
+
+// setBuilderCollateral sets the collateral of a builder in the database and redis. A collateral above zero enables
+// optimistic mode, zero disables it. Unknown builders are left out of redis, with sql.ErrNoRows.
+func (api *RelayAPI) setBuilderCollateral(builderPubkey string, collateral *big.Int) (*BuilderCollateralResponse, error) {
+	isOptimistic := collateral.Sign() > 0
+	log := api.log.WithFields(logrus.Fields{
+		"builderPubkey": builderPubkey,
+		"collateral":    collateral.String(),
+		"isOptimistic":  isOptimistic,
+	})
+	log.Info("updating builder collateral")
+
+	err := api.db.SetBlockBuilderCollateral(builderPubkey, collateral.String(), isOptimistic)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Warn("cannot set the collateral of an unknown builder")
+		return nil, err
+	} else if err != nil {
+		log.WithError(err).Error("could not set block builder collateral in database")
+		return nil, err
+	}
+
+	if isOptimistic {
+		err = api.redis.SetBlockBuilderCollateral(builderPubkey, collateral.String())
+	} else {
+		err = api.redis.DeleteBlockBuilderCollateral(builderPubkey)
+	}
+	if err != nil {
+		log.WithError(err).Error("could not set block builder collateral in redis")
+	}
+
+	return &BuilderCollateralResponse{Collateral: collateral.String(), IsOptimistic: isOptimistic}, nil
+}
+
 // -----------
 //  DATA APIS
 // -----------
@@ -1416,19 +2328,29 @@ func (api *RelayAPI) handleDataProposerPayloadDelivered(w http.ResponseWriter, r
 		Limit: 200,
 	}
 
-	if args.Get("slot") != "" && args.Get("cursor") != "" {
-		api.RespondError(w, http.StatusBadRequest, "cannot specify both slot and cursor")
-		return
-	} else if args.Get("slot") != "" {
-		filters.Slot, err = strconv.ParseUint(args.Get("slot"), 10, 64)
-		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid slot argument")
-			return
+	// numeric cursors are slots (legacy), anything else is an opaque page cursor from a paginated response
+	paginate := args.Get("paginate") == "true"
+	if args.Get("cursor") != "" {
+		if slotCursor, err := strconv.ParseUint(args.Get("cursor"), 10, 64); err == nil {
+			if args.Get("slot") != "" {
+				api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "cannot specify both slot and cursor")
+				return
+			}
+			filters.Cursor = slotCursor
+		} else {
+			filters.PageCursor, err = decodePageCursor(args.Get("cursor"))
+			if err != nil {
+				api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid cursor argument")
+				return
+			}
+			paginate = true
 		}
-	} else if args.Get("cursor") != "" {
-		filters.Cursor, err = strconv.ParseUint(args.Get("cursor"), 10, 64)
+	}
+
+	if args.Get("slot") != "" {
+		filters.Slot, err = strconv.ParseUint(args.Get("slot"), 10, 64)
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid cursor argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid slot argument")
 			return
 		}
 	}
@@ -1437,7 +2359,7 @@ func (api *RelayAPI) handleDataProposerPayloadDelivered(w http.ResponseWriter, r
 		var hash boostTypes.Hash
 		err = hash.UnmarshalText([]byte(args.Get("block_hash")))
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid block_hash argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid block_hash argument")
 			return
 		}
 		filters.BlockHash = args.Get("block_hash")
@@ -1446,14 +2368,14 @@ func (api *RelayAPI) handleDataProposerPayloadDelivered(w http.ResponseWriter, r
 	if args.Get("block_number") != "" {
 		filters.BlockNumber, err = strconv.ParseUint(args.Get("block_number"), 10, 64)
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid block_number argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid block_number argument")
 			return
 		}
 	}
 
 	if args.Get("proposer_pubkey") != "" {
 		if err = checkBLSPublicKeyHex(args.Get("proposer_pubkey")); err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid proposer_pubkey argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid proposer_pubkey argument")
 			return
 		}
 		filters.ProposerPubkey = args.Get("proposer_pubkey")
@@ -1461,7 +2383,7 @@ func (api *RelayAPI) handleDataProposerPayloadDelivered(w http.ResponseWriter, r
 
 	if args.Get("builder_pubkey") != "" {
 		if err = checkBLSPublicKeyHex(args.Get("builder_pubkey")); err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid builder_pubkey argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid builder_pubkey argument")
 			return
 		}
 		filters.BuilderPubkey = args.Get("builder_pubkey")
@@ -1470,11 +2392,11 @@ func (api *RelayAPI) handleDataProposerPayloadDelivered(w http.ResponseWriter, r
 	if args.Get("limit") != "" {
 		_limit, err := strconv.ParseUint(args.Get("limit"), 10, 64)
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid limit argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid limit argument")
 			return
 		}
 		if _limit > filters.Limit {
-			api.RespondError(w, http.StatusBadRequest, fmt.Sprintf("maximum limit is %d", filters.Limit))
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, fmt.Sprintf("maximum limit is %d", filters.Limit))
 			return
 		}
 		filters.Limit = _limit
@@ -1486,16 +2408,37 @@ func (api *RelayAPI) handleDataProposerPayloadDelivered(w http.ResponseWriter, r
 		filters.OrderByValue = -1
 	}
 
+	filters.RangeFilters, err = parseRangeFilters(args)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, err.Error())
+		return
+	}
+
+	if paginate && filters.OrderByValue != 0 {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "order_by is not supported for paginated requests")
+		return
+	}
+
 	deliveredPayloads, err := api.db.GetRecentDeliveredPayloads(filters)
 	if err != nil {
 		api.log.WithError(err).Error("error getting recent payloads")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get delivered payloads")
 		return
 	}
 
-	response := make([]common.BidTraceV2JSON, len(deliveredPayloads))
+	response := make([]common.BidTraceV2WithTimingJSON, len(deliveredPayloads))
 	for i, payload := range deliveredPayloads {
-		response[i] = database.DeliveredPayloadEntryToBidTraceV2JSON(payload)
+		response[i] = database.DeliveredPayloadEntryToBidTraceV2WithTimingJSON(payload)
+	}
+
+	if paginate {
+		nextCursor := ""
+		if len(deliveredPayloads) > 0 {
+			last := deliveredPayloads[len(deliveredPayloads)-1]
+			nextCursor = nextPageCursor(len(deliveredPayloads), filters.Limit, last.Slot, last.ID)
+		}
+		api.RespondOK(w, DataPageResponse{Data: response, NextCursor: nextCursor})
+		return
 	}
 
 	api.RespondOK(w, response)
@@ -1513,15 +2456,21 @@ func (api *RelayAPI) handleDataBuilderBidsReceived(w http.ResponseWriter, req *h
 		BuilderPubkey: "",
 	}
 
+	// only opaque page cursors are supported
+	filters.Paginated = args.Get("paginate") == "true"
 	if args.Get("cursor") != "" {
-		api.RespondError(w, http.StatusBadRequest, "cursor argument not supported")
-		return
+		filters.PageCursor, err = decodePageCursor(args.Get("cursor"))
+		if err != nil {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid cursor argument")
+			return
+		}
+		filters.Paginated = true
 	}
 
 	if args.Get("slot") != "" {
 		filters.Slot, err = strconv.ParseUint(args.Get("slot"), 10, 64)
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid slot argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid slot argument")
 			return
 		}
 	}
@@ -1530,7 +2479,7 @@ func (api *RelayAPI) handleDataBuilderBidsReceived(w http.ResponseWriter, req *h
 		var hash boostTypes.Hash
 		err = hash.UnmarshalText([]byte(args.Get("block_hash")))
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid block_hash argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid block_hash argument")
 			return
 		}
 		filters.BlockHash = args.Get("block_hash")
@@ -1539,33 +2488,48 @@ func (api *RelayAPI) handleDataBuilderBidsReceived(w http.ResponseWriter, req *h
 	if args.Get("block_number") != "" {
 		filters.BlockNumber, err = strconv.ParseUint(args.Get("block_number"), 10, 64)
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid block_number argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid block_number argument")
 			return
 		}
 	}
 
 	if args.Get("builder_pubkey") != "" {
 		if err = checkBLSPublicKeyHex(args.Get("builder_pubkey")); err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid builder_pubkey argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid builder_pubkey argument")
 			return
 		}
 		filters.BuilderPubkey = args.Get("builder_pubkey")
 	}
 
+	if args.Get("include_cancelled") != "" {
+		includeCancelled, err := strconv.ParseBool(args.Get("include_cancelled"))
+		if err != nil {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid include_cancelled argument")
+			return
+		}
+		filters.ExcludeCancelled = !includeCancelled
+	}
+
+	filters.RangeFilters, err = parseRangeFilters(args)
+	if err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, err.Error())
+		return
+	}
+
 	// at least one query arguments is required
-	if filters.Slot == 0 && filters.BlockHash == "" && filters.BlockNumber == 0 && filters.BuilderPubkey == "" {
-		api.RespondError(w, http.StatusBadRequest, "need to query for specific slot or block_hash or block_number or builder_pubkey")
+	if filters.Slot == 0 && filters.BlockHash == "" && filters.BlockNumber == 0 && filters.BuilderPubkey == "" && filters.FromTime.IsZero() {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "need to query for specific slot or block_hash or block_number or builder_pubkey or from_timestamp")
 		return
 	}
 
 	if args.Get("limit") != "" {
 		_limit, err := strconv.ParseUint(args.Get("limit"), 10, 64)
 		if err != nil {
-			api.RespondError(w, http.StatusBadRequest, "invalid limit argument")
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid limit argument")
 			return
 		}
 		if _limit > filters.Limit {
-			api.RespondError(w, http.StatusBadRequest, fmt.Sprintf("maximum limit is %d", filters.Limit))
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, fmt.Sprintf("maximum limit is %d", filters.Limit))
 			return
 		}
 		filters.Limit = _limit
@@ -1574,7 +2538,7 @@ func (api *RelayAPI) handleDataBuilderBidsReceived(w http.ResponseWriter, req *h
 	blockSubmissions, err := api.db.GetBuilderSubmissions(filters)
 	if err != nil {
 		api.log.WithError(err).Error("error getting recent payloads")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get block submissions")
 		return
 	}
 
@@ -1583,38 +2547,57 @@ func (api *RelayAPI) handleDataBuilderBidsReceived(w http.ResponseWriter, req *h
 		response[i] = database.BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(payload)
 	}
 
+	if filters.Paginated {
+		nextCursor := ""
+		if len(blockSubmissions) > 0 {
+			last := blockSubmissions[len(blockSubmissions)-1]
+			nextCursor = nextPageCursor(len(blockSubmissions), filters.Limit, last.Slot, last.ID)
+		}
+		api.RespondOK(w, DataPageResponse{Data: response, NextCursor: nextCursor})
+		return
+	}
+
 	api.RespondOK(w, response)
 }
 
 func (api *RelayAPI) handleDataValidatorRegistration(w http.ResponseWriter, req *http.Request) {
 	pkStr := req.URL.Query().Get("pubkey")
 	if pkStr == "" {
-		api.RespondError(w, http.StatusBadRequest, "missing pubkey argument")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "missing pubkey argument")
 		return
 	}
 
 	var pk boostTypes.PublicKey
 	err := pk.UnmarshalText([]byte(pkStr))
 	if err != nil {
-		api.RespondError(w, http.StatusBadRequest, "invalid pubkey")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidArgument, "invalid pubkey")
 		return
 	}
 
 	registrationEntry, err := api.db.GetValidatorRegistration(pkStr)
 	if err != nil {
+		// the registrations of the upcoming proposers in memory can be older than a re-registration, so they are only
+		// served if the database doesn't have the registration (yet) or fails
+		if registration := api.proposerDuties.registrationForPubkey(pkStr); registration != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				api.log.WithError(err).Warn("could not get validator registration, serving the one of the proposer duties")
+			}
+			api.RespondOK(w, registration)
+			return
+		}
 		if errors.Is(err, sql.ErrNoRows) {
-			api.RespondError(w, http.StatusBadRequest, "no registration found for validator "+pkStr)
+			api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeNoRegistration, "no registration found for validator "+pkStr)
 			return
 		}
 		api.log.WithError(err).Error("error getting validator registration")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeDatabase, "could not get validator registration")
 		return
 	}
 
 	signedRegistration, err := registrationEntry.ToSignedValidatorRegistration()
 	if err != nil {
 		api.log.WithError(err).Error("error converting registration entry to signed validator registration")
-		api.RespondError(w, http.StatusInternalServerError, err.Error())
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeInternal, "could not convert the validator registration")
 		return
 	}
 
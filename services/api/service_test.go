@@ -2,19 +2,33 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	buildercapella "github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/api/v1/capella"
+	"github.com/attestantio/go-eth2-client/spec/altair"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/beaconclient"
 	"github.com/flashbots/mev-boost-relay/common"
 	"github.com/flashbots/mev-boost-relay/database"
 	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/gorilla/mux"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -146,6 +160,18 @@ func TestWebserverRootHandler(t *testing.T) {
 	require.Equal(t, http.StatusOK, rr.Code)
 }
 
+func TestRequestBodyLimits(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.opts.HTTPServer.MaxBodyBytesSubmissions = 100
+
+	rr := backend.request(http.MethodPost, pathSubmitNewBlock, strings.Repeat("a", 200))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	// the limits are per endpoint
+	require.Equal(t, DefaultMaxBodyBytesRegistrations, backend.relay.opts.HTTPServer.maxBodyBytes(pathRegisterValidator))
+	require.Equal(t, DefaultMaxBodyBytes, backend.relay.opts.HTTPServer.maxBodyBytes(pathGetPayload))
+}
+
 func TestStatus(t *testing.T) {
 	backend := newTestBackend(t, 1)
 	path := "/eth/v1/builder/status"
@@ -153,6 +179,302 @@ func TestStatus(t *testing.T) {
 	require.Equal(t, http.StatusOK, rr.Code)
 }
 
+func TestLivezReadyz(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	beaconInstance := beaconclient.NewMockBeaconInstance()
+	backend.relay.beaconClient = beaconclient.NewMultiBeaconClient(common.TestLog, []beaconclient.IBeaconInstance{beaconInstance})
+	backend.relay.blockSimQueue = NewBlockSimulationQueue("", newBlockSimNodePool(common.TestLog, []string{"http://localhost:8545"}, ""))
+
+	rr := backend.request(http.MethodGet, pathLivez, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	readyz := func() (int, ReadinessResponse) {
+		rr := backend.request(http.MethodGet, pathReadyz, nil)
+		resp := ReadinessResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return rr.Code, resp
+	}
+
+	t.Run("ready if all dependencies are available", func(t *testing.T) {
+		code, resp := readyz()
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, resp.Ready)
+		for _, name := range []string{"beacon", "redis", "database", "blocksim"} {
+			require.True(t, resp.Dependencies[name].OK, name)
+		}
+	})
+
+	t.Run("not ready if the beacon node is syncing", func(t *testing.T) {
+		beaconInstance.MockSyncStatus = &beaconclient.SyncStatusPayloadData{HeadSlot: 1, IsSyncing: true}
+		defer func() {
+			beaconInstance.MockSyncStatus = &beaconclient.SyncStatusPayloadData{HeadSlot: 1, IsSyncing: false}
+		}()
+
+		code, resp := readyz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.False(t, resp.Ready)
+		require.False(t, resp.Dependencies["beacon"].OK)
+		require.True(t, resp.Dependencies["redis"].OK)
+	})
+
+	t.Run("not ready if no simulation node is healthy", func(t *testing.T) {
+		node := backend.relay.blockSimQueue.nodes.nodes[0]
+		node.isHealthy.Store(false)
+		defer node.isHealthy.Store(true)
+
+		code, resp := readyz()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.False(t, resp.Dependencies["blocksim"].OK)
+		require.Equal(t, ErrNoHealthyBlockSim.Error(), resp.Dependencies["blocksim"].Error)
+	})
+}
+
+func TestShuttingDown(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.isShuttingDown.Store(true)
+
+	// builders are sent to another instance
+	rr := backend.request(http.MethodPost, pathSubmitNewBlock, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	// and the load balancer stops sending requests, while the instance is still alive
+	rr = backend.request(http.MethodGet, pathReadyz, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	rr = backend.request(http.MethodGet, pathLivez, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestErrorCodes(t *testing.T) {
+	backend := newTestBackend(t, 1)
+
+	decode := func(rr *httptest.ResponseRecorder) HTTPErrorResp {
+		resp := HTTPErrorResp{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, rr.Code, resp.Code)
+		return resp
+	}
+
+	// a specific code
+	rr := backend.request(http.MethodGet, pathDataProposerPayloadDelivered+"?slot=abc", nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, ErrorCodeInvalidArgument, decode(rr).ErrorCode)
+
+	// failed dependencies have their code, without the error
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	backend.relay.redis, err = datastore.NewRedisCache(redisServer.Addr(), "")
+	require.NoError(t, err)
+	redisServer.Close()
+	rr = backend.request(http.MethodGet, "/eth/v1/builder/header/1/0x"+strings.Repeat("01", 32)+"/0x"+strings.Repeat("02", 48), nil)
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	resp := decode(rr)
+	require.Equal(t, ErrorCodeRedis, resp.ErrorCode)
+	require.Equal(t, "could not get bid", resp.Message)
+
+	// the generic code of the status otherwise
+	rr = httptest.NewRecorder()
+	backend.relay.RespondError(rr, http.StatusInternalServerError, "oops")
+	require.Equal(t, ErrorCodeInternal, decode(rr).ErrorCode)
+
+	require.Equal(t, ErrorCodeBadRequest, errorCodeForStatus(http.StatusBadRequest))
+	require.Equal(t, ErrorCodeRateLimited, errorCodeForStatus(http.StatusTooManyRequests))
+	require.Equal(t, ErrorCodeUnavailable, errorCodeForStatus(http.StatusServiceUnavailable))
+	require.Equal(t, ErrorCodeValidatorNotServed, registrationErrorCode(fmt.Errorf("%w: 0x01", ErrValidatorNotServed)))
+	require.Equal(t, ErrorCodeHeaderBidMismatch, headerBidErrorCode(ErrHeaderBidGasMismatch))
+}
+
+func TestShadowMode(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.ffShadowMode = true
+	sk, pubkey, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	proposerPubkey, err := types.BlsPublicKeyToPublicKey(pubkey)
+	require.NoError(t, err)
+	require.NoError(t, backend.redis.SetKnownValidator(types.NewPubkeyHex(proposerPubkey.String()), 1))
+	_, err = backend.datastore.RefreshKnownValidators()
+	require.NoError(t, err)
+
+	rr := backend.request(http.MethodGet, "/", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "true", rr.Header().Get(HeaderShadowMode))
+
+	// requests not signed by the proposer are rejected before anything else
+	payload := &types.SignedBlindedBeaconBlock{
+		Message: &types.BlindedBeaconBlock{
+			Slot:          1,
+			ProposerIndex: 1,
+			Body: &types.BlindedBeaconBlockBody{
+				Eth1Data:               &types.Eth1Data{},
+				SyncAggregate:          &types.SyncAggregate{},
+				ExecutionPayloadHeader: &types.ExecutionPayloadHeader{},
+			},
+		},
+	}
+	rr = backend.request(http.MethodPost, pathGetPayload, payload)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), ErrorCodeInvalidSignature)
+
+	// payloads are never delivered
+	sig, err := types.SignMessage(payload.Message, backend.relay.opts.EthNetDetails.DomainBeaconProposerBellatrix, sk)
+	require.NoError(t, err)
+	payload.Signature = sig
+	rr = backend.request(http.MethodPost, pathGetPayload, payload)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), ErrShadowMode.Error())
+	require.Equal(t, "true", rr.Header().Get(HeaderShadowMode))
+}
+
+func TestDenebForkGating(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.denebEpoch = 2
+	require.False(t, backend.relay.isDeneb(63))
+	require.True(t, backend.relay.isDeneb(64))
+
+	// submissions have to be of the fork of their slot
+	submission := &buildercapella.SubmitBlockRequest{
+		Message: &apiv1.BidTrace{Slot: 64, Value: uint256.NewInt(1)},
+		ExecutionPayload: &consensuscapella.ExecutionPayload{
+			ExtraData:    []byte{},
+			Transactions: []bellatrix.Transaction{},
+			Withdrawals:  []*consensuscapella.Withdrawal{},
+		},
+	}
+	rr := backend.request(http.MethodPost, pathSubmitNewBlock, submission)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "not deneb payload")
+}
+
+func TestForkTransition(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.opts.EthNetDetails.CapellaForkVersionHex = "0x03000000"
+	backend.relay.opts.EthNetDetails.DenebForkVersionHex = "0x04000000"
+	beaconInstance := beaconclient.NewMockBeaconInstance()
+	beaconInstance.SetForks(
+		beaconclient.MockFork{Name: beaconclient.MockForkBellatrix, Version: "0x00000000", Epoch: 0},
+		beaconclient.MockFork{Name: beaconclient.MockForkCapella, Version: "0x03000000", Epoch: 1},
+		beaconclient.MockFork{Name: beaconclient.MockForkDeneb, Version: "0x04000000", Epoch: 2},
+	)
+	backend.relay.beaconClient = beaconclient.NewMultiBeaconClient(common.TestLog, []beaconclient.IBeaconInstance{beaconInstance})
+	require.NoError(t, backend.relay.updateForkEpochs())
+	require.True(t, backend.relay.isBellatrix(31))
+	require.True(t, backend.relay.isCapella(32))
+	require.True(t, backend.relay.isDeneb(64))
+
+	attributesKnown := make(chan uint64, 10)
+	backend.relay.slots.on(slotEventAttributesKnown, func(event *slotEvent) {
+		select {
+		case attributesKnown <- event.slot:
+		default:
+		}
+	})
+	headC := make(chan beaconclient.HeadEventData)
+	beaconInstance.SubscribeToHeadEvents(headC)
+	advanceHead := func(slot uint64) {
+		go beaconInstance.AdvanceHead(slot)
+		for event := range headC {
+			backend.relay.processNewSlot(event.Slot)
+			if event.Slot == slot {
+				return
+			}
+		}
+	}
+	requireAttributesKnown := func(slot uint64) {
+		select {
+		case knownSlot := <-attributesKnown:
+			require.Equal(t, slot, knownSlot)
+		case <-time.After(time.Second):
+			t.Fatalf("attributes of slot %d not known", slot)
+		}
+	}
+
+	// the last bellatrix head doesn't need withdrawals, the first capella head does
+	advanceHead(31)
+	requireAttributesKnown(32)
+	require.Equal(t, slotAttributePrevRandao|slotAttributeParentHash, backend.relay.requiredSlotAttributes(31))
+	advanceHead(32)
+	requireAttributesKnown(33)
+	require.Equal(t, slotAttributePrevRandao|slotAttributeParentHash|slotAttributeWithdrawals, backend.relay.requiredSlotAttributes(32))
+	backend.relay.expectedWithdrawalsLock.RLock()
+	require.Equal(t, uint64(33), backend.relay.expectedWithdrawalsRoot.slot)
+	backend.relay.expectedWithdrawalsLock.RUnlock()
+
+	// the first deneb slot commits to the root of the last capella block
+	advanceHead(63)
+	require.Eventually(t, func() bool {
+		root, _ := backend.relay.expectedParentBeaconBlockRoot(64)
+		return root != nil
+	}, time.Second, 10*time.Millisecond)
+	root, _ := backend.relay.expectedParentBeaconBlockRoot(64)
+	expectedRoot, err := backend.relay.getBlockRoot(63)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	_, submissionErr := backend.relay.expectedParentBeaconBlockRoot(65)
+	require.NotNil(t, submissionErr)
+}
+
+func TestDecodeSignedBlindedBeaconBlockSSZ(t *testing.T) {
+	backend := newTestBackend(t, 1)
+
+	capellaBlock := &capella.SignedBlindedBeaconBlock{
+		Message: &capella.BlindedBeaconBlock{
+			Slot: 100,
+			Body: &capella.BlindedBeaconBlockBody{
+				ETH1Data:               &phase0.ETH1Data{BlockHash: make([]byte, 32)},
+				SyncAggregate:          &altair.SyncAggregate{SyncCommitteeBits: make([]byte, 64)},
+				ExecutionPayloadHeader: &consensuscapella.ExecutionPayloadHeader{},
+			},
+		},
+	}
+	capellaSSZ, err := marshalCapellaSignedBlindedBeaconBlockSSZ(capellaBlock)
+	require.NoError(t, err)
+
+	bellatrixBlock := &types.SignedBlindedBeaconBlock{
+		Message: &types.BlindedBeaconBlock{
+			Slot: 100,
+			Body: &types.BlindedBeaconBlockBody{
+				Eth1Data:               &types.Eth1Data{},
+				SyncAggregate:          &types.SyncAggregate{},
+				ExecutionPayloadHeader: &types.ExecutionPayloadHeader{},
+			},
+		},
+	}
+	bellatrixSSZ, err := bellatrixBlock.MarshalSSZ()
+	require.NoError(t, err)
+
+	t.Run("capella slot", func(t *testing.T) {
+		payload, err := backend.relay.decodeSignedBlindedBeaconBlockSSZ(capellaSSZ)
+		require.NoError(t, err)
+		require.NotNil(t, payload.Capella)
+		require.Equal(t, uint64(100), payload.Slot())
+	})
+
+	t.Run("bellatrix slot", func(t *testing.T) {
+		backend.relay.capellaEpoch = 4
+		defer func() { backend.relay.capellaEpoch = 0 }()
+		payload, err := backend.relay.decodeSignedBlindedBeaconBlockSSZ(bellatrixSSZ)
+		require.NoError(t, err)
+		require.NotNil(t, payload.Bellatrix)
+		require.Equal(t, uint64(100), payload.Slot())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := backend.relay.decodeSignedBlindedBeaconBlockSSZ(capellaSSZ[:50])
+		require.ErrorIs(t, err, ErrInvalidSSZ)
+
+		// a bellatrix block in the capella fork
+		_, err = backend.relay.decodeSignedBlindedBeaconBlockSSZ(bellatrixSSZ)
+		require.ErrorIs(t, err, ErrInvalidSSZ)
+
+		req, err := http.NewRequest(http.MethodPost, pathGetPayload, bytes.NewReader(capellaSSZ[:50]))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/octet-stream")
+		rr := httptest.NewRecorder()
+		backend.relay.getRouter().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrInvalidSSZ.Error())
+	})
+}
+
 func TestRegisterValidator(t *testing.T) {
 	path := "/eth/v1/builder/validators"
 
@@ -190,6 +512,66 @@ func TestRegisterValidator(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, rr.Code)
 	})
 
+	t.Run("unknown validator in load test mode", func(t *testing.T) {
+		backend := newTestBackend(t, 1)
+		backend.relay.ffLoadTestMode = true
+
+		registration, err := generateSignedValidatorRegistration(nil, types.Address{0x01}, uint64(time.Now().Unix()))
+		require.NoError(t, err)
+		rr := backend.request(http.MethodPost, path, []*types.SignedValidatorRegistration{registration})
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("rate limited per validator", func(t *testing.T) {
+		backend := newTestBackend(t, 1)
+		backend.relay.ffLoadTestMode = true
+		backend.relay.pubkeyRateLimiter = NewRateLimiter(1, 1)
+
+		registration, err := generateSignedValidatorRegistration(nil, types.Address{0x01}, uint64(time.Now().Unix()))
+		require.NoError(t, err)
+
+		// registrations with an invalid signature don't use up the limit of the validator
+		invalid := *registration
+		invalid.Message = &types.RegisterValidatorRequestMessage{}
+		*invalid.Message = *registration.Message
+		invalid.Message.GasLimit++
+		for i := 0; i < 3; i++ {
+			rr := backend.request(http.MethodPost, path, []*types.SignedValidatorRegistration{&invalid})
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+
+		rr := backend.request(http.MethodPost, path, []*types.SignedValidatorRegistration{registration})
+		require.Equal(t, http.StatusOK, rr.Code)
+		rr = backend.request(http.MethodPost, path, []*types.SignedValidatorRegistration{registration})
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+
+		// the getHeader requests of the validator are limited separately
+		require.True(t, backend.relay.pubkeyRateLimiter.Allow(strings.ToLower(registration.Message.Pubkey.String())))
+	})
+
+	t.Run("gzip encoded request body", func(t *testing.T) {
+		backend := newTestBackend(t, 1)
+
+		payloadBytes, err := json.Marshal([]types.SignedValidatorRegistration{common.ValidPayloadRegisterValidator})
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		gzipWriter := gzip.NewWriter(&buf)
+		_, err = gzipWriter.Write(payloadBytes)
+		require.NoError(t, err)
+		require.NoError(t, gzipWriter.Close())
+
+		req, err := http.NewRequest(http.MethodPost, path, &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		backend.relay.getRouter().ServeHTTP(rr, req)
+
+		// the body was decoded, and the registration rejected because the validator is unknown
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "not a known validator")
+		require.Contains(t, rr.Body.String(), `"error_code":"`+ErrorCodeUnknownValidator+`"`)
+	})
+
 	t.Run("Reject registration for >10sec into the future", func(t *testing.T) {
 		backend := newTestBackend(t, 1)
 
@@ -224,12 +606,12 @@ func TestBuilderApiGetValidators(t *testing.T) {
 	path := "/relay/v1/builder/validators"
 
 	backend := newTestBackend(t, 1)
-	backend.relay.proposerDutiesResponse = []types.BuilderGetValidatorsResponseEntry{
+	backend.relay.proposerDuties.set([]types.BuilderGetValidatorsResponseEntry{
 		{
 			Slot:  1,
 			Entry: &common.ValidPayloadRegisterValidator,
 		},
-	}
+	}, 0)
 
 	rr := backend.request(http.MethodGet, path, nil)
 	require.Equal(t, http.StatusOK, rr.Code)
@@ -240,6 +622,20 @@ func TestBuilderApiGetValidators(t *testing.T) {
 	require.Equal(t, 1, len(resp))
 	require.Equal(t, uint64(1), resp[0].Slot)
 	require.Equal(t, common.ValidPayloadRegisterValidator, *resp[0].Entry)
+
+	// with the preferences of the proposers
+	rr = backend.request(http.MethodGet, path+"?include_preferences=true", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	respWithPreferences := []common.BuilderGetValidatorsResponseEntryWithPreferences{}
+	err = json.Unmarshal(rr.Body.Bytes(), &respWithPreferences)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(respWithPreferences))
+	require.Equal(t, common.ValidPayloadRegisterValidator, *respWithPreferences[0].Entry)
+	require.Equal(t, common.ValidPayloadRegisterValidator.Message.FeeRecipient.String(), respWithPreferences[0].Preferences.FeeRecipient)
+	require.Equal(t, common.ValidPayloadRegisterValidator.Message.GasLimit, respWithPreferences[0].Preferences.GasLimit)
+
+	rr = backend.request(http.MethodGet, path+"?include_preferences=maybe", nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
 }
 
 func TestDataApiGetDataProposerPayloadDelivered(t *testing.T) {
@@ -274,4 +670,328 @@ func TestDataApiGetDataProposerPayloadDelivered(t *testing.T) {
 			require.Contains(t, rr.Body.String(), "invalid block_hash argument")
 		}
 	})
+
+	t.Run("Paginated response", func(t *testing.T) {
+		backend := newTestBackend(t, 1)
+
+		rr := backend.request(http.MethodGet, path+"?paginate=true", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		resp := new(DataPageResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Empty(t, resp.NextCursor)
+
+		rr = backend.request(http.MethodGet, path+"?cursor="+encodePageCursor(10, 5), nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = backend.request(http.MethodGet, path+"?cursor=invalid", nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = backend.request(http.MethodGet, path+"?paginate=true&order_by=value", nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestDataApiExport(t *testing.T) {
+	backend := newTestBackend(t, 1)
+
+	invalidArgs := []string{
+		"?slot_from=1&slot_to=2",
+		"?type=foo&slot_from=1&slot_to=2",
+		"?type=payload_delivered&slot_to=2",
+		"?type=payload_delivered&slot_from=2&slot_to=1",
+		fmt.Sprintf("?type=builder_block_received&slot_from=1&slot_to=%d", dataExportMaxSlots+1),
+	}
+	for _, args := range invalidArgs {
+		rr := backend.request(http.MethodGet, pathDataExport+args, nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code, args)
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	cursor, err := decodePageCursor(encodePageCursor(123, 456))
+	require.NoError(t, err)
+	require.Equal(t, &database.PageCursor{Slot: 123, ID: 456}, cursor)
+
+	_, err = decodePageCursor("123")
+	require.ErrorIs(t, err, ErrInvalidPageCursor)
+
+	require.Empty(t, nextPageCursor(1, 2, 123, 456))
+	require.Equal(t, encodePageCursor(123, 456), nextPageCursor(2, 2, 123, 456))
+}
+
+func TestParseRangeFilters(t *testing.T) {
+	filters, err := parseRangeFilters(url.Values{
+		"value_min":      {"1000"},
+		"value_max":      {"2000000000000000000000"},
+		"from_timestamp": {"1680000000"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, database.RangeFilters{
+		ValueMin: "1000",
+		ValueMax: "2000000000000000000000",
+		FromTime: time.Unix(1680000000, 0).UTC(),
+	}, filters)
+
+	for _, args := range []url.Values{
+		{"value_min": {"-1"}},
+		{"value_max": {"1e18"}},
+		{"to_timestamp": {"yesterday"}},
+	} {
+		_, err = parseRangeFilters(args)
+		require.Error(t, err)
+	}
+}
+
+func TestProcessRegistrations(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	timestamp := uint64(time.Now().Unix())
+
+	// enough registrations for several shards
+	signedRegistrations := []*types.SignedValidatorRegistration{}
+	for i := 0; i < 3*minRegistrationShardSize; i++ {
+		reg, err := generateSignedValidatorRegistration(nil, types.Address{byte(i)}, timestamp)
+		require.NoError(t, err)
+		require.NoError(t, backend.redis.SetKnownValidator(reg.Message.Pubkey.PubkeyHex(), uint64(i)))
+		signedRegistrations = append(signedRegistrations, reg)
+	}
+	_, err := backend.datastore.RefreshKnownValidators()
+	require.NoError(t, err)
+
+	toPending := func() []pendingRegistration {
+		registrations := []pendingRegistration{}
+		for _, reg := range signedRegistrations {
+			value, err := json.Marshal(reg)
+			require.NoError(t, err)
+			registrations = append(registrations, pendingRegistration{
+				value:        value,
+				pubkey:       reg.Message.Pubkey.PubkeyHex(),
+				timestamp:    reg.Message.Timestamp,
+				feeRecipient: reg.Message.FeeRecipient.String(),
+				gasLimit:     reg.Message.GasLimit,
+			})
+		}
+		return registrations
+	}
+
+	result, err := backend.relay.processRegistrations(common.TestLog, toPending(), 4)
+	require.NoError(t, err)
+	require.Len(t, result.activeValidators, len(signedRegistrations))
+	require.Len(t, result.newRegistrations, len(signedRegistrations))
+	require.Equal(t, signedRegistrations[0], result.newRegistrations[0])
+
+	// registrations which aren't newer than the stored ones are skipped
+	require.NoError(t, backend.redis.SetValidatorRegistrationTimestamp(signedRegistrations[5].Message.Pubkey.PubkeyHex(), timestamp))
+	result, err = backend.relay.processRegistrations(common.TestLog, toPending(), 4)
+	require.NoError(t, err)
+	require.Len(t, result.activeValidators, len(signedRegistrations))
+	require.Equal(t, len(signedRegistrations)-1, result.numNew)
+	require.Equal(t, 1, result.numUnchanged)
+
+	// re-submissions of the stored registrations are skipped, changed registrations with the same timestamp are outdated
+	stored := map[types.PubkeyHex]datastore.ValidatorRegistrationMessage{}
+	for _, reg := range signedRegistrations[:2] {
+		stored[reg.Message.Pubkey.PubkeyHex()] = datastore.ValidatorRegistrationMessage{
+			Timestamp:    reg.Message.Timestamp,
+			FeeRecipient: reg.Message.FeeRecipient.String(),
+			GasLimit:     reg.Message.GasLimit,
+		}
+	}
+	outdated := stored[signedRegistrations[1].Message.Pubkey.PubkeyHex()]
+	outdated.Timestamp = timestamp - 1
+	stored[signedRegistrations[1].Message.Pubkey.PubkeyHex()] = outdated
+	require.NoError(t, backend.redis.SetValidatorRegistrationsIfNewer(stored))
+	signedRegistrations[1].Message.Timestamp = timestamp - 1
+	signedRegistrations[1].Message.GasLimit++
+	result, err = backend.relay.processRegistrations(common.TestLog, toPending(), 4)
+	require.NoError(t, err)
+	require.Equal(t, len(signedRegistrations)-3, result.numNew)
+	require.Equal(t, 2, result.numUnchanged)
+	require.Equal(t, 1, result.numOutdated)
+	signedRegistrations[1].Message.Timestamp = timestamp
+	signedRegistrations[1].Message.GasLimit--
+
+	// invalidate a single signature, in the last shard
+	signedRegistrations[len(signedRegistrations)-2].Message.GasLimit++
+	_, err = backend.relay.processRegistrations(common.TestLog, toPending(), 4)
+	require.ErrorIs(t, err, ErrInvalidRegistrationSignature)
+
+	// the error of the first invalid registration is returned
+	reg, err := generateSignedValidatorRegistration(nil, types.Address{}, timestamp)
+	require.NoError(t, err)
+	signedRegistrations[1] = reg
+	result, err = backend.relay.processRegistrations(common.TestLog, toPending(), 4)
+	require.ErrorContains(t, err, "not a known validator")
+	require.Equal(t, 1, result.errIndex)
+
+	// in closed relay mode, only the validators on the allowlist are accepted
+	signedRegistrations = signedRegistrations[2:]
+	backend.relay.ffProposerAllowlist = true
+	require.NoError(t, backend.redis.SetProposerAllowlist([]string{signedRegistrations[0].Message.Pubkey.String()}))
+	backend.relay.updateProposerAllowlist()
+	result, err = backend.relay.processRegistrations(common.TestLog, toPending(), 4)
+	require.ErrorContains(t, err, "validator not served by this relay")
+	require.Equal(t, 1, result.errIndex)
+}
+
+func TestCheckBuilderAPIKey(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	builderPubkey := "0xb1"
+
+	newRequest := func(apiKey string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, pathSubmitNewBlock, nil)
+		if apiKey != "" {
+			req.Header.Set(HeaderBuilderAPIKey, apiKey)
+		}
+		return req
+	}
+
+	// no key issued: accepted
+	require.NoError(t, backend.relay.checkBuilderAPIKey(newRequest(""), builderPubkey))
+
+	apiKey, err := generateAPIKey()
+	require.NoError(t, err)
+	err = backend.redis.SetBlockBuilderAPIKeyHash(builderPubkey, hashAPIKey(apiKey))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(newRequest(""), builderPubkey), ErrMissingAPIKey)
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(newRequest("wrong"), builderPubkey), ErrInvalidAPIKey)
+	require.NoError(t, backend.relay.checkBuilderAPIKey(newRequest(apiKey), builderPubkey))
+
+	// the pubkey is looked up in lowercase
+	require.NoError(t, backend.relay.checkBuilderAPIKey(newRequest(apiKey), strings.ToUpper(builderPubkey)))
+
+	// revoked: neither the old key nor no key is accepted
+	rr := httptest.NewRecorder()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/internal/v1/builder/api_key/"+builderPubkey, nil), map[string]string{"pubkey": builderPubkey})
+	backend.relay.handleInternalBuilderAPIKey(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(newRequest(apiKey), builderPubkey), ErrRevokedAPIKey)
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(newRequest(""), builderPubkey), ErrRevokedAPIKey)
+
+	// removed: accepted without a key again
+	err = backend.redis.DeleteBlockBuilderAPIKeyHash(builderPubkey)
+	require.NoError(t, err)
+	require.NoError(t, backend.relay.checkBuilderAPIKey(newRequest(""), builderPubkey))
+
+	// redis errors fail closed
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	backend.relay.redis, err = datastore.NewRedisCache(redisServer.Addr(), "")
+	require.NoError(t, err)
+	redisServer.Close()
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(newRequest(apiKey), builderPubkey), ErrAPIKeyUnavailable)
+}
+
+func TestBuilderAPIKeyRateLimit(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.relay.apiKeyRateLimiter = NewRateLimiter(1, 2)
+	builderPubkey := "0xb1"
+
+	apiKey, err := generateAPIKey()
+	require.NoError(t, err)
+	err = backend.redis.SetBlockBuilderAPIKeyHash(builderPubkey, hashAPIKey(apiKey))
+	require.NoError(t, err)
+
+	submit := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodPost, pathSubmitNewBlock, nil)
+		req.Header.Set(HeaderBuilderAPIKey, apiKey)
+		rr := httptest.NewRecorder()
+		if err := backend.relay.checkBuilderAPIKey(req, builderPubkey); err != nil {
+			backend.relay.respondBuilderAPIKeyError(rr, err)
+		}
+		return rr.Code
+	}
+
+	accepted := testutil.ToFloat64(common.BuilderAPIKeyChecksTotal.WithLabelValues(backend.relay.networkName(), builderPubkey, "accepted"))
+	rateLimited := testutil.ToFloat64(common.BuilderAPIKeyChecksTotal.WithLabelValues(backend.relay.networkName(), builderPubkey, "rate_limited"))
+	require.Equal(t, http.StatusOK, submit(apiKey))
+	require.Equal(t, http.StatusOK, submit(apiKey))
+	require.Equal(t, http.StatusTooManyRequests, submit(apiKey))
+	require.Equal(t, accepted+2, testutil.ToFloat64(common.BuilderAPIKeyChecksTotal.WithLabelValues(backend.relay.networkName(), builderPubkey, "accepted")))
+	require.Equal(t, rateLimited+1, testutil.ToFloat64(common.BuilderAPIKeyChecksTotal.WithLabelValues(backend.relay.networkName(), builderPubkey, "rate_limited")))
+
+	// invalid keys don't use up the limit of the valid one
+	require.Equal(t, http.StatusUnauthorized, submit("wrong"))
+
+	// a new key gets its own limit
+	apiKey, err = generateAPIKey()
+	require.NoError(t, err)
+	err = backend.redis.SetBlockBuilderAPIKeyHash(builderPubkey, hashAPIKey(apiKey))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, submit(apiKey))
+}
+
+func TestFilterSubmissionPreSim(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	relay := backend.relay
+	relay.genesisInfo = &beaconclient.GetGenesisResponse{}
+	relay.headSlot.Store(10)
+
+	feeRecipient, err := types.HexToAddress("0xfee0000000000000000000000000000000000000")
+	require.NoError(t, err)
+	relay.proposerDuties.set([]types.BuilderGetValidatorsResponseEntry{
+		{Slot: 11, Entry: &types.SignedValidatorRegistration{Message: &types.RegisterValidatorRequestMessage{FeeRecipient: feeRecipient}}},
+	}, 10)
+	relay.expectedPrevRandao = randaoHelper{slot: 11, prevRandao: "0x01"}
+	relay.expectedParentHash = parentHashHelper{slot: 11, parentHash: "0xaa"}
+
+	newSubmission := func() *preSimSubmission {
+		return &preSimSubmission{
+			slot:                 11,
+			parentHash:           "0xAA",
+			timestamp:            11 * 12,
+			prevRandao:           "0x01",
+			proposerFeeRecipient: feeRecipient.String(),
+		}
+	}
+
+	filterPreSim := func(s *preSimSubmission) (*FilteredSubmission, *submissionError) {
+		filtered := &FilteredSubmission{Log: relay.log, preSim: s}
+		return filtered, relay.filterSubmissionPreSim(filtered)
+	}
+
+	filtered, preSimErr := filterPreSim(newSubmission())
+	require.Nil(t, preSimErr)
+	require.Equal(t, feeRecipient, filtered.SlotDuty.FeeRecipient)
+
+	testCases := []struct {
+		name     string
+		modify   func(s *preSimSubmission)
+		expected string
+	}{
+		{"past slot", func(s *preSimSubmission) { s.slot = 10 }, SubmissionErrSlotPast},
+		{"parent hash", func(s *preSimSubmission) { s.parentHash = "0xbb" }, SubmissionErrParentHashMismatch},
+		{"timestamp", func(s *preSimSubmission) { s.timestamp++ }, SubmissionErrTimestampMismatch},
+		{"fee recipient", func(s *preSimSubmission) { s.proposerFeeRecipient = "0x01" }, SubmissionErrFeeRecipientMismatch},
+		{"prev_randao", func(s *preSimSubmission) { s.prevRandao = "0x02" }, SubmissionErrPrevRandaoMismatch},
+		{"withdrawals unknown", func(s *preSimSubmission) { s.withdrawalsRoot = &phase0.Root{} }, SubmissionErrWithdrawalsUnknown},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSubmission()
+			tc.modify(s)
+			_, preSimErr := filterPreSim(s)
+			require.NotNil(t, preSimErr)
+			require.Equal(t, tc.expected, preSimErr.code)
+		})
+	}
+
+	// too late for the bid of the slot, which started long ago
+	relay.getHeaderPolicy = getHeaderPolicy{hasBidCutoff: true, bidCutoff: time.Second}
+	_, preSimErr = filterPreSim(newSubmission())
+	require.NotNil(t, preSimErr)
+	require.Equal(t, SubmissionErrAfterBidCutoff, preSimErr.code)
+	relay.getHeaderPolicy = getHeaderPolicy{}
+
+	// stale once the payload was delivered
+	err = backend.redis.SetStats(datastore.RedisStatsFieldSlotLastPayloadDelivered, 11)
+	require.NoError(t, err)
+	_, preSimErr = filterPreSim(newSubmission())
+	require.NotNil(t, preSimErr)
+	require.Equal(t, SubmissionErrSlotDelivered, preSimErr.code)
+
+	// synthetic submissions aren't checked in load test mode
+	relay.ffLoadTestMode = true
+	_, preSimErr = filterPreSim(newSubmission())
+	require.Nil(t, preSimErr)
 }
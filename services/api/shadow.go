@@ -0,0 +1,19 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HeaderShadowMode is set on all responses of a relay in shadow mode, which runs the whole pipeline against real traffic
+// but never delivers a payload
+const HeaderShadowMode = "X-Relay-Shadow-Mode"
+
+var ErrShadowMode = errors.New("relay is in shadow mode, payloads are not delivered")
+
+func markShadowMode(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(HeaderShadowMode, "true")
+		next.ServeHTTP(w, req)
+	})
+}
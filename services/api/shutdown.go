@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// getPayloadGracePeriod is how long to wait for getPayload calls after getHeader stops returning bids, since a proposer
+// may have received a bid just before
+var getPayloadGracePeriod = 5 * time.Second
+
+// runInBackground runs work which outlives the request, e.g. saving a delivered payload. Shutdown waits for it.
+func (api *RelayAPI) runInBackground(fn func()) {
+	api.backgroundTasks.Add(1)
+	go func() {
+		defer api.backgroundTasks.Done()
+		fn()
+	}()
+}
+
+// rejectWhenShuttingDown responds with 503 once the shutdown started, so clients retry on another instance
+func (api *RelayAPI) rejectWhenShuttingDown(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if api.isShuttingDown.Load() {
+			api.RespondErrorWithCode(w, http.StatusServiceUnavailable, ErrorCodeShuttingDown, "relay is shutting down")
+			return
+		}
+		next(w, req)
+	}
+}
+
+// StopServer drains the instance within the shutdown timeout: getHeader stops returning bids and new submissions are
+// rejected, the event streams are closed, and the pending getPayload calls, background work, queued validator
+// registrations and queued builder submissions are completed before it returns.
+func (api *RelayAPI) StopServer() (err error) {
+	if api.isShuttingDown.Swap(true) {
+		return nil
+	}
+	defer close(api.stoppedC)
+
+	api.log.WithField("timeout", api.opts.HTTPServer.ShutdownTimeout.String()).Info("Stopping server...")
+	ctx, cancel := context.WithTimeout(context.Background(), api.opts.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	api.stopAcceptingWork(ctx)
+
+	// stop accepting connections, and wait for the requests in flight
+	if err = api.srv.Shutdown(ctx); err != nil {
+		api.log.WithError(err).Error("failed to wait for the requests in flight")
+	}
+	api.drain(ctx, err == nil)
+	return err
+}
+
+// stopAcceptingWork ends the event streams, and gives the proposers a grace period for the getPayload calls of the bids
+// returned before the shutdown started. isShuttingDown must be set already.
+func (api *RelayAPI) stopAcceptingWork(ctx context.Context) {
+	// end the event streams and redis subscriptions, the server would wait for the streams forever
+	api.stopSubscriptions()
+
+	if api.opts.ProposerAPI {
+		api.log.Info("Disabled sending bids, waiting a few seconds for getPayload calls...")
+		select {
+		case <-time.After(getPayloadGracePeriod):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// drain stops the admin server and completes the background work, once the HTTP server serving the instance stopped.
+// The queues are only closed if no requests are left, i.e. requestsDone.
+func (api *RelayAPI) drain(ctx context.Context, requestsDone bool) {
+	if api.adminSrv != nil {
+		if err := api.adminSrv.Shutdown(ctx); err != nil {
+			api.log.WithError(err).Error("failed to stop the admin API")
+		}
+	}
+
+	// wait for the background work of the requests, i.e. block publishing, database writes and optimistic simulations
+	api.waitWithTimeout(ctx, "getPayload calls", &api.getPayloadCallsInFlight)
+	api.waitWithTimeout(ctx, "background tasks", &api.backgroundTasks)
+	optimisticDone := api.waitWithTimeout(ctx, "optimistic simulations", &api.optimisticBlocksInFlight)
+
+	// no requests are left to queue validators, so the processors can finish the queues. If requests are still running
+	// after the timeout, the channels are left open since they may still send.
+	if requestsDone {
+		close(api.activeValidatorC)
+		close(api.validatorRegC)
+		api.waitWithTimeout(ctx, "validator processors", &api.validatorProcessors)
+	}
+
+	// the submissions are queued by the requests and the optimistic simulations, the writers save what's left in the queue
+	if requestsDone && optimisticDone {
+		api.blockSubmissionWriter.stop()
+		api.waitWithTimeout(ctx, "submission writers", &api.blockSubmissionWriter.workers)
+	}
+
+	// the submissions are only mirrored by the requests
+	if requestsDone && api.submissionMirror != nil {
+		api.submissionMirror.stop()
+		api.waitWithTimeout(ctx, "submission mirror", &api.submissionMirror.workers)
+	}
+
+	// only the requests in flight capture, and they are done
+	if requestsDone && api.requestCapture != nil {
+		api.requestCapture.stop()
+		api.waitWithTimeout(ctx, "request capture", &api.requestCapture.workers)
+	}
+
+	api.log.Info("Server stopped")
+}
+
+// waitWithTimeout waits for the wait group, or until the context is done. Returns whether the wait group is done.
+func (api *RelayAPI) waitWithTimeout(ctx context.Context, name string, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		api.log.Errorf("shutdown timeout exceeded while waiting for %s", name)
+		return false
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"crypto/rand"
+	"runtime"
+
+	"github.com/flashbots/go-boost-utils/bls"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/go-utils/cli"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+var (
+	builderSigVerifyWorkers   = cli.GetEnvInt("BUILDER_SIG_VERIFY_WORKERS", runtime.NumCPU())
+	builderSigVerifyBatchSize = cli.GetEnvInt("BUILDER_SIG_VERIFY_BATCH_SIZE", 16)
+
+	// domain separation tag of the signatures, as used by go-boost-utils
+	blsDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+)
+
+// number of random bits per signature when verifying a batch, i.e. the security level against forged batches
+const batchVerifyRandBits = 64
+
+type sigVerifyRequest struct {
+	msg    [32]byte
+	pubkey *bls.PublicKey
+	sig    *bls.Signature
+	result chan bool
+}
+
+// sigVerifier verifies BLS signatures on a pool of workers. Each worker takes all requests waiting at the time (up to
+// the batch size) and verifies them with a single multi-pairing, which is much cheaper than one pairing per signature.
+// Requests are never held back to fill a batch, so a single request is verified as fast as without batching.
+type sigVerifier struct {
+	requests  chan *sigVerifyRequest
+	batchSize int
+}
+
+func newSigVerifier(numWorkers, batchSize int) *sigVerifier {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	v := &sigVerifier{
+		requests:  make(chan *sigVerifyRequest, numWorkers*batchSize),
+		batchSize: batchSize,
+	}
+	for i := 0; i < numWorkers; i++ {
+		go v.runWorker()
+	}
+	return v
+}
+
+// verify checks the signature of a signing root, returning an error if the pubkey or signature are malformed
+func (v *sigVerifier) verify(msg [32]byte, pubkeyBytes, sigBytes []byte) (bool, error) {
+	pubkey, err := bls.PublicKeyFromBytes(pubkeyBytes)
+	if err != nil {
+		return false, err
+	}
+	sig, err := bls.SignatureFromBytes(sigBytes)
+	if err != nil {
+		return false, err
+	}
+
+	req := &sigVerifyRequest{msg: msg, pubkey: pubkey, sig: sig, result: make(chan bool, 1)}
+	v.requests <- req
+	return <-req.result, nil
+}
+
+func (v *sigVerifier) runWorker() {
+	batch := make([]*sigVerifyRequest, 0, v.batchSize)
+	for req := range v.requests {
+		batch = append(batch[:0], req)
+	collect:
+		for len(batch) < v.batchSize {
+			select {
+			case req := <-v.requests:
+				batch = append(batch, req)
+			default:
+				break collect
+			}
+		}
+		verifyBatch(batch)
+	}
+}
+
+// verifyBatch verifies the signatures together, and only if that fails one by one to find the invalid ones
+func verifyBatch(batch []*sigVerifyRequest) {
+	if len(batch) > 1 {
+		sigs := make([]*bls.Signature, len(batch))
+		pubkeys := make([]*bls.PublicKey, len(batch))
+		msgs := make([]blst.Message, len(batch))
+		for i, req := range batch {
+			sigs[i] = req.sig
+			pubkeys[i] = req.pubkey
+			msgs[i] = req.msg[:]
+		}
+
+		// signatures and pubkeys were already validated when deserializing them
+		if new(bls.Signature).MultipleAggregateVerify(sigs, false, pubkeys, false, msgs, blsDST, randomScalar, batchVerifyRandBits) {
+			for _, req := range batch {
+				req.result <- true
+			}
+			return
+		}
+	}
+
+	for _, req := range batch {
+		req.result <- bls.VerifySignature(req.sig, req.pubkey, req.msg[:])
+	}
+}
+
+// verifyBuilderSignature verifies the builder's signature of a bid, batched with the concurrent submissions
+func (api *RelayAPI) verifyBuilderSignature(bid boostTypes.HashTreeRoot, pubkey, sig []byte) (bool, error) {
+	msg, err := boostTypes.ComputeSigningRoot(bid, api.opts.EthNetDetails.DomainBuilder)
+	if err != nil {
+		return false, err
+	}
+	return api.builderSigVerifier.verify(msg, pubkey, sig)
+}
+
+// randomScalar is used to weight the signatures of a batch, so that invalid signatures can't cancel each other out
+func randomScalar(s *blst.Scalar) {
+	var b [blst.BLST_SCALAR_BYTES]byte
+	_, _ = rand.Read(b[:])
+	s.FromBEndian(b[:])
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/rand"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/stretchr/testify/require"
+	uberatomic "go.uber.org/atomic"
+)
+
+type testSignature struct {
+	msg    [32]byte
+	pubkey []byte
+	sig    []byte
+}
+
+func newTestSignatures(t testing.TB, n int) []testSignature {
+	t.Helper()
+	sigs := make([]testSignature, n)
+	for i := range sigs {
+		sk, pk, err := bls.GenerateNewKeypair()
+		require.NoError(t, err)
+		_, err = rand.Read(sigs[i].msg[:])
+		require.NoError(t, err)
+		sigs[i].pubkey = pk.Compress()
+		sigs[i].sig = bls.Sign(sk, sigs[i].msg[:]).Compress()
+	}
+	return sigs
+}
+
+func TestSigVerifier(t *testing.T) {
+	v := newSigVerifier(2, 8)
+	sigs := newTestSignatures(t, 32)
+
+	// every 5th signature is for a different message
+	results := make([]bool, len(sigs))
+	errs := make([]error, len(sigs))
+	var wg sync.WaitGroup
+	for i := range sigs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := sigs[i].msg
+			if i%5 == 0 {
+				msg[0] ^= 0xff
+			}
+			results[i], errs[i] = v.verify(msg, sigs[i].pubkey, sigs[i].sig)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range sigs {
+		require.NoError(t, errs[i])
+		require.Equal(t, i%5 != 0, results[i], "signature %d", i)
+	}
+
+	_, err := v.verify(sigs[0].msg, sigs[0].pubkey, sigs[0].sig[1:])
+	require.ErrorIs(t, err, bls.ErrInvalidSignatureLength)
+}
+
+// BenchmarkSigVerifier compares verifying concurrent submissions one by one with verifying them in batches
+func BenchmarkSigVerifier(b *testing.B) {
+	sigs := newTestSignatures(b, 256)
+	for _, batchSize := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("batch-%d", batchSize), func(b *testing.B) {
+			v := newSigVerifier(runtime.NumCPU(), batchSize)
+			var next uberatomic.Int64
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					s := sigs[next.Inc()%int64(len(sigs))]
+					if ok, err := v.verify(s.msg, s.pubkey, s.sig); !ok || err != nil {
+						b.Fatal("signature verification failed")
+					}
+				}
+			})
+		})
+	}
+}
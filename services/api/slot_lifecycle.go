@@ -0,0 +1,220 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+// Slot lifecycle events, in their order within a slot. Each is emitted at most once per slot.
+const (
+	slotEventStarted          = "slot_started"      // the head moved to the previous slot, the slot is open for bids
+	slotEventAttributesKnown  = "attributes_known"  // the prev_randao, withdrawals and parent hash of the slot are known
+	slotEventFirstBid         = "first_bid"         // the first bid of the slot was accepted
+	slotEventPayloadRequested = "payload_requested" // the proposer requested the payload of a bid
+	slotEventPayloadDelivered = "payload_delivered" // the payload was returned to the proposer
+)
+
+// slotAttribute is a bit of the attributes a slot needs before submissions can be validated
+type slotAttribute uint8
+
+const (
+	slotAttributePrevRandao slotAttribute = 1 << iota
+	slotAttributeWithdrawals
+	slotAttributeParentHash
+)
+
+// number of recent slots whose state is kept, the older ones can't emit events anymore
+const slotLifecycleHistory = 64
+
+// slotEvent is passed to the hooks. The bid trace is set for the first bid and payload delivered events, log for the
+// events of a request.
+type slotEvent struct {
+	eventType string
+	slot      uint64
+	bidTrace  *common.BidTraceV2
+	log       *logrus.Entry
+}
+
+// headSlot is the slot the head moved to when the slot started
+func (e *slotEvent) headSlot() uint64 {
+	return e.slot - 1
+}
+
+type slotHook func(event *slotEvent)
+
+// slotEventTypes are all the slot lifecycle events
+var slotEventTypes = []string{slotEventStarted, slotEventAttributesKnown, slotEventFirstBid, slotEventPayloadRequested, slotEventPayloadDelivered}
+
+type slotState struct {
+	requiredAttributes slotAttribute
+	knownAttributes    slotAttribute
+	emitted            map[string]bool
+}
+
+// slotLifecycle tracks the state of the recent slots, and calls the hooks subscribed to each event. The hooks run
+// synchronously in the goroutine emitting the event, so the slow ones have to continue in the background.
+type slotLifecycle struct {
+	log   *logrus.Entry
+	hooks map[string][]slotHook
+
+	lock   sync.Mutex
+	latest uint64
+	slots  map[uint64]*slotState
+}
+
+func newSlotLifecycle(log *logrus.Entry) *slotLifecycle {
+	return &slotLifecycle{
+		log:   log.WithField("component", "slotLifecycle"),
+		hooks: make(map[string][]slotHook),
+		slots: make(map[uint64]*slotState),
+	}
+}
+
+// on subscribes the hook to an event, before the first slot starts
+func (l *slotLifecycle) on(eventType string, hook slotHook) {
+	l.hooks[eventType] = append(l.hooks[eventType], hook)
+}
+
+// start opens the slot, which emits the attributes known event once all the required attributes are known. Returns
+// false if the slot isn't newer than the latest started one.
+func (l *slotLifecycle) start(slot uint64, requiredAttributes slotAttribute) bool {
+	l.lock.Lock()
+	if slot <= l.latest {
+		l.lock.Unlock()
+		return false
+	}
+	l.latest = slot
+	state := l.state(slot)
+	state.requiredAttributes = requiredAttributes
+	for s := range l.slots {
+		if s+slotLifecycleHistory < slot {
+			delete(l.slots, s)
+		}
+	}
+	l.lock.Unlock()
+
+	l.emit(&slotEvent{eventType: slotEventStarted, slot: slot})
+	return true
+}
+
+// attributeKnown records an attribute of the slot
+func (l *slotLifecycle) attributeKnown(slot uint64, attribute slotAttribute) {
+	l.lock.Lock()
+	state := l.state(slot)
+	state.knownAttributes |= attribute
+	complete := state.requiredAttributes != 0 && state.knownAttributes&state.requiredAttributes == state.requiredAttributes
+	l.lock.Unlock()
+
+	if complete {
+		l.emit(&slotEvent{eventType: slotEventAttributesKnown, slot: slot})
+	}
+}
+
+// bidAccepted emits the first bid event for the first accepted bid of the slot
+func (l *slotLifecycle) bidAccepted(log *logrus.Entry, bidTrace *common.BidTraceV2) {
+	l.emit(&slotEvent{eventType: slotEventFirstBid, slot: bidTrace.Slot, bidTrace: bidTrace, log: log})
+}
+
+// payloadRequested emits the payload requested event for the first getPayload request of the slot
+func (l *slotLifecycle) payloadRequested(log *logrus.Entry, slot uint64) {
+	l.emit(&slotEvent{eventType: slotEventPayloadRequested, slot: slot, log: log})
+}
+
+// payloadDelivered emits the payload delivered event once the payload of the slot was returned to the proposer
+func (l *slotLifecycle) payloadDelivered(log *logrus.Entry, bidTrace *common.BidTraceV2) {
+	l.emit(&slotEvent{eventType: slotEventPayloadDelivered, slot: bidTrace.Slot, bidTrace: bidTrace, log: log})
+}
+
+// state returns the state of the slot, created if it's unknown (e.g. a request for a slot started before this
+// instance). Expects the lock to be held.
+func (l *slotLifecycle) state(slot uint64) *slotState {
+	state := l.slots[slot]
+	if state == nil {
+		state = &slotState{emitted: make(map[string]bool)}
+		l.slots[slot] = state
+	}
+	return state
+}
+
+// emit calls the hooks of the event, unless it was already emitted for the slot or the slot is too old
+func (l *slotLifecycle) emit(event *slotEvent) {
+	l.lock.Lock()
+	if event.slot+slotLifecycleHistory < l.latest {
+		l.lock.Unlock()
+		return
+	}
+	state := l.state(event.slot)
+	if state.emitted[event.eventType] {
+		l.lock.Unlock()
+		return
+	}
+	state.emitted[event.eventType] = true
+	l.lock.Unlock()
+
+	if event.log == nil {
+		event.log = l.log.WithField("slot", event.slot)
+	}
+	for _, hook := range l.hooks[event.eventType] {
+		hook(event)
+	}
+}
+
+// registerSlotHooks subscribes the subsystems of the API to the slot lifecycle
+func (api *RelayAPI) registerSlotHooks() {
+	for _, eventType := range slotEventTypes {
+		api.slots.on(eventType, func(event *slotEvent) {
+			common.SlotEventsTotal.WithLabelValues(api.networkName(), event.eventType).Inc()
+		})
+	}
+
+	// the attributes the submissions are checked against, and the proposer duties
+	api.slots.on(slotEventStarted, func(event *slotEvent) {
+		headSlot := event.headSlot()
+		if api.opts.BlockBuilderAPI {
+			go api.updatedExpectedRandao(headSlot)
+			go api.updatedExpectedWithdrawals(headSlot)
+			go api.updateExpectedParentHash(headSlot)
+		}
+		if api.opts.BlockBuilderAPI || api.opts.ProposerAPI {
+			api.proposerDuties.observeStaleness(headSlot)
+			go api.updateProposerDuties(headSlot)
+		}
+
+		epoch := headSlot / uint64(common.SlotsPerEpoch)
+		api.log.WithFields(logrus.Fields{
+			"epoch":              epoch,
+			"slotHead":           headSlot,
+			"slotStartNextEpoch": (epoch + 1) * uint64(common.SlotsPerEpoch),
+		}).Infof("updated headSlot to %d", headSlot)
+	})
+
+	api.slots.on(slotEventAttributesKnown, func(event *slotEvent) {
+		event.log.Info("slot attributes known, validating submissions")
+	})
+
+	// the data stream and event bus subscribers
+	api.slots.on(slotEventPayloadDelivered, func(event *slotEvent) {
+		api.publishDataStreamEvent(event.log, datastore.DataStreamEventPayloadDelivered, event.bidTrace)
+		api.publishEvent(common.EventPayloadDelivered, event.bidTrace)
+	})
+
+	if api.bidHistory != nil {
+		api.registerBidHistoryHooks()
+	}
+}
+
+// requiredSlotAttributes returns the attributes fetched for the slot after the head slot, none without the block
+// builder API. The withdrawals are only needed from capella on.
+func (api *RelayAPI) requiredSlotAttributes(headSlot uint64) slotAttribute {
+	if !api.opts.BlockBuilderAPI {
+		return 0
+	}
+	attributes := slotAttributePrevRandao | slotAttributeParentHash
+	if !api.isBellatrix(headSlot) {
+		attributes |= slotAttributeWithdrawals
+	}
+	return attributes
+}
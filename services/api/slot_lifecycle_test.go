@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotLifecycle(t *testing.T) {
+	lifecycle := newSlotLifecycle(common.TestLog)
+	events := []string{}
+	for _, eventType := range slotEventTypes {
+		lifecycle.on(eventType, func(event *slotEvent) {
+			events = append(events, event.eventType)
+		})
+	}
+
+	require.True(t, lifecycle.start(10, slotAttributePrevRandao|slotAttributeParentHash))
+	require.False(t, lifecycle.start(10, 0))
+	require.False(t, lifecycle.start(9, 0))
+	require.Equal(t, []string{slotEventStarted}, events)
+
+	// the attributes are known once all the required ones are
+	lifecycle.attributeKnown(10, slotAttributePrevRandao)
+	lifecycle.attributeKnown(10, slotAttributeWithdrawals)
+	require.Len(t, events, 1)
+	lifecycle.attributeKnown(10, slotAttributeParentHash)
+	lifecycle.attributeKnown(10, slotAttributeParentHash)
+	require.Equal(t, []string{slotEventStarted, slotEventAttributesKnown}, events)
+
+	// the other events are emitted once per slot
+	bidTrace := &common.BidTraceV2{}
+	bidTrace.Slot = 10
+	lifecycle.bidAccepted(common.TestLog, bidTrace)
+	lifecycle.bidAccepted(common.TestLog, bidTrace)
+	lifecycle.payloadRequested(common.TestLog, 10)
+	lifecycle.payloadRequested(common.TestLog, 10)
+	lifecycle.payloadDelivered(common.TestLog, bidTrace)
+	require.Equal(t, slotEventTypes, events)
+
+	// too old slots don't emit events anymore
+	events = events[:0]
+	require.True(t, lifecycle.start(10+slotLifecycleHistory+1, 0))
+	lifecycle.payloadRequested(common.TestLog, 9)
+	require.Equal(t, []string{slotEventStarted}, events)
+	require.Len(t, lifecycle.slots, 1)
+}
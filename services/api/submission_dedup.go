@@ -0,0 +1,51 @@
+package api
+
+import (
+	"math/big"
+
+	"github.com/buger/jsonparser"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// peekSubmissionBid extracts the block hash and value from a raw block submission, without decoding the full payload
+func peekSubmissionBid(body []byte) (blockHash, value string, ok bool) {
+	blockHash, err := jsonparser.GetString(body, "message", "block_hash")
+	if err != nil {
+		return "", "", false
+	}
+	valueStr, err := jsonparser.GetString(body, "message", "value")
+	if err != nil {
+		return "", "", false
+	}
+	valueInt, ok := new(big.Int).SetString(valueStr, 10)
+	if !ok {
+		return "", "", false
+	}
+	return blockHash, valueInt.String(), true
+}
+
+// isDuplicateSubmission returns whether the submission repeats the latest one accepted from the builder in the slot, i.e.
+// the same block with the same value, e.g. because of retries. Those are answered right away, without verifying the
+// signature or simulating the block.
+func (api *RelayAPI) isDuplicateSubmission(log *logrus.Entry, slot uint64, builderPubkey string, body []byte) bool {
+	blockHash, value, ok := peekSubmissionBid(body)
+	if !ok {
+		return false
+	}
+	isDuplicate, err := api.redis.IsAcceptedSubmission(slot, builderPubkey, blockHash, value)
+	if err != nil {
+		log.WithError(err).Error("could not check for a duplicate submission")
+		return false
+	}
+	if isDuplicate {
+		common.DuplicateSubmissionsTotal.WithLabelValues(api.networkName()).Inc()
+		log.WithFields(logrus.Fields{
+			"slot":          slot,
+			"builderPubkey": builderPubkey,
+			"blockHash":     blockHash,
+			"value":         value,
+		}).Info("duplicate submission, already accepted")
+	}
+	return isDuplicate
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeekSubmissionBid(t *testing.T) {
+	blockHash, value, ok := peekSubmissionBid([]byte(`{"message":{"block_hash":"0xaa","value":"0100"}}`))
+	require.True(t, ok)
+	require.Equal(t, "0xaa", blockHash)
+	require.Equal(t, "100", value)
+
+	_, _, ok = peekSubmissionBid([]byte(`{"message":{"block_hash":"0xaa","value":"abc"}}`))
+	require.False(t, ok)
+	_, _, ok = peekSubmissionBid([]byte(`{"message":{"value":"100"}}`))
+	require.False(t, ok)
+}
+
+func TestDuplicateSubmission(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	submission := func(value string) map[string]any {
+		return map[string]any{
+			"message": map[string]string{"slot": "10", "builder_pubkey": "0xb1", "block_hash": "0xaa", "value": value},
+		}
+	}
+
+	// unknown submissions are decoded, which fails for this one
+	rr := backend.request(http.MethodPost, pathSubmitNewBlock, submission("100"))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// the latest accepted submission of the builder is answered right away
+	require.NoError(t, backend.redis.SaveAcceptedSubmission(10, "0xb1", "0xaa", "100"))
+	rr = backend.request(http.MethodPost, pathSubmitNewBlock, submission("100"))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = backend.request(http.MethodPost, pathSubmitNewBlock, submission("101"))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// SubmissionErrFiltered is the error code of the submissions rejected by a filter without its own error code
+const SubmissionErrFiltered = "submission_filtered"
+
+// FilteredSubmission is a builder block submission passed through the submission filters, once its signature is verified
+type FilteredSubmission struct {
+	Log               *logrus.Entry
+	Payload           *common.BuilderSubmitBlockRequest
+	ReceivedAt        time.Time
+	BuilderIsHighPrio bool
+	SlotDuty          *boostTypes.RegisterValidatorRequestMessage // set by the pre-simulation filters
+
+	// Optimistic submissions are accepted before the simulation, once the BeforeSimulation filters passed
+	Optimistic bool
+
+	preSim *preSimSubmission
+}
+
+// SubmissionFilter is a policy applied to every builder block submission, e.g. compliance filtering or per-builder caps.
+// The filters run in order, and the first one returning an error rejects the submission. A *submissionError sets the
+// status and error code of the response, other errors are rejected with SubmissionErrFiltered.
+//
+// BeforeSimulation runs before the block is simulated. AfterSimulation runs once the simulation succeeded; optimistic
+// submissions are accepted before that, so their builder is demoted if an AfterSimulation filter rejects them.
+type SubmissionFilter interface {
+	Name() string
+	BeforeSimulation(ctx context.Context, s *FilteredSubmission) error
+	AfterSimulation(ctx context.Context, s *FilteredSubmission, simResult *BlockSimulationResult) error
+}
+
+// NoopSubmissionFilter is embedded by the filters which only run before or after the simulation
+type NoopSubmissionFilter struct{}
+
+func (NoopSubmissionFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	return nil
+}
+
+func (NoopSubmissionFilter) AfterSimulation(ctx context.Context, s *FilteredSubmission, simResult *BlockSimulationResult) error {
+	return nil
+}
+
+// defaultPreSimFilters are the built-in filters applied by filterSubmissionPreSim
+func (api *RelayAPI) defaultPreSimFilters() []SubmissionFilter {
+	return []SubmissionFilter{
+		&staleSubmissionFilter{api: api},
+		&slotAttributesFilter{api: api},
+	}
+}
+
+// defaultSubmissionFilters are the built-in filters, which check their feature flags on each submission
+func (api *RelayAPI) defaultSubmissionFilters() []SubmissionFilter {
+	return []SubmissionFilter{
+		&loadSheddingFilter{api: api},
+		&minBidFilter{api: api},
+		&blockPolicyFilter{api: api},
+		&blocklistFilter{api: api},
+		&inclusionConstraintsFilter{api: api},
+	}
+}
+
+// filterSubmission applies one phase of the filters, and returns the rejection of the first filter failing
+func (api *RelayAPI) filterSubmission(s *FilteredSubmission, filters []SubmissionFilter, apply func(SubmissionFilter) error) *submissionError {
+	for _, filter := range filters {
+		err := apply(filter)
+		if err == nil {
+			continue
+		}
+		var rejection *submissionError
+		if !errors.As(err, &rejection) {
+			rejection = newSubmissionError(http.StatusBadRequest, SubmissionErrFiltered, "%s: %s", filter.Name(), err.Error())
+		}
+		s.Log.WithFields(logrus.Fields{
+			"filter":    filter.Name(),
+			"errorCode": rejection.code,
+		}).Info("rejecting submission - " + rejection.msg)
+		return rejection
+	}
+	return nil
+}
+
+func (api *RelayAPI) filterSubmissionBeforeSimulation(ctx context.Context, s *FilteredSubmission) *submissionError {
+	return api.filterSubmission(s, api.submissionFilters, func(filter SubmissionFilter) error {
+		return filter.BeforeSimulation(ctx, s)
+	})
+}
+
+// filterSubmissionAfterSimulation applies the pre-simulation filters too, they may check if the submission is still
+// valid after the simulation
+func (api *RelayAPI) filterSubmissionAfterSimulation(ctx context.Context, s *FilteredSubmission, simResult *BlockSimulationResult) *submissionError {
+	apply := func(filter SubmissionFilter) error {
+		return filter.AfterSimulation(ctx, s, simResult)
+	}
+	if rejection := api.filterSubmission(s, api.preSimFilters, apply); rejection != nil {
+		return rejection
+	}
+	return api.filterSubmission(s, api.submissionFilters, apply)
+}
+
+// minBidFilter rejects the bids below the minimum, which are never returned by getHeader, so they aren't simulated
+type minBidFilter struct {
+	NoopSubmissionFilter
+	api *RelayAPI
+}
+
+func (f *minBidFilter) Name() string {
+	return "min-bid"
+}
+
+func (f *minBidFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	if !f.api.isBelowMinBid(s.Payload.Value()) {
+		return nil
+	}
+	if f.api.ffSaveBelowMinBids {
+		f.api.saveBlockSubmission(ctx, s.Log, s.Payload, nil, ErrBidBelowMinimum, s.ReceivedAt, false)
+	}
+	return newSubmissionError(http.StatusBadRequest, SubmissionErrBelowMinBid, "%s", ErrBidBelowMinimum.Error())
+}
+
+// blocklistFilter rejects the blocks involving blocklisted addresses, if enabled
+type blocklistFilter struct {
+	NoopSubmissionFilter
+	api *RelayAPI
+}
+
+func (f *blocklistFilter) Name() string {
+	return "blocklist"
+}
+
+func (f *blocklistFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	if !f.api.ffEnableBlocklist {
+		return nil
+	}
+	match, err := f.api.checkBlocklist(s.Payload)
+	if errors.Is(err, ErrBlocklistUnavailable) {
+		return newSubmissionError(http.StatusServiceUnavailable, SubmissionErrBlocklistUnavailable, "%s", err.Error())
+	} else if err != nil {
+		return newSubmissionError(http.StatusBadRequest, "", "%s", err.Error())
+	} else if match != nil {
+		log := s.Log.WithFields(logrus.Fields{
+			"blocklistedAddress": match.address,
+			"blocklistReason":    match.reason,
+		})
+		f.api.runInBackground(func() { f.api.saveBlocklistFiltered(log, s.Payload, match) })
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrBlocklisted, "blocklisted address %s (%s)", match.address, match.reason)
+	}
+	return nil
+}
+
+// inclusionConstraintsFilter rejects the blocks missing transactions the proposer requires, if enabled
+type inclusionConstraintsFilter struct {
+	NoopSubmissionFilter
+	api *RelayAPI
+}
+
+func (f *inclusionConstraintsFilter) Name() string {
+	return "inclusion-constraints"
+}
+
+func (f *inclusionConstraintsFilter) BeforeSimulation(ctx context.Context, s *FilteredSubmission) error {
+	if !f.api.ffInclusionConstraints {
+		return nil
+	}
+	missing, err := f.api.checkInclusionConstraints(s.Payload.Slot(), s.Payload.Transactions())
+	if err != nil {
+		s.Log.WithError(err).Error("could not get inclusion constraints")
+		return newSubmissionError(http.StatusServiceUnavailable, SubmissionErrInclusionConstraintsUnavailable, "inclusion constraints are unavailable")
+	} else if len(missing) > 0 {
+		return newSubmissionError(http.StatusBadRequest, SubmissionErrInclusionConstraints, "missing constrained transactions: %s", strings.Join(missing, ","))
+	}
+	return nil
+}
+
+// ensure the built-in filters implement the interface
+var (
+	_ SubmissionFilter = (*minBidFilter)(nil)
+	_ SubmissionFilter = (*blocklistFilter)(nil)
+	_ SubmissionFilter = (*inclusionConstraintsFilter)(nil)
+)
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/stretchr/testify/require"
+)
+
+type testSubmissionFilter struct {
+	NoopSubmissionFilter
+	name   string
+	calls  *[]string
+	reject error
+}
+
+func (f *testSubmissionFilter) Name() string {
+	return f.name
+}
+
+func (f *testSubmissionFilter) AfterSimulation(ctx context.Context, s *FilteredSubmission, simResult *BlockSimulationResult) error {
+	*f.calls = append(*f.calls, f.name)
+	return f.reject
+}
+
+func TestSubmissionFilters(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	calls := []string{}
+	backend.relay.submissionFilters = append(backend.relay.defaultSubmissionFilters(),
+		&testSubmissionFilter{name: "first", calls: &calls},
+		&testSubmissionFilter{name: "second", calls: &calls, reject: errors.New("builder cap reached")}, //nolint:goerr113
+		&testSubmissionFilter{name: "third", calls: &calls},
+	)
+	s := &FilteredSubmission{
+		Log:     common.TestLog,
+		Payload: &common.BuilderSubmitBlockRequest{Capella: testCapellaSubmission(1)},
+	}
+
+	t.Run("built-in filters", func(t *testing.T) {
+		require.Nil(t, backend.relay.filterSubmissionBeforeSimulation(context.Background(), s))
+
+		backend.relay.ffMinBidValue.Store(big.NewInt(2))
+		defer backend.relay.ffMinBidValue.Store(nil)
+		rejection := backend.relay.filterSubmissionBeforeSimulation(context.Background(), s)
+		require.NotNil(t, rejection)
+		require.Equal(t, http.StatusBadRequest, rejection.status)
+		require.Equal(t, SubmissionErrBelowMinBid, rejection.code)
+	})
+
+	t.Run("filters run in order until one rejects", func(t *testing.T) {
+		rejection := backend.relay.filterSubmissionAfterSimulation(context.Background(), s, &BlockSimulationResult{})
+		require.NotNil(t, rejection)
+		require.Equal(t, []string{"first", "second"}, calls)
+		require.Equal(t, http.StatusBadRequest, rejection.status)
+		require.Equal(t, SubmissionErrFiltered, rejection.code)
+		require.Equal(t, "second: builder cap reached", rejection.msg)
+	})
+	t.Run("stale after the simulation", func(t *testing.T) {
+		relay := newTestBackend(t, 1).relay
+		s := &FilteredSubmission{
+			Log:     common.TestLog,
+			Payload: &common.BuilderSubmitBlockRequest{Capella: testCapellaSubmission(1)},
+		}
+		require.Nil(t, relay.filterSubmissionAfterSimulation(context.Background(), s, &BlockSimulationResult{}))
+
+		// the payload of the slot was delivered during the simulation
+		require.NoError(t, relay.redis.SetStats(datastore.RedisStatsFieldSlotLastPayloadDelivered, s.Payload.Slot()))
+		rejection := relay.filterSubmissionAfterSimulation(context.Background(), s, &BlockSimulationResult{})
+		require.NotNil(t, rejection)
+		require.Equal(t, SubmissionErrSlotDelivered, rejection.code)
+
+		// optimistic submissions are bids already
+		s.Optimistic = true
+		require.Nil(t, relay.filterSubmissionAfterSimulation(context.Background(), s, &BlockSimulationResult{}))
+	})
+}
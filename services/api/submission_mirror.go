@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidMirrorURL = errors.New("invalid submission mirror URL")
+	ErrMirrorStatus     = errors.New("unexpected response status of the mirror relay")
+
+	// the block submissions with a valid signature are forwarded to the secondary relay, e.g. the standby of an
+	// active/standby pair or a shadow environment
+	mirrorSubmissionsURL       = os.Getenv("MIRROR_SUBMISSIONS_URL")
+	numSubmissionMirrorWorkers = cli.GetEnvInt("NUM_SUBMISSION_MIRROR_WORKERS", 4)
+	submissionMirrorQueueSize  = cli.GetEnvInt("SUBMISSION_MIRROR_QUEUE_SIZE", 1000)
+	submissionMirrorTimeout    = time.Duration(cli.GetEnvInt("SUBMISSION_MIRROR_TIMEOUT_MS", 2000)) * time.Millisecond
+)
+
+// mirroredSubmission is the body of a builder submission, along with its content type. No other headers are forwarded,
+// the credentials of the builder (X-Builder-Api-Key, Authorization) must not leave the relay.
+type mirroredSubmission struct {
+	log         *logrus.Entry
+	body        []byte
+	contentType string
+}
+
+// submissionMirror forwards the builder submissions to a secondary relay through a bounded queue, so the submissions
+// never wait for the mirror relay. Submissions which don't fit in the queue are dropped.
+type submissionMirror struct {
+	log     *logrus.Entry
+	url     string
+	client  http.Client
+	queue   chan *mirroredSubmission
+	workers sync.WaitGroup
+}
+
+// newSubmissionMirror returns the mirror posting to the block submission endpoint of the relay at relayURL
+func newSubmissionMirror(log *logrus.Entry, relayURL string, queueSize int, timeout time.Duration) (*submissionMirror, error) {
+	u, err := url.Parse(relayURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMirrorURL, relayURL)
+	}
+	return &submissionMirror{
+		log:    log.WithFields(logrus.Fields{"component": "submissionMirror", "mirrorHost": u.Host}),
+		url:    strings.TrimSuffix(u.String(), "/") + pathSubmitNewBlock,
+		client: http.Client{Timeout: timeout},
+		queue:  make(chan *mirroredSubmission, queueSize),
+	}, nil
+}
+
+// start starts the workers, which run until the queue is closed and drained
+func (m *submissionMirror) start(numWorkers int) {
+	m.log.Infof("starting %d submission mirror workers, queue size %d", numWorkers, cap(m.queue))
+	m.workers.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer m.workers.Done()
+			for submission := range m.queue {
+				if err := m.forward(submission); err != nil {
+					common.SubmissionMirrorTotal.WithLabelValues("failed").Inc()
+					submission.log.WithError(err).Warn("failed to mirror the submission")
+					continue
+				}
+				common.SubmissionMirrorTotal.WithLabelValues("forwarded").Inc()
+			}
+		}()
+	}
+}
+
+// enqueue queues a copy of the submission body without waiting, the body buffer of the request is reused
+func (m *submissionMirror) enqueue(log *logrus.Entry, req *http.Request, body []byte) {
+	submission := &mirroredSubmission{
+		log:         log,
+		body:        bytes.Clone(body),
+		contentType: req.Header.Get("Content-Type"),
+	}
+	select {
+	case m.queue <- submission:
+	default:
+		common.SubmissionMirrorTotal.WithLabelValues("dropped").Inc()
+		log.Warn("submission mirror queue full, dropped the submission")
+	}
+}
+
+func (m *submissionMirror) forward(submission *mirroredSubmission) error {
+	req, err := http.NewRequest(http.MethodPost, m.url, bytes.NewReader(submission.body))
+	if err != nil {
+		return err
+	}
+	contentType := submission.contentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	// the mirror relay may reject submissions on its own (e.g. a newer payload), which is logged but not a failure of the
+	// mirror
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %d", ErrMirrorStatus, resp.StatusCode)
+	} else if resp.StatusCode != http.StatusOK {
+		submission.log.WithField("statusCode", resp.StatusCode).Debug("mirror relay rejected the submission")
+	}
+	return nil
+}
+
+// stop closes the queue, once nothing sends to it anymore. The workers forward the remaining submissions.
+func (m *submissionMirror) stop() {
+	close(m.queue)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmissionMirror(t *testing.T) {
+	var lock sync.Mutex
+	var path, apiKey, authorization string
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		path = req.URL.Path
+		apiKey = req.Header.Get(HeaderBuilderAPIKey)
+		authorization = req.Header.Get("Authorization")
+		bodies = append(bodies, string(body))
+	}))
+	defer srv.Close()
+
+	_, err := newSubmissionMirror(common.TestLog, "localhost:9062", 1, time.Second)
+	require.ErrorIs(t, err, ErrInvalidMirrorURL)
+
+	m, err := newSubmissionMirror(common.TestLog, srv.URL+"/", 1, time.Second)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, pathSubmitNewBlock, nil)
+	req.Header.Set(HeaderBuilderAPIKey, "key")
+	req.Header.Set("Authorization", "Bearer token")
+	body := []byte(`{"message":{}}`)
+	m.enqueue(common.TestLog, req, body)
+	m.enqueue(common.TestLog, req, body) // queue full, dropped
+
+	// the body buffer of the request is reused after enqueueing
+	copy(body, "xxxx")
+
+	m.start(1)
+	m.stop()
+	m.workers.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, pathSubmitNewBlock, path)
+	// the credentials of the builder aren't forwarded
+	require.Empty(t, apiKey)
+	require.Empty(t, authorization)
+	require.Equal(t, []string{`{"message":{}}`}, bodies)
+}
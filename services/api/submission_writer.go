@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SubmissionOverflowDrop drops the submission rows which don't fit in the queue
+	SubmissionOverflowDrop = "drop"
+	// SubmissionOverflowSpill appends the submission rows which don't fit in the queue to the spill file, as JSON lines
+	SubmissionOverflowSpill = "spill"
+)
+
+var (
+	ErrInvalidSubmissionOverflowPolicy = errors.New("invalid submission overflow policy")
+	ErrMissingSubmissionSpillFile      = errors.New("submission spill file is required by the spill overflow policy")
+
+	// the builder_block_submissions rows are written by a pool of workers, so the submission requests don't wait for
+	// the database. Delivered payloads are still written synchronously.
+	numSubmissionWriters     = cli.GetEnvInt("NUM_DB_SUBMISSION_WRITERS", 10)
+	submissionQueueSize      = cli.GetEnvInt("DB_SUBMISSION_QUEUE_SIZE", 10_000)
+	submissionOverflowPolicy = common.GetEnv("DB_SUBMISSION_QUEUE_OVERFLOW", SubmissionOverflowDrop)
+	submissionSpillFile      = os.Getenv("DB_SUBMISSION_SPILL_FILE")
+)
+
+// blockSubmissionWrite is a builder submission waiting to be saved, along with its simulation result
+type blockSubmissionWrite struct {
+	log        *logrus.Entry
+	payload    *common.BuilderSubmitBlockRequest
+	simResult  *BlockSimulationResult
+	simErr     error
+	receivedAt time.Time
+	optimistic bool
+	replayed   bool // replayed from the spill file, after the submission was handled
+}
+
+// spilledBlockSubmission is the line written to the spill file for a submission which didn't fit in the queue, with
+// everything needed to save it later
+type spilledBlockSubmission struct {
+	ReceivedAt    time.Time                         `json:"received_at"`
+	SimError      string                            `json:"sim_error"`
+	FilterError   bool                              `json:"filter_error,omitempty"` // the sim error is a rejection by a filter
+	VerifiedValue string                            `json:"verified_value"`
+	Optimistic    bool                              `json:"optimistic"`
+	Payload       *common.BuilderSubmitBlockRequest `json:"payload"`
+}
+
+// submissionWriter is a bounded queue of builder submissions, saved to the database by a pool of workers. When the queue
+// is full, the submission is dropped or spilled to disk depending on the overflow policy.
+type submissionWriter struct {
+	log     *logrus.Entry
+	save    func(*blockSubmissionWrite)
+	queue   chan *blockSubmissionWrite
+	workers sync.WaitGroup
+
+	overflowPolicy string
+	spillFile      string
+	spillLock      sync.Mutex
+	network        string // label of the metrics
+}
+
+func newSubmissionWriter(log *logrus.Entry, network string, save func(*blockSubmissionWrite), queueSize int, overflowPolicy, spillFile string) (*submissionWriter, error) {
+	switch overflowPolicy {
+	case SubmissionOverflowDrop:
+	case SubmissionOverflowSpill:
+		if spillFile == "" {
+			return nil, ErrMissingSubmissionSpillFile
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSubmissionOverflowPolicy, overflowPolicy)
+	}
+
+	return &submissionWriter{
+		log:            log.WithField("component", "submissionWriter"),
+		save:           save,
+		queue:          make(chan *blockSubmissionWrite, queueSize),
+		overflowPolicy: overflowPolicy,
+		spillFile:      spillFile,
+		network:        network,
+	}, nil
+}
+
+// start starts the workers, which run until the queue is closed and drained
+func (w *submissionWriter) start(numWorkers int) {
+	w.log.Infof("starting %d submission writers, queue size %d, overflow policy %s", numWorkers, cap(w.queue), w.overflowPolicy)
+	w.workers.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer w.workers.Done()
+			for write := range w.queue {
+				common.DBSubmissionQueueLength.WithLabelValues(w.network).Set(float64(len(w.queue)))
+				w.save(write)
+			}
+		}()
+	}
+}
+
+// queueLength returns the number of submissions waiting to be saved
+func (w *submissionWriter) queueLength() int {
+	return len(w.queue)
+}
+
+// enqueue queues the submission without waiting, and applies the overflow policy if the queue is full
+func (w *submissionWriter) enqueue(write *blockSubmissionWrite) {
+	select {
+	case w.queue <- write:
+		common.DBSubmissionQueueLength.WithLabelValues(w.network).Set(float64(len(w.queue)))
+		return
+	default:
+	}
+
+	log := write.log.WithField("overflowPolicy", w.overflowPolicy)
+	if w.overflowPolicy == SubmissionOverflowSpill {
+		err := w.spill(write)
+		if err == nil {
+			common.DBSubmissionQueueOverflowTotal.WithLabelValues(w.network, SubmissionOverflowSpill).Inc()
+			log.Warn("submission queue full, spilled the submission to disk")
+			return
+		}
+		log = log.WithError(err)
+	}
+	common.DBSubmissionQueueOverflowTotal.WithLabelValues(w.network, SubmissionOverflowDrop).Inc()
+	log.Error("submission queue full, dropped the submission")
+}
+
+func (w *submissionWriter) spill(write *blockSubmissionWrite) error {
+	entry := spilledBlockSubmission{
+		ReceivedAt: write.receivedAt,
+		Optimistic: write.optimistic,
+		Payload:    write.payload,
+	}
+	if write.simErr != nil {
+		entry.SimError = write.simErr.Error()
+		entry.FilterError = isFilterRejection(write.simErr)
+	}
+	if write.simResult != nil {
+		entry.VerifiedValue = write.simResult.ProposerPayment
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	w.spillLock.Lock()
+	defer w.spillLock.Unlock()
+	f, err := os.OpenFile(w.spillFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replaySpill saves the submissions spilled by a previous run, and removes the spill file once they are saved. The file
+// is moved aside first, so submissions spilled in the meantime go to a new file and are replayed on the next start. A
+// moved file left by an interrupted replay is replayed again, the rows are saved at least once.
+func (w *submissionWriter) replaySpill() (numReplayed int, err error) {
+	if w.overflowPolicy != SubmissionOverflowSpill {
+		return 0, nil
+	}
+
+	replayFile := w.spillFile + ".replay"
+	if _, err := os.Stat(replayFile); errors.Is(err, os.ErrNotExist) {
+		w.spillLock.Lock()
+		err = os.Rename(w.spillFile, replayFile)
+		w.spillLock.Unlock()
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(replayFile)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// the lines are as long as the submissions, so they're read without the line limit of a bufio.Scanner
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			entry := new(spilledBlockSubmission)
+			if err := json.Unmarshal(line, entry); err != nil || entry.Payload == nil || entry.Payload.Message() == nil {
+				// e.g. the last line of a run which was killed while spilling
+				w.log.WithError(err).Error("skipping invalid line of the submission spill file")
+			} else {
+				w.save(entry.blockSubmissionWrite(w.log))
+				numReplayed++
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return numReplayed, err
+		}
+	}
+	return numReplayed, os.Remove(replayFile)
+}
+
+func (e *spilledBlockSubmission) blockSubmissionWrite(log *logrus.Entry) *blockSubmissionWrite {
+	write := &blockSubmissionWrite{
+		log:        log.WithFields(logrus.Fields{"slot": e.Payload.Slot(), "blockHash": e.Payload.BlockHash()}),
+		payload:    e.Payload,
+		receivedAt: e.ReceivedAt,
+		optimistic: e.Optimistic,
+		replayed:   true,
+	}
+	if e.VerifiedValue != "" {
+		write.simResult = &BlockSimulationResult{ProposerPayment: e.VerifiedValue}
+	}
+	if e.FilterError {
+		write.simErr = &submissionError{msg: e.SimError}
+	} else if e.SimError != "" {
+		write.simErr = errors.New(e.SimError) //nolint:goerr113
+	}
+	return write
+}
+
+// stop closes the queue, once nothing sends to it anymore. The workers save the remaining submissions.
+func (w *submissionWriter) stop() {
+	close(w.queue)
+}
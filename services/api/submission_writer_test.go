@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func testCapellaSubmission(slot uint64) *capella.SubmitBlockRequest {
+	return &capella.SubmitBlockRequest{
+		Message: &apiv1.BidTrace{Slot: slot, Value: uint256.NewInt(1)},
+		ExecutionPayload: &consensuscapella.ExecutionPayload{
+			ExtraData:    []byte{},
+			Transactions: []bellatrix.Transaction{},
+			Withdrawals:  []*consensuscapella.Withdrawal{},
+		},
+	}
+}
+
+func newTestBlockSubmissionWrite(slot uint64) *blockSubmissionWrite {
+	return &blockSubmissionWrite{
+		log:        common.TestLog,
+		payload:    &common.BuilderSubmitBlockRequest{Capella: testCapellaSubmission(slot)},
+		simResult:  &BlockSimulationResult{ProposerPayment: "123"},
+		simErr:     errors.New("simulation failed"), //nolint:goerr113
+		receivedAt: time.Unix(1, 0).UTC(),
+	}
+}
+
+func TestSubmissionWriterInvalidPolicy(t *testing.T) {
+	save := func(*blockSubmissionWrite) {}
+	_, err := newSubmissionWriter(common.TestLog, "", save, 1, "foo", "")
+	require.ErrorIs(t, err, ErrInvalidSubmissionOverflowPolicy)
+	_, err = newSubmissionWriter(common.TestLog, "", save, 1, SubmissionOverflowSpill, "")
+	require.ErrorIs(t, err, ErrMissingSubmissionSpillFile)
+}
+
+func TestSubmissionWriterDrainsQueueOnStop(t *testing.T) {
+	var lock sync.Mutex
+	saved := []uint64{}
+	w, err := newSubmissionWriter(common.TestLog, "", func(write *blockSubmissionWrite) {
+		lock.Lock()
+		defer lock.Unlock()
+		saved = append(saved, write.payload.Slot())
+	}, 10, SubmissionOverflowDrop, "")
+	require.NoError(t, err)
+
+	for slot := uint64(1); slot <= 5; slot++ {
+		w.enqueue(newTestBlockSubmissionWrite(slot))
+	}
+	w.start(2)
+	w.stop()
+	w.workers.Wait()
+	require.ElementsMatch(t, []uint64{1, 2, 3, 4, 5}, saved)
+}
+
+func TestSubmissionWriterOverflow(t *testing.T) {
+	save := func(*blockSubmissionWrite) {}
+
+	t.Run("drop", func(t *testing.T) {
+		w, err := newSubmissionWriter(common.TestLog, "", save, 1, SubmissionOverflowDrop, "")
+		require.NoError(t, err)
+		w.enqueue(newTestBlockSubmissionWrite(1))
+		w.enqueue(newTestBlockSubmissionWrite(2))
+		require.Len(t, w.queue, 1)
+	})
+
+	t.Run("spill", func(t *testing.T) {
+		spillFile := filepath.Join(t.TempDir(), "submissions.jsonl")
+		w, err := newSubmissionWriter(common.TestLog, "", save, 1, SubmissionOverflowSpill, spillFile)
+		require.NoError(t, err)
+		for slot := uint64(1); slot <= 3; slot++ {
+			w.enqueue(newTestBlockSubmissionWrite(slot))
+		}
+		require.Len(t, w.queue, 1)
+
+		f, err := os.Open(spillFile)
+		require.NoError(t, err)
+		defer f.Close()
+		slots := []uint64{}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			entry := new(spilledBlockSubmission)
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), entry))
+			require.Equal(t, "simulation failed", entry.SimError)
+			require.Equal(t, "123", entry.VerifiedValue)
+			require.Equal(t, time.Unix(1, 0).UTC(), entry.ReceivedAt)
+			slots = append(slots, entry.Payload.Slot())
+		}
+		require.NoError(t, scanner.Err())
+		require.Equal(t, []uint64{2, 3}, slots)
+	})
+}
+
+func TestSubmissionWriterReplaySpill(t *testing.T) {
+	spillFile := filepath.Join(t.TempDir(), "submissions.jsonl")
+	var lock sync.Mutex
+	saved := []*blockSubmissionWrite{}
+	save := func(write *blockSubmissionWrite) {
+		lock.Lock()
+		defer lock.Unlock()
+		saved = append(saved, write)
+	}
+
+	// nothing to replay
+	w, err := newSubmissionWriter(common.TestLog, "", save, 1, SubmissionOverflowSpill, spillFile)
+	require.NoError(t, err)
+	numReplayed, err := w.replaySpill()
+	require.NoError(t, err)
+	require.Equal(t, 0, numReplayed)
+
+	for slot := uint64(1); slot <= 3; slot++ {
+		w.enqueue(newTestBlockSubmissionWrite(slot))
+	}
+	filtered := newTestBlockSubmissionWrite(4)
+	filtered.simErr = newSubmissionError(http.StatusBadRequest, ErrorCodeSubmissionNotAccepted, "rejected by a filter")
+	w.enqueue(filtered)
+
+	// a line cut off while spilling is skipped
+	f, err := os.OpenFile(spillFile, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"received_at":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// the submissions spilled by the previous run are saved by the next one
+	w, err = newSubmissionWriter(common.TestLog, "", save, 1, SubmissionOverflowSpill, spillFile)
+	require.NoError(t, err)
+	numReplayed, err = w.replaySpill()
+	require.NoError(t, err)
+	require.Equal(t, 3, numReplayed)
+	require.Len(t, saved, 3)
+	for i, write := range saved {
+		require.Equal(t, uint64(i+2), write.payload.Slot())
+		require.True(t, write.replayed)
+		require.Equal(t, time.Unix(1, 0).UTC(), write.receivedAt)
+		require.Equal(t, "123", write.simResult.ProposerPayment)
+	}
+	require.False(t, isFilterRejection(saved[0].simErr))
+	require.EqualError(t, saved[0].simErr, "simulation failed")
+	require.True(t, isFilterRejection(saved[2].simErr))
+
+	// and only once
+	_, err = os.Stat(spillFile)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(spillFile + ".replay")
+	require.ErrorIs(t, err, os.ErrNotExist)
+	numReplayed, err = w.replaySpill()
+	require.NoError(t, err)
+	require.Equal(t, 0, numReplayed)
+}
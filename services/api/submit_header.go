@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/attestantio/go-builder-client/api/capella"
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-builder-client/spec"
+	consensusspec "github.com/attestantio/go-eth2-client/spec"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDeferredPayloadFetch    = errors.New("failed to fetch deferred payload from builder")
+	ErrDeferredPayloadMismatch = errors.New("deferred payload does not match the bid")
+	ErrHeaderBidBeforeCapella  = errors.New("header-only submissions are only supported from capella")
+	ErrHeaderBidHashMismatch   = errors.New("block hash or parent hash does not match the header")
+	ErrHeaderBidGasMismatch    = errors.New("gas limit or gas used does not match the header")
+	ErrHeaderBidZeroValue      = errors.New("bid with 0 value")
+
+	deferredPayloadTimeout = time.Duration(cli.GetEnvInt("DEFERRED_PAYLOAD_TIMEOUT_MS", 1000)) * time.Millisecond
+)
+
+// handleSubmitNewHeader accepts a header-only (v3) submission. The payload is fetched from the builder when the bid wins getPayload.
+// Since the block can't be simulated upfront, only builders in optimistic mode whose collateral covers the bid may submit headers.
+func (api *RelayAPI) handleSubmitNewHeader(w http.ResponseWriter, req *http.Request) {
+	receivedAt := time.Now().UTC()
+	log := api.requestLogger(req).WithFields(logrus.Fields{
+		"method":        "submitNewHeader",
+		"contentLength": req.ContentLength,
+	})
+
+	payload := new(common.SubmitHeaderRequest)
+	if err := json.NewDecoder(req.Body).Decode(payload); isBodyTooLarge(err) {
+		log.WithError(err).Warn("header submission too large")
+		api.RespondErrorWithCode(w, http.StatusRequestEntityTooLarge, ErrorCodeRequestTooLarge, "request body too large")
+		return
+	} else if err != nil {
+		log.WithError(err).Warn("could not decode payload")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if payload.Message == nil || payload.ExecutionPayloadHeader == nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "missing parts of the payload")
+		return
+	}
+
+	bid := payload.Message
+	header := payload.ExecutionPayloadHeader
+	log = log.WithFields(logrus.Fields{
+		"slot":          bid.Slot,
+		"builderPubkey": bid.BuilderPubkey.String(),
+		"blockHash":     bid.BlockHash.String(),
+	})
+
+	if err := api.checkHeaderBid(bid, header); err != nil {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, headerBidErrorCode(err), err.Error())
+		return
+	}
+
+	if err := api.checkBuilderAPIKey(req, bid.BuilderPubkey.String()); err != nil {
+		log.WithError(err).Info("builder api key check failed")
+		api.respondBuilderAPIKeyError(w, err)
+		return
+	}
+
+	payloadURL, err := url.Parse(payload.PayloadURL)
+	if err != nil || (payloadURL.Scheme != "http" && payloadURL.Scheme != "https") || payloadURL.Host == "" {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "invalid payload_url")
+		return
+	}
+
+	// prev_randao and withdrawals have to be known already, as there is no simulation to wait for
+	preSimErr := api.filterSubmissionPreSim(&FilteredSubmission{Log: log, preSim: headerPreSimSubmission(bid, header)})
+	if preSimErr != nil {
+		api.respondSubmissionError(w, preSimErr)
+		return
+	}
+
+	builderIsHighPrio, builderIsBlacklisted, err := api.getBlockBuilderStatus(bid.BuilderPubkey.String())
+	if err != nil {
+		log.WithError(err).Error("could not get block builder status")
+	}
+	if builderIsBlacklisted {
+		log.Info("builder is blacklisted")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// the transactions of header-only submissions are unknown, so they can't be checked against the blocklist
+	if api.ffEnableBlocklist {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeSubmissionNotAccepted, "header-only submissions are not accepted with the blocklist enabled")
+		return
+	}
+
+	// the transactions of header-only submissions are unknown, so they can't be checked against the inclusion constraints
+	if api.ffInclusionConstraints {
+		hasConstraints, err := api.hasInclusionConstraints(bid.Slot)
+		if err != nil {
+			log.WithError(err).Error("could not get inclusion constraints")
+			api.RespondErrorWithCode(w, http.StatusServiceUnavailable, SubmissionErrInclusionConstraintsUnavailable, "inclusion constraints are unavailable")
+			return
+		} else if hasConstraints {
+			api.RespondErrorWithCode(w, http.StatusBadRequest, SubmissionErrInclusionConstraints, "header-only submissions are not accepted for slots with inclusion constraints")
+			return
+		}
+	}
+
+	if !api.ffEnableOptimistic.Load() || !builderIsHighPrio || !api.isCoveredByCollateral(log, bid.BuilderPubkey.String(), bid.Value.ToBig()) {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeSubmissionNotAccepted, "header-only submissions require optimistic mode with sufficient collateral")
+		return
+	}
+
+	if api.builderSubmissionLimitReached(log, bid.Slot, bid.BuilderPubkey.String()) {
+		api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeTooManySubmissions, "too many submissions for this slot")
+		return
+	}
+
+	ok, err := api.verifyBuilderSignature(bid, bid.BuilderPubkey[:], payload.Signature[:])
+	if !ok || err != nil {
+		log.WithError(err).Warn("could not verify builder signature")
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeInvalidSignature, "invalid signature")
+		return
+	}
+
+	// Only submissions signed by the builder count towards its per-slot limit
+	if !api.allowBuilderSubmission(log, bid.Slot, bid.BuilderPubkey.String()) {
+		api.RespondErrorWithCode(w, http.StatusTooManyRequests, ErrorCodeTooManySubmissions, "too many submissions for this slot")
+		return
+	}
+
+	// Ensure this request is still the latest one
+	latestPayloadReceivedAt, err := api.redis.GetBuilderLatestPayloadReceivedAt(bid.Slot, bid.BuilderPubkey.String(), bid.ParentHash.String(), bid.ProposerPubkey.String())
+	if err != nil {
+		log.WithError(err).Error("failed getting latest payload receivedAt from redis")
+	} else if receivedAt.UnixMilli() < latestPayloadReceivedAt {
+		api.RespondErrorWithCode(w, http.StatusBadRequest, ErrorCodeNewerSubmission, "already using a newer payload")
+		return
+	}
+
+	err = api.saveHeaderBid(bid, header, payloadURL.String(), receivedAt)
+	if err != nil {
+		log.WithError(err).Error("could not save header bid")
+		api.RespondErrorWithCode(w, http.StatusInternalServerError, ErrorCodeRedis, "could not save header bid")
+		return
+	}
+
+	api.publishEvent(common.EventSubmissionAccepted, &common.BidTraceV2{BidTrace: *bid, BlockNumber: header.BlockNumber})
+
+	log.WithFields(logrus.Fields{
+		"proposerPubkey": bid.ProposerPubkey.String(),
+		"value":          bid.Value.String(),
+	}).Info("received header from builder")
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkHeaderBid checks that a bid without payload is for a supported fork, and matches its header
+func (api *RelayAPI) checkHeaderBid(bid *apiv1.BidTrace, header *consensuscapella.ExecutionPayloadHeader) error {
+	if !api.isCapella(bid.Slot) {
+		return ErrHeaderBidBeforeCapella
+	} else if api.isDeneb(bid.Slot) {
+		return ErrDenebNotSupported
+	}
+
+	// The header has to match the signed bid
+	if bid.BlockHash != header.BlockHash || bid.ParentHash != header.ParentHash {
+		return ErrHeaderBidHashMismatch
+	} else if bid.GasLimit != header.GasLimit || bid.GasUsed != header.GasUsed {
+		return ErrHeaderBidGasMismatch
+	}
+
+	if bid.Value == nil || bid.Value.IsZero() {
+		return ErrHeaderBidZeroValue
+	}
+	return nil
+}
+
+func headerPreSimSubmission(bid *apiv1.BidTrace, header *consensuscapella.ExecutionPayloadHeader) *preSimSubmission {
+	return &preSimSubmission{
+		slot:                 bid.Slot,
+		parentHash:           bid.ParentHash.String(),
+		timestamp:            header.Timestamp,
+		prevRandao:           fmt.Sprintf("%#x", header.PrevRandao),
+		proposerFeeRecipient: bid.ProposerFeeRecipient.String(),
+		withdrawalsRoot:      &header.WithdrawalsRoot,
+	}
+}
+
+// saveHeaderBid signs the bid for getHeader and saves it like the bid of a full submission, with the URL its payload is
+// fetched from once it wins instead of the payload
+func (api *RelayAPI) saveHeaderBid(bid *apiv1.BidTrace, header *consensuscapella.ExecutionPayloadHeader, payloadURL string, receivedAt time.Time) error {
+	builderBid := capella.BuilderBid{
+		Value:  bid.Value,
+		Header: header,
+		Pubkey: phase0.BLSPubKey(*api.publicKey),
+	}
+	sig, err := boostTypes.SignMessage(&builderBid, api.opts.EthNetDetails.DomainBuilder, api.blsSk)
+	if err != nil {
+		return err
+	}
+	getHeaderResponse := &common.GetHeaderResponse{
+		Capella: &spec.VersionedSignedBuilderBid{
+			Version: consensusspec.DataVersionCapella,
+			Capella: &capella.SignedBuilderBid{
+				Message:   &builderBid,
+				Signature: phase0.BLSSignature(sig),
+			},
+		},
+	}
+
+	bidTrace := common.BidTraceV2{
+		BidTrace:    *bid,
+		BlockNumber: header.BlockNumber,
+	}
+
+	if err := api.redis.SaveDeferredPayloadHeader(bid.Slot, bid.ProposerPubkey.String(), bid.BlockHash.String(), header); err != nil {
+		return err
+	}
+	if err := api.redis.SaveDeferredPayloadURL(bid.Slot, bid.ProposerPubkey.String(), bid.BlockHash.String(), payloadURL); err != nil {
+		return err
+	}
+	if err := api.redis.SaveBidTrace(&bidTrace); err != nil {
+		return err
+	}
+	if err := api.redis.SaveLatestBuilderBid(bid.Slot, bid.BuilderPubkey.String(), bid.ParentHash.String(), bid.ProposerPubkey.String(), receivedAt, getHeaderResponse); err != nil {
+		return err
+	}
+	topBid, err := api.redis.UpdateTopBid(bid.Slot, bid.ParentHash.String(), bid.ProposerPubkey.String())
+	if err != nil {
+		return err
+	}
+	api.publishEvent(common.EventNewTopBid, topBid)
+	api.recordTopBid(api.log.WithField("slot", bid.Slot), topBid)
+	api.slots.bidAccepted(api.log.WithField("slot", bid.Slot), &bidTrace)
+	return nil
+}
+
+// fetchDeferredPayload requests the payload of a winning header-only submission from the builder, or of a peer bid from the
+// peer relay. On failure the builder is demoted, unless the payload was held by a peer relay.
+func (api *RelayAPI) fetchDeferredPayload(log *logrus.Entry, signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock, proposerPubkey, payloadURL string) (*common.VersionedExecutionPayload, error) {
+	log = log.WithField("payloadURL", payloadURL)
+	header, err := api.redis.GetDeferredPayloadHeader(signedBlindedBeaconBlock.Slot(), proposerPubkey, signedBlindedBeaconBlock.BlockHash())
+	if err != nil {
+		log.WithError(err).Error("failed getting deferred payload header from redis")
+		return nil, err
+	} else if header == nil && signedBlindedBeaconBlock.Capella != nil {
+		// bids saved before the header was stored along with the payload URL
+		header = signedBlindedBeaconBlock.Capella.Message.Body.ExecutionPayloadHeader
+	}
+
+	resp, err := api.requestDeferredPayload(signedBlindedBeaconBlock, payloadURL, header)
+	if err == nil {
+		log.Info("fetched deferred payload from builder")
+		return resp, nil
+	}
+
+	// the payloads of peer bids are held by the peer relay, not the builder
+	peerRelayPubkey, err2 := api.redis.GetPeerBidRelay(signedBlindedBeaconBlock.Slot(), proposerPubkey, signedBlindedBeaconBlock.BlockHash())
+	if err2 != nil {
+		log.WithError(err2).Error("could not get peer bid relay")
+	} else if peerRelayPubkey != "" {
+		log.WithError(err).WithField("peerRelayPubkey", peerRelayPubkey).Error("failed to fetch deferred payload from peer relay")
+		return nil, err
+	}
+
+	log.WithError(err).Error("failed to fetch deferred payload, demoting builder")
+	api.runInBackground(func() {
+		bidTrace, err2 := api.redis.GetBidTrace(signedBlindedBeaconBlock.Slot(), proposerPubkey, signedBlindedBeaconBlock.BlockHash())
+		if err2 != nil || bidTrace == nil {
+			log.WithError(err2).Error("could not get bid trace to demote builder")
+			return
+		}
+		api.demoteBuilder(log, bidTrace, err)
+	})
+	return nil, err
+}
+
+// requestDeferredPayload posts the signed blinded block to the builder and validates that the header of the returned
+// payload is the one of the bid, so that all of its fields (not only the block hash) match what was signed
+func (api *RelayAPI) requestDeferredPayload(signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock, payloadURL string, header *consensuscapella.ExecutionPayloadHeader) (*common.VersionedExecutionPayload, error) {
+	reqBody, err := json.Marshal(signedBlindedBeaconBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.Client{Timeout: deferredPayloadTimeout}
+	resp, err := client.Post(payloadURL, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeferredPayloadFetch, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status code %d", ErrDeferredPayloadFetch, resp.StatusCode)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeferredPayloadFetch, err.Error())
+	}
+
+	executionPayload := new(common.VersionedExecutionPayload)
+	if err := json.Unmarshal(respBody, executionPayload); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeferredPayloadFetch, err.Error())
+	}
+
+	if executionPayload.Capella == nil || executionPayload.Capella.Capella == nil {
+		return nil, fmt.Errorf("%w: not a capella payload", ErrDeferredPayloadMismatch)
+	} else if !strings.EqualFold(executionPayload.Capella.Capella.BlockHash.String(), signedBlindedBeaconBlock.BlockHash()) {
+		return nil, fmt.Errorf("%w: got block hash %s", ErrDeferredPayloadMismatch, executionPayload.Capella.Capella.BlockHash.String())
+	}
+	if err := checkDeferredPayloadHeader(executionPayload.Capella.Capella, header); err != nil {
+		return nil, err
+	}
+	return executionPayload, nil
+}
+
+// checkDeferredPayloadHeader returns ErrDeferredPayloadMismatch unless the header derived from the payload is the expected one
+func checkDeferredPayloadHeader(payload *consensuscapella.ExecutionPayload, header *consensuscapella.ExecutionPayloadHeader) error {
+	if header == nil {
+		return fmt.Errorf("%w: unknown header", ErrDeferredPayloadMismatch)
+	}
+	payloadHeader, err := CapellaPayloadToPayloadHeader(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDeferredPayloadMismatch, err.Error())
+	}
+	payloadHeaderRoot, err := payloadHeader.HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDeferredPayloadMismatch, err.Error())
+	}
+	headerRoot, err := header.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	if payloadHeaderRoot != headerRoot {
+		return fmt.Errorf("%w: header root %#x, expected %#x", ErrDeferredPayloadMismatch, payloadHeaderRoot, headerRoot)
+	}
+	return nil
+}
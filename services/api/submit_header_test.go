@@ -0,0 +1,41 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDeferredPayloadHeader(t *testing.T) {
+	payload := &capella.ExecutionPayload{
+		ParentHash:    [32]byte{0x01},
+		FeeRecipient:  bellatrix.ExecutionAddress{0x02},
+		BlockNumber:   5001,
+		GasLimit:      30_000_000,
+		GasUsed:       5003,
+		BlockHash:     [32]byte{0x09},
+		Transactions:  []bellatrix.Transaction{{0x01, 0x02}},
+		Withdrawals:   []*capella.Withdrawal{{Index: 1, Amount: 100}},
+		ExtraData:     []byte{},
+		BaseFeePerGas: [32]byte{0x07},
+	}
+	header, err := CapellaPayloadToPayloadHeader(payload)
+	require.NoError(t, err)
+	require.NoError(t, checkDeferredPayloadHeader(payload, header))
+	require.ErrorIs(t, checkDeferredPayloadHeader(payload, nil), ErrDeferredPayloadMismatch)
+
+	// a payload with the same block hash but other transactions, fee recipient or withdrawals doesn't match
+	otherTxs := *payload
+	otherTxs.Transactions = []bellatrix.Transaction{{0x03}}
+	require.ErrorIs(t, checkDeferredPayloadHeader(&otherTxs, header), ErrDeferredPayloadMismatch)
+
+	otherFeeRecipient := *payload
+	otherFeeRecipient.FeeRecipient = bellatrix.ExecutionAddress{0x03}
+	require.ErrorIs(t, checkDeferredPayloadHeader(&otherFeeRecipient, header), ErrDeferredPayloadMismatch)
+
+	otherWithdrawals := *payload
+	otherWithdrawals.Withdrawals = nil
+	require.ErrorIs(t, checkDeferredPayloadHeader(&otherWithdrawals, header), ErrDeferredPayloadMismatch)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	ErrTLSWithoutCert      = errors.New("tls key and client CA require a tls certificate")
+	ErrTLSWithoutKey       = errors.New("tls certificate requires a tls key")
+	ErrInvalidTLSClientCA  = errors.New("no certificates found in the tls client CA file")
+	ErrClientCertMismatch  = errors.New("client certificate does not match the builder")
+	errMissingClientCertCN = errors.New("client certificate without common name")
+)
+
+// TLSOpts enable TLS on the API and admin listeners, instead of terminating it in a proxy in front of the relay
+type TLSOpts struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile enables mTLS for builders. Client certificates issued by these CAs authenticate the builder whose
+	// pubkey is the subject common name, instead of its API key. Clients without a certificate are still accepted.
+	ClientCAFile string
+}
+
+func (o TLSOpts) Enabled() bool {
+	return o.CertFile != ""
+}
+
+// serverConfig loads the certificates, nil if TLS isn't enabled
+func (o TLSOpts) serverConfig() (*tls.Config, error) {
+	if !o.Enabled() {
+		if o.KeyFile != "" || o.ClientCAFile != "" {
+			return nil, ErrTLSWithoutCert
+		}
+		return nil, nil //nolint:nilnil
+	} else if o.KeyFile == "" {
+		return nil, ErrTLSWithoutKey
+	}
+
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(o.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, ErrInvalidTLSClientCA
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// clientCertBuilderPubkey returns the builder pubkey of the verified client certificate of the request, an empty
+// string if the request has none
+func clientCertBuilderPubkey(req *http.Request) (string, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return "", nil
+	}
+	cn := req.TLS.VerifiedChains[0][0].Subject.CommonName
+	if cn == "" {
+		return "", errMissingClientCertCN
+	}
+	return strings.ToLower(cn), nil
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSOptsServerConfig(t *testing.T) {
+	config, err := TLSOpts{}.serverConfig()
+	require.NoError(t, err)
+	require.Nil(t, config)
+
+	_, err = TLSOpts{ClientCAFile: "ca.pem"}.serverConfig()
+	require.ErrorIs(t, err, ErrTLSWithoutCert)
+	_, err = TLSOpts{CertFile: "cert.pem"}.serverConfig()
+	require.ErrorIs(t, err, ErrTLSWithoutKey)
+}
+
+func TestCheckBuilderClientCert(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	builderPubkey := "0xb1"
+
+	newRequest := func(commonName string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, pathSubmitNewBlock, nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}} //nolint:exhaustruct
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return req
+	}
+
+	// the certificate replaces the API key of the builder
+	apiKey, err := generateAPIKey()
+	require.NoError(t, err)
+	err = backend.redis.SetBlockBuilderAPIKeyHash(builderPubkey, hashAPIKey(apiKey))
+	require.NoError(t, err)
+	require.NoError(t, backend.relay.checkBuilderAPIKey(newRequest("0xB1"), builderPubkey))
+
+	// but can't be used for other builders
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(newRequest("0xb2"), builderPubkey), ErrClientCertMismatch)
+	require.Error(t, backend.relay.checkBuilderAPIKey(newRequest(""), builderPubkey))
+
+	// unverified certificates are ignored
+	req := newRequest(builderPubkey)
+	req.TLS.VerifiedChains = nil
+	require.ErrorIs(t, backend.relay.checkBuilderAPIKey(req, builderPubkey), ErrMissingAPIKey)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// comma separated list of <token>:<builderPubkey> pairs, which are allowed to subscribe to the top-bid stream
+var topBidStreamTokens = parseBuilderTokens(common.GetEnv("TOPBID_STREAM_TOKENS", ""))
+
+// TopBidStreamEvent is sent to a builder whenever the top bid changes
+type TopBidStreamEvent struct {
+	Slot           uint64 `json:"slot,string"`
+	ParentHash     string `json:"parent_hash"`
+	ProposerPubkey string `json:"proposer_pubkey"`
+	Value          string `json:"value"`
+	IsOwn          bool   `json:"is_own"`
+}
+
+// parseBuilderTokens parses "<token>:<builderPubkey>,..." into a map of token to lowercase builder pubkey
+func parseBuilderTokens(s string) map[string]string {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		token, builderPubkey, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || token == "" || builderPubkey == "" {
+			continue
+		}
+		tokens[token] = strings.ToLower(builderPubkey)
+	}
+	return tokens
+}
+
+// handleBuilderTopBidStream streams top bid updates as server-sent events to an authenticated builder
+func (api *RelayAPI) handleBuilderTopBidStream(w http.ResponseWriter, req *http.Request) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	builderPubkey, ok := topBidStreamTokens[token]
+	if token == "" || !ok {
+		api.RespondErrorWithCode(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "invalid or missing auth token")
+		return
+	}
+
+	log := api.log.WithFields(logrus.Fields{
+		"method":        "topBidStream",
+		"builderPubkey": builderPubkey,
+	})
+
+	rc, ok := api.startEventStream(w, log)
+	if !ok {
+		return
+	}
+
+	updates := api.topBidStream.subscribe()
+	defer api.topBidStream.unsubscribe(updates)
+	log.WithField("numSubscribers", api.topBidStream.numSubscribers()).Info("builder subscribed to top bid stream")
+
+	keepalive := time.NewTicker(eventStreamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			log.Info("builder unsubscribed from top bid stream")
+			return
+		case <-api.shutdownCtx.Done():
+			log.Info("closing top bid stream, shutting down")
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case update := <-updates:
+			event, err := json.Marshal(TopBidStreamEvent{
+				Slot:           update.Slot,
+				ParentHash:     update.ParentHash,
+				ProposerPubkey: update.ProposerPubkey,
+				Value:          update.Value,
+				IsOwn:          strings.EqualFold(update.BuilderPubkey, builderPubkey),
+			})
+			if err != nil {
+				log.WithError(err).Error("could not marshal top bid event")
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: top_bid\ndata: %s\n\n", event); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBuilderTokens(t *testing.T) {
+	tokens := parseBuilderTokens("abc:0xB1, def:0xb2,invalid,:0xb3,ghi:")
+	require.Equal(t, map[string]string{
+		"abc": "0xb1",
+		"def": "0xb2",
+	}, tokens)
+
+	require.Empty(t, parseBuilderTokens(""))
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := newBroadcaster[*datastore.TopBidUpdate]()
+	c1 := b.subscribe()
+	c2 := b.subscribe()
+	require.Equal(t, 2, b.numSubscribers())
+
+	update := &datastore.TopBidUpdate{Slot: 1, BuilderPubkey: "0xb1", Value: "100"}
+	b.broadcast(update)
+	require.Equal(t, update, <-c1)
+	require.Equal(t, update, <-c2)
+
+	// a full subscriber doesn't block the broadcast
+	b.unsubscribe(c2)
+	for i := 0; i < eventStreamSubscriberBuffer+1; i++ {
+		b.broadcast(update)
+	}
+	require.Len(t, c1, eventStreamSubscriberBuffer)
+	require.Equal(t, 1, b.numSubscribers())
+
+	// limited subscriptions are refused once the maximum is reached
+	c3, err := b.subscribeLimited("1.1.1.1", 2, 0)
+	require.NoError(t, err)
+	_, err = b.subscribeLimited("2.2.2.2", 2, 0)
+	require.ErrorIs(t, err, ErrTooManySubscribers)
+	b.unsubscribe(c3)
+	c3, err = b.subscribeLimited("1.1.1.1", 2, 0)
+	require.NoError(t, err)
+
+	// and once the key has the maximum, while other keys can still subscribe
+	_, err = b.subscribeLimited("1.1.1.1", 3, 1)
+	require.ErrorIs(t, err, ErrTooManySubscribersForKey)
+	c4, err := b.subscribeLimited("2.2.2.2", 3, 1)
+	require.NoError(t, err)
+	b.unsubscribe(c3)
+	b.unsubscribe(c3) // unsubscribing twice doesn't free another slot
+	require.Equal(t, map[string]int{"": 1, "2.2.2.2": 1}, b.numByKey)
+	_, err = b.subscribeLimited("1.1.1.1", 3, 1)
+	require.NoError(t, err)
+	b.unsubscribe(c4)
+}
@@ -24,8 +24,9 @@ var (
 )
 
 type HTTPErrorResp struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	ErrorCode string `json:"error_code,omitempty"`
 }
 
 var NilResponse = struct{}{}
@@ -57,6 +58,19 @@ func BuildGetHeaderResponse(payload *common.BuilderSubmitBlockRequest, sk *bls.S
 		}, nil
 	}
 
+	if payload.Deneb != nil {
+		signedBuilderBid, err := DenebBuilderSubmitBlockRequestToSignedBuilderBid(payload.Deneb, sk, (*phase0.BLSPubKey)(pubkey), domain)
+		if err != nil {
+			return nil, err
+		}
+		return &common.GetHeaderResponse{
+			Deneb: &common.DenebGetHeaderResponse{
+				Version: common.ForkVersionDeneb,
+				Data:    signedBuilderBid,
+			},
+		}, nil
+	}
+
 	if payload.Capella != nil {
 		signedBuilderBid, err := CapellaBuilderSubmitBlockRequestToSignedBuilderBid(payload.Capella, sk, (*phase0.BLSPubKey)(pubkey), domain)
 		if err != nil {
@@ -85,6 +99,18 @@ func BuildGetPayloadResponse(payload *common.BuilderSubmitBlockRequest) (*common
 		}, nil
 	}
 
+	if payload.Deneb != nil {
+		return &common.GetPayloadResponse{
+			Deneb: &common.DenebGetPayloadResponse{
+				Version: common.ForkVersionDeneb,
+				Data: &common.ExecutionPayloadAndBlobsBundle{
+					ExecutionPayload: payload.Deneb.ExecutionPayload,
+					BlobsBundle:      payload.Deneb.BlobsBundle,
+				},
+			},
+		}, nil
+	}
+
 	if payload.Capella != nil {
 		return &common.GetPayloadResponse{
 			Capella: &api.VersionedExecutionPayload{
@@ -145,6 +171,45 @@ func CapellaBuilderSubmitBlockRequestToSignedBuilderBid(req *capella.SubmitBlock
 	}, nil
 }
 
+func DenebBuilderSubmitBlockRequestToSignedBuilderBid(req *common.DenebSubmitBlockRequest, sk *bls.SecretKey, pubkey *phase0.BLSPubKey, domain boostTypes.Domain) (*common.DenebSignedBuilderBid, error) {
+	header, err := DenebPayloadToPayloadHeader(req.ExecutionPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	builderBid := common.DenebBuilderBid{
+		Header:             header,
+		BlobKZGCommitments: req.BlobsBundle.Commitments,
+		Value:              req.Message.Value,
+		Pubkey:             *pubkey,
+	}
+
+	sig, err := boostTypes.SignMessage(&builderBid, domain, sk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.DenebSignedBuilderBid{
+		Message:   &builderBid,
+		Signature: phase0.BLSSignature(sig),
+	}, nil
+}
+
+func DenebPayloadToPayloadHeader(p *common.DenebExecutionPayload) (*common.DenebExecutionPayloadHeader, error) {
+	if p == nil {
+		return nil, ErrEmptyPayload
+	}
+	header, err := CapellaPayloadToPayloadHeader(p.Capella)
+	if err != nil {
+		return nil, err
+	}
+	return &common.DenebExecutionPayloadHeader{
+		Capella:       header,
+		BlobGasUsed:   p.BlobGasUsed,
+		ExcessBlobGas: p.ExcessBlobGas,
+	}, nil
+}
+
 func CapellaPayloadToPayloadHeader(p *consensuscapella.ExecutionPayload) (*consensuscapella.ExecutionPayloadHeader, error) {
 	if p == nil {
 		return nil, ErrEmptyPayload
@@ -183,6 +248,30 @@ func CapellaPayloadToPayloadHeader(p *consensuscapella.ExecutionPayload) (*conse
 
 func SignedBlindedBeaconBlockToBeaconBlock(signedBlindedBeaconBlock *common.SignedBlindedBeaconBlock, executionPayload *common.VersionedExecutionPayload) *common.SignedBeaconBlock {
 	var signedBeaconBlock common.SignedBeaconBlock
+	if denebBlindedBlock := signedBlindedBeaconBlock.Deneb; denebBlindedBlock != nil {
+		if executionPayload.Deneb == nil || executionPayload.Deneb.Data == nil {
+			return &signedBeaconBlock
+		}
+		blobsBundle := executionPayload.Deneb.Data.BlobsBundle
+		signedBeaconBlock.Deneb = &common.DenebSignedBlockContents{
+			SignedBlock: &common.DenebSignedBeaconBlock{
+				Signature: denebBlindedBlock.Signature,
+				Message: &common.DenebBeaconBlock{
+					Slot:          denebBlindedBlock.Message.Slot,
+					ProposerIndex: denebBlindedBlock.Message.ProposerIndex,
+					ParentRoot:    denebBlindedBlock.Message.ParentRoot,
+					StateRoot:     denebBlindedBlock.Message.StateRoot,
+					Body: &common.DenebBeaconBlockBody{
+						Blinded:          denebBlindedBlock.Message.Body,
+						ExecutionPayload: executionPayload.Deneb.Data.ExecutionPayload,
+					},
+				},
+			},
+			KZGProofs: blobsBundle.Proofs,
+			Blobs:     blobsBundle.Blobs,
+		}
+		return &signedBeaconBlock
+	}
 	capellaBlindedBlock := signedBlindedBeaconBlock.Capella
 	bellatrixBlindedBlock := signedBlindedBeaconBlock.Bellatrix
 	if capellaBlindedBlock != nil {
@@ -237,6 +326,8 @@ func SignedBlindedBeaconBlockToBeaconBlock(signedBlindedBeaconBlock *common.Sign
 type BuilderBlockValidationRequest struct {
 	common.BuilderSubmitBlockRequest
 	RegisteredGasLimit uint64 `json:"registered_gas_limit,string"`
+	// ParentBeaconBlockRoot is the root deneb blocks commit to, nil before deneb
+	ParentBeaconBlockRoot *phase0.Root `json:"parent_beacon_block_root,omitempty"`
 }
 
 func (r *BuilderBlockValidationRequest) MarshalJSON() ([]byte, error) {
@@ -244,10 +335,16 @@ func (r *BuilderBlockValidationRequest) MarshalJSON() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	var parentBeaconBlockRoot string
+	if r.ParentBeaconBlockRoot != nil {
+		parentBeaconBlockRoot = r.ParentBeaconBlockRoot.String()
+	}
 	gasLimit, err := json.Marshal(&struct {
-		RegisteredGasLimit uint64 `json:"registered_gas_limit,string"`
+		RegisteredGasLimit    uint64 `json:"registered_gas_limit,string"`
+		ParentBeaconBlockRoot string `json:"parent_beacon_block_root,omitempty"`
 	}{
-		RegisteredGasLimit: r.RegisteredGasLimit,
+		RegisteredGasLimit:    r.RegisteredGasLimit,
+		ParentBeaconBlockRoot: parentBeaconBlockRoot,
 	})
 	if err != nil {
 		return nil, err
@@ -1,11 +1,18 @@
 package api
 
 import (
+	"encoding/json"
 	"testing"
 
+	apiv1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	consensuscapella "github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 )
 
@@ -59,3 +66,78 @@ func TestBuilderBlockRequestToSignedBuilderBid(t *testing.T) {
 	require.Equal(t, 0, signedBuilderBid.Message.Value.Cmp(&reqPayload.Message.Value))
 	require.Equal(t, reqPayload.Message.BlockHash, signedBuilderBid.Message.Header.BlockHash)
 }
+
+func TestDenebBuilderSubmitBlockRequest(t *testing.T) {
+	sk, blsPubkey, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	publicKey, err := types.BlsPublicKeyToPublicKey(blsPubkey)
+	require.NoError(t, err)
+
+	blobsBundle := &common.BlobsBundle{
+		Commitments: []common.KZGCommitment{{0x01}},
+		Proofs:      []common.KZGProof{{0x02}},
+		Blobs:       []hexutil.Bytes{make([]byte, common.BlobLength)},
+	}
+	payload := &common.BuilderSubmitBlockRequest{
+		Deneb: &common.DenebSubmitBlockRequest{
+			Message: &apiv1.BidTrace{Slot: 64, BlockHash: phase0.Hash32{0x09}, Value: uint256.NewInt(123)},
+			ExecutionPayload: &common.DenebExecutionPayload{
+				Capella: &consensuscapella.ExecutionPayload{
+					BlockHash:     phase0.Hash32{0x09},
+					ExtraData:     []byte{},
+					Transactions:  []bellatrix.Transaction{},
+					Withdrawals:   []*consensuscapella.Withdrawal{},
+					BaseFeePerGas: [32]byte{0x01},
+				},
+				BlobGasUsed:   131072,
+				ExcessBlobGas: 1,
+			},
+			BlobsBundle: blobsBundle,
+		},
+	}
+
+	t.Run("bid commits to the blobs", func(t *testing.T) {
+		getHeaderResponse, err := BuildGetHeaderResponse(payload, sk, &publicKey, builderSigningDomain)
+		require.NoError(t, err)
+		require.NotNil(t, getHeaderResponse.Deneb)
+		bid := getHeaderResponse.Deneb.Data
+		require.Equal(t, blobsBundle.Commitments, bid.Message.BlobKZGCommitments)
+		require.Equal(t, uint64(131072), bid.Message.Header.BlobGasUsed)
+		require.Equal(t, phase0.Hash32{0x09}, bid.Message.Header.Capella.BlockHash)
+
+		ok, err := types.VerifySignature(bid.Message, builderSigningDomain, publicKey[:], bid.Signature[:])
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		data, err := json.Marshal(getHeaderResponse)
+		require.NoError(t, err)
+		decoded := new(common.GetHeaderResponse)
+		require.NoError(t, json.Unmarshal(data, decoded))
+		require.NotNil(t, decoded.Deneb)
+		require.Equal(t, bid.Message.BlobKZGCommitments, decoded.Deneb.Data.Message.BlobKZGCommitments)
+	})
+
+	t.Run("payload includes the blobs bundle", func(t *testing.T) {
+		getPayloadResponse, err := BuildGetPayloadResponse(payload)
+		require.NoError(t, err)
+		require.NotNil(t, getPayloadResponse.Deneb)
+		require.Equal(t, common.ForkVersionDeneb, getPayloadResponse.Deneb.Version)
+		require.Equal(t, blobsBundle, getPayloadResponse.Deneb.Data.BlobsBundle)
+	})
+
+	t.Run("simulated with the parent beacon block root", func(t *testing.T) {
+		parentBeaconBlockRoot := phase0.Root{0x0a}
+		simRequest := newSimRequest(&BuilderBlockValidationRequest{
+			BuilderSubmitBlockRequest: *payload,
+			ParentBeaconBlockRoot:     &parentBeaconBlockRoot,
+		})
+		require.Equal(t, "flashbots_validateBuilderSubmissionV3", simRequest.Method)
+
+		data, err := json.Marshal(simRequest.Params[0])
+		require.NoError(t, err)
+		fields := make(map[string]json.RawMessage)
+		require.NoError(t, json.Unmarshal(data, &fields))
+		require.Contains(t, fields, "blobs_bundle")
+		require.Equal(t, `"`+parentBeaconBlockRoot.String()+`"`, string(fields["parent_beacon_block_root"]))
+	})
+}
@@ -1,19 +1,87 @@
 package api
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math/big"
+	"mime"
+	"net/url"
+	"strconv"
+	"time"
 
+	apiv1capella "github.com/attestantio/go-eth2-client/api/v1/capella"
 	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
 )
 
 var (
 	ErrBlockHashMismatch  = errors.New("blockHash mismatch")
 	ErrParentHashMismatch = errors.New("parentHash mismatch")
+
+	ErrInvalidRegistrationSignature = errors.New("failed to verify validator signature")
+	ErrUnknownValidator             = errors.New("not a known validator")
+	ErrValidatorNotServed           = errors.New("validator not served by this relay")
+
+	ErrInvalidSSZ = errors.New("invalid SSZ")
 )
 
+// sszSignedBlindedBeaconBlockOffset is the offset of the message of an SSZ-encoded signed blinded beacon block. The
+// message is variable-size, so it's stored after its 4-byte offset and the 96-byte signature.
+const sszSignedBlindedBeaconBlockOffset = 4 + 96
+
+// isSSZContentType returns whether a request body is SSZ-encoded
+func isSSZContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/octet-stream"
+}
+
+// sszSignedBlindedBeaconBlockSlot returns the slot of an SSZ-encoded signed blinded beacon block of any fork, the first
+// field of the message
+func sszSignedBlindedBeaconBlockSlot(data []byte) (uint64, error) {
+	if len(data) < sszSignedBlindedBeaconBlockOffset+8 {
+		return 0, fmt.Errorf("%w: too short", ErrInvalidSSZ)
+	}
+	if offset := binary.LittleEndian.Uint32(data[:4]); offset != sszSignedBlindedBeaconBlockOffset {
+		return 0, fmt.Errorf("%w: unexpected message offset %d", ErrInvalidSSZ, offset)
+	}
+	return binary.LittleEndian.Uint64(data[sszSignedBlindedBeaconBlockOffset:]), nil
+}
+
+// unmarshalCapellaSignedBlindedBeaconBlockSSZ decodes an SSZ-encoded capella signed blinded beacon block. Only the
+// message has an SSZ encoding in go-eth2-client, so the signed container is decoded here.
+func unmarshalCapellaSignedBlindedBeaconBlockSSZ(data []byte) (*apiv1capella.SignedBlindedBeaconBlock, error) {
+	if len(data) < sszSignedBlindedBeaconBlockOffset {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidSSZ)
+	}
+	if offset := binary.LittleEndian.Uint32(data[:4]); offset != sszSignedBlindedBeaconBlockOffset {
+		return nil, fmt.Errorf("%w: unexpected message offset %d", ErrInvalidSSZ, offset)
+	}
+
+	block := &apiv1capella.SignedBlindedBeaconBlock{Message: new(apiv1capella.BlindedBeaconBlock)}
+	copy(block.Signature[:], data[4:sszSignedBlindedBeaconBlockOffset])
+	if err := block.Message.UnmarshalSSZ(data[sszSignedBlindedBeaconBlockOffset:]); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// marshalCapellaSignedBlindedBeaconBlockSSZ is the SSZ encoding of a capella signed blinded beacon block, the
+// counterpart of unmarshalCapellaSignedBlindedBeaconBlockSSZ
+func marshalCapellaSignedBlindedBeaconBlockSSZ(block *apiv1capella.SignedBlindedBeaconBlock) ([]byte, error) {
+	message, err := block.Message.MarshalSSZ()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, sszSignedBlindedBeaconBlockOffset, sszSignedBlindedBeaconBlockOffset+len(message))
+	binary.LittleEndian.PutUint32(data[:4], sszSignedBlindedBeaconBlockOffset)
+	copy(data[4:], block.Signature[:])
+	return append(data, message...), nil
+}
+
 func SanityCheckBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest) error {
 	if payload.BlockHash() != payload.ExecutionPayloadBlockHash() {
 		return ErrBlockHashMismatch
@@ -23,6 +91,10 @@ func SanityCheckBuilderBlockSubmission(payload *common.BuilderSubmitBlockRequest
 		return ErrParentHashMismatch
 	}
 
+	if payload.Deneb != nil {
+		return payload.Deneb.BlobsBundle.Validate()
+	}
+
 	return nil
 }
 
@@ -31,7 +103,50 @@ func checkBLSPublicKeyHex(pkHex string) error {
 	return proposerPubkey.UnmarshalText([]byte(pkHex))
 }
 
+// parseRangeFilters parses the value_min, value_max (in wei), from_timestamp and to_timestamp (unix seconds) data API arguments
+func parseRangeFilters(args url.Values) (filters database.RangeFilters, err error) {
+	for _, arg := range []struct {
+		name  string
+		value *string
+	}{{"value_min", &filters.ValueMin}, {"value_max", &filters.ValueMax}} {
+		if args.Get(arg.name) == "" {
+			continue
+		}
+		value, ok := new(big.Int).SetString(args.Get(arg.name), 10)
+		if !ok || value.Sign() < 0 {
+			return filters, fmt.Errorf("invalid %s argument", arg.name)
+		}
+		*arg.value = value.String()
+	}
+
+	for _, arg := range []struct {
+		name  string
+		value *time.Time
+	}{{"from_timestamp", &filters.FromTime}, {"to_timestamp", &filters.ToTime}} {
+		if args.Get(arg.name) == "" {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(args.Get(arg.name), 10, 64)
+		if err != nil || timestamp < 0 {
+			return filters, fmt.Errorf("invalid %s argument", arg.name)
+		}
+		*arg.value = time.Unix(timestamp, 0).UTC()
+	}
+	return filters, nil
+}
+
 func ComputeWithdrawalsRoot(w []*capella.Withdrawal) (phase0.Root, error) {
 	withdrawals := capella.Withdrawals{Withdrawals: w}
 	return withdrawals.HashTreeRoot()
 }
+
+// verifyValidatorRegistration verifies the signature of a registration
+func verifyValidatorRegistration(reg *types.SignedValidatorRegistration, domain types.Domain) error {
+	ok, err := types.VerifySignature(reg.Message, domain, reg.Message.Pubkey[:], reg.Signature[:])
+	if err != nil {
+		return fmt.Errorf("error verifying registerValidator signature: %w", err)
+	} else if !ok {
+		return fmt.Errorf("%w for %s", ErrInvalidRegistrationSignature, reg.Message.Pubkey.String())
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+	"github.com/flashbots/mev-boost-relay/common"
+)
+
+// dependencyCheckInterval is how often redis and the database are checked, to notify the webhooks of outages
+var dependencyCheckInterval = time.Duration(cli.GetEnvInt("WEBHOOK_DEPENDENCY_CHECK_INTERVAL_SEC", 10)) * time.Second
+
+// notifyWebhooks notifies the webhooks of the operational event, if enabled
+func (api *RelayAPI) notifyWebhooks(event, text string, data any) {
+	if api.opts.Webhooks != nil {
+		api.opts.Webhooks.Notify(event, text, data)
+	}
+}
+
+// notifyPayloadDeliveryFailed notifies the webhooks of a signed blinded block whose payload couldn't be delivered, which
+// likely means a missed slot
+func (api *RelayAPI) notifyPayloadDeliveryFailed(payload *common.SignedBlindedBeaconBlock, proposerPubkey, reason string) {
+	api.notifyWebhooks(common.EventPayloadDeliveryFailed, fmt.Sprintf("relay: failed to deliver the payload of slot %d: %s", payload.Slot(), reason), map[string]string{
+		"slot":            fmt.Sprint(payload.Slot()),
+		"proposer_pubkey": proposerPubkey,
+		"block_hash":      payload.BlockHash(),
+		"reason":          reason,
+	})
+}
+
+// dependencyMonitor tracks whether the dependencies are available, to notify of the changes only
+type dependencyMonitor struct {
+	checks map[string]func(ctx context.Context) error
+	down   map[string]bool
+}
+
+func (api *RelayAPI) newDependencyMonitor() *dependencyMonitor {
+	return &dependencyMonitor{
+		checks: map[string]func(ctx context.Context) error{
+			"redis":    api.redis.Ping,
+			"database": api.db.Ping,
+		},
+		down: make(map[string]bool),
+	}
+}
+
+// check checks every dependency, and notifies the webhooks of the ones which became unavailable or recovered
+func (m *dependencyMonitor) check(api *RelayAPI) {
+	for name, check := range m.checks {
+		ctx, cancel := context.WithTimeout(api.shutdownCtx, healthCheckTimeout)
+		err := check(ctx)
+		cancel()
+		if api.shutdownCtx.Err() != nil {
+			return
+		}
+
+		log := api.log.WithField("dependency", name)
+		if err != nil && !m.down[name] {
+			m.down[name] = true
+			log.WithError(err).Error("dependency unavailable")
+			api.notifyWebhooks(common.EventDependencyDown, fmt.Sprintf("relay: %s unavailable: %s", name, err.Error()), map[string]string{"dependency": name, "error": err.Error()})
+		} else if err == nil && m.down[name] {
+			m.down[name] = false
+			log.Info("dependency recovered")
+			api.notifyWebhooks(common.EventDependencyRecovered, fmt.Sprintf("relay: %s recovered", name), map[string]string{"dependency": name})
+		}
+	}
+}
+
+// startDependencyMonitor checks the dependencies until the shutdown
+func (api *RelayAPI) startDependencyMonitor() {
+	monitor := api.newDependencyMonitor()
+	ticker := time.NewTicker(dependencyCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-api.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			monitor.check(api)
+		}
+	}
+}
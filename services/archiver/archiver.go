@@ -0,0 +1,317 @@
+// Package archiver contains the service continuously exporting the delivered payloads and builder submissions of the
+// database to external sinks
+package archiver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+)
+
+// The streams of entries the archiver exports, named like the data API exports
+const (
+	StreamPayloadDelivered     = "payload_delivered"
+	StreamBuilderBlockReceived = "builder_block_received"
+)
+
+var Streams = []string{StreamPayloadDelivered, StreamBuilderBlockReceived}
+
+var ErrUnknownStream = errors.New("unknown stream")
+
+// Defaults of the archiver options
+var (
+	DefaultPollInterval = common.DurationPerSlot
+	DefaultBatchSize    = uint64(10_000)
+	DefaultInsertLag    = 30 * time.Second
+	DefaultPublishWait  = 5 * time.Minute
+	DefaultSinkTimeout  = time.Minute
+)
+
+type ArchiverOpts struct {
+	Log   *logrus.Entry
+	DB    database.IDatabaseService
+	Sinks []Sink
+
+	// Streams exported to every sink, all if empty
+	Streams []string
+
+	// How often the tables are checked for new entries once the sinks caught up
+	PollInterval time.Duration
+
+	// Maximum number of entries per batch written to a sink
+	BatchSize uint64
+
+	// Builder submissions are only exported once they were inserted this long ago. The ids are assigned before the
+	// inserts commit, so a recent entry can still be followed by a lower id, which would be skipped.
+	InsertLag time.Duration
+
+	// Delivered payloads are only exported once their publish is confirmed, and are held back until the API recorded
+	// the publish status. Payloads whose status still isn't recorded this long after the insert, e.g. because the API
+	// stopped while publishing, are skipped as unconfirmed.
+	PublishWait time.Duration
+}
+
+// archivedPayload is a delivered payload as exported, the data API format with the id to deduplicate
+type archivedPayload struct {
+	ID uint64 `json:"id,string"`
+	common.BidTraceV2WithTimingJSON
+}
+
+// archivedSubmission is a builder submission as exported, the data API format with the id to deduplicate
+type archivedSubmission struct {
+	ID uint64 `json:"id,string"`
+	common.BidTraceV2WithTimestampJSON
+}
+
+// Archiver tails the tables for new entries and exports them to every sink in batches. The last exported entry of each
+// stream is checkpointed per sink in the database, so that a restarted archiver continues where it stopped and a sink
+// which is down catches up once it's back, without holding back the others. The entries are delivered at least once.
+type Archiver struct {
+	opts *ArchiverOpts
+	log  *logrus.Entry
+	db   database.IDatabaseService
+}
+
+func NewArchiver(opts *ArchiverOpts) (*Archiver, error) {
+	if len(opts.Streams) == 0 {
+		opts.Streams = Streams
+	}
+	for _, stream := range opts.Streams {
+		if stream != StreamPayloadDelivered && stream != StreamBuilderBlockReceived {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStream, stream)
+		}
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.InsertLag == 0 {
+		opts.InsertLag = DefaultInsertLag
+	}
+	if opts.PublishWait == 0 {
+		opts.PublishWait = DefaultPublishWait
+	}
+
+	return &Archiver{
+		opts: opts,
+		log:  opts.Log,
+		db:   opts.DB,
+	}, nil
+}
+
+// Run exports the new entries of every stream to every sink until the context ends
+func (a *Archiver) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, sink := range a.opts.Sinks {
+		for _, stream := range a.opts.Streams {
+			wg.Add(1)
+			go func(sink Sink, stream string) {
+				defer wg.Done()
+				a.tail(ctx, sink, stream)
+			}(sink, stream)
+		}
+	}
+	wg.Wait()
+}
+
+// tail exports the batches of the stream back to back while the sink is behind, and polls once it caught up
+func (a *Archiver) tail(ctx context.Context, sink Sink, stream string) {
+	log := a.log.WithFields(logrus.Fields{"sink": sink.Name(), "stream": stream})
+	log.Info("archiving")
+	for {
+		numEntries, err := a.exportNext(ctx, sink, stream)
+		if err != nil {
+			common.ArchiverErrorsTotal.WithLabelValues(sink.Name(), stream).Inc()
+			log.WithError(err).Error("failed to export the next batch")
+		}
+
+		wait := time.Duration(0)
+		if err != nil || uint64(numEntries) < a.opts.BatchSize {
+			wait = a.opts.PollInterval
+		}
+		select {
+		case <-ctx.Done():
+			log.Info("stopped archiving")
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// exportNext writes the batch of entries following the checkpoint of the sink, and moves the checkpoint past them.
+// Returns the number of entries exported.
+func (a *Archiver) exportNext(ctx context.Context, sink Sink, stream string) (int, error) {
+	afterID := uint64(0)
+	checkpoint, err := a.db.GetArchiverCheckpoint(sink.Name(), stream)
+	if err == nil {
+		afterID = checkpoint.LastID
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	batch, next, err := a.nextBatch(stream, afterID, time.Now())
+	if err != nil || next.LastID == afterID {
+		return 0, err
+	}
+	// the checkpoint also moves past the entries which aren't exported, without writing an empty batch
+	if len(batch.Entries) > 0 {
+		if err := sink.Write(ctx, batch); err != nil {
+			return 0, err
+		}
+	}
+	if err := a.db.SetArchiverCheckpoint(sink.Name(), stream, next.LastID, next.LastSlot); err != nil {
+		return 0, err
+	}
+
+	common.ArchiverEntriesTotal.WithLabelValues(sink.Name(), stream).Add(float64(len(batch.Entries)))
+	common.ArchiverCheckpointSlot.WithLabelValues(sink.Name(), stream).Set(float64(next.LastSlot))
+	a.log.WithFields(logrus.Fields{
+		"sink":       sink.Name(),
+		"stream":     stream,
+		"numEntries": len(batch.Entries),
+		"lastID":     next.LastID,
+		"lastSlot":   next.LastSlot,
+	}).Debug("exported batch")
+	return len(batch.Entries), nil
+}
+
+// nextBatch reads the entries of the stream following the id which are ready to be exported, and returns them with
+// the checkpoint following them. The checkpoint can be past the last entry of the batch, if the entries after it are
+// skipped.
+func (a *Archiver) nextBatch(stream string, afterID uint64, now time.Time) (*Batch, *database.ArchiverCheckpointEntry, error) {
+	batch := &Batch{Stream: stream}
+	next := &database.ArchiverCheckpointEntry{Stream: stream, LastID: afterID}
+	switch stream {
+	case StreamPayloadDelivered:
+		entries, err := a.db.GetDeliveredPayloadsAfterID(afterID, a.opts.BatchSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			status := a.publishStatus(entry, now)
+			if status == publishPending {
+				break
+			}
+			if status == publishConfirmed {
+				batch.add(uint64(entry.ID), entry.Slot, archivePayload(entry))
+			} else {
+				a.log.WithFields(logrus.Fields{
+					"slot":      entry.Slot,
+					"blockHash": entry.BlockHash,
+				}).Info("skipping delivered payload, its publish wasn't confirmed")
+			}
+			next.LastID, next.LastSlot = uint64(entry.ID), entry.Slot
+		}
+	case StreamBuilderBlockReceived:
+		entries, err := a.db.GetBuilderSubmissionsAfterID(afterID, now.Add(-a.opts.InsertLag), a.opts.BatchSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			batch.add(uint64(entry.ID), entry.Slot, archiveSubmission(entry))
+			next.LastID, next.LastSlot = uint64(entry.ID), entry.Slot
+		}
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownStream, stream)
+	}
+	return batch, next, nil
+}
+
+type publishStatus int
+
+const (
+	publishPending publishStatus = iota
+	publishConfirmed
+	publishUnconfirmed
+)
+
+// publishStatus returns whether the publish of the delivered payload was confirmed, or is still pending if the API
+// didn't record the status yet and the payload was delivered less than PublishWait ago
+func (a *Archiver) publishStatus(entry *database.DeliveredPayloadEntry, now time.Time) publishStatus {
+	switch {
+	case entry.PublishConfirmed.Valid && entry.PublishConfirmed.Bool:
+		return publishConfirmed
+	case !entry.PublishConfirmed.Valid && now.Sub(entry.InsertedAt) < a.opts.PublishWait:
+		return publishPending
+	}
+	return publishUnconfirmed
+}
+
+// Backfill exports the entries of the streams in the slot range (inclusive) to every sink again, e.g. to fill a gap
+// of a sink. Like when tailing, only the delivered payloads whose publish was confirmed are exported. The checkpoints
+// aren't changed. Returns the number of entries exported per sink.
+func (a *Archiver) Backfill(ctx context.Context, slotFrom, slotTo uint64) (numEntries int, err error) {
+	for _, stream := range a.opts.Streams {
+		log := a.log.WithFields(logrus.Fields{"stream": stream, "slotFrom": slotFrom, "slotTo": slotTo})
+		log.Info("backfilling")
+
+		batch := &Batch{Stream: stream}
+		flush := func() error {
+			if len(batch.Entries) == 0 {
+				return nil
+			}
+			for _, sink := range a.opts.Sinks {
+				if err := sink.Write(ctx, batch); err != nil {
+					common.ArchiverErrorsTotal.WithLabelValues(sink.Name(), stream).Inc()
+					return fmt.Errorf("%s: %w", sink.Name(), err)
+				}
+				common.ArchiverEntriesTotal.WithLabelValues(sink.Name(), stream).Add(float64(len(batch.Entries)))
+			}
+			numEntries += len(batch.Entries)
+			log.WithField("lastSlot", batch.MaxSlot).Info("backfilled batch")
+			batch = &Batch{Stream: stream}
+			return nil
+		}
+		add := func(id, slot uint64, entry any) error {
+			batch.add(id, slot, entry)
+			if uint64(len(batch.Entries)) < a.opts.BatchSize {
+				return nil
+			}
+			return flush()
+		}
+
+		if stream == StreamPayloadDelivered {
+			now := time.Now()
+			err = a.db.StreamDeliveredPayloads(ctx, slotFrom, slotTo, func(entry *database.DeliveredPayloadEntry) error {
+				if a.publishStatus(entry, now) != publishConfirmed {
+					return nil
+				}
+				return add(uint64(entry.ID), entry.Slot, archivePayload(entry))
+			})
+		} else {
+			err = a.db.StreamBuilderSubmissions(ctx, slotFrom, slotTo, func(entry *database.BuilderBlockSubmissionEntry) error {
+				return add(uint64(entry.ID), entry.Slot, archiveSubmission(entry))
+			})
+		}
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return numEntries, err
+		}
+	}
+	return numEntries, nil
+}
+
+func archivePayload(entry *database.DeliveredPayloadEntry) *archivedPayload {
+	return &archivedPayload{
+		ID:                       uint64(entry.ID),
+		BidTraceV2WithTimingJSON: database.DeliveredPayloadEntryToBidTraceV2WithTimingJSON(entry),
+	}
+}
+
+func archiveSubmission(entry *database.BuilderBlockSubmissionEntry) *archivedSubmission {
+	return &archivedSubmission{
+		ID:                          uint64(entry.ID),
+		BidTraceV2WithTimestampJSON: database.BuilderSubmissionEntryToBidTraceV2WithTimestampJSON(entry),
+	}
+}
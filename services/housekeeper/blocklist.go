@@ -0,0 +1,136 @@
+package housekeeper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+var ErrEmptyBlocklist = errors.New("blocklist source contains no addresses")
+
+// the blocklist is dropped if the source grows beyond this, rather than filling up redis
+const maxBlocklistSize = 10 << 20
+
+var listSourceHTTPClient = http.Client{Timeout: 30 * time.Second} //nolint:exhaustruct
+
+// periodicTaskUpdateBlocklist reloads the blocklist from its source into redis, where the API instances pick it up
+func (hk *Housekeeper) periodicTaskUpdateBlocklist() {
+	for {
+		hk.runJob("updateBlocklist", hk.updateBlocklist)
+		hk.sleep(hk.opts.BlocklistInterval)
+	}
+}
+
+// updateBlocklist keeps the previous blocklist if the source can't be loaded, so a broken source never disables filtering
+func (hk *Housekeeper) updateBlocklist() {
+	log := hk.log.WithField("blocklistSource", hk.opts.BlocklistSource)
+	addresses, err := loadBlocklist(hk.opts.BlocklistSource)
+	if err != nil {
+		log.WithError(err).Error("failed to load blocklist, keeping the previous one")
+		return
+	}
+
+	previous, err := hk.redis.GetBlocklist()
+	if err != nil {
+		log.WithError(err).Warn("failed to get the previous blocklist from redis")
+	}
+	err = hk.redis.SetBlocklist(addresses)
+	if err != nil {
+		log.WithError(err).Error("failed to save blocklist to redis")
+		return
+	}
+	hk.auditListChange(database.AdminAuditActionBlocklist, hk.opts.BlocklistSource, previous, addresses)
+	log.WithField("numAddresses", len(addresses)).Info("updated blocklist")
+}
+
+// loadBlocklist reads the blocklist from a http(s) URL or a file
+func loadBlocklist(source string) ([]string, error) {
+	data, err := readListSource(source, maxBlocklistSize)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlocklist(data)
+}
+
+// readListSource reads a list from a http(s) URL or a file, failing if it exceeds maxSize bytes
+func readListSource(source string, maxSize int) ([]byte, error) {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := listSourceHTTPClient.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxSize {
+		return nil, fmt.Errorf("list source exceeds %d bytes", maxSize)
+	}
+	return data, nil
+}
+
+// parseListEntries accepts either a JSON array of strings, or one entry per line with '#' starting a comment
+func parseListEntries(data []byte) ([]string, error) {
+	var entries []string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parseBlocklist accepts either a JSON array of addresses, or one address per line with '#' starting a comment.
+// Any invalid address fails the whole list.
+func parseBlocklist(data []byte) ([]string, error) {
+	entries, err := parseListEntries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.HasPrefix(entry, "0x") || !ethcommon.IsHexAddress(entry) {
+			return nil, fmt.Errorf("invalid address in blocklist: %q", entry)
+		}
+		addresses = append(addresses, strings.ToLower(entry))
+	}
+	if len(addresses) == 0 {
+		return nil, ErrEmptyBlocklist
+	}
+	return addresses, nil
+}
@@ -0,0 +1,87 @@
+package housekeeper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+// auditTestDB records the admin audit log entries
+type auditTestDB struct {
+	database.MockDB
+	entries []database.AdminAuditLogEntry
+}
+
+func (db *auditTestDB) InsertAdminAuditLog(entry database.AdminAuditLogEntry) error {
+	db.entries = append(db.entries, entry)
+	return nil
+}
+
+func TestParseBlocklist(t *testing.T) {
+	expected := []string{"0xaa00000000000000000000000000000000000001", "0xaa00000000000000000000000000000000000002"}
+
+	addresses, err := parseBlocklist([]byte(`
+		# sanctioned addresses
+		0xAA00000000000000000000000000000000000001
+		0xaa00000000000000000000000000000000000002 # comment
+	`))
+	require.NoError(t, err)
+	require.Equal(t, expected, addresses)
+
+	addresses, err = parseBlocklist([]byte(`["0xAA00000000000000000000000000000000000001", "0xaa00000000000000000000000000000000000002"]`))
+	require.NoError(t, err)
+	require.Equal(t, expected, addresses)
+
+	_, err = parseBlocklist([]byte("0xaa00000000000000000000000000000000000001\nnot-an-address"))
+	require.Error(t, err)
+
+	_, err = parseBlocklist([]byte("# nothing here"))
+	require.ErrorIs(t, err, ErrEmptyBlocklist)
+}
+
+func TestDiffList(t *testing.T) {
+	added, removed := diffList(map[string]bool{"0xaa": true, "0xbb": true}, []string{"0xcc", "0xaa"})
+	require.Equal(t, []string{"0xcc"}, added)
+	require.Equal(t, []string{"0xbb"}, removed)
+	require.Equal(t, "2 entries, added 0xcc, removed 0xbb", formatListChange(2, added, removed))
+
+	added, removed = diffList(map[string]bool{"0xaa": true}, []string{"0xaa"})
+	require.Empty(t, added)
+	require.Empty(t, removed)
+
+	// long lists are cut off
+	many := make([]string, maxAuditedListEntries+2)
+	for i := range many {
+		many[i] = "0x" + string(rune('a'+i))
+	}
+	require.Contains(t, formatListChange(len(many), many, nil), "and 2 more")
+}
+
+func TestBlocklistAudit(t *testing.T) {
+	hk, _ := newTestHousekeeper(t)
+	db := &auditTestDB{}
+	hk.db = db
+	hk.opts.BlocklistSource = filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist := func(content string) {
+		require.NoError(t, os.WriteFile(hk.opts.BlocklistSource, []byte(content), 0o600))
+	}
+
+	// the first load isn't audited, the previous list is unknown
+	writeBlocklist("0xaa00000000000000000000000000000000000001\n0xaa00000000000000000000000000000000000002")
+	hk.updateBlocklist()
+	require.Empty(t, db.entries)
+
+	// reloading the same list isn't audited either
+	hk.updateBlocklist()
+	require.Empty(t, db.entries)
+
+	writeBlocklist("0xaa00000000000000000000000000000000000001\n0xaa00000000000000000000000000000000000003")
+	hk.updateBlocklist()
+	require.Len(t, db.entries, 1)
+	require.Equal(t, database.AdminAuditActionBlocklist, db.entries[0].Action)
+	require.Equal(t, "2 entries", db.entries[0].OldValue)
+	require.Equal(t, "2 entries, added 0xaa00000000000000000000000000000000000003, removed 0xaa00000000000000000000000000000000000002", db.entries[0].NewValue)
+}
@@ -0,0 +1,123 @@
+package housekeeper
+
+import (
+	"math/big"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons recorded in the demotions when a builder is promoted again
+const (
+	PromotionReasonRefundConfirmed       = "refund_confirmed"
+	PromotionReasonSuccessfulSubmissions = "successful_submissions"
+	PromotionReasonManual                = "manual"
+)
+
+// BuilderPromotionPolicy decides when demoted builders get optimistic mode back without an operator
+type BuilderPromotionPolicy struct {
+	// Promote after this many successful non-optimistic submissions since the latest demotion, 0 to disable
+	MinSuccessfulSubmissions uint64
+
+	// Promote once the refunds owed for the demotions are confirmed
+	OnRefundConfirmed bool
+}
+
+func (p BuilderPromotionPolicy) Enabled() bool {
+	return p.MinSuccessfulSubmissions > 0 || p.OnRefundConfirmed
+}
+
+// promotionReason returns why the builder is promoted given its open demotions, or an empty string if it stays
+// demoted. Builders owing an unconfirmed refund are never promoted. numSuccessfulSubmissions is only called if needed.
+func (p BuilderPromotionPolicy) promotionReason(demotions []*database.BuilderDemotionEntry, numSuccessfulSubmissions func() (uint64, error)) (string, error) {
+	refundRequired := false
+	for _, demotion := range demotions {
+		if demotion.RefundRequired && !demotion.RefundConfirmed {
+			return "", nil
+		}
+		refundRequired = refundRequired || demotion.RefundRequired
+	}
+	if p.OnRefundConfirmed && refundRequired {
+		return PromotionReasonRefundConfirmed, nil
+	}
+
+	if p.MinSuccessfulSubmissions > 0 {
+		num, err := numSuccessfulSubmissions()
+		if err != nil {
+			return "", err
+		}
+		if num >= p.MinSuccessfulSubmissions {
+			return PromotionReasonSuccessfulSubmissions, nil
+		}
+	}
+	return "", nil
+}
+
+func (hk *Housekeeper) periodicTaskPromoteBuilders() {
+	for {
+		hk.sleep(hk.opts.BuilderPromotionInterval)
+		hk.runJob("promoteBuilders", hk.promoteBuilders)
+	}
+}
+
+// promoteBuilders applies the promotion policy to the builders with open demotions. Demotions of builders which were
+// promoted manually are closed as well. The builder status job pushes the promotions to redis.
+func (hk *Housekeeper) promoteBuilders() {
+	demotions, err := hk.db.GetOpenBuilderDemotions()
+	if err != nil {
+		hk.log.WithError(err).Error("failed to get open builder demotions")
+		return
+	}
+	if len(demotions) == 0 {
+		return
+	}
+
+	demotionsByBuilder := make(map[string][]*database.BuilderDemotionEntry)
+	for _, demotion := range demotions {
+		demotionsByBuilder[demotion.BuilderPubkey] = append(demotionsByBuilder[demotion.BuilderPubkey], demotion)
+	}
+
+	builders, err := hk.db.GetBlockBuilders()
+	if err != nil {
+		hk.log.WithError(err).Error("failed to get block builders from db")
+		return
+	}
+
+	for _, builder := range builders {
+		builderDemotions := demotionsByBuilder[builder.BuilderPubkey]
+		if len(builderDemotions) == 0 || builder.IsBlacklisted {
+			continue
+		}
+		log := hk.log.WithFields(logrus.Fields{
+			"builderPubkey": builder.BuilderPubkey,
+			"numDemotions":  len(builderDemotions),
+		})
+
+		var reason string
+		if builder.IsOptimistic {
+			reason = PromotionReasonManual
+		} else {
+			collateral, ok := new(big.Int).SetString(builder.Collateral, 10)
+			if !ok || collateral.Sign() <= 0 {
+				continue
+			}
+			latestDemotion := builderDemotions[len(builderDemotions)-1]
+			reason, err = hk.opts.BuilderPromotionPolicy.promotionReason(builderDemotions, func() (uint64, error) {
+				return hk.db.GetNumSuccessfulBuilderSubmissions(builder.BuilderPubkey, latestDemotion.InsertedAt)
+			})
+			if err != nil {
+				log.WithError(err).Error("failed to check the builder promotion policy")
+				continue
+			}
+			if reason == "" {
+				continue
+			}
+		}
+
+		if err := hk.db.PromoteBuilder(builder.BuilderPubkey, reason); err != nil {
+			log.WithError(err).Error("failed to promote builder")
+			continue
+		}
+		log.WithField("reason", reason).Info("promoted demoted builder")
+	}
+}
@@ -0,0 +1,40 @@
+package housekeeper
+
+import (
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderPromotionPolicy(t *testing.T) {
+	numSuccessful := func(num uint64) func() (uint64, error) {
+		return func() (uint64, error) { return num, nil }
+	}
+	noRefund := []*database.BuilderDemotionEntry{{}}
+	refundOwed := []*database.BuilderDemotionEntry{{}, {RefundRequired: true}}
+	refundPaid := []*database.BuilderDemotionEntry{{}, {RefundRequired: true, RefundConfirmed: true}}
+
+	testCases := []struct {
+		name       string
+		policy     BuilderPromotionPolicy
+		demotions  []*database.BuilderDemotionEntry
+		successful uint64
+		reason     string
+	}{
+		{"disabled", BuilderPromotionPolicy{}, refundPaid, 100, ""},
+		{"refund confirmed", BuilderPromotionPolicy{OnRefundConfirmed: true}, refundPaid, 0, PromotionReasonRefundConfirmed},
+		{"refund not confirmed", BuilderPromotionPolicy{OnRefundConfirmed: true, MinSuccessfulSubmissions: 1}, refundOwed, 100, ""},
+		{"no refund to confirm", BuilderPromotionPolicy{OnRefundConfirmed: true}, noRefund, 100, ""},
+		{"successful submissions", BuilderPromotionPolicy{MinSuccessfulSubmissions: 10}, noRefund, 10, PromotionReasonSuccessfulSubmissions},
+		{"successful submissions after refund", BuilderPromotionPolicy{MinSuccessfulSubmissions: 10}, refundPaid, 10, PromotionReasonSuccessfulSubmissions},
+		{"too few successful submissions", BuilderPromotionPolicy{MinSuccessfulSubmissions: 10}, noRefund, 9, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := tc.policy.promotionReason(tc.demotions, numSuccessful(tc.successful))
+			require.NoError(t, err)
+			require.Equal(t, tc.reason, reason)
+		})
+	}
+}
@@ -8,10 +8,13 @@
 package housekeeper
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/flashbots/go-boost-utils/types"
@@ -23,11 +26,59 @@ import (
 	uberatomic "go.uber.org/atomic"
 )
 
+// Default cadences of the periodic jobs
+var (
+	DefaultKnownValidatorsInterval     = common.DurationPerEpoch / 2
+	DefaultBuilderStatusInterval       = common.DurationPerSlot / 2
+	DefaultKnownValidatorsFullSync     = time.Hour
+	DefaultProposerDutiesIntervalSlots = uint64(common.SlotsPerEpoch / 2)
+	DefaultRedisGCInterval             = 10 * time.Minute
+	DefaultBlocklistInterval           = 10 * time.Minute
+	DefaultProposerAllowlistInterval   = time.Minute
+	DefaultBuilderPromotionInterval    = common.DurationPerEpoch
+	DefaultBuilderScoreboardInterval   = 10 * time.Minute
+	DefaultStatsViewsInterval          = common.DurationPerEpoch
+	DefaultJitter                      = 0.1
+)
+
+// redis garbage collection only deletes keys of slots this many epochs behind the head, well past their TTLs
+const redisGCEpochMargin = 2
+
 type HousekeeperOpts struct {
 	Log          *logrus.Entry
 	Redis        *datastore.RedisCache
 	DB           database.IDatabaseService
 	BeaconClient beaconclient.IMultiBeaconClient
+
+	// Cadences of the periodic jobs, the defaults are used for zero values
+	KnownValidatorsInterval     time.Duration
+	KnownValidatorsFullSync     time.Duration // in between, only validators with new indices are fetched
+	BuilderStatusInterval       time.Duration
+	ProposerDutiesIntervalSlots uint64
+	RedisGCInterval             time.Duration
+	BlocklistInterval           time.Duration
+	ProposerAllowlistInterval   time.Duration
+	BuilderPromotionInterval    time.Duration
+	BuilderScoreboardInterval   time.Duration
+	StatsViewsInterval          time.Duration
+
+	// File or http(s) URL of the address blocklist. Optional, the blocklist is only loaded if set.
+	BlocklistSource string
+
+	// File or http(s) URL of the validator pubkeys the relay serves, or ProposerAllowlistSourceDB for the
+	// proposer_allowlist table. Optional, the allowlist is only loaded if set.
+	ProposerAllowlistSource string
+
+	// Policy re-enabling optimistic mode for demoted builders, demotions are only lifted manually if disabled
+	BuilderPromotionPolicy BuilderPromotionPolicy
+
+	// Every wait between two runs of a periodic job is extended by a random duration of up to this fraction of its
+	// interval, so that relays sharing a beacon node don't run their heavy fetches at the same time
+	Jitter float64
+
+	// With leader election, only the instance holding the leader lock in redis runs the jobs
+	LeaderElection bool
+	LeaderLockTTL  time.Duration
 }
 
 type Housekeeper struct {
@@ -39,30 +90,81 @@ type Housekeeper struct {
 	beaconClient beaconclient.IMultiBeaconClient
 
 	isStarted                uberatomic.Bool
+	isStopping               uberatomic.Bool
+	stopC                    chan struct{}
 	isUpdatingProposerDuties uberatomic.Bool
 	proposerDutiesSlot       uint64
 
 	headSlot uberatomic.Uint64
 
-	proposersAlreadySaved map[string]bool // to avoid repeating redis writes
+	instanceID          string
+	isLeader            uberatomic.Bool
+	leaderTerm          uberatomic.Uint64 // incremented every time this instance becomes leader
+	leaderLockRenewedAt time.Time
+	leaderElection      sync.WaitGroup // the leader election loop, until stopped
+
+	// the jobs running, which are waited for on shutdown. jobsLock orders starting jobs and stopping.
+	jobsLock     sync.Mutex
+	jobsInFlight sync.WaitGroup
+
+	// known validators as saved in redis, so that only changes are written
+	knownValidators             map[types.PubkeyHex]uint64
+	knownValidatorsMaxIndex     uint64
+	knownValidatorsLastFullSync time.Time
+	knownValidatorsTerm         uint64
 }
 
 var ErrServerAlreadyStarted = errors.New("server was already started")
 
 func NewHousekeeper(opts *HousekeeperOpts) *Housekeeper {
+	if opts.KnownValidatorsInterval == 0 {
+		opts.KnownValidatorsInterval = DefaultKnownValidatorsInterval
+	}
+	if opts.KnownValidatorsFullSync == 0 {
+		opts.KnownValidatorsFullSync = DefaultKnownValidatorsFullSync
+	}
+	if opts.BuilderStatusInterval == 0 {
+		opts.BuilderStatusInterval = DefaultBuilderStatusInterval
+	}
+	if opts.ProposerDutiesIntervalSlots == 0 {
+		opts.ProposerDutiesIntervalSlots = DefaultProposerDutiesIntervalSlots
+	}
+	if opts.RedisGCInterval == 0 {
+		opts.RedisGCInterval = DefaultRedisGCInterval
+	}
+	if opts.BlocklistInterval == 0 {
+		opts.BlocklistInterval = DefaultBlocklistInterval
+	}
+	if opts.ProposerAllowlistInterval == 0 {
+		opts.ProposerAllowlistInterval = DefaultProposerAllowlistInterval
+	}
+	if opts.BuilderPromotionInterval == 0 {
+		opts.BuilderPromotionInterval = DefaultBuilderPromotionInterval
+	}
+	if opts.BuilderScoreboardInterval == 0 {
+		opts.BuilderScoreboardInterval = DefaultBuilderScoreboardInterval
+	}
+	if opts.StatsViewsInterval == 0 {
+		opts.StatsViewsInterval = DefaultStatsViewsInterval
+	}
+	if opts.LeaderLockTTL == 0 {
+		opts.LeaderLockTTL = DefaultLeaderLockTTL
+	}
+
 	server := &Housekeeper{
-		opts:                  opts,
-		log:                   opts.Log,
-		redis:                 opts.Redis,
-		db:                    opts.DB,
-		beaconClient:          opts.BeaconClient,
-		proposersAlreadySaved: make(map[string]bool),
+		opts:         opts,
+		log:          opts.Log,
+		redis:        opts.Redis,
+		db:           opts.DB,
+		beaconClient: opts.BeaconClient,
+		instanceID:   newInstanceID(),
+		stopC:        make(chan struct{}),
 	}
 
 	return server
 }
 
-// Start starts the housekeeper service, blocking
+// Start starts the housekeeper service, blocking until Stop is called
 func (hk *Housekeeper) Start() (err error) {
 	defer hk.isStarted.Store(false)
 	if hk.isStarted.Swap(true) {
@@ -75,14 +177,34 @@ func (hk *Housekeeper) Start() (err error) {
 		return err
 	}
 
-	// Start initial tasks
-	go hk.updateBlockBuildersInRedis()
-	go hk.updateValidatorRegistrationsInRedis()
+	// Start the initial tasks once leader
+	if hk.opts.LeaderElection {
+		hk.log.WithField("instanceID", hk.instanceID).Info("leader election enabled")
+		hk.updateLeadership()
+		hk.leaderElection.Add(1)
+		go hk.runLeaderElection()
+	} else {
+		hk.isLeader.Store(true)
+		hk.onBecomeLeader()
+	}
 
 	// Start the periodic task loops
 	go hk.periodicTaskUpdateKnownValidators()
 	go hk.periodicTaskLogValidators()
 	go hk.periodicTaskUpdateBuilderStatusInRedis()
+	go hk.periodicTaskRefreshStatsViews()
+	go hk.periodicTaskUpdateDailyAggregates()
+	go hk.periodicTaskUpdateBuilderScoreboard()
+	go hk.periodicTaskCollectRedisGarbage()
+	if hk.opts.BlocklistSource != "" {
+		go hk.periodicTaskUpdateBlocklist()
+	}
+	if hk.opts.ProposerAllowlistSource != "" {
+		go hk.periodicTaskUpdateProposerAllowlist()
+	}
+	if hk.opts.BuilderPromotionPolicy.Enabled() {
+		go hk.periodicTaskPromoteBuilders()
+	}
 
 	// Process the current slot
 	headSlot := bestSyncStatus.HeadSlot
@@ -92,49 +214,192 @@ func (hk *Housekeeper) Start() (err error) {
 	c := make(chan beaconclient.HeadEventData)
 	hk.beaconClient.SubscribeToHeadEvents(c)
 	for {
-		headEvent := <-c
-		hk.processNewSlot(headEvent.Slot)
+		select {
+		case headEvent := <-c:
+			hk.processNewSlot(headEvent.Slot)
+		case <-hk.stopC:
+			return nil
+		}
+	}
+}
+
+// sleep waits for the interval of a periodic job, plus jitter
+func (hk *Housekeeper) sleep(interval time.Duration) {
+	time.Sleep(withJitter(interval, hk.opts.Jitter))
+}
+
+// withJitter returns the interval extended by a random duration of up to jitter * interval
+func withJitter(interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return interval
 	}
+	return interval + time.Duration(rand.Float64()*jitter*float64(interval)) //nolint:gosec
+}
+
+// runJob runs a job and logs how long it took. Standby instances skip the job, and no jobs are started once stopping.
+func (hk *Housekeeper) runJob(name string, job func()) {
+	hk.jobsLock.Lock()
+	if hk.isStopping.Load() {
+		hk.jobsLock.Unlock()
+		return
+	} else if !hk.isLeader.Load() {
+		hk.jobsLock.Unlock()
+		hk.log.WithField("job", name).Debug("not leader, skipping housekeeper job")
+		return
+	}
+	hk.jobsInFlight.Add(1)
+	hk.jobsLock.Unlock()
+	defer hk.jobsInFlight.Done()
+
+	timeStarted := time.Now()
+	job()
+	duration := time.Since(timeStarted)
+	common.HousekeeperJobDuration.WithLabelValues(name).Observe(duration.Seconds())
+	hk.log.WithFields(logrus.Fields{
+		"job":         name,
+		"durationSec": duration.Seconds(),
+	}).Info("housekeeper job done")
 }
 
 func (hk *Housekeeper) periodicTaskLogValidators() {
 	for {
-		numRegisteredValidators, err := hk.db.NumRegisteredValidators()
-		if err == nil {
-			hk.log.WithField("numRegisteredValidators", numRegisteredValidators).Infof("registered validators: %d", numRegisteredValidators)
-		} else {
-			hk.log.WithError(err).Error("failed to get number of registered validators")
-		}
+		hk.runJob("logValidators", hk.logValidators)
+		hk.sleep(common.DurationPerEpoch / 2)
+	}
+}
 
-		activeValidators, err := hk.redis.GetActiveValidators()
-		if err == nil {
-			hk.log.WithField("numActiveValidators", len(activeValidators)).Infof("active validators: %d", len(activeValidators))
-		} else {
-			hk.log.WithError(err).Error("failed to get number of active validators")
-		}
+func (hk *Housekeeper) logValidators() {
+	numRegisteredValidators, err := hk.db.NumRegisteredValidators()
+	if err == nil {
+		hk.log.WithField("numRegisteredValidators", numRegisteredValidators).Infof("registered validators: %d", numRegisteredValidators)
+	} else {
+		hk.log.WithError(err).Error("failed to get number of registered validators")
+	}
 
-		time.Sleep(common.DurationPerEpoch / 2)
+	activeValidators, err := hk.redis.GetActiveValidators()
+	if err == nil {
+		hk.log.WithField("numActiveValidators", len(activeValidators)).Infof("active validators: %d", len(activeValidators))
+	} else {
+		hk.log.WithError(err).Error("failed to get number of active validators")
 	}
 }
 
 func (hk *Housekeeper) periodicTaskUpdateKnownValidators() {
 	for {
-		hk.log.Debug("periodicTaskUpdateKnownValidators start")
-		hk.updateKnownValidators()
-		hk.log.Debug("periodicTaskUpdateKnownValidators done")
-
-		// Wait half an epoch
-		time.Sleep(common.DurationPerEpoch / 2)
+		hk.runJob("updateKnownValidators", hk.updateKnownValidators)
+		hk.sleep(hk.opts.KnownValidatorsInterval)
 	}
 }
 
 func (hk *Housekeeper) periodicTaskUpdateBuilderStatusInRedis() {
 	for {
-		// builders, err := hk.da
-		time.Sleep(common.DurationPerEpoch / 2)
+		hk.sleep(hk.opts.BuilderStatusInterval)
+		hk.runJob("updateBuilderStatusInRedis", hk.updateBuilderStatusInRedis)
 	}
 }
 
+// updateBuilderStatusInRedis keeps the builder status and optimistic mode in sync with the database, which is the source
+// of truth for demotions and manual status changes. Changed statuses are pushed to the API instances.
+func (hk *Housekeeper) updateBuilderStatusInRedis() {
+	builders, err := hk.db.GetBlockBuilders()
+	if err != nil {
+		hk.log.WithError(err).Error("failed to get block builders from db")
+		return
+	}
+
+	statuses := make(map[string]datastore.BlockBuilderStatus, len(builders))
+	for _, builder := range builders {
+		statuses[builder.BuilderPubkey] = datastore.MakeBlockBuilderStatus(builder.IsHighPrio, builder.IsBlacklisted)
+		hk.updateBuilderCollateralInRedis(builder)
+	}
+	changed, err := hk.redis.UpdateBlockBuilderStatuses(statuses)
+	if err != nil {
+		hk.log.WithError(err).Error("failed to update block builder statuses in redis")
+	}
+	for _, builderPubkey := range changed {
+		hk.log.WithFields(logrus.Fields{
+			"builderPubkey": builderPubkey,
+			"status":        statuses[builderPubkey],
+		}).Info("updated block builder status from the database")
+	}
+}
+
+// periodicTaskRefreshStatsViews recomputes the aggregate stats served by the data API and the website, which read the
+// views instead of aggregating the large tables on every request
+func (hk *Housekeeper) periodicTaskRefreshStatsViews() {
+	for {
+		hk.runJob("refreshStatsViews", func() {
+			if err := hk.db.RefreshStatsViews(); err != nil {
+				hk.log.WithError(err).Error("failed to refresh stats views")
+			}
+		})
+		hk.sleep(hk.opts.StatsViewsInterval)
+	}
+}
+
+// periodicTaskUpdateDailyAggregates aggregates the completed days for the website charts. Runs hourly, so a day is
+// aggregated soon after midnight (UTC).
+func (hk *Housekeeper) periodicTaskUpdateDailyAggregates() {
+	for {
+		hk.runJob("updateDailyAggregates", func() {
+			if err := hk.db.UpdateDailyAggregates(); err != nil {
+				hk.log.WithError(err).Error("failed to update daily aggregates")
+			}
+		})
+		hk.sleep(time.Hour)
+	}
+}
+
+// periodicTaskUpdateBuilderScoreboard aggregates the per-builder performance of the days since the last run, which the
+// builder stats view served by the data API and the website sums up
+func (hk *Housekeeper) periodicTaskUpdateBuilderScoreboard() {
+	for {
+		hk.runJob("updateBuilderScoreboard", hk.updateBuilderScoreboard)
+		hk.sleep(hk.opts.BuilderScoreboardInterval)
+	}
+}
+
+func (hk *Housekeeper) updateBuilderScoreboard() {
+	genesis, err := hk.beaconClient.GetGenesis()
+	if err != nil {
+		hk.log.WithError(err).Error("failed to get genesis for the builder scoreboard")
+		return
+	}
+	if err := hk.db.UpdateBuilderDailyAggregates(genesis.Data.GenesisTime); err != nil {
+		hk.log.WithError(err).Error("failed to update builder daily aggregates")
+	}
+}
+
+// periodicTaskCollectRedisGarbage deletes per-slot keys which outlived their TTL, e.g. because setting it failed
+func (hk *Housekeeper) periodicTaskCollectRedisGarbage() {
+	for {
+		hk.sleep(hk.opts.RedisGCInterval)
+		hk.runJob("collectRedisGarbage", hk.collectRedisGarbage)
+	}
+}
+
+func (hk *Housekeeper) collectRedisGarbage() {
+	headSlot := hk.headSlot.Load()
+	slotMargin := uint64(redisGCEpochMargin * common.SlotsPerEpoch)
+	if headSlot < slotMargin {
+		return
+	}
+
+	minSlot := headSlot - slotMargin
+	result, err := hk.redis.CollectGarbage(context.Background(), minSlot, time.Now())
+	log := hk.log.WithFields(logrus.Fields{
+		"minSlot":        minSlot,
+		"numKeysScanned": result.NumKeysScanned,
+		"numKeysDeleted": result.NumKeysDeleted,
+		"bytesReclaimed": result.BytesReclaimed,
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to collect redis garbage")
+		return
+	}
+	log.Info("collected redis garbage")
+}
+
 func (hk *Housekeeper) processNewSlot(headSlot uint64) {
 	prevHeadSlot := hk.headSlot.Load()
 	if headSlot <= prevHeadSlot {
@@ -152,16 +417,19 @@ func (hk *Housekeeper) processNewSlot(headSlot uint64) {
 		}
 	}
 
+	hk.headSlot.Store(headSlot)
+
 	// Update proposer duties
-	go hk.updateProposerDuties(headSlot)
-	go func() {
-		err := hk.redis.SetStats(datastore.RedisStatsFieldLatestSlot, headSlot)
-		if err != nil {
-			log.WithError(err).Error("failed to set stats")
-		}
-	}()
+	if hk.isLeader.Load() {
+		go hk.updateProposerDuties(headSlot)
+		go func() {
+			err := hk.redis.SetStats(datastore.RedisStatsFieldLatestSlot, headSlot)
+			if err != nil {
+				log.WithError(err).Error("failed to set stats")
+			}
+		}()
+	}
 
-	hk.headSlot.Store(headSlot)
 	currentEpoch := headSlot / uint64(common.SlotsPerEpoch)
 	log.WithFields(logrus.Fields{
 		"epoch":              currentEpoch,
@@ -169,56 +437,86 @@ func (hk *Housekeeper) processNewSlot(headSlot uint64) {
 	}).Infof("updated headSlot to %d", headSlot)
 }
 
+// updateKnownValidators fetches the validators with indices above the highest known one, and periodically all of them
+// to reconcile changes. Only new or changed validators are written to redis.
 func (hk *Housekeeper) updateKnownValidators() {
+	// start over with a full sync if another instance may have been leader in the meantime
+	if term := hk.leaderTerm.Load(); term != hk.knownValidatorsTerm {
+		hk.knownValidators = nil
+		hk.knownValidatorsMaxIndex = 0
+		hk.knownValidatorsLastFullSync = time.Time{}
+		hk.knownValidatorsTerm = term
+	}
+
+	if hk.knownValidators == nil {
+		knownValidators, err := hk.redis.GetKnownValidators()
+		if err != nil {
+			hk.log.WithError(err).Error("failed to get known validators from redis")
+			return
+		}
+		hk.knownValidators = knownValidators
+	}
+
+	headSlot := hk.headSlot.Load() - 1 // -1 to avoid "Invalid state ID: requested slot number is higher than head slot number" with multiple BNs
+	isFullSync := time.Since(hk.knownValidatorsLastFullSync) >= hk.opts.KnownValidatorsFullSync
+	log := hk.log.WithField("isFullSync", isFullSync)
+
 	// Query beacon node for known validators
-	hk.log.Debug("Querying validators from beacon node... (this may take a while)")
+	log.Debug("Querying validators from beacon node...")
 	timeStartFetching := time.Now()
-	validators, err := hk.beaconClient.FetchValidators(hk.headSlot.Load() - 1) // -1 to avoid "Invalid state ID: requested slot number is higher than head slot number" with multiple BNs
+	var validators map[types.PubkeyHex]beaconclient.ValidatorResponseEntry
+	var err error
+	if isFullSync {
+		validators, err = hk.beaconClient.FetchValidators(headSlot)
+	} else {
+		validators, err = hk.beaconClient.FetchValidatorsFromIndex(headSlot, hk.knownValidatorsMaxIndex+1)
+	}
 	if err != nil {
-		hk.log.WithError(err).Error("failed to fetch validators from all beacon nodes")
+		log.WithError(err).Error("failed to fetch validators from all beacon nodes")
 		return
 	}
-
-	numValidators := len(validators)
-	log := hk.log.WithField("numKnownValidators", numValidators)
-	log.WithField("durationFetchValidators", time.Since(timeStartFetching).Seconds()).Infof("received validators from beacon-node")
+	log = log.WithField("numFetchedValidators", len(validators))
+	log.WithField("durationFetchValidators", time.Since(timeStartFetching).Seconds()).Info("received validators from beacon-node")
 
 	// Store total number of validators
-	err = hk.redis.SetStats(datastore.RedisStatsFieldValidatorsTotal, fmt.Sprint(numValidators))
-	if err != nil {
-		log.WithError(err).Error("failed to set stats for RedisStatsFieldValidatorsTotal")
+	if isFullSync {
+		err = hk.redis.SetStats(datastore.RedisStatsFieldValidatorsTotal, fmt.Sprint(len(validators)))
+		if err != nil {
+			log.WithError(err).Error("failed to set stats for RedisStatsFieldValidatorsTotal")
+		}
 	}
 
-	// Update Redis with validators
-	log.Debug("Writing to Redis...")
-	timeStartWriting := time.Now()
-
-	printCounter := len(hk.proposersAlreadySaved) == 0 // only on first round
-	i := 0
-	newValidators := 0
-	for _, validator := range validators {
-		i++
-		if printCounter && i%10000 == 0 {
-			hk.log.Debugf("writing to redis: %d / %d", i, numValidators)
+	changedValidators := make(map[types.PubkeyHex]uint64)
+	maxIndex := hk.knownValidatorsMaxIndex
+	for pubkey, validator := range validators {
+		if index, found := hk.knownValidators[pubkey]; !found || index != validator.Index {
+			changedValidators[pubkey] = validator.Index
 		}
-
-		// avoid resaving
-		if hk.proposersAlreadySaved[validator.Validator.Pubkey] {
-			continue
+		if validator.Index > maxIndex {
+			maxIndex = validator.Index
 		}
+	}
 
-		err := hk.redis.SetKnownValidatorNX(types.PubkeyHex(validator.Validator.Pubkey), validator.Index)
-		if err != nil {
-			log.WithError(err).WithField("pubkey", validator.Validator.Pubkey).Error("failed to set known validator in Redis")
-		} else {
-			hk.proposersAlreadySaved[validator.Validator.Pubkey] = true
-			newValidators++
-		}
+	// Update Redis with the changed validators
+	timeStartWriting := time.Now()
+	err = hk.redis.SetKnownValidators(changedValidators)
+	if err != nil {
+		log.WithError(err).Error("failed to set known validators in Redis")
+		return
+	}
+
+	for pubkey, index := range changedValidators {
+		hk.knownValidators[pubkey] = index
+	}
+	hk.knownValidatorsMaxIndex = maxIndex
+	if isFullSync {
+		hk.knownValidatorsLastFullSync = time.Now()
 	}
 
 	log.WithFields(logrus.Fields{
-		"durationRedisWrite": time.Since(timeStartWriting).Seconds(),
-		"newValidators":      newValidators,
+		"durationRedisWrite":   time.Since(timeStartWriting).Seconds(),
+		"numChangedValidators": len(changedValidators),
+		"maxValidatorIndex":    hk.knownValidatorsMaxIndex,
 	}).Info("updateKnownValidators done")
 }
 
@@ -229,9 +527,12 @@ func (hk *Housekeeper) updateProposerDuties(headSlot uint64) {
 	}
 	defer hk.isUpdatingProposerDuties.Store(false)
 
-	if headSlot%uint64(common.SlotsPerEpoch/2) != 0 && headSlot-hk.proposerDutiesSlot < uint64(common.SlotsPerEpoch/2) {
+	interval := hk.opts.ProposerDutiesIntervalSlots
+	if headSlot%interval != 0 && headSlot-hk.proposerDutiesSlot < interval {
 		return
 	}
+	timeStarted := time.Now()
+	defer common.ObserveDuration(common.HousekeeperJobDuration.WithLabelValues("updateProposerDuties"), timeStarted)
 
 	epoch := headSlot / uint64(common.SlotsPerEpoch)
 
@@ -241,17 +542,24 @@ func (hk *Housekeeper) updateProposerDuties(headSlot uint64) {
 	})
 	log.Debug("updating proposer duties...")
 
-	// Query current epoch
-	r, err := hk.beaconClient.GetProposerDuties(epoch)
-	if err != nil {
+	// Query current epoch. The duties are cross-checked across all beacon nodes, and the previous duties are kept if the
+	// nodes disagree, instead of serving the duties of a forked or buggy node.
+	r, err := hk.beaconClient.GetCrossCheckedProposerDuties(epoch)
+	if errors.Is(err, beaconclient.ErrBeaconNodesDisagree) {
+		log.WithError(err).Error("beacon nodes disagree on the proposer duties, not updating them")
+		return
+	} else if err != nil {
 		log.WithError(err).Error("failed to get proposer duties for all beacon nodes")
 		return
 	}
 	entries := r.Data
 
-	// Query next epoch
-	r2, err := hk.beaconClient.GetProposerDuties(epoch + 1)
-	if err != nil {
+	// Query next epoch. Its duties can still change with the head, so nodes a block apart may briefly disagree on them,
+	// and only the agreed duties of the current epoch are saved then.
+	r2, err := hk.beaconClient.GetCrossCheckedProposerDuties(epoch + 1)
+	if errors.Is(err, beaconclient.ErrBeaconNodesDisagree) {
+		log.WithError(err).Warn("beacon nodes disagree on the proposer duties of the next epoch, only updating the current epoch")
+	} else if err != nil {
 		log.WithError(err).Error("failed to get proposer duties for next epoch for all beacon nodes")
 	} else if r2 != nil {
 		entries = append(entries, r2.Data...)
@@ -305,7 +613,11 @@ func (hk *Housekeeper) updateProposerDuties(headSlot uint64) {
 		_duties[i] = fmt.Sprint(duty.Slot)
 	}
 	sort.Strings(_duties)
-	log.WithField("numDuties", len(_duties)).Infof("proposer duties updated: %s", strings.Join(_duties, ", "))
+	log.WithFields(logrus.Fields{
+		"numDuties":   len(_duties),
+		"job":         "updateProposerDuties",
+		"durationSec": time.Since(timeStarted).Seconds(),
+	}).Infof("proposer duties updated: %s", strings.Join(_duties, ", "))
 }
 
 // updateValidatorRegistrationsInRedis saves all latest validator registrations from the database to Redis
@@ -344,5 +656,28 @@ func (hk *Housekeeper) updateBlockBuildersInRedis() {
 		if err != nil {
 			hk.log.WithError(err).Error("failed to set block builder status in redis")
 		}
+		hk.updateBuilderCollateralInRedis(builder)
+
+		if builder.APIKeyHash.Valid {
+			err = hk.redis.SetBlockBuilderAPIKeyHash(builder.BuilderPubkey, builder.APIKeyHash.String)
+		} else {
+			err = hk.redis.DeleteBlockBuilderAPIKeyHash(builder.BuilderPubkey)
+		}
+		if err != nil {
+			hk.log.WithError(err).Error("failed to set block builder api key in redis")
+		}
+	}
+}
+
+// updateBuilderCollateralInRedis enables optimistic mode in redis for optimistic builders, and disables it for all others (i.e. demoted ones)
+func (hk *Housekeeper) updateBuilderCollateralInRedis(builder *database.BlockBuilderEntry) {
+	var err error
+	if builder.IsOptimistic {
+		err = hk.redis.SetBlockBuilderCollateral(builder.BuilderPubkey, builder.Collateral)
+	} else {
+		err = hk.redis.DeleteBlockBuilderCollateral(builder.BuilderPubkey)
+	}
+	if err != nil {
+		hk.log.WithError(err).WithField("builderPubkey", builder.BuilderPubkey).Error("failed to update block builder collateral in redis")
 	}
 }
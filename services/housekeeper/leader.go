@@ -0,0 +1,123 @@
+package housekeeper
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"time"
+)
+
+var (
+	// DefaultLeaderLockTTL is how long the leader lock is valid without renewal, i.e. the maximum failover time
+	DefaultLeaderLockTTL = 10 * time.Second
+
+	// jobsShutdownTimeout is how long Stop waits for the running jobs before giving up the leadership
+	jobsShutdownTimeout = 30 * time.Second
+)
+
+// newInstanceID returns a unique id for this housekeeper instance, prefixed with the hostname for debugging
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hostname + "-" + hex.EncodeToString(b)
+}
+
+// runLeaderElection tries to acquire or renew the leader lock several times per TTL, so a standby takes over soon after
+// the leader stops renewing. Returns once the housekeeper is stopped.
+func (hk *Housekeeper) runLeaderElection() {
+	defer hk.leaderElection.Done()
+	ticker := time.NewTicker(hk.opts.LeaderLockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			hk.updateLeadership()
+		case <-hk.stopC:
+			return
+		}
+	}
+}
+
+func (hk *Housekeeper) updateLeadership() {
+	log := hk.log.WithField("instanceID", hk.instanceID)
+
+	// while stopping, the lock is only renewed for the jobs still running
+	if hk.isStopping.Load() && !hk.isLeader.Load() {
+		return
+	}
+
+	requestedAt := time.Now()
+	isLeader, err := hk.redis.AcquireOrRenewLeaderLock(hk.instanceID, hk.opts.LeaderLockTTL)
+	if err != nil {
+		log.WithError(err).Error("failed to renew leader lock")
+
+		// step down well before the lock expires, after which another instance can take over, so that the jobs of two
+		// instances don't overlap
+		if hk.isLeader.Load() && time.Since(hk.leaderLockRenewedAt) >= hk.opts.LeaderLockTTL*2/3 {
+			hk.isLeader.Store(false)
+			log.Warn("stepping down as leader, lock could not be renewed")
+		}
+		return
+	}
+
+	if !isLeader {
+		if hk.isLeader.Swap(false) {
+			log.Warn("lost leadership")
+		}
+		return
+	}
+
+	hk.leaderLockRenewedAt = requestedAt
+	if !hk.isLeader.Swap(true) {
+		log.Info("became leader")
+		hk.onBecomeLeader()
+	}
+}
+
+// onBecomeLeader runs the one-off jobs, and makes the periodic jobs start from a clean state since another instance
+// may have been doing them in the meantime
+func (hk *Housekeeper) onBecomeLeader() {
+	hk.leaderTerm.Inc()
+	go hk.updateBlockBuildersInRedis()
+	go hk.updateValidatorRegistrationsInRedis()
+}
+
+// Stop waits for the running jobs, and then gives up the leadership so that a standby instance takes over right away
+// instead of once the lock expires. Start returns afterwards.
+func (hk *Housekeeper) Stop() {
+	hk.jobsLock.Lock()
+	isStopping := hk.isStopping.Swap(true)
+	hk.jobsLock.Unlock()
+	if isStopping {
+		return
+	}
+
+	hk.log.WithField("timeout", jobsShutdownTimeout.String()).Info("Stopping housekeeper...")
+	jobsDone := make(chan struct{})
+	go func() {
+		hk.jobsInFlight.Wait()
+		close(jobsDone)
+	}()
+	select {
+	case <-jobsDone:
+	case <-time.After(jobsShutdownTimeout):
+		hk.log.Warn("housekeeper jobs still running after the shutdown timeout")
+	}
+
+	close(hk.stopC)
+	if !hk.opts.LeaderElection {
+		return
+	}
+	hk.leaderElection.Wait()
+	if hk.isLeader.Swap(false) {
+		if err := hk.redis.ReleaseLeaderLock(hk.instanceID); err != nil {
+			hk.log.WithError(err).Error("failed to release leader lock")
+		} else {
+			hk.log.WithField("instanceID", hk.instanceID).Info("released leader lock")
+		}
+	}
+}
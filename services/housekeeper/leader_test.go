@@ -0,0 +1,63 @@
+package housekeeper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHousekeeper(t *testing.T) (*Housekeeper, *miniredis.Miniredis) {
+	t.Helper()
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(redisServer.Close)
+	redisCache, err := datastore.NewRedisCache(redisServer.Addr(), "")
+	require.NoError(t, err)
+
+	return NewHousekeeper(&HousekeeperOpts{
+		Log:            common.TestLog,
+		Redis:          redisCache,
+		DB:             database.MockDB{},
+		LeaderElection: true,
+		LeaderLockTTL:  3 * time.Second,
+	}), redisServer
+}
+
+func TestLeaderStepDown(t *testing.T) {
+	hk, redisServer := newTestHousekeeper(t)
+	hk.updateLeadership()
+	require.True(t, hk.isLeader.Load())
+
+	// a failed renewal keeps the leadership for a third of the TTL, but not for two thirds
+	redisServer.Close()
+	hk.leaderLockRenewedAt = time.Now().Add(-hk.opts.LeaderLockTTL / 3)
+	hk.updateLeadership()
+	require.True(t, hk.isLeader.Load())
+	hk.leaderLockRenewedAt = time.Now().Add(-hk.opts.LeaderLockTTL * 2 / 3)
+	hk.updateLeadership()
+	require.False(t, hk.isLeader.Load())
+}
+
+func TestStopReleasesLeaderLock(t *testing.T) {
+	hk, _ := newTestHousekeeper(t)
+	hk.updateLeadership()
+	require.True(t, hk.isLeader.Load())
+	hk.leaderElection.Add(1)
+	go hk.runLeaderElection()
+
+	hk.Stop()
+	require.False(t, hk.isLeader.Load())
+
+	// no jobs are run once stopped
+	hk.runJob("test", func() { t.Fatal("job run after stop") })
+
+	// another instance takes over right away
+	isLeader, err := hk.redis.AcquireOrRenewLeaderLock("other", hk.opts.LeaderLockTTL)
+	require.NoError(t, err)
+	require.True(t, isLeader)
+}
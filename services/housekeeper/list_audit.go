@@ -0,0 +1,79 @@
+package housekeeper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+// auditOperator is the operator recorded in the admin audit log for the list changes the housekeeper applies
+const auditOperator = "housekeeper"
+
+// maxAuditedListEntries caps the entries added and removed listed in an audit log entry, beyond that only the counts
+// are recorded
+const maxAuditedListEntries = 20
+
+// diffList returns the entries added to and removed from the previous list, sorted
+func diffList(previous map[string]bool, next []string) (added, removed []string) {
+	nextSet := make(map[string]bool, len(next))
+	for _, entry := range next {
+		nextSet[entry] = true
+		if !previous[entry] {
+			added = append(added, entry)
+		}
+	}
+	for entry := range previous {
+		if !nextSet[entry] {
+			removed = append(removed, entry)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// formatListChange describes a list change for the audit log, e.g. "3 entries, added 0xaa, removed 0xbb"
+func formatListChange(numEntries int, added, removed []string) string {
+	formatEntries := func(entries []string) string {
+		if len(entries) > maxAuditedListEntries {
+			return fmt.Sprintf("%s and %d more", strings.Join(entries[:maxAuditedListEntries], " "), len(entries)-maxAuditedListEntries)
+		}
+		return strings.Join(entries, " ")
+	}
+
+	parts := []string{fmt.Sprintf("%d entries", numEntries)}
+	if len(added) > 0 {
+		parts = append(parts, "added "+formatEntries(added))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "removed "+formatEntries(removed))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// auditListChange records the change of a list loaded from its source in the admin audit log, nothing if the list
+// didn't change. The previous list is nil if it's unknown (not loaded yet, or redis lost or failed to return it), then
+// nothing is recorded either, as every entry would be listed as added.
+func (hk *Housekeeper) auditListChange(action, source string, previous map[string]bool, next []string) {
+	if previous == nil {
+		hk.log.WithField("action", action).Info("previous list unknown, not recording the change in the admin audit log")
+		return
+	}
+	added, removed := diffList(previous, next)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	err := hk.db.InsertAdminAuditLog(database.AdminAuditLogEntry{ //nolint:exhaustruct
+		Operator: auditOperator,
+		Action:   action,
+		Target:   source,
+		OldValue: fmt.Sprintf("%d entries", len(previous)),
+		NewValue: formatListChange(len(next), added, removed),
+	})
+	if err != nil {
+		hk.log.WithError(err).WithField("action", action).Error("could not record the list change in the admin audit log")
+	}
+}
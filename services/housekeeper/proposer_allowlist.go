@@ -0,0 +1,90 @@
+package housekeeper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	boostTypes "github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+// ProposerAllowlistSourceDB loads the proposer allowlist from the proposer_allowlist table instead of a file or URL
+const ProposerAllowlistSourceDB = "db"
+
+// the allowlist is dropped if the source grows beyond this, rather than filling up redis
+const maxProposerAllowlistSize = 10 << 20
+
+var ErrEmptyProposerAllowlist = errors.New("proposer allowlist source contains no pubkeys")
+
+// periodicTaskUpdateProposerAllowlist reloads the proposer allowlist from its source into redis, where the API instances
+// pick it up
+func (hk *Housekeeper) periodicTaskUpdateProposerAllowlist() {
+	for {
+		hk.runJob("updateProposerAllowlist", hk.updateProposerAllowlist)
+		hk.sleep(hk.opts.ProposerAllowlistInterval)
+	}
+}
+
+// updateProposerAllowlist keeps the previous allowlist if the source can't be loaded, or if a file or URL source is
+// empty, so a broken source never locks out all the proposers. An empty proposer_allowlist table empties the allowlist,
+// the rows were deleted on purpose.
+func (hk *Housekeeper) updateProposerAllowlist() {
+	log := hk.log.WithField("proposerAllowlistSource", hk.opts.ProposerAllowlistSource)
+
+	var pubkeys []string
+	var err error
+	if hk.opts.ProposerAllowlistSource == ProposerAllowlistSourceDB {
+		pubkeys, err = hk.db.GetProposerAllowlist()
+		if err == nil {
+			pubkeys, err = checkProposerAllowlist(pubkeys)
+		}
+	} else {
+		var data []byte
+		data, err = readListSource(hk.opts.ProposerAllowlistSource, maxProposerAllowlistSize)
+		if err == nil {
+			pubkeys, err = parseProposerAllowlist(data)
+		}
+	}
+	if err != nil {
+		log.WithError(err).Error("failed to load proposer allowlist, keeping the previous one")
+		return
+	}
+
+	previous, err := hk.redis.GetProposerAllowlist()
+	if err != nil {
+		log.WithError(err).Warn("failed to get the previous proposer allowlist from redis")
+	}
+	err = hk.redis.SetProposerAllowlist(pubkeys)
+	if err != nil {
+		log.WithError(err).Error("failed to save proposer allowlist to redis")
+		return
+	}
+	hk.auditListChange(database.AdminAuditActionProposerAllowlist, hk.opts.ProposerAllowlistSource, previous, pubkeys)
+	log.WithField("numPubkeys", len(pubkeys)).Info("updated proposer allowlist")
+}
+
+// parseProposerAllowlist accepts either a JSON array of validator pubkeys, or one pubkey per line with '#' starting a
+// comment. Any invalid pubkey fails the whole list.
+func parseProposerAllowlist(data []byte) ([]string, error) {
+	entries, err := parseListEntries(data)
+	if err != nil {
+		return nil, err
+	} else if len(entries) == 0 {
+		return nil, ErrEmptyProposerAllowlist
+	}
+	return checkProposerAllowlist(entries)
+}
+
+// checkProposerAllowlist returns the lowercase pubkeys, or an error if any is invalid
+func checkProposerAllowlist(entries []string) ([]string, error) {
+	pubkeys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		pubkey, err := boostTypes.HexToPubkey(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pubkey in proposer allowlist: %q", entry)
+		}
+		pubkeys = append(pubkeys, strings.ToLower(pubkey.String()))
+	}
+	return pubkeys, nil
+}
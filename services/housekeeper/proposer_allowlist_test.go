@@ -0,0 +1,31 @@
+package housekeeper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProposerAllowlist(t *testing.T) {
+	pubkey1 := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	pubkey2 := "0xb5246e299aeb782fbc7c91b41b3284245b1ed5206134b0028b81dfb974e5900616c67847c2354479934fc4bb75519ee1"
+	expected := []string{pubkey1, pubkey2}
+
+	pubkeys, err := parseProposerAllowlist([]byte(`
+		# staking entity validators
+		0x8A1D7B8DD64E0AAFE7EA7B6C95065C9364CF99D38470C12EE807D55F7DE1529AD29CE2C422E0B65E3D5A05C02CACA249
+		` + pubkey2 + ` # comment
+	`))
+	require.NoError(t, err)
+	require.Equal(t, expected, pubkeys)
+
+	pubkeys, err = parseProposerAllowlist([]byte(`["` + pubkey1 + `", "` + pubkey2 + `"]`))
+	require.NoError(t, err)
+	require.Equal(t, expected, pubkeys)
+
+	_, err = parseProposerAllowlist([]byte(pubkey1 + "\n0xaa00000000000000000000000000000000000001"))
+	require.Error(t, err)
+
+	_, err = parseProposerAllowlist([]byte("# nothing here"))
+	require.ErrorIs(t, err, ErrEmptyProposerAllowlist)
+}
@@ -0,0 +1,170 @@
+package website
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/flashbots/mev-boost-relay/datastore"
+	"github.com/gorilla/mux"
+)
+
+const (
+	pathBuilder = "/builder/{pubkey:0x[a-fA-F0-9]+}"
+
+	numBuilderPagePayloads = 30
+
+	// bound of the negative cache, requests with random pubkeys would grow it without limit otherwise
+	maxUnknownBuilderPubkeys = 10_000
+)
+
+type builderPage struct {
+	page       *renderedPage
+	renderedAt time.Time
+}
+
+// builderPageCache holds the recently rendered builder pages. Only pages of known builders are cached, so the number
+// of entries is bounded by the number of builders. The pubkeys that were looked up without finding a builder are
+// remembered for the refresh interval too, so that requests for unknown pubkeys don't query the database every time.
+type builderPageCache struct {
+	lock    sync.Mutex
+	pages   map[string]*builderPage
+	unknown map[string]time.Time // pubkey -> time of the lookup
+}
+
+func newBuilderPageCache() *builderPageCache {
+	return &builderPageCache{
+		pages:   make(map[string]*builderPage),
+		unknown: make(map[string]time.Time),
+	}
+}
+
+// get returns the cached page if it was rendered within the refresh interval
+func (c *builderPageCache) get(pubkey string) *renderedPage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := c.pages[pubkey]
+	if entry == nil || time.Since(entry.renderedAt) > websiteRefreshInterval {
+		return nil
+	}
+	return entry.page
+}
+
+func (c *builderPageCache) set(pubkey string, page *renderedPage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pages[pubkey] = &builderPage{page: page, renderedAt: time.Now()}
+	delete(c.unknown, pubkey)
+}
+
+// isUnknown returns whether the pubkey was looked up without finding a builder within the refresh interval
+func (c *builderPageCache) isUnknown(pubkey string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	lookedUpAt, ok := c.unknown[pubkey]
+	return ok && time.Since(lookedUpAt) <= websiteRefreshInterval
+}
+
+// setUnknown remembers a pubkey without builder. When the negative cache is full, the expired entries are dropped, and
+// all of them if none expired yet.
+func (c *builderPageCache) setUnknown(pubkey string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.unknown) >= maxUnknownBuilderPubkeys {
+		for key, lookedUpAt := range c.unknown {
+			if time.Since(lookedUpAt) > websiteRefreshInterval {
+				delete(c.unknown, key)
+			}
+		}
+		if len(c.unknown) >= maxUnknownBuilderPubkeys {
+			c.unknown = make(map[string]time.Time)
+		}
+	}
+	c.unknown[pubkey] = time.Now()
+}
+
+// renderBuilderPage returns the profile page of a builder, or sql.ErrNoRows if the builder is unknown
+func (srv *Webserver) renderBuilderPage(pubkey string) (*renderedPage, error) {
+	builder, err := srv.db.GetBlockBuilderByPubkey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := srv.db.GetBuilderStatsByPubkey(pubkey)
+	if errors.Is(err, sql.ErrNoRows) {
+		stats = nil
+	} else if err != nil {
+		srv.log.WithError(err).Error("error getting builder stats")
+		stats = nil
+	}
+
+	payloads, err := srv.db.GetRecentDeliveredPayloads(database.GetPayloadsFilters{Limit: numBuilderPagePayloads, BuilderPubkey: pubkey})
+	if err != nil {
+		return nil, err
+	}
+
+	data := BuilderHTMLData{
+		Network:         srv.statusHTMLData.Network,
+		Builder:         builder,
+		Stats:           stats,
+		Status:          string(datastore.MakeBlockBuilderStatus(builder.IsHighPrio, builder.IsBlacklisted)),
+		SimErrorRate:    prettyPercent(builder.NumSubmissionsSimError, builder.NumSubmissionsTotal),
+		WinRate:         "-",
+		Payloads:        payloads,
+		LinkBeaconchain: srv.opts.LinkBeaconchain,
+	}
+	if builder.IsOptimistic {
+		data.Status += ", optimistic"
+	}
+	if stats != nil {
+		data.WinRate = prettyPercent(stats.NumBlocksDelivered, stats.NumSlotsSubmitted)
+	}
+
+	html := bytes.Buffer{}
+	if err := srv.builderTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	minified, err := srv.minifier.Bytes("text/html", html.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return newRenderedPage(minified), nil
+}
+
+func (srv *Webserver) handleBuilder(w http.ResponseWriter, req *http.Request) {
+	var pubkey types.PublicKey
+	if err := pubkey.UnmarshalText([]byte(mux.Vars(req)["pubkey"])); err != nil {
+		http.Error(w, "invalid builder pubkey", http.StatusBadRequest)
+		return
+	}
+	pubkeyHex := strings.ToLower(pubkey.String())
+
+	if srv.builderPages.isUnknown(pubkeyHex) {
+		http.Error(w, "builder not found", http.StatusNotFound)
+		return
+	}
+
+	page := srv.builderPages.get(pubkeyHex)
+	if page == nil {
+		var err error
+		page, err = srv.renderBuilderPage(pubkeyHex)
+		if errors.Is(err, sql.ErrNoRows) {
+			srv.builderPages.setUnknown(pubkeyHex)
+			http.Error(w, "builder not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			srv.log.WithError(err).WithField("builderPubkey", pubkeyHex).Error("error rendering builder page")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		srv.builderPages.set(pubkeyHex, page)
+	}
+
+	srv.writePage(w, req, page, "text/html; charset=utf-8", websiteRefreshInterval)
+}
@@ -0,0 +1,114 @@
+package website
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const testBuilderPubkey = "0xa1885d66bef164889a2e35845c3b626545d7b0e513efe335e97c3a45e534013fa3bc38c3b7e6143695aecc4872ac52c4"
+
+func newTestWebserver(t *testing.T, db database.IDatabaseService) *Webserver {
+	t.Helper()
+	networkDetails, err := common.NewEthNetworkDetails(common.EthNetworkGoerli)
+	require.NoError(t, err)
+	srv, err := NewWebserver(&WebserverOpts{ //nolint:exhaustruct
+		NetworkDetails: networkDetails,
+		DB:             db,
+		Log:            logrus.NewEntry(logrus.New()),
+	})
+	require.NoError(t, err)
+	return srv
+}
+
+// builderDB knows a single builder and counts the builder lookups
+type builderDB struct {
+	database.MockDB
+	numLookups int
+}
+
+func (db *builderDB) GetBlockBuilderByPubkey(pubkey string) (*database.BlockBuilderEntry, error) {
+	db.numLookups++
+	if pubkey != testBuilderPubkey {
+		return nil, sql.ErrNoRows
+	}
+	return &database.BlockBuilderEntry{
+		BuilderPubkey:          pubkey,
+		Description:            "test builder",
+		NumSubmissionsTotal:    10,
+		NumSubmissionsSimError: 1,
+	}, nil
+}
+
+func (db *builderDB) GetBuilderStatsByPubkey(pubkey string) (*database.BuilderStatsEntry, error) {
+	return nil, sql.ErrNoRows
+}
+
+func TestHandleBuilder(t *testing.T) {
+	db := &builderDB{}
+	srv := newTestWebserver(t, db)
+	router := srv.getRouter()
+
+	request := func(pubkey string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/builder/"+pubkey, nil))
+		return rr
+	}
+
+	t.Run("known builder", func(t *testing.T) {
+		rr := request(testBuilderPubkey)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "test builder")
+		require.NotEmpty(t, rr.Header().Get("ETag"))
+		require.Equal(t, 1, db.numLookups)
+
+		// the rendered page is cached
+		rr = request(testBuilderPubkey)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, 1, db.numLookups)
+	})
+
+	t.Run("unknown builder", func(t *testing.T) {
+		db.numLookups = 0
+		unknownPubkey := "0x" + testBuilderPubkey[4:] + "00"
+		rr := request(unknownPubkey)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, 1, db.numLookups)
+
+		// unknown pubkeys are answered from the negative cache
+		rr = request(unknownPubkey)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, 1, db.numLookups)
+	})
+
+	t.Run("invalid pubkey", func(t *testing.T) {
+		rr := request("0x1234")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestBuilderPageCacheUnknownBound(t *testing.T) {
+	c := newBuilderPageCache()
+	for i := 0; i < maxUnknownBuilderPubkeys; i++ {
+		c.setUnknown(strconv.Itoa(i))
+	}
+	require.Len(t, c.unknown, maxUnknownBuilderPubkeys)
+	require.True(t, c.isUnknown(strconv.Itoa(0)))
+
+	// a full negative cache without expired entries is reset
+	c.setUnknown("0xabcd")
+	require.Len(t, c.unknown, 1)
+	require.True(t, c.isUnknown("0xabcd"))
+	require.False(t, c.isUnknown(strconv.Itoa(0)))
+
+	// rendering a page clears the negative entry
+	c.set("0xabcd", newRenderedPage([]byte{}))
+	require.False(t, c.isUnknown("0xabcd"))
+}
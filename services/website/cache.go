@@ -0,0 +1,91 @@
+package website
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/go-utils/cli"
+)
+
+var (
+	// how often the pages are re-rendered, which is also how long clients may cache them
+	websiteRefreshInterval = time.Duration(cli.GetEnvInt("WEBSITE_REFRESH_INTERVAL_SEC", 10)) * time.Second
+
+	// how long the results of the expensive queries (counts, value-sorted payloads, builder stats) are reused
+	websiteQueryCacheDuration = time.Duration(cli.GetEnvInt("WEBSITE_QUERY_CACHE_SEC", 60)) * time.Second
+)
+
+// cachedQuery reuses the result of a query until it's older than maxAge
+type cachedQuery[T any] struct {
+	lock      sync.Mutex
+	maxAge    time.Duration
+	value     T
+	updatedAt time.Time
+}
+
+func newCachedQuery[T any](maxAge time.Duration) *cachedQuery[T] {
+	return &cachedQuery[T]{maxAge: maxAge} //nolint:exhaustruct
+}
+
+// get returns the cached result, or runs the query if it is stale. If the query fails, the stale result is returned
+// along with the error.
+func (c *cachedQuery[T]) get(query func() (T, error)) (T, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.updatedAt.IsZero() && time.Since(c.updatedAt) < c.maxAge {
+		return c.value, nil
+	}
+
+	value, err := query()
+	if err != nil {
+		return c.value, err
+	}
+	c.value = value
+	c.updatedAt = time.Now()
+	return value, nil
+}
+
+// renderedPage is a rendered response along with its ETag
+type renderedPage struct {
+	body []byte
+	etag string
+}
+
+func newRenderedPage(body []byte) *renderedPage {
+	hash := sha256.Sum256(body)
+	return &renderedPage{
+		body: body,
+		etag: `"` + hex.EncodeToString(hash[:16]) + `"`,
+	}
+}
+
+// etagMatches returns true if the If-None-Match header contains the ETag (or is a wildcard)
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writePage writes the page with caching headers, or only a 304 if the client already has this version
+func (srv *Webserver) writePage(w http.ResponseWriter, req *http.Request, page *renderedPage, contentType string, maxAge time.Duration) {
+	w.Header().Set("ETag", page.etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, page.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(page.body); err != nil {
+		srv.log.WithError(err).Error("error writing page")
+	}
+}
@@ -0,0 +1,64 @@
+package website
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedQuery(t *testing.T) {
+	numQueries := 0
+	query := func() (int, error) {
+		numQueries++
+		return numQueries, nil
+	}
+
+	c := newCachedQuery[int](time.Hour)
+	value, err := c.get(query)
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+
+	value, err = c.get(query)
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+	require.Equal(t, 1, numQueries)
+
+	// stale values are refreshed, and kept if the refresh fails
+	c.updatedAt = time.Now().Add(-2 * time.Hour)
+	value, err = c.get(func() (int, error) { return 0, errors.New("db down") })
+	require.Error(t, err)
+	require.Equal(t, 1, value)
+
+	value, err = c.get(query)
+	require.NoError(t, err)
+	require.Equal(t, 2, value)
+}
+
+func TestWritePage(t *testing.T) {
+	srv := &Webserver{log: logrus.NewEntry(logrus.New())} //nolint:exhaustruct
+	page := newRenderedPage([]byte("<html></html>"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+	srv.writePage(rr, req, page, "text/html", 10*time.Second)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, page.etag, rr.Header().Get("ETag"))
+	require.Equal(t, "public, max-age=10", rr.Header().Get("Cache-Control"))
+	require.Equal(t, "<html></html>", rr.Body.String())
+
+	req.Header.Set("If-None-Match", `"foo", W/`+page.etag)
+	rr = httptest.NewRecorder()
+	srv.writePage(rr, req, page, "text/html", 10*time.Second)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.String())
+
+	req.Header.Set("If-None-Match", `"foo"`)
+	rr = httptest.NewRecorder()
+	srv.writePage(rr, req, page, "text/html", 10*time.Second)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
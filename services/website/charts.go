@@ -0,0 +1,79 @@
+package website
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+const (
+	pathChartsDaily = "/api/v1/charts/daily"
+
+	chartsDefaultDays = 30
+	chartsMaxDays     = 365
+)
+
+// DailyChartsJSON holds one series per metric, aligned with Days (oldest first)
+type DailyChartsJSON struct {
+	Days                 []string `json:"days"`
+	NumPayloadsDelivered []uint64 `json:"num_payloads_delivered"`
+	TotalValue           []string `json:"total_value"`
+	NumBuilders          []uint64 `json:"num_builders"`
+}
+
+// dailyCharts returns the series of the last numDays aggregated days
+func dailyCharts(entries []*database.DailyAggregateEntry, numDays int) DailyChartsJSON {
+	if len(entries) > numDays {
+		entries = entries[len(entries)-numDays:]
+	}
+
+	charts := DailyChartsJSON{
+		Days:                 make([]string, len(entries)),
+		NumPayloadsDelivered: make([]uint64, len(entries)),
+		TotalValue:           make([]string, len(entries)),
+		NumBuilders:          make([]uint64, len(entries)),
+	}
+	for i, entry := range entries {
+		charts.Days[i] = entry.Day.UTC().Format("2006-01-02")
+		charts.NumPayloadsDelivered[i] = entry.NumPayloadsDelivered
+		charts.TotalValue[i] = entry.TotalValue
+		charts.NumBuilders[i] = entry.NumBuilders
+	}
+	return charts
+}
+
+// handleChartsDaily returns the daily series for the last ?days=... days (default 30, max 365)
+func (srv *Webserver) handleChartsDaily(w http.ResponseWriter, req *http.Request) {
+	numDays := chartsDefaultDays
+	if daysArg := req.URL.Query().Get("days"); daysArg != "" {
+		var err error
+		numDays, err = strconv.Atoi(daysArg)
+		if err != nil || numDays < 1 || numDays > chartsMaxDays {
+			http.Error(w, "invalid days argument", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := srv.dailyAggregatesQuery.get(func() ([]*database.DailyAggregateEntry, error) {
+		return srv.db.GetDailyAggregates(chartsMaxDays)
+	})
+	if err != nil {
+		srv.log.WithError(err).Error("error getting daily aggregates")
+		if entries == nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	chartsJSON, err := json.Marshal(dailyCharts(entries, numDays))
+	if err != nil {
+		srv.log.WithError(err).Error("error marshalling daily charts")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	srv.writePage(w, req, newRenderedPage(chartsJSON), "application/json", websiteQueryCacheDuration)
+}
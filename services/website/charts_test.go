@@ -0,0 +1,31 @@
+package website
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyCharts(t *testing.T) {
+	day := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*database.DailyAggregateEntry{
+		{Day: day, NumPayloadsDelivered: 10, TotalValue: "100", NumBuilders: 2},
+		{Day: day.AddDate(0, 0, 1), NumPayloadsDelivered: 20, TotalValue: "200", NumBuilders: 3},
+		{Day: day.AddDate(0, 0, 2), NumPayloadsDelivered: 30, TotalValue: "300", NumBuilders: 4},
+	}
+
+	charts := dailyCharts(entries, 2)
+	require.Equal(t, []string{"2023-03-02", "2023-03-03"}, charts.Days)
+	require.Equal(t, []uint64{20, 30}, charts.NumPayloadsDelivered)
+	require.Equal(t, []string{"200", "300"}, charts.TotalValue)
+	require.Equal(t, []uint64{3, 4}, charts.NumBuilders)
+
+	charts = dailyCharts(entries, 30)
+	require.Len(t, charts.Days, 3)
+
+	charts = dailyCharts(nil, 30)
+	require.Empty(t, charts.Days)
+	require.NotNil(t, charts.Days)
+}
@@ -44,6 +44,19 @@ type StatusHTMLData struct { //nolint:musttag
 	RelayURL          string
 }
 
+type BuilderHTMLData struct { //nolint:musttag
+	Network string
+	Builder *database.BlockBuilderEntry
+	Stats   *database.BuilderStatsEntry // nil if the builder has no submissions in the stats window
+
+	Status       string
+	SimErrorRate string
+	WinRate      string
+
+	Payloads        []*database.DeliveredPayloadEntry
+	LinkBeaconchain string
+}
+
 func weiToEth(wei string) string {
 	weiBigInt := new(big.Int)
 	weiBigInt.SetString(wei, 10)
@@ -59,6 +72,14 @@ func weiBigIntToEthBigFloat(wei *big.Int) (ethValue *big.Float) {
 	return
 }
 
+// prettyPercent formats n/total as percentage, or "-" if total is 0
+func prettyPercent(n, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return printer.Sprintf("%.2f%%", float64(n)*100/float64(total))
+}
+
 func prettyInt(i uint64) string {
 	return printer.Sprintf("%d", i)
 }
@@ -76,6 +97,13 @@ var funcMap = template.FuncMap{
 //go:embed website.html
 var htmlContent string
 
+//go:embed builder.html
+var builderHTMLContent string
+
 func ParseIndexTemplate() (*template.Template, error) {
 	return template.New("index").Funcs(funcMap).Parse(htmlContent)
 }
+
+func ParseBuilderTemplate() (*template.Template, error) {
+	return template.New("builder").Funcs(funcMap).Parse(builderHTMLContent)
+}
@@ -0,0 +1,109 @@
+package website
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/flashbots/mev-boost-relay/database"
+)
+
+// number of builders in the top builders list, ranked by blocks delivered in the last 7 days
+const numTopBuilders = 10
+
+// StatusJSONData is the machine-readable version of the website stats
+type StatusJSONData struct {
+	Network              string                  `json:"network"`
+	HeadSlot             uint64                  `json:"head_slot,string"`
+	ValidatorsTotal      uint64                  `json:"validators_total,string"`
+	ValidatorsRegistered uint64                  `json:"validators_registered,string"`
+	ValidatorsActive     uint64                  `json:"validators_active,string"`
+	NumPayloadsDelivered uint64                  `json:"num_payloads_delivered,string"`
+	TopBuilders          []TopBuilderJSON        `json:"top_builders"`
+	RecentPayloads       []common.BidTraceV2JSON `json:"recent_payloads"`
+	Config               *StatusJSONConfig       `json:"config,omitempty"`
+}
+
+type TopBuilderJSON struct {
+	BuilderPubkey      string `json:"builder_pubkey"`
+	NumBlocksDelivered uint64 `json:"num_blocks_delivered,string"`
+	TotalValue         string `json:"total_value"`
+}
+
+// StatusJSONConfig holds the relay configuration, only included if the website shows the config details
+type StatusJSONConfig struct {
+	RelayPubkey                 string `json:"relay_pubkey"`
+	GenesisForkVersion          string `json:"genesis_fork_version"`
+	BellatrixForkVersion        string `json:"bellatrix_fork_version"`
+	CapellaForkVersion          string `json:"capella_fork_version"`
+	GenesisValidatorsRoot       string `json:"genesis_validators_root"`
+	BuilderSigningDomain        string `json:"builder_signing_domain"`
+	BeaconProposerSigningDomain string `json:"beacon_proposer_signing_domain"`
+}
+
+// updateStatusJSON renders the JSON stats from the freshly updated status data
+func (srv *Webserver) updateStatusJSON(payloads []*database.DeliveredPayloadEntry) {
+	topBuilders, err := srv.topBuildersQuery.get(func() ([]*database.TopBuilderEntry, error) {
+		return srv.db.GetTopBuilders(numTopBuilders)
+	})
+	if err != nil {
+		srv.log.WithError(err).Error("error getting top builders")
+	}
+
+	data := StatusJSONData{
+		Network:              srv.statusHTMLData.Network,
+		HeadSlot:             srv.statusHTMLData.HeadSlot,
+		ValidatorsTotal:      srv.statusHTMLData.ValidatorsTotal,
+		ValidatorsRegistered: srv.statusHTMLData.ValidatorsRegistered,
+		ValidatorsActive:     srv.statusHTMLData.ValidatorsActive,
+		NumPayloadsDelivered: srv.statusHTMLData.NumPayloadsDelivered,
+		TopBuilders:          make([]TopBuilderJSON, len(topBuilders)),
+		RecentPayloads:       make([]common.BidTraceV2JSON, len(payloads)),
+	}
+
+	for i, entry := range topBuilders {
+		data.TopBuilders[i] = TopBuilderJSON{
+			BuilderPubkey:      entry.BuilderPubkey,
+			NumBlocksDelivered: entry.NumBlocksDelivered,
+			TotalValue:         entry.TotalValue,
+		}
+	}
+
+	for i, payload := range payloads {
+		data.RecentPayloads[i] = database.DeliveredPayloadEntryToBidTraceV2JSON(payload)
+	}
+
+	if srv.statusHTMLData.ShowConfigDetails {
+		data.Config = &StatusJSONConfig{
+			RelayPubkey:                 srv.statusHTMLData.RelayPubkey,
+			GenesisForkVersion:          srv.statusHTMLData.GenesisForkVersion,
+			BellatrixForkVersion:        srv.statusHTMLData.BellatrixForkVersion,
+			CapellaForkVersion:          srv.statusHTMLData.CapellaForkVersion,
+			GenesisValidatorsRoot:       srv.statusHTMLData.GenesisValidatorsRoot,
+			BuilderSigningDomain:        srv.statusHTMLData.BuilderSigningDomain,
+			BeaconProposerSigningDomain: srv.statusHTMLData.BeaconProposerSigningDomain,
+		}
+	}
+
+	statusJSON, err := json.Marshal(data)
+	if err != nil {
+		srv.log.WithError(err).Error("error marshalling status json")
+		return
+	}
+
+	srv.rootResponseLock.Lock()
+	srv.statusJSON = newRenderedPage(statusJSON)
+	srv.rootResponseLock.Unlock()
+}
+
+func (srv *Webserver) handleStatusJSON(w http.ResponseWriter, req *http.Request) {
+	srv.rootResponseLock.RLock()
+	statusJSON := srv.statusJSON
+	srv.rootResponseLock.RUnlock()
+
+	if statusJSON == nil {
+		http.Error(w, "stats not available yet", http.StatusServiceUnavailable)
+		return
+	}
+	srv.writePage(w, req, statusJSON, "application/json", websiteRefreshInterval)
+}
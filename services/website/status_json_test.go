@@ -0,0 +1,71 @@
+package website
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flashbots/mev-boost-relay/database"
+	"github.com/stretchr/testify/require"
+)
+
+type topBuildersDB struct {
+	database.MockDB
+}
+
+func (db topBuildersDB) GetTopBuilders(limit uint64) ([]*database.TopBuilderEntry, error) {
+	return []*database.TopBuilderEntry{{BuilderPubkey: testBuilderPubkey, NumBlocksDelivered: 7, TotalValue: "1000"}}, nil
+}
+
+func TestHandleStatusJSON(t *testing.T) {
+	srv := newTestWebserver(t, topBuildersDB{})
+	router := srv.getRouter()
+
+	request := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, pathStatusJSON, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// not rendered yet
+	rr := request("")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	srv.statusHTMLData.HeadSlot = 123
+	srv.statusHTMLData.NumPayloadsDelivered = 1
+	srv.updateStatusJSON([]*database.DeliveredPayloadEntry{{Slot: 122, BuilderPubkey: testBuilderPubkey, Value: "1000"}})
+
+	rr = request("")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	etag := rr.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	data := new(StatusJSONData)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), data))
+	require.Equal(t, "goerli", data.Network)
+	require.Equal(t, uint64(123), data.HeadSlot)
+	require.Equal(t, []TopBuilderJSON{{BuilderPubkey: testBuilderPubkey, NumBlocksDelivered: 7, TotalValue: "1000"}}, data.TopBuilders)
+	require.Len(t, data.RecentPayloads, 1)
+	require.Equal(t, uint64(122), data.RecentPayloads[0].Slot)
+	require.Nil(t, data.Config)
+
+	rr = request(etag)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+
+	// the config is only included if the website shows the config details
+	srv.statusHTMLData.ShowConfigDetails = true
+	srv.updateStatusJSON(nil)
+	rr = request(etag)
+	require.Equal(t, http.StatusOK, rr.Code)
+	data = new(StatusJSONData)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), data))
+	require.NotNil(t, data.Config)
+	require.Equal(t, srv.statusHTMLData.GenesisForkVersion, data.Config.GenesisForkVersion)
+	require.Empty(t, data.RecentPayloads)
+}
@@ -31,12 +31,14 @@ var (
 	EnablePprof             = os.Getenv("PPROF") == "1"
 )
 
+const pathStatusJSON = "/api/v1/stats"
+
 type WebserverOpts struct {
 	ListenAddress  string
 	RelayPubkeyHex string
 	NetworkDetails *common.EthNetworkDetails
 	Redis          *datastore.RedisCache
-	DB             *database.DatabaseService
+	DB             database.IDatabaseService
 	Log            *logrus.Entry
 
 	ShowConfigDetails bool
@@ -50,18 +52,30 @@ type Webserver struct {
 	log  *logrus.Entry
 
 	redis *datastore.RedisCache
-	db    *database.DatabaseService
+	db    database.IDatabaseService
 
 	srv        *http.Server
 	srvStarted uberatomic.Bool
 
 	indexTemplate    *template.Template
+	builderTemplate  *template.Template
 	statusHTMLData   StatusHTMLData
 	rootResponseLock sync.RWMutex
 
-	htmlDefault     *[]byte
-	htmlByValueDesc *[]byte
-	htmlByValueAsc  *[]byte
+	htmlDefault     *renderedPage
+	htmlByValueDesc *renderedPage
+	htmlByValueAsc  *renderedPage
+	statusJSON      *renderedPage
+
+	// the expensive queries are only refreshed every websiteQueryCacheDuration
+	numRegisteredQuery       *cachedQuery[uint64]
+	numPayloadsQuery         *cachedQuery[uint64]
+	payloadsByValueDescQuery *cachedQuery[[]*database.DeliveredPayloadEntry]
+	payloadsByValueAscQuery  *cachedQuery[[]*database.DeliveredPayloadEntry]
+	topBuildersQuery         *cachedQuery[[]*database.TopBuilderEntry]
+	dailyAggregatesQuery     *cachedQuery[[]*database.DailyAggregateEntry]
+
+	builderPages *builderPageCache
 
 	minifier *minify.M
 }
@@ -79,9 +93,18 @@ func NewWebserver(opts *WebserverOpts) (*Webserver, error) {
 		redis: opts.Redis,
 		db:    opts.DB,
 
-		htmlDefault:     &[]byte{},
-		htmlByValueDesc: &[]byte{},
-		htmlByValueAsc:  &[]byte{},
+		htmlDefault:     newRenderedPage([]byte{}),
+		htmlByValueDesc: newRenderedPage([]byte{}),
+		htmlByValueAsc:  newRenderedPage([]byte{}),
+
+		numRegisteredQuery:       newCachedQuery[uint64](websiteQueryCacheDuration),
+		numPayloadsQuery:         newCachedQuery[uint64](websiteQueryCacheDuration),
+		payloadsByValueDescQuery: newCachedQuery[[]*database.DeliveredPayloadEntry](websiteQueryCacheDuration),
+		payloadsByValueAscQuery:  newCachedQuery[[]*database.DeliveredPayloadEntry](websiteQueryCacheDuration),
+		topBuildersQuery:         newCachedQuery[[]*database.TopBuilderEntry](websiteQueryCacheDuration),
+		dailyAggregatesQuery:     newCachedQuery[[]*database.DailyAggregateEntry](websiteQueryCacheDuration),
+
+		builderPages: newBuilderPageCache(),
 
 		minifier: minifier,
 	}
@@ -91,6 +114,11 @@ func NewWebserver(opts *WebserverOpts) (*Webserver, error) {
 		return nil, err
 	}
 
+	server.builderTemplate, err = ParseBuilderTemplate()
+	if err != nil {
+		return nil, err
+	}
+
 	server.statusHTMLData = StatusHTMLData{
 		Network:                     opts.NetworkDetails.Name,
 		RelayPubkey:                 opts.RelayPubkeyHex,
@@ -126,7 +154,7 @@ func (srv *Webserver) StartServer() (err error) {
 	go func() {
 		for {
 			srv.updateHTML()
-			time.Sleep(10 * time.Second)
+			time.Sleep(websiteRefreshInterval)
 		}
 	}()
 
@@ -140,7 +168,11 @@ func (srv *Webserver) StartServer() (err error) {
 		IdleTimeout:       3 * time.Second,
 	}
 
-	err = srv.srv.ListenAndServe()
+	ln, err := common.Listen(srv.opts.ListenAddress)
+	if err != nil {
+		return err
+	}
+	err = srv.srv.Serve(ln)
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
@@ -150,6 +182,9 @@ func (srv *Webserver) StartServer() (err error) {
 func (srv *Webserver) getRouter() http.Handler {
 	r := mux.NewRouter()
 	r.HandleFunc("/", srv.handleRoot).Methods(http.MethodGet)
+	r.HandleFunc(pathStatusJSON, srv.handleStatusJSON).Methods(http.MethodGet)
+	r.HandleFunc(pathBuilder, srv.handleBuilder).Methods(http.MethodGet)
+	r.HandleFunc(pathChartsDaily, srv.handleChartsDaily).Methods(http.MethodGet)
 	if EnablePprof {
 		srv.log.Info("pprof API enabled")
 		r.PathPrefix("/debug/pprof/").Handler(http.DefaultServeMux)
@@ -161,7 +196,7 @@ func (srv *Webserver) getRouter() http.Handler {
 }
 
 func (srv *Webserver) updateHTML() {
-	_numRegistered, err := srv.db.NumRegisteredValidators()
+	_numRegistered, err := srv.numRegisteredQuery.get(srv.db.NumRegisteredValidators)
 	if err != nil {
 		srv.log.WithError(err).Error("error getting number of registered validators in updateStatusHTMLData")
 	}
@@ -176,17 +211,21 @@ func (srv *Webserver) updateHTML() {
 		srv.log.WithError(err).Error("error getting recent payloads")
 	}
 
-	payloadsByValueDesc, err := srv.db.GetRecentDeliveredPayloads(database.GetPayloadsFilters{Limit: 30, OrderByValue: -1})
+	payloadsByValueDesc, err := srv.payloadsByValueDescQuery.get(func() ([]*database.DeliveredPayloadEntry, error) {
+		return srv.db.GetRecentDeliveredPayloads(database.GetPayloadsFilters{Limit: 30, OrderByValue: -1})
+	})
 	if err != nil {
 		srv.log.WithError(err).Error("error getting recent payloads")
 	}
 
-	payloadsByValueAsc, err := srv.db.GetRecentDeliveredPayloads(database.GetPayloadsFilters{Limit: 30, OrderByValue: 1})
+	payloadsByValueAsc, err := srv.payloadsByValueAscQuery.get(func() ([]*database.DeliveredPayloadEntry, error) {
+		return srv.db.GetRecentDeliveredPayloads(database.GetPayloadsFilters{Limit: 30, OrderByValue: 1})
+	})
 	if err != nil {
 		srv.log.WithError(err).Error("error getting recent payloads")
 	}
 
-	_numPayloadsDelivered, err := srv.db.GetNumDeliveredPayloads()
+	_numPayloadsDelivered, err := srv.numPayloadsQuery.get(srv.db.GetNumDeliveredPayloads)
 	if err != nil {
 		srv.log.WithError(err).Error("error getting number of delivered payloads")
 	}
@@ -212,6 +251,8 @@ func (srv *Webserver) updateHTML() {
 	srv.statusHTMLData.NumPayloadsDelivered = _numPayloadsDelivered
 	srv.statusHTMLData.HeadSlot = _latestSlotInt
 
+	srv.updateStatusJSON(payloads)
+
 	// Now generate the HTML
 	htmlDefault := bytes.Buffer{}
 	htmlByValueDesc := bytes.Buffer{}
@@ -257,25 +298,21 @@ func (srv *Webserver) updateHTML() {
 
 	// Swap the html pointers
 	srv.rootResponseLock.Lock()
-	srv.htmlDefault = &htmlDefaultBytes
-	srv.htmlByValueDesc = &htmlValueDescBytes
-	srv.htmlByValueAsc = &htmlValueDescAsc
+	srv.htmlDefault = newRenderedPage(htmlDefaultBytes)
+	srv.htmlByValueDesc = newRenderedPage(htmlValueDescBytes)
+	srv.htmlByValueAsc = newRenderedPage(htmlValueDescAsc)
 	srv.rootResponseLock.Unlock()
 }
 
 func (srv *Webserver) handleRoot(w http.ResponseWriter, req *http.Request) {
-	var err error
-
 	srv.rootResponseLock.RLock()
-	defer srv.rootResponseLock.RUnlock()
+	page := srv.htmlDefault
 	if req.URL.Query().Get("order_by") == "-value" {
-		_, err = w.Write(*srv.htmlByValueDesc)
+		page = srv.htmlByValueDesc
 	} else if req.URL.Query().Get("order_by") == "value" {
-		_, err = w.Write(*srv.htmlByValueAsc)
-	} else {
-		_, err = w.Write(*srv.htmlDefault)
-	}
-	if err != nil {
-		srv.log.WithError(err).Error("error writing template")
+		page = srv.htmlByValueAsc
 	}
+	srv.rootResponseLock.RUnlock()
+
+	srv.writePage(w, req, page, "text/html; charset=utf-8", websiteRefreshInterval)
 }
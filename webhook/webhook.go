@@ -0,0 +1,228 @@
+// Package webhook notifies operators of operational events of the relay, like failed payload deliveries or unavailable
+// dependencies, by posting them to webhooks (e.g. Slack, or PagerDuty through an integration).
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypes are all the event types webhooks are notified of
+var EventTypes = []string{common.EventPayloadDeliveryFailed, common.EventBeaconPublishFailed, common.EventBuilderDemoted, common.EventDependencyDown, common.EventDependencyRecovered, common.EventFeeRecipientChanged}
+
+const (
+	// HeaderTimestamp is the unix timestamp (seconds) of the notification, which is part of the signed message
+	HeaderTimestamp = "X-Relay-Timestamp"
+	// HeaderSignature is the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret
+	HeaderSignature = "X-Relay-Signature"
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("invalid webhook URL")
+	ErrUnknownEventType  = errors.New("unknown webhook event type")
+	ErrWebhookStatus     = errors.New("unexpected webhook response status")
+)
+
+// Notification is the JSON body posted to the webhooks. The text field makes it usable for Slack incoming webhooks as it is.
+type Notification struct {
+	Event     string `json:"event"`
+	Network   string `json:"network,omitempty"`
+	Timestamp int64  `json:"timestamp,string"`
+	Text      string `json:"text"`
+	Data      any    `json:"data,omitempty"`
+}
+
+// Opts configures the notifier
+type Opts struct {
+	URLs          []string
+	Secret        string   // HMAC key of the signature header, not signed if empty
+	Events        []string // event types to notify of, all if empty
+	Timeout       time.Duration
+	MaxRetries    int
+	RetryInterval time.Duration // doubled after every retry
+}
+
+// Notifier posts the notifications to all webhooks in the background, retrying failed requests
+type Notifier struct {
+	log     *logrus.Entry
+	opts    Opts
+	events  map[string]bool
+	client  *http.Client
+	network string
+
+	pending *sync.WaitGroup
+}
+
+// ParseURLs parses the comma-separated webhook URLs
+func ParseURLs(s string) ([]string, error) {
+	urls := []string{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidWebhookURL, entry)
+		}
+		urls = append(urls, entry)
+	}
+	return urls, nil
+}
+
+func NewNotifier(log *logrus.Entry, opts Opts) (*Notifier, error) {
+	events := make(map[string]bool)
+	for _, event := range opts.Events {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		} else if !isEventType(event) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, event)
+		}
+		events[event] = true
+	}
+	if len(events) == 0 {
+		for _, event := range EventTypes {
+			events[event] = true
+		}
+	}
+
+	return &Notifier{
+		log:     log.WithField("component", "webhookNotifier"),
+		opts:    opts,
+		events:  events,
+		client:  &http.Client{Timeout: opts.Timeout},
+		pending: new(sync.WaitGroup),
+	}, nil
+}
+
+// WithNetwork returns a notifier for the events of the network, which are sent with the network name. It shares the
+// webhooks and the notifications in flight with n.
+func (n *Notifier) WithNetwork(network string) *Notifier {
+	withNetwork := *n
+	withNetwork.log = n.log.WithField("network", network)
+	withNetwork.network = network
+	return &withNetwork
+}
+
+func isEventType(event string) bool {
+	for _, t := range EventTypes {
+		if t == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify posts the notification to all webhooks without waiting, if the event type is enabled
+func (n *Notifier) Notify(event, text string, data any) {
+	if !n.events[event] {
+		return
+	}
+	now := time.Now()
+	if n.network != "" {
+		text = fmt.Sprintf("[%s] %s", n.network, text)
+	}
+	body, err := json.Marshal(Notification{
+		Event:     event,
+		Network:   n.network,
+		Timestamp: now.Unix(),
+		Text:      text,
+		Data:      data,
+	})
+	if err != nil {
+		n.log.WithError(err).WithField("event", event).Error("failed to encode webhook notification")
+		return
+	}
+
+	for _, webhookURL := range n.opts.URLs {
+		n.pending.Add(1)
+		go func(webhookURL string) {
+			defer n.pending.Done()
+			n.send(event, webhookURL, now, body)
+		}(webhookURL)
+	}
+}
+
+// send posts the notification, retrying network errors, 429 and 5xx responses
+func (n *Notifier) send(event, webhookURL string, timestamp time.Time, body []byte) {
+	log := n.log.WithField("event", event)
+	retryInterval := n.opts.RetryInterval
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(webhookURL, timestamp, body)
+		if err == nil {
+			common.WebhookNotificationsTotal.WithLabelValues(event, "success").Inc()
+			return
+		}
+		if !retry || attempt >= n.opts.MaxRetries {
+			common.WebhookNotificationsTotal.WithLabelValues(event, "failure").Inc()
+			// the URL isn't logged, as it usually contains a token
+			log.WithError(err).WithField("attempts", attempt+1).Error("failed to notify webhook")
+			return
+		}
+		time.Sleep(retryInterval)
+		retryInterval *= 2
+	}
+}
+
+func (n *Notifier) post(webhookURL string, timestamp time.Time, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.opts.Secret != "" {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, Sign(n.opts.Secret, ts, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// the error of the client includes the URL
+		return true, errors.Unwrap(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%w: %d", ErrWebhookStatus, resp.StatusCode)
+}
+
+// Sign returns the signature of the notification, which receivers recompute with the shared secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Wait waits for the notifications in flight, up to the timeout
+func (n *Notifier) Wait(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		n.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		n.log.Warn("timed out waiting for the webhook notifications")
+	}
+}
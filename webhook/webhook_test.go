@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURLs(t *testing.T) {
+	urls, err := ParseURLs("")
+	require.NoError(t, err)
+	require.Len(t, urls, 0)
+
+	urls, err = ParseURLs("https://hooks.slack.com/services/T0/B0/x, http://localhost:8080/alerts")
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://hooks.slack.com/services/T0/B0/x", "http://localhost:8080/alerts"}, urls)
+
+	for _, s := range []string{"hooks.slack.com", "ftp://localhost", "https://"} {
+		_, err = ParseURLs(s)
+		require.ErrorIs(t, err, ErrInvalidWebhookURL, s)
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	n, err := NewNotifier(common.TestLog, Opts{})
+	require.NoError(t, err)
+	require.Len(t, n.events, len(EventTypes))
+
+	n, err = NewNotifier(common.TestLog, Opts{Events: []string{common.EventBuilderDemoted, " dependency_down"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{common.EventBuilderDemoted: true, common.EventDependencyDown: true}, n.events)
+
+	_, err = NewNotifier(common.TestLog, Opts{Events: []string{"foo"}})
+	require.ErrorIs(t, err, ErrUnknownEventType)
+}
+
+func TestNotify(t *testing.T) {
+	var numRequests atomic.Int32
+	var notification Notification
+	var timestamp, signature, body string
+	var lock sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the first request fails, and is retried
+		if numRequests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodyBytes, _ := io.ReadAll(req.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		body = string(bodyBytes)
+		timestamp = req.Header.Get(HeaderTimestamp)
+		signature = req.Header.Get(HeaderSignature)
+		_ = json.Unmarshal(bodyBytes, &notification)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(common.TestLog, Opts{
+		URLs:          []string{srv.URL},
+		Secret:        "secret",
+		Events:        []string{common.EventBuilderDemoted},
+		Timeout:       time.Second,
+		MaxRetries:    2,
+		RetryInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	n.Notify(common.EventDependencyDown, "not enabled", nil)
+	n.Notify(common.EventBuilderDemoted, "builder demoted", map[string]string{"slot": "1"})
+	n.Wait(time.Second)
+
+	require.Equal(t, int32(2), numRequests.Load())
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, common.EventBuilderDemoted, notification.Event)
+	require.Equal(t, "builder demoted", notification.Text)
+	require.Equal(t, map[string]any{"slot": "1"}, notification.Data)
+	require.Equal(t, Sign("secret", timestamp, []byte(body)), signature)
+	require.Empty(t, notification.Network)
+
+	// the notifications of a network carry its name, and are waited for by the shared notifier
+	lock.Unlock()
+	n.WithNetwork("holesky").Notify(common.EventBuilderDemoted, "builder demoted", nil)
+	n.Wait(time.Second)
+	lock.Lock()
+	require.Equal(t, int32(3), numRequests.Load())
+	require.Equal(t, "holesky", notification.Network)
+	require.Equal(t, "[holesky] builder demoted", notification.Text)
+}
+
+func TestNotifyRetries(t *testing.T) {
+	var numRequests, status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		numRequests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(common.TestLog, Opts{URLs: []string{srv.URL}, Timeout: time.Second, MaxRetries: 2, RetryInterval: time.Millisecond})
+	require.NoError(t, err)
+
+	// server errors are retried
+	n.Notify(common.EventDependencyDown, "redis unavailable", nil)
+	n.Wait(time.Second)
+	require.Equal(t, int32(3), numRequests.Load())
+
+	// client errors aren't
+	numRequests.Store(0)
+	status.Store(http.StatusBadRequest)
+	n.Notify(common.EventDependencyDown, "redis unavailable", nil)
+	n.Wait(time.Second)
+	require.Equal(t, int32(1), numRequests.Load())
+}